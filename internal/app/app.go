@@ -65,7 +65,7 @@ func (a *App) Close(ctx context.Context) error {
 	// 关闭 Telegram Bot
 	if a.TelegramBot != nil {
 		if err := a.TelegramBot.Stop(ctx); err != nil {
-			logger.L().Warnf("Failed to stop Telegram bot: %v", err)
+			logger.Ctx(ctx).Warnf("Failed to stop Telegram bot: %v", err)
 		}
 	}
 
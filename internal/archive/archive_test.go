@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/config"
+)
+
+func TestLocalStoragePut(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path, err := storage.Put(context.Background(), "-100/20240102/42_abc.txt", []byte("hello"), "text/plain")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("expected archived content, got %q err=%v", data, err)
+	}
+	if filepath.Base(path) != "42_abc.txt" {
+		t.Fatalf("unexpected archive path: %s", path)
+	}
+
+	if _, err := storage.Put(context.Background(), "../escape.txt", []byte("x"), ""); err == nil {
+		t.Fatal("expected key escaping archive root to be rejected")
+	}
+}
+
+func TestS3StorageSign(t *testing.T) {
+	storage, err := NewS3Storage(config.S3Config{
+		Endpoint:  "http://minio:9000",
+		Bucket:    "bucket",
+		AccessKey: "AKID",
+		SecretKey: "SECRET",
+	}, WithS3NowFunc(func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, "http://minio:9000/bucket/-100/20240102/42_abc.txt", strings.NewReader("hello"))
+	storage.sign(req, []byte("hello"))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKID/20240102/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=e4088a800a1b08d912a0c31a5951466a2453632a6ae94825492c1bf016c3b124"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("unexpected authorization header:\n got %s\nwant %s", got, want)
+	}
+	if req.Header.Get("X-Amz-Date") != "20240102T030405Z" {
+		t.Fatalf("unexpected x-amz-date: %s", req.Header.Get("X-Amz-Date"))
+	}
+}
+
+func TestS3StoragePut(t *testing.T) {
+	var gotPath, gotBody, gotType string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody, gotType = r.URL.Path, string(body), r.Header.Get("Content-Type")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	storage, err := NewS3Storage(config.S3Config{Endpoint: server.URL, Bucket: "bucket", AccessKey: "AKID", SecretKey: "SECRET"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	location, err := storage.Put(context.Background(), "-100/a.pdf", []byte("pdf"), "application/pdf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if location != server.URL+"/bucket/-100/a.pdf" || gotPath != "/bucket/-100/a.pdf" || gotBody != "pdf" || gotType != "application/pdf" {
+		t.Fatalf("unexpected upload: location=%s path=%s body=%q type=%s", location, gotPath, gotBody, gotType)
+	}
+
+	status = http.StatusForbidden
+	if _, err := storage.Put(context.Background(), "-100/a.pdf", []byte("pdf"), ""); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	if storage, err := NewStorage(config.MediaArchiveConfig{}); err != nil || storage != nil {
+		t.Fatalf("expected nil storage when backend is empty, got %v err=%v", storage, err)
+	}
+	if _, err := NewStorage(config.MediaArchiveConfig{Backend: "ftp"}); err == nil {
+		t.Fatal("expected unsupported backend error")
+	}
+	if _, err := NewStorage(config.MediaArchiveConfig{Backend: "s3", S3: config.S3Config{Endpoint: "minio:9000"}}); err == nil {
+		t.Fatal("expected invalid endpoint error")
+	}
+}
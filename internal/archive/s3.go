@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go_bot/internal/config"
+)
+
+const s3RequestTimeout = 60 * time.Second
+
+// S3Storage S3 兼容对象存储（AWS S3、MinIO 等），按 path-style 访问并使用 SigV4 签名
+type S3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+
+	httpClient *http.Client
+	nowFunc    func() time.Time
+}
+
+// S3Option 自定义 S3 存储行为
+type S3Option func(*S3Storage)
+
+// WithS3HTTPClient 自定义 HTTP 客户端（测试时使用）
+func WithS3HTTPClient(hc *http.Client) S3Option {
+	return func(s *S3Storage) {
+		if hc != nil {
+			s.httpClient = hc
+		}
+	}
+}
+
+// WithS3NowFunc 自定义签名时间（测试时使用）
+func WithS3NowFunc(now func() time.Time) S3Option {
+	return func(s *S3Storage) {
+		if now != nil {
+			s.nowFunc = now
+		}
+	}
+}
+
+// NewS3Storage 根据配置创建 S3 兼容存储
+func NewS3Storage(cfg config.S3Config, opts ...S3Option) (*S3Storage, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 bucket and credentials are required")
+	}
+
+	storage := &S3Storage{
+		endpoint:   endpoint,
+		bucket:     cfg.Bucket,
+		region:     cfg.Region,
+		accessKey:  cfg.AccessKey,
+		secretKey:  cfg.SecretKey,
+		httpClient: &http.Client{Timeout: s3RequestTimeout},
+		nowFunc:    time.Now,
+	}
+	if storage.region == "" {
+		storage.region = "us-east-1"
+	}
+	for _, opt := range opts {
+		opt(storage)
+	}
+	return storage, nil
+}
+
+// Put 以 PUT Object 上传文件，返回对象 URL
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	objectURL := *s.endpoint
+	objectURL.Path = strings.TrimRight(s.endpoint.Path, "/") + "/" + s.bucket + "/" + strings.TrimLeft(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("build s3 request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, data)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("s3 put: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("s3 put: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return objectURL.String(), nil
+}
+
+// sign 按 AWS Signature Version 4 为请求签名（签名 host、x-amz-content-sha256、x-amz-date）
+func (s *S3Storage) sign(req *http.Request, payload []byte) {
+	now := s.nowFunc().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,28 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+
+	"go_bot/internal/config"
+)
+
+// Storage 归档存储：按 key 写入文件内容，返回可追溯的归档地址
+type Storage interface {
+	// Put 写入文件，同一 key 重复写入会覆盖；返回的地址会记录到消息文档
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+// NewStorage 按配置创建存储后端，未配置后端时返回 nil
+func NewStorage(cfg config.MediaArchiveConfig) (Storage, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		return NewLocalStorage(cfg.Dir)
+	case "s3":
+		return NewS3Storage(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unsupported media archive backend: %s", cfg.Backend)
+	}
+}
@@ -10,14 +10,73 @@ import (
 
 // Config 应用程序配置
 type Config struct {
-	TelegramToken        string  // Telegram Bot API Token
-	BotOwnerIDs          []int64 // Bot管理员ID列表
-	MongoURI             string  // MongoDB连接URI
-	MongoDBName          string  // MongoDB数据库名称
-	MessageRetentionDays int     // 消息保留天数（过期自动删除）
-	ChannelID            int64   // 源频道 ID（用于转发功能）
-	DailyBillPushEnabled bool    // 是否启用每日账单推送
-	Payment              PaymentConfig
+	TelegramToken             string         // Telegram Bot API Token
+	BotOwnerIDs               []int64        // Bot管理员ID列表
+	MongoURI                  string         // MongoDB连接URI
+	MongoDBName               string         // MongoDB数据库名称
+	MessageRetentionDays      int            // 消息保留天数（过期自动删除）
+	MessageRetentionTierDays  map[string]int // 按群等级覆盖的消息保留天数（basic/merchant/upstream -> 天数）
+	ChannelID                 int64          // 源频道 ID（用于转发功能）
+	ChannelForwardMode        string         // 频道消息投递方式：forward（显示来源）或 copy（不显示来源）
+	ChannelForwardTarget      string         // 频道消息默认投递目标（tier=... tag=...），空表示所有启用转发的群
+	DailyBillPushEnabled      bool           // 是否启用每日账单推送
+	PanicAlertEnabled         bool           // handler panic 时是否私聊告警 owner
+	BalanceAlertChatID        int64          // 上游低余额告警群 ID，0 表示未配置
+	BalanceAlertToOwners      bool           // 上游低余额告警改为私聊 owner
+	SettlementFallbackToTotal bool           // 日结找不到目标日期账单时回退到汇总总额
+	HealthPort                int            // 健康探针 HTTP 端口（/healthz、/readyz），0 表示不启动
+	PendingStateSweepInterval time.Duration  // 内存待处理状态（订单联动、四方待确认下发）的过期清理间隔
+	CommandRateLimit          CommandRateLimitConfig
+	MediaDedup                MediaDedupConfig
+	MessageBatch              MessageBatchConfig
+	MediaArchive              MediaArchiveConfig
+	WorkerPool                WorkerPoolConfig
+	Payment                   PaymentConfig
+}
+
+// MediaDedupConfig 媒体消息去重配置
+type MediaDedupConfig struct {
+	Mode   string        // 去重策略：off（关闭）、mark（照常记录并标记重复）、skip（不再记录，仅累加原消息的重复次数）
+	Window time.Duration // 回溯窗口，只与窗口内本群已记录的媒体比较
+}
+
+// MessageBatchConfig 消息批量写入配置
+type MessageBatchConfig struct {
+	Size          int           // 缓冲达到该条数立即落库，<= 1 表示逐条写入
+	FlushInterval time.Duration // 缓冲最长停留时间，到期即落库
+}
+
+// MediaArchiveConfig 媒体归档配置：群内命中配置类型的媒体下载后转存到本地目录或 S3 兼容存储
+type MediaArchiveConfig struct {
+	Backend     string // 存储后端：空（关闭）、local、s3
+	Dir         string // local 后端的归档根目录
+	MaxBytes    int64  // 单个文件大小上限，超过不归档（Bot API 下载上限为 20MB）
+	Concurrency int    // 同时进行的归档任务数
+	MaxAttempts int    // 单个文件最多尝试次数（含首次）
+	S3          S3Config
+}
+
+// S3Config S3 兼容对象存储配置（按 path-style 访问：endpoint/bucket/key）
+type S3Config struct {
+	Endpoint  string // 例如 https://s3.amazonaws.com、http://minio:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// WorkerPoolConfig 消息处理工作池配置：worker 数在上下限之间按队列水位自动伸缩
+type WorkerPoolConfig struct {
+	MinWorkers   int           // 常驻 worker 数
+	MaxWorkers   int           // 队列持续积压时最多扩容到的 worker 数
+	DrainTimeout time.Duration // 关闭时等待已入队任务处理完的最长时间，超时后放弃剩余任务
+}
+
+// CommandRateLimitConfig 按用户 + 命令的限流配置
+type CommandRateLimitConfig struct {
+	Window    time.Duration  // 滑动窗口长度
+	Limit     int            // 窗口内每个用户每个命令允许的默认次数，0 表示关闭限流
+	Overrides map[string]int // 指定命令的阈值（命令或功能名 -> 次数），0 表示该命令不限流
 }
 
 // PaymentConfig 支付相关配置
@@ -43,10 +102,12 @@ func Load() (*Config, error) {
 	}
 
 	cfg := &Config{
-		TelegramToken:        os.Getenv("TELEGRAM_TOKEN"),
-		MongoURI:             os.Getenv("MONGO_URI"),
-		MongoDBName:          mongoDBName,
-		DailyBillPushEnabled: true,
+		TelegramToken:             os.Getenv("TELEGRAM_TOKEN"),
+		MongoURI:                  os.Getenv("MONGO_URI"),
+		MongoDBName:               mongoDBName,
+		DailyBillPushEnabled:      true,
+		PendingStateSweepInterval: time.Minute,
+		WorkerPool:                WorkerPoolConfig{MinWorkers: 10, MaxWorkers: 50, DrainTimeout: 5 * time.Second},
 	}
 
 	if enabled := strings.TrimSpace(os.Getenv("DAILY_BILL_PUSH_ENABLED")); enabled != "" {
@@ -57,6 +118,81 @@ func Load() (*Config, error) {
 		cfg.DailyBillPushEnabled = value
 	}
 
+	if enabled := strings.TrimSpace(os.Getenv("PANIC_ALERT_ENABLED")); enabled != "" {
+		value, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PANIC_ALERT_ENABLED: %w", err)
+		}
+		cfg.PanicAlertEnabled = value
+	}
+
+	// 解析BALANCE_ALERT_TARGET（可选：owners 或告警群 chat ID，未设置时告警发到原群）
+	if target := strings.TrimSpace(os.Getenv("BALANCE_ALERT_TARGET")); target != "" {
+		if strings.EqualFold(target, "owners") {
+			cfg.BalanceAlertToOwners = true
+		} else {
+			chatID, err := strconv.ParseInt(target, 10, 64)
+			if err != nil || chatID == 0 {
+				return nil, fmt.Errorf("invalid BALANCE_ALERT_TARGET: %s (expected owners or a chat ID)", target)
+			}
+			cfg.BalanceAlertChatID = chatID
+		}
+	}
+
+	if enabled := strings.TrimSpace(os.Getenv("SETTLEMENT_FALLBACK_TO_TOTAL")); enabled != "" {
+		value, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SETTLEMENT_FALLBACK_TO_TOTAL: %w", err)
+		}
+		cfg.SettlementFallbackToTotal = value
+	}
+
+	// 解析HEALTH_PORT（可选，未设置时不启动健康探针 HTTP 服务）
+	if portStr := strings.TrimSpace(os.Getenv("HEALTH_PORT")); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid HEALTH_PORT: %s (expected 0-65535)", portStr)
+		}
+		cfg.HealthPort = port
+	}
+
+	// 解析PENDING_STATE_SWEEP_SECONDS（可选，默认 60 秒）
+	if intervalStr := strings.TrimSpace(os.Getenv("PENDING_STATE_SWEEP_SECONDS")); intervalStr != "" {
+		seconds, err := strconv.Atoi(intervalStr)
+		if err != nil || seconds < 10 || seconds > 3600 {
+			return nil, fmt.Errorf("invalid PENDING_STATE_SWEEP_SECONDS: %s (expected 10-3600)", intervalStr)
+		}
+		cfg.PendingStateSweepInterval = time.Duration(seconds) * time.Second
+	}
+
+	// 解析WORKER_POOL_MIN_WORKERS / WORKER_POOL_MAX_WORKERS（可选，默认 10 / 50）
+	if minStr := strings.TrimSpace(os.Getenv("WORKER_POOL_MIN_WORKERS")); minStr != "" {
+		workers, err := strconv.Atoi(minStr)
+		if err != nil || workers < 1 || workers > 500 {
+			return nil, fmt.Errorf("invalid WORKER_POOL_MIN_WORKERS: %s (expected 1-500)", minStr)
+		}
+		cfg.WorkerPool.MinWorkers = workers
+	}
+	if maxStr := strings.TrimSpace(os.Getenv("WORKER_POOL_MAX_WORKERS")); maxStr != "" {
+		workers, err := strconv.Atoi(maxStr)
+		if err != nil || workers < 1 || workers > 500 {
+			return nil, fmt.Errorf("invalid WORKER_POOL_MAX_WORKERS: %s (expected 1-500)", maxStr)
+		}
+		cfg.WorkerPool.MaxWorkers = workers
+	}
+	if cfg.WorkerPool.MaxWorkers < cfg.WorkerPool.MinWorkers {
+		return nil, fmt.Errorf("WORKER_POOL_MAX_WORKERS (%d) must be >= WORKER_POOL_MIN_WORKERS (%d)", cfg.WorkerPool.MaxWorkers, cfg.WorkerPool.MinWorkers)
+	}
+
+	// 解析WORKER_POOL_DRAIN_TIMEOUT_SECONDS（可选，默认 5 秒）
+	if drainStr := strings.TrimSpace(os.Getenv("WORKER_POOL_DRAIN_TIMEOUT_SECONDS")); drainStr != "" {
+		seconds, err := strconv.Atoi(drainStr)
+		if err != nil || seconds < 1 || seconds > 60 {
+			return nil, fmt.Errorf("invalid WORKER_POOL_DRAIN_TIMEOUT_SECONDS: %s (expected 1-60)", drainStr)
+		}
+		cfg.WorkerPool.DrainTimeout = time.Duration(seconds) * time.Second
+	}
+
 	// 解析BOT_OWNER_IDS
 	ownerIDsStr := os.Getenv("BOT_OWNER_IDS")
 	if ownerIDsStr != "" {
@@ -82,6 +218,16 @@ func Load() (*Config, error) {
 		cfg.MessageRetentionDays = days
 	}
 
+	// 解析MESSAGE_RETENTION_TIER_DAYS（可选，如 "merchant=30,upstream=90"）
+	cfg.MessageRetentionTierDays = map[string]int{}
+	if tierDaysStr := strings.TrimSpace(os.Getenv("MESSAGE_RETENTION_TIER_DAYS")); tierDaysStr != "" {
+		tierDays, err := parseRetentionTierDays(tierDaysStr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MessageRetentionTierDays = tierDays
+	}
+
 	// 解析CHANNEL_ID（可选，用于转发功能）
 	channelIDStr := os.Getenv("CHANNEL_ID")
 	if channelIDStr != "" {
@@ -92,6 +238,47 @@ func Load() (*Config, error) {
 		cfg.ChannelID = channelID
 	}
 
+	// 解析CHANNEL_FORWARD_MODE（可选，默认 forward）
+	cfg.ChannelForwardMode = "forward"
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv("CHANNEL_FORWARD_MODE"))); mode != "" {
+		switch mode {
+		case "forward", "copy":
+			cfg.ChannelForwardMode = mode
+		default:
+			return nil, fmt.Errorf("invalid CHANNEL_FORWARD_MODE: %s (expected forward or copy)", mode)
+		}
+	}
+	// CHANNEL_FORWARD_TARGET 的格式由转发服务在启动时校验
+	cfg.ChannelForwardTarget = strings.TrimSpace(os.Getenv("CHANNEL_FORWARD_TARGET"))
+
+	// 加载命令限流配置
+	rateLimitCfg, err := loadCommandRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.CommandRateLimit = rateLimitCfg
+
+	// 加载媒体去重配置
+	mediaDedupCfg, err := loadMediaDedupConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.MediaDedup = mediaDedupCfg
+
+	// 加载消息批量写入配置
+	messageBatchCfg, err := loadMessageBatchConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.MessageBatch = messageBatchCfg
+
+	// 加载媒体归档配置
+	mediaArchiveCfg, err := loadMediaArchiveConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.MediaArchive = mediaArchiveCfg
+
 	// 加载四方支付配置
 	sifangCfg, err := loadSifangConfig()
 	if err != nil {
@@ -156,6 +343,216 @@ func loadSifangConfig() (SifangConfig, error) {
 	return cfg, nil
 }
 
+func loadCommandRateLimitConfig() (CommandRateLimitConfig, error) {
+	cfg := CommandRateLimitConfig{
+		Window:    10 * time.Second,
+		Limit:     5,
+		Overrides: map[string]int{},
+	}
+
+	if windowStr := strings.TrimSpace(os.Getenv("COMMAND_RATE_LIMIT_WINDOW_SECONDS")); windowStr != "" {
+		seconds, err := strconv.Atoi(windowStr)
+		if err != nil || seconds <= 0 {
+			return CommandRateLimitConfig{}, fmt.Errorf("invalid COMMAND_RATE_LIMIT_WINDOW_SECONDS: %s", windowStr)
+		}
+		cfg.Window = time.Duration(seconds) * time.Second
+	}
+
+	if limitStr := strings.TrimSpace(os.Getenv("COMMAND_RATE_LIMIT_MAX")); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return CommandRateLimitConfig{}, fmt.Errorf("invalid COMMAND_RATE_LIMIT_MAX: %s", limitStr)
+		}
+		cfg.Limit = limit
+	}
+
+	if overridesStr := strings.TrimSpace(os.Getenv("COMMAND_RATE_LIMIT_OVERRIDES")); overridesStr != "" {
+		overrides, err := parseRateLimitOverrides(overridesStr)
+		if err != nil {
+			return CommandRateLimitConfig{}, err
+		}
+		cfg.Overrides = overrides
+	}
+
+	return cfg, nil
+}
+
+// loadMediaDedupConfig 读取媒体去重策略，默认仅标记重复、回溯 24 小时
+func loadMediaDedupConfig() (MediaDedupConfig, error) {
+	cfg := MediaDedupConfig{
+		Mode:   "mark",
+		Window: 24 * time.Hour,
+	}
+
+	if mode := strings.ToLower(strings.TrimSpace(os.Getenv("MEDIA_DEDUP_MODE"))); mode != "" {
+		switch mode {
+		case "off", "mark", "skip":
+			cfg.Mode = mode
+		default:
+			return MediaDedupConfig{}, fmt.Errorf("invalid MEDIA_DEDUP_MODE: %s (expected off, mark or skip)", mode)
+		}
+	}
+
+	if windowStr := strings.TrimSpace(os.Getenv("MEDIA_DEDUP_WINDOW_HOURS")); windowStr != "" {
+		hours, err := strconv.Atoi(windowStr)
+		if err != nil || hours <= 0 {
+			return MediaDedupConfig{}, fmt.Errorf("invalid MEDIA_DEDUP_WINDOW_HOURS: %s", windowStr)
+		}
+		cfg.Window = time.Duration(hours) * time.Hour
+	}
+
+	return cfg, nil
+}
+
+// loadMessageBatchConfig 读取消息批量写入配置，默认 50 条或 2 秒落库一次；最长停留不超过 30 秒，避免消息长时间不落库
+func loadMessageBatchConfig() (MessageBatchConfig, error) {
+	cfg := MessageBatchConfig{
+		Size:          50,
+		FlushInterval: 2 * time.Second,
+	}
+
+	if sizeStr := strings.TrimSpace(os.Getenv("MESSAGE_BATCH_SIZE")); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil || size < 1 || size > 1000 {
+			return MessageBatchConfig{}, fmt.Errorf("invalid MESSAGE_BATCH_SIZE: %s (expected 1-1000)", sizeStr)
+		}
+		cfg.Size = size
+	}
+
+	if intervalStr := strings.TrimSpace(os.Getenv("MESSAGE_BATCH_FLUSH_SECONDS")); intervalStr != "" {
+		seconds, err := strconv.Atoi(intervalStr)
+		if err != nil || seconds < 1 || seconds > 30 {
+			return MessageBatchConfig{}, fmt.Errorf("invalid MESSAGE_BATCH_FLUSH_SECONDS: %s (expected 1-30)", intervalStr)
+		}
+		cfg.FlushInterval = time.Duration(seconds) * time.Second
+	}
+
+	return cfg, nil
+}
+
+// loadMediaArchiveConfig 读取媒体归档配置，MEDIA_ARCHIVE_BACKEND 为空时关闭；默认单文件 20MB、2 个并发、最多尝试 3 次
+func loadMediaArchiveConfig() (MediaArchiveConfig, error) {
+	cfg := MediaArchiveConfig{
+		Backend:     strings.ToLower(strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_BACKEND"))),
+		MaxBytes:    20 << 20,
+		Concurrency: 2,
+		MaxAttempts: 3,
+	}
+
+	switch cfg.Backend {
+	case "":
+		return cfg, nil
+	case "local":
+		cfg.Dir = strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_DIR"))
+		if cfg.Dir == "" {
+			return MediaArchiveConfig{}, fmt.Errorf("MEDIA_ARCHIVE_DIR is required when MEDIA_ARCHIVE_BACKEND=local")
+		}
+	case "s3":
+		cfg.S3 = S3Config{
+			Endpoint:  strings.TrimRight(strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_S3_ENDPOINT")), "/"),
+			Bucket:    strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_S3_BUCKET")),
+			Region:    strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_S3_REGION")),
+			AccessKey: strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_S3_ACCESS_KEY")),
+			SecretKey: strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_S3_SECRET_KEY")),
+		}
+		if cfg.S3.Region == "" {
+			cfg.S3.Region = "us-east-1"
+		}
+		if cfg.S3.Endpoint == "" || cfg.S3.Bucket == "" || cfg.S3.AccessKey == "" || cfg.S3.SecretKey == "" {
+			return MediaArchiveConfig{}, fmt.Errorf("MEDIA_ARCHIVE_S3_ENDPOINT, MEDIA_ARCHIVE_S3_BUCKET, MEDIA_ARCHIVE_S3_ACCESS_KEY and MEDIA_ARCHIVE_S3_SECRET_KEY are required when MEDIA_ARCHIVE_BACKEND=s3")
+		}
+	default:
+		return MediaArchiveConfig{}, fmt.Errorf("invalid MEDIA_ARCHIVE_BACKEND: %s (expected local or s3)", cfg.Backend)
+	}
+
+	if sizeStr := strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_MAX_MB")); sizeStr != "" {
+		mb, err := strconv.Atoi(sizeStr)
+		if err != nil || mb < 1 || mb > 20 {
+			return MediaArchiveConfig{}, fmt.Errorf("invalid MEDIA_ARCHIVE_MAX_MB: %s (expected 1-20)", sizeStr)
+		}
+		cfg.MaxBytes = int64(mb) << 20
+	}
+
+	if concurrencyStr := strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_CONCURRENCY")); concurrencyStr != "" {
+		concurrency, err := strconv.Atoi(concurrencyStr)
+		if err != nil || concurrency < 1 || concurrency > 16 {
+			return MediaArchiveConfig{}, fmt.Errorf("invalid MEDIA_ARCHIVE_CONCURRENCY: %s (expected 1-16)", concurrencyStr)
+		}
+		cfg.Concurrency = concurrency
+	}
+
+	if attemptsStr := strings.TrimSpace(os.Getenv("MEDIA_ARCHIVE_MAX_ATTEMPTS")); attemptsStr != "" {
+		attempts, err := strconv.Atoi(attemptsStr)
+		if err != nil || attempts < 1 || attempts > 10 {
+			return MediaArchiveConfig{}, fmt.Errorf("invalid MEDIA_ARCHIVE_MAX_ATTEMPTS: %s (expected 1-10)", attemptsStr)
+		}
+		cfg.MaxAttempts = attempts
+	}
+
+	return cfg, nil
+}
+
+// parseRateLimitOverrides 解析格式为 "/ping=3,crypto=10" 的字符串
+func parseRateLimitOverrides(input string) (map[string]int, error) {
+	pairs := strings.Split(input, ",")
+	result := make(map[string]int, len(pairs))
+
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		command, limitStr, found := strings.Cut(pair, "=")
+		command = strings.TrimSpace(command)
+		limitStr = strings.TrimSpace(limitStr)
+		if !found || command == "" || limitStr == "" {
+			return nil, fmt.Errorf("invalid COMMAND_RATE_LIMIT_OVERRIDES entry: %s", pair)
+		}
+
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit in COMMAND_RATE_LIMIT_OVERRIDES: %s", pair)
+		}
+
+		result[command] = limit
+	}
+
+	return result, nil
+}
+
+// parseRetentionTierDays 解析格式为 "merchant=30,upstream=90" 的字符串，群等级为 basic/merchant/upstream，天数 1~365
+func parseRetentionTierDays(input string) (map[string]int, error) {
+	pairs := strings.Split(input, ",")
+	result := make(map[string]int, len(pairs))
+
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		tier, daysStr, found := strings.Cut(pair, "=")
+		tier = strings.ToLower(strings.TrimSpace(tier))
+		daysStr = strings.TrimSpace(daysStr)
+		if !found || daysStr == "" {
+			return nil, fmt.Errorf("invalid MESSAGE_RETENTION_TIER_DAYS entry: %s", pair)
+		}
+		if tier != "basic" && tier != "merchant" && tier != "upstream" {
+			return nil, fmt.Errorf("unknown tier in MESSAGE_RETENTION_TIER_DAYS: %s", pair)
+		}
+
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > 365 {
+			return nil, fmt.Errorf("invalid days in MESSAGE_RETENTION_TIER_DAYS (1-365): %s", pair)
+		}
+
+		result[tier] = days
+	}
+
+	return result, nil
+}
+
 // parseMerchantKeys 解析格式为 "1001:secret,1002:secret2" 的字符串
 func parseMerchantKeys(input string) (map[int64]string, error) {
 	pairs := strings.Split(input, ",")
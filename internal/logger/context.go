@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TraceIDField is the log field carrying the trace id of the current update.
+const TraceIDField = "trace_id"
+
+type traceIDKey struct{}
+
+// NewTraceID returns a short random id used to correlate all logs of one update.
+func NewTraceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// WithTraceID stores the trace id in ctx so that Ctx(ctx) can attach it to every log line.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceID returns the trace id stored in ctx, or an empty string.
+func TraceID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// Ctx returns a log entry carrying the fields stored in ctx (currently the trace id).
+// Without a trace id it behaves exactly like L().
+func Ctx(ctx context.Context) *log.Entry {
+	if traceID := TraceID(ctx); traceID != "" {
+		return L().WithField(TraceIDField, traceID)
+	}
+	return log.NewEntry(L())
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCtxAttachesTraceID(t *testing.T) {
+	var buf bytes.Buffer
+	original := L().Out
+	L().SetOutput(&buf)
+	defer L().SetOutput(original)
+
+	ctx := WithTraceID(context.Background(), "abc123")
+	if got := TraceID(ctx); got != "abc123" {
+		t.Fatalf("expected trace id abc123, got %q", got)
+	}
+
+	Ctx(ctx).Info("traced")
+	Ctx(context.Background()).Info("untraced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "trace_id=abc123") {
+		t.Fatalf("expected trace id in traced line, got %q", lines[0])
+	}
+	if strings.Contains(lines[1], TraceIDField) {
+		t.Fatalf("expected no trace id in untraced line, got %q", lines[1])
+	}
+}
+
+func TestNewTraceIDIsUnique(t *testing.T) {
+	first, second := NewTraceID(), NewTraceID()
+	if len(first) != 16 || first == second {
+		t.Fatalf("unexpected trace ids %q and %q", first, second)
+	}
+}
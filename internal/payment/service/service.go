@@ -12,6 +12,14 @@ import (
 	"go_bot/internal/payment/sifang"
 )
 
+// ErrOrderNotFound 表示上游明确返回订单不存在（404 业务错误或空详情）
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrInvalidRequest 表示请求在发出前即校验失败，上游未收到该请求
+var ErrInvalidRequest = errors.New("invalid request")
+
+const orderNotFoundCode = 404
+
 // Service 定义四方支付相关操作
 type Service interface {
 	GetBalance(ctx context.Context, merchantID int64, historyDays int) (*Balance, error)
@@ -25,6 +33,11 @@ type Service interface {
 	FindOrderChannelBinding(ctx context.Context, merchantID int64, orderNo string, numberType OrderNumberType) (*OrderChannelBinding, error)
 }
 
+// Pinger 可选接口：支持连通性检测的支付服务实现
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 type sifangService struct {
 	client *sifang.Client
 }
@@ -154,6 +167,14 @@ func NewSifangService(client *sifang.Client) Service {
 	return &sifangService{client: client}
 }
 
+// Ping 检测四方支付网关连通性
+func (s *sifangService) Ping(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("sifang client is not configured")
+	}
+	return s.client.Ping(ctx)
+}
+
 func (s *sifangService) GetBalance(ctx context.Context, merchantID int64, historyDays int) (*Balance, error) {
 	if merchantID == 0 {
 		return nil, fmt.Errorf("merchant id is required")
@@ -336,10 +357,10 @@ func (s *sifangService) GetWithdrawList(ctx context.Context, merchantID int64, s
 
 func (s *sifangService) SendMoney(ctx context.Context, merchantID int64, amount float64, opts SendMoneyOptions) (*SendMoneyResult, error) {
 	if merchantID == 0 {
-		return nil, fmt.Errorf("merchant id is required")
+		return nil, fmt.Errorf("%w: merchant id is required", ErrInvalidRequest)
 	}
 	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be positive")
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
 	}
 
 	formattedAmount := fmt.Sprintf("%.2f", amount)
@@ -410,9 +431,12 @@ func (s *sifangService) GetOrderDetail(ctx context.Context, merchantID int64, or
 			}
 
 			var apiErr *sifang.APIError
-			if errors.As(err, &apiErr) {
+			switch {
+			case errors.As(err, &apiErr) && apiErr.Code == orderNotFoundCode:
+				lastErr = fmt.Errorf("get order detail failed with sifang error (%s number): %w: %w", describeOrderNumberType(kind), ErrOrderNotFound, err)
+			case apiErr != nil:
 				lastErr = fmt.Errorf("get order detail failed with sifang error (%s number): %w", describeOrderNumberType(kind), err)
-			} else {
+			default:
 				lastErr = fmt.Errorf("get order detail failed (%s number): %w", describeOrderNumberType(kind), err)
 			}
 
@@ -425,7 +449,7 @@ func (s *sifangService) GetOrderDetail(ctx context.Context, merchantID int64, or
 
 		detail := decodeOrderDetail(raw)
 		if detail == nil || detail.Order == nil {
-			lastErr = fmt.Errorf("order detail is empty (%s number)", describeOrderNumberType(kind))
+			lastErr = fmt.Errorf("order detail is empty (%s number): %w", describeOrderNumberType(kind), ErrOrderNotFound)
 			if idx < len(lookupOrder)-1 {
 				continue
 			}
@@ -1156,7 +1180,8 @@ func buildPZIDSummaryItem(value interface{}) *SummaryByPZIDItem {
 		OrderCount: pickString(m,
 			"order_count", "order_num", "orders", "count", "total_orders", "success_count", "total_count"),
 		GrossAmount: pickString(m,
-			"gross_amount", "total_amount", "amount", "total_money", "sum_amount", "money", "order_amount", "success_amount"),
+			"gross_amount", "total_amount", "amount", "total_money", "sum_amount", "money", "order_amount", "success_amount",
+			"gross", "volume", "total_volume", "pay_amount", "paid_amount", "success_money", "trade_amount"),
 		MerchantIncome: pickString(m,
 			"merchant_income", "merchant_amount", "merchant_money", "merchant", "merchant_real", "merchant_real_amount", "real_amount"),
 		AgentIncome: pickString(m,
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -540,9 +541,36 @@ func TestSifangService_GetOrderDetail_NoData(t *testing.T) {
 	}
 
 	svc := NewSifangService(client)
-	if _, err := svc.GetOrderDetail(context.Background(), 1001, "MER-1", OrderNumberTypeMerchant); err == nil {
+	_, err = svc.GetOrderDetail(context.Background(), 1001, "MER-1", OrderNumberTypeMerchant)
+	if err == nil {
 		t.Fatalf("expected error for empty detail")
 	}
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestSifangService_GetOrderDetail_NotFoundCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"code":404,"message":"not found","data":null}`)
+	}))
+	defer ts.Close()
+
+	cfg := config.SifangConfig{
+		BaseURL:            ts.URL,
+		DefaultMerchantKey: "secret",
+		Timeout:            2 * time.Second,
+	}
+	client, err := sifang.NewClient(cfg, sifang.WithHTTPClient(ts.Client()), sifang.WithNowFunc(func() time.Time { return time.Unix(1700000000, 0) }))
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	svc := NewSifangService(client)
+	_, err = svc.GetOrderDetail(context.Background(), 1001, "MER-1", OrderNumberTypeAuto)
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
 }
 
 func TestSifangService_GetOrderDetail_APIError(t *testing.T) {
@@ -562,9 +590,13 @@ func TestSifangService_GetOrderDetail_APIError(t *testing.T) {
 	}
 
 	svc := NewSifangService(client)
-	if _, err := svc.GetOrderDetail(context.Background(), 1001, "MER-1", OrderNumberTypeMerchant); err == nil {
+	_, err = svc.GetOrderDetail(context.Background(), 1001, "MER-1", OrderNumberTypeMerchant)
+	if err == nil {
 		t.Fatalf("expected api error")
 	}
+	if errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("did not expect server error to be treated as not found: %v", err)
+	}
 }
 
 func TestSifangService_FindOrderChannelBinding_Success(t *testing.T) {
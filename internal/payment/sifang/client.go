@@ -124,7 +124,7 @@ func (c *Client) Post(ctx context.Context, action string, merchantID int64, busi
 		form.Set(k, v)
 	}
 
-	logger.L().Infof("Sifang request: action=%s merchant_id=%d params=%v", action, merchantID, sanitizeParamsForLog(params))
+	logger.Ctx(ctx).Infof("Sifang request: action=%s merchant_id=%d params=%v", action, merchantID, sanitizeParamsForLog(params))
 
 	endpoint := c.buildEndpoint(action)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
@@ -145,11 +145,11 @@ func (c *Client) Post(ctx context.Context, action string, merchantID int64, busi
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		logger.L().Warnf("Sifang response: action=%s merchant_id=%d status=%d body=%s", action, merchantID, resp.StatusCode, truncate(string(body), 512))
+		logger.Ctx(ctx).Warnf("Sifang response: action=%s merchant_id=%d status=%d body=%s", action, merchantID, resp.StatusCode, truncate(string(body), 512))
 		return fmt.Errorf("sifang http error: status=%d, body=%s", resp.StatusCode, truncate(string(body), 256))
 	}
 
-	logger.L().Infof("Sifang response: action=%s merchant_id=%d status=%d body=%s", action, merchantID, resp.StatusCode, truncate(string(body), 512))
+	logger.Ctx(ctx).Infof("Sifang response: action=%s merchant_id=%d status=%d body=%s", action, merchantID, resp.StatusCode, truncate(string(body), 512))
 
 	var envelope struct {
 		Code    int             `json:"code"`
@@ -174,6 +174,30 @@ func (c *Client) Post(ctx context.Context, action string, merchantID int64, busi
 	return nil
 }
 
+// Ping 检测四方支付网关是否可达，任何非 5xx 的 HTTP 响应均视为可达
+func (c *Client) Ping(ctx context.Context) error {
+	if c.baseURL == "" {
+		return fmt.Errorf("sifang baseURL is empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request sifang api failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("sifang http error: status=%d", resp.StatusCode)
+	}
+	return nil
+}
+
 func (c *Client) buildEndpoint(action string) string {
 	action = strings.Trim(action, "/")
 	return fmt.Sprintf("%s/%s", c.baseURL, action)
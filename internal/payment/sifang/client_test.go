@@ -145,3 +145,37 @@ func TestPostMissingMerchantKey(t *testing.T) {
 		t.Fatalf("expected error when merchant key missing")
 	}
 }
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "reachable", status: http.StatusOK},
+		{name: "client error still reachable", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusBadGateway, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(config.SifangConfig{BaseURL: server.URL, Timeout: 3 * time.Second})
+			if err != nil {
+				t.Fatalf("new client: %v", err)
+			}
+
+			err = client.Ping(context.Background())
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected error but got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
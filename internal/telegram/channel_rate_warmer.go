@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_bot/internal/logger"
+)
+
+const (
+	channelRateWarmInterval    = 15 * time.Minute
+	channelRateWarmListTimeout = 10 * time.Second
+)
+
+// channelRateWarmer 定时预热商户群的通道费率缓存，使「费率」命令尽量命中缓存
+type channelRateWarmer struct {
+	bot      *Bot
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	running  atomic.Bool
+}
+
+func newChannelRateWarmer(bot *Bot) *channelRateWarmer {
+	return &channelRateWarmer{
+		bot:      bot,
+		interval: channelRateWarmInterval,
+	}
+}
+
+func (w *channelRateWarmer) start() {
+	if w == nil || w.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run(ctx)
+	}()
+
+	w.running.Store(true)
+	logger.L().Info("Channel rate warmer started")
+}
+
+// isRunning 预热任务是否处于运行状态
+func (w *channelRateWarmer) isRunning() bool {
+	return w != nil && w.running.Load()
+}
+
+func (w *channelRateWarmer) stop() {
+	if w == nil || w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+	w.cancel = nil
+	w.running.Store(false)
+	logger.L().Info("Channel rate warmer stopped")
+}
+
+func (w *channelRateWarmer) run(ctx context.Context) {
+	w.warm(ctx)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.warm(ctx)
+		}
+	}
+}
+
+// warm 收集启用四方支付的商户群绑定的商户号并预热费率缓存
+func (w *channelRateWarmer) warm(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, channelRateWarmListTimeout)
+	groups, err := w.bot.groupService.ListActiveGroups(listCtx)
+	cancel()
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Channel rate warmer failed to list groups: %v", err)
+		return
+	}
+
+	merchantIDs := make([]int64, 0, len(groups))
+	for _, group := range groups {
+		if !isEligibleMerchantGroup(group) {
+			continue
+		}
+		merchantIDs = append(merchantIDs, int64(group.Settings.MerchantID))
+	}
+	if len(merchantIDs) == 0 {
+		return
+	}
+
+	if warmed := w.bot.sifangFeature.WarmChannelRates(ctx, merchantIDs); warmed > 0 {
+		logger.Ctx(ctx).Infof("Channel rate warmer refreshed %d merchants", warmed)
+	}
+}
@@ -0,0 +1,238 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/features"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	commandAliasAddCommand    = "/alias"
+	commandAliasRemoveCommand = "/unalias"
+	commandAliasUsage         = "用法：/alias &lt;别名&gt; &lt;内置命令&gt; 添加别名（例如 /alias 查账 查询记账），/unalias &lt;别名&gt; 删除别名\n不带参数时查看本群别名"
+)
+
+// builtinTextCommand 已注册的内置文本命令，prefix 为 true 时按前缀匹配（与 registerHandlers 中的 MatchType 一致）
+type builtinTextCommand struct {
+	name   string
+	prefix bool
+}
+
+// builtinTextCommands 内置文本命令表，用于别名冲突检测与目标命令校验；在 registerHandlers 新增文本命令时需同步维护
+var builtinTextCommands = []builtinTextCommand{
+	{name: "/start", prefix: true},
+	{name: "/ping"},
+	{name: "/lang", prefix: true},
+	{name: "/help"},
+	{name: "/grant", prefix: true},
+	{name: "/revoke", prefix: true},
+	{name: "/perm", prefix: true},
+	{name: "/validate"},
+	{name: "/repair"},
+	{name: "/note", prefix: true},
+	{name: "/tag", prefix: true},
+	{name: "/health"},
+	{name: "/uptime"},
+	{name: "/command_stats", prefix: true},
+	{name: "/broadcast", prefix: true},
+	{name: "/balances", prefix: true},
+	{name: "/resend_bills"},
+	{name: "/purge_users", prefix: true},
+	{name: exportConfigsCommand},
+	{name: importConfigsCommand, prefix: true},
+	{name: "/余额", prefix: true},
+	{name: "/set_min_balance", prefix: true},
+	{name: "/set_balance_alert_limit", prefix: true},
+	{name: balanceConfigCommand},
+	{name: "/日结"},
+	{name: "/admins", prefix: true},
+	{name: "/userinfo", prefix: true},
+	{name: "/add_group_admin", prefix: true},
+	{name: "/del_group_admin", prefix: true},
+	{name: "/group_admins"},
+	{name: "/leave"},
+	{name: "/configs"},
+	{name: "/features"},
+	{name: "/msgstats"},
+	{name: mentionsCommand, prefix: true},
+	{name: "/deleted"},
+	{name: "/edits", prefix: true},
+	{name: "/members", prefix: true},
+	{name: "/groupstats", prefix: true},
+	{name: groupStatusCommand},
+	{name: sifangAutoLookupEnableCommand},
+	{name: sifangAutoLookupDisableCommand},
+	{name: scheduleAddCommand, prefix: true},
+	{name: "/schedules"},
+	{name: scheduleDeleteCommand, prefix: true},
+	{name: commandAliasAddCommand, prefix: true},
+	{name: commandAliasRemoveCommand, prefix: true},
+	{name: accountingQueryCommand, prefix: true},
+	{name: "删除记账记录"},
+	{name: accountingClearCommand, prefix: true},
+	{name: "撤回"},
+}
+
+// matchBuiltinCommand 判断文本是否会被内置命令或功能插件处理，返回命中的命令/功能名称
+func matchBuiltinCommand(ctx context.Context, featureManager *features.Manager, msg *botModels.Message, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+	lower := strings.ToLower(text)
+	for _, cmd := range builtinTextCommands {
+		if lower == cmd.name || (cmd.prefix && strings.HasPrefix(lower, cmd.name)) {
+			return cmd.name, true
+		}
+	}
+	if isAccountingDateDeleteCommand(text) {
+		return accountingDateDeleteCommand, true
+	}
+	if _, ok := parseMessageSearchKeyword(text); ok {
+		return messageSearchCommand, true
+	}
+	if featureManager == nil || msg == nil {
+		return "", false
+	}
+
+	probe := *msg
+	probe.Text = text
+	probe.Entities = nil
+	var matched string
+	featureManager.ForEach(func(feature features.Feature) bool {
+		if feature.Match(ctx, &probe) {
+			matched = feature.Name()
+			return false
+		}
+		return true
+	})
+	return matched, matched != ""
+}
+
+// handleCommandAlias 处理 /alias 命令（Admin+）：添加本群命令别名，不带参数时列出现有别名
+func (b *Bot) handleCommandAlias(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	group, ok := b.loadLabelGroup(ctx, msg)
+	if !ok {
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 1 {
+		b.sendMessage(ctx, msg.Chat.ID, formatCommandAliases(group.Settings.CommandAliases)+"\n\n"+commandAliasUsage, msg.ID)
+		return
+	}
+	if len(fields) < 3 {
+		b.sendErrorMessage(ctx, msg.Chat.ID, commandAliasUsage, msg.ID)
+		return
+	}
+
+	alias, err := models.NormalizeCommandAlias(fields[1])
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+	command := strings.Join(fields[2:], " ")
+
+	// 别名不得被内置命令或功能插件抢先处理，否则别名永远不会生效
+	if name, conflict := matchBuiltinCommand(ctx, b.featureManager, msg, alias); conflict {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTMLf("别名「%s」与已有命令「%s」冲突，请换一个", alias, name), msg.ID)
+		return
+	}
+	if _, ok := matchBuiltinCommand(ctx, b.featureManager, msg, command); !ok {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTMLf("「%s」不是可识别的内置命令", command), msg.ID)
+		return
+	}
+
+	if err := b.groupService.AddCommandAlias(ctx, group.TelegramID, alias, command); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	logger.Ctx(ctx).Infof("Command alias updated: chat_id=%d alias=%s command=%s operator=%d", msg.Chat.ID, alias, command, msg.From.ID)
+	b.sendSuccessMessage(ctx, msg.Chat.ID, safeHTMLf("已添加别名：%s → %s", alias, command), msg.ID)
+}
+
+// handleRemoveCommandAlias 处理 /unalias 命令（Admin+）：删除本群命令别名
+func (b *Bot) handleRemoveCommandAlias(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	group, ok := b.loadLabelGroup(ctx, msg)
+	if !ok {
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) != 2 {
+		b.sendErrorMessage(ctx, msg.Chat.ID, commandAliasUsage, msg.ID)
+		return
+	}
+
+	if err := b.groupService.RemoveCommandAlias(ctx, group.TelegramID, fields[1]); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	logger.Ctx(ctx).Infof("Command alias removed: chat_id=%d alias=%s operator=%d", msg.Chat.ID, fields[1], msg.From.ID)
+	b.sendSuccessMessage(ctx, msg.Chat.ID, safeHTMLf("已删除别名：%s", strings.ToLower(fields[1])), msg.ID)
+}
+
+// handleUnknownCommand 未被任何内置命令匹配的 / 开头消息，仅尝试按本群别名转发
+func (b *Bot) handleUnknownCommand(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	b.dispatchCommandAlias(ctx, botInstance, update)
+}
+
+// dispatchCommandAlias 按本群别名表改写消息首词并重新分发给内置命令，命中别名时返回 true
+func (b *Bot) dispatchCommandAlias(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) bool {
+	msg := update.Message
+	if msg == nil || msg.From == nil || msg.From.IsBot || !models.IsGroupChatID(msg.Chat.ID) || botInstance == nil {
+		return false
+	}
+	// 首词超过别名长度上限时不可能命中，避免每条长消息都查询群组配置
+	first := strings.TrimSpace(msg.Text)
+	if idx := strings.IndexFunc(first, unicode.IsSpace); idx >= 0 {
+		first = first[:idx]
+	}
+	if first == "" || utf8.RuneCountInString(first) > models.MaxCommandAliasLength {
+		return false
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil || group == nil {
+		return false
+	}
+	text, ok := group.Settings.ResolveCommandAlias(msg.Text)
+	if !ok {
+		return false
+	}
+
+	rewritten := *msg
+	rewritten.Text = text
+	rewritten.Entities = nil
+	logger.Ctx(ctx).Infof("Command alias resolved: chat_id=%d user_id=%d alias=%s command=%q", msg.Chat.ID, msg.From.ID, first, text)
+	botInstance.ProcessUpdate(ctx, &botModels.Update{ID: update.ID, Message: &rewritten})
+	return true
+}
+
+// formatCommandAliases 展示本群命令别名列表
+func formatCommandAliases(aliases []models.CommandAlias) string {
+	var text strings.Builder
+	text.WriteString("🔤 <b>本群命令别名</b>")
+	if len(aliases) == 0 {
+		text.WriteString("\n（未设置）")
+		return text.String()
+	}
+	for _, item := range aliases {
+		text.WriteString(fmt.Sprintf("\n%s → %s", html.EscapeString(item.Alias), html.EscapeString(item.Command)))
+	}
+	return text.String()
+}
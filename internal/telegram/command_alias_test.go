@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/features"
+	"go_bot/internal/telegram/features/calculator"
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestMatchBuiltinCommand(t *testing.T) {
+	manager := features.NewManager(nil)
+	manager.Register(calculator.New())
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -100, Type: "supergroup"}}
+
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{text: "查询记账", want: "查询记账", ok: true},
+		{text: "/余额 2024-01-05", want: "/余额", ok: true},
+		{text: "/TAGS", want: "/tag", ok: true},
+		{text: "删除记账 2024-01-05", want: accountingDateDeleteCommand, ok: true},
+		{text: "搜索消息 转账", want: messageSearchCommand, ok: true},
+		{text: "1+1", want: "calculator", ok: true},
+		{text: "查账", ok: false},
+		{text: "/ye", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := matchBuiltinCommand(context.Background(), manager, msg, tt.text)
+		if ok != tt.ok || got != tt.want {
+			t.Fatalf("matchBuiltinCommand(%q) = %q, %t; want %q, %t", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatCommandAliases(t *testing.T) {
+	if text := formatCommandAliases(nil); !strings.Contains(text, "（未设置）") {
+		t.Fatalf("expected empty placeholder, got %q", text)
+	}
+	text := formatCommandAliases([]models.CommandAlias{{Alias: "查账", Command: "查询记账"}, {Alias: "<x>", Command: "/余额"}})
+	for _, want := range []string{"查账 → 查询记账", "&lt;x&gt; → /余额"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected output to contain %q, got %q", want, text)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+)
+
+// commandRateKey 限流维度：用户 + 命令
+type commandRateKey struct {
+	userID  int64
+	command string
+}
+
+// commandRateWindow 单个用户单个命令在窗口内的调用记录
+type commandRateWindow struct {
+	hits     []time.Time // 窗口内的放行时间（升序）
+	notified bool        // 本轮超限是否已提示过，避免刷屏时 Bot 也跟着刷屏
+}
+
+// commandRateLimiter 按用户 + 命令的滑动窗口限流器，并发安全，仅保存在内存
+type commandRateLimiter struct {
+	mu           sync.Mutex
+	window       time.Duration
+	defaultLimit int
+	limits       map[string]int
+	entries      map[commandRateKey]*commandRateWindow
+	lastSweep    time.Time
+	now          func() time.Time
+}
+
+// commandRateDecision 限流判定结果
+type commandRateDecision struct {
+	Allowed    bool
+	Notify     bool          // 被拒绝且本轮首次超限，需要提示用户
+	RetryAfter time.Duration // 被拒绝时距离最早一次调用滑出窗口的时间
+}
+
+// newCommandRateLimiter 创建限流器，defaultLimit 为未单独配置命令的阈值，0 表示不限流
+func newCommandRateLimiter(window time.Duration, defaultLimit int, overrides map[string]int) *commandRateLimiter {
+	limits := make(map[string]int, len(overrides))
+	for command, limit := range overrides {
+		limits[command] = limit
+	}
+	return &commandRateLimiter{
+		window:       window,
+		defaultLimit: defaultLimit,
+		limits:       limits,
+		entries:      make(map[commandRateKey]*commandRateWindow),
+		now:          time.Now,
+	}
+}
+
+// limitFor 返回命令的阈值，未单独配置时使用默认值
+func (l *commandRateLimiter) limitFor(command string) int {
+	if limit, ok := l.limits[command]; ok {
+		return limit
+	}
+	return l.defaultLimit
+}
+
+// hasOverride 命令是否单独配置了阈值
+func (l *commandRateLimiter) hasOverride(command string) bool {
+	if l == nil {
+		return false
+	}
+	_, ok := l.limits[command]
+	return ok
+}
+
+// allow 判定本次调用是否放行，放行时记录调用时间
+func (l *commandRateLimiter) allow(userID int64, command string) commandRateDecision {
+	if l == nil || l.window <= 0 {
+		return commandRateDecision{Allowed: true}
+	}
+	limit := l.limitFor(command)
+	if limit <= 0 {
+		return commandRateDecision{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweepLocked(now)
+
+	key := commandRateKey{userID: userID, command: command}
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &commandRateWindow{}
+		l.entries[key] = entry
+	}
+	entry.prune(now.Add(-l.window))
+
+	if len(entry.hits) < limit {
+		entry.hits = append(entry.hits, now)
+		entry.notified = false
+		return commandRateDecision{Allowed: true}
+	}
+
+	decision := commandRateDecision{
+		Notify:     !entry.notified,
+		RetryAfter: entry.hits[0].Add(l.window).Sub(now),
+	}
+	entry.notified = true
+	return decision
+}
+
+// sweepLocked 每个窗口周期清理一次已无调用记录的用户，防止内存无限增长
+func (l *commandRateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	cutoff := now.Add(-l.window)
+	for key, entry := range l.entries {
+		entry.prune(cutoff)
+		if len(entry.hits) == 0 {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// size 返回当前跟踪的用户 + 命令数量
+func (l *commandRateLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// prune 移除 cutoff（含）之前的调用记录
+func (w *commandRateWindow) prune(cutoff time.Time) {
+	idx := 0
+	for idx < len(w.hits) && !w.hits[idx].After(cutoff) {
+		idx++
+	}
+	if idx > 0 {
+		w.hits = append(w.hits[:0], w.hits[idx:]...)
+	}
+}
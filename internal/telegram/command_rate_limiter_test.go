@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestCommandRateLimiterSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	limiter := newCommandRateLimiter(10*time.Second, 2, map[string]int{"/ping": 1, "/health": 0})
+	limiter.now = func() time.Time { return now }
+
+	if !limiter.allow(1, "/help").Allowed || !limiter.allow(1, "/help").Allowed {
+		t.Fatalf("expected first two calls within limit to pass")
+	}
+
+	now = now.Add(4 * time.Second)
+	rejected := limiter.allow(1, "/help")
+	if rejected.Allowed || !rejected.Notify {
+		t.Fatalf("expected third call to be rejected with notice, got %+v", rejected)
+	}
+	if rejected.RetryAfter != 6*time.Second {
+		t.Fatalf("expected retry after 6s, got %s", rejected.RetryAfter)
+	}
+	if again := limiter.allow(1, "/help"); again.Allowed || again.Notify {
+		t.Fatalf("expected repeated rejection to be silent, got %+v", again)
+	}
+
+	if !limiter.allow(2, "/help").Allowed {
+		t.Fatalf("expected other users to be unaffected")
+	}
+	if !limiter.allow(1, "/configs").Allowed {
+		t.Fatalf("expected other commands to be unaffected")
+	}
+
+	now = now.Add(6 * time.Second)
+	if !limiter.allow(1, "/help").Allowed {
+		t.Fatalf("expected call to pass once the oldest hit leaves the window")
+	}
+
+	if !limiter.allow(1, "/ping").Allowed || limiter.allow(1, "/ping").Allowed {
+		t.Fatalf("expected per-command override of 1 to apply")
+	}
+	for i := 0; i < 10; i++ {
+		if !limiter.allow(1, "/health").Allowed {
+			t.Fatalf("expected override of 0 to disable limiting")
+		}
+	}
+}
+
+func TestCommandRateLimiterSweepsIdleEntries(t *testing.T) {
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	limiter := newCommandRateLimiter(time.Second, 1, nil)
+	limiter.now = func() time.Time { return now }
+
+	for userID := int64(1); userID <= 5; userID++ {
+		limiter.allow(userID, "/ping")
+	}
+	if limiter.size() != 5 {
+		t.Fatalf("expected 5 tracked entries, got %d", limiter.size())
+	}
+
+	now = now.Add(2 * time.Second)
+	limiter.allow(99, "/ping")
+	if limiter.size() != 1 {
+		t.Fatalf("expected idle entries to be swept, got %d", limiter.size())
+	}
+}
+
+func TestCommandRateLimiterDisabledAndConcurrent(t *testing.T) {
+	var nilLimiter *commandRateLimiter
+	if !nilLimiter.allow(1, "/ping").Allowed {
+		t.Fatalf("expected nil limiter to allow everything")
+	}
+	if !newCommandRateLimiter(time.Minute, 0, nil).allow(1, "/ping").Allowed {
+		t.Fatalf("expected zero default limit to disable limiting")
+	}
+
+	limiter := newCommandRateLimiter(time.Minute, 10, nil)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.allow(1, "/ping").Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 10 {
+		t.Fatalf("expected exactly 10 concurrent calls to pass, got %d", allowed)
+	}
+}
+
+func TestGuardFeatureOnlyLimitsPriceAndOverriddenFeatures(t *testing.T) {
+	b := &Bot{commandLimiter: newCommandRateLimiter(time.Minute, 1, map[string]int{"upstream_balance": 2})}
+	msg := &botModels.Message{ID: 1, Chat: botModels.Chat{ID: -100}, From: &botModels.User{ID: 7}}
+
+	// 四方下发、上游日结等业务功能不受默认阈值约束
+	for i := 0; i < 5; i++ {
+		if !b.guardFeature(context.Background(), msg, "sifang_payment") {
+			t.Fatalf("expected sifang command %d not to be throttled", i+1)
+		}
+		if !b.guardFeature(context.Background(), msg, "upstream") {
+			t.Fatalf("expected upstream command %d not to be throttled", i+1)
+		}
+	}
+
+	if !b.guardFeature(context.Background(), msg, "crypto") || !b.guardFeature(context.Background(), msg, "upstream_balance") {
+		t.Fatal("expected first calls within limit to pass")
+	}
+	for _, feature := range []string{"crypto", "upstream_balance"} {
+		if _, ok := b.commandLimiter.entries[commandRateKey{userID: 7, command: feature}]; !ok {
+			t.Fatalf("expected %s to be rate limited", feature)
+		}
+	}
+	for _, feature := range []string{"sifang_payment", "upstream"} {
+		if _, ok := b.commandLimiter.entries[commandRateKey{userID: 7, command: feature}]; ok {
+			t.Fatalf("expected %s to bypass the limiter", feature)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"context"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/i18n"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// commandScope 命令允许执行的聊天类型
+type commandScope int
+
+const (
+	commandScopeAny     commandScope = iota // 群聊与私聊均可
+	commandScopeGroup                       // 仅群组（group / supergroup）
+	commandScopePrivate                     // 仅私聊
+)
+
+// String 返回作用域名称，用于日志
+func (s commandScope) String() string {
+	switch s {
+	case commandScopeGroup:
+		return "group"
+	case commandScopePrivate:
+		return "private"
+	default:
+		return "any"
+	}
+}
+
+// allows 判断作用域是否允许在该类型的聊天中执行
+func (s commandScope) allows(chatType botModels.ChatType) bool {
+	switch s {
+	case commandScopeGroup:
+		return isGroupChatType(chatType)
+	case commandScopePrivate:
+		return chatType == botModels.ChatTypePrivate
+	default:
+		return true
+	}
+}
+
+// noticeKey 不允许执行时的提示文案
+func (s commandScope) noticeKey() string {
+	if s == commandScopePrivate {
+		return "common.private_only"
+	}
+	return "common.group_only"
+}
+
+// isGroupChatType 是否为群组（普通群或超级群）
+func isGroupChatType(chatType botModels.ChatType) bool {
+	return chatType == botModels.ChatTypeGroup || chatType == botModels.ChatTypeSupergroup
+}
+
+// RequireChatScope 中间件：在注册时声明命令的聊天作用域，不匹配时回复提示且不进入 handler
+// 需包在权限中间件外层，避免在不适用的聊天中先查询权限
+func (b *Bot) RequireChatScope(scope commandScope, next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		msg := update.Message
+		if msg == nil || scope.allows(msg.Chat.Type) {
+			next(ctx, botInstance, update)
+			return
+		}
+
+		var userID int64
+		if msg.From != nil {
+			userID = msg.From.ID
+		}
+		logger.Ctx(ctx).Infof("Command blocked due to chat scope: chat_id=%d chat_type=%s user_id=%d scope=%s text=%q",
+			msg.Chat.ID, msg.Chat.Type, userID, scope, truncateForDisplay(msg.Text, permissionCommandMaxRunes))
+		b.sendErrorMessage(ctx, msg.Chat.ID, i18n.T(b.messageLang(ctx, msg), scope.noticeKey()), msg.ID)
+	}
+}
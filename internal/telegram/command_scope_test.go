@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestCommandScopeAllows(t *testing.T) {
+	cases := []struct {
+		scope    commandScope
+		chatType botModels.ChatType
+		want     bool
+	}{
+		{commandScopeAny, botModels.ChatTypePrivate, true},
+		{commandScopeAny, botModels.ChatTypeChannel, true},
+		{commandScopeGroup, botModels.ChatTypeGroup, true},
+		{commandScopeGroup, botModels.ChatTypeSupergroup, true},
+		{commandScopeGroup, botModels.ChatTypePrivate, false},
+		{commandScopeGroup, botModels.ChatTypeChannel, false},
+		{commandScopePrivate, botModels.ChatTypePrivate, true},
+		{commandScopePrivate, botModels.ChatTypeSupergroup, false},
+	}
+	for _, tc := range cases {
+		if got := tc.scope.allows(tc.chatType); got != tc.want {
+			t.Fatalf("%s scope in %s chat: expected %v, got %v", tc.scope, tc.chatType, tc.want, got)
+		}
+	}
+}
+
+func TestRequireChatScopePassesMatchingUpdates(t *testing.T) {
+	b := &Bot{}
+	called := 0
+	handler := b.RequireChatScope(commandScopeGroup, func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		called++
+	})
+
+	handler(context.Background(), nil, &botModels.Update{Message: &botModels.Message{
+		Chat: botModels.Chat{ID: -100, Type: botModels.ChatTypeSupergroup},
+		Text: "/configs",
+	}})
+	// 非消息类 update 不做作用域判断，交给内层 handler 自行处理
+	handler(context.Background(), nil, &botModels.Update{CallbackQuery: &botModels.CallbackQuery{ID: "q1"}})
+	if called != 2 {
+		t.Fatalf("expected both updates to pass, called=%d", called)
+	}
+}
@@ -2,8 +2,11 @@ package telegram
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 
+	"go_bot/internal/telegram/i18n"
 	"go_bot/internal/telegram/models"
 )
 
@@ -145,6 +148,36 @@ func (b *Bot) getConfigItems() []models.ConfigItem {
 			RequireAdmin: true,
 		},
 
+		// 记账金额异常提醒倍数（单笔超过近期均值 N 倍时软提醒，仍然记录）
+		{
+			ID:       "accounting_anomaly_ratio",
+			Name:     "记账异常提醒",
+			Icon:     "⚠️",
+			Type:     models.ConfigTypeInput,
+			Category: "功能管理",
+			InputGetter: func(g *models.Group) string {
+				switch {
+				case g.Settings.AccountingAnomalyRatio < 0:
+					return "关闭"
+				case g.Settings.AccountingAnomalyRatio == 0:
+					return fmt.Sprintf("默认（%d 倍）", models.DefaultAccountingAnomalyRatio)
+				default:
+					return fmt.Sprintf("%d 倍", g.Settings.AccountingAnomalyRatio)
+				}
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				ratio, _ := models.ParseAccountingAnomalyRatio(val)
+				s.AccountingAnomalyRatio = ratio
+			},
+			InputPrompt: fmt.Sprintf("⚠️ 请输入记账金额异常提醒倍数（%d~%d）\n\n单笔金额超过本群同币种近 %d 笔均值的该倍数时提醒核对（仍会记录），输入 0 表示默认 %d 倍，输入「关闭」不提醒",
+				models.MinAccountingAnomalyRatio, models.MaxAccountingAnomalyRatio, models.AccountingAnomalySampleSize, models.DefaultAccountingAnomalyRatio),
+			InputValidator: func(text string) error {
+				_, err := models.ParseAccountingAnomalyRatio(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
 		// 四方支付功能开关
 		{
 			ID:       "sifang_enabled",
@@ -189,6 +222,87 @@ func (b *Bot) getConfigItems() []models.ConfigItem {
 			RequireAdmin: true,
 		},
 
+		// 四方下发每日限额
+		{
+			ID:       "send_money_daily_limit",
+			Name:     "每日下发限额",
+			Icon:     "💸",
+			Type:     models.ConfigTypeInput,
+			Category: "功能管理",
+			AllowedTiers: []models.GroupTier{
+				models.GroupTierMerchant,
+			},
+			InputGetter: func(g *models.Group) string {
+				if g.Settings.SendMoneyDailyLimit <= 0 {
+					return "不限"
+				}
+				return strconv.FormatFloat(g.Settings.SendMoneyDailyLimit, 'f', -1, 64)
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				limit, _ := parseSendMoneyAmountSetting(val)
+				s.SendMoneyDailyLimit = limit
+			},
+			InputPrompt: "💸 请输入每日下发限额（元）\n\n输入 0 表示不限制",
+			InputValidator: func(text string) error {
+				_, err := parseSendMoneyAmountSetting(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 四方大额下发复核阈值
+		{
+			ID:       "send_money_review_threshold",
+			Name:     "大额下发复核",
+			Icon:     "👥",
+			Type:     models.ConfigTypeInput,
+			Category: "功能管理",
+			AllowedTiers: []models.GroupTier{
+				models.GroupTierMerchant,
+			},
+			InputGetter: func(g *models.Group) string {
+				if g.Settings.SendMoneyReviewThreshold <= 0 {
+					return "关闭"
+				}
+				return strconv.FormatFloat(g.Settings.SendMoneyReviewThreshold, 'f', -1, 64)
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				threshold, _ := parseSendMoneyAmountSetting(val)
+				s.SendMoneyReviewThreshold = threshold
+			},
+			InputPrompt: "👥 请输入大额下发复核阈值（元）\n\n超过该金额的下发需两位管理员分别确认，输入 0 表示关闭",
+			InputValidator: func(text string) error {
+				_, err := parseSendMoneyAmountSetting(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 四方下发联动记账开关
+		{
+			ID:       "send_money_accounting_link",
+			Name:     "下发联动记账",
+			Icon:     "📒",
+			Type:     models.ConfigTypeToggle,
+			Category: "功能管理",
+			AllowedTiers: []models.GroupTier{
+				models.GroupTierMerchant,
+			},
+			ToggleGetter: func(g *models.Group) bool {
+				return g.Settings.SendMoneyAccountingLink
+			},
+			ToggleSetter: func(s *models.GroupSettings, val bool) {
+				s.SendMoneyAccountingLink = val
+			},
+			ToggleDisabled: func(g *models.Group) (bool, string) {
+				if !g.Settings.AccountingEnabled {
+					return true, "需先开启收支记账"
+				}
+				return false, ""
+			},
+			RequireAdmin: true,
+		},
+
 		// 订单联动转发开关（仅上游群）
 		{
 			ID:       "cascade_forward_enabled",
@@ -229,6 +343,265 @@ func (b *Bot) getConfigItems() []models.ConfigItem {
 			RequireAdmin: true,
 		},
 
+		// 入群验证开关
+		{
+			ID:       "join_verify_enabled",
+			Name:     "入群验证",
+			Icon:     "🛡",
+			Type:     models.ConfigTypeToggle,
+			Category: "群组管理",
+			ToggleGetter: func(g *models.Group) bool {
+				return g.Settings.JoinVerifyEnabled
+			},
+			ToggleSetter: func(s *models.GroupSettings, val bool) {
+				s.JoinVerifyEnabled = val
+			},
+			RequireAdmin: true,
+		},
+
+		// 入群验证超时
+		{
+			ID:       "join_verify_timeout",
+			Name:     "入群验证超时",
+			Icon:     "⏳",
+			Type:     models.ConfigTypeSelect,
+			Category: "群组管理",
+			SelectGetter: func(g *models.Group) string {
+				return strconv.Itoa(int(models.JoinVerifyTimeoutDuration(g.Settings).Seconds()))
+			},
+			SelectOptions: []models.SelectOption{
+				{Value: "60", Label: "1分钟", Icon: "1️⃣"},
+				{Value: "120", Label: "2分钟", Icon: "2️⃣"},
+				{Value: "300", Label: "5分钟", Icon: "5️⃣"},
+				{Value: "600", Label: "10分钟", Icon: "🔟"},
+			},
+			SelectSetter: func(s *models.GroupSettings, val string) {
+				seconds, _ := strconv.Atoi(val)
+				s.JoinVerifyTimeout = seconds
+			},
+			RequireAdmin: true,
+		},
+
+		// 大文件告警阈值
+		{
+			ID:       "media_size_limit",
+			Name:     "大文件告警",
+			Icon:     "📦",
+			Type:     models.ConfigTypeInput,
+			Category: "群组管理",
+			InputGetter: func(g *models.Group) string {
+				if g.Settings.MediaSizeLimitMB <= 0 {
+					return "关闭"
+				}
+				return fmt.Sprintf("%d MB", g.Settings.MediaSizeLimitMB)
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				limit, _ := models.ParseMediaSizeLimit(val)
+				s.MediaSizeLimitMB = limit
+			},
+			InputPrompt: "📦 请输入大文件告警阈值（MB）\n\n超过该大小的文件会被记录告警，输入 0 表示关闭",
+			InputValidator: func(text string) error {
+				_, err := models.ParseMediaSizeLimit(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 可疑文件类型黑名单
+		{
+			ID:       "media_blocked_types",
+			Name:     "可疑类型告警",
+			Icon:     "🚫",
+			Type:     models.ConfigTypeInput,
+			Category: "群组管理",
+			InputGetter: func(g *models.Group) string {
+				if len(g.Settings.MediaBlockedTypes) == 0 {
+					return "未设置"
+				}
+				return strings.Join(g.Settings.MediaBlockedTypes, ", ")
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				if strings.TrimSpace(val) == "0" {
+					s.MediaBlockedTypes = nil
+					return
+				}
+				types, _ := models.NormalizeMediaBlockedTypes(val)
+				s.MediaBlockedTypes = types
+			},
+			InputPrompt: "🚫 请输入可疑文件类型，用逗号或空格分隔\n\n扩展名如 .exe .apk .bat，MIME 如 application/x-msdownload、application/*\n输入 0 表示清空",
+			InputValidator: func(text string) error {
+				if strings.TrimSpace(text) == "0" {
+					return nil
+				}
+				types, err := models.NormalizeMediaBlockedTypes(text)
+				if err == nil && len(types) == 0 {
+					return fmt.Errorf("请至少输入一个扩展名或 MIME 类型")
+				}
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 媒体告警群内提醒
+		{
+			ID:       "media_alert_notify",
+			Name:     "媒体告警提醒",
+			Icon:     "📣",
+			Type:     models.ConfigTypeToggle,
+			Category: "群组管理",
+			ToggleGetter: func(g *models.Group) bool {
+				return g.Settings.MediaAlertNotify
+			},
+			ToggleSetter: func(s *models.GroupSettings, val bool) {
+				s.MediaAlertNotify = val
+			},
+			ToggleDisabled: func(g *models.Group) (bool, string) {
+				if !models.HasMediaAlertRules(g.Settings) {
+					return true, "需先设置大文件阈值或可疑类型"
+				}
+				return false, ""
+			},
+			RequireAdmin: true,
+		},
+
+		// 媒体归档类型（需部署时配置 MEDIA_ARCHIVE_BACKEND）
+		{
+			ID:       "media_archive_types",
+			Name:     "媒体归档",
+			Icon:     "🗃",
+			Type:     models.ConfigTypeInput,
+			Category: "群组管理",
+			InputGetter: func(g *models.Group) string {
+				if len(g.Settings.MediaArchiveTypes) == 0 {
+					return "关闭"
+				}
+				value := strings.Join(g.Settings.MediaArchiveTypes, ", ")
+				if b.mediaArchiver == nil {
+					value += "（未配置存储，暂不生效）"
+				}
+				return value
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				if strings.TrimSpace(val) == "0" {
+					s.MediaArchiveTypes = nil
+					return
+				}
+				types, _ := models.NormalizeMediaArchiveTypes(val)
+				s.MediaArchiveTypes = types
+			},
+			InputPrompt: "🗃 请输入需要自动归档的媒体类型，用逗号或空格分隔\n\n可选：photo/图片、video/视频、document/文件、voice/语音、audio/音频、animation/动图，all 表示全部\n命中的文件会下载并转存到部署配置的存储，输入 0 表示关闭",
+			InputValidator: func(text string) error {
+				if strings.TrimSpace(text) == "0" {
+					return nil
+				}
+				types, err := models.NormalizeMediaArchiveTypes(text)
+				if err == nil && len(types) == 0 {
+					return fmt.Errorf("请至少输入一种媒体类型")
+				}
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 消息保留天数（0 跟随群等级默认值，修改后重算本群已有消息的过期时间）
+		{
+			ID:       "message_retention_days",
+			Name:     "消息保留",
+			Icon:     "🗄",
+			Type:     models.ConfigTypeInput,
+			Category: "群组管理",
+			InputGetter: func(g *models.Group) string {
+				if g.Settings.MessageRetentionDays <= 0 {
+					if days := b.messageRetention.TierDefaultDays(g.Tier); days > 0 {
+						return fmt.Sprintf("默认（%d 天）", days)
+					}
+					return "默认（不自动清理）"
+				}
+				return fmt.Sprintf("%d 天", g.Settings.MessageRetentionDays)
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				days, _ := models.ParseMessageRetentionDays(val)
+				s.MessageRetentionDays = days
+			},
+			InputPrompt: fmt.Sprintf("🗄 请输入本群消息保留天数（1~%d）\n\n过期消息会被自动删除，输入 0 表示跟随群等级默认值", models.MaxMessageRetentionDays),
+			InputValidator: func(text string) error {
+				_, err := models.ParseMessageRetentionDays(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 群组时区（影响日结、账单、记账的「当天」边界）
+		{
+			ID:       "timezone",
+			Name:     "群组时区",
+			Icon:     "🕒",
+			Type:     models.ConfigTypeInput,
+			Category: "基础设置",
+			InputGetter: func(g *models.Group) string {
+				if g.Settings.Timezone == "" {
+					return models.DefaultGroupTimezone
+				}
+				return g.Settings.Timezone
+			},
+			InputSetter: func(s *models.GroupSettings, val string) {
+				s.Timezone = strings.TrimSpace(val)
+			},
+			InputPrompt: "🕒 请输入 IANA 时区名称\n\n例如：Asia/Shanghai、Asia/Bangkok、Europe/London",
+			InputValidator: func(text string) error {
+				_, err := models.ParseTimezone(text)
+				return err
+			},
+			RequireAdmin: true,
+		},
+
+		// 群组语言（/start、/help、错误提示等已本地化的文案）
+		{
+			ID:       "language",
+			Name:     "群组语言",
+			Icon:     "🌐",
+			Type:     models.ConfigTypeSelect,
+			Category: "基础设置",
+			SelectGetter: func(g *models.Group) string {
+				if g.Settings.Language == "" {
+					return "auto"
+				}
+				return g.Settings.Language
+			},
+			SelectOptions: []models.SelectOption{
+				{Value: "auto", Label: "跟随用户", Icon: "👤"},
+				{Value: string(i18n.LangZH), Label: "中文", Icon: "🇨🇳"},
+				{Value: string(i18n.LangEN), Label: "English", Icon: "🇬🇧"},
+			},
+			SelectSetter: func(s *models.GroupSettings, val string) {
+				if !i18n.IsSupported(val) {
+					val = ""
+				}
+				s.Language = val
+			},
+			RequireAdmin: true,
+		},
+
+		// 调度推送静默发送（日结、日账、余额告警不响铃）
+		{
+			ID:       "silent_scheduled_push",
+			Name:     "定时推送静默",
+			Icon:     "🔕",
+			Type:     models.ConfigTypeToggle,
+			Category: "基础设置",
+			AllowedTiers: []models.GroupTier{
+				models.GroupTierMerchant,
+				models.GroupTierUpstream,
+			},
+			ToggleGetter: func(g *models.Group) bool {
+				return g.Settings.SilentScheduledPush
+			},
+			ToggleSetter: func(s *models.GroupSettings, val bool) {
+				s.SilentScheduledPush = val
+			},
+			RequireAdmin: true,
+		},
+
 		// ========== 扩展示例（已注释）==========
 		//
 		// 需要更多配置？取消注释或添加新配置项即可：
@@ -283,3 +656,15 @@ func (b *Bot) getConfigItems() []models.ConfigItem {
 		// },
 	}
 }
+
+// parseSendMoneyAmountSetting 解析下发限额/复核阈值等金额输入，0 表示不启用
+func parseSendMoneyAmountSetting(text string) (float64, error) {
+	limit, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(text), ",", ""), 64)
+	if err != nil || math.IsNaN(limit) || math.IsInf(limit, 0) {
+		return 0, fmt.Errorf("请输入有效的金额")
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("金额不能为负数")
+	}
+	return math.Round(limit*100) / 100, nil
+}
@@ -6,26 +6,68 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
 )
 
+const (
+	// scheduleMaxWait 调度器单次最长等待时间
+	scheduleMaxWait = time.Hour
+	// scheduleDueWindow 群组本地零点后允许触发的时间窗口
+	scheduleDueWindow = 10 * time.Minute
+)
+
+const (
+	// dailyBillPushMaxAttempts 单次调度内单个群的最大推送尝试次数
+	dailyBillPushMaxAttempts = 3
+	// dailyBillPushRetryDelay 重试基础间隔，按尝试次数线性递增
+	dailyBillPushRetryDelay = 2 * time.Second
+	// dailyBillCatchUpDays 只补发最近 N 天内的账单，更早的视为过期
+	dailyBillCatchUpDays = 7
+	// dailyBillCatchUpBatch 单次补发的最大记录数
+	dailyBillCatchUpBatch = 200
+	// dailyBillPushTimeout 定时推送当天账单的总耗时上限
+	dailyBillPushTimeout = 5 * time.Minute
+	// dailyBillCatchUpTimeout 定时推送后补发历史账单的总耗时上限，与当天推送分开计算
+	dailyBillCatchUpTimeout = 5 * time.Minute
+	// dailyBillMarkTimeout 单次写入推送状态的超时
+	dailyBillMarkTimeout = 5 * time.Second
+)
+
+// errDailyBillEmpty 生成的账单消息为空
+var errDailyBillEmpty = errors.New("生成的消息为空")
+
 type dailySummaryScheduler struct {
 	bot      *Bot
 	cancel   context.CancelFunc
 	done     chan struct{}
 	location *time.Location
+	running  atomic.Bool
+
+	// repo 推送记录，nil 时不做防重复与补发
+	repo repository.DailyBillPushRepository
+	// push 生成并发送单个群的账单，测试时可替换
+	push       func(ctx context.Context, group *models.Group, target time.Time) error
+	retryDelay time.Duration
+	// catchUpMu 保证定时补发与手动补发不会并发执行
+	catchUpMu sync.Mutex
 }
 
 func newDailySummaryScheduler(bot *Bot) *dailySummaryScheduler {
-	return &dailySummaryScheduler{
-		bot:      bot,
-		location: mustLoadChinaLocation(),
+	s := &dailySummaryScheduler{
+		bot:        bot,
+		location:   mustLoadChinaLocation(),
+		repo:       bot.dailyBillPushRepo,
+		retryDelay: dailyBillPushRetryDelay,
 	}
+	s.push = s.pushSummary
+	return s
 }
 
 func (s *dailySummaryScheduler) start() {
@@ -40,10 +82,16 @@ func (s *dailySummaryScheduler) start() {
 	s.cancel = cancel
 	s.done = make(chan struct{})
 
+	s.running.Store(true)
 	go s.run(ctx)
 	logger.L().Info("Daily bill push scheduler started")
 }
 
+// isRunning 调度器是否处于运行状态
+func (s *dailySummaryScheduler) isRunning() bool {
+	return s != nil && s.running.Load()
+}
+
 func (s *dailySummaryScheduler) stop() {
 	if s == nil {
 		return
@@ -56,6 +104,7 @@ func (s *dailySummaryScheduler) stop() {
 	<-s.done
 	s.cancel = nil
 	s.done = nil
+	s.running.Store(false)
 	logger.L().Info("Daily bill push scheduler stopped")
 }
 
@@ -63,22 +112,30 @@ func (s *dailySummaryScheduler) run(ctx context.Context) {
 	defer close(s.done)
 
 	for {
-		now := time.Now().In(s.location)
-		next := nextDailyRun(now, s.location)
+		now := time.Now()
+		groups := s.bot.listScheduledGroups(ctx, filterEligibleMerchantGroups)
+		next := nextZonedDailyRun(now, s.location, groups)
 		wait := time.Until(next)
 		if wait <= 0 {
 			wait = time.Second
 		}
+		// 等待时间封顶，以便及时感知新设置的群组时区
+		capped := wait > scheduleMaxWait
+		if capped {
+			wait = scheduleMaxWait
+		}
 
 		timer := time.NewTimer(wait)
-		logger.L().Debugf("Daily bill push waiting %s until %s", wait.String(), next.Format(time.RFC3339))
+		logger.Ctx(ctx).Debugf("Daily bill push waiting %s until %s", wait.String(), next.Format(time.RFC3339))
 
 		select {
 		case <-ctx.Done():
 			timer.Stop()
 			return
 		case <-timer.C:
-			s.dispatch(ctx)
+			if !capped {
+				s.dispatch(ctx)
+			}
 		}
 	}
 }
@@ -89,10 +146,11 @@ func (s *dailySummaryScheduler) dispatch(parent context.Context) {
 	}
 
 	startTime := time.Now()
-	now := time.Now().In(s.location)
-	targetDate := previousBillingDate(now, s.location)
+	now := startTime
+	targetDate := models.PreviousBillingDate(now, s.location)
+	defaultDue := isLocationDueAt(s.location, now)
 
-	runCtx, cancel := context.WithTimeout(parent, 2*time.Minute)
+	runCtx, cancel := context.WithTimeout(parent, dailyBillPushTimeout)
 	defer cancel()
 
 	groups, err := s.bot.groupService.ListActiveGroups(runCtx)
@@ -104,20 +162,30 @@ func (s *dailySummaryScheduler) dispatch(parent context.Context) {
 		return
 	}
 
-	eligible := filterEligibleMerchantGroups(groups)
+	eligible := filterDueGroups(filterEligibleMerchantGroups(groups), now)
 	if len(eligible) == 0 {
+		if !defaultDue {
+			return
+		}
+		// 没有当天账单要推送时仍然补发历史失败的账单
+		catchUp := s.runCatchUp(parent)
 		logger.L().Infof("Daily bill push skipped: no eligible groups for %s", targetDate.Format("2006-01-02"))
 		duration := time.Since(startTime)
 		note := "无符合条件的群组，已跳过推送。"
+		if catchUp.total > 0 {
+			note += "\n" + catchUp.summary()
+		}
 		s.notifyOwners(parent, targetDate, 0, 0, 0, duration, note, nil)
 		return
 	}
 
+	targetDate = models.PreviousBillingDate(now, models.GroupLocation(eligible[0].Settings))
 	logger.L().Infof("Daily bill push started for %d groups, target_date=%s", len(eligible), targetDate.Format("2006-01-02"))
 
 	const workerLimit = 8
 
 	successCount := 0
+	skippedCount := 0
 	failureDetails := make([]string, 0)
 	aborted := false
 	var mu sync.Mutex
@@ -128,49 +196,33 @@ func (s *dailySummaryScheduler) dispatch(parent context.Context) {
 	for _, group := range eligible {
 		group := group
 		merchantID := int64(group.Settings.MerchantID)
+		groupTarget := models.PreviousBillingDate(now, models.GroupLocation(group.Settings))
 
 		groupRunner.Go(func() error {
 			if groupCtx.Err() != nil {
 				return groupCtx.Err()
 			}
 
-			ctxWithTimeout, cancelGroup := context.WithTimeout(groupCtx, 15*time.Second)
+			ctxWithTimeout, cancelGroup := context.WithTimeout(groupCtx, 45*time.Second)
 			defer cancelGroup()
 
-			message, err := s.bot.sifangFeature.BuildSummaryMessage(ctxWithTimeout, merchantID, targetDate)
+			skipped, err := s.deliver(ctxWithTimeout, group, groupTarget)
 			if err != nil {
-				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-					return err
+				// 仅整体任务被取消时中止其余群，单群超时按普通失败处理（已登记待补发）
+				if groupCtx.Err() != nil {
+					return groupCtx.Err()
 				}
-				logger.L().Errorf("Daily bill push failed: chat_id=%d, merchant_id=%d, err=%v", group.TelegramID, merchantID, err)
 				mu.Lock()
 				failureDetails = append(failureDetails, fmt.Sprintf("chat_id=%d, merchant_id=%d: %v", group.TelegramID, merchantID, err))
 				mu.Unlock()
 				return nil
 			}
 
-			if message == "" {
-				logger.L().Warnf("Daily bill push produced empty message: chat_id=%d", group.TelegramID)
-				mu.Lock()
-				failureDetails = append(failureDetails, fmt.Sprintf("chat_id=%d: 生成的消息为空", group.TelegramID))
-				mu.Unlock()
-				return nil
-			}
-
-			if _, sendErr := s.bot.sendMessageWithMarkupAndMessage(ctxWithTimeout, group.TelegramID, message, nil); sendErr != nil {
-				if errors.Is(sendErr, context.Canceled) || errors.Is(sendErr, context.DeadlineExceeded) {
-					return sendErr
-				}
-				logger.L().Errorf("Daily bill push failed to send: chat_id=%d, merchant_id=%d, err=%v", group.TelegramID, merchantID, sendErr)
-				mu.Lock()
-				failureDetails = append(failureDetails, fmt.Sprintf("chat_id=%d, merchant_id=%d: 发送失败 (%v)", group.TelegramID, merchantID, sendErr))
-				mu.Unlock()
-				return nil
-			}
-
-			logger.L().Infof("Daily bill push sent: chat_id=%d, merchant_id=%d, target_date=%s", group.TelegramID, merchantID, targetDate.Format("2006-01-02"))
 			mu.Lock()
 			successCount++
+			if skipped {
+				skippedCount++
+			}
 			mu.Unlock()
 
 			return nil
@@ -186,18 +238,208 @@ func (s *dailySummaryScheduler) dispatch(parent context.Context) {
 		}
 	}
 
+	// 当天账单推送完毕后再补发之前失败的群，补发使用独立的时间预算，不挤占当天推送
+	catchUp := s.runCatchUp(parent)
+
 	duration := time.Since(startTime)
 	failureCount := len(failureDetails)
-	note := ""
+	notes := make([]string, 0, 3)
 	if aborted {
-		note = "任务在完成前被取消。"
+		notes = append(notes, "任务在完成前被取消。")
+	}
+	if skippedCount > 0 {
+		notes = append(notes, fmt.Sprintf("其中 %d 个群当天账单已推送过，本次跳过。", skippedCount))
+	}
+	if failureCount > 0 && s.repo != nil {
+		notes = append(notes, "失败的群已登记待补发，下次调度或 /resend_bills 会自动补发。")
 	}
+	if catchUp.total > 0 {
+		notes = append(notes, catchUp.summary())
+	}
+	note := strings.Join(notes, "\n")
 
 	logger.L().Infof("Daily bill push completed for %d groups (success=%d, failure=%d), target_date=%s", len(eligible), successCount, failureCount, targetDate.Format("2006-01-02"))
 
 	s.notifyOwners(parent, targetDate, len(eligible), successCount, failureCount, duration, note, failureDetails)
 }
 
+// pushSummary 生成并发送单个群的账单
+func (s *dailySummaryScheduler) pushSummary(ctx context.Context, group *models.Group, target time.Time) error {
+	message, err := s.bot.sifangFeature.BuildSummaryMessage(ctx, int64(group.Settings.MerchantID), target)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		return errDailyBillEmpty
+	}
+	if _, err := s.bot.sendScheduledPush(ctx, group, group.TelegramID, message); err != nil {
+		return fmt.Errorf("发送失败 (%w)", err)
+	}
+	return nil
+}
+
+// deliver 推送单个群某一天的账单：已推送过的直接跳过（skipped=true），
+// 失败时有限次重试，仍失败则登记到待补发列表并返回最后一次错误
+func (s *dailySummaryScheduler) deliver(ctx context.Context, group *models.Group, target time.Time) (bool, error) {
+	date := target.Format("2006-01-02")
+	merchantID := int64(group.Settings.MerchantID)
+
+	if s.repo != nil {
+		sent, err := s.repo.IsSent(ctx, group.TelegramID, date)
+		if err != nil {
+			// 查询失败时宁可推送，也不能让账单丢失
+			logger.Ctx(ctx).Warnf("Daily bill push failed to check sent mark: chat_id=%d, date=%s, err=%v", group.TelegramID, date, err)
+		} else if sent {
+			logger.Ctx(ctx).Infof("Daily bill push skipped, already sent: chat_id=%d, date=%s", group.TelegramID, date)
+			return true, nil
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= dailyBillPushMaxAttempts; attempt++ {
+		if lastErr = s.push(ctx, group, target); lastErr == nil {
+			break
+		}
+		logger.Ctx(ctx).Warnf("Daily bill push attempt %d/%d failed: chat_id=%d, merchant_id=%d, err=%v",
+			attempt, dailyBillPushMaxAttempts, group.TelegramID, merchantID, lastErr)
+		if ctx.Err() != nil || errors.Is(lastErr, context.Canceled) || errors.Is(lastErr, context.DeadlineExceeded) {
+			break
+		}
+		if attempt < dailyBillPushMaxAttempts {
+			timer := time.NewTimer(time.Duration(attempt) * s.retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			if ctx.Err() != nil {
+				lastErr = ctx.Err()
+				break
+			}
+		}
+	}
+
+	if lastErr != nil {
+		logger.Ctx(ctx).Errorf("Daily bill push failed: chat_id=%d, merchant_id=%d, date=%s, err=%v", group.TelegramID, merchantID, date, lastErr)
+		if s.repo != nil {
+			// 推送任务可能已超时，登记状态使用独立的短超时
+			markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dailyBillMarkTimeout)
+			defer cancel()
+			if err := s.repo.MarkPending(markCtx, group.TelegramID, merchantID, date, lastErr.Error()); err != nil {
+				logger.Ctx(ctx).Errorf("Daily bill push failed to record pending: chat_id=%d, date=%s, err=%v", group.TelegramID, date, err)
+			}
+		}
+		return false, lastErr
+	}
+
+	logger.Ctx(ctx).Infof("Daily bill push sent: chat_id=%d, merchant_id=%d, target_date=%s", group.TelegramID, merchantID, date)
+	s.markSent(ctx, group.TelegramID, merchantID, date)
+	return false, nil
+}
+
+// markSent 登记已推送：账单已经发出，标记写入失败时补发会重复推送，因此有限次重试写入，
+// 每次使用独立的短超时（推送任务本身可能已超时）
+func (s *dailySummaryScheduler) markSent(ctx context.Context, chatID, merchantID int64, date string) {
+	if s.repo == nil {
+		return
+	}
+	base := context.WithoutCancel(ctx)
+	for attempt := 1; attempt <= dailyBillPushMaxAttempts; attempt++ {
+		markCtx, cancel := context.WithTimeout(base, dailyBillMarkTimeout)
+		err := s.repo.MarkSent(markCtx, chatID, merchantID, date)
+		cancel()
+		if err == nil {
+			return
+		}
+		logger.Ctx(ctx).Errorf("Daily bill push failed to record sent (attempt %d/%d): chat_id=%d, date=%s, err=%v",
+			attempt, dailyBillPushMaxAttempts, chatID, date, err)
+		if attempt < dailyBillPushMaxAttempts {
+			time.Sleep(time.Duration(attempt) * s.retryDelay)
+		}
+	}
+}
+
+// dailyBillCatchUpResult 一次补发的结果
+type dailyBillCatchUpResult struct {
+	total    int      // 待补发记录数
+	sent     int      // 补发成功（含已被其他流程推送而跳过的）
+	failures []string // 补发失败或无法补发的原因
+}
+
+// summary 补发结果摘要
+func (r dailyBillCatchUpResult) summary() string {
+	text := fmt.Sprintf("补发历史失败账单：共 %d 条，成功 %d 条", r.total, r.sent)
+	for _, failure := range r.failures {
+		text += "\n• " + failure
+	}
+	return text
+}
+
+// runCatchUp 定时推送后的补发，使用独立于当天推送的超时
+func (s *dailySummaryScheduler) runCatchUp(parent context.Context) dailyBillCatchUpResult {
+	if parent.Err() != nil {
+		return dailyBillCatchUpResult{}
+	}
+	ctx, cancel := context.WithTimeout(parent, dailyBillCatchUpTimeout)
+	defer cancel()
+	return s.catchUp(ctx)
+}
+
+// catchUp 补发最近几天登记为失败的账单，群组已不可用或商户号已变更的记录跳过
+func (s *dailySummaryScheduler) catchUp(ctx context.Context) dailyBillCatchUpResult {
+	var result dailyBillCatchUpResult
+	if s == nil || s.repo == nil {
+		return result
+	}
+
+	s.catchUpMu.Lock()
+	defer s.catchUpMu.Unlock()
+
+	since := time.Now().In(s.location).AddDate(0, 0, -dailyBillCatchUpDays).Format("2006-01-02")
+	records, err := s.repo.ListPending(ctx, since, dailyBillCatchUpBatch)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Daily bill catch-up failed to list pending: %v", err)
+		result.failures = append(result.failures, fmt.Sprintf("读取待补发列表失败: %v", err))
+		return result
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		result.total++
+
+		group, err := s.bot.groupService.GetGroupInfo(ctx, record.ChatID)
+		if err != nil || !isEligibleMerchantGroup(group) {
+			result.failures = append(result.failures, fmt.Sprintf("chat_id=%d, date=%s: 群组不可用，跳过", record.ChatID, record.Date))
+			continue
+		}
+		if int64(group.Settings.MerchantID) != record.MerchantID {
+			result.failures = append(result.failures, fmt.Sprintf("chat_id=%d, date=%s: 商户号已变更，跳过", record.ChatID, record.Date))
+			continue
+		}
+		target, err := time.ParseInLocation("2006-01-02", record.Date, models.GroupLocation(group.Settings))
+		if err != nil {
+			result.failures = append(result.failures, fmt.Sprintf("chat_id=%d: 日期无效 %s", record.ChatID, record.Date))
+			continue
+		}
+
+		groupCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
+		_, err = s.deliver(groupCtx, group, target)
+		cancel()
+		if err != nil {
+			result.failures = append(result.failures, fmt.Sprintf("chat_id=%d, date=%s: %v", record.ChatID, record.Date, err))
+			continue
+		}
+		result.sent++
+	}
+
+	if result.total > 0 {
+		logger.Ctx(ctx).Infof("Daily bill catch-up completed: total=%d, sent=%d, failed=%d", result.total, result.sent, len(result.failures))
+	}
+	return result
+}
+
 func filterEligibleMerchantGroups(groups []*models.Group) []*models.Group {
 	eligible := make([]*models.Group, 0, len(groups))
 	for _, group := range groups {
@@ -241,17 +483,65 @@ func isEligibleMerchantGroup(group *models.Group) bool {
 
 func nextDailyRun(now time.Time, location *time.Location) time.Time {
 	local := now.In(location)
-	next := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 5, 0, location)
+	// 按日历日推进而不是加 24 小时，夏令时切换当天也落在次日零点
+	next := models.DayStart(local.Year(), local.Month(), local.Day(), location).Add(5 * time.Second)
 	if !next.After(local) {
-		next = next.Add(24 * time.Hour)
+		next = models.DayStart(local.Year(), local.Month(), local.Day()+1, location).Add(5 * time.Second)
 	}
 	return next
 }
 
-func previousBillingDate(now time.Time, location *time.Location) time.Time {
-	local := now.In(location)
-	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
-	return midnight.AddDate(0, 0, -1)
+// nextZonedDailyRun 在默认时区与各群组时区中取最近的一次零点触发时间
+func nextZonedDailyRun(now time.Time, fallback *time.Location, groups []*models.Group) time.Time {
+	next := nextDailyRun(now, fallback)
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		candidate := nextDailyRun(now, models.GroupLocation(group.Settings))
+		if candidate.Before(next) {
+			next = candidate
+		}
+	}
+	return next
+}
+
+// isLocationDueAt 判断指定时区的本地时间是否处于零点后的触发窗口内
+func isLocationDueAt(location *time.Location, at time.Time) bool {
+	local := at.In(location)
+	midnight := models.DayStart(local.Year(), local.Month(), local.Day(), location)
+	return local.Sub(midnight) < scheduleDueWindow
+}
+
+// filterDueGroups 仅保留本地时间刚过零点的群组
+func filterDueGroups(groups []*models.Group, at time.Time) []*models.Group {
+	due := make([]*models.Group, 0, len(groups))
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		if isLocationDueAt(models.GroupLocation(group.Settings), at) {
+			due = append(due, group)
+		}
+	}
+	return due
+}
+
+// listScheduledGroups 获取调度器关注的群组，失败时返回空列表
+func (b *Bot) listScheduledGroups(ctx context.Context, filter func([]*models.Group) []*models.Group) []*models.Group {
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var matched []*models.Group
+	err := b.groupService.IterateActiveGroups(listCtx, func(groups []*models.Group) error {
+		matched = append(matched, filter(groups)...)
+		return nil
+	})
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Scheduler failed to list groups for timezone planning: %v", err)
+		return nil
+	}
+	return matched
 }
 
 func mustLoadChinaLocation() *time.Location {
@@ -300,7 +590,7 @@ func buildDailySummaryReport(targetDate time.Time, total, success, failure int,
 	builder.WriteString(fmt.Sprintf("耗时：%s\n", duration.Round(time.Millisecond)))
 
 	if note != "" {
-		builder.WriteString(note)
+		builder.WriteString(safeHTML(note))
 		builder.WriteString("\n")
 	}
 
@@ -308,7 +598,7 @@ func buildDailySummaryReport(targetDate time.Time, total, success, failure int,
 		builder.WriteString("失败详情：\n")
 		for _, detail := range failureDetails {
 			builder.WriteString("• ")
-			builder.WriteString(detail)
+			builder.WriteString(safeHTML(detail))
 			builder.WriteString("\n")
 		}
 	}
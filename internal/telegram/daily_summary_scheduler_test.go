@@ -1,11 +1,15 @@
 package telegram
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
 )
 
 func TestNextDailyRun(t *testing.T) {
@@ -38,33 +42,37 @@ func TestNextDailyRun(t *testing.T) {
 	}
 }
 
-func TestPreviousBillingDate(t *testing.T) {
-	loc := mustLoadChinaLocation()
+func TestDailyScheduleAcrossDST(t *testing.T) {
+	// 2018-11-04 圣保罗在 00:00 进入夏令时，当天 00:00 不存在，起点为 01:00 -02
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	dayStart := time.Date(2018, 11, 4, 1, 0, 0, 0, saoPaulo)
 
-	tests := []struct {
-		name     string
-		now      time.Time
-		expected time.Time
-	}{
-		{
-			name:     "SameDay",
-			now:      time.Date(2024, 10, 2, 0, 0, 10, 0, loc),
-			expected: time.Date(2024, 10, 1, 0, 0, 0, 0, loc),
-		},
-		{
-			name:     "CrossMonth",
-			now:      time.Date(2024, 3, 1, 8, 0, 0, 0, loc),
-			expected: time.Date(2024, 2, 29, 0, 0, 0, 0, loc),
-		},
+	next := nextDailyRun(time.Date(2018, 11, 3, 12, 0, 0, 0, saoPaulo), saoPaulo)
+	if want := dayStart.Add(5 * time.Second); !next.Equal(want) {
+		t.Fatalf("expected next run %v, got %v", want, next)
+	}
+	if !isLocationDueAt(saoPaulo, next) {
+		t.Fatalf("expected %v to be due", next)
+	}
+	if got := models.PreviousBillingDate(next.Add(time.Hour), saoPaulo); got.Format("2006-01-02") != "2018-11-03" {
+		t.Fatalf("expected billing date 2018-11-03, got %v", got)
+	}
+	// 次日结算的是切换当天
+	if got := models.PreviousBillingDate(time.Date(2018, 11, 5, 0, 0, 30, 0, saoPaulo), saoPaulo); !got.Equal(dayStart) {
+		t.Fatalf("expected billing date %v, got %v", dayStart, got)
 	}
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			got := previousBillingDate(tc.now, loc)
-			if !got.Equal(tc.expected) {
-				t.Fatalf("expected %v, got %v", tc.expected, got)
-			}
-		})
+	// 纽约夏令时结束当天有 25 小时，下一次触发仍在次日零点
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	next = nextDailyRun(time.Date(2024, 11, 3, 0, 1, 0, 0, newYork), newYork)
+	if want := time.Date(2024, 11, 4, 0, 0, 5, 0, newYork); !next.Equal(want) {
+		t.Fatalf("expected next run %v, got %v", want, next)
 	}
 }
 
@@ -142,21 +150,23 @@ func TestBuildDailySummaryReport(t *testing.T) {
 	failures := []string{
 		"chat_id=1, merchant_id=2: 超时",
 		"chat_id=3: 生成的消息为空",
+		"chat_id=4: 发送失败 (Bad Request: can't parse entities: <b> & </i>)",
 	}
 
-	report := buildDailySummaryReport(targetDate, 5, 3, len(failures), duration, note, failures)
+	report := buildDailySummaryReport(targetDate, 5, 2, len(failures), duration, note, failures)
 
 	expectedLines := []string{
 		"📊 每日账单推送报告",
 		"日期：2024-10-02",
 		"目标群组：5",
-		"成功：3",
-		"失败：2",
+		"成功：2",
+		"失败：3",
 		"耗时：1m30.125s",
 		note,
 		"失败详情：",
 		"• chat_id=1, merchant_id=2: 超时",
 		"• chat_id=3: 生成的消息为空",
+		"• chat_id=4: 发送失败 (Bad Request: can&#39;t parse entities: &lt;b&gt; &amp; &lt;/i&gt;)",
 	}
 
 	for _, line := range expectedLines {
@@ -183,3 +193,193 @@ func TestBuildDailySummaryReportWithoutFailures(t *testing.T) {
 		t.Fatalf("expected report duration to be rounded to milliseconds, got %q", report)
 	}
 }
+
+func TestNextZonedDailyRun(t *testing.T) {
+	loc := mustLoadChinaLocation()
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, loc)
+
+	groups := []*models.Group{
+		nil,
+		{TelegramID: 1},
+		{TelegramID: 2, Settings: models.GroupSettings{Timezone: "Asia/Tokyo"}},
+	}
+
+	got := nextZonedDailyRun(now, loc, groups)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	expected := time.Date(2024, 10, 2, 0, 0, 5, 0, tokyo)
+	if !got.Equal(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if fallback := nextZonedDailyRun(now, loc, nil); !fallback.Equal(time.Date(2024, 10, 2, 0, 0, 5, 0, loc)) {
+		t.Fatalf("expected default location run, got %v", fallback)
+	}
+}
+
+func TestFilterDueGroups(t *testing.T) {
+	loc := mustLoadChinaLocation()
+	at := time.Date(2024, 10, 2, 0, 0, 5, 0, loc)
+
+	groups := []*models.Group{
+		{TelegramID: 1},
+		{TelegramID: 2, Settings: models.GroupSettings{Timezone: "Asia/Bangkok"}},
+		{TelegramID: 3, Settings: models.GroupSettings{Timezone: "Invalid/Zone"}},
+	}
+
+	due := filterDueGroups(groups, at)
+	if len(due) != 2 {
+		t.Fatalf("expected 2 due groups, got %d", len(due))
+	}
+	for _, g := range due {
+		if g.TelegramID == 2 {
+			t.Fatalf("did not expect Bangkok group to be due at %v", at)
+		}
+	}
+}
+
+// memoryDailyBillPushRepository 内存版推送记录，按 chat_id:date 存储
+type memoryDailyBillPushRepository struct {
+	repository.DailyBillPushRepository
+	records map[string]*models.DailyBillPush
+	// markSentFailures MarkSent 前若干次调用返回错误
+	markSentFailures int
+}
+
+func newMemoryDailyBillPushRepository() *memoryDailyBillPushRepository {
+	return &memoryDailyBillPushRepository{records: make(map[string]*models.DailyBillPush)}
+}
+
+func (r *memoryDailyBillPushRepository) key(chatID int64, date string) string {
+	return fmt.Sprintf("%d:%s", chatID, date)
+}
+
+func (r *memoryDailyBillPushRepository) IsSent(ctx context.Context, chatID int64, date string) (bool, error) {
+	record, ok := r.records[r.key(chatID, date)]
+	return ok && record.Status == models.DailyBillPushSent, nil
+}
+
+func (r *memoryDailyBillPushRepository) MarkSent(ctx context.Context, chatID, merchantID int64, date string) error {
+	if r.markSentFailures > 0 {
+		r.markSentFailures--
+		return errors.New("mongo unavailable")
+	}
+	record, ok := r.records[r.key(chatID, date)]
+	if !ok {
+		record = &models.DailyBillPush{ChatID: chatID, Date: date}
+		r.records[r.key(chatID, date)] = record
+	}
+	record.MerchantID = merchantID
+	record.Status = models.DailyBillPushSent
+	record.LastError = ""
+	return nil
+}
+
+func (r *memoryDailyBillPushRepository) MarkPending(ctx context.Context, chatID, merchantID int64, date string, reason string) error {
+	record, ok := r.records[r.key(chatID, date)]
+	if ok && record.Status == models.DailyBillPushSent {
+		return nil
+	}
+	if !ok {
+		record = &models.DailyBillPush{ChatID: chatID, Date: date}
+		r.records[r.key(chatID, date)] = record
+	}
+	record.MerchantID = merchantID
+	record.Status = models.DailyBillPushPending
+	record.Attempts++
+	record.LastError = reason
+	return nil
+}
+
+func TestDailySummaryDeliverRetriesAndRecordsPending(t *testing.T) {
+	repo := newMemoryDailyBillPushRepository()
+	calls := 0
+	s := &dailySummaryScheduler{repo: repo, push: func(ctx context.Context, group *models.Group, target time.Time) error {
+		calls++
+		return errors.New("telegram unavailable")
+	}}
+	group := &models.Group{TelegramID: -100, Settings: models.GroupSettings{MerchantID: 2024}}
+	target := time.Date(2024, 10, 1, 0, 0, 0, 0, mustLoadChinaLocation())
+
+	if _, err := s.deliver(context.Background(), group, target); err == nil {
+		t.Fatalf("expected delivery error")
+	}
+	if calls != dailyBillPushMaxAttempts {
+		t.Fatalf("expected %d attempts, got %d", dailyBillPushMaxAttempts, calls)
+	}
+	record := repo.records["-100:2024-10-01"]
+	if record == nil || record.Status != models.DailyBillPushPending || record.MerchantID != 2024 || record.LastError != "telegram unavailable" {
+		t.Fatalf("expected pending record, got %+v", record)
+	}
+
+	// 补发成功后标记为已推送
+	calls = 0
+	s.push = func(ctx context.Context, group *models.Group, target time.Time) error {
+		calls++
+		return nil
+	}
+	if skipped, err := s.deliver(context.Background(), group, target); err != nil || skipped {
+		t.Fatalf("expected resend success, skipped=%v err=%v", skipped, err)
+	}
+	if record.Status != models.DailyBillPushSent || calls != 1 {
+		t.Fatalf("expected sent after one attempt, status=%s calls=%d", record.Status, calls)
+	}
+
+	// 同一天不会重复推送
+	skipped, err := s.deliver(context.Background(), group, target)
+	if err != nil || !skipped || calls != 1 {
+		t.Fatalf("expected duplicate push skipped, skipped=%v err=%v calls=%d", skipped, err, calls)
+	}
+}
+
+func TestDailySummaryDeliverStopsOnContextCancel(t *testing.T) {
+	repo := newMemoryDailyBillPushRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	s := &dailySummaryScheduler{repo: repo, retryDelay: time.Hour, push: func(ctx context.Context, group *models.Group, target time.Time) error {
+		calls++
+		cancel()
+		return errors.New("boom")
+	}}
+	group := &models.Group{TelegramID: -100, Settings: models.GroupSettings{MerchantID: 2024}}
+
+	if _, err := s.deliver(ctx, group, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatalf("expected error after cancel")
+	}
+	if calls != 1 {
+		t.Fatalf("expected no retry after cancel, got %d calls", calls)
+	}
+	if record := repo.records["-100:2024-10-01"]; record == nil || record.Status != models.DailyBillPushPending {
+		t.Fatalf("expected canceled push recorded as pending, got %+v", record)
+	}
+}
+
+func TestDailySummaryDeliverRetriesSentMark(t *testing.T) {
+	repo := newMemoryDailyBillPushRepository()
+	repo.markSentFailures = dailyBillPushMaxAttempts - 1
+	calls := 0
+	s := &dailySummaryScheduler{repo: repo, push: func(ctx context.Context, group *models.Group, target time.Time) error {
+		calls++
+		return nil
+	}}
+	group := &models.Group{TelegramID: -100, Settings: models.GroupSettings{MerchantID: 2024}}
+	target := time.Date(2024, 10, 1, 0, 0, 0, 0, mustLoadChinaLocation())
+
+	if _, err := s.deliver(context.Background(), group, target); err != nil {
+		t.Fatalf("expected delivery success, got %v", err)
+	}
+	// 标记写入重试成功，补发不会再次推送
+	if skipped, err := s.deliver(context.Background(), group, target); err != nil || !skipped || calls != 1 {
+		t.Fatalf("expected sent mark to be recorded, skipped=%v err=%v calls=%d", skipped, err, calls)
+	}
+}
+
+func TestDailyBillCatchUpSummary(t *testing.T) {
+	result := dailyBillCatchUpResult{total: 2, sent: 1, failures: []string{"chat_id=-1, date=2024-10-01: 群组不可用，跳过"}}
+	want := "补发历史失败账单：共 2 条，成功 1 条\n• chat_id=-1, date=2024-10-01: 群组不可用，跳过"
+	if got := result.summary(); got != want {
+		t.Fatalf("unexpected summary:\n%s", got)
+	}
+}
@@ -3,6 +3,9 @@ package calculator
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
+	"time"
 
 	botModels "github.com/go-telegram/bot/models"
 	"go_bot/internal/logger"
@@ -10,12 +13,23 @@ import (
 	"go_bot/internal/telegram/models"
 )
 
+const (
+	historyCommand      = "计算历史"
+	clearHistoryCommand = "清空计算历史"
+)
+
 // CalculatorFeature 计算器功能插件
-type CalculatorFeature struct{}
+type CalculatorFeature struct {
+	history *History
+	now     func() time.Time
+}
 
 // New 创建计算器功能实例
 func New() *CalculatorFeature {
-	return &CalculatorFeature{}
+	return &CalculatorFeature{
+		history: NewHistory(DefaultHistoryLimit),
+		now:     time.Now,
+	}
 }
 
 // Name 返回功能名称
@@ -28,6 +42,15 @@ func (f *CalculatorFeature) Enabled(ctx context.Context, group *models.Group) bo
 	return group.Settings.CalculatorEnabled
 }
 
+// HelpLines 帮助文本
+func (f *CalculatorFeature) HelpLines() []string {
+	return []string{
+		"<b>计算器</b>",
+		"直接发送数学表达式，例如：<code>(100+20)*1.5</code>",
+		fmt.Sprintf("%s - 查看本群最近 %d 条计算记录；%s - 清空本群记录", historyCommand, f.history.limit, clearHistoryCommand),
+	}
+}
+
 // Match 检查消息是否匹配(只处理群组中的数学表达式)
 func (f *CalculatorFeature) Match(ctx context.Context, msg *botModels.Message) bool {
 	// 只处理群组消息
@@ -35,30 +58,64 @@ func (f *CalculatorFeature) Match(ctx context.Context, msg *botModels.Message) b
 		return false
 	}
 
+	text := strings.TrimSpace(msg.Text)
+	if text == historyCommand || text == clearHistoryCommand {
+		return true
+	}
+
 	// 检查是否为数学表达式
 	return IsMathExpression(msg.Text)
 }
 
 // Process 处理计算请求
 func (f *CalculatorFeature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
+	switch strings.TrimSpace(msg.Text) {
+	case historyCommand:
+		text := formatHistory(f.history.List(msg.Chat.ID), models.GroupLocation(group.Settings))
+		return &types.Response{Text: text}, true, nil
+	case clearHistoryCommand:
+		count := f.history.Clear(msg.Chat.ID)
+		logger.Ctx(ctx).Infof("Calculator history cleared: chat_id=%d, count=%d", msg.Chat.ID, count)
+		return &types.Response{Text: fmt.Sprintf("🧹 已清空 %d 条计算历史", count)}, true, nil
+	}
+
 	// 执行计算
 	result, err := Calculate(msg.Text)
 	if err != nil {
 		// 计算失败
-		logger.L().Warnf("Calculator failed: chat_id=%d, text=%s, error=%v", msg.Chat.ID, msg.Text, err)
+		logger.Ctx(ctx).Warnf("Calculator failed: chat_id=%d, text=%s, error=%v", msg.Chat.ID, msg.Text, err)
 		return &types.Response{
-			Text: fmt.Sprintf("❌ 计算错误: %v", err),
+			Text: fmt.Sprintf("❌ 计算错误: %s", html.EscapeString(err.Error())),
 		}, true, nil
 	}
 
 	// 计算成功
-	logger.L().Infof("Calculator: %s = %g (chat_id=%d)", msg.Text, result, msg.Chat.ID)
-	return &types.Response{
-		Text: fmt.Sprintf("🧮 %s = %g", msg.Text, result),
-	}, true, nil
+	f.history.Add(msg.Chat.ID, HistoryEntry{Expression: msg.Text, Result: result, At: f.now()})
+	logger.Ctx(ctx).Infof("Calculator: %s = %g (chat_id=%d)", msg.Text, result, msg.Chat.ID)
+	return &types.Response{Text: FormatResult(msg.Text, result)}, true, nil
+}
+
+// FormatResult 渲染计算结果（HTML），群内消息与 inline 查询共用
+func FormatResult(expression string, result float64) string {
+	return fmt.Sprintf("🧮 %s = %g", html.EscapeString(expression), result)
 }
 
 // Priority 返回优先级(20 = 高优先级)
 func (f *CalculatorFeature) Priority() int {
 	return 20
 }
+
+// formatHistory 计算历史列表，最新的在前，时间按群组时区显示
+func formatHistory(entries []HistoryEntry, loc *time.Location) string {
+	if len(entries) == 0 {
+		return "ℹ️ 暂无计算历史"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧮 <b>计算历史</b>（最近 %d 条）\n", len(entries)))
+	for i, entry := range entries {
+		sb.WriteString(fmt.Sprintf("%d. <code>%s</code> %s = %g\n",
+			i+1, entry.At.In(loc).Format("01-02 15:04:05"), html.EscapeString(entry.Expression), entry.Result))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
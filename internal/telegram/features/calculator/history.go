@@ -0,0 +1,70 @@
+package calculator
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultHistoryLimit 每个群保留的计算历史条数
+const DefaultHistoryLimit = 20
+
+// HistoryEntry 一条计算历史：表达式、结果与计算时间
+type HistoryEntry struct {
+	Expression string
+	Result     float64
+	At         time.Time
+}
+
+// History 按群隔离的计算历史（内存），每群最多保留 limit 条，超出时丢弃最早的记录
+type History struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[int64][]HistoryEntry
+}
+
+// NewHistory 创建计算历史存储，limit <= 0 时使用 DefaultHistoryLimit
+func NewHistory(limit int) *History {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	return &History{
+		limit:   limit,
+		entries: make(map[int64][]HistoryEntry),
+	}
+}
+
+// Add 追加一条记录
+func (h *History) Add(chatID int64, entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := append(h.entries[chatID], entry)
+	if len(list) > h.limit {
+		// 复制到新切片，避免底层数组随丢弃的旧记录一起常驻内存
+		list = append([]HistoryEntry(nil), list[len(list)-h.limit:]...)
+	}
+	h.entries[chatID] = list
+}
+
+// List 返回本群历史，最新的在前
+func (h *History) List(chatID int64) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := h.entries[chatID]
+	result := make([]HistoryEntry, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		result = append(result, list[i])
+	}
+	return result
+}
+
+// Clear 清空本群历史，返回清除的条数
+func (h *History) Clear(chatID int64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := len(h.entries[chatID])
+	delete(h.entries, chatID)
+	return count
+}
@@ -0,0 +1,78 @@
+package calculator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestHistoryKeepsLatestEntriesPerChat(t *testing.T) {
+	history := NewHistory(2)
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, expr := range []string{"1+1", "2+2", "3+3"} {
+		history.Add(-1, HistoryEntry{Expression: expr, Result: float64(i), At: base.Add(time.Duration(i) * time.Minute)})
+	}
+	history.Add(-2, HistoryEntry{Expression: "9*9", Result: 81, At: base})
+
+	list := history.List(-1)
+	if len(list) != 2 || list[0].Expression != "3+3" || list[1].Expression != "2+2" {
+		t.Fatalf("expected newest two entries, got %+v", list)
+	}
+	if other := history.List(-2); len(other) != 1 || other[0].Result != 81 {
+		t.Fatalf("expected chats to be isolated, got %+v", other)
+	}
+
+	if cleared := history.Clear(-1); cleared != 2 {
+		t.Fatalf("expected 2 entries cleared, got %d", cleared)
+	}
+	if len(history.List(-1)) != 0 {
+		t.Fatalf("expected history to be empty after clear")
+	}
+	if len(history.List(-2)) != 1 {
+		t.Fatalf("expected other chats to be untouched by clear")
+	}
+}
+
+func TestFeatureRecordsAndShowsHistory(t *testing.T) {
+	feature := New()
+	feature.now = func() time.Time { return time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC) }
+	group := &models.Group{}
+	chat := botModels.Chat{ID: -100, Type: "supergroup"}
+	send := func(text string) string {
+		msg := &botModels.Message{Chat: chat, Text: text}
+		if !feature.Match(context.Background(), msg) {
+			t.Fatalf("expected %q to match", text)
+		}
+		resp, handled, err := feature.Process(context.Background(), msg, group)
+		if err != nil || !handled || resp == nil {
+			t.Fatalf("unexpected result for %q: resp=%+v handled=%v err=%v", text, resp, handled, err)
+		}
+		return resp.Text
+	}
+
+	if got := send("计算历史"); got != "ℹ️ 暂无计算历史" {
+		t.Fatalf("unexpected empty history: %q", got)
+	}
+
+	send("1+2")
+	send("5/0")
+	send("(100+20)*1.5")
+
+	got := send("计算历史")
+	want := "🧮 <b>计算历史</b>（最近 2 条）\n1. <code>01-01 12:00:00</code> (100+20)*1.5 = 180\n2. <code>01-01 12:00:00</code> 1+2 = 3"
+	if got != want {
+		t.Fatalf("unexpected history:\n%s", got)
+	}
+
+	if got := send("清空计算历史"); !strings.Contains(got, "2 条") {
+		t.Fatalf("unexpected clear response: %q", got)
+	}
+	if len(feature.history.List(-100)) != 0 {
+		t.Fatalf("expected history cleared")
+	}
+}
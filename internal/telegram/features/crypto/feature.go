@@ -3,6 +3,7 @@ package crypto
 import (
 	"context"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 
@@ -36,6 +37,15 @@ func (f *CryptoFeature) Enabled(ctx context.Context, group *models.Group) bool {
 	return group.Settings.CryptoEnabled
 }
 
+// HelpLines 帮助文本
+func (f *CryptoFeature) HelpLines() []string {
+	return []string{
+		"<b>USDT 价格查询</b>",
+		"<code>[a|z|k|w][序号] [金额]</code> - a=全部、z=支付宝、k=银行卡、w=微信；示例：z3 100",
+		"<code>[a|z|k|w][序号] 金额元换U</code> / <code>金额U换元</code> - 人民币与 USDT 双向换算（买入 U 用买价、卖出 U 用卖价）；示例：1000元换U、z1 100U换元",
+	}
+}
+
 // Match 检查消息是否匹配（只处理群组中的特定命令）
 func (f *CryptoFeature) Match(ctx context.Context, msg *botModels.Message) bool {
 	// 只处理群组消息
@@ -43,50 +53,91 @@ func (f *CryptoFeature) Match(ctx context.Context, msg *botModels.Message) bool
 		return false
 	}
 
-	// 检查是否匹配命令格式
-	_, err := ParseCommand(msg.Text)
+	return IsQuoteCommand(msg.Text)
+}
+
+// IsQuoteCommand 文本是否为 U 价查询或换算命令
+func IsQuoteCommand(text string) bool {
+	if _, err := ParseCommand(text); err == nil {
+		return true
+	}
+	_, err := ParseConvertCommand(text)
 	return err == nil
 }
 
 // Process 处理价格查询请求
 func (f *CryptoFeature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
-	// 解析命令
-	cmdInfo, err := ParseCommand(msg.Text)
-	if err != nil {
-		logger.L().Warnf("Crypto command parse failed: chat_id=%d, text=%s, error=%v", msg.Chat.ID, msg.Text, err)
+	result, ok, err := Quote(ctx, msg.Text, group.Settings.CryptoFloatRate)
+	if !ok {
+		logger.Ctx(ctx).Warnf("Crypto command parse failed: chat_id=%d, text=%s", msg.Chat.ID, msg.Text)
 		return &types.Response{Text: "❌ 命令格式错误"}, true, nil
 	}
+	if err != nil {
+		return &types.Response{Text: "❌ " + err.Error()}, true, nil
+	}
+	return &types.Response{Text: result}, true, nil
+}
+
+// Quote 解析 U 价查询或换算命令并返回 HTML 结果，群内消息与 inline 查询共用
+// ok 为 false 表示文本不是 crypto 命令；err 为可直接展示给用户的错误（不含 ❌ 前缀）
+func Quote(ctx context.Context, text string, floatRate float64) (result string, ok bool, err error) {
+	// 法币换算命令
+	if convertInfo, err := ParseConvertCommand(text); err == nil {
+		result, err := quoteConvert(ctx, convertInfo, floatRate)
+		return result, true, err
+	}
+
+	cmdInfo, err := ParseCommand(text)
+	if err != nil {
+		return "", false, nil
+	}
+	result, err = quotePrice(ctx, cmdInfo, floatRate)
+	return result, true, err
+}
 
+// ReferenceRate 返回 USDT→人民币参考汇率：欧易 OTC 全部支付方式卖单的最优价格（不含群内浮动费率）
+func ReferenceRate(ctx context.Context) (float64, error) {
+	orders, err := FetchC2COrders(ctx, PaymentMethodMap["a"])
+	if err != nil {
+		return 0, err
+	}
+	if len(orders) == 0 {
+		return 0, fmt.Errorf("no OKX C2C orders available")
+	}
+	price, err := strconv.ParseFloat(orders[0].Price, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("invalid OKX C2C price: %q", orders[0].Price)
+	}
+	return price, nil
+}
+
+// quotePrice 查询商家价格列表并高亮选中商家，带金额时计算总价
+func quotePrice(ctx context.Context, cmdInfo *CommandInfo, floatRate float64) (string, error) {
 	// 从 OKX 获取订单列表
 	orders, err := FetchC2COrders(ctx, cmdInfo.PaymentMethod)
 	if err != nil {
-		logger.L().Errorf("Failed to fetch OKX orders: payment_method=%s, error=%v", cmdInfo.PaymentMethod, err)
-		return &types.Response{Text: "❌ 获取价格失败，请稍后重试"}, true, nil
+		logger.Ctx(ctx).Errorf("Failed to fetch OKX orders: payment_method=%s, error=%v", cmdInfo.PaymentMethod, err)
+		return "", fmt.Errorf("获取价格失败，请稍后重试")
 	}
 
 	// 检查订单数量
 	if len(orders) == 0 {
-		return &types.Response{Text: "❌ 暂无可用订单"}, true, nil
+		return "", fmt.Errorf("暂无可用订单")
 	}
 
 	// 检查序号是否超出范围
 	if cmdInfo.SerialNum > len(orders) {
-		return &types.Response{
-			Text: fmt.Sprintf("❌ 商家序号超出范围（最多 %d 个）", len(orders)),
-		}, true, nil
+		return "", fmt.Errorf("商家序号超出范围（最多 %d 个）", len(orders))
 	}
 
 	// 获取选中的订单（序号从 1 开始，数组从 0 开始）
 	selectedOrder := orders[cmdInfo.SerialNum-1]
 	selectedPrice, err := strconv.ParseFloat(selectedOrder.Price, 64)
 	if err != nil {
-		logger.L().Errorf("Failed to parse selected price: price=%s, error=%v", selectedOrder.Price, err)
-		return &types.Response{Text: "❌ 价格解析失败"}, true, nil
+		logger.Ctx(ctx).Errorf("Failed to parse selected price: price=%s, error=%v", selectedOrder.Price, err)
+		return "", fmt.Errorf("价格解析失败")
 	}
 
-	// 从群组配置读取浮动费率
-	floatRate := group.Settings.CryptoFloatRate
-
 	// 计算最终价格
 	finalPrice := selectedPrice + floatRate
 
@@ -111,14 +162,14 @@ func (f *CryptoFeature) Process(ctx context.Context, msg *botModels.Message, gro
 			if floatRate > 0 {
 				// 有浮动：显示完整格式
 				response.WriteString(fmt.Sprintf("✅<b>%.2f        %s</b>___➕<b>%.2f</b>🟰<code>%.2f</code>⬅️\n",
-					price, order.NickName, floatRate, finalPrice))
+					price, html.EscapeString(order.NickName), floatRate, finalPrice))
 			} else {
 				// 无浮动：不显示加号部分
 				response.WriteString(fmt.Sprintf("✅<b>%.2f        %s</b> 🟰 <code>%.2f</code>⬅️\n",
-					price, order.NickName, finalPrice))
+					price, html.EscapeString(order.NickName), finalPrice))
 			}
 		} else {
-			response.WriteString(fmt.Sprintf("     <code>%.2f   %s</code>\n", price, order.NickName))
+			response.WriteString(fmt.Sprintf("     <code>%.2f   %s</code>\n", price, html.EscapeString(order.NickName)))
 		}
 	}
 
@@ -129,10 +180,86 @@ func (f *CryptoFeature) Process(ctx context.Context, msg *botModels.Message, gro
 			finalPrice, cmdInfo.Amount, totalPrice))
 	}
 
-	logger.L().Infof("Crypto query: chat_id=%d, payment=%s, serial=%d, amount=%.0f, price=%.2f",
-		msg.Chat.ID, cmdInfo.PaymentMethod, cmdInfo.SerialNum, cmdInfo.Amount, finalPrice)
+	logger.Ctx(ctx).Infof("Crypto query: payment=%s, serial=%d, amount=%.0f, price=%.2f",
+		cmdInfo.PaymentMethod, cmdInfo.SerialNum, cmdInfo.Amount, finalPrice)
+
+	return response.String(), nil
+}
 
-	return &types.Response{Text: response.String()}, true, nil
+// quoteConvert 处理人民币与 USDT 的双向换算
+// 用户买入 U（人民币换 U）取商家卖单价格并加浮动费率，即买价；
+// 用户卖出 U（U 换人民币）取商家买单价格并减浮动费率，即卖价
+func quoteConvert(ctx context.Context, info *ConvertInfo, floatRate float64) (string, error) {
+	side := SideSell
+	if info.Direction == ConvertUSDTToCNY {
+		side = SideBuy
+	}
+
+	orders, err := FetchC2COrdersBySide(ctx, info.PaymentMethod, side)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to fetch OKX orders: payment_method=%s, side=%s, error=%v", info.PaymentMethod, side, err)
+		return "", fmt.Errorf("获取价格失败，请稍后重试")
+	}
+	if len(orders) == 0 {
+		return "", fmt.Errorf("暂无可用订单")
+	}
+	if info.SerialNum > len(orders) {
+		return "", fmt.Errorf("商家序号超出范围（最多 %d 个）", len(orders))
+	}
+
+	selectedOrder := orders[info.SerialNum-1]
+	price, err := strconv.ParseFloat(selectedOrder.Price, 64)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to parse selected price: price=%s, error=%v", selectedOrder.Price, err)
+		return "", fmt.Errorf("价格解析失败")
+	}
+
+	rate, ok := convertRate(info.Direction, price, floatRate)
+	if !ok {
+		return "", fmt.Errorf("浮动费率大于卖价，无法换算")
+	}
+
+	logger.Ctx(ctx).Infof("Crypto convert: payment=%s, serial=%d, direction=%s, amount=%.2f, rate=%.2f",
+		info.PaymentMethod, info.SerialNum, info.Direction, info.Amount, rate)
+
+	return formatConvert(info, selectedOrder.NickName, price, floatRate, rate), nil
+}
+
+// convertRate 按换算方向计算汇率：买价 = 商家价 + 浮动，卖价 = 商家价 - 浮动；卖价不为正时返回 false
+func convertRate(direction string, price, floatRate float64) (float64, bool) {
+	if direction == ConvertUSDTToCNY {
+		rate := price - floatRate
+		return rate, rate > 0
+	}
+	return price + floatRate, price+floatRate > 0
+}
+
+// formatConvert 渲染换算结果，标明所用汇率是买价还是卖价
+func formatConvert(info *ConvertInfo, nickName string, price, floatRate, rate float64) string {
+	var response strings.Builder
+	response.WriteString("<b>USDT 换算</b>\n\n")
+	response.WriteString(fmt.Sprintf("信息来源: 欧易 <b>%s</b> · 商家 %d <b>%s</b>\n",
+		info.PaymentMethodName, info.SerialNum, html.EscapeString(nickName)))
+
+	if info.Direction == ConvertUSDTToCNY {
+		response.WriteString("汇率: <b>卖价</b>（卖出 USDT）")
+		if floatRate > 0 {
+			response.WriteString(fmt.Sprintf(" <code>%.2f</code> ➖ <b>%.2f</b>", price, floatRate))
+		}
+		response.WriteString(fmt.Sprintf(" 🟰 <code>%.2f</code>\n\n", rate))
+		response.WriteString(fmt.Sprintf("<code>%.2f</code> <b>U</b> ✖️ <code>%.2f</code> 🟰 <code>%.2f</code> <b>¥</b>",
+			info.Amount, rate, info.Amount*rate))
+		return response.String()
+	}
+
+	response.WriteString("汇率: <b>买价</b>（买入 USDT）")
+	if floatRate > 0 {
+		response.WriteString(fmt.Sprintf(" <code>%.2f</code> ➕ <b>%.2f</b>", price, floatRate))
+	}
+	response.WriteString(fmt.Sprintf(" 🟰 <code>%.2f</code>\n\n", rate))
+	response.WriteString(fmt.Sprintf("<code>%.2f</code> <b>¥</b> ➗ <code>%.2f</code> 🟰 <code>%.2f</code> <b>U</b>",
+		info.Amount, rate, info.Amount/rate))
+	return response.String()
 }
 
 // Priority 返回优先级（30 = 中优先级）
@@ -14,12 +14,17 @@ import (
 
 const (
 	okxC2CAPI = "https://www.okx.com/v3/c2c/tradingOrders/books"
+
+	// SideSell 商家卖出 USDT 的挂单（用户买入 USDT 时成交的价格，即买价）
+	SideSell = "sell"
+	// SideBuy 商家买入 USDT 的挂单（用户卖出 USDT 时成交的价格，即卖价）
+	SideBuy = "buy"
 )
 
 // C2COrder OKX C2C 订单结构
 type C2COrder struct {
-	Price        string `json:"price"`        // 价格
-	NickName     string `json:"nickName"`     // 商家昵称
+	Price           string `json:"price"`           // 价格
+	NickName        string `json:"nickName"`        // 商家昵称
 	AvailableAmount string `json:"availableAmount"` // 可用数量
 }
 
@@ -31,18 +36,23 @@ type C2CData struct {
 
 // C2CResponse OKX API 响应结构
 type C2CResponse struct {
-	Code int       `json:"code"` // 响应码（0 表示成功）
-	Data C2CData   `json:"data"` // 数据对象（包含 buy/sell 列表）
-	Msg  string    `json:"msg"`  // 消息
+	Code int     `json:"code"` // 响应码（0 表示成功）
+	Data C2CData `json:"data"` // 数据对象（包含 buy/sell 列表）
+	Msg  string  `json:"msg"`  // 消息
 }
 
-// FetchC2COrders 从 OKX 获取 C2C 订单列表
+// FetchC2COrders 从 OKX 获取 C2C 商家卖单列表（U 价查询使用）
 func FetchC2COrders(ctx context.Context, paymentMethod string) ([]C2COrder, error) {
+	return FetchC2COrdersBySide(ctx, paymentMethod, SideSell)
+}
+
+// FetchC2COrdersBySide 从 OKX 获取指定方向的 C2C 订单列表（side 为 SideSell 或 SideBuy）
+func FetchC2COrdersBySide(ctx context.Context, paymentMethod, side string) ([]C2COrder, error) {
 	// 构建请求参数
 	params := url.Values{
 		"quoteCurrency": {"CNY"},
 		"baseCurrency":  {"USDT"},
-		"side":          {"sell"},
+		"side":          {side},
 		"paymentMethod": {paymentMethod},
 		"userType":      {"all"},
 	}
@@ -65,7 +75,7 @@ func FetchC2COrders(ctx context.Context, paymentMethod string) ([]C2COrder, erro
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
 	// 发送请求
-	logger.L().Debugf("Fetching OKX C2C orders: payment_method=%s", paymentMethod)
+	logger.Ctx(ctx).Debugf("Fetching OKX C2C orders: payment_method=%s, side=%s", paymentMethod, side)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch OKX API: %w", err)
@@ -74,14 +84,14 @@ func FetchC2COrders(ctx context.Context, paymentMethod string) ([]C2COrder, erro
 
 	// 检查 HTTP 状态码
 	if resp.StatusCode != http.StatusOK {
-		logger.L().Errorf("OKX API HTTP error: status=%d, url=%s", resp.StatusCode, fullURL)
+		logger.Ctx(ctx).Errorf("OKX API HTTP error: status=%d, url=%s", resp.StatusCode, fullURL)
 		return nil, fmt.Errorf("OKX API returned non-200 status: %d", resp.StatusCode)
 	}
 
 	// 读取响应体
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		logger.L().Errorf("Failed to read OKX API response body: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to read OKX API response body: %v", err)
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
@@ -89,33 +99,36 @@ func FetchC2COrders(ctx context.Context, paymentMethod string) ([]C2COrder, erro
 	bodyStr := string(body)
 	bodyLen := len(bodyStr)
 	if bodyLen > 500 {
-		logger.L().Debugf("OKX API response (size=%d bytes, truncated): %s...", bodyLen, bodyStr[:500])
+		logger.Ctx(ctx).Debugf("OKX API response (size=%d bytes, truncated): %s...", bodyLen, bodyStr[:500])
 	} else {
-		logger.L().Debugf("OKX API response (size=%d bytes): %s", bodyLen, bodyStr)
+		logger.Ctx(ctx).Debugf("OKX API response (size=%d bytes): %s", bodyLen, bodyStr)
 	}
 
 	// 解析 JSON
 	var apiResp C2CResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
 		// 解析失败时打印完整响应（用于调试）
-		logger.L().Errorf("Failed to parse JSON response: %s", bodyStr)
+		logger.Ctx(ctx).Errorf("Failed to parse JSON response: %s", bodyStr)
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
 	// 检查业务响应码
 	if apiResp.Code != 0 {
-		logger.L().Errorf("OKX API business error: code=%d, msg=%s, payment_method=%s",
+		logger.Ctx(ctx).Errorf("OKX API business error: code=%d, msg=%s, payment_method=%s",
 			apiResp.Code, apiResp.Msg, paymentMethod)
 		return nil, fmt.Errorf("OKX API error: code=%d, msg=%s", apiResp.Code, apiResp.Msg)
 	}
 
-	// 检查数据（从 sell 列表中提取订单）
+	// 检查数据（按方向从 sell/buy 列表中提取订单）
 	orders := apiResp.Data.Sell
+	if side == SideBuy {
+		orders = apiResp.Data.Buy
+	}
 	if len(orders) == 0 {
-		logger.L().Warnf("OKX API returned empty order list: payment_method=%s", paymentMethod)
+		logger.Ctx(ctx).Warnf("OKX API returned empty order list: payment_method=%s, side=%s", paymentMethod, side)
 		return nil, fmt.Errorf("no orders available")
 	}
 
-	logger.L().Infof("Fetched %d orders from OKX: payment_method=%s", len(orders), paymentMethod)
+	logger.Ctx(ctx).Infof("Fetched %d orders from OKX: payment_method=%s, side=%s", len(orders), paymentMethod, side)
 	return orders, nil
 }
@@ -94,3 +94,77 @@ func ParseCommand(text string) (*CommandInfo, error) {
 		HasAmount:           hasAmount,
 	}, nil
 }
+
+// 换算方向
+const (
+	// ConvertCNYToUSDT 人民币换 USDT：用户买入 U，使用商家卖单价格（买价）
+	ConvertCNYToUSDT = "cny_to_usdt"
+	// ConvertUSDTToCNY USDT 换人民币：用户卖出 U，使用商家买单价格（卖价）
+	ConvertUSDTToCNY = "usdt_to_cny"
+)
+
+// defaultConvertSerial 换算未指定商家时使用的序号，与「序号 0」口径一致
+const defaultConvertSerial = 3
+
+var (
+	// 换算正则：[支付方式字母][商家序号（可选）] 金额 币种 换/转 币种
+	convertRegex = regexp.MustCompile(`(?i)^(?:([azwk])([0-9])\s+)?(\d+(?:\.\d+)?)\s*(元|块|人民币|rmb|cny|u|usdt)\s*[换转]\s*(元|块|人民币|rmb|cny|u|usdt)$`)
+)
+
+// ConvertInfo 解析后的换算命令信息
+type ConvertInfo struct {
+	PaymentMethod     string  // 支付方式 API 参数（all/aliPay/bank/wxPay）
+	PaymentMethodName string  // 支付方式名称（全部/支付宝/银行卡/微信）
+	SerialNum         int     // 商家序号（未指定时为 3）
+	Amount            float64 // 输入金额（币种由 Direction 决定）
+	Direction         string  // ConvertCNYToUSDT 或 ConvertUSDTToCNY
+}
+
+// ParseConvertCommand 解析法币换算命令
+// 格式：[支付方式字母][商家序号]（可选） 金额 币种 换 币种
+// 示例：1000元换U、100U换人民币、z1 500rmb换usdt
+func ParseConvertCommand(text string) (*ConvertInfo, error) {
+	matches := convertRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid convert command format")
+	}
+
+	fromUSDT := isUSDTUnit(matches[4])
+	if fromUSDT == isUSDTUnit(matches[5]) {
+		return nil, fmt.Errorf("convert currencies must differ: %s -> %s", matches[4], matches[5])
+	}
+
+	amount, err := strconv.ParseFloat(matches[3], 64)
+	if err != nil || amount <= 0 {
+		return nil, fmt.Errorf("invalid amount: %s", matches[3])
+	}
+
+	paymentMethod := "all"
+	serialNum := defaultConvertSerial
+	if matches[1] != "" {
+		paymentMethod = PaymentMethodMap[strings.ToLower(matches[1])]
+		serialNum, _ = strconv.Atoi(matches[2])
+		if serialNum == 0 {
+			serialNum = defaultConvertSerial
+		}
+	}
+
+	direction := ConvertCNYToUSDT
+	if fromUSDT {
+		direction = ConvertUSDTToCNY
+	}
+
+	return &ConvertInfo{
+		PaymentMethod:     paymentMethod,
+		PaymentMethodName: PaymentMethodName[paymentMethod],
+		SerialNum:         serialNum,
+		Amount:            amount,
+		Direction:         direction,
+	}, nil
+}
+
+// isUSDTUnit 判断币种单位是否为 USDT（其余均视为人民币）
+func isUSDTUnit(unit string) bool {
+	unit = strings.ToLower(unit)
+	return unit == "u" || unit == "usdt"
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import "testing"
+
+func TestParseConvertCommand(t *testing.T) {
+	cases := []struct {
+		text      string
+		direction string
+		amount    float64
+		payment   string
+		serial    int
+	}{
+		{"1000元换U", ConvertCNYToUSDT, 1000, "all", 3},
+		{"1000 人民币 换 usdt", ConvertCNYToUSDT, 1000, "all", 3},
+		{"100U换元", ConvertUSDTToCNY, 100, "all", 3},
+		{"z1 500rmb转U", ConvertCNYToUSDT, 500, "aliPay", 1},
+		{"K0 20.5usdt换CNY", ConvertUSDTToCNY, 20.5, "bank", 3},
+	}
+
+	for _, tc := range cases {
+		info, err := ParseConvertCommand(tc.text)
+		if err != nil {
+			t.Fatalf("ParseConvertCommand(%q) unexpected error: %v", tc.text, err)
+		}
+		if info.Direction != tc.direction || info.Amount != tc.amount || info.PaymentMethod != tc.payment || info.SerialNum != tc.serial {
+			t.Fatalf("ParseConvertCommand(%q) got %+v", tc.text, info)
+		}
+	}
+
+	for _, text := range []string{"100U换U", "1000元换人民币", "0元换U", "元换U", "z3 100", "1000元"} {
+		if _, err := ParseConvertCommand(text); err == nil {
+			t.Fatalf("ParseConvertCommand(%q) expected error", text)
+		}
+	}
+}
+
+func TestConvertRateDirection(t *testing.T) {
+	if rate, ok := convertRate(ConvertCNYToUSDT, 7.2, 0.12); !ok || rate < 7.319 || rate > 7.321 {
+		t.Fatalf("expected buy rate 7.32, got %v (%v)", rate, ok)
+	}
+	if rate, ok := convertRate(ConvertUSDTToCNY, 7.1, 0.1); !ok || rate < 6.999 || rate > 7.001 {
+		t.Fatalf("expected sell rate 7.00, got %v (%v)", rate, ok)
+	}
+	if _, ok := convertRate(ConvertUSDTToCNY, 0.1, 0.12); ok {
+		t.Fatal("expected non-positive sell rate to be rejected")
+	}
+}
+
+func TestFormatConvertLabelsSide(t *testing.T) {
+	info := &ConvertInfo{PaymentMethodName: "全部", SerialNum: 1, Amount: 100, Direction: ConvertUSDTToCNY}
+	got := formatConvert(info, "商家<A>", 7.1, 0.1, 7.0)
+	expected := "<b>USDT 换算</b>\n\n信息来源: 欧易 <b>全部</b> · 商家 1 <b>商家&lt;A&gt;</b>\n" +
+		"汇率: <b>卖价</b>（卖出 USDT） <code>7.10</code> ➖ <b>0.10</b> 🟰 <code>7.00</code>\n\n" +
+		"<code>100.00</code> <b>U</b> ✖️ <code>7.00</code> 🟰 <code>700.00</code> <b>¥</b>"
+	if got != expected {
+		t.Fatalf("unexpected convert text:\n%s", got)
+	}
+}
@@ -78,3 +78,26 @@ type Response = types.Response
 type TierAwareFeature interface {
 	AllowedGroupTiers() []models.GroupTier
 }
+
+// GatedFeature 可选接口：功能自行给出群组的使用前置条件（功能开关、群等级、绑定等）
+//
+// 未实现时，未启用的功能静默跳过、群等级不符时回复通用的适用范围提示；
+// 实现后只要消息匹配，Manager 会在其余功能均未处理时回复 Gate 返回的引导提示（例如去 /configs 开启、先绑定商户号），
+// Gate 返回空字符串表示群组满足条件
+type GatedFeature interface {
+	Gate(ctx context.Context, group *models.Group) string
+}
+
+// HelpProvider 可选接口：实现后 /help 会在功能生效的群组中展示这些帮助行
+//
+// 第一行通常为分组标题（支持 HTML），其余为命令说明
+type HelpProvider interface {
+	HelpLines() []string
+}
+
+// StatusProvider 可选接口：实现后「状态」命令会在功能生效的群组中展示该功能的状态片段
+//
+// 第一行通常为分组标题（支持 HTML），其余为当前绑定或数据；无可展示内容时返回 nil
+type StatusProvider interface {
+	StatusLines(ctx context.Context, group *models.Group) []string
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	botModels "github.com/go-telegram/bot/models"
 	"go_bot/internal/logger"
@@ -13,11 +14,25 @@ import (
 	"go_bot/internal/telegram/service"
 )
 
+// Guard 功能执行前的拦截函数，返回 false 时丢弃本次处理（例如限流）
+type Guard func(ctx context.Context, msg *botModels.Message, featureName string) bool
+
 // Manager 功能管理器
 // 负责注册、管理和执行所有功能插件
 type Manager struct {
 	features     []Feature
 	groupService service.GroupService
+	guard        Guard
+
+	// tierMu 保护按群等级预筛的候选功能，Register 时整体失效
+	tierMu     sync.RWMutex
+	tierSplits map[models.GroupTier]tierSplit
+}
+
+// tierSplit 某个群等级下的功能划分（均按优先级排序）
+type tierSplit struct {
+	allowed []Feature // 群等级允许的功能
+	blocked []Feature // 群等级不允许的功能，仅用于匹配后回复适用范围提示
 }
 
 // NewManager 创建功能管理器
@@ -25,6 +40,7 @@ func NewManager(groupService service.GroupService) *Manager {
 	return &Manager{
 		features:     make([]Feature, 0),
 		groupService: groupService,
+		tierSplits:   make(map[models.GroupTier]tierSplit),
 	}
 }
 
@@ -38,31 +54,41 @@ func (m *Manager) Register(feature Feature) {
 		return m.features[i].Priority() < m.features[j].Priority()
 	})
 
+	m.tierMu.Lock()
+	m.tierSplits = make(map[models.GroupTier]tierSplit)
+	m.tierMu.Unlock()
+
 	logger.L().Infof("Registered feature: %s (priority: %d)", feature.Name(), feature.Priority())
 }
 
+// SetGuard 设置功能执行前的拦截函数
+func (m *Manager) SetGuard(guard Guard) {
+	m.guard = guard
+}
+
 // Process 处理消息
-// 按优先级顺序执行所有已启用且匹配的功能
+// 先按群等级与启用状态筛掉不适用的功能，再按优先级短路匹配、执行
+// 群等级允许的功能均不匹配时，才检查群等级不允许的功能，匹配则回复适用范围提示
 // 返回值:
 //   - responseText: 响应文本(如果有功能处理)
 //   - handled: 是否已被某个功能处理
 //   - error: 处理过程中的错误
 func (m *Manager) Process(ctx context.Context, msg *botModels.Message) (response *types.Response, handled bool, err error) {
-	// 获取群组配置
+	// 获取群组配置（GroupService 内有短 TTL 缓存，写操作时失效）
 	group, err := m.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
 		// 群组不存在或获取失败,跳过功能处理
-		logger.L().Debugf("Skip feature processing: group not found or error, chat_id=%d", msg.Chat.ID)
+		logger.Ctx(ctx).Debugf("Skip feature processing: group not found or error, chat_id=%d", msg.Chat.ID)
 		return nil, false, nil
 	}
 
 	tier := models.NormalizeGroupTier(group.Tier)
+	split := m.splitForTier(tier)
 
-	// 按优先级顺序执行功能
-	for _, feature := range m.features {
-		// 1. 检查功能是否启用
+	// 按优先级顺序执行群等级允许的功能
+	for _, feature := range split.allowed {
+		// 1. 检查功能是否启用（只读群配置，开销远小于 Match）
 		if !feature.Enabled(ctx, group) {
-			logger.L().Debugf("Feature %s disabled, skipping", feature.Name())
 			continue
 		}
 
@@ -71,36 +97,169 @@ func (m *Manager) Process(ctx context.Context, msg *botModels.Message) (response
 			continue
 		}
 
-		// 3. 判断群等级是否允许
-		if tierAware, ok := feature.(TierAwareFeature); ok {
-			if allowed := tierAware.AllowedGroupTiers(); len(allowed) > 0 && !models.IsTierAllowed(tier, allowed) {
-				logger.L().Infof("Feature blocked: chat_id=%d feature=%s tier=%s allowed=%v text=%q",
-					msg.Chat.ID, feature.Name(), tier, allowed, strings.TrimSpace(msg.Text))
-				msgText := fmt.Sprintf("⚠️ 该功能仅适用于：%s\n当前群类型：%s",
-					models.FormatAllowedTierList(allowed), models.GroupTierDisplayName(tier))
-				return &types.Response{
-					Text:      msgText,
-					Temporary: true,
-				}, true, nil
-			}
+		// 3. 执行前拦截（例如按用户限流），被拦截的消息视为已处理
+		if m.guard != nil && !m.guard(ctx, msg, feature.Name()) {
+			logger.Ctx(ctx).Debugf("Feature %s blocked by guard", feature.Name())
+			return nil, true, nil
 		}
 
-		logger.L().Debugf("Feature %s matched message, processing...", feature.Name())
+		logger.Ctx(ctx).Debugf("Feature %s matched message, processing...", feature.Name())
 
 		// 4. 执行功能处理（传递 group 参数）
 		response, handled, err := feature.Process(ctx, msg, group)
 
 		// 5. 如果功能已处理(handled=true)或发生错误,停止后续功能执行
 		if handled || err != nil {
-			logger.L().Infof("Feature %s processed message (handled=%v, error=%v)", feature.Name(), handled, err)
+			logger.Ctx(ctx).Infof("Feature %s processed message (handled=%v, error=%v)", feature.Name(), handled, err)
 			return response, handled, err
 		}
 	}
 
+	// 群等级允许但未启用的功能：实现 GatedFeature 时匹配则回复引导提示
+	for _, feature := range split.allowed {
+		if _, ok := feature.(GatedFeature); !ok || feature.Enabled(ctx, group) {
+			continue
+		}
+		if response, ok := gateResponse(ctx, feature, msg, group); ok {
+			return response, true, nil
+		}
+	}
+
+	// 群等级不允许但已启用的功能：匹配时提示适用范围（GatedFeature 由功能给出引导提示）
+	for _, feature := range split.blocked {
+		if !feature.Enabled(ctx, group) {
+			continue
+		}
+		if _, ok := feature.(GatedFeature); ok {
+			if response, ok := gateResponse(ctx, feature, msg, group); ok {
+				return response, true, nil
+			}
+			continue
+		}
+		if !feature.Match(ctx, msg) {
+			continue
+		}
+		allowed := feature.(TierAwareFeature).AllowedGroupTiers()
+		logger.Ctx(ctx).Infof("Feature blocked: chat_id=%d feature=%s tier=%s allowed=%v text=%q",
+			msg.Chat.ID, feature.Name(), tier, allowed, strings.TrimSpace(msg.Text))
+		msgText := fmt.Sprintf("⚠️ 该功能仅适用于：%s\n当前群类型：%s",
+			models.FormatAllowedTierList(allowed), models.GroupTierDisplayName(tier))
+		return &types.Response{
+			Text:      msgText,
+			Temporary: true,
+		}, true, nil
+	}
+
 	// 没有任何功能处理该消息
 	return nil, false, nil
 }
 
+// gateResponse 消息匹配 GatedFeature 且群组不满足前置条件时，返回引导提示（临时消息）
+func gateResponse(ctx context.Context, feature Feature, msg *botModels.Message, group *models.Group) (*types.Response, bool) {
+	if !feature.Match(ctx, msg) {
+		return nil, false
+	}
+	hint := feature.(GatedFeature).Gate(ctx, group)
+	if hint == "" {
+		return nil, false
+	}
+	logger.Ctx(ctx).Infof("Feature gated: chat_id=%d feature=%s tier=%s text=%q",
+		msg.Chat.ID, feature.Name(), group.Tier, strings.TrimSpace(msg.Text))
+	return &types.Response{Text: hint, Temporary: true}, true
+}
+
+// splitForTier 返回指定群等级下的功能划分，首次计算后缓存（AllowedGroupTiers 为静态声明）
+func (m *Manager) splitForTier(tier models.GroupTier) tierSplit {
+	m.tierMu.RLock()
+	split, ok := m.tierSplits[tier]
+	m.tierMu.RUnlock()
+	if ok {
+		return split
+	}
+
+	for _, feature := range m.features {
+		if tierAware, ok := feature.(TierAwareFeature); ok {
+			if allowed := tierAware.AllowedGroupTiers(); len(allowed) > 0 && !models.IsTierAllowed(tier, allowed) {
+				split.blocked = append(split.blocked, feature)
+				continue
+			}
+		}
+		split.allowed = append(split.allowed, feature)
+	}
+
+	m.tierMu.Lock()
+	m.tierSplits[tier] = split
+	m.tierMu.Unlock()
+	return split
+}
+
+// HelpLines 汇总在指定群组中生效的功能帮助文本
+// 仅包含已启用且群等级匹配的功能，各功能之间以空行分隔
+func (m *Manager) HelpLines(ctx context.Context, group *models.Group) []string {
+	if group == nil {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	for _, feature := range m.features {
+		provider, ok := feature.(HelpProvider)
+		if !ok || !availableIn(ctx, feature, group) {
+			continue
+		}
+		lines = appendSection(lines, provider.HelpLines())
+	}
+	return lines
+}
+
+// StatusLines 汇总在指定群组中生效的功能状态片段，筛选规则与 HelpLines 一致
+func (m *Manager) StatusLines(ctx context.Context, group *models.Group) []string {
+	if group == nil {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	for _, feature := range m.features {
+		provider, ok := feature.(StatusProvider)
+		if !ok || !availableIn(ctx, feature, group) {
+			continue
+		}
+		lines = appendSection(lines, provider.StatusLines(ctx, group))
+	}
+	return lines
+}
+
+// availableIn 功能是否已启用且群等级匹配
+func availableIn(ctx context.Context, feature Feature, group *models.Group) bool {
+	if !feature.Enabled(ctx, group) {
+		return false
+	}
+	if tierAware, ok := feature.(TierAwareFeature); ok {
+		allowed := tierAware.AllowedGroupTiers()
+		return len(allowed) == 0 || models.IsTierAllowed(models.NormalizeGroupTier(group.Tier), allowed)
+	}
+	return true
+}
+
+// appendSection 追加一个功能的文本片段，各功能之间以空行分隔
+func appendSection(lines, section []string) []string {
+	if len(section) == 0 {
+		return lines
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, section...)
+}
+
+// ForEach 按优先级顺序遍历已注册的功能，fn 返回 false 时停止遍历
+func (m *Manager) ForEach(fn func(feature Feature) bool) {
+	for _, feature := range m.features {
+		if !fn(feature) {
+			return
+		}
+	}
+}
+
 // ListFeatures 列出所有已注册的功能(用于调试)
 func (m *Manager) ListFeatures() []string {
 	names := make([]string, len(m.features))
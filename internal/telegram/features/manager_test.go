@@ -0,0 +1,264 @@
+package features
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	botModels "github.com/go-telegram/bot/models"
+	"go_bot/internal/telegram/features/types"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+type fakeFeature struct {
+	name     string
+	priority int
+	enabled  bool
+	tiers    []models.GroupTier
+	help     []string
+	status   []string
+}
+
+func (f *fakeFeature) Name() string { return f.name }
+
+func (f *fakeFeature) Enabled(ctx context.Context, group *models.Group) bool { return f.enabled }
+
+func (f *fakeFeature) Match(ctx context.Context, msg *botModels.Message) bool { return false }
+
+func (f *fakeFeature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
+	return nil, false, nil
+}
+
+func (f *fakeFeature) Priority() int { return f.priority }
+
+func (f *fakeFeature) AllowedGroupTiers() []models.GroupTier { return f.tiers }
+
+func (f *fakeFeature) HelpLines() []string { return f.help }
+
+func (f *fakeFeature) StatusLines(ctx context.Context, group *models.Group) []string { return f.status }
+
+func TestManagerHelpLines(t *testing.T) {
+	manager := NewManager(nil)
+	manager.Register(&fakeFeature{name: "b", priority: 20, enabled: true, help: []string{"B1", "B2"}})
+	manager.Register(&fakeFeature{name: "a", priority: 10, enabled: true, help: []string{"A1"}})
+	manager.Register(&fakeFeature{name: "disabled", priority: 30, enabled: false, help: []string{"D1"}})
+	manager.Register(&fakeFeature{
+		name:     "upstream-only",
+		priority: 40,
+		enabled:  true,
+		tiers:    []models.GroupTier{models.GroupTierUpstream},
+		help:     []string{"U1"},
+	})
+
+	group := &models.Group{Tier: models.GroupTierMerchant}
+	got := strings.Join(manager.HelpLines(context.Background(), group), "|")
+	expected := "A1||B1|B2"
+	if got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	if lines := manager.HelpLines(context.Background(), nil); len(lines) != 0 {
+		t.Fatalf("expected no help lines for nil group, got %v", lines)
+	}
+}
+
+func TestManagerStatusLines(t *testing.T) {
+	manager := NewManager(nil)
+	manager.Register(&fakeFeature{name: "merchant", priority: 10, enabled: true, status: []string{"M1", "M2"}})
+	manager.Register(&fakeFeature{name: "empty", priority: 15, enabled: true})
+	manager.Register(&fakeFeature{name: "disabled", priority: 20, enabled: false, status: []string{"D1"}})
+	manager.Register(&fakeFeature{
+		name:     "balance",
+		priority: 30,
+		enabled:  true,
+		tiers:    []models.GroupTier{models.GroupTierUpstream},
+		status:   []string{"B1"},
+	})
+
+	got := strings.Join(manager.StatusLines(context.Background(), &models.Group{Tier: models.GroupTierUpstream}), "|")
+	if expected := "M1|M2||B1"; got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	got = strings.Join(manager.StatusLines(context.Background(), &models.Group{Tier: models.GroupTierBasic}), "|")
+	if expected := "M1|M2"; got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+type stubGroupService struct {
+	service.GroupService
+	group *models.Group
+}
+
+func (s *stubGroupService) GetGroupInfo(ctx context.Context, telegramID int64) (*models.Group, error) {
+	return s.group, nil
+}
+
+type countingFeature struct {
+	fakeFeature
+	processed int
+}
+
+func (f *countingFeature) Match(ctx context.Context, msg *botModels.Message) bool { return true }
+
+func (f *countingFeature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
+	f.processed++
+	return &types.Response{Text: "ok"}, true, nil
+}
+
+func TestManagerProcessGuard(t *testing.T) {
+	manager := NewManager(&stubGroupService{group: &models.Group{Tier: models.GroupTierBasic}})
+	feature := &countingFeature{fakeFeature: fakeFeature{name: "crypto", enabled: true}}
+	manager.Register(feature)
+
+	var guarded []string
+	allow := true
+	manager.SetGuard(func(ctx context.Context, msg *botModels.Message, featureName string) bool {
+		guarded = append(guarded, featureName)
+		return allow
+	})
+
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, Text: "z0"}
+	response, handled, err := manager.Process(context.Background(), msg)
+	if err != nil || !handled || response == nil || feature.processed != 1 {
+		t.Fatalf("expected feature to run when guard allows, got handled=%v response=%v err=%v", handled, response, err)
+	}
+
+	allow = false
+	response, handled, err = manager.Process(context.Background(), msg)
+	if err != nil || !handled || response != nil || feature.processed != 1 {
+		t.Fatalf("expected guard to swallow message, got handled=%v response=%v processed=%d", handled, response, feature.processed)
+	}
+	if strings.Join(guarded, ",") != "crypto,crypto" {
+		t.Fatalf("expected guard to receive feature name, got %v", guarded)
+	}
+}
+
+// matchCountingFeature 记录 Match 调用次数，按文本前缀匹配
+type matchCountingFeature struct {
+	fakeFeature
+	prefix  string
+	matched int
+}
+
+func (f *matchCountingFeature) Match(ctx context.Context, msg *botModels.Message) bool {
+	f.matched++
+	return strings.HasPrefix(msg.Text, f.prefix)
+}
+
+func (f *matchCountingFeature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
+	return &types.Response{Text: f.name}, true, nil
+}
+
+func TestManagerProcessSkipsInapplicableFeatures(t *testing.T) {
+	groups := &stubGroupService{group: &models.Group{Tier: models.GroupTierMerchant}}
+	manager := NewManager(groups)
+	disabled := &matchCountingFeature{fakeFeature: fakeFeature{name: "disabled", priority: 10}, prefix: "x"}
+	upstreamOnly := &matchCountingFeature{fakeFeature: fakeFeature{name: "upstream", priority: 20, enabled: true, tiers: []models.GroupTier{models.GroupTierUpstream}}, prefix: "x"}
+	merchant := &matchCountingFeature{fakeFeature: fakeFeature{name: "merchant", priority: 30, enabled: true}, prefix: "x"}
+	manager.Register(disabled)
+	manager.Register(upstreamOnly)
+	manager.Register(merchant)
+
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, Text: "x1"}
+	response, handled, err := manager.Process(context.Background(), msg)
+	if err != nil || !handled || response == nil || response.Text != "merchant" {
+		t.Fatalf("expected applicable feature to handle message, got handled=%v response=%v err=%v", handled, response, err)
+	}
+	if disabled.matched != 0 || upstreamOnly.matched != 0 {
+		t.Fatalf("expected inapplicable features to be skipped before Match, got disabled=%d upstream=%d", disabled.matched, upstreamOnly.matched)
+	}
+
+	// 适用功能均不匹配时，群等级不允许的功能仍回复适用范围提示
+	merchant.prefix = "y"
+	response, handled, _ = manager.Process(context.Background(), msg)
+	if !handled || response == nil || !strings.Contains(response.Text, "仅适用于") || !response.Temporary {
+		t.Fatalf("expected tier hint, got handled=%v response=%v", handled, response)
+	}
+}
+
+// gatedFeature 实现 GatedFeature，未满足前置条件时返回 hint
+type gatedFeature struct {
+	matchCountingFeature
+	hint string
+}
+
+func (f *gatedFeature) Gate(ctx context.Context, group *models.Group) string { return f.hint }
+
+func TestManagerProcessGatedFeature(t *testing.T) {
+	groups := &stubGroupService{group: &models.Group{Tier: models.GroupTierMerchant}}
+	manager := NewManager(groups)
+	feature := &gatedFeature{
+		matchCountingFeature: matchCountingFeature{fakeFeature: fakeFeature{name: "sifang", tiers: []models.GroupTier{models.GroupTierMerchant}}, prefix: "余额"},
+		hint:                 "请先开启",
+	}
+	manager.Register(feature)
+
+	// 群等级允许但功能未启用：回复功能自己的引导提示
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, Text: "余额"}
+	response, handled, err := manager.Process(context.Background(), msg)
+	if err != nil || !handled || response == nil || response.Text != "请先开启" || !response.Temporary {
+		t.Fatalf("expected gate hint for disabled feature, got handled=%v response=%v err=%v", handled, response, err)
+	}
+
+	// 群等级不符且功能未启用：不回复
+	groups.group = &models.Group{Tier: models.GroupTierBasic}
+	feature.hint = "仅适用于商户群"
+	if _, handled, _ := manager.Process(context.Background(), msg); handled {
+		t.Fatal("expected disabled feature with tier mismatch to be ignored")
+	}
+
+	// 群等级不符但功能已启用：使用引导提示而非通用适用范围提示
+	feature.enabled = true
+	response, handled, _ = manager.Process(context.Background(), msg)
+	if !handled || response == nil || response.Text != "仅适用于商户群" {
+		t.Fatalf("expected gate hint for tier mismatch, got handled=%v response=%v", handled, response)
+	}
+
+	// 不匹配或 Gate 放行时不回复
+	if _, handled, _ := manager.Process(context.Background(), &botModels.Message{Chat: botModels.Chat{ID: -1}, Text: "hello"}); handled {
+		t.Fatal("expected unmatched message to be ignored")
+	}
+	feature.hint = ""
+	if _, handled, _ := manager.Process(context.Background(), msg); handled {
+		t.Fatal("expected empty gate hint to be ignored")
+	}
+}
+
+// BenchmarkManagerProcess 模拟线上注册规模：多数功能对普通聊天消息不匹配，部分功能受群等级限制
+func BenchmarkManagerProcess(b *testing.B) {
+	groups := &stubGroupService{group: &models.Group{Tier: models.GroupTierBasic}}
+	manager := NewManager(groups)
+	for i, tiers := range [][]models.GroupTier{
+		nil,
+		{models.GroupTierBasic, models.GroupTierMerchant},
+		{models.GroupTierUpstream},
+		{models.GroupTierUpstream},
+		{models.GroupTierMerchant},
+		nil,
+	} {
+		manager.Register(&regexFeature{fakeFeature: fakeFeature{name: fmt.Sprintf("f%d", i), priority: i, enabled: true, tiers: tiers}})
+	}
+
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, Text: "今天天气不错，大家辛苦了"}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		manager.Process(ctx, msg)
+	}
+}
+
+var benchCommandPattern = regexp.MustCompile(`^(绑定\s+\d+|解绑(\s+(\d+|全部))?|切换商户号\s*\d+|商户号|绑定状态)$`)
+
+// regexFeature 以正则匹配消息，模拟真实功能的 Match 开销
+type regexFeature struct {
+	fakeFeature
+}
+
+func (f *regexFeature) Match(ctx context.Context, msg *botModels.Message) bool {
+	return benchCommandPattern.MatchString(strings.TrimSpace(msg.Text))
+}
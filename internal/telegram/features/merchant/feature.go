@@ -3,9 +3,12 @@ package merchant
 import (
 	"context"
 	"fmt"
+	"html"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/features/types"
@@ -15,17 +18,22 @@ import (
 	botModels "github.com/go-telegram/bot/models"
 )
 
+// historyLimit 「商户号历史」展示的最近记录条数
+const historyLimit = 20
+
 // Feature 商户号绑定功能
 type Feature struct {
 	groupService service.GroupService
 	userService  service.UserService
+	history      service.MerchantHistoryService // 为 nil 时不记录变更历史
 }
 
 // New 创建商户号绑定功能实例
-func New(groupService service.GroupService, userService service.UserService) *Feature {
+func New(groupService service.GroupService, userService service.UserService, history service.MerchantHistoryService) *Feature {
 	return &Feature{
 		groupService: groupService,
 		userService:  userService,
+		history:      history,
 	}
 }
 
@@ -48,29 +56,40 @@ func (f *Feature) Enabled(ctx context.Context, group *models.Group) bool {
 	return true
 }
 
+// HelpLines 帮助文本
+func (f *Feature) HelpLines() []string {
+	return []string{
+		"<b>商户号管理（Admin+）</b>",
+		"绑定 <code>[商户号]</code> - 绑定四方商户号，可绑定多个，首个绑定的为当前商户号",
+		"切换商户号 <code>[商户号或序号]</code> - 切换查询/下发默认使用的当前商户号",
+		"解绑 <code>[商户号|全部]</code> - 解除指定商户号或全部商户号，仅绑定一个时可省略参数",
+		"商户号 / 绑定状态 - 查看全部已绑定商户号及当前商户号",
+		"商户号历史 - 查看本群最近的商户号绑定/解绑/切换记录（操作人、时间、变更前后）",
+	}
+}
+
+// commandPattern 匹配: "绑定 123456", "解绑", "解绑 123456", "解绑 全部", "切换商户号 2", "商户号", "绑定状态", "商户号历史"
+var commandPattern = regexp.MustCompile(`^(绑定\s+\d+|解绑(\s+(\d+|全部))?|切换商户号\s*\d+|商户号|绑定状态|商户号历史)$`)
+
 // Match 检查消息是否匹配商户号命令
 func (f *Feature) Match(ctx context.Context, msg *botModels.Message) bool {
 	if msg.Text == "" {
 		return false
 	}
-
-	// 匹配: "绑定 123456", "解绑", "商户号", "绑定状态"
-	pattern := `^(绑定\s+\d+|解绑|商户号|绑定状态)$`
-	matched, _ := regexp.MatchString(pattern, strings.TrimSpace(msg.Text))
-	return matched
+	return commandPattern.MatchString(strings.TrimSpace(msg.Text))
 }
 
 // Process 处理商户号命令
 func (f *Feature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
 	// 权限检查: 仅 Admin+ 可操作
-	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID)
+	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to check admin permission: user_id=%d, err=%v", msg.From.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to check admin permission: user_id=%d, err=%v", msg.From.ID, err)
 		return resp("❌ 权限检查失败"), true, nil
 	}
 
 	if !isAdmin {
-		logger.L().Warnf("Unauthorized merchant operation attempt: user_id=%d, chat_id=%d", msg.From.ID, msg.Chat.ID)
+		logger.Ctx(ctx).Warnf("Unauthorized merchant operation attempt: user_id=%d, chat_id=%d", msg.From.ID, msg.Chat.ID)
 		return resp("❌ 仅管理员可以操作商户号绑定"), true, nil
 	}
 
@@ -82,9 +101,21 @@ func (f *Feature) Process(ctx context.Context, msg *botModels.Message, group *mo
 		return resp(respText), handled, err
 	}
 
+	// 切换命令
+	if strings.HasPrefix(text, "切换商户号") {
+		respText, handled, err := f.handleSwitch(ctx, msg, strings.TrimSpace(strings.TrimPrefix(text, "切换商户号")))
+		return resp(respText), handled, err
+	}
+
 	// 解绑命令
-	if text == "解绑" {
-		respText, handled, err := f.handleUnbind(ctx, msg)
+	if strings.HasPrefix(text, "解绑") {
+		respText, handled, err := f.handleUnbind(ctx, msg, strings.TrimSpace(strings.TrimPrefix(text, "解绑")))
+		return resp(respText), handled, err
+	}
+
+	// 变更历史
+	if text == "商户号历史" {
+		respText, handled, err := f.handleHistory(ctx, msg, group)
 		return resp(respText), handled, err
 	}
 
@@ -103,7 +134,7 @@ func (f *Feature) Priority() int {
 	return 15
 }
 
-// handleBind 处理绑定命令
+// handleBind 处理绑定命令，已有商户号时追加绑定，当前商户号保持不变
 func (f *Feature) handleBind(ctx context.Context, msg *botModels.Message, text string) (string, bool, error) {
 	// 提取商户号
 	parts := strings.Fields(text)
@@ -111,23 +142,15 @@ func (f *Feature) handleBind(ctx context.Context, msg *botModels.Message, text s
 		return "❌ 绑定格式错误，请使用: 绑定 [商户号]\n例如: 绑定 2025100", true, nil
 	}
 
-	merchantIDStr := parts[1]
-
-	// 验证商户号格式 (纯数字)
-	if !regexp.MustCompile(`^\d+$`).MatchString(merchantIDStr) {
-		return "❌ 商户号必须为纯数字", true, nil
-	}
-
-	// 解析为数字
-	merchantID, err := strconv.Atoi(merchantIDStr)
-	if err != nil || merchantID <= 0 {
-		return "❌ 商户号格式错误", true, nil
+	merchantID, errMsg := parseMerchantID(parts[1])
+	if errMsg != "" {
+		return errMsg, true, nil
 	}
 
 	// 获取当前群组信息
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 获取群组信息失败", true, nil
 	}
 
@@ -135,57 +158,130 @@ func (f *Feature) handleBind(ctx context.Context, msg *botModels.Message, text s
 		return "❌ 当前群组已绑定接口 ID，请先使用「解绑接口」解除全部接口后再操作商户号", true, nil
 	}
 
-	// 检查是否已绑定其他商户号
-	if group.Settings.MerchantID != 0 && group.Settings.MerchantID != int32(merchantID) {
-		return fmt.Sprintf("❌ 当前已绑定商户号: %d\n请先使用「解绑」命令解绑后再绑定新的商户号", group.Settings.MerchantID), true, nil
-	}
-
-	// 检查是否已绑定相同商户号
-	if group.Settings.MerchantID == int32(merchantID) {
+	bound := models.BoundMerchantIDs(group.Settings)
+	if slices.Contains(bound, merchantID) {
 		return fmt.Sprintf("✅ 当前群组已绑定商户号: %d", merchantID), true, nil
 	}
 
-	// 执行绑定
+	// 执行绑定：首个商户号直接作为当前商户号，其余追加到列表
+	before := group.Settings
 	settings := group.Settings
-	settings.MerchantID = int32(merchantID)
+	if len(bound) == 0 {
+		settings.MerchantID = merchantID
+		settings.MerchantIDs = []int32{merchantID}
+	} else {
+		settings.MerchantIDs = append(bound, merchantID)
+	}
 	settings.InterfaceBindings = nil
 
 	if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
-		logger.L().Errorf("Failed to bind merchant ID: chat_id=%d, merchant_id=%d, err=%v", msg.Chat.ID, merchantID, err)
+		logger.Ctx(ctx).Errorf("Failed to bind merchant ID: chat_id=%d, merchant_id=%d, err=%v", msg.Chat.ID, merchantID, err)
 		return "❌ 绑定失败，请稍后重试", true, nil
 	}
 
-	logger.L().Infof("Merchant ID bound: chat_id=%d, merchant_id=%d, operator=%d", msg.Chat.ID, merchantID, msg.From.ID)
-	return fmt.Sprintf("✅ 商户号绑定成功: %d", merchantID), true, nil
+	f.recordHistory(ctx, msg, models.MerchantHistoryBind, before, settings)
+	logger.Ctx(ctx).Infof("Merchant ID bound: chat_id=%d, merchant_id=%d, current=%d, operator=%d", msg.Chat.ID, merchantID, settings.MerchantID, msg.From.ID)
+	if len(bound) == 0 {
+		return fmt.Sprintf("✅ 商户号绑定成功: %d", merchantID), true, nil
+	}
+	return fmt.Sprintf("✅ 商户号绑定成功: %d\n当前商户号仍为 %d，可使用「切换商户号 %d」切换", merchantID, settings.MerchantID, merchantID), true, nil
 }
 
-// handleUnbind 处理解绑命令
-func (f *Feature) handleUnbind(ctx context.Context, msg *botModels.Message) (string, bool, error) {
+// handleSwitch 处理切换当前商户号命令，参数可以是商户号或「商户号」列表中的序号
+func (f *Feature) handleSwitch(ctx context.Context, msg *botModels.Message, arg string) (string, bool, error) {
+	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		return "❌ 获取群组信息失败", true, nil
+	}
+
+	bound := models.BoundMerchantIDs(group.Settings)
+	if len(bound) == 0 {
+		return "ℹ️ 当前群组未绑定任何商户号", true, nil
+	}
+
+	target, ok := resolveMerchant(bound, arg)
+	if !ok {
+		return fmt.Sprintf("❌ 未找到商户号或序号: %s\n\n%s", arg, formatMerchantList(bound, group.Settings.MerchantID)), true, nil
+	}
+	if target == group.Settings.MerchantID {
+		return fmt.Sprintf("ℹ️ 当前商户号已是 %d", target), true, nil
+	}
+
+	previous := group.Settings.MerchantID
+	before := group.Settings
+	settings := group.Settings
+	settings.MerchantID = target
+	settings.MerchantIDs = bound
+
+	if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to switch merchant ID: chat_id=%d, merchant_id=%d, err=%v", msg.Chat.ID, target, err)
+		return "❌ 切换失败，请稍后重试", true, nil
+	}
+
+	f.recordHistory(ctx, msg, models.MerchantHistorySwitch, before, settings)
+	logger.Ctx(ctx).Infof("Merchant ID switched: chat_id=%d, from=%d, to=%d, operator=%d", msg.Chat.ID, previous, target, msg.From.ID)
+	return fmt.Sprintf("✅ 当前商户号已切换为: %d", target), true, nil
+}
+
+// handleUnbind 处理解绑命令：arg 为空时仅在绑定一个商户号时直接解绑，「全部」解绑所有商户号
+func (f *Feature) handleUnbind(ctx context.Context, msg *botModels.Message, arg string) (string, bool, error) {
 	// 获取当前群组信息
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 获取群组信息失败", true, nil
 	}
 
 	// 检查是否已绑定
-	if group.Settings.MerchantID == 0 {
+	bound := models.BoundMerchantIDs(group.Settings)
+	if len(bound) == 0 {
 		return "ℹ️ 当前群组未绑定任何商户号", true, nil
 	}
 
-	oldMerchantID := group.Settings.MerchantID
-
-	// 执行解绑
+	previous := group.Settings.MerchantID
+	before := group.Settings
 	settings := group.Settings
-	settings.MerchantID = 0
+	var removed []int32
+	switch {
+	case arg == "全部" || (arg == "" && len(bound) == 1):
+		removed = bound
+		settings.MerchantID = 0
+		settings.MerchantIDs = nil
+	case arg == "":
+		return fmt.Sprintf("ℹ️ 当前群组绑定了 %d 个商户号，请使用「解绑 [商户号]」或「解绑 全部」\n\n%s", len(bound), formatMerchantList(bound, group.Settings.MerchantID)), true, nil
+	default:
+		merchantID, errMsg := parseMerchantID(arg)
+		if errMsg != "" {
+			return errMsg, true, nil
+		}
+		if !slices.Contains(bound, merchantID) {
+			return fmt.Sprintf("❌ 当前群组未绑定商户号: %d", merchantID), true, nil
+		}
+		removed = []int32{merchantID}
+		remaining := slices.DeleteFunc(slices.Clone(bound), func(id int32) bool { return id == merchantID })
+		settings.MerchantIDs = remaining
+		if settings.MerchantID == merchantID {
+			settings.MerchantID = 0
+			if len(remaining) > 0 {
+				settings.MerchantID = remaining[0]
+			}
+		}
+	}
 
+	// 执行解绑
 	if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
-		logger.L().Errorf("Failed to unbind merchant ID: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to unbind merchant ID: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 解绑失败，请稍后重试", true, nil
 	}
 
-	logger.L().Infof("Merchant ID unbound: chat_id=%d, old_merchant_id=%d, operator=%d", msg.Chat.ID, oldMerchantID, msg.From.ID)
-	return fmt.Sprintf("✅ 已解绑商户号: %d", oldMerchantID), true, nil
+	f.recordHistory(ctx, msg, models.MerchantHistoryUnbind, before, settings)
+	logger.Ctx(ctx).Infof("Merchant ID unbound: chat_id=%d, removed=%v, current=%d, operator=%d", msg.Chat.ID, removed, settings.MerchantID, msg.From.ID)
+	respText := fmt.Sprintf("✅ 已解绑商户号: %s", joinMerchantIDs(removed))
+	if settings.MerchantID != 0 && settings.MerchantID != previous {
+		respText += fmt.Sprintf("\n当前商户号已切换为: %d", settings.MerchantID)
+	}
+	return respText, true, nil
 }
 
 // handleQuery 处理查询命令
@@ -193,16 +289,193 @@ func (f *Feature) handleQuery(ctx context.Context, msg *botModels.Message) (stri
 	// 获取当前群组信息
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 获取群组信息失败", true, nil
 	}
 
 	// 返回绑定状态
-	if group.Settings.MerchantID == 0 {
+	bound := models.BoundMerchantIDs(group.Settings)
+	if len(bound) == 0 {
 		return "ℹ️ 当前群组未绑定商户号\n\n使用「绑定 [商户号]」进行绑定\n例如: 绑定 2025100", true, nil
 	}
 
-	return fmt.Sprintf("✅ 当前绑定商户号: %d\n\n使用「解绑」可以解除绑定", group.Settings.MerchantID), true, nil
+	if len(bound) == 1 {
+		return fmt.Sprintf("✅ 当前绑定商户号: %d\n\n使用「解绑」可以解除绑定", bound[0]), true, nil
+	}
+	example := bound[0]
+	for _, id := range bound {
+		if id != group.Settings.MerchantID {
+			example = id
+			break
+		}
+	}
+	return fmt.Sprintf("✅ 已绑定 %d 个商户号：\n%s\n\n查询命令默认使用当前商户号，可追加「#商户号」临时指定，例如：余额 #%d\n使用「切换商户号 [序号]」切换，「解绑 [商户号|全部]」解除绑定",
+		len(bound), formatMerchantList(bound, group.Settings.MerchantID), example), true, nil
+}
+
+// handleHistory 处理「商户号历史」命令，按群时区展示最近的变更记录
+func (f *Feature) handleHistory(ctx context.Context, msg *botModels.Message, group *models.Group) (string, bool, error) {
+	if f.history == nil {
+		return "ℹ️ 商户号变更历史未启用", true, nil
+	}
+
+	entries, err := f.history.ListRecent(ctx, msg.Chat.ID, historyLimit)
+	if err != nil {
+		return "❌ " + err.Error(), true, nil
+	}
+
+	loc := models.DefaultLocation()
+	if group != nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+	return formatHistory(entries, loc), true, nil
+}
+
+// recordHistory 写入商户号变更记录（变更前后的列表与当前商户号），失败仅记录日志，不影响绑定结果
+func (f *Feature) recordHistory(ctx context.Context, msg *botModels.Message, action string, before, after models.GroupSettings) {
+	if f.history == nil {
+		return
+	}
+
+	entry := &models.MerchantHistory{
+		ChatID:         msg.Chat.ID,
+		Action:         action,
+		OldMerchantIDs: models.BoundMerchantIDs(before),
+		NewMerchantIDs: models.BoundMerchantIDs(after),
+		OldCurrent:     before.MerchantID,
+		NewCurrent:     after.MerchantID,
+	}
+	if msg.From != nil {
+		entry.OperatorID = msg.From.ID
+		entry.OperatorUsername = msg.From.Username
+		entry.OperatorName = strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName)
+	}
+
+	if err := f.history.Record(ctx, entry); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to record merchant history: chat_id=%d, action=%s, err=%v", msg.Chat.ID, action, err)
+	}
+}
+
+// historyActionNames 变更动作的中文名称
+var historyActionNames = map[string]string{
+	models.MerchantHistoryBind:    "绑定",
+	models.MerchantHistoryUnbind:  "解绑",
+	models.MerchantHistorySwitch:  "切换",
+	models.MerchantHistoryRestore: "恢复",
+	models.MerchantHistoryImport:  "导入",
+}
+
+// formatHistory 格式化变更历史：时间、动作、操作人，以及变更前后的商户号列表和当前商户号
+func formatHistory(entries []*models.MerchantHistory, loc *time.Location) string {
+	if len(entries) == 0 {
+		return "ℹ️ 本群暂无商户号变更记录"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📜 <b>商户号变更历史</b>（最近 %d 条）\n", len(entries)))
+	for i, entry := range entries {
+		action := historyActionNames[entry.Action]
+		if action == "" {
+			action = entry.Action
+		}
+		sb.WriteString(fmt.Sprintf("\n%d. <code>%s</code> %s · %s\n", i+1, entry.CreatedAt.In(loc).Format("2006-01-02 15:04"), action, historyOperatorName(entry)))
+		sb.WriteString(fmt.Sprintf("   商户号：%s → %s\n", historyMerchantList(entry.OldMerchantIDs), historyMerchantList(entry.NewMerchantIDs)))
+		if entry.OldCurrent == entry.NewCurrent {
+			sb.WriteString(fmt.Sprintf("   当前：%s\n", historyMerchant(entry.NewCurrent)))
+		} else {
+			sb.WriteString(fmt.Sprintf("   当前：%s → %s\n", historyMerchant(entry.OldCurrent), historyMerchant(entry.NewCurrent)))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// historyOperatorName 操作人显示名：姓名 + @用户名，均缺失时显示 ID
+func historyOperatorName(entry *models.MerchantHistory) string {
+	name := entry.OperatorName
+	if name == "" {
+		name = fmt.Sprintf("用户 %d", entry.OperatorID)
+	}
+	name = html.EscapeString(name)
+	if entry.OperatorUsername != "" {
+		name += " (@" + html.EscapeString(entry.OperatorUsername) + ")"
+	}
+	return name
+}
+
+func historyMerchantList(ids []int32) string {
+	if len(ids) == 0 {
+		return "无"
+	}
+	return joinMerchantIDs(ids)
+}
+
+func historyMerchant(id int32) string {
+	if id == 0 {
+		return "无"
+	}
+	return strconv.Itoa(int(id))
+}
+
+// StatusLines 「状态」命令片段：已绑定商户号并标记当前商户号
+func (f *Feature) StatusLines(ctx context.Context, group *models.Group) []string {
+	lines := []string{"<b>🏪 商户号</b>"}
+	bound := models.BoundMerchantIDs(group.Settings)
+	if len(bound) == 0 {
+		return append(lines, "未绑定")
+	}
+	return append(lines, strings.Split(formatMerchantList(bound, group.Settings.MerchantID), "\n")...)
+}
+
+// parseMerchantID 校验并解析商户号，失败时返回提示文案
+func parseMerchantID(raw string) (int32, string) {
+	// 验证商户号格式 (纯数字)
+	if !regexp.MustCompile(`^\d+$`).MatchString(raw) {
+		return 0, "❌ 商户号必须为纯数字"
+	}
+
+	merchantID, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || merchantID <= 0 {
+		return 0, "❌ 商户号格式错误"
+	}
+	return int32(merchantID), ""
+}
+
+// resolveMerchant 先按商户号匹配，再按列表序号（从 1 开始）匹配
+func resolveMerchant(bound []int32, arg string) (int32, bool) {
+	value, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	for _, id := range bound {
+		if int64(id) == value {
+			return id, true
+		}
+	}
+	if value >= 1 && value <= int64(len(bound)) {
+		return bound[value-1], true
+	}
+	return 0, false
+}
+
+// formatMerchantList 格式化带序号的商户号列表，并标记当前商户号
+func formatMerchantList(bound []int32, current int32) string {
+	lines := make([]string, 0, len(bound))
+	for idx, id := range bound {
+		line := fmt.Sprintf("%d. %d", idx+1, id)
+		if id == current {
+			line += "（当前）"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func joinMerchantIDs(ids []int32) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(int(id)))
+	}
+	return strings.Join(parts, ", ")
 }
 
 func resp(text string) *types.Response {
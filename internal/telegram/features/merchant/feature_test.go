@@ -0,0 +1,175 @@
+package merchant
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestFeatureMatchesCommands(t *testing.T) {
+	feature := New(nil, nil, nil)
+	for _, text := range []string{"绑定 1001", "解绑", "解绑 1001", "解绑 全部", "切换商户号 2", "商户号", "绑定状态", "商户号历史"} {
+		if !feature.Match(context.Background(), &botModels.Message{Text: text}) {
+			t.Fatalf("expected %q to match", text)
+		}
+	}
+	for _, text := range []string{"解绑接口 1024", "绑定接口 支付宝 1024", "切换商户号", "解绑 abc"} {
+		if feature.Match(context.Background(), &botModels.Message{Text: text}) {
+			t.Fatalf("expected %q not to match", text)
+		}
+	}
+}
+
+func TestFeatureMultipleMerchants(t *testing.T) {
+	groups := &stubGroupService{group: &models.Group{Settings: models.GroupSettings{MerchantID: 1001}}}
+	feature := New(groups, &stubUserService{}, nil)
+
+	run := func(text string) string {
+		t.Helper()
+		msg := &botModels.Message{Text: text, Chat: botModels.Chat{ID: -1}, From: &botModels.User{ID: 1}}
+		resp, handled, err := feature.Process(context.Background(), msg, groups.group)
+		if err != nil || !handled || resp == nil {
+			t.Fatalf("%s: unexpected result handled=%v err=%v", text, handled, err)
+		}
+		return resp.Text
+	}
+
+	if text := run("绑定 1002"); !strings.Contains(text, "当前商户号仍为 1001") {
+		t.Fatalf("expected current merchant to be kept, got %q", text)
+	}
+	run("绑定 1003")
+	assertMerchants(t, groups.group.Settings, 1001, []int32{1001, 1002, 1003})
+
+	if text := run("解绑"); !strings.Contains(text, "解绑 全部") {
+		t.Fatalf("expected bare unbind to require a target, got %q", text)
+	}
+
+	if text := run("切换商户号 2"); !strings.Contains(text, "已切换为: 1002") {
+		t.Fatalf("unexpected switch result: %q", text)
+	}
+	assertMerchants(t, groups.group.Settings, 1002, []int32{1001, 1002, 1003})
+	if text := run("商户号"); !strings.Contains(text, "2. 1002（当前）") {
+		t.Fatalf("expected list to mark current merchant, got %q", text)
+	}
+
+	if text := run("解绑 1002"); !strings.Contains(text, "当前商户号已切换为: 1001") {
+		t.Fatalf("expected current merchant to fall back, got %q", text)
+	}
+	assertMerchants(t, groups.group.Settings, 1001, []int32{1001, 1003})
+
+	run("解绑 全部")
+	assertMerchants(t, groups.group.Settings, 0, nil)
+}
+
+func assertMerchants(t *testing.T, settings models.GroupSettings, current int32, bound []int32) {
+	t.Helper()
+	if settings.MerchantID != current || !slices.Equal(models.BoundMerchantIDs(settings), bound) {
+		t.Fatalf("expected current=%d bound=%v, got current=%d bound=%v", current, bound, settings.MerchantID, settings.MerchantIDs)
+	}
+}
+
+type stubGroupService struct {
+	service.GroupService
+	group *models.Group
+}
+
+func (s *stubGroupService) GetGroupInfo(ctx context.Context, telegramID int64) (*models.Group, error) {
+	return s.group, nil
+}
+
+func (s *stubGroupService) UpdateGroupSettings(ctx context.Context, telegramID int64, settings models.GroupSettings) error {
+	settings.MerchantIDs = models.BoundMerchantIDs(settings)
+	s.group.Settings = settings
+	return nil
+}
+
+type stubUserService struct {
+	service.UserService
+}
+
+func (s *stubUserService) CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	return true, nil
+}
+
+func TestStatusLines(t *testing.T) {
+	f := New(nil, nil, nil)
+	group := &models.Group{Settings: models.GroupSettings{MerchantID: 2002, MerchantIDs: []int32{1001, 2002}}}
+	got := strings.Join(f.StatusLines(context.Background(), group), "|")
+	if expected := "<b>🏪 商户号</b>|1. 1001|2. 2002（当前）"; got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	got = strings.Join(f.StatusLines(context.Background(), &models.Group{}), "|")
+	if expected := "<b>🏪 商户号</b>|未绑定"; got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFeatureRecordsHistory(t *testing.T) {
+	groups := &stubGroupService{group: &models.Group{}}
+	history := &stubHistoryService{}
+	feature := New(groups, &stubUserService{}, history)
+
+	for _, text := range []string{"绑定 1001", "绑定 1002", "切换商户号 1002", "解绑 全部"} {
+		msg := &botModels.Message{Text: text, Chat: botModels.Chat{ID: -1}, From: &botModels.User{ID: 7, FirstName: "Ops", Username: "ops"}}
+		if _, handled, err := feature.Process(context.Background(), msg, groups.group); err != nil || !handled {
+			t.Fatalf("%s: unexpected result handled=%v err=%v", text, handled, err)
+		}
+	}
+
+	if len(history.entries) != 4 {
+		t.Fatalf("expected 4 history entries, got %d", len(history.entries))
+	}
+	second, last := history.entries[1], history.entries[3]
+	if second.Action != models.MerchantHistoryBind || !slices.Equal(second.OldMerchantIDs, []int32{1001}) || !slices.Equal(second.NewMerchantIDs, []int32{1001, 1002}) {
+		t.Fatalf("unexpected bind entry: %+v", second)
+	}
+	if history.entries[2].OldCurrent != 1001 || history.entries[2].NewCurrent != 1002 {
+		t.Fatalf("unexpected switch entry: %+v", history.entries[2])
+	}
+	if last.Action != models.MerchantHistoryUnbind || last.NewCurrent != 0 || len(last.NewMerchantIDs) != 0 || last.OperatorID != 7 || last.OperatorUsername != "ops" {
+		t.Fatalf("unexpected unbind entry: %+v", last)
+	}
+}
+
+func TestFormatHistory(t *testing.T) {
+	entries := []*models.MerchantHistory{{
+		Action:         models.MerchantHistorySwitch,
+		OldMerchantIDs: []int32{1001, 1002},
+		NewMerchantIDs: []int32{1001, 1002},
+		OldCurrent:     1001,
+		NewCurrent:     1002,
+		OperatorID:     7,
+		OperatorName:   "<Ops>",
+		CreatedAt:      time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC),
+	}}
+
+	got := formatHistory(entries, time.UTC)
+	expected := "📜 <b>商户号变更历史</b>（最近 1 条）\n\n1. <code>2025-01-02 03:04</code> 切换 · &lt;Ops&gt;\n   商户号：1001, 1002 → 1001, 1002\n   当前：1001 → 1002"
+	if got != expected {
+		t.Fatalf("unexpected history text:\n%s", got)
+	}
+	if got := formatHistory(nil, time.UTC); !strings.Contains(got, "暂无") {
+		t.Fatalf("expected empty hint, got %q", got)
+	}
+}
+
+type stubHistoryService struct {
+	entries []*models.MerchantHistory
+}
+
+func (s *stubHistoryService) Record(ctx context.Context, entry *models.MerchantHistory) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *stubHistoryService) ListRecent(ctx context.Context, chatID int64, limit int) ([]*models.MerchantHistory, error) {
+	return s.entries, nil
+}
@@ -0,0 +1,225 @@
+package sifang
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/features/types"
+)
+
+const (
+	// exportSuffix 查询命令末尾带该关键字时导出为 CSV 文件
+	exportSuffix = "导出"
+	// withdrawExportPageSize 导出提款明细时每批拉取的条数（上游单页上限）
+	withdrawExportPageSize = 100
+	// withdrawExportMaxPages 最多拉取的批次，避免异常分页导致无限请求
+	withdrawExportMaxPages = 50
+	// exportPageTimeout 单批请求超时
+	exportPageTimeout = 15 * time.Second
+)
+
+// splitExportSuffix 拆出末尾的「导出」，例如「通道账单10月26 导出」
+func splitExportSuffix(text string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasSuffix(trimmed, exportSuffix) {
+		return text, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(trimmed, exportSuffix)), true
+}
+
+// matchExportCommand 判断是否为支持导出的查询命令
+func matchExportCommand(text string) bool {
+	rest, ok := splitExportSuffix(text)
+	if !ok {
+		return false
+	}
+	if _, ok := extractDateSuffix(rest, "通道账单"); ok {
+		return true
+	}
+	_, ok = extractDateSuffix(rest, "提款明细")
+	return ok
+}
+
+// handleExport 处理「通道账单导出」「提款明细导出」，完整结果写成 CSV 文件
+func (f *Feature) handleExport(ctx context.Context, merchantID int64, text string, loc *time.Location) (*types.Response, bool, error) {
+	rest, _ := splitExportSuffix(text)
+	now := time.Now().In(loc)
+
+	if _, ok := extractDateSuffix(rest, "通道账单"); ok {
+		targetDate, err := parseSummaryDate(strings.TrimSpace(strings.TrimPrefix(rest, "通道账单")), now, "通道账单")
+		if err != nil {
+			return wrapResponse(fmt.Sprintf("❌ %v", err)), true, nil
+		}
+		return f.exportChannelSummary(ctx, merchantID, targetDate), true, nil
+	}
+
+	targetDate, err := parseSummaryDate(strings.TrimSpace(strings.TrimPrefix(rest, "提款明细")), now, "提款明细")
+	if err != nil {
+		return wrapResponse(fmt.Sprintf("❌ %v", err)), true, nil
+	}
+	return f.exportWithdrawList(ctx, merchantID, targetDate), true, nil
+}
+
+// exportChannelSummary 导出通道账单（上游一次返回全部通道）
+func (f *Feature) exportChannelSummary(ctx context.Context, merchantID int64, targetDate time.Time) *types.Response {
+	date := targetDate.Format("2006-01-02")
+	items, err := f.paymentService.GetSummaryByDayByChannel(ctx, merchantID, targetDate)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Sifang channel summary export failed: merchant_id=%d, date=%s, err=%v", merchantID, date, err)
+		return wrapResponse(fmt.Sprintf("❌ 导出通道账单失败：%v", err))
+	}
+	if len(items) == 0 {
+		return wrapResponse(fmt.Sprintf("ℹ️ %s 暂无通道账单数据", date))
+	}
+
+	data, err := encodeChannelSummaryCSV(items)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Sifang channel summary export encode failed: merchant_id=%d, err=%v", merchantID, err)
+		return wrapResponse("❌ 生成 CSV 失败")
+	}
+
+	logger.Ctx(ctx).Infof("Sifang channel summary exported: merchant_id=%d, date=%s, channels=%d", merchantID, date, len(items))
+	return &types.Response{
+		Text: fmt.Sprintf("📑 通道账单 - %s（%d 个通道）", date, len(items)),
+		Document: &types.Document{
+			Filename: fmt.Sprintf("channel_summary_%d_%s.csv", merchantID, targetDate.Format("20060102")),
+			Data:     data,
+		},
+	}
+}
+
+// exportWithdrawList 分批拉取当天全部提款记录并导出
+func (f *Feature) exportWithdrawList(ctx context.Context, merchantID int64, targetDate time.Time) *types.Response {
+	date := targetDate.Format("2006-01-02")
+	start := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 0, 0, 0, 0, targetDate.Location())
+	end := start.Add(24*time.Hour - time.Second)
+
+	items, truncated, err := f.fetchAllWithdraws(ctx, merchantID, start, end)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Sifang withdraw export failed: merchant_id=%d, date=%s, fetched=%d, err=%v", merchantID, date, len(items), err)
+		return wrapResponse(fmt.Sprintf("❌ 导出提款明细失败：%v", err))
+	}
+	if len(items) == 0 {
+		return wrapResponse(fmt.Sprintf("ℹ️ %s 暂无提款记录", date))
+	}
+
+	data, err := encodeWithdrawCSV(items)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Sifang withdraw export encode failed: merchant_id=%d, err=%v", merchantID, err)
+		return wrapResponse("❌ 生成 CSV 失败")
+	}
+
+	total := 0.0
+	for _, item := range items {
+		if amount, ok := parseAmountToFloat(item.Amount); ok {
+			total += amount
+		}
+	}
+	caption := fmt.Sprintf("💸 提款明细 - %s（总计 %s｜%d 笔）", date, formatFloat(total), len(items))
+	if truncated {
+		caption += fmt.Sprintf("\n⚠️ 记录过多，仅导出前 %d 笔", len(items))
+	}
+
+	logger.Ctx(ctx).Infof("Sifang withdraw list exported: merchant_id=%d, date=%s, count=%d, truncated=%t", merchantID, date, len(items), truncated)
+	return &types.Response{
+		Text: caption,
+		Document: &types.Document{
+			Filename: fmt.Sprintf("withdraws_%d_%s.csv", merchantID, targetDate.Format("20060102")),
+			Data:     data,
+		},
+	}
+}
+
+// fetchAllWithdraws 按页拉取提款记录，每页单独超时；超过最大批次时返回已拉取部分并标记 truncated
+func (f *Feature) fetchAllWithdraws(ctx context.Context, merchantID int64, start, end time.Time) ([]*paymentservice.Withdraw, bool, error) {
+	items := make([]*paymentservice.Withdraw, 0)
+	for page := 1; page <= withdrawExportMaxPages; page++ {
+		pageCtx, cancel := context.WithTimeout(ctx, exportPageTimeout)
+		list, err := f.paymentService.GetWithdrawList(pageCtx, merchantID, start, end, page, withdrawExportPageSize)
+		cancel()
+		if err != nil {
+			return items, false, fmt.Errorf("第 %d 批拉取失败：%w", page, err)
+		}
+		if list == nil {
+			break
+		}
+		items = append(items, list.Items...)
+
+		// 优先按总页数判断，上游未返回分页信息时以本页不足一整页为结束
+		if list.TotalPages > 0 {
+			if page >= list.TotalPages {
+				return items, false, nil
+			}
+		} else if len(list.Items) < withdrawExportPageSize {
+			return items, false, nil
+		}
+		if page == withdrawExportMaxPages {
+			return items, true, nil
+		}
+	}
+	return items, false, nil
+}
+
+// encodeChannelSummaryCSV 生成通道账单 CSV（带 UTF-8 BOM，Excel 直接打开不乱码）
+func encodeChannelSummaryCSV(items []*paymentservice.SummaryByDayChannel) ([]byte, error) {
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{
+			strings.TrimSpace(item.Date),
+			strings.TrimSpace(item.ChannelName),
+			strings.TrimSpace(item.ChannelCode),
+			emptyFallback(strings.TrimSpace(item.TotalAmount), "0"),
+			emptyFallback(combineAmounts(item.MerchantIncome, item.AgentIncome), "0"),
+			emptyFallback(strings.TrimSpace(item.MerchantIncome), "0"),
+			emptyFallback(strings.TrimSpace(item.AgentIncome), "0"),
+			emptyFallback(strings.TrimSpace(item.OrderCount), "0"),
+			emptyFallback(strings.TrimSpace(item.SuccessCount), "0"),
+		})
+	}
+	return encodeCSV([]string{"日期", "通道名称", "通道编码", "跑量", "成交", "商户收入", "代理收入", "笔数", "成功笔数"}, rows)
+}
+
+// encodeWithdrawCSV 生成提款明细 CSV
+func encodeWithdrawCSV(items []*paymentservice.Withdraw) ([]byte, error) {
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{
+			strings.TrimSpace(item.WithdrawNo),
+			strings.TrimSpace(item.OrderNo),
+			emptyFallback(strings.TrimSpace(item.Amount), "0"),
+			strings.TrimSpace(item.Fee),
+			strings.TrimSpace(item.Status),
+			strings.TrimSpace(item.Channel),
+			strings.TrimSpace(item.CreatedAt),
+			strings.TrimSpace(item.PaidAt),
+		})
+	}
+	return encodeCSV([]string{"提款单号", "订单号", "金额", "手续费", "状态", "通道", "创建时间", "支付时间"}, rows)
+}
+
+// encodeCSV 写入表头与数据行，带 UTF-8 BOM
+func encodeCSV(header []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
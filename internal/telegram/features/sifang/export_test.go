@@ -0,0 +1,106 @@
+package sifang
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// pagedWithdrawService 按页返回提款记录，记录每次请求的页码
+type pagedWithdrawService struct {
+	fakePaymentService
+	total       int
+	reportPages bool
+	pages       []int
+}
+
+func (s *pagedWithdrawService) GetWithdrawList(ctx context.Context, merchantID int64, start, end time.Time, page, pageSize int) (*paymentservice.WithdrawList, error) {
+	s.pages = append(s.pages, page)
+	list := &paymentservice.WithdrawList{Page: page, PageSize: pageSize}
+	if s.reportPages {
+		list.Total = s.total
+		list.TotalPages = (s.total + pageSize - 1) / pageSize
+	}
+	for i := (page - 1) * pageSize; i < s.total && i < page*pageSize; i++ {
+		list.Items = append(list.Items, &paymentservice.Withdraw{WithdrawNo: fmt.Sprintf("W%d", i), Amount: "1.00"})
+	}
+	return list, nil
+}
+
+func TestMatchExportCommand(t *testing.T) {
+	f := &Feature{}
+	for _, text := range []string{"通道账单导出", "通道账单10月26 导出", "提款明细 导出", "提款明细2025/10/31导出 #2024"} {
+		msg := &botModels.Message{Chat: botModels.Chat{Type: "group"}, Text: text}
+		if !f.Match(context.Background(), msg) {
+			t.Fatalf("expected %q to match", text)
+		}
+	}
+	for _, text := range []string{"账单导出", "余额导出", "导出"} {
+		if matchExportCommand(text) {
+			t.Fatalf("expected %q not to be an export command", text)
+		}
+	}
+}
+
+func TestFetchAllWithdrawsPaginates(t *testing.T) {
+	svc := &pagedWithdrawService{total: 250, reportPages: true}
+	f := New(svc, nil, nil, nil)
+
+	items, truncated, err := f.fetchAllWithdraws(context.Background(), 1, time.Now(), time.Now())
+	if err != nil || truncated {
+		t.Fatalf("unexpected result: truncated=%v err=%v", truncated, err)
+	}
+	if len(items) != 250 || len(svc.pages) != 3 {
+		t.Fatalf("expected 250 items in 3 pages, got %d items, pages %v", len(items), svc.pages)
+	}
+
+	// 上游不返回分页信息时，以不足一整页为结束
+	svc = &pagedWithdrawService{total: 200}
+	f = New(svc, nil, nil, nil)
+	items, _, _ = f.fetchAllWithdraws(context.Background(), 1, time.Now(), time.Now())
+	if len(items) != 200 || len(svc.pages) != 3 {
+		t.Fatalf("expected 200 items in 3 pages, got %d items, pages %v", len(items), svc.pages)
+	}
+
+	svc = &pagedWithdrawService{total: withdrawExportPageSize*withdrawExportMaxPages + 1, reportPages: true}
+	f = New(svc, nil, nil, nil)
+	items, truncated, _ = f.fetchAllWithdraws(context.Background(), 1, time.Now(), time.Now())
+	if !truncated || len(items) != withdrawExportPageSize*withdrawExportMaxPages {
+		t.Fatalf("expected truncated export, got %d items truncated=%v", len(items), truncated)
+	}
+}
+
+func TestProcessExportsChannelSummaryAsDocument(t *testing.T) {
+	svc := &fakePaymentService{channelSummaryResp: []*paymentservice.SummaryByDayChannel{
+		{Date: "2025-10-31", ChannelName: "支付宝", ChannelCode: "alipay", TotalAmount: "1000", MerchantIncome: "900", AgentIncome: "50", OrderCount: "10"},
+	}}
+	f := New(svc, nil, nil, nil)
+	group := &models.Group{Settings: models.GroupSettings{MerchantID: 2024, SifangEnabled: true}}
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 1},
+		Text: "通道账单 导出",
+	}
+
+	resp, handled, err := f.Process(context.Background(), msg, group)
+	if err != nil || !handled {
+		t.Fatalf("unexpected result: handled=%v err=%v", handled, err)
+	}
+	if resp == nil || resp.Document == nil {
+		t.Fatalf("expected document response, got %+v", resp)
+	}
+	if !strings.HasPrefix(resp.Document.Filename, "channel_summary_2024_") {
+		t.Fatalf("unexpected filename %s", resp.Document.Filename)
+	}
+	content := string(resp.Document.Data)
+	if !strings.HasPrefix(content, "\ufeff日期,通道名称,通道编码,跑量,成交") || !strings.Contains(content, "2025-10-31,支付宝,alipay,1000,950,900,50,10,0") {
+		t.Fatalf("unexpected csv content:\n%s", content)
+	}
+}
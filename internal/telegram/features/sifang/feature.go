@@ -29,13 +29,18 @@ var (
 	chinaLocation          = mustLoadChinaLocation()
 	dateSuffixRegexp       = regexp.MustCompile(`^[0-9\s./\-年月日号]*$`)
 	googleCodeSuffixRegexp = regexp.MustCompile(`\s+(\d{6})$`)
+	merchantOverrideRegexp = regexp.MustCompile(`\s*[#＃](\d+)$`)
 )
 
 const (
-	SendMoneyConfirmTTL     = 60 * time.Second
-	SendMoneyCallbackPrefix = "sifang:sendmoney:"
-	sendMoneyActionConfirm  = "confirm"
-	sendMoneyActionCancel   = "cancel"
+	SendMoneyConfirmTTL      = 60 * time.Second
+	SendMoneyReviewTTL       = 5 * time.Minute // 大额下发等待双人确认的有效期
+	SendMoneyCallbackPrefix  = "sifang:sendmoney:"
+	sendMoneyActionConfirm   = "confirm"
+	sendMoneyActionCancel    = "cancel"
+	sendMoneyReviewApprovals = 2
+	// sendMoneySettledRetention 已确认/取消的 token 保留时长，需覆盖最长有效期加上过期扫描间隔
+	sendMoneySettledRetention = 2 * SendMoneyReviewTTL
 )
 
 type pendingSendMoney struct {
@@ -45,7 +50,24 @@ type pendingSendMoney struct {
 	merchantID int64
 	amount     float64
 	googleCode string
+	dailyLimit float64
+	loc        *time.Location
 	createdAt  time.Time
+
+	// requiresReview 为 true 时需要两位不同管理员确认，confirmedBy 记录已确认的用户
+	requiresReview bool
+	confirmedBy    map[int64]struct{}
+
+	// linkAccounting 下发成功后联动写一笔出账记账（发起时按群配置确定）
+	linkAccounting bool
+}
+
+// ttl 返回待确认请求的有效期，大额复核请求有效期更长
+func (p *pendingSendMoney) ttl() time.Duration {
+	if p.requiresReview {
+		return SendMoneyReviewTTL
+	}
+	return SendMoneyConfirmTTL
 }
 
 func mustLoadChinaLocation() *time.Location {
@@ -60,16 +82,33 @@ func mustLoadChinaLocation() *time.Location {
 type Feature struct {
 	paymentService paymentservice.Service
 	userService    service.UserService
+	quotaService   service.SendMoneyQuotaService
+	accounting     service.AccountingService
 	mu             sync.Mutex
 	pending        map[string]*pendingSendMoney
+	settled        map[string]time.Time // 已被确认或取消（含执行中）的 token，过期处理据此不覆盖结果提示
+
+	// 谷歌验证码连续失败计数与锁定状态（内存），按群 + 用户区分
+	codeMu       sync.Mutex
+	codeFailures map[string]*googleCodeFailure
+
+	// 通道费率缓存，按商户号区分
+	rateMu sync.Mutex
+	rates  map[int64]*channelRateEntry
+	clock  func() time.Time
 }
 
-// New 创建四方支付功能实例
-func New(paymentSvc paymentservice.Service, userSvc service.UserService) *Feature {
+// New 创建四方支付功能实例，quotaSvc 为空时不做每日下发限额与累计，accountingSvc 为空时不做下发联动记账
+func New(paymentSvc paymentservice.Service, userSvc service.UserService, quotaSvc service.SendMoneyQuotaService, accountingSvc service.AccountingService) *Feature {
 	return &Feature{
 		paymentService: paymentSvc,
 		userService:    userSvc,
+		quotaService:   quotaSvc,
+		accounting:     accountingSvc,
 		pending:        make(map[string]*pendingSendMoney),
+		settled:        make(map[string]time.Time),
+		codeFailures:   make(map[string]*googleCodeFailure),
+		rates:          make(map[int64]*channelRateEntry),
 	}
 }
 
@@ -90,6 +129,21 @@ func (f *Feature) Enabled(ctx context.Context, group *models.Group) bool {
 	return group.Settings.SifangEnabled
 }
 
+// HelpLines 帮助文本
+func (f *Feature) HelpLines() []string {
+	return []string{
+		"<b>四方支付查询</b>",
+		"余额[可选日期] - 查询余额，例如：余额、余额10月26",
+		"账单[可选日期] - 查询日汇总，例如：账单2023/10/26",
+		"通道账单[可选日期] - 查看通道维度汇总，末尾加「导出」发送 CSV 文件",
+		"提款明细[可选日期] - 查看提款记录，末尾加「导出」发送全部记录的 CSV 文件",
+		"费率 - 查看通道费率（缓存 30 分钟），「费率 刷新」强制获取最新",
+		"查询命令末尾追加 <code>#商户号</code> 可临时查询本群绑定的其他商户号，例如：余额 #2025100",
+		"下发 <code>金额</code> [谷歌验证码] - 申请下发，支持表达式和谷歌验证码，需在 60 秒内按钮确认",
+		"每日 00:00:05（群组时区）自动推送昨日账单",
+	}
+}
+
 // Match 支持命令：
 //   - 余额
 //   - 账单 / 账单10月26（可指定日期）
@@ -99,7 +153,7 @@ func (f *Feature) Match(ctx context.Context, msg *botModels.Message) bool {
 		return false
 	}
 
-	text := strings.TrimSpace(msg.Text)
+	text, _ := splitMerchantOverride(strings.TrimSpace(msg.Text))
 	if text == "" {
 		return false
 	}
@@ -120,7 +174,11 @@ func (f *Feature) Match(ctx context.Context, msg *botModels.Message) bool {
 		return true
 	}
 
-	if text == "费率" {
+	if matchExportCommand(text) {
+		return true
+	}
+
+	if _, ok := parseChannelRatesCommand(text); ok {
 		return true
 	}
 
@@ -141,39 +199,56 @@ func (f *Feature) Process(ctx context.Context, msg *botModels.Message, group *mo
 		return nil, false, nil
 	}
 
+	// 群等级与功能开关已由 Manager 按 Enabled/Gate 把关，这里只兜底商户号缺失的脏数据
 	merchantID := int64(group.Settings.MerchantID)
 	if merchantID == 0 {
-		return wrapResponse("ℹ️ 当前群组未绑定商户号，请先使用「绑定 [商户号]」命令"), true, nil
+		return wrapResponse(unboundMerchantHint), true, nil
+	}
+
+	text, override := splitMerchantOverride(strings.TrimSpace(msg.Text))
+	if override != "" {
+		if isSendMoneyCommand(text) {
+			return wrapResponse("❌ 下发不支持临时指定商户号，请先使用「切换商户号」切换当前商户号"), true, nil
+		}
+		resolved, ok := resolveMerchantOverride(group.Settings, override)
+		if !ok {
+			return wrapResponse(fmt.Sprintf("❌ 商户号 %s 未绑定到当前群组，请使用「商户号」查看已绑定列表", override)), true, nil
+		}
+		merchantID = resolved
+	}
+
+	loc := models.GroupLocation(group.Settings)
+	if matchExportCommand(text) {
+		return f.handleExport(ctx, merchantID, text, loc)
 	}
 
-	text := strings.TrimSpace(msg.Text)
 	if suffix, ok := extractDateSuffix(text, "余额"); ok {
-		respText, handled, err := f.handleBalance(ctx, merchantID, suffix)
+		respText, handled, err := f.handleBalance(ctx, merchantID, suffix, loc)
 		return wrapResponse(respText), handled, err
 	}
 
-	if text == "费率" {
-		respText, handled, err := f.handleChannelRates(ctx, merchantID)
+	if refresh, ok := parseChannelRatesCommand(text); ok {
+		respText, handled, err := f.handleChannelRates(ctx, merchantID, refresh, loc)
 		return wrapResponse(respText), handled, err
 	}
 
 	if _, ok := extractDateSuffix(text, "账单"); ok {
-		respText, handled, err := f.handleSummary(ctx, merchantID, text)
+		respText, handled, err := f.handleSummary(ctx, merchantID, text, loc)
 		return wrapResponse(respText), handled, err
 	}
 
 	if _, ok := extractDateSuffix(text, "通道账单"); ok {
-		respText, handled, err := f.handleChannelSummary(ctx, merchantID, text)
+		respText, handled, err := f.handleChannelSummary(ctx, merchantID, text, loc)
 		return wrapResponse(respText), handled, err
 	}
 
 	if _, ok := extractDateSuffix(text, "提款明细"); ok {
-		respText, handled, err := f.handleWithdrawList(ctx, merchantID, text)
+		respText, handled, err := f.handleWithdrawList(ctx, merchantID, text, loc)
 		return wrapResponse(respText), handled, err
 	}
 
 	if isSendMoneyCommand(text) {
-		return f.handleSendMoney(ctx, msg, merchantID, text)
+		return f.handleSendMoney(ctx, msg, merchantID, text, group.Settings)
 	}
 
 	return nil, false, nil
@@ -184,8 +259,8 @@ func (f *Feature) Priority() int {
 	return 25
 }
 
-func (f *Feature) handleBalance(ctx context.Context, merchantID int64, rawSuffix string) (string, bool, error) {
-	now := time.Now().In(chinaLocation)
+func (f *Feature) handleBalance(ctx context.Context, merchantID int64, rawSuffix string, loc *time.Location) (string, bool, error) {
+	now := time.Now().In(loc)
 	targetDate, err := parseBalanceDate(rawSuffix, now)
 	if err != nil {
 		return fmt.Sprintf("❌ %v", err), true, nil
@@ -200,11 +275,11 @@ func (f *Feature) handleBalance(ctx context.Context, merchantID int64, rawSuffix
 
 	balance, err := f.paymentService.GetBalance(ctx, merchantID, historyDays)
 	if err != nil {
-		logger.L().Errorf("Sifang balance query failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, err)
+		logger.Ctx(ctx).Errorf("Sifang balance query failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, err)
 		return fmt.Sprintf("❌ 查询余额失败：%v", err), true, nil
 	}
 	if balance == nil {
-		logger.L().Warnf("Sifang balance query returned empty result: merchant_id=%d, history_days=%d", merchantID, historyDays)
+		logger.Ctx(ctx).Warnf("Sifang balance query returned empty result: merchant_id=%d, history_days=%d", merchantID, historyDays)
 		return "ℹ️ 暂未取得余额数据，请稍后重试", true, nil
 	}
 
@@ -219,13 +294,13 @@ func (f *Feature) handleBalance(ctx context.Context, merchantID int64, rawSuffix
 		merchant = strconv.FormatInt(merchantID, 10)
 	}
 
-	logger.L().Infof("Sifang balance queried: merchant_id=%s history_days=%d date=%s", merchant, historyDays, targetDate.Format("2006-01-02"))
-	return amount, true, nil
+	logger.Ctx(ctx).Infof("Sifang balance queried: merchant_id=%s history_days=%d date=%s", merchant, historyDays, targetDate.Format("2006-01-02"))
+	return formatBalanceAmount(amount), true, nil
 }
 
-func (f *Feature) handleSummary(ctx context.Context, merchantID int64, text string) (string, bool, error) {
+func (f *Feature) handleSummary(ctx context.Context, merchantID int64, text string, loc *time.Location) (string, bool, error) {
 	dateText := strings.TrimSpace(strings.TrimPrefix(text, "账单"))
-	now := time.Now().In(chinaLocation)
+	now := time.Now().In(loc)
 	targetDate, err := parseSummaryDate(dateText, now, "账单")
 	if err != nil {
 		return fmt.Sprintf("❌ %v", err), true, nil
@@ -239,10 +314,10 @@ func (f *Feature) handleSummary(ctx context.Context, merchantID int64, text stri
 	return message, true, nil
 }
 
-// BuildSummaryMessage 构建指定日期的账单消息
+// BuildSummaryMessage 构建指定日期的账单消息，日期边界按 targetDate 所在时区计算
 func (f *Feature) BuildSummaryMessage(ctx context.Context, merchantID int64, targetDate time.Time) (string, error) {
-	now := time.Now().In(chinaLocation)
-	return f.buildSummaryMessage(ctx, merchantID, targetDate.In(chinaLocation), now)
+	now := time.Now().In(targetDate.Location())
+	return f.buildSummaryMessage(ctx, merchantID, targetDate, now)
 }
 
 func (f *Feature) buildSummaryMessage(ctx context.Context, merchantID int64, targetDate, now time.Time) (string, error) {
@@ -250,12 +325,12 @@ func (f *Feature) buildSummaryMessage(ctx context.Context, merchantID int64, tar
 
 	summary, err := f.paymentService.GetSummaryByDay(ctx, merchantID, targetDate)
 	if err != nil {
-		logger.L().Errorf("Sifang summary query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
+		logger.Ctx(ctx).Errorf("Sifang summary query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
 		return "", fmt.Errorf("查询账单失败：%w", err)
 	}
 
 	if summary == nil {
-		return fmt.Sprintf("ℹ️ %s 暂无账单数据", targetDate.Format("2006-01-02")), nil
+		return fmt.Sprintf("ℹ️ %s 暂无账单数据", formatLocalDate(targetDate.Format("2006-01-02"))), nil
 	}
 
 	if strings.TrimSpace(summary.Date) == "" {
@@ -266,19 +341,19 @@ func (f *Feature) buildSummaryMessage(ctx context.Context, merchantID int64, tar
 	balanceAmount, balanceErr := f.queryBalanceAmount(ctx, merchantID, historyDays)
 	withdrawMessage, withdrawErr := f.queryWithdrawMessage(ctx, merchantID, targetDate)
 
-	logger.L().Infof("Sifang summary queried: merchant_id=%d, date=%s", merchantID, summary.Date)
+	logger.Ctx(ctx).Infof("Sifang summary queried: merchant_id=%d, date=%s", merchantID, summary.Date)
 	message := formatSummaryMessage(summary)
 
 	if withdrawErr != nil {
-		logger.L().Errorf("Sifang withdraw list in summary failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), withdrawErr)
+		logger.Ctx(ctx).Errorf("Sifang withdraw list in summary failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), withdrawErr)
 	} else if withdrawMessage != "" {
 		message = fmt.Sprintf("%s\n\n%s", message, withdrawMessage)
 	}
 
 	if balanceErr != nil {
-		logger.L().Errorf("Sifang balance in summary failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, balanceErr)
+		logger.Ctx(ctx).Errorf("Sifang balance in summary failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, balanceErr)
 	} else if balanceAmount != "" {
-		message = fmt.Sprintf("%s\n\n余额：%s", message, balanceAmount)
+		message = fmt.Sprintf("%s\n\n余额：%s", message, formatBalanceAmount(balanceAmount))
 	}
 
 	return message, nil
@@ -405,31 +480,34 @@ func calculateHistoryDays(target, now time.Time) int {
 	return days
 }
 
+// formatSummaryMessage 账单消息：本地化日期标题 + 跑量/成交/笔数等宽表格
 func formatSummaryMessage(summary *paymentservice.SummaryByDay) string {
-	var sb strings.Builder
-
 	date := strings.TrimSpace(summary.Date)
 	if date == "" {
 		date = "-"
 	}
-	sb.WriteString(fmt.Sprintf("📑 账单 - %s\n", html.EscapeString(date)))
+	title := fmt.Sprintf("📑 <b>账单</b> · %s", html.EscapeString(formatLocalDate(date)))
 
+	var rows []amountRow
 	if value := strings.TrimSpace(summary.TotalAmount); value != "" {
-		sb.WriteString(fmt.Sprintf("跑量：%s\n", html.EscapeString(value)))
+		rows = append(rows, amountRow{label: "跑量", value: formatAmountText(value)})
 	}
 	if combinedIncome := combineAmounts(summary.MerchantIncome, summary.AgentIncome); combinedIncome != "" {
-		sb.WriteString(fmt.Sprintf("成交：%s\n", html.EscapeString(combinedIncome)))
+		rows = append(rows, amountRow{label: "成交", value: formatAmountText(combinedIncome)})
 	}
 	if value := strings.TrimSpace(summary.OrderCount); value != "" {
-		sb.WriteString(fmt.Sprintf("笔数：%s\n", html.EscapeString(value)))
+		rows = append(rows, amountRow{label: "笔数", value: formatCountText(value)})
+	}
+	if len(rows) == 0 {
+		return title
 	}
 
-	return strings.TrimRight(sb.String(), "\n")
+	return title + "\n" + formatAmountTable(rows, amountColumnWidth(rows))
 }
 
-func (f *Feature) handleChannelSummary(ctx context.Context, merchantID int64, text string) (string, bool, error) {
+func (f *Feature) handleChannelSummary(ctx context.Context, merchantID int64, text string, loc *time.Location) (string, bool, error) {
 	dateText := strings.TrimSpace(strings.TrimPrefix(text, "通道账单"))
-	now := time.Now().In(chinaLocation)
+	now := time.Now().In(loc)
 	targetDate, err := parseSummaryDate(dateText, now, "通道账单")
 	if err != nil {
 		return fmt.Sprintf("❌ %v", err), true, nil
@@ -437,15 +515,15 @@ func (f *Feature) handleChannelSummary(ctx context.Context, merchantID int64, te
 
 	items, err := f.paymentService.GetSummaryByDayByChannel(ctx, merchantID, targetDate)
 	if err != nil {
-		logger.L().Errorf("Sifang channel summary query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
+		logger.Ctx(ctx).Errorf("Sifang channel summary query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
 		return fmt.Sprintf("❌ 查询通道账单失败：%v", err), true, nil
 	}
 
 	if len(items) == 0 {
-		return fmt.Sprintf("ℹ️ %s 暂无通道账单数据", targetDate.Format("2006-01-02")), true, nil
+		return fmt.Sprintf("ℹ️ %s 暂无通道账单数据", formatLocalDate(targetDate.Format("2006-01-02"))), true, nil
 	}
 
-	logger.L().Infof("Sifang channel summary queried: merchant_id=%d, date=%s, channels=%d", merchantID, targetDate.Format("2006-01-02"), len(items))
+	logger.Ctx(ctx).Infof("Sifang channel summary queried: merchant_id=%d, date=%s, channels=%d", merchantID, targetDate.Format("2006-01-02"), len(items))
 
 	message := formatChannelSummaryMessage(targetDate.Format("2006-01-02"), items)
 
@@ -454,29 +532,60 @@ func (f *Feature) handleChannelSummary(ctx context.Context, merchantID int64, te
 	withdrawMessage, withdrawErr := f.queryWithdrawMessage(ctx, merchantID, targetDate)
 
 	if withdrawErr != nil {
-		logger.L().Errorf("Sifang withdraw list in channel summary failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), withdrawErr)
+		logger.Ctx(ctx).Errorf("Sifang withdraw list in channel summary failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), withdrawErr)
 	} else if withdrawMessage != "" {
 		message = fmt.Sprintf("%s\n\n%s", message, withdrawMessage)
 	}
 
 	if balanceErr != nil {
-		logger.L().Errorf("Sifang balance in channel summary failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, balanceErr)
+		logger.Ctx(ctx).Errorf("Sifang balance in channel summary failed: merchant_id=%d, history_days=%d, err=%v", merchantID, historyDays, balanceErr)
 	} else if balanceAmount != "" {
-		message = fmt.Sprintf("%s\n\n余额：%s", message, balanceAmount)
+		message = fmt.Sprintf("%s\n\n余额：%s", message, formatBalanceAmount(balanceAmount))
 	}
 
 	return message, true, nil
 }
 
+// formatChannelSummaryMessage 通道账单消息：合计行加粗，各通道一张等宽表格，所有表格数值列同宽
 func formatChannelSummaryMessage(date string, items []*paymentservice.SummaryByDayChannel) string {
 	if len(items) == 0 {
-		return fmt.Sprintf("ℹ️ %s 暂无通道账单数据", html.EscapeString(date))
+		return fmt.Sprintf("ℹ️ %s 暂无通道账单数据", html.EscapeString(formatLocalDate(date)))
 	}
 
+	var totalVolume, totalIncome, totalCount float64
+	tables := make([][]amountRow, 0, len(items))
+	var allRows []amountRow
+	for _, item := range items {
+		volume := emptyFallback(strings.TrimSpace(item.TotalAmount), "0")
+		combined := emptyFallback(combineAmounts(item.MerchantIncome, item.AgentIncome), "0")
+		count := emptyFallback(strings.TrimSpace(item.OrderCount), "0")
+
+		if value, ok := parseAmountToFloat(volume); ok {
+			totalVolume += value
+		}
+		if value, ok := parseAmountToFloat(combined); ok {
+			totalIncome += value
+		}
+		if value, ok := parseAmountToFloat(count); ok {
+			totalCount += value
+		}
+
+		rows := []amountRow{
+			{label: "跑量", value: formatAmountText(volume)},
+			{label: "成交", value: formatAmountText(combined)},
+			{label: "笔数", value: formatCountText(count)},
+		}
+		tables = append(tables, rows)
+		allRows = append(allRows, rows...)
+	}
+	width := amountColumnWidth(allRows)
+
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("📑 通道账单 - %s\n", html.EscapeString(date)))
+	sb.WriteString(fmt.Sprintf("📑 <b>通道账单</b> · %s\n", html.EscapeString(formatLocalDate(date))))
+	sb.WriteString(fmt.Sprintf("合计：跑量 <b>%s</b>｜成交 <b>%s</b>｜<b>%s</b> 笔\n",
+		formatThousands(totalVolume, 2), formatThousands(totalIncome, 2), formatThousands(totalCount, 0)))
 
-	for _, item := range items {
+	for i, item := range items {
 		name := strings.TrimSpace(item.ChannelName)
 		code := strings.TrimSpace(item.ChannelCode)
 
@@ -491,24 +600,7 @@ func formatChannelSummaryMessage(date string, items []*paymentservice.SummaryByD
 		default:
 			sb.WriteString("-\n")
 		}
-
-		volume := strings.TrimSpace(item.TotalAmount)
-		if volume == "" {
-			volume = "0"
-		}
-		sb.WriteString(fmt.Sprintf("跑量：%s\n", html.EscapeString(volume)))
-
-		combined := combineAmounts(item.MerchantIncome, item.AgentIncome)
-		if combined == "" {
-			combined = "0"
-		}
-		sb.WriteString(fmt.Sprintf("成交：%s\n", html.EscapeString(combined)))
-
-		count := strings.TrimSpace(item.OrderCount)
-		if count == "" {
-			count = "0"
-		}
-		sb.WriteString(fmt.Sprintf("笔数：%s\n", html.EscapeString(count)))
+		sb.WriteString(formatAmountTable(tables[i], width) + "\n")
 	}
 
 	return strings.TrimRight(sb.String(), "\n")
@@ -521,9 +613,9 @@ func emptyFallback(value, fallback string) string {
 	return value
 }
 
-func (f *Feature) handleWithdrawList(ctx context.Context, merchantID int64, text string) (string, bool, error) {
+func (f *Feature) handleWithdrawList(ctx context.Context, merchantID int64, text string, loc *time.Location) (string, bool, error) {
 	dateText := strings.TrimSpace(strings.TrimPrefix(text, "提款明细"))
-	now := time.Now().In(chinaLocation)
+	now := time.Now().In(loc)
 	targetDate, err := parseSummaryDate(dateText, now, "提款明细")
 	if err != nil {
 		return fmt.Sprintf("❌ %v", err), true, nil
@@ -534,90 +626,111 @@ func (f *Feature) handleWithdrawList(ctx context.Context, merchantID int64, text
 
 	list, err := f.paymentService.GetWithdrawList(ctx, merchantID, start, end, 1, 10)
 	if err != nil {
-		logger.L().Errorf("Sifang withdraw list query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
+		logger.Ctx(ctx).Errorf("Sifang withdraw list query failed: merchant_id=%d, date=%s, err=%v", merchantID, targetDate.Format("2006-01-02"), err)
 		return fmt.Sprintf("❌ 查询提款明细失败：%v", err), true, nil
 	}
 
 	message := formatWithdrawListMessage(targetDate.Format("2006-01-02"), list)
-	logger.L().Infof("Sifang withdraw list queried: merchant_id=%d, date=%s, count=%d", merchantID, targetDate.Format("2006-01-02"), len(list.Items))
+	logger.Ctx(ctx).Infof("Sifang withdraw list queried: merchant_id=%d, date=%s, count=%d", merchantID, targetDate.Format("2006-01-02"), len(list.Items))
 	return message, true, nil
 }
 
+// formatWithdrawListMessage 提款明细：总计与笔数加粗，逐笔「时间 金额」放入等宽表格右对齐
+// 超长列表由发送层按行分片，<pre> 会在下一片重新打开，不会破坏对齐
 func formatWithdrawListMessage(date string, list *paymentservice.WithdrawList) string {
-	var sb strings.Builder
+	title := "💸 <b>提款明细</b>"
+	if len(list.Items) == 0 {
+		return fmt.Sprintf("%s\n暂无提款记录", title)
+	}
 
 	totalAmount := 0.0
-	itemCount := 0
+	rows := make([]amountRow, 0, len(list.Items))
 	for _, item := range list.Items {
 		if amount, ok := parseAmountToFloat(item.Amount); ok {
 			totalAmount += amount
 		}
-		itemCount++
-	}
-
-	title := "💸 提款明细"
 
-	if itemCount == 0 {
-		return fmt.Sprintf("%s\n暂无提款记录", title)
-	}
-
-	sb.WriteString(fmt.Sprintf("%s（总计 %s｜%d 笔）\n", title, html.EscapeString(formatFloat(totalAmount)), itemCount))
-
-	for _, item := range list.Items {
-		created := strings.TrimSpace(item.CreatedAt)
-		timePart := extractTime(created)
+		timePart := extractTime(strings.TrimSpace(item.CreatedAt))
 		if timePart == "" {
 			timePart = "--:--:--"
 		}
-
-		amount := strings.TrimSpace(item.Amount)
-		if amount == "" {
-			amount = "0"
-		}
-
-		sb.WriteString(fmt.Sprintf("%s      %s\n", html.EscapeString(timePart), html.EscapeString(amount)))
+		amount := emptyFallback(strings.TrimSpace(item.Amount), "0")
+		rows = append(rows, amountRow{label: timePart, value: formatAmountText(amount)})
 	}
 
-	return strings.TrimRight(sb.String(), "\n")
+	header := fmt.Sprintf("%s（总计 <b>%s</b>｜<b>%d</b> 笔）", title, formatThousands(totalAmount, 2), len(rows))
+	return header + "\n" + formatAmountTable(rows, amountColumnWidth(rows))
 }
 
-func (f *Feature) handleSendMoney(ctx context.Context, msg *botModels.Message, merchantID int64, text string) (*types.Response, bool, error) {
+func (f *Feature) handleSendMoney(ctx context.Context, msg *botModels.Message, merchantID int64, text string, settings models.GroupSettings) (*types.Response, bool, error) {
 	if f.userService == nil {
-		logger.L().Error("Sifang send money: user service is nil")
+		logger.Ctx(ctx).Error("Sifang send money: user service is nil")
 		return wrapResponse("❌ 未配置管理员校验服务，请联系管理员"), true, nil
 	}
 
-	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID)
+	// 下发直接动用资金，只认可全局管理员，群级管理员不可用
+	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID, 0)
 	if err != nil {
-		logger.L().Errorf("Sifang send money admin check failed: user_id=%d, err=%v", msg.From.ID, err)
+		logger.Ctx(ctx).Errorf("Sifang send money admin check failed: user_id=%d, err=%v", msg.From.ID, err)
 		return wrapResponse("❌ 权限检查失败，请稍后重试"), true, nil
 	}
 	if !isAdmin {
-		logger.L().Warnf("Sifang send money unauthorized: user_id=%d, chat_id=%d", msg.From.ID, msg.Chat.ID)
+		logger.Ctx(ctx).Warnf("Sifang send money unauthorized: user_id=%d, chat_id=%d", msg.From.ID, msg.Chat.ID)
 		return wrapResponse("❌ 仅管理员可以下发"), true, nil
 	}
 
+	if until := f.googleCodeLockedUntil(msg.Chat.ID, msg.From.ID, time.Now()); !until.IsZero() {
+		logger.Ctx(ctx).Warnf("Sifang send money rejected, google code locked: chat_id=%d, user_id=%d, until=%s", msg.Chat.ID, msg.From.ID, until.Format(time.RFC3339))
+		return wrapResponse("🔒 " + formatGoogleCodeLocked(until, models.GroupLocation(settings))), true, nil
+	}
+
 	payload := strings.TrimSpace(strings.TrimPrefix(text, "下发"))
-	amount, googleCode, parseErr := parseSendMoneyPayload(payload)
+	amount, expression, googleCode, parseErr := parseSendMoneyPayload(payload)
 	if parseErr != nil {
 		return wrapResponse(fmt.Sprintf("❌ %v", parseErr)), true, nil
 	}
 
-	pending, err := f.createPendingSend(msg.Chat.ID, msg.From.ID, merchantID, amount, googleCode)
+	dailyLimit := settings.SendMoneyDailyLimit
+	if dailyLimit > 0 && amount > dailyLimit {
+		return wrapResponse(fmt.Sprintf("❌ 下发金额超过每日限额 %s 元", html.EscapeString(formatFloat(dailyLimit)))), true, nil
+	}
+
+	reviewThreshold := settings.SendMoneyReviewThreshold
+	pending, err := f.createPendingSend(&pendingSendMoney{
+		chatID:         msg.Chat.ID,
+		userID:         msg.From.ID,
+		merchantID:     merchantID,
+		amount:         amount,
+		googleCode:     googleCode,
+		dailyLimit:     dailyLimit,
+		loc:            models.GroupLocation(settings),
+		requiresReview: reviewThreshold > 0 && amount > reviewThreshold,
+		linkAccounting: settings.SendMoneyAccountingLink && settings.AccountingEnabled,
+	})
 	if err != nil {
-		logger.L().Errorf("Sifang create pending send failed: chat_id=%d, user_id=%d, err=%v", msg.Chat.ID, msg.From.ID, err)
+		logger.Ctx(ctx).Errorf("Sifang create pending send failed: chat_id=%d, user_id=%d, err=%v", msg.Chat.ID, msg.From.ID, err)
 		return wrapResponse("❌ 创建下发确认状态失败，请稍后重试"), true, nil
 	}
 
 	merchantText := strconv.FormatInt(merchantID, 10)
 	message := fmt.Sprintf("是否确认下发 %s 元 | %s", html.EscapeString(formatFloat(amount)), html.EscapeString(merchantText))
+	if expression != "" {
+		// 表达式下发先展示求值结果，确认的是算好的金额而不是原始表达式
+		message = fmt.Sprintf("🧮 %s = %s\n即将下发：%s 元 | %s\n请核对计算结果后确认",
+			html.EscapeString(expression), html.EscapeString(formatFloat(amount)),
+			html.EscapeString(formatFloat(amount)), html.EscapeString(merchantText))
+	}
 	if googleCode != "" {
 		message += "\n🔐 将附带当前谷歌验证码"
 	}
+	if pending.requiresReview {
+		message += fmt.Sprintf("\n⚠️ 大额下发（超过 %s 元），需 %d 位不同管理员分别点击确认",
+			html.EscapeString(formatFloat(reviewThreshold)), sendMoneyReviewApprovals)
+	}
 
 	markup := buildSendMoneyKeyboard(pending.token)
 
-	logger.L().Infof("Sifang send money pending confirmation: merchant_id=%d, user_id=%d, amount=%.2f, token=%s", merchantID, msg.From.ID, amount, pending.token)
+	logger.Ctx(ctx).Infof("Sifang send money pending confirmation: merchant_id=%d, user_id=%d, amount=%.2f, expression=%q, token=%s", merchantID, msg.From.ID, amount, expression, pending.token)
 
 	return &types.Response{
 		Text:        message,
@@ -625,10 +738,12 @@ func (f *Feature) handleSendMoney(ctx context.Context, msg *botModels.Message, m
 	}, true, nil
 }
 
-func parseSendMoneyPayload(raw string) (float64, string, error) {
+// parseSendMoneyPayload 解析下发金额与可选谷歌验证码；金额为表达式时复用 calculator 求值，
+// 并返回原始表达式供确认消息展示（纯数字时为空）
+func parseSendMoneyPayload(raw string) (float64, string, string, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
-		return 0, "", fmt.Errorf("下发金额不能为空")
+		return 0, "", "", fmt.Errorf("下发金额不能为空")
 	}
 
 	googleCode := ""
@@ -638,36 +753,38 @@ func parseSendMoneyPayload(raw string) (float64, string, error) {
 	}
 
 	if raw == "" {
-		return 0, "", fmt.Errorf("下发金额不能为空")
+		return 0, "", "", fmt.Errorf("下发金额不能为空")
 	}
 
 	var (
-		amount float64
-		err    error
+		amount     float64
+		expression string
+		err        error
 	)
 
 	if calculator.IsMathExpression(raw) {
 		amount, err = calculator.Calculate(raw)
 		if err != nil {
-			return 0, "", fmt.Errorf("金额计算失败：%v", err)
+			return 0, "", "", fmt.Errorf("金额表达式无效：%v", err)
 		}
+		expression = raw
 	} else {
 		amount, err = strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
 		if err != nil {
-			return 0, "", fmt.Errorf("金额格式错误")
+			return 0, "", "", fmt.Errorf("金额格式错误")
 		}
 	}
 
 	if math.IsNaN(amount) || math.IsInf(amount, 0) {
-		return 0, "", fmt.Errorf("金额计算结果异常")
+		return 0, "", "", fmt.Errorf("金额计算结果异常")
 	}
 
 	amount = roundToTwoDecimals(amount)
 	if amount <= 0 {
-		return 0, "", fmt.Errorf("下发金额必须大于 0")
+		return 0, "", "", fmt.Errorf("下发金额必须大于 0")
 	}
 
-	return amount, googleCode, nil
+	return amount, expression, googleCode, nil
 }
 
 func roundToTwoDecimals(value float64) float64 {
@@ -790,6 +907,30 @@ func isValidDateSuffix(raw string) bool {
 	return dateSuffixRegexp.MatchString(trimmed)
 }
 
+// splitMerchantOverride 拆出末尾的「#商户号」，用于查询命令临时指定商户号
+// 下发命令末尾的谷歌验证码不带 #，不会被误识别
+func splitMerchantOverride(text string) (string, string) {
+	match := merchantOverrideRegexp.FindStringSubmatchIndex(text)
+	if match == nil {
+		return text, ""
+	}
+	return strings.TrimSpace(text[:match[0]]), text[match[2]:match[3]]
+}
+
+// resolveMerchantOverride 校验临时指定的商户号已绑定到当前群组
+func resolveMerchantOverride(settings models.GroupSettings, raw string) (int64, bool) {
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	for _, id := range models.BoundMerchantIDs(settings) {
+		if int64(id) == value {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
 func isSendMoneyCommand(text string) bool {
 	if !strings.HasPrefix(text, "下发") {
 		return false
@@ -798,19 +939,16 @@ func isSendMoneyCommand(text string) bool {
 	return payload != ""
 }
 
-func (f *Feature) createPendingSend(chatID, userID, merchantID int64, amount float64, googleCode string) (*pendingSendMoney, error) {
+// createPendingSend 为下发请求分配 token 并登记为待确认状态
+func (f *Feature) createPendingSend(pending *pendingSendMoney) (*pendingSendMoney, error) {
 	token, err := generateToken()
 	if err != nil {
 		return nil, err
 	}
-	pending := &pendingSendMoney{
-		token:      token,
-		chatID:     chatID,
-		userID:     userID,
-		merchantID: merchantID,
-		amount:     amount,
-		googleCode: googleCode,
-		createdAt:  time.Now(),
+	pending.token = token
+	pending.createdAt = time.Now()
+	if pending.requiresReview {
+		pending.confirmedBy = make(map[int64]struct{}, sendMoneyReviewApprovals)
 	}
 
 	f.mu.Lock()
@@ -840,25 +978,79 @@ func (f *Feature) getPendingByToken(token string) (*pendingSendMoney, bool) {
 	return pending, ok
 }
 
-func (f *Feature) deletePending(token string) {
+// takePending 将仍有效的待确认下发移出列表并标记为已处理，与 ExpirePending 互斥，保证只有一方生效
+func (f *Feature) takePending(token string) bool {
 	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cleanupExpiredLocked()
+	if _, ok := f.pending[token]; !ok {
+		return false
+	}
 	delete(f.pending, token)
-	f.mu.Unlock()
+	f.markSettledLocked(token)
+	return true
 }
 
-func (f *Feature) cleanupExpiredLocked() {
-	if len(f.pending) == 0 {
-		return
+// markSettledLocked 记录已被确认或取消的 token
+func (f *Feature) markSettledLocked(token string) {
+	if f.settled == nil {
+		f.settled = make(map[string]time.Time)
 	}
+	f.settled[token] = time.Now()
+}
+
+func (f *Feature) cleanupExpiredLocked() {
 	now := time.Now()
+	for token, settledAt := range f.settled {
+		if now.Sub(settledAt) > sendMoneySettledRetention {
+			delete(f.settled, token)
+		}
+	}
 	for token, pending := range f.pending {
-		if now.Sub(pending.createdAt) > SendMoneyConfirmTTL {
+		if now.Sub(pending.createdAt) > pending.ttl() {
 			delete(f.pending, token)
 		}
 	}
 }
 
-// ExpirePending 在确认超时后删除待处理请求
+// SweepExpiredPending 移除超过有效期 grace 以上的待确认下发，返回移除数与剩余数
+// 留出 grace 让确认超时的定时器先编辑过期提示，周期清理只回收被遗漏的记录
+func (f *Feature) SweepExpiredPending(now time.Time, grace time.Duration) (removed, remaining int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for token, pending := range f.pending {
+		if now.Sub(pending.createdAt) > pending.ttl()+grace {
+			delete(f.pending, token)
+			removed++
+		}
+	}
+	for token, settledAt := range f.settled {
+		if now.Sub(settledAt) > sendMoneySettledRetention {
+			delete(f.settled, token)
+		}
+	}
+	return removed, len(f.pending)
+}
+
+// HasPending 请求是否仍在待确认列表中（含已超时但尚未清理的请求）
+func (f *Feature) HasPending(token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.pending[token]
+	return ok
+}
+
+// IsSettled 请求是否已被确认或取消（下发可能仍在执行），此时不应再提示失效
+func (f *Feature) IsSettled(token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.settled[token]
+	return ok
+}
+
+// ExpirePending 在确认超时后删除待处理请求，只有本次调用把请求移为过期时返回 true
 func (f *Feature) ExpirePending(token string) bool {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -868,7 +1060,7 @@ func (f *Feature) ExpirePending(token string) bool {
 		return false
 	}
 
-	if time.Since(pending.createdAt) < SendMoneyConfirmTTL {
+	if time.Since(pending.createdAt) < pending.ttl() {
 		return false
 	}
 
@@ -877,6 +1069,43 @@ func (f *Feature) ExpirePending(token string) bool {
 	return true
 }
 
+// PendingTTL 返回待确认下发的有效期及是否为大额复核请求
+func (f *Feature) PendingTTL(token string) (time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending, ok := f.pending[token]
+	if !ok {
+		return SendMoneyConfirmTTL, false
+	}
+	return pending.ttl(), pending.requiresReview
+}
+
+// approveReview 记录复核确认；集齐确认人数时将请求移出待确认列表，保证只执行一次
+func (f *Feature) approveReview(token string, userID int64) (count int, duplicate, ready, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cleanupExpiredLocked()
+	pending, exists := f.pending[token]
+	if !exists {
+		return 0, false, false, false
+	}
+
+	if _, confirmed := pending.confirmedBy[userID]; confirmed {
+		return len(pending.confirmedBy), true, false, true
+	}
+	pending.confirmedBy[userID] = struct{}{}
+
+	count = len(pending.confirmedBy)
+	if count >= sendMoneyReviewApprovals {
+		delete(f.pending, token)
+		f.markSettledLocked(token)
+		return count, false, true, true
+	}
+	return count, false, false, true
+}
+
 func generateToken() (string, error) {
 	b := make([]byte, 8)
 	if _, err := rand.Read(b); err != nil {
@@ -914,6 +1143,7 @@ type SendMoneyCallbackResult struct {
 	Markup     botModels.ReplyMarkup
 	Answer     string
 	ShowAlert  bool
+	Finished   bool // 下发请求已结束（已执行、已取消或已过期），不再需要过期处理
 }
 
 // HandleSendMoneyCallback 处理确认/取消回调
@@ -925,11 +1155,16 @@ func (f *Feature) HandleSendMoneyCallback(ctx context.Context, query *botModels.
 	pending, ok := f.getPendingByToken(token)
 	if !ok {
 		result.ShouldEdit = true
+		result.Finished = true
 		result.Text = "下发请求已过期"
 		result.Answer = "操作已过期"
 		return result, nil
 	}
 
+	if action == sendMoneyActionConfirm && pending.requiresReview {
+		return f.handleReviewConfirm(ctx, query, token, pending, result)
+	}
+
 	if query.From.ID != pending.userID {
 		result.ShouldEdit = false
 		result.Answer = "仅原管理员可以操作此下发"
@@ -937,53 +1172,243 @@ func (f *Feature) HandleSendMoneyCallback(ctx context.Context, query *botModels.
 		return result, nil
 	}
 
+	// 确认与取消都需先从待确认列表取出请求，已被过期处理或另一次点击取走时按过期处理
+	if (action == sendMoneyActionCancel || action == sendMoneyActionConfirm) && !f.takePending(token) {
+		result.ShouldEdit = true
+		result.Finished = true
+		result.Text = "下发请求已过期"
+		result.Answer = "操作已过期"
+		return result, nil
+	}
+
 	switch action {
 	case sendMoneyActionCancel:
-		f.deletePending(token)
 		result.ShouldEdit = true
+		result.Finished = true
 		merchantText := strconv.FormatInt(pending.merchantID, 10)
 		result.Text = fmt.Sprintf("已取消下发 %s 元给商户 %s", html.EscapeString(formatFloat(pending.amount)), html.EscapeString(merchantText))
 		result.Answer = "已取消"
 		return result, nil
 	case sendMoneyActionConfirm:
-		f.deletePending(token)
-		opts := paymentservice.SendMoneyOptions{GoogleCode: pending.googleCode}
-		sendResult, err := f.paymentService.SendMoney(ctx, pending.merchantID, pending.amount, opts)
+		return f.executeSendMoney(ctx, pending, result), nil
+	default:
+		result.ShouldEdit = false
+		result.Answer = "未知操作"
+		result.ShowAlert = true
+		return result, nil
+	}
+}
+
+// handleReviewConfirm 处理大额下发的复核确认，两位不同管理员确认后才真正执行
+func (f *Feature) handleReviewConfirm(ctx context.Context, query *botModels.CallbackQuery, token string, pending *pendingSendMoney, result *SendMoneyCallbackResult) (*SendMoneyCallbackResult, error) {
+	if query.From.ID != pending.userID {
+		if f.userService == nil {
+			result.Answer = "未配置管理员校验服务"
+			result.ShowAlert = true
+			return result, nil
+		}
+		isAdmin, err := f.userService.CheckAdminPermission(ctx, query.From.ID, 0)
 		if err != nil {
-			logger.L().Errorf("Sifang send money (callback) failed: merchant_id=%d, user_id=%d, amount=%.2f, err=%v", pending.merchantID, pending.userID, pending.amount, err)
-			var apiErr *sifang.APIError
-			if errors.As(err, &apiErr) {
-				logger.L().Errorf("Sifang send money API error detail: code=%d message=%s", apiErr.Code, apiErr.Message)
-				result.Text = fmt.Sprintf("下发失败：%s", html.EscapeString(apiErr.Message))
-			} else {
-				result.Text = fmt.Sprintf("下发失败：%s", html.EscapeString(err.Error()))
-			}
-			result.ShouldEdit = true
-			result.Answer = "下发失败"
+			logger.Ctx(ctx).Errorf("Sifang send money review admin check failed: user_id=%d, err=%v", query.From.ID, err)
+			result.Answer = "权限检查失败，请稍后重试"
+			result.ShowAlert = true
 			return result, nil
 		}
-
-		message := formatSendMoneyMessage(pending.merchantID, pending.amount, sendResult)
-		if sendResult != nil && sendResult.Withdraw != nil {
-			logger.L().Infof("Sifang send money response detail: merchant_id=%d, withdraw_no=%s, response_amount=%s, status=%s",
-				pending.merchantID,
-				strings.TrimSpace(sendResult.Withdraw.WithdrawNo),
-				strings.TrimSpace(sendResult.Withdraw.Amount),
-				strings.TrimSpace(sendResult.Withdraw.Status),
-			)
+		if !isAdmin {
+			result.Answer = "仅管理员可以复核下发"
+			result.ShowAlert = true
+			return result, nil
 		}
-		logger.L().Infof("Sifang send money success: merchant_id=%d, user_id=%d, amount=%.2f", pending.merchantID, pending.userID, pending.amount)
+	}
 
+	count, duplicate, ready, ok := f.approveReview(token, query.From.ID)
+	if !ok {
 		result.ShouldEdit = true
-		result.Text = message
-		result.Answer = "下发成功"
+		result.Finished = true
+		result.Text = "下发请求已过期"
+		result.Answer = "操作已过期"
 		return result, nil
-	default:
-		result.ShouldEdit = false
-		result.Answer = "未知操作"
+	}
+	if duplicate {
+		result.Answer = "您已确认过，需其他管理员复核"
 		result.ShowAlert = true
 		return result, nil
 	}
+
+	logger.Ctx(ctx).Infof("Sifang send money review confirmed: token=%s user_id=%d merchant_id=%d amount=%.2f approvals=%d/%d",
+		token, query.From.ID, pending.merchantID, pending.amount, count, sendMoneyReviewApprovals)
+
+	if !ready {
+		merchantText := strconv.FormatInt(pending.merchantID, 10)
+		result.ShouldEdit = true
+		result.Text = fmt.Sprintf("⏳ 待复核：下发 %s 元 | %s\n已确认 %d/%d，需另一位管理员点击确认",
+			html.EscapeString(formatFloat(pending.amount)), html.EscapeString(merchantText), count, sendMoneyReviewApprovals)
+		result.Markup = buildSendMoneyKeyboard(token)
+		result.Answer = "已确认，等待其他管理员复核"
+		return result, nil
+	}
+
+	return f.executeSendMoney(ctx, pending, result), nil
+}
+
+// executeSendMoney 预占额度后调用四方下发接口并生成回调结果
+func (f *Feature) executeSendMoney(ctx context.Context, pending *pendingSendMoney, result *SendMoneyCallbackResult) *SendMoneyCallbackResult {
+	result.Finished = true
+	// 发起后、确认前被锁定的请求同样拒绝
+	if until := f.googleCodeLockedUntil(pending.chatID, pending.userID, time.Now()); !until.IsZero() {
+		result.ShouldEdit = true
+		result.Text = "下发失败：" + formatGoogleCodeLocked(until, pending.loc)
+		result.Answer = "下发已锁定"
+		return result
+	}
+
+	quota, rejectText := f.reserveSendMoneyQuota(ctx, pending)
+	if rejectText != "" {
+		result.ShouldEdit = true
+		result.Text = rejectText
+		result.Answer = "超出每日限额"
+		return result
+	}
+
+	opts := paymentservice.SendMoneyOptions{GoogleCode: pending.googleCode}
+	sendResult, err := f.paymentService.SendMoney(ctx, pending.merchantID, pending.amount, opts)
+	if err != nil {
+		// 只有上游明确拒绝或请求未发出时才回退额度；超时、连接中断等无法确认结果的错误保留预占，避免额度被重复使用
+		if isSendMoneyRejected(err) {
+			f.releaseSendMoneyQuota(ctx, quota)
+		}
+		logger.Ctx(ctx).Errorf("Sifang send money (callback) failed: merchant_id=%d, user_id=%d, amount=%.2f, err=%v", pending.merchantID, pending.userID, pending.amount, err)
+		var apiErr *sifang.APIError
+		if errors.As(err, &apiErr) {
+			logger.Ctx(ctx).Errorf("Sifang send money API error detail: code=%d message=%s", apiErr.Code, apiErr.Message)
+			result.Text = fmt.Sprintf("下发失败：%s", html.EscapeString(apiErr.Message))
+		} else {
+			result.Text = fmt.Sprintf("下发失败：%s", html.EscapeString(err.Error()))
+		}
+		if pending.googleCode != "" && isGoogleCodeError(err) {
+			if until := f.recordGoogleCodeFailure(pending.chatID, pending.userID, time.Now()); !until.IsZero() {
+				result.Text += "\n🔒 " + formatGoogleCodeLocked(until, pending.loc)
+			}
+		}
+		result.ShouldEdit = true
+		result.Answer = "下发失败"
+		return result
+	}
+
+	if pending.googleCode != "" {
+		f.resetGoogleCodeFailures(pending.chatID, pending.userID)
+	}
+
+	message := formatSendMoneyMessage(pending.merchantID, pending.amount, sendResult)
+	if quotaLine := formatSendMoneyQuota(quota); quotaLine != "" {
+		message += "\n" + quotaLine
+	}
+	if pending.requiresReview {
+		message += fmt.Sprintf("\n👥 已由 %d 位管理员复核确认", len(pending.confirmedBy))
+	}
+	if linkLine := f.linkSendMoneyAccounting(ctx, pending, sendResult); linkLine != "" {
+		message += "\n" + linkLine
+	}
+	if sendResult != nil && sendResult.Withdraw != nil {
+		logger.Ctx(ctx).Infof("Sifang send money response detail: merchant_id=%d, withdraw_no=%s, response_amount=%s, status=%s",
+			pending.merchantID,
+			strings.TrimSpace(sendResult.Withdraw.WithdrawNo),
+			strings.TrimSpace(sendResult.Withdraw.Amount),
+			strings.TrimSpace(sendResult.Withdraw.Status),
+		)
+	}
+	logger.Ctx(ctx).Infof("Sifang send money success: merchant_id=%d, user_id=%d, amount=%.2f", pending.merchantID, pending.userID, pending.amount)
+
+	result.ShouldEdit = true
+	result.Text = message
+	result.Answer = "下发成功"
+	return result
+}
+
+// sendMoneyOperationID 下发联动的幂等键：优先使用四方返回的下发单号，缺失时退回确认 token
+func sendMoneyOperationID(pending *pendingSendMoney, sendResult *paymentservice.SendMoneyResult) string {
+	if sendResult != nil && sendResult.Withdraw != nil {
+		if withdrawNo := strings.TrimSpace(sendResult.Withdraw.WithdrawNo); withdrawNo != "" {
+			return "sendmoney:" + withdrawNo
+		}
+	}
+	return "sendmoney:token:" + pending.token
+}
+
+// linkSendMoneyAccounting 下发成功后按群配置联动写一笔出账记账，返回追加到结果中的提示行；
+// 联动失败不影响下发结果，只记录错误日志并在结果中提醒手动补记
+func (f *Feature) linkSendMoneyAccounting(ctx context.Context, pending *pendingSendMoney, sendResult *paymentservice.SendMoneyResult) string {
+	if !pending.linkAccounting || f.accounting == nil {
+		return ""
+	}
+
+	operationID := sendMoneyOperationID(pending, sendResult)
+	remark := fmt.Sprintf("下发 %s（商户 %d）", formatFloat(pending.amount), pending.merchantID)
+	if _, err := f.accounting.AddLinkedExpense(ctx, pending.chatID, pending.userID, pending.amount, models.CurrencyCNY, remark, operationID); err != nil {
+		logger.Ctx(ctx).Errorf("Sifang send money accounting link failed: chat_id=%d, merchant_id=%d, amount=%.2f, operation_id=%s, err=%v",
+			pending.chatID, pending.merchantID, pending.amount, operationID, err)
+		return "⚠️ 联动记账失败，请手动补记一笔出账"
+	}
+	return fmt.Sprintf("📒 已联动记账：-%s 元", html.EscapeString(formatFloat(pending.amount)))
+}
+
+// reserveSendMoneyQuota 预占当天下发额度；超限或限额校验失败时返回拒绝文案
+func (f *Feature) reserveSendMoneyQuota(ctx context.Context, pending *pendingSendMoney) (*service.SendMoneyQuota, string) {
+	if f.quotaService == nil {
+		return nil, ""
+	}
+
+	loc := pending.loc
+	if loc == nil {
+		loc = models.DefaultLocation()
+	}
+
+	quota, err := f.quotaService.Reserve(ctx, pending.chatID, pending.amount, pending.dailyLimit, time.Now().In(loc))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Sifang send money quota reserve failed: chat_id=%d, amount=%.2f, err=%v", pending.chatID, pending.amount, err)
+		if pending.dailyLimit > 0 {
+			return nil, "下发失败：每日限额校验失败，请稍后重试"
+		}
+		return nil, ""
+	}
+
+	if !quota.Allowed {
+		logger.Ctx(ctx).Warnf("Sifang send money exceeds daily limit: chat_id=%d, amount=%.2f, used=%.2f, limit=%.2f", pending.chatID, pending.amount, quota.Used, quota.Limit)
+		return nil, fmt.Sprintf("下发失败：超出每日限额 %s 元\n今日已下发 %s 元，剩余额度 %s 元",
+			html.EscapeString(formatFloat(quota.Limit)),
+			html.EscapeString(formatFloat(quota.Used)),
+			html.EscapeString(formatFloat(quota.Remaining)))
+	}
+
+	return quota, ""
+}
+
+// isSendMoneyRejected 下发是否确定未执行：四方返回业务错误，或请求在发出前校验失败
+func isSendMoneyRejected(err error) bool {
+	var apiErr *sifang.APIError
+	return errors.As(err, &apiErr) || errors.Is(err, paymentservice.ErrInvalidRequest)
+}
+
+// releaseSendMoneyQuota 下发失败后回退预占的额度
+func (f *Feature) releaseSendMoneyQuota(ctx context.Context, quota *service.SendMoneyQuota) {
+	if f.quotaService == nil || quota == nil {
+		return
+	}
+	if err := f.quotaService.Release(ctx, quota); err != nil {
+		logger.Ctx(ctx).Errorf("Sifang send money quota release failed: chat_id=%d, date=%s, amount=%.2f, err=%v", quota.ChatID, quota.Date, quota.Amount, err)
+	}
+}
+
+func formatSendMoneyQuota(quota *service.SendMoneyQuota) string {
+	if quota == nil {
+		return ""
+	}
+	if quota.Limit <= 0 {
+		return fmt.Sprintf("今日已下发 %s 元", html.EscapeString(formatFloat(quota.Used)))
+	}
+	return fmt.Sprintf("今日已下发 %s 元，剩余额度 %s 元",
+		html.EscapeString(formatFloat(quota.Used)),
+		html.EscapeString(formatFloat(quota.Remaining)))
 }
 
 func wrapResponse(text string) *types.Response {
@@ -993,10 +1418,18 @@ func wrapResponse(text string) *types.Response {
 	return &types.Response{Text: text}
 }
 
-func (f *Feature) handleChannelRates(ctx context.Context, merchantID int64) (string, bool, error) {
-	statuses, err := f.paymentService.GetChannelStatus(ctx, merchantID)
+// handleChannelRates 查询通道费率，优先使用缓存；refresh 为 true 时绕过缓存
+func (f *Feature) handleChannelRates(ctx context.Context, merchantID int64, refresh bool, loc *time.Location) (string, bool, error) {
+	if !refresh {
+		if entry, ok := f.cachedChannelRates(merchantID, f.now()); ok {
+			logger.Ctx(ctx).Infof("Sifang channel status cache hit: merchant_id=%d, channels=%d", merchantID, len(entry.statuses))
+			return formatChannelRatesMessage(entry.statuses) + "\n" + formatChannelRatesCachedAt(entry.fetchedAt, loc), true, nil
+		}
+	}
+
+	statuses, err := f.fetchChannelRates(ctx, merchantID)
 	if err != nil {
-		logger.L().Errorf("Sifang channel status query failed: merchant_id=%d, err=%v", merchantID, err)
+		logger.Ctx(ctx).Errorf("Sifang channel status query failed: merchant_id=%d, err=%v", merchantID, err)
 		return fmt.Sprintf("❌ 查询费率失败：%v", err), true, nil
 	}
 
@@ -1005,7 +1438,7 @@ func (f *Feature) handleChannelRates(ctx context.Context, merchantID int64) (str
 	}
 
 	message := formatChannelRatesMessage(statuses)
-	logger.L().Infof("Sifang channel status queried: merchant_id=%d, channels=%d", merchantID, len(statuses))
+	logger.Ctx(ctx).Infof("Sifang channel status queried: merchant_id=%d, channels=%d", merchantID, len(statuses))
 	return message, true, nil
 }
 
@@ -2,11 +2,13 @@ package sifang
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/payment/sifang"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/service"
 
@@ -78,9 +80,9 @@ func TestParseBalanceDate_RewritesErrorMessage(t *testing.T) {
 }
 
 func TestExpirePending(t *testing.T) {
-	feature := New(nil, nil)
+	feature := New(nil, nil, nil, nil)
 
-	pending, err := feature.createPendingSend(100, 200, 300, 123.45, "")
+	pending, err := feature.createPendingSend(&pendingSendMoney{chatID: 100, userID: 200, merchantID: 300, amount: 123.45})
 	if err != nil {
 		t.Fatalf("unexpected error creating pending send: %v", err)
 	}
@@ -99,7 +101,7 @@ func TestExpirePending(t *testing.T) {
 	}
 
 	// 新的 pending 仍在有效期内，不应过期
-	active, err := feature.createPendingSend(100, 200, 300, 50, "")
+	active, err := feature.createPendingSend(&pendingSendMoney{chatID: 100, userID: 200, merchantID: 300, amount: 50})
 	if err != nil {
 		t.Fatalf("unexpected error creating active pending: %v", err)
 	}
@@ -109,6 +111,31 @@ func TestExpirePending(t *testing.T) {
 	}
 }
 
+func TestSweepExpiredPendingKeepsGracePeriod(t *testing.T) {
+	feature := New(nil, nil, nil, nil)
+	now := time.Now()
+
+	ages := []time.Duration{time.Second, SendMoneyConfirmTTL + 30*time.Second, SendMoneyConfirmTTL + 2*time.Minute}
+	tokens := make([]string, 0, len(ages))
+	for range ages {
+		pending, err := feature.createPendingSend(&pendingSendMoney{chatID: 100, userID: 200, amount: 10})
+		if err != nil {
+			t.Fatalf("unexpected error creating pending send: %v", err)
+		}
+		tokens = append(tokens, pending.token)
+	}
+	feature.mu.Lock()
+	for i, token := range tokens {
+		feature.pending[token].createdAt = now.Add(-ages[i])
+	}
+	feature.mu.Unlock()
+
+	removed, remaining := feature.SweepExpiredPending(now, time.Minute)
+	if removed != 1 || remaining != 2 {
+		t.Fatalf("expected only the record past ttl+grace to be removed, got removed=%d remaining=%d", removed, remaining)
+	}
+}
+
 func TestCalculateHistoryDays(t *testing.T) {
 	loc := mustLoadChinaLocation()
 	now := time.Date(2024, 11, 5, 12, 0, 0, 0, loc)
@@ -159,7 +186,7 @@ func TestFormatSummaryMessage(t *testing.T) {
 	}
 
 	got := formatSummaryMessage(summary)
-	expected := "📑 账单 - 2025-10-31\n跑量：4650.00\n成交：4336.75\n笔数：40"
+	expected := "📑 <b>账单</b> · 2025年10月31日 周五\n<pre>跑量  4,650.00\n成交  4,336.75\n笔数        40</pre>"
 	if got != expected {
 		t.Fatalf("unexpected message:\n%s", got)
 	}
@@ -186,7 +213,9 @@ func TestFormatChannelSummaryMessage(t *testing.T) {
 	}
 
 	got := formatChannelSummaryMessage("2025-10-31", items)
-	expected := "📑 通道账单 - 2025-10-31\n\nUSDT通道：<code>USDT</code>\n跑量：5000.00\n成交：4900\n笔数：20\n\n支付宝：<code>ALIPAY</code>\n跑量：2000\n成交：1800\n笔数：5"
+	expected := "📑 <b>通道账单</b> · 2025年10月31日 周五\n合计：跑量 <b>7,000.00</b>｜成交 <b>6,700.00</b>｜<b>25</b> 笔\n\n" +
+		"USDT通道：<code>USDT</code>\n<pre>跑量  5,000.00\n成交  4,900.00\n笔数        20</pre>\n\n" +
+		"支付宝：<code>ALIPAY</code>\n<pre>跑量  2,000.00\n成交  1,800.00\n笔数         5</pre>"
 	if got != expected {
 		t.Fatalf("unexpected channel message:\n%s", got)
 	}
@@ -194,7 +223,7 @@ func TestFormatChannelSummaryMessage(t *testing.T) {
 
 func TestFormatChannelSummaryMessage_NoItems(t *testing.T) {
 	got := formatChannelSummaryMessage("2025-10-31", nil)
-	expected := "ℹ️ 2025-10-31 暂无通道账单数据"
+	expected := "ℹ️ 2025年10月31日 周五 暂无通道账单数据"
 	if got != expected {
 		t.Fatalf("unexpected channel message for no items:\n%s", got)
 	}
@@ -349,53 +378,62 @@ func TestFormatWithdrawListMessage(t *testing.T) {
 	}
 
 	got := formatWithdrawListMessage("2025-10-31", list)
-	expected := "💸 提款明细（总计 100｜1 笔）\n10:00:00      100.00"
+	expected := "💸 <b>提款明细</b>（总计 <b>100.00</b>｜<b>1</b> 笔）\n<pre>10:00:00  100.00</pre>"
 	if got != expected {
 		t.Fatalf("unexpected withdraw message:\n%s", got)
 	}
 
 	gotEmpty := formatWithdrawListMessage("2025-10-31", &paymentservice.WithdrawList{})
-	if gotEmpty != "💸 提款明细\n暂无提款记录" {
+	if gotEmpty != "💸 <b>提款明细</b>\n暂无提款记录" {
 		t.Fatalf("unexpected empty withdraw message:\n%s", gotEmpty)
 	}
 }
 
 func TestParseSendMoneyPayload_Number(t *testing.T) {
-	amount, code, err := parseSendMoneyPayload(" 1,234.5678 ")
+	amount, expression, code, err := parseSendMoneyPayload(" 1,234.5678 ")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if amount != 1234.57 {
 		t.Fatalf("expected rounded amount 1234.57, got %.2f", amount)
 	}
+	if expression != "" {
+		t.Fatalf("expected no expression for plain number, got %s", expression)
+	}
 	if code != "" {
 		t.Fatalf("expected empty google code, got %s", code)
 	}
 }
 
 func TestParseSendMoneyPayload_ExpressionWithGoogleCode(t *testing.T) {
-	amount, code, err := parseSendMoneyPayload("(1+2)*3  123456")
+	amount, expression, code, err := parseSendMoneyPayload("(1+2)*3  123456")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if amount != 9 {
 		t.Fatalf("expected amount 9, got %.2f", amount)
 	}
+	if expression != "(1+2)*3" {
+		t.Fatalf("expected expression (1+2)*3, got %s", expression)
+	}
 	if code != "123456" {
 		t.Fatalf("expected google code 123456, got %s", code)
 	}
 }
 
 func TestParseSendMoneyPayload_Invalid(t *testing.T) {
-	if _, _, err := parseSendMoneyPayload(""); err == nil {
+	if _, _, _, err := parseSendMoneyPayload(""); err == nil {
 		t.Fatalf("expected error for empty payload")
 	}
-	if _, _, err := parseSendMoneyPayload("abc"); err == nil {
+	if _, _, _, err := parseSendMoneyPayload("abc"); err == nil {
 		t.Fatalf("expected error for invalid payload")
 	}
-	if _, _, err := parseSendMoneyPayload("-100"); err == nil {
+	if _, _, _, err := parseSendMoneyPayload("-100"); err == nil {
 		t.Fatalf("expected error for negative amount")
 	}
+	if _, _, _, err := parseSendMoneyPayload("100/0"); err == nil {
+		t.Fatalf("expected error for invalid expression")
+	}
 }
 
 func TestFormatSendMoneyMessage(t *testing.T) {
@@ -417,7 +455,7 @@ func TestHandleSendMoneyCreatesPending(t *testing.T) {
 	ctx := context.Background()
 	fakeSvc := &fakePaymentService{}
 	stubUser := &stubUserService{isAdmin: true}
-	feature := New(fakeSvc, stubUser)
+	feature := New(fakeSvc, stubUser, nil, nil)
 
 	msg := &botModels.Message{
 		Chat: botModels.Chat{ID: -1, Type: "group"},
@@ -425,7 +463,7 @@ func TestHandleSendMoneyCreatesPending(t *testing.T) {
 		Text: "下发 12",
 	}
 
-	resp, handled, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text)
+	resp, handled, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text, models.GroupSettings{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -458,6 +496,49 @@ func TestHandleSendMoneyCreatesPending(t *testing.T) {
 	}
 }
 
+func TestHandleSendMoneyRejectsGroupAdmin(t *testing.T) {
+	feature := New(&fakePaymentService{}, &stubUserService{groupAdmin: true}, nil, nil)
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 12",
+	}
+
+	resp, handled, err := feature.handleSendMoney(context.Background(), msg, 2023100, msg.Text, models.GroupSettings{})
+	if err != nil || !handled {
+		t.Fatalf("expected handled without error, got handled=%t err=%v", handled, err)
+	}
+	if resp == nil || !strings.Contains(resp.Text, "仅管理员可以下发") {
+		t.Fatalf("expected group admin rejected, got %+v", resp)
+	}
+	if len(feature.pending) != 0 {
+		t.Fatalf("expected no pending send for group admin")
+	}
+}
+
+func TestHandleSendMoneyExpressionShowsEvaluatedAmount(t *testing.T) {
+	feature := New(&fakePaymentService{}, &stubUserService{isAdmin: true}, nil, nil)
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 100*3",
+	}
+
+	resp, _, err := feature.handleSendMoney(context.Background(), msg, 2023100, msg.Text, models.GroupSettings{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || !strings.Contains(resp.Text, "100*3 = 300") || !strings.Contains(resp.Text, "即将下发：300 元 | 2023100") {
+		t.Fatalf("expected evaluated amount in confirmation, got %+v", resp)
+	}
+
+	msg.Text = "下发 100*(3"
+	resp, _, _ = feature.handleSendMoney(context.Background(), msg, 2023100, msg.Text, models.GroupSettings{})
+	if resp == nil || resp.ReplyMarkup != nil || !strings.Contains(resp.Text, "金额表达式无效") {
+		t.Fatalf("expected invalid expression rejected without keyboard, got %+v", resp)
+	}
+}
+
 func TestHandleSendMoneyCallbackConfirm(t *testing.T) {
 	ctx := context.Background()
 	fakeSvc := &fakePaymentService{
@@ -467,14 +548,14 @@ func TestHandleSendMoneyCallbackConfirm(t *testing.T) {
 		},
 	}
 	stubUser := &stubUserService{isAdmin: true}
-	feature := New(fakeSvc, stubUser)
+	feature := New(fakeSvc, stubUser, nil, nil)
 
 	msg := &botModels.Message{
 		Chat: botModels.Chat{ID: -1, Type: "group"},
 		From: &botModels.User{ID: 123},
 		Text: "下发 12",
 	}
-	resp, handled, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text)
+	resp, handled, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text, models.GroupSettings{})
 	if err != nil || !handled || resp == nil {
 		t.Fatalf("unexpected setup result: resp=%v handled=%v err=%v", resp, handled, err)
 	}
@@ -508,18 +589,93 @@ func TestHandleSendMoneyCallbackConfirm(t *testing.T) {
 	}
 }
 
+func TestHandleSendMoneyCallbackLinksAccounting(t *testing.T) {
+	ctx := context.Background()
+	fakeSvc := &fakePaymentService{
+		sendMoneyResult: &paymentservice.SendMoneyResult{
+			MerchantID: "2023100",
+			Withdraw:   &paymentservice.Withdraw{Amount: "12.00", WithdrawNo: "NO1"},
+		},
+	}
+	accounting := &linkedAccountingService{seen: make(map[string]bool)}
+	feature := New(fakeSvc, &stubUserService{isAdmin: true}, nil, accounting)
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 12",
+	}
+	query := &botModels.CallbackQuery{From: botModels.User{ID: 123}}
+	send := func(settings models.GroupSettings) string {
+		t.Helper()
+		if _, _, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text, settings); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var token string
+		for data := range feature.pending {
+			token = data
+		}
+		result, err := feature.HandleSendMoneyCallback(ctx, query, sendMoneyActionConfirm, token)
+		if err != nil || !strings.Contains(result.Text, "已成功下发") {
+			t.Fatalf("expected send success, got %+v err=%v", result, err)
+		}
+		return result.Text
+	}
+
+	// 未开启联动（或未开启记账）时不记账
+	if text := send(models.GroupSettings{SendMoneyAccountingLink: true}); strings.Contains(text, "联动记账") || len(accounting.seen) != 0 {
+		t.Fatalf("expected no accounting link without accounting enabled, got %q", text)
+	}
+
+	linked := models.GroupSettings{AccountingEnabled: true, SendMoneyAccountingLink: true}
+	if text := send(linked); !strings.Contains(text, "📒 已联动记账：-12 元") {
+		t.Fatalf("expected accounting link line, got %q", text)
+	}
+	// 同一下发单号重复联动只记一次
+	send(linked)
+	if accounting.created != 1 || !accounting.seen["sendmoney:NO1"] {
+		t.Fatalf("expected one linked record for NO1, got created=%d seen=%v", accounting.created, accounting.seen)
+	}
+
+	// 联动失败不影响下发结果，只提示手动补记
+	accounting.err = errors.New("mongo down")
+	fakeSvc.sendMoneyResult.Withdraw.WithdrawNo = "NO2"
+	if text := send(linked); !strings.Contains(text, "联动记账失败") {
+		t.Fatalf("expected link failure hint, got %q", text)
+	}
+}
+
+// linkedAccountingService 按 operationID 去重的联动记账 stub
+type linkedAccountingService struct {
+	service.AccountingService
+	seen    map[string]bool
+	created int
+	err     error
+}
+
+func (s *linkedAccountingService) AddLinkedExpense(ctx context.Context, chatID, userID int64, amount float64, currency, remark, operationID string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.seen[operationID] {
+		return false, nil
+	}
+	s.seen[operationID] = true
+	s.created++
+	return true, nil
+}
+
 func TestHandleSendMoneyCallbackCancel(t *testing.T) {
 	ctx := context.Background()
 	fakeSvc := &fakePaymentService{}
 	stubUser := &stubUserService{isAdmin: true}
-	feature := New(fakeSvc, stubUser)
+	feature := New(fakeSvc, stubUser, nil, nil)
 
 	msg := &botModels.Message{
 		Chat: botModels.Chat{ID: -5, Type: "group"},
 		From: &botModels.User{ID: 555},
 		Text: "下发 20",
 	}
-	resp, handled, err := feature.handleSendMoney(ctx, msg, 2024001, msg.Text)
+	resp, handled, err := feature.handleSendMoney(ctx, msg, 2024001, msg.Text, models.GroupSettings{})
 	if err != nil || !handled || resp == nil {
 		t.Fatalf("unexpected setup result: resp=%v handled=%v err=%v", resp, handled, err)
 	}
@@ -564,7 +720,7 @@ func TestHandleChannelRates(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	message, handled, err := feature.handleChannelRates(context.Background(), 1001)
+	message, handled, err := feature.handleChannelRates(context.Background(), 1001, false, chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -586,11 +742,11 @@ func TestHandleBalanceReturnsCurrentAmount(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	amount, _, err := feature.handleBalance(context.Background(), 1001, "")
+	amount, _, err := feature.handleBalance(context.Background(), 1001, "", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if amount != "123.45" {
+	if amount != "<b>123.45</b>" {
 		t.Fatalf("expected current balance, got %s", amount)
 	}
 	if fake.lastHistoryDays != 0 {
@@ -598,6 +754,40 @@ func TestHandleBalanceReturnsCurrentAmount(t *testing.T) {
 	}
 }
 
+func TestProcessMerchantOverride(t *testing.T) {
+	fake := &fakePaymentService{balanceResp: &paymentservice.Balance{Balance: "1.00"}}
+	feature := &Feature{paymentService: fake}
+	group := &models.Group{Settings: models.GroupSettings{MerchantID: 1001, MerchantIDs: []int32{1001, 1002}}}
+	newMsg := func(text string) *botModels.Message {
+		return &botModels.Message{Text: text, Chat: botModels.Chat{ID: -1, Type: "supergroup"}, From: &botModels.User{ID: 1}}
+	}
+
+	if !feature.Match(context.Background(), newMsg("余额10月26 #1002")) {
+		t.Fatalf("expected command with merchant override to match")
+	}
+
+	resp, handled, err := feature.Process(context.Background(), newMsg("余额 #1002"), group)
+	if err != nil || !handled || resp == nil {
+		t.Fatalf("unexpected result: handled=%v err=%v", handled, err)
+	}
+	if fake.lastMerchantID != 1002 {
+		t.Fatalf("expected override merchant 1002, got %d", fake.lastMerchantID)
+	}
+
+	if _, _, err := feature.Process(context.Background(), newMsg("余额"), group); err != nil || fake.lastMerchantID != 1001 {
+		t.Fatalf("expected current merchant 1001 by default, got %d err=%v", fake.lastMerchantID, err)
+	}
+
+	resp, _, _ = feature.Process(context.Background(), newMsg("余额 #2002"), group)
+	if resp == nil || !strings.Contains(resp.Text, "未绑定到当前群组") {
+		t.Fatalf("expected unbound merchant to be rejected, got %+v", resp)
+	}
+	resp, _, _ = feature.Process(context.Background(), newMsg("下发 100 #1002"), group)
+	if resp == nil || !strings.Contains(resp.Text, "下发不支持临时指定商户号") {
+		t.Fatalf("expected send money override to be rejected, got %+v", resp)
+	}
+}
+
 func TestHandleBalanceReturnsHistoryAmount(t *testing.T) {
 	fake := &fakePaymentService{
 		balanceResp: &paymentservice.Balance{
@@ -608,11 +798,11 @@ func TestHandleBalanceReturnsHistoryAmount(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	amount, _, err := feature.handleBalance(context.Background(), 1001, "2000-01-01")
+	amount, _, err := feature.handleBalance(context.Background(), 1001, "2000-01-01", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if amount != "67.89" {
+	if amount != "<b>67.89</b>" {
 		t.Fatalf("expected history balance, got %s", amount)
 	}
 	if fake.lastHistoryDays <= 0 {
@@ -636,17 +826,17 @@ func TestHandleSummaryIncludesWithdrawAndBalance(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	message, _, err := feature.handleSummary(context.Background(), 1001, "账单")
+	message, _, err := feature.handleSummary(context.Background(), 1001, "账单", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(message, "📑 账单 - ") {
+	if !strings.Contains(message, "📑 <b>账单</b> · ") {
 		t.Fatalf("expected summary header, got %s", message)
 	}
-	if !strings.Contains(message, "💸 提款明细（总计 ") {
+	if !strings.Contains(message, "💸 <b>提款明细</b>（总计 ") {
 		t.Fatalf("expected withdraw section, got %s", message)
 	}
-	if !strings.Contains(message, "余额：5000") {
+	if !strings.Contains(message, "余额：<b>5,000.00</b>") {
 		t.Fatalf("expected balance amount, got %s", message)
 	}
 }
@@ -669,7 +859,7 @@ func TestBuildSummaryMessageMatchesHandleSummary(t *testing.T) {
 
 	feature := &Feature{paymentService: fake}
 
-	expected, _, err := feature.handleSummary(context.Background(), 1001, "账单")
+	expected, _, err := feature.handleSummary(context.Background(), 1001, "账单", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error from handleSummary: %v", err)
 	}
@@ -694,11 +884,11 @@ func TestHandleSummaryUsesHistoryBalanceForPastDate(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	message, _, err := feature.handleSummary(context.Background(), 1001, "账单01-01")
+	message, _, err := feature.handleSummary(context.Background(), 1001, "账单01-01", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(message, "余额：4000") {
+	if !strings.Contains(message, "余额：<b>4,000.00</b>") {
 		t.Fatalf("expected history balance in message, got %s", message)
 	}
 	if fake.lastHistoryDays <= 0 {
@@ -732,17 +922,17 @@ func TestHandleChannelSummaryIncludesWithdrawAndBalance(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	message, _, err := feature.handleChannelSummary(context.Background(), 1001, "通道账单")
+	message, _, err := feature.handleChannelSummary(context.Background(), 1001, "通道账单", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(message, "📑 通道账单 - ") {
+	if !strings.Contains(message, "📑 <b>通道账单</b> · ") {
 		t.Fatalf("expected channel summary header, got %s", message)
 	}
-	if !strings.Contains(message, "💸 提款明细（总计 ") {
+	if !strings.Contains(message, "💸 <b>提款明细</b>（总计 ") {
 		t.Fatalf("expected withdraw section, got %s", message)
 	}
-	if !strings.Contains(message, "余额：5000") {
+	if !strings.Contains(message, "余额：<b>5,000.00</b>") {
 		t.Fatalf("expected balance amount, got %s", message)
 	}
 	if fake.lastHistoryDays != 0 {
@@ -770,11 +960,11 @@ func TestHandleChannelSummaryUsesHistoryBalanceForPastDate(t *testing.T) {
 	}
 	feature := &Feature{paymentService: fake}
 
-	message, _, err := feature.handleChannelSummary(context.Background(), 1001, "通道账单01-01")
+	message, _, err := feature.handleChannelSummary(context.Background(), 1001, "通道账单01-01", chinaLocation)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(message, "余额：4000") {
+	if !strings.Contains(message, "余额：<b>4,000.00</b>") {
 		t.Fatalf("expected history balance in channel summary, got %s", message)
 	}
 	if fake.lastHistoryDays <= 0 {
@@ -782,6 +972,189 @@ func TestHandleChannelSummaryUsesHistoryBalanceForPastDate(t *testing.T) {
 	}
 }
 
+func TestHandleSendMoneyRejectsAmountAboveDailyLimit(t *testing.T) {
+	feature := New(&fakePaymentService{}, &stubUserService{isAdmin: true}, nil, nil)
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 200",
+	}
+
+	resp, handled, err := feature.handleSendMoney(context.Background(), msg, 2023100, msg.Text, models.GroupSettings{SendMoneyDailyLimit: 100})
+	if err != nil || !handled {
+		t.Fatalf("unexpected result: handled=%v err=%v", handled, err)
+	}
+	if resp == nil || !strings.Contains(resp.Text, "超过每日限额 100 元") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(feature.pending) != 0 {
+		t.Fatalf("expected no pending send")
+	}
+}
+
+func TestHandleSendMoneyCallbackConfirmDailyLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		quota       *service.SendMoneyQuota
+		sendErr     error
+		wantText    string
+		wantSend    bool
+		wantRelease bool
+	}{
+		{
+			name:     "exceeds limit",
+			quota:    &service.SendMoneyQuota{Limit: 100, Used: 95, Remaining: 5},
+			wantText: "超出每日限额 100 元\n今日已下发 95 元，剩余额度 5 元",
+		},
+		{
+			name:     "within limit",
+			quota:    &service.SendMoneyQuota{Limit: 100, Used: 12, Remaining: 88, Allowed: true},
+			wantText: "今日已下发 12 元，剩余额度 88 元",
+			wantSend: true,
+		},
+		{
+			name:        "upstream rejection releases quota",
+			quota:       &service.SendMoneyQuota{Limit: 100, Used: 12, Remaining: 88, Allowed: true},
+			sendErr:     &sifang.APIError{Code: 1, Message: "余额不足"},
+			wantText:    "下发失败：余额不足",
+			wantSend:    true,
+			wantRelease: true,
+		},
+		{
+			name:     "ambiguous failure keeps quota reserved",
+			quota:    &service.SendMoneyQuota{Limit: 100, Used: 12, Remaining: 88, Allowed: true},
+			sendErr:  errors.New("request sifang api failed: context deadline exceeded"),
+			wantText: "下发失败",
+			wantSend: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fakeSvc := &fakePaymentService{
+				sendMoneyErr:    tt.sendErr,
+				sendMoneyResult: &paymentservice.SendMoneyResult{MerchantID: "2023100"},
+			}
+			quotaSvc := &stubQuotaService{quota: tt.quota}
+			feature := New(fakeSvc, &stubUserService{isAdmin: true}, quotaSvc, nil)
+
+			pending, err := feature.createPendingSend(&pendingSendMoney{chatID: -1, userID: 123, merchantID: 2023100, amount: 12, dailyLimit: 100, loc: chinaLocation})
+			if err != nil {
+				t.Fatalf("create pending: %v", err)
+			}
+
+			query := &botModels.CallbackQuery{From: botModels.User{ID: 123}}
+			result, err := feature.HandleSendMoneyCallback(ctx, query, sendMoneyActionConfirm, pending.token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(result.Text, tt.wantText) {
+				t.Fatalf("expected %q in %q", tt.wantText, result.Text)
+			}
+			if sent := fakeSvc.lastSendAmount != 0; sent != tt.wantSend {
+				t.Fatalf("expected send=%v, got %v", tt.wantSend, sent)
+			}
+			if quotaSvc.released != tt.wantRelease {
+				t.Fatalf("expected release=%v, got %v", tt.wantRelease, quotaSvc.released)
+			}
+			if quotaSvc.lastLimit != 100 || quotaSvc.lastChatID != -1 {
+				t.Fatalf("unexpected reserve args: chat_id=%d limit=%.2f", quotaSvc.lastChatID, quotaSvc.lastLimit)
+			}
+		})
+	}
+}
+
+func TestHandleSendMoneyCallbackRequiresTwoReviewers(t *testing.T) {
+	ctx := context.Background()
+	fakeSvc := &fakePaymentService{sendMoneyResult: &paymentservice.SendMoneyResult{MerchantID: "2023100"}}
+	stubUser := &stubUserService{isAdmin: true}
+	feature := New(fakeSvc, stubUser, nil, nil)
+
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 500",
+	}
+	resp, handled, err := feature.handleSendMoney(ctx, msg, 2023100, msg.Text, models.GroupSettings{SendMoneyReviewThreshold: 100})
+	if err != nil || !handled || resp == nil {
+		t.Fatalf("unexpected setup result: resp=%v handled=%v err=%v", resp, handled, err)
+	}
+	if !strings.Contains(resp.Text, "需 2 位不同管理员分别点击确认") {
+		t.Fatalf("expected review hint in prompt: %s", resp.Text)
+	}
+
+	token := ""
+	for data := range feature.pending {
+		token = data
+	}
+	if ttl, review := feature.PendingTTL(token); !review || ttl != SendMoneyReviewTTL {
+		t.Fatalf("expected review ttl, got ttl=%s review=%v", ttl, review)
+	}
+
+	confirm := func(userID int64) *SendMoneyCallbackResult {
+		t.Helper()
+		query := &botModels.CallbackQuery{From: botModels.User{ID: userID}}
+		result, err := feature.HandleSendMoneyCallback(ctx, query, sendMoneyActionConfirm, token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	first := confirm(123)
+	if !first.ShouldEdit || !strings.Contains(first.Text, "待复核") || !strings.Contains(first.Text, "已确认 1/2") {
+		t.Fatalf("expected pending review text, got %+v", first)
+	}
+	if first.Markup == nil {
+		t.Fatalf("expected keyboard kept for reviewer")
+	}
+
+	repeat := confirm(123)
+	if repeat.ShouldEdit || !repeat.ShowAlert || !strings.Contains(repeat.Answer, "已确认过") {
+		t.Fatalf("expected duplicate confirmation to be ignored, got %+v", repeat)
+	}
+
+	stubUser.isAdmin = false
+	outsider := confirm(789)
+	if outsider.ShouldEdit || !strings.Contains(outsider.Answer, "仅管理员") {
+		t.Fatalf("expected non-admin reviewer rejected, got %+v", outsider)
+	}
+	if fakeSvc.lastSendAmount != 0 {
+		t.Fatalf("expected no send before second approval")
+	}
+
+	stubUser.isAdmin = true
+	second := confirm(456)
+	if !strings.Contains(second.Text, "已成功下发") || !strings.Contains(second.Text, "已由 2 位管理员复核确认") {
+		t.Fatalf("expected send after second approval, got %+v", second)
+	}
+	if fakeSvc.lastSendAmount != 500 {
+		t.Fatalf("expected send amount 500, got %.2f", fakeSvc.lastSendAmount)
+	}
+	if _, ok := feature.pending[token]; ok {
+		t.Fatalf("expected pending removed after execution")
+	}
+}
+
+type stubQuotaService struct {
+	quota      *service.SendMoneyQuota
+	lastChatID int64
+	lastLimit  float64
+	released   bool
+}
+
+func (s *stubQuotaService) Reserve(ctx context.Context, chatID int64, amount, limit float64, at time.Time) (*service.SendMoneyQuota, error) {
+	s.lastChatID = chatID
+	s.lastLimit = limit
+	return s.quota, nil
+}
+
+func (s *stubQuotaService) Release(ctx context.Context, quota *service.SendMoneyQuota) error {
+	s.released = true
+	return nil
+}
+
 type fakePaymentService struct {
 	balanceResp        *paymentservice.Balance
 	balanceErr         error
@@ -794,6 +1167,7 @@ type fakePaymentService struct {
 	channelStatusResp  []*paymentservice.ChannelStatus
 	channelStatusErr   error
 	lastHistoryDays    int
+	lastMerchantID     int64
 	sendMoneyResult    *paymentservice.SendMoneyResult
 	sendMoneyErr       error
 	lastSendAmount     float64
@@ -803,6 +1177,7 @@ type fakePaymentService struct {
 
 func (f *fakePaymentService) GetBalance(ctx context.Context, merchantID int64, historyDays int) (*paymentservice.Balance, error) {
 	f.lastHistoryDays = historyDays
+	f.lastMerchantID = merchantID
 	if f.balanceErr != nil {
 		return nil, f.balanceErr
 	}
@@ -877,7 +1252,8 @@ func (f *fakePaymentService) FindOrderChannelBinding(ctx context.Context, mercha
 }
 
 type stubUserService struct {
-	isAdmin bool
+	isAdmin    bool
+	groupAdmin bool // 仅为群级管理员：只在带群 ID 的检查中放行
 }
 
 func (s *stubUserService) RegisterOrUpdateUser(ctx context.Context, info *service.TelegramUserInfo) error {
@@ -888,6 +1264,10 @@ func (s *stubUserService) GrantAdminPermission(ctx context.Context, targetID, gr
 	return nil
 }
 
+func (s *stubUserService) GrantRole(ctx context.Context, targetID int64, role string, grantedBy int64) error {
+	return nil
+}
+
 func (s *stubUserService) RevokeAdminPermission(ctx context.Context, targetID, revokedBy int64) error {
 	return nil
 }
@@ -900,14 +1280,110 @@ func (s *stubUserService) ListAllAdmins(ctx context.Context) ([]*models.User, er
 	return nil, nil
 }
 
-func (s *stubUserService) CheckOwnerPermission(ctx context.Context, telegramID int64) (bool, error) {
-	return false, nil
+func (s *stubUserService) ListAdminsPage(ctx context.Context, role string, page, pageSize int) (*service.AdminPage, error) {
+	return &service.AdminPage{Page: 1, TotalPages: 1}, nil
+}
+
+func (s *stubUserService) CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	return s.isAdmin || (s.groupAdmin && chatID != 0), nil
 }
 
-func (s *stubUserService) CheckAdminPermission(ctx context.Context, telegramID int64) (bool, error) {
-	return s.isAdmin, nil
+func (s *stubUserService) InvalidateGroupAdminCache(chatID int64) {}
+
+func (s *stubUserService) HasPermission(ctx context.Context, telegramID int64, perm string) (bool, error) {
+	return s.isAdmin && perm == models.PermAdmin, nil
+}
+
+func (s *stubUserService) UpdateUserPermissions(ctx context.Context, targetID int64, add, remove []string, operatorID int64) (*models.User, error) {
+	return nil, nil
 }
 
 func (s *stubUserService) UpdateUserActivity(ctx context.Context, telegramID int64) error {
 	return nil
 }
+
+func (s *stubUserService) SetPreferredLanguage(ctx context.Context, telegramID int64, lang string) error {
+	return nil
+}
+
+func (s *stubUserService) GetPreferredLanguage(ctx context.Context, telegramID int64) (string, error) {
+	return "", nil
+}
+
+func (s *stubUserService) PreviewInactiveUsers(ctx context.Context, cutoff time.Time, limit int64) (int64, []*models.User, error) {
+	return 0, nil, nil
+}
+
+func (s *stubUserService) PurgeInactiveUsers(ctx context.Context, cutoff time.Time, operatorID int64) (int64, error) {
+	return 0, nil
+}
+
+func (s *stubUserService) RecordStartSource(ctx context.Context, telegramID int64, payload string) error {
+	return nil
+}
+
+// blockingSendMoneyService 下发调用阻塞到 release 关闭，用于模拟确认执行中
+type blockingSendMoneyService struct {
+	*fakePaymentService
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSendMoneyService) SendMoney(ctx context.Context, merchantID int64, amount float64, opts paymentservice.SendMoneyOptions) (*paymentservice.SendMoneyResult, error) {
+	close(s.started)
+	<-s.release
+	return s.fakePaymentService.SendMoney(ctx, merchantID, amount, opts)
+}
+
+func TestSendMoneyConfirmInFlightIsNotExpired(t *testing.T) {
+	ctx := context.Background()
+	payments := &blockingSendMoneyService{
+		fakePaymentService: &fakePaymentService{sendMoneyResult: &paymentservice.SendMoneyResult{
+			MerchantID: "2023100",
+			Withdraw:   &paymentservice.Withdraw{Amount: "12.00", WithdrawNo: "NO1"},
+		}},
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	feature := New(payments, &stubUserService{isAdmin: true}, nil, nil)
+	pending, err := feature.createPendingSend(&pendingSendMoney{chatID: -1, userID: 123, merchantID: 2023100, amount: 12, loc: chinaLocation})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := &botModels.CallbackQuery{
+		From:    botModels.User{ID: 123},
+		Message: botModels.MaybeInaccessibleMessage{Message: &botModels.Message{Chat: botModels.Chat{ID: -1}, ID: 99}},
+	}
+	done := make(chan *SendMoneyCallbackResult)
+	go func() {
+		result, _ := feature.HandleSendMoneyCallback(ctx, query, sendMoneyActionConfirm, pending.token)
+		done <- result
+	}()
+	<-payments.started
+
+	// 确认执行中过期扫描到达：请求已被取走，不能判定为过期
+	if feature.ExpirePending(pending.token) || feature.HasPending(pending.token) || !feature.IsSettled(pending.token) {
+		t.Fatalf("expected in-flight confirm to be settled, not expired")
+	}
+	close(payments.release)
+	if result := <-done; result == nil || !strings.Contains(result.Text, "已成功下发") {
+		t.Fatalf("expected confirm to complete, got %+v", result)
+	}
+
+	// 反过来：过期先生效后，确认按过期处理且不标记为已处理
+	expired, err := feature.createPendingSend(&pendingSendMoney{chatID: -1, userID: 123, merchantID: 2023100, amount: 12, loc: chinaLocation})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feature.mu.Lock()
+	feature.pending[expired.token].createdAt = time.Now().Add(-SendMoneyConfirmTTL - time.Second)
+	feature.mu.Unlock()
+	if !feature.ExpirePending(expired.token) {
+		t.Fatalf("expected pending send to expire")
+	}
+	result, err := feature.HandleSendMoneyCallback(ctx, query, sendMoneyActionConfirm, expired.token)
+	if err != nil || result == nil || result.Text != "下发请求已过期" || feature.IsSettled(expired.token) {
+		t.Fatalf("expected confirm after expiry to be rejected, got %+v err=%v", result, err)
+	}
+}
@@ -0,0 +1,105 @@
+package sifang
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localWeekdays 周几的中文写法，下标与 time.Weekday 一致
+var localWeekdays = [...]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// formatLocalDate 将 2006-01-02 形式的日期转为「2025年10月31日 周五」，无法解析时原样返回
+func formatLocalDate(date string) string {
+	date = strings.TrimSpace(date)
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return fmt.Sprintf("%d年%d月%d日 %s", parsed.Year(), int(parsed.Month()), parsed.Day(), localWeekdays[parsed.Weekday()])
+}
+
+// formatAmountText 将金额文本格式化为两位小数并加千分位，无法解析时原样返回
+func formatAmountText(raw string) string {
+	raw = strings.TrimSpace(raw)
+	value, ok := parseAmountToFloat(raw)
+	if !ok {
+		return raw
+	}
+	return formatThousands(value, 2)
+}
+
+// formatCountText 将笔数文本格式化为带千分位的整数，无法解析时原样返回
+func formatCountText(raw string) string {
+	raw = strings.TrimSpace(raw)
+	value, ok := parseAmountToFloat(raw)
+	if !ok {
+		return raw
+	}
+	return formatThousands(value, 0)
+}
+
+// formatThousands 按指定小数位格式化数字，整数部分每三位插入逗号
+func formatThousands(value float64, decimals int) string {
+	text := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	intPart, fracPart, hasFrac := strings.Cut(text, ".")
+
+	var sb strings.Builder
+	if value < 0 && strings.Trim(text, "0.") != "" {
+		sb.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	if hasFrac {
+		sb.WriteString("." + fracPart)
+	}
+	return sb.String()
+}
+
+// amountRow 等宽表格中的一行：左侧标签、右侧右对齐的数值
+type amountRow struct {
+	label string
+	value string
+}
+
+// amountColumnWidth 数值列宽度，多个表格共用同一宽度时各表的数字也能上下对齐
+func amountColumnWidth(rows []amountRow) int {
+	width := 0
+	for _, row := range rows {
+		if n := len([]rune(row.value)); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
+// formatAmountTable 将多行渲染为 <pre> 等宽块，数值按 width 右对齐
+// 标签须等宽（同为两个汉字或同为 HH:MM:SS），数值只含 ASCII，保证 Telegram 等宽字体下列对齐稳定
+func formatAmountTable(rows []amountRow, width int) string {
+	var sb strings.Builder
+	sb.WriteString("<pre>")
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		padding := width - len([]rune(row.value))
+		if padding < 0 {
+			padding = 0
+		}
+		sb.WriteString(html.EscapeString(row.label) + "  " + strings.Repeat(" ", padding) + html.EscapeString(row.value))
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// formatBalanceAmount 余额金额加粗显示，非数字（如「未知」）保持原样
+func formatBalanceAmount(raw string) string {
+	return "<b>" + html.EscapeString(formatAmountText(raw)) + "</b>"
+}
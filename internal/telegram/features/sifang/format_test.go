@@ -0,0 +1,57 @@
+package sifang
+
+import "testing"
+
+func TestFormatAmountText(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0.00"},
+		{"999.5", "999.50"},
+		{"1000", "1,000.00"},
+		{"4,231.5", "4,231.50"},
+		{"1234567.891", "1,234,567.89"},
+		{"-12345.6", "-12,345.60"},
+		{"-0.001", "0.00"},
+		{"未知", "未知"},
+		{" ", ""},
+	}
+
+	for _, tc := range cases {
+		if got := formatAmountText(tc.input); got != tc.expected {
+			t.Fatalf("formatAmountText(%q) expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestFormatCountText(t *testing.T) {
+	if got := formatCountText("12345"); got != "12,345" {
+		t.Fatalf("expected 12,345, got %q", got)
+	}
+	if got := formatCountText("abc"); got != "abc" {
+		t.Fatalf("expected raw text for invalid count, got %q", got)
+	}
+}
+
+func TestFormatLocalDate(t *testing.T) {
+	if got := formatLocalDate("2025-10-31"); got != "2025年10月31日 周五" {
+		t.Fatalf("unexpected local date: %q", got)
+	}
+	if got := formatLocalDate("2025/10/31"); got != "2025/10/31" {
+		t.Fatalf("expected raw date for unknown format, got %q", got)
+	}
+}
+
+func TestFormatAmountTableRightAligns(t *testing.T) {
+	rows := []amountRow{
+		{label: "10:00:00", value: "100.00"},
+		{label: "10:30:00", value: "12,000.00"},
+	}
+
+	got := formatAmountTable(rows, amountColumnWidth(rows))
+	expected := "<pre>10:00:00     100.00\n10:30:00  12,000.00</pre>"
+	if got != expected {
+		t.Fatalf("unexpected table:\n%s", got)
+	}
+}
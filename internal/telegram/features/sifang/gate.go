@@ -0,0 +1,45 @@
+package sifang
+
+import (
+	"context"
+	"fmt"
+
+	"go_bot/internal/telegram/models"
+)
+
+// 四方支付前置检查的引导提示
+const (
+	basicTierHint       = "ℹ️ 四方支付仅适用于商户群（当前：%s）\n请先使用「绑定 [商户号]」绑定商户号"
+	upstreamTierHint    = "ℹ️ 四方支付仅适用于商户群（当前：%s）\n本群已绑定接口 ID，请在商户群中查询"
+	disabledHint        = "ℹ️ 本群未开启四方支付查询\n请管理员发送 /configs，在「🏦 四方支付查询」中开启"
+	unboundMerchantHint = "ℹ️ 当前群组未绑定商户号，请先使用「绑定 [商户号]」命令"
+)
+
+// CheckGroup 四方支付的统一前置检查：群等级、功能开关、商户号绑定
+// 不满足时返回引导提示（告诉用户先绑定商户号或去 /configs 开启），满足时返回空字符串
+// Manager 对未启用/等级不符的群、Process 对未绑定商户号的群都使用同一组提示，保证各查询命令提示一致
+func CheckGroup(group *models.Group) string {
+	if group == nil {
+		return "ℹ️ 获取群组信息失败，请稍后重试"
+	}
+	tier := models.NormalizeGroupTier(group.Tier)
+	switch tier {
+	case models.GroupTierMerchant:
+	case models.GroupTierUpstream:
+		return fmt.Sprintf(upstreamTierHint, models.GroupTierDisplayName(tier))
+	default:
+		return fmt.Sprintf(basicTierHint, models.GroupTierDisplayName(tier))
+	}
+	if !group.Settings.SifangEnabled {
+		return disabledHint
+	}
+	if group.Settings.MerchantID == 0 {
+		return unboundMerchantHint
+	}
+	return ""
+}
+
+// Gate 实现 features.GatedFeature：未开启或群等级不符时，匹配的命令回复引导提示而非静默忽略
+func (f *Feature) Gate(ctx context.Context, group *models.Group) string {
+	return CheckGroup(group)
+}
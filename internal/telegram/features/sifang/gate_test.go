@@ -0,0 +1,44 @@
+package sifang
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestCheckGroup(t *testing.T) {
+	merchant := func(enabled bool, merchantID int32) *models.Group {
+		return &models.Group{
+			Tier:     models.GroupTierMerchant,
+			Settings: models.GroupSettings{SifangEnabled: enabled, MerchantID: merchantID},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		group *models.Group
+		want  string
+	}{
+		{name: "nil group", group: nil, want: "获取群组信息失败"},
+		{name: "basic tier", group: &models.Group{Tier: models.GroupTierBasic, Settings: models.GroupSettings{SifangEnabled: true}}, want: "请先使用「绑定 [商户号]」"},
+		{name: "upstream tier", group: &models.Group{Tier: models.GroupTierUpstream, Settings: models.GroupSettings{SifangEnabled: true}}, want: "本群已绑定接口 ID"},
+		{name: "disabled", group: merchant(false, 1001), want: "/configs"},
+		{name: "unbound", group: merchant(true, 0), want: "未绑定商户号"},
+		{name: "ok", group: merchant(true, 1001), want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CheckGroup(tc.group)
+			if tc.want == "" {
+				if got != "" {
+					t.Fatalf("expected no hint, got %q", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tc.want) {
+				t.Fatalf("expected hint containing %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package sifang
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/payment/sifang"
+)
+
+const (
+	googleCodeMaxFailures   = 5                // 连续失败达到该次数后锁定
+	googleCodeFailureWindow = 30 * time.Minute // 距上次失败超过该时长，失败计数重新开始
+	googleCodeLockDuration  = 30 * time.Minute // 锁定时长
+)
+
+// googleCodeFailure 同一群同一用户的谷歌验证码失败记录
+type googleCodeFailure struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// googleCodeKey 失败计数按群 + 用户区分
+func googleCodeKey(chatID, userID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, userID)
+}
+
+// isGoogleCodeError 判断下发失败是否由谷歌验证码错误导致（四方接口以错误文案区分）
+func isGoogleCodeError(err error) bool {
+	var apiErr *sifang.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "谷歌") || strings.Contains(message, "google") || strings.Contains(message, "验证码")
+}
+
+// googleCodeLockedUntil 返回锁定截止时间，未锁定时返回零值；顺带清理已过期的记录
+func (f *Feature) googleCodeLockedUntil(chatID, userID int64, now time.Time) time.Time {
+	f.codeMu.Lock()
+	defer f.codeMu.Unlock()
+
+	for key, failure := range f.codeFailures {
+		if now.After(failure.lockedUntil) && now.Sub(failure.lastFailure) > googleCodeFailureWindow {
+			delete(f.codeFailures, key)
+		}
+	}
+
+	failure, ok := f.codeFailures[googleCodeKey(chatID, userID)]
+	if !ok || !now.Before(failure.lockedUntil) {
+		return time.Time{}
+	}
+	return failure.lockedUntil
+}
+
+// recordGoogleCodeFailure 累计一次验证码失败，达到阈值时锁定并写审计日志，返回锁定截止时间（未锁定为零值）
+func (f *Feature) recordGoogleCodeFailure(chatID, userID int64, now time.Time) time.Time {
+	f.codeMu.Lock()
+	defer f.codeMu.Unlock()
+
+	if f.codeFailures == nil {
+		f.codeFailures = make(map[string]*googleCodeFailure)
+	}
+	key := googleCodeKey(chatID, userID)
+	failure, ok := f.codeFailures[key]
+	if !ok || now.Sub(failure.lastFailure) > googleCodeFailureWindow {
+		failure = &googleCodeFailure{}
+		f.codeFailures[key] = failure
+	}
+	failure.count++
+	failure.lastFailure = now
+
+	if failure.count < googleCodeMaxFailures {
+		logger.L().Warnf("Sifang google code failed: chat_id=%d user_id=%d failures=%d/%d", chatID, userID, failure.count, googleCodeMaxFailures)
+		return time.Time{}
+	}
+
+	failure.count = 0
+	failure.lockedUntil = now.Add(googleCodeLockDuration)
+	logger.L().Warnf("Send money audit: google code locked chat_id=%d user_id=%d failures=%d locked_until=%s",
+		chatID, userID, googleCodeMaxFailures, failure.lockedUntil.Format(time.RFC3339))
+	return failure.lockedUntil
+}
+
+// resetGoogleCodeFailures 验证码校验通过后清空失败计数
+func (f *Feature) resetGoogleCodeFailures(chatID, userID int64) {
+	f.codeMu.Lock()
+	delete(f.codeFailures, googleCodeKey(chatID, userID))
+	f.codeMu.Unlock()
+}
+
+// formatGoogleCodeLocked 锁定提示文案
+func formatGoogleCodeLocked(until time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = chinaLocation
+	}
+	return fmt.Sprintf("谷歌验证码连续错误 %d 次，下发已锁定至 %s，请稍后再试",
+		googleCodeMaxFailures, until.In(loc).Format("15:04"))
+}
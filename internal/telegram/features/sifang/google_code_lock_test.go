@@ -0,0 +1,101 @@
+package sifang
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/payment/sifang"
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestRecordGoogleCodeFailureLocksAfterThreshold(t *testing.T) {
+	f := New(nil, nil, nil, nil)
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 1; i < googleCodeMaxFailures; i++ {
+		if until := f.recordGoogleCodeFailure(-1, 7, now); !until.IsZero() {
+			t.Fatalf("unexpected lock after %d failures", i)
+		}
+	}
+	// 其他用户的失败不影响计数
+	f.recordGoogleCodeFailure(-1, 8, now)
+
+	until := f.recordGoogleCodeFailure(-1, 7, now)
+	if !until.Equal(now.Add(googleCodeLockDuration)) {
+		t.Fatalf("expected lock until %v, got %v", now.Add(googleCodeLockDuration), until)
+	}
+	if got := f.googleCodeLockedUntil(-1, 7, now.Add(time.Minute)); !got.Equal(until) {
+		t.Fatalf("expected user locked, got %v", got)
+	}
+	if got := f.googleCodeLockedUntil(-2, 7, now); !got.IsZero() {
+		t.Fatalf("expected lock scoped to chat, got %v", got)
+	}
+	if got := f.googleCodeLockedUntil(-1, 7, until); !got.IsZero() {
+		t.Fatalf("expected lock released at %v, got %v", until, got)
+	}
+}
+
+func TestRecordGoogleCodeFailureResetsAfterWindow(t *testing.T) {
+	f := New(nil, nil, nil, nil)
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 1; i < googleCodeMaxFailures; i++ {
+		f.recordGoogleCodeFailure(-1, 7, now)
+	}
+	later := now.Add(googleCodeFailureWindow + time.Second)
+	if until := f.recordGoogleCodeFailure(-1, 7, later); !until.IsZero() {
+		t.Fatalf("expected counter reset after window, got lock until %v", until)
+	}
+
+	f.resetGoogleCodeFailures(-1, 7)
+	if len(f.codeFailures) != 0 {
+		t.Fatalf("expected failures cleared, got %d", len(f.codeFailures))
+	}
+}
+
+func TestSendMoneyGoogleCodeLockout(t *testing.T) {
+	fakeSvc := &fakePaymentService{sendMoneyErr: &sifang.APIError{Code: 400, Message: "谷歌验证码错误"}}
+	feature := New(fakeSvc, &stubUserService{isAdmin: true}, nil, nil)
+
+	var result *SendMoneyCallbackResult
+	for i := 0; i < googleCodeMaxFailures; i++ {
+		pending, err := feature.createPendingSend(&pendingSendMoney{chatID: -1, userID: 123, merchantID: 2023100, amount: 12, googleCode: "123456", loc: chinaLocation})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		feature.takePending(pending.token)
+		result = feature.executeSendMoney(context.Background(), pending, &SendMoneyCallbackResult{})
+	}
+	if !strings.Contains(result.Text, "下发已锁定") {
+		t.Fatalf("expected lock notice after %d failures, got %q", googleCodeMaxFailures, result.Text)
+	}
+
+	msg := &botModels.Message{
+		Chat: botModels.Chat{ID: -1, Type: "group"},
+		From: &botModels.User{ID: 123},
+		Text: "下发 12 123456",
+	}
+	resp, _, err := feature.handleSendMoney(context.Background(), msg, 2023100, msg.Text, models.GroupSettings{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || resp.ReplyMarkup != nil || !strings.Contains(resp.Text, "下发已锁定") {
+		t.Fatalf("expected locked user rejected, got %+v", resp)
+	}
+}
+
+func TestIsGoogleCodeError(t *testing.T) {
+	if !isGoogleCodeError(&sifang.APIError{Message: "Google code invalid"}) {
+		t.Fatalf("expected google code error detected")
+	}
+	if isGoogleCodeError(&sifang.APIError{Message: "余额不足"}) {
+		t.Fatalf("expected unrelated api error ignored")
+	}
+	if isGoogleCodeError(context.DeadlineExceeded) {
+		t.Fatalf("expected non-api error ignored")
+	}
+}
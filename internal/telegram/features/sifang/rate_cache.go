@@ -0,0 +1,123 @@
+package sifang
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	paymentservice "go_bot/internal/payment/service"
+)
+
+const (
+	// channelRateCacheTTL 通道费率变化不频繁，缓存较长时间以降低上游压力
+	channelRateCacheTTL = 30 * time.Minute
+	// channelRateRefreshKeyword 「费率 刷新」绕过缓存强制查询上游
+	channelRateRefreshKeyword = "刷新"
+	// channelRateWarmTimeout 后台预热单个商户的超时
+	channelRateWarmTimeout = 10 * time.Second
+)
+
+// channelRateEntry 单个商户的通道费率缓存
+type channelRateEntry struct {
+	statuses  []*paymentservice.ChannelStatus
+	fetchedAt time.Time
+}
+
+// parseChannelRatesCommand 识别「费率」与「费率 刷新」，返回是否强制刷新
+func parseChannelRatesCommand(text string) (refresh bool, ok bool) {
+	if text == "费率" {
+		return false, true
+	}
+	if strings.HasPrefix(text, "费率") && strings.TrimSpace(strings.TrimPrefix(text, "费率")) == channelRateRefreshKeyword {
+		return true, true
+	}
+	return false, false
+}
+
+// cachedChannelRates 读取未过期的费率缓存
+func (f *Feature) cachedChannelRates(merchantID int64, now time.Time) (channelRateEntry, bool) {
+	f.rateMu.Lock()
+	defer f.rateMu.Unlock()
+
+	entry, ok := f.rates[merchantID]
+	if !ok {
+		return channelRateEntry{}, false
+	}
+	if now.Sub(entry.fetchedAt) >= channelRateCacheTTL {
+		delete(f.rates, merchantID)
+		return channelRateEntry{}, false
+	}
+	return *entry, true
+}
+
+// fetchChannelRates 查询上游费率并写入缓存，空结果不缓存
+func (f *Feature) fetchChannelRates(ctx context.Context, merchantID int64) ([]*paymentservice.ChannelStatus, error) {
+	statuses, err := f.paymentService.GetChannelStatus(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) > 0 {
+		f.rateMu.Lock()
+		if f.rates == nil {
+			f.rates = make(map[int64]*channelRateEntry)
+		}
+		f.rates[merchantID] = &channelRateEntry{statuses: statuses, fetchedAt: f.now()}
+		f.rateMu.Unlock()
+	}
+	return statuses, nil
+}
+
+// WarmChannelRates 后台预热商户的通道费率缓存，仅刷新即将过期（剩余不足一半 TTL）或缺失的项，返回成功刷新的商户数
+func (f *Feature) WarmChannelRates(ctx context.Context, merchantIDs []int64) int {
+	if f == nil || f.paymentService == nil {
+		return 0
+	}
+
+	warmed := 0
+	seen := make(map[int64]struct{}, len(merchantIDs))
+	for _, merchantID := range merchantIDs {
+		if merchantID == 0 {
+			continue
+		}
+		if _, dup := seen[merchantID]; dup {
+			continue
+		}
+		seen[merchantID] = struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := f.now()
+		if entry, ok := f.cachedChannelRates(merchantID, now); ok && now.Sub(entry.fetchedAt) < channelRateCacheTTL/2 {
+			continue
+		}
+
+		warmCtx, cancel := context.WithTimeout(ctx, channelRateWarmTimeout)
+		_, err := f.fetchChannelRates(warmCtx, merchantID)
+		cancel()
+		if err != nil {
+			logger.Ctx(ctx).Warnf("Sifang channel rates warm failed: merchant_id=%d, err=%v", merchantID, err)
+			continue
+		}
+		warmed++
+	}
+	return warmed
+}
+
+// now 当前时间，测试可通过 clock 注入
+func (f *Feature) now() time.Time {
+	if f.clock != nil {
+		return f.clock()
+	}
+	return time.Now()
+}
+
+// formatChannelRatesCachedAt 缓存命中时追加的更新时间说明
+func formatChannelRatesCachedAt(fetchedAt time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = chinaLocation
+	}
+	return fmt.Sprintf("🕒 更新于 %s（缓存），发送「费率 %s」获取最新", fetchedAt.In(loc).Format("15:04:05"), channelRateRefreshKeyword)
+}
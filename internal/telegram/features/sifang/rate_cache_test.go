@@ -0,0 +1,93 @@
+package sifang
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+)
+
+// countingRateService 记录费率查询次数
+type countingRateService struct {
+	fakePaymentService
+	calls int
+}
+
+func (s *countingRateService) GetChannelStatus(ctx context.Context, merchantID int64) ([]*paymentservice.ChannelStatus, error) {
+	s.calls++
+	return s.fakePaymentService.GetChannelStatus(ctx, merchantID)
+}
+
+func TestParseChannelRatesCommand(t *testing.T) {
+	cases := []struct {
+		text    string
+		refresh bool
+		ok      bool
+	}{
+		{"费率", false, true},
+		{"费率 刷新", true, true},
+		{"费率刷新", true, true},
+		{"费率 查询", false, false},
+		{"通道费率", false, false},
+	}
+	for _, tc := range cases {
+		refresh, ok := parseChannelRatesCommand(tc.text)
+		if refresh != tc.refresh || ok != tc.ok {
+			t.Fatalf("%q: expected (%v,%v), got (%v,%v)", tc.text, tc.refresh, tc.ok, refresh, ok)
+		}
+	}
+}
+
+func TestHandleChannelRatesUsesCache(t *testing.T) {
+	svc := &countingRateService{fakePaymentService: fakePaymentService{channelStatusResp: []*paymentservice.ChannelStatus{
+		{ChannelCode: "zft", ChannelName: "直付通", SystemEnabled: true, MerchantEnabled: true, Rate: "0.09"},
+	}}}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, chinaLocation)
+	f := New(svc, nil, nil, nil)
+	f.clock = func() time.Time { return now }
+
+	first, _, _ := f.handleChannelRates(context.Background(), 1001, false, chinaLocation)
+	if strings.Contains(first, "缓存") {
+		t.Fatalf("first query should not be served from cache: %s", first)
+	}
+
+	now = now.Add(10 * time.Minute)
+	second, _, _ := f.handleChannelRates(context.Background(), 1001, false, chinaLocation)
+	if svc.calls != 1 || !strings.Contains(second, "更新于 10:00:00（缓存）") {
+		t.Fatalf("expected cache hit, calls=%d message=%s", svc.calls, second)
+	}
+
+	f.handleChannelRates(context.Background(), 1001, true, chinaLocation)
+	if svc.calls != 2 {
+		t.Fatalf("expected refresh to bypass cache, calls=%d", svc.calls)
+	}
+
+	now = now.Add(channelRateCacheTTL)
+	f.handleChannelRates(context.Background(), 1001, false, chinaLocation)
+	if svc.calls != 3 {
+		t.Fatalf("expected expired cache to query upstream, calls=%d", svc.calls)
+	}
+}
+
+func TestWarmChannelRatesSkipsFreshEntries(t *testing.T) {
+	svc := &countingRateService{fakePaymentService: fakePaymentService{channelStatusResp: []*paymentservice.ChannelStatus{
+		{ChannelCode: "zft", Rate: "0.09"},
+	}}}
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, chinaLocation)
+	f := New(svc, nil, nil, nil)
+	f.clock = func() time.Time { return now }
+
+	if warmed := f.WarmChannelRates(context.Background(), []int64{1001, 1001, 0, 1002}); warmed != 2 || svc.calls != 2 {
+		t.Fatalf("expected 2 merchants warmed, got %d (calls=%d)", warmed, svc.calls)
+	}
+	if warmed := f.WarmChannelRates(context.Background(), []int64{1001, 1002}); warmed != 0 {
+		t.Fatalf("expected fresh entries skipped, got %d", warmed)
+	}
+
+	now = now.Add(channelRateCacheTTL/2 + time.Second)
+	if warmed := f.WarmChannelRates(context.Background(), []int64{1001}); warmed != 1 || svc.calls != 3 {
+		t.Fatalf("expected stale entry refreshed, got %d (calls=%d)", warmed, svc.calls)
+	}
+}
@@ -4,8 +4,16 @@ import botModels "github.com/go-telegram/bot/models"
 
 // Response 表示功能输出内容。
 // Text 按 HTML 解析，ReplyMarkup 用于附加按钮等交互组件。
+// Document 非空时以文件形式发送，Text 作为文件说明。
 type Response struct {
 	Text        string
 	ReplyMarkup botModels.ReplyMarkup
 	Temporary   bool // 标记为临时消息时由 handler 发送后自动删除
+	Document    *Document
+}
+
+// Document 功能输出的文件（如 CSV 导出）
+type Document struct {
+	Filename string
+	Data     []byte
 }
@@ -57,6 +57,19 @@ func (f *BalanceFeature) Enabled(ctx context.Context, group *models.Group) bool
 	return len(group.Settings.InterfaceBindings) > 0
 }
 
+// HelpLines 帮助文本
+func (f *BalanceFeature) HelpLines() []string {
+	return []string{
+		"<b>上游余额（Admin+）</b>",
+		"/余额 - 查询当前余额与阈值",
+		"<code>+金额 [备注]</code> / <code>-金额 [备注]</code> - 加款或扣款",
+		"/set_min_balance <code>金额</code> - 设置最低余额告警阈值",
+		"/set_balance_alert_limit <code>次数</code> - 设置每小时告警次数上限",
+		"/balance_config - 打开余额告警配置菜单（最低余额、告警限频、告警目标）",
+		"/日结 - 按昨日跑量与费率手动日结扣费",
+	}
+}
+
 // Match 匹配余额相关指令
 func (f *BalanceFeature) Match(ctx context.Context, msg *botModels.Message) bool {
 	if msg == nil || msg.Text == "" {
@@ -83,9 +96,9 @@ func (f *BalanceFeature) Process(ctx context.Context, msg *botModels.Message, gr
 		return nil, false, nil
 	}
 
-	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID)
+	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to check admin permission: user_id=%d err=%v", msg.From.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to check admin permission: user_id=%d err=%v", msg.From.ID, err)
 		return respond("❌ 权限检查失败"), true, nil
 	}
 	if !isAdmin {
@@ -104,10 +117,16 @@ func (f *BalanceFeature) Process(ctx context.Context, msg *botModels.Message, gr
 		resp, handlerErr := f.handleSetAlertLimit(ctx, msg, text)
 		return respond(resp), true, handlerErr
 	case text == "/日结":
-		resp, handlerErr := f.handleSettlement(ctx, msg)
+		if denied := f.requireGlobalAdmin(ctx, msg); denied != "" {
+			return respond(denied), true, nil
+		}
+		resp, handlerErr := f.handleSettlement(ctx, msg, group)
 		return respond(resp), true, handlerErr
 	default:
 		if adjustCommandPattern.MatchString(text) {
+			if denied := f.requireGlobalAdmin(ctx, msg); denied != "" {
+				return respond(denied), true, nil
+			}
 			resp, handlerErr := f.handleAdjust(ctx, msg, text)
 			return respond(resp), true, handlerErr
 		}
@@ -116,6 +135,19 @@ func (f *BalanceFeature) Process(ctx context.Context, msg *botModels.Message, gr
 	return nil, false, nil
 }
 
+// requireGlobalAdmin 调整余额与日结直接改动资金，只认可全局管理员，群级管理员不可用；拒绝时返回提示文本
+func (f *BalanceFeature) requireGlobalAdmin(ctx context.Context, msg *botModels.Message) string {
+	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID, 0)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to check global admin permission: user_id=%d err=%v", msg.From.ID, err)
+		return "❌ 权限检查失败"
+	}
+	if !isAdmin {
+		return "❌ 仅全局管理员可以调整余额或日结"
+	}
+	return ""
+}
+
 // Priority 在接口绑定之后
 func (f *BalanceFeature) Priority() int {
 	return 17
@@ -124,7 +156,7 @@ func (f *BalanceFeature) Priority() int {
 func (f *BalanceFeature) handleQueryBalance(ctx context.Context, msg *botModels.Message) (string, error) {
 	result, err := f.balanceService.Get(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Query balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Query balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		return "❌ 查询余额失败", nil
 	}
 
@@ -141,6 +173,25 @@ func (f *BalanceFeature) handleQueryBalance(ctx context.Context, msg *botModels.
 	), nil
 }
 
+// StatusLines 「状态」命令片段：当前余额与最低余额阈值
+func (f *BalanceFeature) StatusLines(ctx context.Context, group *models.Group) []string {
+	lines := []string{"<b>💰 上游余额</b>"}
+	result, err := f.balanceService.Get(ctx, group.TelegramID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Query balance for status failed: chat_id=%d err=%v", group.TelegramID, err)
+		return append(lines, "查询余额失败")
+	}
+
+	status := "✅ 余额正常"
+	if result.Balance < result.MinBalance {
+		status = "⚠️ 余额低于阈值"
+	}
+	return append(lines,
+		fmt.Sprintf("当前余额：%s CNY（%s）", formatAmount(result.Balance), status),
+		fmt.Sprintf("最低余额：%s CNY", formatAmount(result.MinBalance)),
+	)
+}
+
 func (f *BalanceFeature) handleSetMinBalance(ctx context.Context, msg *botModels.Message, text string) (string, error) {
 	fields := strings.Fields(text)
 	if len(fields) < 2 {
@@ -154,7 +205,7 @@ func (f *BalanceFeature) handleSetMinBalance(ctx context.Context, msg *botModels
 
 	result, err := f.balanceService.SetMinBalance(ctx, msg.Chat.ID, threshold, msg.From.ID)
 	if err != nil {
-		logger.L().Errorf("Set min balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Set min balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		return "❌ 设置失败", nil
 	}
 
@@ -175,21 +226,21 @@ func (f *BalanceFeature) handleSetAlertLimit(ctx context.Context, msg *botModels
 
 	result, err := f.balanceService.SetAlertLimit(ctx, msg.Chat.ID, limit, msg.From.ID)
 	if err != nil {
-		logger.L().Errorf("Set alert limit failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Set alert limit failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		return "❌ 设置失败", nil
 	}
 
 	return fmt.Sprintf("✅ 告警频率已更新为 每小时 %d 次\n当前余额：%s CNY", result.AlertLimitPerHour, formatAmount(result.Balance)), nil
 }
 
-func (f *BalanceFeature) handleSettlement(ctx context.Context, msg *botModels.Message) (string, error) {
+func (f *BalanceFeature) handleSettlement(ctx context.Context, msg *botModels.Message, group *models.Group) (string, error) {
 	now := f.currentTime()
-	target := previousBillingDate(now, upstreamChinaLocation)
-	operationID := fmt.Sprintf("settle:%s", target.Format("2006-01-02"))
+	target := models.PreviousBillingDate(now, models.GroupLocation(group.Settings))
+	operationID := service.SettlementOperationID(msg.Chat.ID, target)
 
 	result, err := f.balanceService.SettleDaily(ctx, msg.Chat.ID, target, msg.From.ID, operationID)
 	if err != nil {
-		logger.L().Errorf("Manual settlement failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Manual settlement failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		return fmt.Sprintf("❌ 日结失败：%v", err), nil
 	}
 
@@ -221,9 +272,10 @@ func (f *BalanceFeature) handleAdjust(ctx context.Context, msg *botModels.Messag
 		action = "扣款"
 	}
 
-	result, below, err := f.balanceService.Adjust(ctx, msg.Chat.ID, delta, msg.From.ID, remark, "")
+	// 同一条消息（重复投递的 update、重复处理）只生效一次
+	result, below, err := f.balanceService.Adjust(ctx, msg.Chat.ID, delta, msg.From.ID, remark, adjustOperationID(msg))
 	if err != nil {
-		logger.L().Errorf("Adjust balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Adjust balance failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		return "❌ 调整失败", nil
 	}
 
@@ -240,6 +292,11 @@ func (f *BalanceFeature) handleAdjust(ctx context.Context, msg *botModels.Messag
 	), nil
 }
 
+// adjustOperationID 基于群 ID 与消息 ID 生成加扣款的幂等键
+func adjustOperationID(msg *botModels.Message) string {
+	return fmt.Sprintf("adjust:%d:%d", msg.Chat.ID, msg.ID)
+}
+
 func (f *BalanceFeature) currentTime() time.Time {
 	if f.nowFunc != nil {
 		return f.nowFunc()
@@ -262,9 +319,3 @@ func parseAmount(raw string) (float64, error) {
 func formatAmount(value float64) string {
 	return fmt.Sprintf("%.2f", value)
 }
-
-func previousBillingDate(now time.Time, location *time.Location) time.Time {
-	local := now.In(location)
-	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
-	return midnight.AddDate(0, 0, -1)
-}
@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestBalanceFeatureAdjustIsIdempotentPerMessage(t *testing.T) {
+	balances := &idempotentBalanceService{seen: make(map[string]bool)}
+	feature := NewBalanceFeature(balances, adminUserService{}, nil)
+	group := &models.Group{TelegramID: -1, Tier: models.GroupTierUpstream}
+	newMessage := func(id int, text string) *botModels.Message {
+		return &botModels.Message{ID: id, Chat: botModels.Chat{ID: -1}, From: &botModels.User{ID: 7}, Text: text}
+	}
+
+	// 同一条消息并发重复处理（重复投递的 update）只加一次
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, handled, err := feature.Process(context.Background(), newMessage(10, "+1000"), group); !handled || err != nil {
+				t.Errorf("expected handled without error, got handled=%t err=%v", handled, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if balances.balance != 1000 {
+		t.Fatalf("expected balance 1000 after duplicate processing, got %.2f", balances.balance)
+	}
+
+	// 内容相同但是不同的消息分别生效
+	if _, _, err := feature.Process(context.Background(), newMessage(11, "+1000"), group); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := feature.Process(context.Background(), newMessage(12, "-500 退款"), group); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if balances.balance != 1500 {
+		t.Fatalf("expected balance 1500, got %.2f", balances.balance)
+	}
+	for _, id := range []string{"adjust:-1:10", "adjust:-1:11", "adjust:-1:12"} {
+		if !balances.seen[id] {
+			t.Fatalf("expected operation id %q, got %v", id, balances.seen)
+		}
+	}
+}
+
+// idempotentBalanceService 按 operationID 去重的内存余额服务
+type idempotentBalanceService struct {
+	service.UpstreamBalanceService
+	mu      sync.Mutex
+	seen    map[string]bool
+	balance float64
+}
+
+func (s *idempotentBalanceService) Adjust(ctx context.Context, groupID int64, delta float64, operatorID int64, remark string, operationID string) (*service.UpstreamBalanceResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if operationID == "" || !s.seen[operationID] {
+		s.balance += delta
+		s.seen[operationID] = true
+	}
+	return &service.UpstreamBalanceResult{GroupID: groupID, Balance: s.balance}, false, nil
+}
+
+type adminUserService struct {
+	service.UserService
+}
+
+func (adminUserService) CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	return true, nil
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"go_bot/internal/logger"
+	paymentservice "go_bot/internal/payment/service"
 	"go_bot/internal/telegram/features/types"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/service"
@@ -17,23 +20,41 @@ import (
 
 var (
 	interfaceIDPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
-	ratePattern            = regexp.MustCompile(`^\d+(\.\d+)?%?$`)
-	upstreamCommandPattern = regexp.MustCompile(`^(绑定接口\s+\S+.*|解绑接口(\s+\S+)?|接口ID|接口状态)$`)
+	ratePattern            = regexp.MustCompile(`^\d+(\.\d{1,4})?%?$`)
+	upstreamCommandPattern = regexp.MustCompile(`^(绑定接口\s+\S+.*|解绑接口(\s+.+)?|修改费率(\s+.*)?|接口ID|接口状态)$`)
 )
 
-const bindCommandGuide = "绑定接口 [接口名称] [接口ID] [接口费率]\n例如: 绑定接口 支付宝8888 123 7%"
+const (
+	bindCommandGuide       = "绑定接口 [接口名称] [接口ID] [接口费率]\n例如: 绑定接口 支付宝8888 123 7%"
+	updateRateCommandGuide = "修改费率 [接口ID] [接口费率]\n例如: 修改费率 123 6.5%"
+	rateFormatGuide        = "费率须为 0-100 之间的数值（最多 4 位小数），可带 % 符号\n例如: 7%、6.5、0.35%"
+	maxRatePercent         = 100
+)
+
+const (
+	// UnbindCallbackPrefix 解绑接口多项匹配时选择按钮的回调前缀：upunbind:pick:<接口ID> / upunbind:cancel
+	UnbindCallbackPrefix = "upunbind:"
+	unbindPickAction     = "pick"
+	unbindCancelAction   = "cancel"
+	// maxCallbackDataLen Telegram 回调数据上限（字节）
+	maxCallbackDataLen = 64
+)
 
 // Feature 处理接口 ID 绑定逻辑
 type Feature struct {
-	groupService service.GroupService
-	userService  service.UserService
+	groupService   service.GroupService
+	userService    service.UserService
+	paymentService paymentservice.Service // 查询接口当日跑量，为 nil 时「接口状态」只列出绑定信息
+	nowFunc        func() time.Time
 }
 
 // New 创建 Upstream 功能
-func New(groupService service.GroupService, userService service.UserService) *Feature {
+func New(groupService service.GroupService, userService service.UserService, paymentSvc paymentservice.Service) *Feature {
 	return &Feature{
-		groupService: groupService,
-		userService:  userService,
+		groupService:   groupService,
+		userService:    userService,
+		paymentService: paymentSvc,
+		nowFunc:        time.Now,
 	}
 }
 
@@ -55,6 +76,17 @@ func (f *Feature) Enabled(ctx context.Context, group *models.Group) bool {
 	return true
 }
 
+// HelpLines 帮助文本
+func (f *Feature) HelpLines() []string {
+	return []string{
+		"<b>接口管理（Admin+）</b>",
+		"绑定接口 <code>[接口名称] [接口ID] [费率]</code> - 绑定上游接口并保存名称/费率，可重复执行绑定多个接口",
+		"解绑接口 <code>[接口ID/名称]</code> - 解除指定接口，可按名称或名称片段匹配，多项匹配时需点选；仅发送“解绑接口”可清空全部",
+		"修改费率 <code>[接口ID] [费率]</code> - 修改已绑定接口的费率，无需解绑重绑",
+		"接口ID / 接口状态 - 查看当前已绑定的接口列表及今日跑量（按跑量排序）",
+	}
+}
+
 // Match 判断是否命中命令
 func (f *Feature) Match(ctx context.Context, msg *botModels.Message) bool {
 	if msg.Text == "" {
@@ -66,9 +98,9 @@ func (f *Feature) Match(ctx context.Context, msg *botModels.Message) bool {
 
 // Process 处理命令
 func (f *Feature) Process(ctx context.Context, msg *botModels.Message, group *models.Group) (*types.Response, bool, error) {
-	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID)
+	isAdmin, err := f.userService.CheckAdminPermission(ctx, msg.From.ID, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to check admin permission: user_id=%d, err=%v", msg.From.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to check admin permission: user_id=%d, err=%v", msg.From.ID, err)
 		return respond("❌ 权限检查失败"), true, nil
 	}
 	if !isAdmin {
@@ -81,8 +113,10 @@ func (f *Feature) Process(ctx context.Context, msg *botModels.Message, group *mo
 	case strings.HasPrefix(text, "绑定接口 "):
 		respText, handled, handlerErr := f.handleBind(ctx, msg, text)
 		return respond(respText), handled, handlerErr
-	case text == "解绑接口":
-		respText, handled, handlerErr := f.handleUnbind(ctx, msg)
+	case text == "解绑接口" || strings.HasPrefix(text, "解绑接口 "):
+		return f.handleUnbind(ctx, msg, text)
+	case text == "修改费率" || strings.HasPrefix(text, "修改费率 "):
+		respText, handled, handlerErr := f.handleUpdateRate(ctx, msg, text)
 		return respond(respText), handled, handlerErr
 	case text == "接口ID" || text == "接口状态":
 		respText, handled, handlerErr := f.handleQuery(ctx, msg)
@@ -106,16 +140,17 @@ func (f *Feature) handleBind(ctx context.Context, msg *botModels.Message, text s
 
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 获取群组信息失败", true, nil
 	}
 
 	if group.Settings.MerchantID != 0 {
-		return fmt.Sprintf("❌ 当前已绑定商户号: %d\n如需绑定接口，请先「解绑」商户号。", group.Settings.MerchantID), true, nil
+		return fmt.Sprintf("❌ 当前已绑定商户号: %d\n如需绑定接口，请先「解绑 全部」商户号。", group.Settings.MerchantID), true, nil
 	}
 
 	settings := group.Settings
 	settings.MerchantID = 0
+	settings.MerchantIDs = nil
 
 	newBinding := models.InterfaceBinding{
 		Name: name,
@@ -133,60 +168,126 @@ func (f *Feature) handleBind(ctx context.Context, msg *botModels.Message, text s
 	}
 
 	if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
-		logger.L().Errorf("Failed to bind interface ID: chat_id=%d, interface_id=%s, err=%v", msg.Chat.ID, interfaceID, err)
+		logger.Ctx(ctx).Errorf("Failed to bind interface ID: chat_id=%d, interface_id=%s, err=%v", msg.Chat.ID, interfaceID, err)
 		return "❌ 绑定失败，请稍后重试", true, nil
 	}
 
-	logger.L().Infof("Interface binding saved: chat_id=%d, interface_id=%s, name=%s, rate=%s, operator=%d",
+	logger.Ctx(ctx).Infof("Interface binding saved: chat_id=%d, interface_id=%s, name=%s, rate=%s, operator=%d",
 		msg.Chat.ID, interfaceID, name, rate, msg.From.ID)
 	return fmt.Sprintf("✅ 接口%s：%s", action, formatInterfaceBindingSummary(newBinding)), true, nil
 }
 
-func (f *Feature) handleUnbind(ctx context.Context, msg *botModels.Message) (string, bool, error) {
+func (f *Feature) handleUnbind(ctx context.Context, msg *botModels.Message, text string) (*types.Response, bool, error) {
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
-		return "❌ 获取群组信息失败", true, nil
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		return respond("❌ 获取群组信息失败"), true, nil
 	}
 
 	current := group.Settings.InterfaceBindings
 	if len(current) == 0 {
-		return "ℹ️ 当前群组未绑定接口 ID", true, nil
+		return respond("ℹ️ 当前群组未绑定接口 ID"), true, nil
 	}
 
-	parts := strings.Fields(strings.TrimSpace(msg.Text))
+	target := strings.TrimSpace(strings.TrimPrefix(text, "解绑接口"))
 	settings := group.Settings
-	if len(parts) == 1 {
+	if target == "" {
 		settings.InterfaceBindings = nil
 		if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
-			logger.L().Errorf("Failed to unbind all interface IDs: chat_id=%d, err=%v", msg.Chat.ID, err)
-			return "❌ 解绑失败，请稍后重试", true, nil
+			logger.Ctx(ctx).Errorf("Failed to unbind all interface IDs: chat_id=%d, err=%v", msg.Chat.ID, err)
+			return respond("❌ 解绑失败，请稍后重试"), true, nil
 		}
-		logger.L().Infof("All interface IDs unbound: chat_id=%d, operator=%d", msg.Chat.ID, msg.From.ID)
-		return "✅ 已解绑所有接口 ID", true, nil
+		logger.Ctx(ctx).Infof("All interface IDs unbound: chat_id=%d, operator=%d", msg.Chat.ID, msg.From.ID)
+		return respond("✅ 已解绑所有接口 ID"), true, nil
+	}
+
+	matches := matchInterfaceBindings(current, target)
+	switch len(matches) {
+	case 0:
+		return respond(fmt.Sprintf("ℹ️ 未找到匹配「%s」的接口\n可发送「接口ID」查看已绑定接口", html.EscapeString(target))), true, nil
+	case 1:
+		return respond(f.unbindInterface(ctx, msg.Chat.ID, settings, matches[0].ID, msg.From.ID)), true, nil
+	default:
+		// 多项匹配时不直接删除，列出候选项由管理员点选
+		return &types.Response{
+			Text:        formatUnbindCandidates(target, matches),
+			ReplyMarkup: buildUnbindKeyboard(matches),
+		}, true, nil
+	}
+}
+
+// HandleUnbindCallback 处理多项匹配时的解绑选择按钮，返回用于替换原消息的文本
+func (f *Feature) HandleUnbindCallback(ctx context.Context, chatID, operatorID int64, data string) string {
+	action, interfaceID, ok := parseUnbindCallback(data)
+	if !ok {
+		return "❌ 无效的操作"
+	}
+	if action == unbindCancelAction {
+		return "🚫 已取消解绑"
 	}
 
-	target := parts[1]
-	newList, removed := removeInterfaceBinding(current, target)
+	group, err := f.groupService.GetGroupInfo(ctx, chatID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", chatID, err)
+		return "❌ 获取群组信息失败"
+	}
+	return f.unbindInterface(ctx, chatID, group.Settings, interfaceID, operatorID)
+}
+
+// unbindInterface 按精确接口 ID 解绑并保存，返回结果文本
+func (f *Feature) unbindInterface(ctx context.Context, chatID int64, settings models.GroupSettings, interfaceID string, operatorID int64) string {
+	newList, removed := removeInterfaceBinding(settings.InterfaceBindings, interfaceID)
 	if removed == nil {
-		return fmt.Sprintf("ℹ️ 未找到接口 ID: %s", target), true, nil
+		return fmt.Sprintf("ℹ️ 未找到接口 ID: %s", html.EscapeString(interfaceID))
 	}
 
 	settings.InterfaceBindings = newList
+	if err := f.groupService.UpdateGroupSettings(ctx, chatID, settings); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to unbind interface ID: chat_id=%d, interface_id=%s, err=%v", chatID, interfaceID, err)
+		return "❌ 解绑失败，请稍后重试"
+	}
+
+	logger.Ctx(ctx).Infof("Interface ID unbound: chat_id=%d, interface_id=%s, operator=%d", chatID, removed.ID, operatorID)
+	return fmt.Sprintf("✅ 已解绑接口：%s", formatInterfaceBindingSummary(*removed))
+}
+
+func (f *Feature) handleUpdateRate(ctx context.Context, msg *botModels.Message, text string) (string, bool, error) {
+	interfaceID, rate, errMsg := parseUpdateRateArguments(text)
+	if errMsg != "" {
+		return errMsg, true, nil
+	}
+
+	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		return "❌ 获取群组信息失败", true, nil
+	}
+
+	settings := group.Settings
+	idx := findBindingIndex(settings.InterfaceBindings, interfaceID)
+	if idx < 0 {
+		return fmt.Sprintf("ℹ️ 未找到接口 ID: %s\n可发送「接口ID」查看已绑定接口", html.EscapeString(interfaceID)), true, nil
+	}
+
+	bindings := append([]models.InterfaceBinding(nil), settings.InterfaceBindings...)
+	oldRate := bindings[idx].Rate
+	bindings[idx].Rate = rate
+	settings.InterfaceBindings = bindings
 
 	if err := f.groupService.UpdateGroupSettings(ctx, msg.Chat.ID, settings); err != nil {
-		logger.L().Errorf("Failed to unbind interface ID: chat_id=%d, interface_id=%s, err=%v", msg.Chat.ID, target, err)
-		return "❌ 解绑失败，请稍后重试", true, nil
+		logger.Ctx(ctx).Errorf("Failed to update interface rate: chat_id=%d, interface_id=%s, err=%v", msg.Chat.ID, interfaceID, err)
+		return "❌ 修改费率失败，请稍后重试", true, nil
 	}
 
-	logger.L().Infof("Interface ID unbound: chat_id=%d, interface_id=%s, operator=%d", msg.Chat.ID, target, msg.From.ID)
-	return fmt.Sprintf("✅ 已解绑接口：%s", formatInterfaceBindingSummary(*removed)), true, nil
+	logger.Ctx(ctx).Infof("Interface rate updated: chat_id=%d, interface_id=%s, rate=%s -> %s, operator=%d",
+		msg.Chat.ID, bindings[idx].ID, oldRate, rate, msg.From.ID)
+	return fmt.Sprintf("✅ 费率已更新：%s", formatInterfaceBindingSummary(bindings[idx])), true, nil
 }
 
 func (f *Feature) handleQuery(ctx context.Context, msg *botModels.Message) (string, bool, error) {
 	group, err := f.groupService.GetGroupInfo(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info: chat_id=%d, err=%v", msg.Chat.ID, err)
 		return "❌ 获取群组信息失败", true, nil
 	}
 
@@ -195,15 +296,33 @@ func (f *Feature) handleQuery(ctx context.Context, msg *botModels.Message) (stri
 	}
 
 	builder := strings.Builder{}
-	builder.WriteString("✅ 当前绑定接口：\n")
-	for _, binding := range group.Settings.InterfaceBindings {
-		builder.WriteString(fmt.Sprintf("• %s\n", formatInterfaceBindingSummary(binding)))
+	if f.paymentService != nil {
+		today := f.nowFunc().In(models.GroupLocation(group.Settings))
+		volumes := queryInterfaceVolumes(ctx, f.paymentService, group.Settings.InterfaceBindings, today)
+		builder.WriteString(formatInterfaceVolumes(volumes, today))
+	} else {
+		builder.WriteString("✅ 当前绑定接口：\n")
+		for _, binding := range group.Settings.InterfaceBindings {
+			builder.WriteString(fmt.Sprintf("• %s\n", formatInterfaceBindingSummary(binding)))
+		}
 	}
-	builder.WriteString("\n使用「解绑接口 [接口ID]」解除单个接口，或直接发送「解绑接口」清空全部")
+	builder.WriteString("\n使用「解绑接口 [接口ID/名称]」解除单个接口，或直接发送「解绑接口」清空全部")
 
 	return builder.String(), true, nil
 }
 
+// StatusLines 「状态」命令片段：接口名称、ID 与费率
+func (f *Feature) StatusLines(ctx context.Context, group *models.Group) []string {
+	lines := []string{"<b>🔌 接口绑定</b>"}
+	if len(group.Settings.InterfaceBindings) == 0 {
+		return append(lines, "未绑定")
+	}
+	for _, binding := range group.Settings.InterfaceBindings {
+		lines = append(lines, "• "+formatInterfaceBindingSummary(binding))
+	}
+	return lines
+}
+
 func respond(text string) *types.Response {
 	if strings.TrimSpace(text) == "" {
 		return nil
@@ -231,26 +350,42 @@ func parseBindArguments(text string) (name, interfaceID, rate, errMsg string) {
 	rawRate := parts[len(parts)-1]
 	normalizedRate, ok := normalizeRateInput(rawRate)
 	if !ok {
-		return "", "", "", "❌ 费率仅支持数字，可选结尾 % 符号\n例如: 7%"
+		return "", "", "", fmt.Sprintf("❌ 费率 %s 无效，已拒绝绑定\n%s", html.EscapeString(rawRate), rateFormatGuide)
 	}
 
 	return name, interfaceID, normalizedRate, ""
 }
 
+func parseUpdateRateArguments(text string) (interfaceID, rate, errMsg string) {
+	parts := strings.Fields(text)
+	if len(parts) != 3 {
+		return "", "", fmt.Sprintf("❌ 格式错误，请使用: %s", updateRateCommandGuide)
+	}
+
+	interfaceID = parts[1]
+	if !interfaceIDPattern.MatchString(interfaceID) {
+		return "", "", "❌ 接口 ID 仅支持字母、数字、下划线或中划线"
+	}
+
+	rate, ok := normalizeRateInput(parts[2])
+	if !ok {
+		return "", "", fmt.Sprintf("❌ 费率 %s 无效\n%s", html.EscapeString(parts[2]), rateFormatGuide)
+	}
+	return interfaceID, rate, ""
+}
+
+// normalizeRateInput 校验费率并统一保存为「百分比数值%」，避免日结时把不带 % 的小数误读
 func normalizeRateInput(raw string) (string, bool) {
-	trimmed := strings.TrimSpace(raw)
+	trimmed := strings.ReplaceAll(strings.TrimSpace(raw), "％", "%")
 	if trimmed == "" || !ratePattern.MatchString(trimmed) {
 		return "", false
 	}
 
-	if strings.HasSuffix(trimmed, "%") {
-		withoutPercent := strings.TrimSpace(strings.TrimSuffix(trimmed, "%"))
-		if withoutPercent == "" {
-			return "", false
-		}
-		return fmt.Sprintf("%s%%", withoutPercent), true
+	value, err := strconv.ParseFloat(strings.TrimSuffix(trimmed, "%"), 64)
+	if err != nil || value < 0 || value > maxRatePercent {
+		return "", false
 	}
-	return trimmed, true
+	return strconv.FormatFloat(value, 'f', -1, 64) + "%", true
 }
 
 func findBindingIndex(bindings []models.InterfaceBinding, target string) int {
@@ -288,6 +423,82 @@ func removeInterfaceBinding(bindings []models.InterfaceBinding, target string) (
 	return result, removed
 }
 
+// matchInterfaceBindings 按优先级匹配待解绑接口：接口 ID 完全相同 > 名称完全相同 > 名称或 ID 包含关键字（均不区分大小写）
+func matchInterfaceBindings(bindings []models.InterfaceBinding, target string) []models.InterfaceBinding {
+	keyword := strings.ToLower(strings.TrimSpace(target))
+	if keyword == "" {
+		return nil
+	}
+
+	if idx := findBindingIndex(bindings, keyword); idx >= 0 {
+		return []models.InterfaceBinding{bindings[idx]}
+	}
+
+	var exact, partial []models.InterfaceBinding
+	for _, binding := range bindings {
+		name := strings.ToLower(strings.TrimSpace(binding.Name))
+		switch {
+		case name == keyword:
+			exact = append(exact, binding)
+		case strings.Contains(name, keyword) || strings.Contains(strings.ToLower(binding.ID), keyword):
+			partial = append(partial, binding)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+// formatUnbindCandidates 多项匹配时的候选列表
+func formatUnbindCandidates(target string, matches []models.InterfaceBinding) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔍 找到 %d 个匹配「%s」的接口，请点击选择要解绑的一项：\n", len(matches), html.EscapeString(target)))
+	for i, binding := range matches {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatInterfaceBindingSummary(binding)))
+		if !unbindCallbackFits(binding.ID) {
+			sb.WriteString("   （ID 过长无法生成按钮，请发送「解绑接口 完整接口ID」）\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// buildUnbindKeyboard 每个候选接口一个按钮，末尾附取消按钮
+func buildUnbindKeyboard(matches []models.InterfaceBinding) *botModels.InlineKeyboardMarkup {
+	rows := make([][]botModels.InlineKeyboardButton, 0, len(matches)+1)
+	for i, binding := range matches {
+		if !unbindCallbackFits(binding.ID) {
+			continue
+		}
+		rows = append(rows, []botModels.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("%d. 解绑 %s（%s）", i+1, bindingDisplayName(binding.Name), binding.ID),
+			CallbackData: UnbindCallbackPrefix + unbindPickAction + ":" + binding.ID,
+		}})
+	}
+	rows = append(rows, []botModels.InlineKeyboardButton{{
+		Text:         "❌ 取消",
+		CallbackData: UnbindCallbackPrefix + unbindCancelAction,
+	}})
+	return &botModels.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func unbindCallbackFits(interfaceID string) bool {
+	return len(UnbindCallbackPrefix+unbindPickAction+":"+interfaceID) <= maxCallbackDataLen
+}
+
+// parseUnbindCallback 解析回调数据：upunbind:pick:<接口ID> 或 upunbind:cancel
+func parseUnbindCallback(data string) (string, string, bool) {
+	payload := strings.TrimPrefix(data, UnbindCallbackPrefix)
+	if payload == unbindCancelAction {
+		return unbindCancelAction, "", true
+	}
+	action, interfaceID, found := strings.Cut(payload, ":")
+	if !found || action != unbindPickAction || !interfaceIDPattern.MatchString(interfaceID) {
+		return "", "", false
+	}
+	return action, interfaceID, true
+}
+
 func formatInterfaceBindingSummary(binding models.InterfaceBinding) string {
 	name := bindingDisplayName(binding.Name)
 	rate := strings.TrimSpace(binding.Rate)
@@ -0,0 +1,257 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestNormalizeRateInput(t *testing.T) {
+	valid := map[string]string{
+		"7%":     "7%",
+		"7":      "7%",
+		"6.5":    "6.5%",
+		"0.35%":  "0.35%",
+		"07.50%": "7.5%",
+		"0":      "0%",
+		"100%":   "100%",
+		"8％":     "8%",
+	}
+	for input, want := range valid {
+		got, ok := normalizeRateInput(input)
+		if !ok || got != want {
+			t.Fatalf("normalizeRateInput(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+
+	for _, input := range []string{"", "%", "abc", "-1", "100.01", "7%%", "1.23456", "1e2", "7 %"} {
+		if got, ok := normalizeRateInput(input); ok {
+			t.Fatalf("expected %q to be rejected, got %q", input, got)
+		}
+	}
+}
+
+func TestParseBindArgumentsRejectsInvalidRate(t *testing.T) {
+	name, id, rate, errMsg := parseBindArguments("绑定接口 支付宝 8888 1024 0.5")
+	if errMsg != "" || name != "支付宝 8888" || id != "1024" || rate != "0.5%" {
+		t.Fatalf("unexpected parse: name=%q id=%q rate=%q err=%q", name, id, rate, errMsg)
+	}
+
+	if _, _, _, errMsg := parseBindArguments("绑定接口 支付宝 1024 150%"); !strings.Contains(errMsg, "已拒绝绑定") {
+		t.Fatalf("expected out-of-range rate to be rejected, got %q", errMsg)
+	}
+	if _, _, _, errMsg := parseBindArguments("绑定接口 支付宝 1024 <b>"); !strings.Contains(errMsg, "&lt;b&gt;") {
+		t.Fatalf("expected rejected rate to be escaped, got %q", errMsg)
+	}
+}
+
+func TestFeatureUpdateRate(t *testing.T) {
+	groups := &stubGroupService{group: &models.Group{
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{
+				{Name: "支付宝", ID: "1024", Rate: "7%"},
+				{Name: "微信", ID: "2048", Rate: "5%"},
+			},
+		},
+	}}
+	feature := New(groups, nil, nil)
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, From: &botModels.User{ID: 1}}
+
+	text, handled, err := feature.handleUpdateRate(context.Background(), msg, "修改费率 1024 6.5%")
+	if err != nil || !handled || !strings.Contains(text, "费率已更新") {
+		t.Fatalf("unexpected result: text=%q handled=%v err=%v", text, handled, err)
+	}
+	if groups.saved == nil || groups.saved.InterfaceBindings[0].Rate != "6.5%" || groups.saved.InterfaceBindings[1].Rate != "5%" {
+		t.Fatalf("expected only the target rate to change, got %+v", groups.saved)
+	}
+	if groups.group.Settings.InterfaceBindings[0].Rate != "7%" {
+		t.Fatalf("expected original settings to be left untouched")
+	}
+
+	groups.saved = nil
+	for _, input := range []string{"修改费率 9999 6%", "修改费率 1024 101", "修改费率 1024"} {
+		text, _, _ := feature.handleUpdateRate(context.Background(), msg, input)
+		if groups.saved != nil || !strings.HasPrefix(text, "❌") && !strings.HasPrefix(text, "ℹ️") {
+			t.Fatalf("expected %q to be rejected without saving, got %q", input, text)
+		}
+	}
+}
+
+func TestMatchInterfaceBindings(t *testing.T) {
+	bindings := []models.InterfaceBinding{
+		{Name: "支付宝8888", ID: "1024"},
+		{Name: "支付宝9999", ID: "2048"},
+		{Name: "微信", ID: "wx01"},
+		{Name: "支付宝", ID: "4096"},
+	}
+
+	cases := []struct {
+		target string
+		want   []string
+	}{
+		{"2048", []string{"2048"}},
+		{"WX01", []string{"wx01"}},
+		{"支付宝", []string{"4096"}},
+		{"支付宝8", []string{"1024"}},
+		{"支付", []string{"1024", "2048", "4096"}},
+		{"wx", []string{"wx01"}},
+		{"银联", nil},
+	}
+	for _, tc := range cases {
+		got := matchInterfaceBindings(bindings, tc.target)
+		ids := make([]string, 0, len(got))
+		for _, binding := range got {
+			ids = append(ids, binding.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("matchInterfaceBindings(%q) = %v, want %v", tc.target, ids, tc.want)
+		}
+	}
+}
+
+func TestFeatureUnbindByName(t *testing.T) {
+	newGroups := func() *stubGroupService {
+		return &stubGroupService{group: &models.Group{
+			Settings: models.GroupSettings{
+				InterfaceBindings: []models.InterfaceBinding{
+					{Name: "支付宝8888", ID: "1024", Rate: "7%"},
+					{Name: "支付宝9999", ID: "2048", Rate: "6%"},
+					{Name: "微信", ID: "wx01", Rate: "5%"},
+				},
+			},
+		}}
+	}
+	msg := &botModels.Message{Chat: botModels.Chat{ID: -1}, From: &botModels.User{ID: 1}}
+
+	groups := newGroups()
+	feature := New(groups, nil, nil)
+	resp, handled, err := feature.handleUnbind(context.Background(), msg, "解绑接口 微")
+	if err != nil || !handled || !strings.Contains(resp.Text, "已解绑接口") {
+		t.Fatalf("expected unique match to unbind, got %+v err=%v", resp, err)
+	}
+	if groups.saved == nil || len(groups.saved.InterfaceBindings) != 2 {
+		t.Fatalf("expected wx01 to be removed, got %+v", groups.saved)
+	}
+
+	groups = newGroups()
+	feature = New(groups, nil, nil)
+	resp, _, _ = feature.handleUnbind(context.Background(), msg, "解绑接口 支付宝")
+	if groups.saved != nil {
+		t.Fatalf("expected multiple matches not to unbind directly")
+	}
+	markup, ok := resp.ReplyMarkup.(*botModels.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 3 || !strings.Contains(resp.Text, "找到 2 个") {
+		t.Fatalf("expected two candidate buttons plus cancel, got %+v", resp)
+	}
+	data := markup.InlineKeyboard[1][0].CallbackData
+	if data != UnbindCallbackPrefix+"pick:2048" {
+		t.Fatalf("unexpected callback data: %q", data)
+	}
+
+	text := feature.HandleUnbindCallback(context.Background(), -1, 1, data)
+	if !strings.Contains(text, "已解绑接口") || len(groups.saved.InterfaceBindings) != 2 || groups.saved.InterfaceBindings[1].ID != "wx01" {
+		t.Fatalf("expected 2048 to be removed via callback, text=%q saved=%+v", text, groups.saved)
+	}
+
+	groups = newGroups()
+	feature = New(groups, nil, nil)
+	if text := feature.HandleUnbindCallback(context.Background(), -1, 1, UnbindCallbackPrefix+"cancel"); !strings.Contains(text, "已取消") || groups.saved != nil {
+		t.Fatalf("expected cancel to keep bindings, got %q", text)
+	}
+	if text := feature.HandleUnbindCallback(context.Background(), -1, 1, UnbindCallbackPrefix+"pick:<b>"); !strings.Contains(text, "无效") {
+		t.Fatalf("expected malformed callback to be rejected, got %q", text)
+	}
+
+	resp, _, _ = feature.handleUnbind(context.Background(), msg, "解绑接口 银联")
+	if groups.saved != nil || !strings.Contains(resp.Text, "未找到匹配") {
+		t.Fatalf("expected no match hint, got %+v", resp)
+	}
+}
+
+type stubGroupService struct {
+	service.GroupService
+	group *models.Group
+	saved *models.GroupSettings
+}
+
+func (s *stubGroupService) GetGroupInfo(ctx context.Context, telegramID int64) (*models.Group, error) {
+	return s.group, nil
+}
+
+func (s *stubGroupService) UpdateGroupSettings(ctx context.Context, telegramID int64, settings models.GroupSettings) error {
+	s.saved = &settings
+	return nil
+}
+
+func TestFeatureQueryShowsVolumesSortedByAmount(t *testing.T) {
+	payments := &volumePaymentService{
+		summaries: map[string]*paymentservice.SummaryByPZID{
+			"1001": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "2024-11-20", GrossAmount: "200"}}},
+			"2002": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "2024-11-20", GrossAmount: "1500.5"}}},
+			"3003": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "2024-11-19", GrossAmount: "999"}}},
+		},
+		errs: map[string]error{"4004": errors.New("timeout")},
+	}
+	groups := &stubGroupService{group: &models.Group{
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{
+				{Name: "渠道A", ID: "1001", Rate: "7%"},
+				{Name: "渠道B", ID: "2002", Rate: "6%"},
+				{Name: "渠道C", ID: "3003", Rate: "5%"},
+				{Name: "渠道D", ID: "4004", Rate: "4%"},
+			},
+		},
+	}}
+	feature := New(groups, nil, payments)
+	feature.nowFunc = func() time.Time { return time.Date(2024, 11, 20, 4, 0, 0, 0, time.UTC) }
+
+	text, handled, err := feature.handleQuery(context.Background(), &botModels.Message{Chat: botModels.Chat{ID: -1}})
+	if err != nil || !handled {
+		t.Fatalf("unexpected result: handled=%v err=%v", handled, err)
+	}
+	if len(payments.calls) != 4 {
+		t.Fatalf("expected every interface to be queried, got %v", payments.calls)
+	}
+
+	order := []string{"2002", "1001", "3003", "4004"}
+	last := -1
+	for _, id := range order {
+		idx := strings.Index(text, "<code>"+id+"</code>")
+		if idx <= last {
+			t.Fatalf("expected interfaces sorted by volume %v, got:\n%s", order, text)
+		}
+		last = idx
+	}
+	for _, want := range []string{"2024-11-20 跑量", "跑量：1500.50", "跑量：0（暂无数据）", "⚠️ 跑量查询失败", "合计跑量：1700.50（1 个接口查询失败，未计入）"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+}
+
+type volumePaymentService struct {
+	paymentservice.Service
+	mu        sync.Mutex
+	summaries map[string]*paymentservice.SummaryByPZID
+	errs      map[string]error
+	calls     []string
+}
+
+func (s *volumePaymentService) GetSummaryByDayByPZID(ctx context.Context, pzid string, start, end time.Time) (*paymentservice.SummaryByPZID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, pzid)
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("expected per-interface timeout")
+	}
+	return s.summaries[pzid], s.errs[pzid]
+}
@@ -0,0 +1,122 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// interfaceVolumeConcurrency 同时查询的接口数，避免瞬间压垮四方接口
+	interfaceVolumeConcurrency = 4
+	// interfaceVolumeTimeout 单个接口的查询超时
+	interfaceVolumeTimeout = 8 * time.Second
+)
+
+// interfaceVolume 单个接口当日跑量查询结果
+type interfaceVolume struct {
+	Binding models.InterfaceBinding
+	Volume  float64
+	HasData bool  // 当日有账单记录
+	Err     error // 查询或解析失败
+}
+
+// queryInterfaceVolumes 并发查询各接口在 date 当天的跑量，单个接口失败只记录在对应结果中
+func queryInterfaceVolumes(ctx context.Context, paymentSvc paymentservice.Service, bindings []models.InterfaceBinding, date time.Time) []interfaceVolume {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.Add(24*time.Hour - time.Second)
+
+	results := make([]interfaceVolume, len(bindings))
+
+	runner, runCtx := errgroup.WithContext(ctx)
+	runner.SetLimit(interfaceVolumeConcurrency)
+	for i, binding := range bindings {
+		runner.Go(func() error {
+			queryCtx, cancel := context.WithTimeout(runCtx, interfaceVolumeTimeout)
+			summary, err := paymentSvc.GetSummaryByDayByPZID(queryCtx, binding.ID, start, end)
+			cancel()
+			if err != nil {
+				logger.Ctx(ctx).Warnf("Interface volume query failed: pzid=%s date=%s err=%v", binding.ID, start.Format("2006-01-02"), err)
+			}
+
+			results[i] = buildInterfaceVolume(binding, summary, date, err)
+			return nil
+		})
+	}
+	_ = runner.Wait()
+
+	sortInterfaceVolumes(results)
+	return results
+}
+
+// buildInterfaceVolume 从账单汇总中解析当日跑量
+func buildInterfaceVolume(binding models.InterfaceBinding, summary *paymentservice.SummaryByPZID, date time.Time, err error) interfaceVolume {
+	result := interfaceVolume{Binding: binding, Err: err}
+	if err != nil {
+		return result
+	}
+
+	item := pickSummaryItem(summary, date)
+	if item == nil {
+		return result
+	}
+	result.HasData = true
+	if raw := strings.TrimSpace(item.GrossAmount); raw != "" {
+		volume, parseErr := strconv.ParseFloat(raw, 64)
+		if parseErr != nil {
+			result.Err = fmt.Errorf("跑量解析失败: %w", parseErr)
+			return result
+		}
+		result.Volume = volume
+	}
+	return result
+}
+
+// sortInterfaceVolumes 按跑量从高到低排序，查询失败的接口排在最后，同量时保持绑定顺序
+func sortInterfaceVolumes(results []interfaceVolume) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		return results[i].Volume > results[j].Volume
+	})
+}
+
+// formatInterfaceVolumes 格式化带当日跑量的接口列表
+func formatInterfaceVolumes(results []interfaceVolume, date time.Time) string {
+	var (
+		builder strings.Builder
+		total   float64
+		failed  int
+	)
+	builder.WriteString(fmt.Sprintf("✅ 当前绑定接口（%s 跑量）：\n", date.Format("2006-01-02")))
+	for _, result := range results {
+		summary := formatInterfaceBindingSummary(result.Binding)
+		switch {
+		case result.Err != nil:
+			failed++
+			builder.WriteString(fmt.Sprintf("• %s\n  ⚠️ 跑量查询失败\n", summary))
+		case !result.HasData:
+			builder.WriteString(fmt.Sprintf("• %s\n  跑量：0（暂无数据）\n", summary))
+		default:
+			total += result.Volume
+			builder.WriteString(fmt.Sprintf("• %s\n  跑量：%.2f\n", summary, result.Volume))
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("\n合计跑量：%.2f", total))
+	if failed > 0 {
+		builder.WriteString(fmt.Sprintf("（%d 个接口查询失败，未计入）", failed))
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
@@ -59,6 +59,15 @@ func (f *SummaryFeature) Enabled(ctx context.Context, group *models.Group) bool
 	return len(group.Settings.InterfaceBindings) > 0
 }
 
+// HelpLines 帮助文本
+func (f *SummaryFeature) HelpLines() []string {
+	return []string{
+		"<b>上游账单查询</b>",
+		"上游账单 <code>[接口ID或名称] [可选日期]</code> - 查询指定接口的跑量、成交和笔数，日期默认为当天",
+		"上游账单 <code>[接口ID或名称] [开始日期] [结束日期]</code> - 按天汇总区间内跑量、商户实收、代理收益和笔数并给出合计，最长 31 天",
+	}
+}
+
 // Match 匹配「上游账单」指令
 func (f *SummaryFeature) Match(ctx context.Context, msg *botModels.Message) bool {
 	if msg == nil || msg.Text == "" {
@@ -84,19 +93,19 @@ func (f *SummaryFeature) Process(ctx context.Context, msg *botModels.Message, gr
 		return respond(fmt.Sprintf("❌ %v", err)), true, nil
 	}
 
-	now := f.currentTime()
-	targetDate, err := sifangfeature.ParseSummaryDate(dateSuffix, now, "上游账单")
+	now := f.currentTime().In(models.GroupLocation(group.Settings))
+	targetDate, lastDate, err := parseSummaryDateRange(dateSuffix, now)
 	if err != nil {
 		return respond(fmt.Sprintf("❌ %v", err)), true, nil
 	}
 
 	start := time.Date(targetDate.Year(), targetDate.Month(), targetDate.Day(), 0, 0, 0, 0, targetDate.Location())
-	end := start.Add(24*time.Hour - time.Second)
+	end := time.Date(lastDate.Year(), lastDate.Month(), lastDate.Day(), 0, 0, 0, 0, lastDate.Location()).Add(24*time.Hour - time.Second)
 
 	targetBindings := f.buildTargetBindings(bindings, selectedBinding)
 	responses := make([]string, 0, len(targetBindings))
 	for _, binding := range targetBindings {
-		responseText, err := f.queryUpstreamSummary(ctx, msg, binding, start, end, targetDate)
+		responseText, err := f.queryUpstreamSummary(ctx, msg, binding, start, end, targetDate, lastDate)
 		if err != nil {
 			return respond(fmt.Sprintf("❌ 查询上游账单失败：%v", err)), true, nil
 		}
@@ -147,7 +156,8 @@ func (f *SummaryFeature) resolveTarget(bindings []models.InterfaceBinding, text
 		return
 	}
 
-	if len(fields) > 1 {
+	// 首个参数是日期时视为未指定接口（例如「上游账单 2024-01-01 2024-01-07」）
+	if _, dateErr := sifangfeature.ParseSummaryDate(first, f.currentTime(), "上游账单"); len(fields) > 1 && dateErr != nil {
 		return nil, "", fmt.Errorf("未绑定接口 ID: %s", html.EscapeString(first))
 	}
 
@@ -177,9 +187,9 @@ func (f *SummaryFeature) queryUpstreamSummary(
 	ctx context.Context,
 	msg *botModels.Message,
 	binding models.InterfaceBinding,
-	start, end, targetDate time.Time,
+	start, end, targetDate, lastDate time.Time,
 ) (string, error) {
-	logger.L().Infof("Requesting upstream summary: chat_id=%d pzid=%s start=%s end=%s user=%d",
+	logger.Ctx(ctx).Infof("Requesting upstream summary: chat_id=%d pzid=%s start=%s end=%s user=%d",
 		msg.Chat.ID, binding.ID,
 		start.Format("2006-01-02 15:04:05"),
 		end.Format("2006-01-02 15:04:05"),
@@ -187,16 +197,20 @@ func (f *SummaryFeature) queryUpstreamSummary(
 
 	summary, err := f.paymentService.GetSummaryByDayByPZID(ctx, binding.ID, start, end)
 	if err != nil {
-		logger.L().Errorf("Upstream summary query failed: chat_id=%d pzid=%s start=%s err=%v",
+		logger.Ctx(ctx).Errorf("Upstream summary query failed: chat_id=%d pzid=%s start=%s err=%v",
 			msg.Chat.ID, binding.ID, start.Format("2006-01-02"), err)
 		return "", err
 	}
 
-	item := pickSummaryItem(summary, targetDate)
-	message := formatUpstreamSummary(binding, summary, targetDate, item)
+	var message string
+	if summaryRangeDays(targetDate, lastDate) > 1 {
+		message = formatUpstreamSummaryRange(binding, summary, targetDate, lastDate)
+	} else {
+		message = formatUpstreamSummary(binding, summary, targetDate, pickSummaryItem(summary, targetDate))
+	}
 
-	logger.L().Infof("Upstream summary queried: chat_id=%d pzid=%s date=%s user=%d",
-		msg.Chat.ID, binding.ID, targetDate.Format("2006-01-02"), msg.From.ID)
+	logger.Ctx(ctx).Infof("Upstream summary queried: chat_id=%d pzid=%s date=%s~%s user=%d",
+		msg.Chat.ID, binding.ID, targetDate.Format("2006-01-02"), lastDate.Format("2006-01-02"), msg.From.ID)
 
 	return message, nil
 }
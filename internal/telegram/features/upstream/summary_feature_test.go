@@ -246,3 +246,71 @@ func (s *stubPaymentService) GetOrderDetail(ctx context.Context, merchantID int6
 func (s *stubPaymentService) FindOrderChannelBinding(ctx context.Context, merchantID int64, orderNo string, numberType paymentservice.OrderNumberType) (*paymentservice.OrderChannelBinding, error) {
 	return nil, nil
 }
+
+func TestSummaryFeature_ProcessDateRange(t *testing.T) {
+	stub := &stubPaymentService{
+		summaryByPZID: &paymentservice.SummaryByPZID{
+			PZName: "支付宝代收",
+			Items: []*paymentservice.SummaryByPZIDItem{
+				{Date: "2024-01-03", OrderCount: "2", GrossAmount: "300", MerchantIncome: "280", AgentIncome: "20"},
+				{Date: "2024-01-01 00:00:00", OrderCount: "5", GrossAmount: "1000.50", MerchantIncome: "950", AgentIncome: "50.5"},
+				{Date: "2023-12-31", OrderCount: "9", GrossAmount: "9999"},
+			},
+		},
+	}
+
+	feature := NewSummaryFeature(stub)
+	feature.nowFunc = func() time.Time {
+		return time.Date(2024, 1, 10, 12, 0, 0, 0, upstreamChinaLocation)
+	}
+	group := &models.Group{
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{{Name: "支付宝渠道", ID: "1024"}},
+		},
+	}
+
+	for _, text := range []string{"上游账单 1024 2024-01-01 2024-01-07", "上游账单 2024/1/1 至 1月7日"} {
+		msg := &botModels.Message{Text: text, Chat: botModels.Chat{ID: 1001, Type: "supergroup"}, From: &botModels.User{ID: 42}}
+		resp, handled, err := feature.Process(context.Background(), msg, group)
+		if err != nil || !handled || resp == nil {
+			t.Fatalf("%s: unexpected result handled=%v err=%v", text, handled, err)
+		}
+
+		if stub.lastStart.Format("2006-01-02 15:04:05") != "2024-01-01 00:00:00" || stub.lastEnd.Format("2006-01-02 15:04:05") != "2024-01-07 23:59:59" {
+			t.Fatalf("%s: unexpected range %s ~ %s", text, stub.lastStart, stub.lastEnd)
+		}
+		for _, want := range []string{
+			"📈 上游账单 - 2024-01-01 ~ 2024-01-07",
+			"01-01  跑量 1000.50 | 实收 950.00 | 代理 50.50 | 5 笔",
+			"01-03  跑量 300.00 | 实收 280.00 | 代理 20.00 | 2 笔",
+			"合计（2 天有数据）</b>\n跑量: 1300.50\n商户实收: 1230.00\n代理收益: 70.50\n笔数: 7",
+		} {
+			if !strings.Contains(resp.Text, want) {
+				t.Fatalf("%s: expected %q in output:\n%s", text, want, resp.Text)
+			}
+		}
+		if strings.Index(resp.Text, "01-01") > strings.Index(resp.Text, "01-03") {
+			t.Fatalf("expected days in ascending order:\n%s", resp.Text)
+		}
+	}
+}
+
+func TestParseSummaryDateRangeRejectsInvalidRanges(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, upstreamChinaLocation)
+
+	cases := map[string]string{
+		"2024-01-07 2024-01-01":            "开始日期不能晚于结束日期",
+		"2024-01-01 2024-02-01":            "最长 31 天",
+		"2024-01-01 2024-01-02 2024-01-03": "日期区间格式错误",
+	}
+	for input, want := range cases {
+		if _, _, err := parseSummaryDateRange(input, now); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("parseSummaryDateRange(%q) err=%v, want %q", input, err, want)
+		}
+	}
+
+	start, end, err := parseSummaryDateRange("2024-01-01 2024-01-31", now)
+	if err != nil || summaryRangeDays(start, end) != maxSummaryRangeDays {
+		t.Fatalf("expected 31-day range to be accepted, got %s ~ %s err=%v", start, end, err)
+	}
+}
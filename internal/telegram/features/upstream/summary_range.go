@@ -0,0 +1,148 @@
+package upstream
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	paymentservice "go_bot/internal/payment/service"
+	sifangfeature "go_bot/internal/telegram/features/sifang"
+	"go_bot/internal/telegram/models"
+)
+
+// maxSummaryRangeDays 区间查询最多覆盖的天数，避免一次拉取过多数据压垮上游
+const maxSummaryRangeDays = 31
+
+// summaryRangeSeparator 日期区间分隔符：空白、~、～、至、到
+var summaryRangeSeparator = regexp.MustCompile(`\s*(?:~|～|至|到)\s*|\s+`)
+
+// summaryDayTotals 单日（或合计）汇总
+type summaryDayTotals struct {
+	Date           string
+	GrossAmount    float64
+	MerchantIncome float64
+	AgentIncome    float64
+	OrderCount     int64
+}
+
+// parseSummaryDateRange 解析日期或日期区间，单个日期时 start 与 end 相同
+// 日期格式与「账单」命令一致（2024-01-01、2024/1/1、1月1日、1.1 等）
+func parseSummaryDateRange(raw string, now time.Time) (start, end time.Time, err error) {
+	raw = strings.TrimSpace(raw)
+	parts := summaryRangeSeparator.Split(raw, -1)
+	if raw == "" || len(parts) == 1 {
+		start, err = sifangfeature.ParseSummaryDate(raw, now, "上游账单")
+		return start, start, err
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("日期区间格式错误，请使用「上游账单 [接口ID] 2024-01-01 2024-01-07」")
+	}
+
+	if start, err = sifangfeature.ParseSummaryDate(parts[0], now, "上游账单"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end, err = sifangfeature.ParseSummaryDate(parts[1], now, "上游账单"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期不能晚于结束日期")
+	}
+	if days := summaryRangeDays(start, end); days > maxSummaryRangeDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("日期区间最长 %d 天，当前为 %d 天", maxSummaryRangeDays, days)
+	}
+	return start, end, nil
+}
+
+// summaryRangeDays 返回区间包含的自然日数量（含首尾）
+func summaryRangeDays(start, end time.Time) int {
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(endDay.Sub(startDay).Hours()/24) + 1
+}
+
+// aggregateSummaryRange 按日汇总区间内的账单，返回按日期升序的每日数据与合计
+func aggregateSummaryRange(summary *paymentservice.SummaryByPZID, start, end time.Time) ([]summaryDayTotals, summaryDayTotals) {
+	var total summaryDayTotals
+	if summary == nil {
+		return nil, total
+	}
+
+	first, last := start.Format("2006-01-02"), end.Format("2006-01-02")
+	byDate := make(map[string]*summaryDayTotals)
+	dates := make([]string, 0)
+	for _, item := range summary.Items {
+		if item == nil {
+			continue
+		}
+		date := normalizeSummaryDate(item.Date)
+		if date == "" || date < first || date > last {
+			continue
+		}
+
+		day, ok := byDate[date]
+		if !ok {
+			day = &summaryDayTotals{Date: date}
+			byDate[date] = day
+			dates = append(dates, date)
+		}
+		day.GrossAmount += parseSummaryAmount(summary.PZID, item.GrossAmount)
+		day.MerchantIncome += parseSummaryAmount(summary.PZID, item.MerchantIncome)
+		day.AgentIncome += parseSummaryAmount(summary.PZID, item.AgentIncome)
+		day.OrderCount += int64(parseSummaryAmount(summary.PZID, item.OrderCount))
+	}
+
+	sort.Strings(dates)
+	days := make([]summaryDayTotals, 0, len(dates))
+	for _, date := range dates {
+		day := *byDate[date]
+		total.GrossAmount += day.GrossAmount
+		total.MerchantIncome += day.MerchantIncome
+		total.AgentIncome += day.AgentIncome
+		total.OrderCount += day.OrderCount
+		days = append(days, day)
+	}
+	return days, total
+}
+
+// parseSummaryAmount 解析上游返回的数值字段，空值视为 0，异常值记录日志后按 0 计
+func parseSummaryAmount(pzid, raw string) float64 {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil {
+		logger.L().Warnf("Upstream summary value invalid: pzid=%s value=%q err=%v", pzid, trimmed, err)
+		return 0
+	}
+	return value
+}
+
+// formatUpstreamSummaryRange 格式化区间账单：逐日明细 + 合计
+func formatUpstreamSummaryRange(binding models.InterfaceBinding, summary *paymentservice.SummaryByPZID, start, end time.Time) string {
+	rangeText := fmt.Sprintf("%s ~ %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	days, total := aggregateSummaryRange(summary, start, end)
+	if len(days) == 0 {
+		return fmt.Sprintf("ℹ️ %s 暂无上游账单数据（接口 %s）", rangeText, formatInterfaceDescriptor(binding))
+	}
+
+	pzName := ""
+	if summary != nil {
+		pzName = strings.TrimSpace(summary.PZName)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📈 上游账单 - %s\n接口：%s%s\n\n", rangeText, formatInterfaceDescriptor(binding), formatChannelLine(pzName)))
+	for _, day := range days {
+		sb.WriteString(fmt.Sprintf("%s  跑量 %.2f | 实收 %.2f | 代理 %.2f | %d 笔\n",
+			html.EscapeString(day.Date[5:]), day.GrossAmount, day.MerchantIncome, day.AgentIncome, day.OrderCount))
+	}
+	sb.WriteString(fmt.Sprintf("\n<b>合计（%d 天有数据）</b>\n跑量: %.2f\n商户实收: %.2f\n代理收益: %.2f\n笔数: %d",
+		len(days), total.GrossAmount, total.MerchantIncome, total.AgentIncome, total.OrderCount))
+	return sb.String()
+}
@@ -32,7 +32,7 @@ func (s *Service) HandleRecallCallback(ctx context.Context, botInstance *bot.Bot
 		ShowAlert:       true,
 	})
 	if err != nil {
-		logger.L().Errorf("Failed to answer callback query: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 	}
 
 	// 更新消息按钮为确认界面
@@ -43,18 +43,18 @@ func (s *Service) HandleRecallCallback(ctx context.Context, botInstance *bot.Bot
 			ReplyMarkup: keyboard,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to edit message markup: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to edit message markup: %v", err)
 		}
 	}
 
-	logger.L().Infof("User %d requested recall confirmation for task %s", query.From.ID, taskID)
+	logger.Ctx(ctx).Infof("User %d requested recall confirmation for task %s", query.From.ID, taskID)
 }
 
 // HandleRecallConfirmCallback 处理确认撤回
 func (s *Service) HandleRecallConfirmCallback(ctx context.Context, botInstance *bot.Bot, query *botModels.CallbackQuery) {
 	taskID := strings.TrimPrefix(query.Data, "recall_confirm:")
 
-	logger.L().Infof("User %d confirmed recall for task %s", query.From.ID, taskID)
+	logger.Ctx(ctx).Infof("User %d confirmed recall for task %s", query.From.ID, taskID)
 
 	// 执行撤回
 	successCount, failedCount, err := s.RecallForwardedMessages(ctx, botInstance, taskID, query.From.ID)
@@ -62,10 +62,10 @@ func (s *Service) HandleRecallConfirmCallback(ctx context.Context, botInstance *
 	var resultText string
 	if err != nil {
 		resultText = fmt.Sprintf("❌ 撤回失败: %v", err)
-		logger.L().Errorf("Recall failed for task %s: %v", taskID, err)
+		logger.Ctx(ctx).Errorf("Recall failed for task %s: %v", taskID, err)
 	} else {
 		resultText = fmt.Sprintf("✅ 撤回完成\n\n成功: %d 条\n失败: %d 条", successCount, failedCount)
-		logger.L().Infof("Recall completed for task %s: success=%d, failed=%d", taskID, successCount, failedCount)
+		logger.Ctx(ctx).Infof("Recall completed for task %s: success=%d, failed=%d", taskID, successCount, failedCount)
 	}
 
 	// 回复用户撤回结果
@@ -75,7 +75,7 @@ func (s *Service) HandleRecallConfirmCallback(ctx context.Context, botInstance *
 		ShowAlert:       true,
 	})
 	if err != nil {
-		logger.L().Errorf("Failed to answer callback query: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 	}
 
 	// 禁用按钮，显示"已撤回"
@@ -91,14 +91,14 @@ func (s *Service) HandleRecallConfirmCallback(ctx context.Context, botInstance *
 			ReplyMarkup: keyboard,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to edit message markup: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to edit message markup: %v", err)
 		}
 	}
 }
 
 // HandleRecallCancelCallback 处理取消撤回
 func (s *Service) HandleRecallCancelCallback(ctx context.Context, botInstance *bot.Bot, query *botModels.CallbackQuery) {
-	logger.L().Infof("User %d canceled recall", query.From.ID)
+	logger.Ctx(ctx).Infof("User %d canceled recall", query.From.ID)
 
 	// 回复用户
 	_, err := botInstance.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
@@ -106,7 +106,7 @@ func (s *Service) HandleRecallCancelCallback(ctx context.Context, botInstance *b
 		Text:            "操作已取消",
 	})
 	if err != nil {
-		logger.L().Errorf("Failed to answer callback query: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 	}
 
 	// 恢复原始按钮（从消息中解析 taskID）
@@ -123,7 +123,7 @@ func (s *Service) HandleRecallCancelCallback(ctx context.Context, botInstance *b
 			ReplyMarkup: keyboard,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to edit message markup: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to edit message markup: %v", err)
 		}
 	}
 }
@@ -17,9 +17,23 @@ import (
 	botModels "github.com/go-telegram/bot/models"
 )
 
+// Mode 频道消息投递到群组的方式
+type Mode string
+
+const (
+	// ModeForward 调用 forwardMessage(s) 转发，群内显示「转发自」频道来源
+	ModeForward Mode = "forward"
+	// ModeCopy 调用 copyMessage(s) 复制，不显示来源
+	ModeCopy Mode = "copy"
+)
+
 // Service 转发服务实现
+// 两种方式都由 Telegram 服务端按原消息投递：图片/视频/文件等媒体、说明文字、文本与说明的 entities（加粗、链接等）原样保留，
+// 媒体组通过批量接口整组发送，群内仍显示为相册而不会拆成多条
 type Service struct {
 	channelID            int64
+	mode                 Mode
+	defaultTarget        Target // 消息没有投递标记时的目标
 	groupService         service.GroupService
 	userService          service.UserService
 	forwardRecordRepo    repository.ForwardRecordRepository
@@ -30,12 +44,19 @@ type Service struct {
 // NewService 创建转发服务实例
 func NewService(
 	channelID int64,
+	mode Mode,
+	defaultTarget Target,
 	groupService service.GroupService,
 	userService service.UserService,
 	forwardRecordRepo repository.ForwardRecordRepository,
 ) *Service {
+	if mode != ModeCopy {
+		mode = ModeForward
+	}
 	return &Service{
 		channelID:            channelID,
+		mode:                 mode,
+		defaultTarget:        defaultTarget,
 		groupService:         groupService,
 		userService:          userService,
 		forwardRecordRepo:    forwardRecordRepo,
@@ -62,7 +83,12 @@ func (s *Service) HandleChannelMessage(ctx context.Context, botInterface interfa
 
 	// 检查是否来自配置的频道
 	if update.ChannelPost.Chat.ID != s.channelID {
-		logger.L().Debugf("Channel message from %d, expected %d, skipping", update.ChannelPost.Chat.ID, s.channelID)
+		logger.Ctx(ctx).Debugf("Channel message from %d, expected %d, skipping", update.ChannelPost.Chat.ID, s.channelID)
+		return nil
+	}
+
+	// 幂等：同一条频道消息因重试重复到达时只转发一次
+	if !s.claimChannelPost(ctx, update.ChannelPost) {
 		return nil
 	}
 
@@ -72,7 +98,7 @@ func (s *Service) HandleChannelMessage(ctx context.Context, botInterface interfa
 		return fmt.Errorf("failed to list active groups: %w", err)
 	}
 
-	// 过滤启用转发的目标群组，排除私聊
+	// 过滤启用转发的群组，排除私聊；投递目标（等级/标签）在确定消息内容后再筛选
 	var targetGroups []*models.Group
 	for _, group := range groups {
 		if !group.Settings.ForwardEnabled {
@@ -80,7 +106,7 @@ func (s *Service) HandleChannelMessage(ctx context.Context, botInterface interfa
 		}
 
 		if group.Type == "private" {
-			logger.L().Debugf("Skipping private chat from forward targets: chat_id=%d", group.TelegramID)
+			logger.Ctx(ctx).Debugf("Skipping private chat from forward targets: chat_id=%d", group.TelegramID)
 			continue
 		}
 
@@ -88,31 +114,53 @@ func (s *Service) HandleChannelMessage(ctx context.Context, botInterface interfa
 	}
 
 	if len(targetGroups) == 0 {
-		logger.L().Info("No target groups with forward enabled, skipping forward")
+		logger.Ctx(ctx).Info("No target groups with forward enabled, skipping forward")
 		return nil
 	}
 
 	// 检查是否为媒体组
 	if update.ChannelPost.MediaGroupID != "" {
 		// 媒体组消息，使用收集器
-		logger.L().Debugf("Media group message detected: media_group_id=%s, message_id=%d",
+		logger.Ctx(ctx).Debugf("Media group message detected: media_group_id=%s, message_id=%d",
 			update.ChannelPost.MediaGroupID, update.ChannelPost.ID)
 		return s.handleMediaGroupMessage(ctx, botInstance, update.ChannelPost, targetGroups)
 	}
 
-	// 单条消息，直接转发
+	// 单条消息，按投递标记或默认目标筛选后直接转发
+	target, marked := s.resolveTarget(update.ChannelPost)
+	targetGroups = filterTargetGroups(targetGroups, target)
+	if len(targetGroups) == 0 {
+		logger.Ctx(ctx).Infof("No groups match forward target, skipping: channel_message_id=%d, target=%s, marked=%t",
+			update.ChannelPost.ID, target, marked)
+		return nil
+	}
+
 	taskID := uuid.New().String()
-	logger.L().Infof("Starting forward task: task_id=%s, channel_message_id=%d, target_groups=%d",
-		taskID, update.ChannelPost.ID, len(targetGroups))
+	logger.Ctx(ctx).Infof("Starting forward task: task_id=%s, channel_message_id=%d, content=%s, mode=%s, target=%s, marked=%t, target_groups=%d",
+		taskID, update.ChannelPost.ID, describeContent(update.ChannelPost), s.mode, target, marked, len(targetGroups))
 
 	// 异步执行转发任务
-	go s.forwardTask(context.Background(), botInstance, update.ChannelPost, targetGroups, taskID)
+	go s.forwardTask(context.Background(), botInstance, update.ChannelPost, targetGroups, target, taskID)
 
 	return nil
 }
 
+// claimChannelPost 写入频道消息处理标记，已处理过返回 false；标记失败时仍继续转发，宁可重复也不漏发
+func (s *Service) claimChannelPost(ctx context.Context, post *botModels.Message) bool {
+	first, err := s.forwardRecordRepo.MarkChannelPostProcessed(ctx, post.Chat.ID, int64(post.ID), time.Now())
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to mark channel post processed, forwarding anyway: channel_id=%d, message_id=%d, error=%v",
+			post.Chat.ID, post.ID, err)
+		return true
+	}
+	if !first {
+		logger.Ctx(ctx).Infof("Duplicate channel post skipped: channel_id=%d, message_id=%d", post.Chat.ID, post.ID)
+	}
+	return first
+}
+
 // forwardTask 异步转发任务
-func (s *Service) forwardTask(ctx context.Context, botInstance *bot.Bot, message *botModels.Message, groups []*models.Group, taskID string) {
+func (s *Service) forwardTask(ctx context.Context, botInstance *bot.Bot, message *botModels.Message, groups []*models.Group, target Target, taskID string) {
 	startTime := time.Now()
 	limiter := NewRateLimiter(30) // 30条/秒
 	defer limiter.Close()
@@ -138,10 +186,10 @@ func (s *Service) forwardTask(ctx context.Context, botInstance *bot.Bot, message
 			if err == nil {
 				successCount++
 				status = models.ForwardStatusSuccess
-				logger.L().Debugf("Forwarded to group %d: message_id=%d", g.TelegramID, forwardedMsgID)
+				logger.Ctx(ctx).Debugf("Forwarded to group %d: message_id=%d", g.TelegramID, forwardedMsgID)
 			} else {
 				failedCount++
-				logger.L().Errorf("Failed to forward to group %d: %v", g.TelegramID, err)
+				logger.Ctx(ctx).Errorf("Failed to forward to group %d: %v", g.TelegramID, err)
 			}
 
 			records = append(records, &models.ForwardRecord{
@@ -161,16 +209,16 @@ func (s *Service) forwardTask(ctx context.Context, botInstance *bot.Bot, message
 	// 批量插入记录
 	if len(records) > 0 {
 		if err := s.forwardRecordRepo.BulkCreateRecords(ctx, records); err != nil {
-			logger.L().Errorf("Failed to save forward records: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to save forward records: %v", err)
 		}
 	}
 
 	duration := time.Since(startTime)
-	logger.L().Infof("Forward task completed: task_id=%s, success=%d, failed=%d, duration=%v",
+	logger.Ctx(ctx).Infof("Forward task completed: task_id=%s, success=%d, failed=%d, duration=%v",
 		taskID, successCount, failedCount, duration)
 
 	// 发送报告给管理员
-	s.sendReportToAdmins(ctx, botInstance, taskID, successCount, failedCount, duration)
+	s.sendReportToAdmins(ctx, botInstance, taskID, target, successCount, failedCount, duration)
 }
 
 // forwardToGroup 转发到单个群组（带重试）
@@ -181,20 +229,14 @@ func (s *Service) forwardToGroup(ctx context.Context, botInstance *bot.Bot, mess
 			return 0, fmt.Errorf("rate limiter wait error: %w", err)
 		}
 
-		// 尝试转发消息
-		msg, err := botInstance.ForwardMessage(ctx, &bot.ForwardMessageParams{
-			ChatID:     groupID,
-			FromChatID: message.Chat.ID,
-			MessageID:  message.ID,
-		})
-
+		msgID, err := s.deliverMessage(ctx, botInstance, message, groupID)
 		if err == nil {
-			return int64(msg.ID), nil
+			return int64(msgID), nil
 		}
 
 		// 如果不是最后一次重试，等待2秒后重试
 		if i < 2 {
-			logger.L().Warnf("Forward attempt %d failed for group %d: %v, retrying in 2s", i+1, groupID, err)
+			logger.Ctx(ctx).Warnf("Forward attempt %d failed for group %d: %v, retrying in 2s", i+1, groupID, err)
 			time.Sleep(2 * time.Second)
 		}
 	}
@@ -205,7 +247,7 @@ func (s *Service) forwardToGroup(ctx context.Context, botInstance *bot.Bot, mess
 // RecallForwardedMessages 撤回转发消息
 func (s *Service) RecallForwardedMessages(ctx context.Context, botInterface interface{}, taskID string, requesterID int64) (int, int, error) {
 	// 验证权限
-	isAdmin, err := s.userService.CheckAdminPermission(ctx, requesterID)
+	isAdmin, err := s.userService.CheckAdminPermission(ctx, requesterID, 0)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to check permission: %w", err)
 	}
@@ -229,7 +271,7 @@ func (s *Service) RecallForwardedMessages(ctx context.Context, botInterface inte
 		return 0, 0, fmt.Errorf("no records found for task %s", taskID)
 	}
 
-	logger.L().Infof("Starting recall: task_id=%s, total_records=%d", taskID, len(records))
+	logger.Ctx(ctx).Infof("Starting recall: task_id=%s, total_records=%d", taskID, len(records))
 
 	// 批量删除消息
 	limiter := NewRateLimiter(30)
@@ -240,7 +282,7 @@ func (s *Service) RecallForwardedMessages(ctx context.Context, botInterface inte
 
 	for _, record := range records {
 		if err := limiter.Wait(ctx); err != nil {
-			logger.L().Errorf("Rate limiter wait error during recall: %v", err)
+			logger.Ctx(ctx).Errorf("Rate limiter wait error during recall: %v", err)
 			break
 		}
 
@@ -253,41 +295,42 @@ func (s *Service) RecallForwardedMessages(ctx context.Context, botInterface inte
 			successCount++
 		} else {
 			failedCount++
-			logger.L().Warnf("Failed to delete message: group=%d, msg_id=%d, err=%v",
+			logger.Ctx(ctx).Warnf("Failed to delete message: group=%d, msg_id=%d, err=%v",
 				record.TargetGroupID, record.ForwardedMessageID, err)
 		}
 	}
 
 	// 删除记录
 	if err := s.forwardRecordRepo.DeleteRecordsByTaskID(ctx, taskID); err != nil {
-		logger.L().Errorf("Failed to delete forward records: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to delete forward records: %v", err)
 	}
 
-	logger.L().Infof("Recall completed: task_id=%s, success=%d, failed=%d", taskID, successCount, failedCount)
+	logger.Ctx(ctx).Infof("Recall completed: task_id=%s, success=%d, failed=%d", taskID, successCount, failedCount)
 	return successCount, failedCount, nil
 }
 
 // sendReportToAdmins 发送报告给所有管理员
-func (s *Service) sendReportToAdmins(ctx context.Context, botInstance *bot.Bot, taskID string, successCount, failedCount int, duration time.Duration) {
+func (s *Service) sendReportToAdmins(ctx context.Context, botInstance *bot.Bot, taskID string, target Target, successCount, failedCount int, duration time.Duration) {
 	// 查询所有管理员
 	admins, err := s.userService.ListAllAdmins(ctx)
 	if err != nil {
-		logger.L().Errorf("Failed to list admins: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to list admins: %v", err)
 		return
 	}
 
 	if len(admins) == 0 {
-		logger.L().Warn("No admins found, skipping report")
+		logger.Ctx(ctx).Warn("No admins found, skipping report")
 		return
 	}
 
 	// 构造报告消息
 	reportText := fmt.Sprintf(
 		"📊 频道消息转发完成\n\n"+
+			"🎯 目标: %s\n"+
 			"✅ 成功: %d 个群组\n"+
 			"❌ 失败: %d 个群组\n"+
 			"⏱️ 耗时: %.2f 秒",
-		successCount, failedCount, duration.Seconds(),
+		target, successCount, failedCount, duration.Seconds(),
 	)
 
 	// 添加撤回按钮
@@ -305,9 +348,9 @@ func (s *Service) sendReportToAdmins(ctx context.Context, botInstance *bot.Bot,
 			ReplyMarkup: keyboard,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to send report to admin %d: %v", admin.TelegramID, err)
+			logger.Ctx(ctx).Errorf("Failed to send report to admin %d: %v", admin.TelegramID, err)
 		} else {
-			logger.L().Infof("Sent forward report to admin %d", admin.TelegramID)
+			logger.Ctx(ctx).Infof("Sent forward report to admin %d", admin.TelegramID)
 		}
 	}
 }
@@ -321,17 +364,29 @@ func (s *Service) handleMediaGroupMessage(ctx context.Context, botInstance *bot.
 	if !exists {
 		// 创建新的收集器
 		collector = NewMediaGroupCollector(1500*time.Millisecond, func(messages []*botModels.Message) {
+			// 清理收集器
+			defer func() {
+				s.collectorMutex.Lock()
+				delete(s.mediaGroupCollectors, mediaGroupID)
+				s.collectorMutex.Unlock()
+			}()
+
+			// 投递标记写在媒体组的说明里（通常是第一条），收齐后按消息顺序查找
+			sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+			target, marked := s.resolveTarget(messages...)
+			targetGroups := filterTargetGroups(groups, target)
+			if len(targetGroups) == 0 {
+				logger.Ctx(ctx).Infof("No groups match media group forward target, skipping: media_group_id=%s, target=%s, marked=%t",
+					mediaGroupID, target, marked)
+				return
+			}
+
 			taskID := uuid.New().String()
-			logger.L().Infof("Starting media group forward task: task_id=%s, media_group_id=%s, message_count=%d, target_groups=%d",
-				taskID, mediaGroupID, len(messages), len(groups))
+			logger.Ctx(ctx).Infof("Starting media group forward task: task_id=%s, media_group_id=%s, message_count=%d, mode=%s, target=%s, marked=%t, target_groups=%d",
+				taskID, mediaGroupID, len(messages), s.mode, target, marked, len(targetGroups))
 
 			// 异步转发媒体组
-			go s.forwardMediaGroup(context.Background(), botInstance, messages, groups, taskID)
-
-			// 清理收集器
-			s.collectorMutex.Lock()
-			delete(s.mediaGroupCollectors, mediaGroupID)
-			s.collectorMutex.Unlock()
+			go s.forwardMediaGroup(context.Background(), botInstance, messages, targetGroups, target, taskID)
 		})
 		s.mediaGroupCollectors[mediaGroupID] = collector
 	}
@@ -343,7 +398,7 @@ func (s *Service) handleMediaGroupMessage(ctx context.Context, botInstance *bot.
 }
 
 // forwardMediaGroup 批量转发媒体组
-func (s *Service) forwardMediaGroup(ctx context.Context, botInstance *bot.Bot, messages []*botModels.Message, groups []*models.Group, taskID string) {
+func (s *Service) forwardMediaGroup(ctx context.Context, botInstance *bot.Bot, messages []*botModels.Message, groups []*models.Group, target Target, taskID string) {
 	startTime := time.Now()
 	limiter := NewRateLimiter(30)
 	defer limiter.Close()
@@ -367,7 +422,7 @@ func (s *Service) forwardMediaGroup(ctx context.Context, botInstance *bot.Bot, m
 		go func(g *models.Group) {
 			defer wg.Done()
 
-			forwardedMsgIDs, err := s.forwardMediaGroupToGroup(ctx, botInstance, messages[0].Chat.ID, messageIDs, g.TelegramID, limiter)
+			forwardedMsgIDs, err := s.forwardMediaGroupToGroup(ctx, botInstance, messages, messageIDs, g.TelegramID, limiter)
 
 			mu.Lock()
 			defer mu.Unlock()
@@ -385,10 +440,10 @@ func (s *Service) forwardMediaGroup(ctx context.Context, botInstance *bot.Bot, m
 						CreatedAt:          time.Now(),
 					})
 				}
-				logger.L().Debugf("Forwarded media group to group %d: %d messages", g.TelegramID, len(forwardedMsgIDs))
+				logger.Ctx(ctx).Debugf("Forwarded media group to group %d: %d messages", g.TelegramID, len(forwardedMsgIDs))
 			} else {
 				failedCount++
-				logger.L().Errorf("Failed to forward media group to group %d: %v", g.TelegramID, err)
+				logger.Ctx(ctx).Errorf("Failed to forward media group to group %d: %v", g.TelegramID, err)
 			}
 		}(group)
 	}
@@ -399,48 +454,183 @@ func (s *Service) forwardMediaGroup(ctx context.Context, botInstance *bot.Bot, m
 	// 批量插入记录
 	if len(records) > 0 {
 		if err := s.forwardRecordRepo.BulkCreateRecords(ctx, records); err != nil {
-			logger.L().Errorf("Failed to save forward records: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to save forward records: %v", err)
 		}
 	}
 
 	duration := time.Since(startTime)
-	logger.L().Infof("Media group forward task completed: task_id=%s, media_count=%d, success=%d, failed=%d, duration=%v",
+	logger.Ctx(ctx).Infof("Media group forward task completed: task_id=%s, media_count=%d, success=%d, failed=%d, duration=%v",
 		taskID, len(messages), successCount, failedCount, duration)
 
 	// 发送报告给管理员
-	s.sendReportToAdmins(ctx, botInstance, taskID, successCount, failedCount, duration)
+	s.sendReportToAdmins(ctx, botInstance, taskID, target, successCount, failedCount, duration)
 }
 
-// forwardMediaGroupToGroup 转发媒体组到单个群组（带重试）
-func (s *Service) forwardMediaGroupToGroup(ctx context.Context, botInstance *bot.Bot, fromChatID int64, messageIDs []int, groupID int64, limiter *RateLimiter) ([]int, error) {
+// forwardMediaGroupToGroup 转发媒体组到单个群组（带重试），messages 与 messageIDs 均按消息 ID 升序
+func (s *Service) forwardMediaGroupToGroup(ctx context.Context, botInstance *bot.Bot, messages []*botModels.Message, messageIDs []int, groupID int64, limiter *RateLimiter) ([]int, error) {
 	for i := 0; i < 3; i++ {
 		// 等待速率限制
 		if err := limiter.Wait(ctx); err != nil {
 			return nil, fmt.Errorf("rate limiter wait error: %w", err)
 		}
 
-		// 使用 ForwardMessages API 批量转发
-		result, err := botInstance.ForwardMessages(ctx, &bot.ForwardMessagesParams{
-			ChatID:     groupID,
-			FromChatID: fromChatID,
-			MessageIDs: messageIDs,
-		})
-
+		ids, err := s.deliverMediaGroup(ctx, botInstance, messages[0].Chat.ID, messageIDs, groupID)
 		if err == nil {
-			// 提取转发后的消息 ID
-			ids := make([]int, len(result))
-			for j, msgID := range result {
-				ids[j] = msgID.ID
-			}
+			s.stripCopiedMediaGroupMarker(ctx, botInstance, messages, ids, groupID)
 			return ids, nil
 		}
 
 		// 如果不是最后一次重试，等待2秒后重试
 		if i < 2 {
-			logger.L().Warnf("Media group forward attempt %d failed for group %d: %v, retrying in 2s", i+1, groupID, err)
+			logger.Ctx(ctx).Warnf("Media group forward attempt %d failed for group %d: %v, retrying in 2s", i+1, groupID, err)
 			time.Sleep(2 * time.Second)
 		}
 	}
 
 	return nil, fmt.Errorf("failed after 3 retries")
 }
+
+// deliverMessage 按投递方式把单条频道消息发到群组，返回群内的新消息 ID
+func (s *Service) deliverMessage(ctx context.Context, botInstance *bot.Bot, message *botModels.Message, groupID int64) (int, error) {
+	if s.mode == ModeCopy {
+		return s.copyMessage(ctx, botInstance, message, groupID)
+	}
+
+	msg, err := botInstance.ForwardMessage(ctx, &bot.ForwardMessageParams{
+		ChatID:     groupID,
+		FromChatID: message.Chat.ID,
+		MessageID:  message.ID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return msg.ID, nil
+}
+
+// copyMessage 复制单条频道消息，首行的投递标记不带到群里
+func (s *Service) copyMessage(ctx context.Context, botInstance *bot.Bot, message *botModels.Message, groupID int64) (int, error) {
+	if text, entities, ok := stripTargetMarker(message.Text, message.Entities); ok && text != "" {
+		// copyMessage 不能改写正文，去掉标记后按原 entities 重新发送
+		sent, err := botInstance.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:             groupID,
+			Text:               text,
+			Entities:           entities,
+			LinkPreviewOptions: message.LinkPreviewOptions,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return sent.ID, nil
+	}
+
+	// 不传 caption 时 Telegram 沿用原说明文字及其 entities，只有带标记时才改传去掉标记的说明
+	params := &bot.CopyMessageParams{
+		ChatID:     groupID,
+		FromChatID: message.Chat.ID,
+		MessageID:  message.ID,
+	}
+	caption, entities, marked := stripTargetMarker(message.Caption, message.CaptionEntities)
+	if marked && caption != "" {
+		params.Caption = caption
+		params.CaptionEntities = entities
+	}
+	result, err := botInstance.CopyMessage(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	if marked && caption == "" {
+		// 说明只有标记：空 caption 等同于沿用原说明，复制后再清空
+		s.editCopiedCaption(ctx, botInstance, groupID, result.ID, "", nil)
+	}
+	return result.ID, nil
+}
+
+// stripCopiedMediaGroupMarker 复制模式下 copyMessages 不能改写说明，复制后把带投递标记的说明（与 resolveTarget 一致取第一条带说明的消息）改为去掉标记的正文
+func (s *Service) stripCopiedMediaGroupMarker(ctx context.Context, botInstance *bot.Bot, messages []*botModels.Message, copiedIDs []int, groupID int64) {
+	if s.mode != ModeCopy {
+		return
+	}
+	for i, message := range messages {
+		if message.Caption == "" {
+			continue
+		}
+		if caption, entities, ok := stripTargetMarker(message.Caption, message.CaptionEntities); ok && i < len(copiedIDs) {
+			s.editCopiedCaption(ctx, botInstance, groupID, copiedIDs[i], caption, entities)
+		}
+		return
+	}
+}
+
+// editCopiedCaption 改写群内复制消息的说明，失败只记日志（消息已送达，保留标记好过重复投递）
+func (s *Service) editCopiedCaption(ctx context.Context, botInstance *bot.Bot, groupID int64, messageID int, caption string, entities []botModels.MessageEntity) {
+	if _, err := botInstance.EditMessageCaption(ctx, &bot.EditMessageCaptionParams{
+		ChatID:          groupID,
+		MessageID:       messageID,
+		Caption:         caption,
+		CaptionEntities: entities,
+	}); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to strip target marker from copied caption: group_id=%d, message_id=%d, error=%v",
+			groupID, messageID, err)
+	}
+}
+
+// deliverMediaGroup 按投递方式用批量接口整组发送媒体组（messageIDs 需升序），返回群内的新消息 ID
+func (s *Service) deliverMediaGroup(ctx context.Context, botInstance *bot.Bot, fromChatID int64, messageIDs []int, groupID int64) ([]int, error) {
+	var (
+		result []botModels.MessageID
+		err    error
+	)
+	if s.mode == ModeCopy {
+		result, err = botInstance.CopyMessages(ctx, &bot.CopyMessagesParams{
+			ChatID:     groupID,
+			FromChatID: fromChatID,
+			MessageIDs: messageIDs,
+		})
+	} else {
+		result, err = botInstance.ForwardMessages(ctx, &bot.ForwardMessagesParams{
+			ChatID:     groupID,
+			FromChatID: fromChatID,
+			MessageIDs: messageIDs,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(result))
+	for i, msgID := range result {
+		ids[i] = msgID.ID
+	}
+	return ids, nil
+}
+
+// describeContent 概括频道消息内容（类型与 entities 数量），用于转发日志排查格式或附件丢失问题
+func describeContent(message *botModels.Message) string {
+	kind := "other"
+	switch {
+	case len(message.Photo) > 0:
+		kind = "photo"
+	case message.Video != nil:
+		kind = "video"
+	case message.Animation != nil:
+		kind = "animation"
+	case message.Document != nil:
+		kind = "document"
+	case message.Audio != nil:
+		kind = "audio"
+	case message.Voice != nil:
+		kind = "voice"
+	case message.Sticker != nil:
+		kind = "sticker"
+	case message.Poll != nil:
+		kind = "poll"
+	case message.Text != "":
+		kind = "text"
+	}
+
+	entities := len(message.Entities) + len(message.CaptionEntities)
+	if entities > 0 {
+		return fmt.Sprintf("%s(entities=%d)", kind, entities)
+	}
+	return kind
+}
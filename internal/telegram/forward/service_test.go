@@ -0,0 +1,232 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/repository"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestClaimChannelPostSkipsDuplicates(t *testing.T) {
+	repo := &stubForwardRecordRepository{marked: map[[2]int64]bool{}}
+	s := NewService(-100, ModeForward, Target{}, nil, nil, repo)
+	post := &botModels.Message{ID: 42, Chat: botModels.Chat{ID: -100}}
+
+	if !s.claimChannelPost(context.Background(), post) {
+		t.Fatalf("expected first delivery to be claimed")
+	}
+	if s.claimChannelPost(context.Background(), post) {
+		t.Fatalf("expected duplicate delivery to be skipped")
+	}
+
+	repo.err = errors.New("mongo unavailable")
+	if !s.claimChannelPost(context.Background(), &botModels.Message{ID: 43, Chat: botModels.Chat{ID: -100}}) {
+		t.Fatalf("expected forwarding to continue when marking fails")
+	}
+}
+
+func TestDeliverMessageByMode(t *testing.T) {
+	tests := []struct {
+		mode       Mode
+		wantMethod string
+	}{
+		{mode: ModeForward, wantMethod: "forwardMessage"},
+		{mode: ModeCopy, wantMethod: "copyMessage"},
+		{mode: "", wantMethod: "forwardMessage"},
+	}
+
+	for _, tt := range tests {
+		server := newFakeBotServer(t, `{"message_id":77}`)
+		s := NewService(-100, tt.mode, Target{}, nil, nil, nil)
+		post := &botModels.Message{ID: 42, Chat: botModels.Chat{ID: -100}, Photo: []botModels.PhotoSize{{FileID: "p"}}, Caption: "hi"}
+
+		id, err := s.deliverMessage(context.Background(), server.bot, post, -200)
+		if err != nil {
+			t.Fatalf("mode %q: unexpected error: %v", tt.mode, err)
+		}
+		if id != 77 {
+			t.Fatalf("mode %q: expected message id 77, got %d", tt.mode, id)
+		}
+		call := server.lastCall(t)
+		if call.method != tt.wantMethod || call.form["from_chat_id"] != "-100" || call.form["message_id"] != "42" || call.form["chat_id"] != "-200" {
+			t.Fatalf("mode %q: unexpected call %+v", tt.mode, call)
+		}
+		// 不得覆盖说明文字，否则原 caption_entities 会丢失
+		if _, ok := call.form["caption"]; ok {
+			t.Fatalf("mode %q: caption must not be overridden", tt.mode)
+		}
+	}
+}
+
+func TestCopyModeStripsTargetMarker(t *testing.T) {
+	s := NewService(-100, ModeCopy, Target{}, nil, nil, nil)
+
+	server := newFakeBotServer(t, `{"message_id":77}`)
+	post := &botModels.Message{ID: 42, Chat: botModels.Chat{ID: -100}, Text: "#upstream\n今日公告"}
+	if _, err := s.deliverMessage(context.Background(), server.bot, post, -200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if call := server.lastCall(t); call.method != "sendMessage" || call.form["text"] != "今日公告" {
+		t.Fatalf("expected marker-free text to be sent, got %+v", call)
+	}
+
+	server = newFakeBotServer(t, `{"message_id":77}`)
+	post = &botModels.Message{ID: 42, Chat: botModels.Chat{ID: -100}, Photo: []botModels.PhotoSize{{FileID: "p"}}, Caption: "#vip\n图片说明"}
+	if _, err := s.deliverMessage(context.Background(), server.bot, post, -200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if call := server.lastCall(t); call.method != "copyMessage" || call.form["caption"] != "图片说明" {
+		t.Fatalf("expected caption without marker, got %+v", call)
+	}
+
+	server = newFakeBotServer(t, `[{"message_id":7},{"message_id":8}]`)
+	album := []*botModels.Message{
+		{ID: 10, Chat: botModels.Chat{ID: -100}, Caption: "#vip\n相册说明"},
+		{ID: 11, Chat: botModels.Chat{ID: -100}},
+	}
+	limiter := NewRateLimiter(30)
+	defer limiter.Close()
+	if _, err := s.forwardMediaGroupToGroup(context.Background(), server.bot, album, []int{10, 11}, -200, limiter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if call := server.lastCall(t); call.method != "editMessageCaption" || call.form["message_id"] != "7" || call.form["caption"] != "相册说明" {
+		t.Fatalf("expected copied album caption to be edited, got %+v", call)
+	}
+}
+
+func TestDeliverMediaGroupSendsWholeAlbum(t *testing.T) {
+	tests := []struct {
+		mode       Mode
+		wantMethod string
+	}{
+		{mode: ModeForward, wantMethod: "forwardMessages"},
+		{mode: ModeCopy, wantMethod: "copyMessages"},
+	}
+
+	for _, tt := range tests {
+		server := newFakeBotServer(t, `[{"message_id":7},{"message_id":8},{"message_id":9}]`)
+		s := NewService(-100, tt.mode, Target{}, nil, nil, nil)
+
+		ids, err := s.deliverMediaGroup(context.Background(), server.bot, -100, []int{10, 11, 12}, -200)
+		if err != nil {
+			t.Fatalf("mode %q: unexpected error: %v", tt.mode, err)
+		}
+		if len(ids) != 3 || ids[0] != 7 || ids[2] != 9 {
+			t.Fatalf("mode %q: unexpected ids %v", tt.mode, ids)
+		}
+		call := server.lastCall(t)
+		if call.method != tt.wantMethod || call.form["message_ids"] != "[10,11,12]" {
+			t.Fatalf("mode %q: expected a single %s call with all ids, got %+v", tt.mode, tt.wantMethod, call)
+		}
+		if server.callCount() != 1 {
+			t.Fatalf("mode %q: expected 1 call, got %d", tt.mode, server.callCount())
+		}
+	}
+}
+
+func TestDescribeContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *botModels.Message
+		want    string
+	}{
+		{name: "plain text", message: &botModels.Message{Text: "hello"}, want: "text"},
+		{name: "formatted text", message: &botModels.Message{Text: "hello", Entities: []botModels.MessageEntity{{Type: botModels.MessageEntityTypeBold}}}, want: "text(entities=1)"},
+		{name: "photo with caption entities", message: &botModels.Message{Photo: []botModels.PhotoSize{{FileID: "p"}}, Caption: "c", CaptionEntities: []botModels.MessageEntity{{Type: botModels.MessageEntityTypeTextLink}, {Type: botModels.MessageEntityTypeBold}}}, want: "photo(entities=2)"},
+		{name: "video", message: &botModels.Message{Video: &botModels.Video{FileID: "v"}}, want: "video"},
+		{name: "document", message: &botModels.Message{Document: &botModels.Document{FileID: "d"}}, want: "document"},
+		{name: "unknown", message: &botModels.Message{}, want: "other"},
+	}
+
+	for _, tt := range tests {
+		if got := describeContent(tt.message); got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// fakeBotCall 记录一次 Bot API 调用
+type fakeBotCall struct {
+	method string
+	form   map[string]string
+}
+
+// fakeBotServer 模拟 Bot API，所有方法都返回同一个 result
+type fakeBotServer struct {
+	bot   *bot.Bot
+	mu    sync.Mutex
+	calls []fakeBotCall
+}
+
+func newFakeBotServer(t *testing.T, result string) *fakeBotServer {
+	t.Helper()
+	fake := &fakeBotServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		call := fakeBotCall{method: path.Base(r.URL.Path), form: map[string]string{}}
+		if r.MultipartForm != nil {
+			for key, values := range r.MultipartForm.Value {
+				call.form[key] = values[0]
+			}
+		}
+		fake.mu.Lock()
+		fake.calls = append(fake.calls, call)
+		fake.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"result":` + result + `}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	b, err := bot.New("test-token", bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatalf("create bot: %v", err)
+	}
+	fake.bot = b
+	return fake
+}
+
+func (f *fakeBotServer) lastCall(t *testing.T) fakeBotCall {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		t.Fatalf("expected a Bot API call")
+	}
+	return f.calls[len(f.calls)-1]
+}
+
+func (f *fakeBotServer) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls)
+}
+
+// stubForwardRecordRepository 仅实现幂等标记，其余方法未被调用
+type stubForwardRecordRepository struct {
+	repository.ForwardRecordRepository
+	marked map[[2]int64]bool
+	err    error
+}
+
+func (r *stubForwardRecordRepository) MarkChannelPostProcessed(ctx context.Context, channelID, messageID int64, processedAt time.Time) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	key := [2]int64{channelID, messageID}
+	if r.marked[key] {
+		return false, nil
+	}
+	r.marked[key] = true
+	return true, nil
+}
@@ -0,0 +1,195 @@
+package forward
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	targetTierOption = "tier="
+	targetTagOption  = "tag="
+)
+
+// Target 频道消息的投递目标：群等级与标签同时满足才投递，标签满足任一即可；两者都为空表示所有启用转发的群
+type Target struct {
+	Tiers []models.GroupTier
+	Tags  []string
+}
+
+// IsEmpty 是否未限定任何条件
+func (t Target) IsEmpty() bool {
+	return len(t.Tiers) == 0 && len(t.Tags) == 0
+}
+
+// Matches 判断群组是否属于投递目标，未设置等级的群按普通群处理
+func (t Target) Matches(group *models.Group) bool {
+	if group == nil {
+		return false
+	}
+	tier := group.Tier
+	if tier == "" {
+		tier = models.GroupTierBasic
+	}
+	return models.IsTierAllowed(tier, t.Tiers) && group.HasAnyTag(t.Tags)
+}
+
+// String 描述投递目标，用于日志与转发报告
+func (t Target) String() string {
+	label := models.FormatAllowedTierList(t.Tiers)
+	if len(t.Tags) > 0 {
+		label += "，标签 " + models.FormatGroupTags(t.Tags)
+	}
+	return label
+}
+
+// addToken 把单个名称归入等级或标签：basic/merchant/upstream 视为群等级，其余视为标签
+func (t *Target) addToken(name string) {
+	name = models.NormalizeGroupTag(name)
+	if name == "" {
+		return
+	}
+	switch tier := models.GroupTier(name); tier {
+	case models.GroupTierBasic, models.GroupTierMerchant, models.GroupTierUpstream:
+		if !slices.Contains(t.Tiers, tier) {
+			t.Tiers = append(t.Tiers, tier)
+		}
+	default:
+		if !slices.Contains(t.Tags, name) {
+			t.Tags = append(t.Tags, name)
+		}
+	}
+}
+
+// ParseOption 解析单个 tier=/tag= 选项并合并到投递目标，不是这两种选项时返回 false；ParseTarget 与 /broadcast 共用
+func (t *Target) ParseOption(option string) (bool, error) {
+	if value, ok := strings.CutPrefix(option, targetTagOption); ok {
+		before := len(t.Tags)
+		for _, name := range strings.Split(value, ",") {
+			if tag := models.NormalizeGroupTag(name); tag != "" && !slices.Contains(t.Tags, tag) {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+		if len(t.Tags) == before {
+			return true, fmt.Errorf("tag= 后需要至少一个标签")
+		}
+		return true, nil
+	}
+
+	value, ok := strings.CutPrefix(option, targetTierOption)
+	if !ok {
+		return false, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		tier := models.GroupTier(strings.ToLower(strings.TrimSpace(name)))
+		switch tier {
+		case models.GroupTierBasic, models.GroupTierMerchant, models.GroupTierUpstream:
+		default:
+			return true, fmt.Errorf("未知的群等级：%s（可选 basic / merchant / upstream）", name)
+		}
+		if !slices.Contains(t.Tiers, tier) {
+			t.Tiers = append(t.Tiers, tier)
+		}
+	}
+	return true, nil
+}
+
+// ParseTarget 解析频道默认投递目标配置，格式与 /broadcast 一致：tier=merchant,upstream tag=vip,test（空格分隔，均可省略）
+func ParseTarget(spec string) (Target, error) {
+	var target Target
+	for _, option := range strings.Fields(spec) {
+		ok, err := target.ParseOption(option)
+		if err != nil {
+			return Target{}, err
+		}
+		if !ok {
+			return Target{}, fmt.Errorf("无法识别的投递目标 %q（格式：tier=basic,merchant,upstream tag=标签1,标签2）", option)
+		}
+	}
+	return target, nil
+}
+
+// parseTargetMarker 解析消息首行的投递标记：首行只由 #标记 组成时生效，如 "#upstream" 或 "#merchant #vip"
+// 首行夹杂普通文字（例如正文以话题标签开头）时不视为标记，按默认目标投递
+func parseTargetMarker(text string) (Target, bool) {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return parseMarkerLine(firstLine)
+}
+
+// stripTargetMarker 去掉首行的投递标记，返回剩余正文与平移后的 entities（偏移按 UTF-16 码元计算）；首行不是标记时 ok 为 false
+func stripTargetMarker(text string, entities []botModels.MessageEntity) (string, []botModels.MessageEntity, bool) {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	firstLine, rest, _ := strings.Cut(trimmed, "\n")
+	if _, ok := parseMarkerLine(firstLine); !ok {
+		return text, entities, false
+	}
+
+	body := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	shift := len(utf16.Encode([]rune(text[:len(text)-len(body)])))
+	shifted := make([]botModels.MessageEntity, 0, len(entities))
+	for _, entity := range entities {
+		end := entity.Offset + entity.Length
+		if end <= shift {
+			continue
+		}
+		entity.Offset = max(entity.Offset-shift, 0)
+		entity.Length = end - shift - entity.Offset
+		shifted = append(shifted, entity)
+	}
+	return body, shifted, true
+}
+
+// parseMarkerLine 解析单行投递标记，每个字段都必须是 #标记
+func parseMarkerLine(line string) (Target, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Target{}, false
+	}
+
+	var target Target
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "#") || len(field) == 1 {
+			return Target{}, false
+		}
+		target.addToken(field)
+	}
+	return target, !target.IsEmpty()
+}
+
+// resolveTarget 从频道消息（媒体组取第一条带说明的消息）中读取投递标记，没有标记时返回默认目标
+func (s *Service) resolveTarget(messages ...*botModels.Message) (Target, bool) {
+	for _, message := range messages {
+		text := message.Text
+		if text == "" {
+			text = message.Caption
+		}
+		if text == "" {
+			continue
+		}
+		if target, ok := parseTargetMarker(text); ok {
+			return target, true
+		}
+		break
+	}
+	return s.defaultTarget, false
+}
+
+// filterTargetGroups 按投递目标筛选群组
+func filterTargetGroups(groups []*models.Group, target Target) []*models.Group {
+	if target.IsEmpty() {
+		return groups
+	}
+	matched := make([]*models.Group, 0, len(groups))
+	for _, group := range groups {
+		if target.Matches(group) {
+			matched = append(matched, group)
+		}
+	}
+	return matched
+}
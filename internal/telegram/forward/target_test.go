@@ -0,0 +1,131 @@
+package forward
+
+import (
+	"slices"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseTargetMarker(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantOK    bool
+		wantTiers []models.GroupTier
+		wantTags  []string
+	}{
+		{name: "tier only", text: "#upstream\n今日公告", wantOK: true, wantTiers: []models.GroupTier{models.GroupTierUpstream}},
+		{name: "tier and tags", text: "#Merchant #VIP #vip\n内容", wantOK: true, wantTiers: []models.GroupTier{models.GroupTierMerchant}, wantTags: []string{"vip"}},
+		{name: "single line marker", text: "  #test  ", wantOK: true, wantTags: []string{"test"}},
+		{name: "hashtag mixed with text", text: "#upstream 今日公告", wantOK: false},
+		{name: "marker not on first line", text: "公告\n#upstream", wantOK: false},
+		{name: "bare hash", text: "# 标题", wantOK: false},
+		{name: "empty", text: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		target, ok := parseTargetMarker(tt.text)
+		if ok != tt.wantOK {
+			t.Fatalf("%s: expected ok=%t, got %t", tt.name, tt.wantOK, ok)
+		}
+		if !slices.Equal(target.Tiers, tt.wantTiers) || !slices.Equal(target.Tags, tt.wantTags) {
+			t.Fatalf("%s: unexpected target %+v", tt.name, target)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("tier=merchant,upstream tag=VIP,#test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(target.Tiers, []models.GroupTier{models.GroupTierMerchant, models.GroupTierUpstream}) || !slices.Equal(target.Tags, []string{"vip", "test"}) {
+		t.Fatalf("unexpected target %+v", target)
+	}
+
+	if target, err := ParseTarget(""); err != nil || !target.IsEmpty() {
+		t.Fatalf("expected empty target, got %+v err=%v", target, err)
+	}
+	for _, spec := range []string{"tier=gold", "tag=", "upstream"} {
+		if _, err := ParseTarget(spec); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestResolveTargetFiltersGroups(t *testing.T) {
+	groups := []*models.Group{
+		{TelegramID: -1, Tier: models.GroupTierUpstream},
+		{TelegramID: -2, Tier: models.GroupTierMerchant, Tags: []string{"vip"}},
+		{TelegramID: -3, Tier: models.GroupTierMerchant},
+		{TelegramID: -4},
+	}
+	s := NewService(-100, ModeForward, Target{Tiers: []models.GroupTier{models.GroupTierMerchant}}, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		messages   []*botModels.Message
+		wantMarked bool
+		wantIDs    []int64
+	}{
+		{name: "no marker uses default target", messages: []*botModels.Message{{Text: "普通公告"}}, wantIDs: []int64{-2, -3}},
+		{name: "tier marker", messages: []*botModels.Message{{Text: "#upstream\n上游通知"}}, wantMarked: true, wantIDs: []int64{-1}},
+		{name: "tier and tag marker", messages: []*botModels.Message{{Text: "#merchant #vip\n活动"}}, wantMarked: true, wantIDs: []int64{-2}},
+		{name: "basic marker matches groups without tier", messages: []*botModels.Message{{Text: "#basic"}}, wantMarked: true, wantIDs: []int64{-4}},
+		{
+			name: "media group marker in first caption",
+			messages: []*botModels.Message{
+				{Photo: []botModels.PhotoSize{{FileID: "a"}}, Caption: "#upstream\n图集"},
+				{Photo: []botModels.PhotoSize{{FileID: "b"}}},
+			},
+			wantMarked: true,
+			wantIDs:    []int64{-1},
+		},
+		{
+			name: "media group without caption uses default",
+			messages: []*botModels.Message{
+				{Photo: []botModels.PhotoSize{{FileID: "a"}}},
+				{Photo: []botModels.PhotoSize{{FileID: "b"}}},
+			},
+			wantIDs: []int64{-2, -3},
+		},
+	}
+
+	for _, tt := range tests {
+		target, marked := s.resolveTarget(tt.messages...)
+		if marked != tt.wantMarked {
+			t.Fatalf("%s: expected marked=%t, got %t", tt.name, tt.wantMarked, marked)
+		}
+		var ids []int64
+		for _, group := range filterTargetGroups(groups, target) {
+			ids = append(ids, group.TelegramID)
+		}
+		if !slices.Equal(ids, tt.wantIDs) {
+			t.Fatalf("%s: expected groups %v, got %v", tt.name, tt.wantIDs, ids)
+		}
+	}
+}
+
+func TestStripTargetMarker(t *testing.T) {
+	entities := []botModels.MessageEntity{
+		{Type: botModels.MessageEntityTypeHashtag, Offset: 0, Length: 9},
+		{Type: botModels.MessageEntityTypeBold, Offset: 10, Length: 2},
+	}
+	text, shifted, ok := stripTargetMarker("#upstream\n公告内容", entities)
+	if !ok || text != "公告内容" {
+		t.Fatalf("unexpected strip result: text=%q ok=%t", text, ok)
+	}
+	if len(shifted) != 1 || shifted[0].Type != botModels.MessageEntityTypeBold || shifted[0].Offset != 0 || shifted[0].Length != 2 {
+		t.Fatalf("unexpected entities %+v", shifted)
+	}
+
+	if text, _, ok := stripTargetMarker("#upstream 今日公告", nil); ok || text != "#upstream 今日公告" {
+		t.Fatalf("expected unmarked text to be kept, got %q ok=%t", text, ok)
+	}
+	if text, _, ok := stripTargetMarker("  #vip  ", nil); !ok || text != "" {
+		t.Fatalf("expected marker-only text to become empty, got %q ok=%t", text, ok)
+	}
+}
@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/service"
+)
+
+const (
+	groupArchivePurgeInterval = time.Hour
+	groupArchivePurgeTimeout  = 30 * time.Second
+)
+
+// groupArchivePurger 周期清理 Bot 离开超过保留期的群组记录（保留期内的记录用于重新入群时恢复配置）
+type groupArchivePurger struct {
+	groupService service.GroupService
+	interval     time.Duration
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	running      atomic.Bool
+}
+
+func newGroupArchivePurger(groupService service.GroupService) *groupArchivePurger {
+	return &groupArchivePurger{
+		groupService: groupService,
+		interval:     groupArchivePurgeInterval,
+	}
+}
+
+func (p *groupArchivePurger) start() {
+	if p == nil || p.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.run(ctx)
+	}()
+
+	p.running.Store(true)
+	logger.L().Info("Group archive purger started")
+}
+
+// isRunning 清理任务是否处于运行状态
+func (p *groupArchivePurger) isRunning() bool {
+	return p != nil && p.running.Load()
+}
+
+func (p *groupArchivePurger) stop() {
+	if p == nil || p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+	p.cancel = nil
+	p.running.Store(false)
+	logger.L().Info("Group archive purger stopped")
+}
+
+func (p *groupArchivePurger) run(ctx context.Context) {
+	// 启动时立即清理一次，处理停机期间到期的记录
+	p.purge(ctx, time.Now())
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.purge(ctx, now)
+		}
+	}
+}
+
+func (p *groupArchivePurger) purge(ctx context.Context, now time.Time) {
+	purgeCtx, cancel := context.WithTimeout(ctx, groupArchivePurgeTimeout)
+	defer cancel()
+	if _, err := p.groupService.PurgeExpiredGroups(purgeCtx, now); err != nil {
+		logger.Ctx(ctx).Warnf("Group archive purge failed: %v", err)
+	}
+}
+
+func (b *Bot) initGroupArchivePurger() {
+	if b.groupService == nil {
+		logger.L().Warn("Group archive purger not started: group service unavailable")
+		return
+	}
+	purger := newGroupArchivePurger(b.groupService)
+	b.groupArchivePurger = purger
+	purger.start()
+}
@@ -10,7 +10,9 @@ import (
 
 	"go_bot/internal/logger"
 	sifangfeature "go_bot/internal/telegram/features/sifang"
+	"go_bot/internal/telegram/features/upstream"
 	"go_bot/internal/telegram/forward"
+	"go_bot/internal/telegram/i18n"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/service"
 
@@ -20,43 +22,114 @@ import (
 
 // registerHandlers 注册所有命令处理器（异步执行）
 func (b *Bot) registerHandlers() {
-	// 普通命令 - 异步执行
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypeExact,
-		b.asyncHandler(b.handleStart))
+	// 普通命令 - 异步执行（文本命令统一经过 RateLimit 按用户 + 命令限流）
+	// 仅适用于群组的命令通过 RequireChatScope 声明作用域，私聊调用统一回复提示，handler 内无需再判断 Chat.Type
+	// /start 可带深链接参数（t.me/bot?start=xxx 会发送 "/start xxx"），按命令实体匹配
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "start", bot.MatchTypeCommandStartOnly,
+		b.RateLimit("/start", b.asyncHandler(b.handleStart)))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/ping", bot.MatchTypeExact,
-		b.asyncHandler(b.handlePing))
+		b.RateLimit("/ping", b.asyncHandler(b.handlePing)))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/lang", bot.MatchTypePrefix,
+		b.RateLimit("/lang", b.asyncHandler(b.handleLang)))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleHelp)))
+		b.RateLimit("/help", b.asyncHandler(b.RequireAdmin(b.handleHelp))))
 
-	// 管理员命令（仅 Owner） - 异步执行
+	// 高级管理命令（按权限位控制，Owner 拥有全部权限） - 异步执行
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/grant", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireOwner(b.handleGrantAdmin)))
+		b.RateLimit("/grant", b.asyncHandler(b.RequirePermission(models.PermManageAdmins, b.handleGrantAdmin))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/revoke", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireOwner(b.handleRevokeAdmin)))
+		b.RateLimit("/revoke", b.asyncHandler(b.RequirePermission(models.PermManageAdmins, b.handleRevokeAdmin))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/perm", bot.MatchTypePrefix,
+		b.RateLimit("/perm", b.asyncHandler(b.RequirePermission(models.PermManageAdmins, b.handleUserPermissions))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/validate", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireOwner(b.handleValidateGroupsCommand)))
+		b.RateLimit("/validate", b.asyncHandler(b.RequirePermission(models.PermManageGroups, b.handleValidateGroupsCommand))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/repair", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireOwner(b.handleRepairGroupsCommand)))
+		b.RateLimit("/repair", b.asyncHandler(b.RequirePermission(models.PermManageGroups, b.handleRepairGroupsCommand))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/note", bot.MatchTypePrefix,
+		b.RateLimit("/note", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequirePermission(models.PermManageGroups, b.handleGroupNote)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/tag", bot.MatchTypePrefix,
+		b.RateLimit("/tag", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequirePermission(models.PermManageGroups, b.handleGroupTags)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/health", bot.MatchTypeExact,
+		b.RateLimit("/health", b.asyncHandler(b.RequirePermission(models.PermSystem, b.handleHealth))))
+
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/uptime", bot.MatchTypeExact,
+		b.RateLimit("/uptime", b.asyncHandler(b.RequirePermission(models.PermSystem, b.handleUptime))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/command_stats", bot.MatchTypePrefix,
+		b.RateLimit("/command_stats", b.asyncHandler(b.RequirePermission(models.PermSystem, b.handleCommandStats))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/broadcast", bot.MatchTypePrefix,
+		b.RateLimit("/broadcast", b.asyncHandler(b.RequirePermission(models.PermBroadcast, b.handleBroadcast))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/balances", bot.MatchTypePrefix,
+		b.RateLimit("/balances", b.asyncHandler(b.RequirePermission(models.PermFinance, b.handleBalanceExport))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/resend_bills", bot.MatchTypeExact,
+		b.RateLimit("/resend_bills", b.asyncHandler(b.RequirePermission(models.PermFinance, b.handleResendBills))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/purge_users", bot.MatchTypePrefix,
+		b.RateLimit("/purge_users", b.asyncHandler(b.RequirePermission(models.PermSystem, b.handlePurgeUsers))))
+	// 群组配置导出/导入（system，仅私聊，导出文件含全部群配置；导入在 handler 中再限定 Owner）；导入命令可写在 JSON 文件的说明中
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, exportConfigsCommand, bot.MatchTypeExact,
+		b.RateLimit(exportConfigsCommand, b.asyncHandler(b.RequireChatScope(commandScopePrivate, b.RequirePermission(models.PermSystem, b.handleExportConfigs)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return isImportConfigsCommand(update.Message)
+	}, b.RateLimit(importConfigsCommand, b.asyncHandler(b.RequireChatScope(commandScopePrivate, b.RequirePermission(models.PermSystem, b.handleImportConfigs)))))
 
 	// 上游余额相关（Admin+）
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/余额", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireAdmin(b.handleUpstreamBalanceQuery)))
+		b.RateLimit("/余额", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleUpstreamBalanceQuery)))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/set_min_balance", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireAdmin(b.handleUpstreamSetMinBalance)))
+		b.RateLimit("/set_min_balance", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleUpstreamSetMinBalance)))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/set_balance_alert_limit", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireAdmin(b.handleUpstreamSetAlertLimit)))
+		b.RateLimit("/set_balance_alert_limit", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleUpstreamSetAlertLimit)))))
+	// 余额告警交互式配置菜单，输入复用 configMenuService 的输入态
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, balanceConfigCommand, bot.MatchTypeExact,
+		b.RateLimit(balanceConfigCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleBalanceConfig)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, balanceConfigCallbackPrefix)
+	}, b.asyncHandler(b.handleBalanceConfigCallback))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/日结", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleUpstreamSettlement)))
+		b.RateLimit("/日结", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleUpstreamSettlement)))))
 
 	// 管理员命令（Admin+） - 异步执行
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/admins", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleListAdmins)))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/admins", bot.MatchTypePrefix,
+		b.RateLimit("/admins", b.asyncHandler(b.RequireGlobalAdmin(b.handleListAdmins))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/userinfo", bot.MatchTypePrefix,
-		b.asyncHandler(b.RequireAdmin(b.handleUserInfo)))
+		b.RateLimit("/userinfo", b.asyncHandler(b.RequireGlobalAdmin(b.handleUserInfo))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/add_group_admin", bot.MatchTypePrefix,
+		b.RateLimit("/add_group_admin", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.handleAddGroupAdmin))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/del_group_admin", bot.MatchTypePrefix,
+		b.RateLimit("/del_group_admin", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.handleRemoveGroupAdmin))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/group_admins", bot.MatchTypeExact,
+		b.RateLimit("/group_admins", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleListGroupAdmins)))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/leave", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleLeave)))
+		b.RateLimit("/leave", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleLeave)))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/configs", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleConfigs)))
+		b.RateLimit("/configs", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleConfigs)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/features", bot.MatchTypeExact,
+		b.RateLimit("/features", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleFeatures)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/msgstats", bot.MatchTypeExact,
+		b.RateLimit("/msgstats", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleMessageStats)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/deleted", bot.MatchTypeExact,
+		b.RateLimit("/deleted", b.asyncHandler(b.RequireAdmin(b.handleDeletedMessages))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/edits", bot.MatchTypePrefix,
+		b.RateLimit("/edits", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleMessageEdits)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/members", bot.MatchTypePrefix,
+		b.RateLimit("/members", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleMemberStats)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/groupstats", bot.MatchTypePrefix,
+		b.RateLimit("/groupstats", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleGroupStats)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, groupStatusCommand, bot.MatchTypeExact,
+		b.RateLimit(groupStatusCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleGroupStatus)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, sifangAutoLookupEnableCommand, bot.MatchTypeExact,
+		b.RateLimit(sifangAutoLookupEnableCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleSifangAutoLookupToggle)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, sifangAutoLookupDisableCommand, bot.MatchTypeExact,
+		b.RateLimit(sifangAutoLookupDisableCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleSifangAutoLookupToggle)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, scheduleAddCommand, bot.MatchTypePrefix,
+		b.RateLimit(scheduleAddCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleScheduleAdd)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "/schedules", bot.MatchTypeExact,
+		b.RateLimit("/schedules", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleScheduleList)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, scheduleDeleteCommand, bot.MatchTypePrefix,
+		b.RateLimit(scheduleDeleteCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleScheduleDelete)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, commandAliasAddCommand, bot.MatchTypePrefix,
+		b.RateLimit(commandAliasAddCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleCommandAlias)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, commandAliasRemoveCommand, bot.MatchTypePrefix,
+		b.RateLimit(commandAliasRemoveCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleRemoveCommandAlias)))))
 
 	// 配置菜单回调查询处理器
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
@@ -68,6 +141,11 @@ func (b *Bot) registerHandlers() {
 		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, sifangfeature.SendMoneyCallbackPrefix)
 	}, b.asyncHandler(b.handleSifangSendMoneyCallback))
 
+	// 解绑接口多项匹配选择回调（Admin+）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, upstream.UnbindCallbackPrefix)
+	}, b.asyncHandler(b.RequireAdmin(b.handleInterfaceUnbindCallback)))
+
 	// 订单联动反馈回调处理
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
 		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, orderCascadeCallbackPrefix)
@@ -84,18 +162,73 @@ func (b *Bot) registerHandlers() {
 	}
 
 	// 收支记账命令
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "查询记账", bot.MatchTypeExact,
-		b.asyncHandler(b.handleQueryAccounting))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, accountingQueryCommand, bot.MatchTypePrefix,
+		b.RateLimit(accountingQueryCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.handleQueryAccounting))))
 	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "删除记账记录", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleDeleteAccounting)))
-	b.bot.RegisterHandler(bot.HandlerTypeMessageText, "清零记账", bot.MatchTypeExact,
-		b.asyncHandler(b.RequireAdmin(b.handleClearAccounting)))
+		b.RateLimit("删除记账记录", b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleDeleteAccounting)))))
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, accountingClearCommand, bot.MatchTypePrefix,
+		b.RateLimit(accountingClearCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleClearAccounting)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.Message != nil && isAccountingDateDeleteCommand(update.Message.Text)
+	}, b.RateLimit(accountingDateDeleteCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleDeleteAccountingByDate)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, accountingDateDeleteCallbackPrefix)
+	}, b.asyncHandler(b.handleDeleteAccountingByDateCallback))
+
+	// 消息搜索命令（Admin+）及翻页回调
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		_, ok := parseMessageSearchKeyword(update.Message.Text)
+		return ok
+	}, b.RateLimit(messageSearchCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleMessageSearch)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, messageSearchCallbackPrefix)
+	}, b.asyncHandler(b.handleMessageSearchCallback))
+
+	// 提及查询命令（Admin+）及翻页回调
+	b.bot.RegisterHandler(bot.HandlerTypeMessageText, mentionsCommand, bot.MatchTypePrefix,
+		b.RateLimit(mentionsCommand, b.asyncHandler(b.RequireChatScope(commandScopeGroup, b.RequireAdmin(b.handleMentions)))))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, mentionsCallbackPrefix)
+	}, b.asyncHandler(b.handleMentionsCallback))
+
+	// 管理员列表翻页回调（Admin+）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, adminListCallbackPrefix)
+	}, b.asyncHandler(b.RequireGlobalAdmin(b.handleListAdminsCallback)))
+
+	// 群发广播确认回调（Owner）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, broadcastCallbackPrefix)
+	}, b.asyncHandler(b.handleBroadcastCallback))
+
+	// 离群确认回调（仅发起 /leave 的管理员可确认）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, leaveCallbackPrefix)
+	}, b.asyncHandler(b.handleLeaveCallback))
+
+	// 不活跃用户清理确认回调（仅发起 /purge_users 的 Owner 可确认）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, purgeUsersCallbackPrefix)
+	}, b.asyncHandler(b.handlePurgeUsersCallback))
+
+	// 入群验证回调
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, joinVerifyCallbackPrefix)
+	}, b.asyncHandler(b.handleJoinVerifyCallback))
 
 	// 收支记账删除回调处理器
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
 		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, "acc_del:")
 	}, b.asyncHandler(b.handleAccountingDeleteCallback))
 
+	// inline 查询（任意聊天 @bot 计算表达式或查询 U 价）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.InlineQuery != nil
+	}, b.asyncHandler(b.handleInlineQuery))
+
 	// Bot 状态变化事件 (MyChatMember)
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
 		return update.MyChatMember != nil
@@ -106,6 +239,14 @@ func (b *Bot) registerHandlers() {
 		return update.EditedMessage != nil
 	}, b.asyncHandler(b.handleEditedMessage))
 
+	// Business 连接消息与删除事件（普通群组不会推送删除事件）
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.BusinessMessage != nil
+	}, b.asyncHandler(b.handleBusinessMessage))
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.DeletedBusinessMessages != nil
+	}, b.asyncHandler(b.handleDeletedBusinessMessages))
+
 	// 频道消息
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
 		return update.ChannelPost != nil
@@ -136,6 +277,11 @@ func (b *Bot) registerHandlers() {
 		return update.Message != nil && update.Message.LeftChatMember != nil
 	}, b.asyncHandler(b.handleLeftChatMember))
 
+	// 未匹配任何内置命令的 / 开头消息，尝试按本群命令别名转发
+	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
+		return update.Message != nil && strings.HasPrefix(update.Message.Text, "/")
+	}, b.asyncHandler(b.handleUnknownCommand))
+
 	// 普通文本消息（放在最后，作为 fallback）
 	b.bot.RegisterHandlerMatchFunc(func(update *botModels.Update) bool {
 		if update.Message == nil || update.Message.Text == "" {
@@ -170,18 +316,31 @@ func (b *Bot) handleStart(ctx context.Context, botInstance *bot.Bot, update *bot
 	}
 
 	if err := b.userService.RegisterOrUpdateUser(ctx, userInfo); err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, "注册失败，请稍后重试")
+		b.sendErrorMessage(ctx, update.Message.Chat.ID, i18n.T(userLang(update.Message.From), "start.register_failed"))
 		return
 	}
 
-	welcomeText := fmt.Sprintf(
-		"👋 你好, %s!\n\n欢迎使用本 Bot。\n\n可用命令:\n/start - 开始\n/ping - 测试连接\n/admins - 查看管理员列表（需要管理员权限）",
-		update.Message.From.FirstName,
-	)
+	// 深链接参数用于来源统计，校验失败只记日志，不影响欢迎消息
+	if payload := parseStartPayload(update.Message.Text); payload != "" {
+		_ = b.userService.RecordStartSource(ctx, update.Message.From.ID, payload)
+	}
+
+	lang := b.messageLang(ctx, update.Message)
+
+	welcomeText := i18n.T(lang, "start.welcome", html.EscapeString(update.Message.From.FirstName))
 
 	b.sendMessage(ctx, update.Message.Chat.ID, welcomeText)
 }
 
+// parseStartPayload 提取 "/start <payload>" 中的参数，无参数时返回空字符串
+func parseStartPayload(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
 // handlePing 处理 /ping 命令
 func (b *Bot) handlePing(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.Message == nil {
@@ -197,69 +356,141 @@ func (b *Bot) handlePing(ctx context.Context, botInstance *bot.Bot, update *botM
 	b.sendMessage(ctx, update.Message.Chat.ID, message)
 }
 
+// handleHealth 处理 /health 命令（仅 Owner），汇总各组件健康状态
+func (b *Bot) handleHealth(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	report := b.buildHealthReport(ctx)
+	b.sendMessage(ctx, update.Message.Chat.ID, report)
+}
+
+// handleUptime 处理 /uptime 命令（仅 Owner），展示实例运行时长、版本与后台任务状态
+func (b *Bot) handleUptime(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	b.sendMessage(ctx, update.Message.Chat.ID, b.buildUptimeMessage(ctx, time.Now()))
+}
+
 // handleHelp 处理 /help 命令（仅 Admin+）
+// 群组内按群配置与调用者角色动态拼装，私聊中展示通用帮助
 func (b *Bot) handleHelp(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
-	if update.Message == nil {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
 		return
 	}
 
+	user, err := b.userService.GetUserInfo(ctx, msg.From.ID)
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Help permission check failed: user_id=%d err=%v", msg.From.ID, err)
+		user = nil
+	}
+
+	var group *models.Group
+	if isGroupChatType(msg.Chat.Type) {
+		group, err = b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+		if err != nil {
+			logger.Ctx(ctx).Warnf("Help failed to load group: chat_id=%d err=%v", msg.Chat.ID, err)
+		}
+	}
+
+	var featureLines []string
+	if group != nil {
+		featureLines = b.featureManager.HelpLines(ctx, group)
+	}
+
+	// 群级管理员在本群享有通用管理命令
+	can := func(perm string) bool {
+		if perm == models.PermAdmin && group != nil && group.Settings.IsGroupAdmin(msg.From.ID) {
+			return true
+		}
+		return user.HasPermission(perm)
+	}
+	b.sendMessage(ctx, msg.Chat.ID, buildHelpText(b.resolveLang(ctx, group, msg.From), group, can, featureLines))
+}
+
+// privilegedHelpLines 高级管理命令的帮助行及所需权限，按展示顺序排列
+var privilegedHelpLines = []struct {
+	key  string
+	perm string
+}{
+	{"help.grant", models.PermManageAdmins},
+	{"help.revoke", models.PermManageAdmins},
+	{"help.perm", models.PermManageAdmins},
+	{"help.validate", models.PermManageGroups},
+	{"help.repair", models.PermManageGroups},
+	{"help.health", models.PermSystem},
+	{"help.uptime", models.PermSystem},
+	{"help.command_stats", models.PermSystem},
+	{"help.broadcast", models.PermBroadcast},
+	{"help.note", models.PermManageGroups},
+	{"help.tag", models.PermManageGroups},
+	{"help.balances", models.PermFinance},
+	{"help.resend_bills", models.PermFinance},
+	{"help.purge_users", models.PermSystem},
+	{"help.configs_transfer", models.PermSystem},
+}
+
+// buildHelpText 拼装帮助文本；group 为 nil 时视为私聊，仅展示通用帮助
+// 高级管理命令按 can 判断的权限逐条展示；功能插件的帮助行（featureLines）由各插件提供，暂不随 lang 切换
+func buildHelpText(lang i18n.Lang, group *models.Group, can func(perm string) bool, featureLines []string) string {
 	var text strings.Builder
-	text.WriteString("<b>🆘 管理员帮助总览</b>\n\n")
-
-	text.WriteString("<b>通用命令（所有成员）</b>\n")
-	text.WriteString("/start - 与机器人建立会话并登记用户信息\n")
-	text.WriteString("/ping - 测试机器人连接状态\n\n")
-
-	text.WriteString("<b>管理员命令（Admin+）</b>\n")
-	text.WriteString("/help - 查看本帮助\n")
-	text.WriteString("/admins - 查看管理员列表\n")
-	text.WriteString("/userinfo &lt;user_id&gt; - 查询指定用户信息\n")
-	text.WriteString("/leave - 让机器人离开当前群组（仅限群组内执行）\n")
-	text.WriteString("/configs - 打开群组功能配置菜单（仅限群组内执行）\n")
-	text.WriteString("撤回 - 在群组中引用机器人的消息发送“撤回”以删除该消息\n\n")
-
-	text.WriteString("<b>Owner 专属命令</b>\n")
-	text.WriteString("/grant &lt;user_id&gt; - 授予管理员权限\n")
-	text.WriteString("/revoke &lt;user_id&gt; - 撤销管理员权限\n\n")
-	text.WriteString("/validate - 校验数据库中的群组配置状态\n")
-	text.WriteString("/repair - 自动修复可识别的群组配置问题（例如缺少 tier）\n\n")
-
-	text.WriteString("<b>商户号管理（Admin+，群组）</b>\n")
-	text.WriteString("绑定 <code>[商户号]</code> - 绑定当前群组的四方商户号\n")
-	text.WriteString("解绑 - 解除已绑定的商户号\n")
-	text.WriteString("商户号 / 绑定状态 - 查看当前绑定情况\n\n")
-
-	text.WriteString("<b>接口管理（Admin+，群组）</b>\n")
-	text.WriteString("绑定接口 <code>[接口名称] [接口ID] [费率]</code> - 绑定上游接口并保存名称/费率，可重复执行绑定多个接口\n")
-	text.WriteString("解绑接口 <code>[接口ID]</code> - 解除指定接口；仅发送“解绑接口”可清空全部\n")
-	text.WriteString("接口ID / 接口状态 - 查看当前已绑定的接口列表\n\n")
-
-	text.WriteString("<b>上游账单查询（Admin+，上游群）</b>\n")
-	text.WriteString("上游账单 <code>[接口ID或名称] [可选日期]</code> - 查询指定接口的跑量、商户实收、代理收益和订单数，日期默认为当天\n\n")
-
-	text.WriteString("<b>四方支付查询（需开启“🏦 四方支付查询”功能并完成商户号绑定）</b>\n")
-	text.WriteString("余额[可选日期] - 查询余额，例如：余额、余额10月26\n")
-	text.WriteString("账单[可选日期] - 查询日汇总，例如：账单2023/10/26\n")
-	text.WriteString("每日00:00:05（北京时间）自动向已绑定商户号的群推送昨日账单\n")
-	text.WriteString("通道账单[可选日期] - 查看通道维度汇总\n")
-	text.WriteString("提款明细[可选日期] - 查看提款记录\n")
-	text.WriteString("费率 - 查看通道费率\n")
-	text.WriteString("自动查单 - 默认开启，自动识别文字/图片/视频标题中的订单号并异步查询，可在 /configs 的“🔍 四方自动查单”中关闭\n")
-	text.WriteString("下发 <code>金额</code> [谷歌验证码] - 申请下发，支持表达式和谷歌验证码，需在 60 秒内按钮确认\n\n")
-
-	text.WriteString("<b>USDT 价格查询（需开启“💰 USDT价格查询”功能，群组）</b>\n")
-	text.WriteString("<code>[a|z|k|w][序号] [金额]</code> - a=全部、z=支付宝、k=银行卡、w=微信；示例：z3 100\n\n")
-
-	text.WriteString("<b>计算器（需开启“🧮 计算器功能”，群组）</b>\n")
-	text.WriteString("直接发送数学表达式，例如：<code>(100+20)*1.5</code>\n\n")
-
-	text.WriteString("<b>收支记账（需开启“💳 收支记账”功能，仅 Admin+，群组）</b>\n")
-	text.WriteString("查询记账 - 查看今日账单\n")
-	text.WriteString("删除记账记录 - 打开最近记录删除菜单\n")
-	text.WriteString("清零记账 - 清空所有记录\n")
-	text.WriteString("记账输入格式示例：<code>+100U</code>、<code>-50Y</code>、<code>入100*7.2</code>、<code>出50/2Y</code>\n")
+	line := func(keys ...string) {
+		for _, key := range keys {
+			text.WriteString(i18n.T(lang, key))
+			text.WriteString("\n")
+		}
+	}
 
-	b.sendMessage(ctx, update.Message.Chat.ID, text.String())
+	line("help.title")
+	text.WriteString("\n")
+
+	line("help.section.common", "help.start", "help.ping", "help.lang")
+	text.WriteString("\n")
+
+	line("help.section.admin", "help.help", "help.admins", "help.userinfo", "help.group_admins", "help.group_status")
+	if group != nil {
+		line("help.leave", "help.configs", "help.features", "help.msgstats", "help.mentions", "help.deleted", "help.edits",
+			"help.members", "help.groupstats", "help.schedule_add", "help.schedules", "help.schedule_del", "help.alias", "help.recall")
+	}
+	text.WriteString("\n")
+
+	var privileged []string
+	for _, item := range privilegedHelpLines {
+		if can != nil && can(item.perm) {
+			privileged = append(privileged, item.key)
+		}
+	}
+	if len(privileged) > 0 {
+		line("help.section.owner")
+		line(privileged...)
+		text.WriteString("\n")
+	}
+
+	if group == nil {
+		text.WriteString(i18n.T(lang, "help.private_hint"))
+		return strings.TrimRight(text.String(), "\n")
+	}
+
+	if len(featureLines) > 0 {
+		text.WriteString(strings.Join(featureLines, "\n"))
+		text.WriteString("\n\n")
+	}
+
+	if group.Settings.SifangEnabled && group.Settings.SifangAutoLookupEnabled {
+		line("help.section.auto_lookup", "help.auto_lookup")
+		text.WriteString("\n")
+	}
+
+	if group.Settings.AccountingEnabled {
+		line("help.section.accounting", "help.accounting_query", "help.accounting_delete",
+			"help.accounting_by_date", "help.accounting_clear", "help.accounting_format")
+	}
+
+	return strings.TrimRight(text.String(), "\n")
 }
 
 func (b *Bot) handleUpstreamBalanceQuery(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
@@ -270,7 +501,7 @@ func (b *Bot) handleUpstreamBalanceQuery(ctx context.Context, botInstance *bot.B
 
 	result, err := b.balanceService.Get(ctx, msg.Chat.ID)
 	if err != nil {
-		logger.L().Errorf("Balance query failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Errorf("Balance query failed: chat_id=%d err=%v", msg.Chat.ID, err)
 		b.sendErrorMessage(ctx, msg.Chat.ID, "查询余额失败", msg.ID)
 		return
 	}
@@ -296,15 +527,15 @@ func (b *Bot) handleUpstreamSetMinBalance(ctx context.Context, botInstance *bot.
 		return
 	}
 
-	threshold, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
-	if err != nil || threshold < 0 {
-		b.sendErrorMessage(ctx, msg.Chat.ID, "请输入合法的金额（>=0）", msg.ID)
+	threshold, err := parseMinBalanceInput(fields[1])
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
 		return
 	}
 
 	result, setErr := b.balanceService.SetMinBalance(ctx, msg.Chat.ID, threshold, msg.From.ID)
 	if setErr != nil {
-		logger.L().Errorf("Set min balance failed: chat_id=%d err=%v", msg.Chat.ID, setErr)
+		logger.Ctx(ctx).Errorf("Set min balance failed: chat_id=%d err=%v", msg.Chat.ID, setErr)
 		b.sendErrorMessage(ctx, msg.Chat.ID, "设置失败", msg.ID)
 		return
 	}
@@ -324,15 +555,15 @@ func (b *Bot) handleUpstreamSetAlertLimit(ctx context.Context, botInstance *bot.
 		return
 	}
 
-	limit, err := strconv.Atoi(strings.TrimSpace(fields[1]))
-	if err != nil || limit <= 0 {
-		b.sendErrorMessage(ctx, msg.Chat.ID, "请输入大于 0 的整数", msg.ID)
+	limit, err := parseAlertLimitInput(fields[1])
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
 		return
 	}
 
 	result, setErr := b.balanceService.SetAlertLimit(ctx, msg.Chat.ID, limit, msg.From.ID)
 	if setErr != nil {
-		logger.L().Errorf("Set alert limit failed: chat_id=%d err=%v", msg.Chat.ID, setErr)
+		logger.Ctx(ctx).Errorf("Set alert limit failed: chat_id=%d err=%v", msg.Chat.ID, setErr)
 		b.sendErrorMessage(ctx, msg.Chat.ID, "设置失败", msg.ID)
 		return
 	}
@@ -347,14 +578,17 @@ func (b *Bot) handleUpstreamSettlement(ctx context.Context, botInstance *bot.Bot
 		return
 	}
 
-	loc := mustLoadChinaLocation()
-	target := previousBillingDate(time.Now().In(loc), loc)
-	operationID := fmt.Sprintf("settle:%s", target.Format("2006-01-02"))
+	loc := models.DefaultLocation()
+	if group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+	target := models.PreviousBillingDate(time.Now(), loc)
+	operationID := service.SettlementOperationID(msg.Chat.ID, target)
 
 	result, err := b.balanceService.SettleDaily(ctx, msg.Chat.ID, target, msg.From.ID, operationID)
 	if err != nil {
-		logger.L().Errorf("Manual upstream settlement failed: chat_id=%d err=%v", msg.Chat.ID, err)
-		b.sendErrorMessage(ctx, msg.Chat.ID, fmt.Sprintf("日结失败：%v", err), msg.ID)
+		logger.Ctx(ctx).Errorf("Manual upstream settlement failed: chat_id=%d err=%v", msg.Chat.ID, err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTMLf("日结失败：%v", err), msg.ID)
 		return
 	}
 
@@ -371,7 +605,7 @@ func (b *Bot) handleGrantAdmin(ctx context.Context, botInstance *bot.Bot, update
 	parts := strings.Fields(update.Message.Text)
 	if len(parts) < 2 {
 		b.sendErrorMessage(ctx, update.Message.Chat.ID,
-			"用法: /grant <user_id>\n例如: /grant 123456789")
+			safeHTML("用法: /grant <user_id> [super]\n例如: /grant 123456789（管理员）、/grant 123456789 super（超级管理员）"))
 		return
 	}
 
@@ -382,14 +616,22 @@ func (b *Bot) handleGrantAdmin(ctx context.Context, botInstance *bot.Bot, update
 		return
 	}
 
-	// 使用 Service 授予管理员权限（包含业务验证）
-	if err := b.userService.GrantAdminPermission(ctx, targetID, update.Message.From.ID); err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, err.Error())
+	role := models.RoleAdmin
+	if len(parts) > 2 {
+		if role, err = parseGrantRole(parts[2]); err != nil {
+			b.sendErrorMessage(ctx, update.Message.Chat.ID, safeHTML(err.Error()))
+			return
+		}
+	}
+
+	// 使用 Service 授予管理角色（包含业务验证）
+	if err := b.userService.GrantRole(ctx, targetID, role, update.Message.From.ID); err != nil {
+		b.sendErrorMessage(ctx, update.Message.Chat.ID, safeHTML(err.Error()))
 		return
 	}
 
 	b.sendSuccessMessage(ctx, update.Message.Chat.ID,
-		fmt.Sprintf("已授予用户 %d 管理员权限", targetID))
+		fmt.Sprintf("已授予用户 %d %s权限", targetID, models.RoleLabel(role)))
 }
 
 // handleRevokeAdmin 处理 /revoke 命令（撤销管理员权限）
@@ -415,7 +657,7 @@ func (b *Bot) handleRevokeAdmin(ctx context.Context, botInstance *bot.Bot, updat
 
 	// 使用 Service 撤销管理员权限（包含业务验证）
 	if err := b.userService.RevokeAdminPermission(ctx, targetID, update.Message.From.ID); err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, err.Error())
+		b.sendErrorMessage(ctx, update.Message.Chat.ID, safeHTML(err.Error()))
 		return
 	}
 
@@ -431,7 +673,7 @@ func (b *Bot) handleValidateGroupsCommand(ctx context.Context, botInstance *bot.
 
 	result, err := b.groupService.ValidateGroups(ctx)
 	if err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, fmt.Sprintf("校验失败：%v", err))
+		b.sendErrorMessage(ctx, update.Message.Chat.ID, safeHTMLf("校验失败：%v", err))
 		return
 	}
 
@@ -454,7 +696,7 @@ func (b *Bot) handleValidateGroupsCommand(ctx context.Context, botInstance *bot.
 
 	for i := 0; i < maxDetails; i++ {
 		issue := result.Issues[i]
-		text.WriteString(fmt.Sprintf("%d. %s (%d)\n", i+1, html.EscapeString(issue.Title), issue.GroupID))
+		text.WriteString(fmt.Sprintf("%d. %s%s (%d)\n", i+1, html.EscapeString(issue.Title), formatGroupNoteSuffix(issue.Note, issue.Tags), issue.GroupID))
 
 		tier := "(未设置)"
 		if issue.StoredTier != "" {
@@ -485,7 +727,7 @@ func (b *Bot) handleRepairGroupsCommand(ctx context.Context, botInstance *bot.Bo
 
 	result, err := b.groupService.RepairGroups(ctx)
 	if err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, fmt.Sprintf("修复失败：%v", err))
+		b.sendErrorMessage(ctx, update.Message.Chat.ID, safeHTMLf("修复失败：%v", err))
 		return
 	}
 
@@ -501,43 +743,6 @@ func (b *Bot) handleRepairGroupsCommand(ctx context.Context, botInstance *bot.Bo
 	b.sendMessage(ctx, update.Message.Chat.ID, text.String())
 }
 
-// handleListAdmins 处理 /admins 命令（列出所有管理员）
-func (b *Bot) handleListAdmins(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
-	if update.Message == nil {
-		return
-	}
-
-	// 使用 Service 获取管理员列表
-	admins, err := b.userService.ListAllAdmins(ctx)
-	if err != nil {
-		b.sendErrorMessage(ctx, update.Message.Chat.ID, "查询失败")
-		return
-	}
-
-	if len(admins) == 0 {
-		b.sendMessage(ctx, update.Message.Chat.ID, "📝 暂无管理员")
-		return
-	}
-
-	var text strings.Builder
-	text.WriteString("👥 管理员列表:\n\n")
-	for i, admin := range admins {
-		roleEmoji := "👤"
-		if admin.Role == models.RoleOwner {
-			roleEmoji = "👑"
-		}
-		text.WriteString(fmt.Sprintf("%d. %s %s (@%s) - ID: %d\n",
-			i+1,
-			roleEmoji,
-			admin.FirstName,
-			admin.Username,
-			admin.TelegramID,
-		))
-	}
-
-	b.sendMessage(ctx, update.Message.Chat.ID, text.String())
-}
-
 // handleUserInfo 处理 /userinfo 命令（查看用户信息）
 func (b *Bot) handleUserInfo(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.Message == nil {
@@ -570,6 +775,8 @@ func (b *Bot) handleUserInfo(ctx context.Context, botInstance *bot.Bot, update *
 	switch user.Role {
 	case models.RoleOwner:
 		roleEmoji = "👑"
+	case models.RoleSuperAdmin:
+		roleEmoji = "🌟"
 	case models.RoleAdmin:
 		roleEmoji = "⭐"
 	default:
@@ -581,7 +788,7 @@ func (b *Bot) handleUserInfo(ctx context.Context, botInstance *bot.Bot, update *
 		premiumBadge = " 💎"
 	}
 
-	text := fmt.Sprintf(
+	text := safeHTMLf(
 		"👤 用户信息\n\n"+
 			"ID: %d\n"+
 			"姓名: %s %s%s\n"+
@@ -601,39 +808,14 @@ func (b *Bot) handleUserInfo(ctx context.Context, botInstance *bot.Bot, update *
 		user.CreatedAt.Format("2006-01-02 15:04:05"),
 		user.LastActiveAt.Format("2006-01-02 15:04:05"),
 	)
-
-	b.sendMessage(ctx, update.Message.Chat.ID, text)
-}
-
-// handleLeave 处理 /leave 命令（让 Bot 离开群组）
-func (b *Bot) handleLeave(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
-	if update.Message == nil {
-		return
+	if user.StartSource != "" {
+		text += safeHTMLf("\n来源: %s", user.StartSource)
 	}
-
-	chatID := update.Message.Chat.ID
-
-	// 只能在群组中使用
-	if update.Message.Chat.Type != "group" && update.Message.Chat.Type != "supergroup" {
-		b.sendErrorMessage(ctx, chatID, "此命令只能在群组中使用")
-		return
+	if len(user.Permissions) > 0 {
+		text += safeHTMLf("\n额外权限: %s", strings.Join(user.Permissions, ", "))
 	}
 
-	// 发送离别消息
-	b.sendMessage(ctx, chatID, "👋 再见！我将离开这个群组。")
-
-	// 标记 Bot 离开并删除群组记录
-	if err := b.groupService.LeaveGroup(ctx, chatID); err != nil {
-		logger.L().Errorf("Failed to mark group as left: chat_id=%d, error=%v", chatID, err)
-	}
-
-	// 让 Bot 离开群组
-	_, err := botInstance.LeaveChat(ctx, &bot.LeaveChatParams{
-		ChatID: chatID,
-	})
-	if err != nil {
-		logger.L().Errorf("Failed to leave chat: chat_id=%d, error=%v", chatID, err)
-	}
+	b.sendMessage(ctx, update.Message.Chat.ID, text)
 }
 
 // handleMyChatMember 处理 Bot 状态变化（被添加到群组/被踢出群组）
@@ -647,7 +829,7 @@ func (b *Bot) handleMyChatMember(ctx context.Context, botInstance *bot.Bot, upda
 	oldStatus := chatMember.OldChatMember.Type
 	newStatus := chatMember.NewChatMember.Type
 
-	logger.L().Infof("Bot status change: chat_id=%d, old=%s, new=%s", chat.ID, oldStatus, newStatus)
+	logger.Ctx(ctx).Infof("Bot status change: chat_id=%d, old=%s, new=%s", chat.ID, oldStatus, newStatus)
 
 	// Bot 被添加到群组
 	if (oldStatus == botModels.ChatMemberTypeLeft || oldStatus == botModels.ChatMemberTypeBanned) &&
@@ -660,18 +842,29 @@ func (b *Bot) handleMyChatMember(ctx context.Context, botInstance *bot.Bot, upda
 			BotStatus:  models.BotStatusActive,
 		}
 
-		if err := b.groupService.HandleBotAddedToGroup(ctx, group); err != nil {
-			logger.L().Errorf("Failed to handle bot added to group: %v", err)
+		restored, err := b.groupService.HandleBotAddedToGroup(ctx, group)
+		if err != nil {
+			logger.Ctx(ctx).Errorf("Failed to handle bot added to group: %v", err)
 			return
 		}
 
+		// 入群时拉取成员数基准值，此后由入群/退群事件增减
+		if isGroupChatType(chat.Type) {
+			if _, err := b.syncMemberCount(ctx, botInstance, chat.ID); err != nil {
+				logger.Ctx(ctx).Warnf("Failed to sync member count on join: chat_id=%d err=%v", chat.ID, err)
+			}
+		}
+
 		// 发送欢迎消息（频道除外）
 		if chat.Type != "channel" {
-			welcomeText := fmt.Sprintf(
+			welcomeText := safeHTMLf(
 				"👋 你好！我是 Bot，感谢邀请我加入 %s！\n\n"+
 					"使用 /configs 查看可用配置命令。",
 				chat.Title,
 			)
+			if restored {
+				welcomeText += "\n\n♻️ 已恢复上次配置（商户号、接口绑定与功能开关），可使用 /configs 确认。"
+			}
 			b.sendMessage(ctx, chat.ID, welcomeText)
 		}
 	}
@@ -685,7 +878,7 @@ func (b *Bot) handleMyChatMember(ctx context.Context, botInstance *bot.Bot, upda
 		}
 
 		if err := b.groupService.HandleBotRemovedFromGroup(ctx, chat.ID, reason); err != nil {
-			logger.L().Errorf("Failed to handle bot removed from group: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to handle bot removed from group: %v", err)
 		}
 	}
 }
@@ -727,6 +920,10 @@ func (b *Bot) handleTextMessage(ctx context.Context, botInstance *bot.Bot, updat
 	if msg.From != nil && b.configMenuService != nil {
 		// 先检查是否有待处理状态
 		state := b.configMenuService.GetUserState(msg.Chat.ID, msg.From.ID)
+		// 余额配置菜单（/balance_config）的输入态单独处理
+		if b.processBalanceConfigInput(ctx, msg, state) {
+			return
+		}
 		if state != nil {
 			// 有状态，获取或创建群组记录
 			chatInfo := &service.TelegramChatInfo{
@@ -742,20 +939,36 @@ func (b *Bot) handleTextMessage(ctx context.Context, botInstance *bot.Bot, updat
 			}
 
 			items := b.getConfigItems()
+			menuMessageID := state.MessageID
+			before := *group
 			responseMsg, err := b.configMenuService.ProcessUserInput(ctx, group, msg.From.ID, msg.Text, items)
 
 			// 如果有响应消息（无论成功或失败），说明这是配置输入
 			if responseMsg != "" {
 				if err != nil {
 					b.sendErrorMessage(ctx, msg.Chat.ID, responseMsg)
+				} else if changes := diffConfigItems(items, &before, group); len(changes) > 0 {
+					b.announceConfigChanges(ctx, msg.Chat.ID, *msg.From, changes)
+					if before.Settings.MessageRetentionDays != group.Settings.MessageRetentionDays {
+						b.applyMessageRetention(ctx, msg.Chat.ID)
+					}
 				} else {
 					b.sendSuccessMessage(ctx, msg.Chat.ID, responseMsg)
 				}
+				// 输入结束（成功或重试耗尽）后，把输入提示恢复为配置菜单
+				if menuMessageID != 0 && b.configMenuService.GetUserState(msg.Chat.ID, msg.From.ID) == nil {
+					b.refreshConfigMenu(ctx, group, filterConfigItemsByTier(b.getConfigItems(), group.Tier), msg.Chat.ID, menuMessageID)
+				}
 				return // 处理完配置输入，不再记录为普通消息
 			}
 		}
 	}
 
+	// 命中本群命令别名时改写为内置命令重新分发
+	if b.dispatchCommandAlias(ctx, botInstance, update) {
+		return
+	}
+
 	// 尝试处理记账输入
 	if b.handleAccountingInput(ctx, botInstance, update) {
 		return // 记账已处理，不再记录为普通消息
@@ -766,7 +979,11 @@ func (b *Bot) handleTextMessage(ctx context.Context, botInstance *bot.Bot, updat
 	response, handled, err := b.featureManager.Process(ctx, msg)
 	if handled {
 		sendFeatureResponse := func() {
-			if response == nil || response.Text == "" {
+			if response == nil || (response.Text == "" && response.Document == nil) {
+				return
+			}
+			if response.Document != nil {
+				b.sendDocument(ctx, msg.Chat.ID, response.Document.Filename, response.Document.Data, response.Text, msg.ID)
 				return
 			}
 
@@ -806,13 +1023,14 @@ func (b *Bot) handleTextMessage(ctx context.Context, botInstance *bot.Bot, updat
 		ChatID:            msg.Chat.ID,
 		UserID:            msg.From.ID,
 		Text:              msg.Text,
+		Entities:          messageEntities(msg.Entities),
 		ReplyToMessageID:  replyToID,
 		SentAt:            time.Unix(int64(msg.Date), 0),
 	}
 
 	// 记录消息
 	if err := b.messageService.HandleTextMessage(ctx, textMsg); err != nil {
-		logger.L().Errorf("Failed to handle text message: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to handle text message: %v", err)
 	}
 
 	b.tryTriggerSifangAutoLookup(ctx, msg)
@@ -824,46 +1042,45 @@ func (b *Bot) tryHandleRecallCommand(ctx context.Context, botInstance *bot.Bot,
 		return false
 	}
 
-	isAdmin, err := b.userService.CheckAdminPermission(ctx, msg.From.ID)
-	if err != nil {
-		logger.L().Errorf("Failed to check admin permission: user=%d err=%v", msg.From.ID, err)
-		b.sendErrorMessage(ctx, msg.Chat.ID, "权限检查失败，请稍后重试", msg.ID)
-		return true
-	}
-
-	if !isAdmin {
-		b.sendErrorMessage(ctx, msg.Chat.ID, "此命令需要管理员权限", msg.ID)
+	if !b.checkPermission(ctx, botInstance, &botModels.Update{Message: msg}, models.PermAdmin) {
 		return true
 	}
 
+	// 未引用消息时撤回本群最近一条 Bot 消息
+	var targetID int
 	if msg.ReplyToMessage == nil {
-		b.sendErrorMessage(ctx, msg.Chat.ID, "请引用需要撤回的机器人消息", msg.ID)
-		return true
-	}
-
-	target := msg.ReplyToMessage
-	if target.From == nil || target.From.ID != botInstance.ID() {
-		b.sendErrorMessage(ctx, msg.Chat.ID, "只能撤回本机器人的消息", msg.ID)
-		return true
+		id, ok := b.latestBotMessageID(msg.Chat.ID)
+		if !ok {
+			b.sendErrorMessage(ctx, msg.Chat.ID, "没有可撤回的机器人消息（仅能撤回 48 小时内发出的消息）", msg.ID)
+			return true
+		}
+		targetID = id
+	} else {
+		if !b.isBotMessage(msg.ReplyToMessage) {
+			b.sendErrorMessage(ctx, msg.Chat.ID, "只能撤回本机器人 48 小时内发出的消息", msg.ID)
+			return true
+		}
+		targetID = msg.ReplyToMessage.ID
 	}
 
-	_, err = botInstance.DeleteMessage(ctx, &bot.DeleteMessageParams{
+	_, err := botInstance.DeleteMessage(ctx, &bot.DeleteMessageParams{
 		ChatID:    msg.Chat.ID,
-		MessageID: target.ID,
+		MessageID: targetID,
 	})
 	if err != nil {
-		logger.L().Errorf("Failed to delete recalled message: chat=%d target_msg=%d err=%v",
-			msg.Chat.ID, target.ID, err)
+		logger.Ctx(ctx).Errorf("Failed to delete recalled message: chat=%d target_msg=%d err=%v",
+			msg.Chat.ID, targetID, err)
 		b.sendErrorMessage(ctx, msg.Chat.ID, "撤回失败，请稍后重试", msg.ID)
 		return true
 	}
+	b.sentMessages.forget(msg.Chat.ID, targetID)
 
 	_, err = botInstance.DeleteMessage(ctx, &bot.DeleteMessageParams{
 		ChatID:    msg.Chat.ID,
 		MessageID: msg.ID,
 	})
 	if err != nil {
-		logger.L().Warnf("Failed to delete recall command message: chat=%d msg=%d err=%v",
+		logger.Ctx(ctx).Warnf("Failed to delete recall command message: chat=%d msg=%d err=%v",
 			msg.Chat.ID, msg.ID, err)
 	}
 
@@ -893,7 +1110,7 @@ func (b *Bot) handleSifangSendMoneyCallback(ctx context.Context, botInstance *bo
 
 	result, err := b.sifangFeature.HandleSendMoneyCallback(ctx, query, action, token)
 	if err != nil {
-		logger.L().Errorf("handle sifang send money callback failed: action=%s token=%s err=%v", action, token, err)
+		logger.Ctx(ctx).Errorf("handle sifang send money callback failed: action=%s token=%s err=%v", action, token, err)
 		b.answerCallback(ctx, botInstance, query.ID, "处理失败，请稍后重试", true)
 		return
 	}
@@ -904,6 +1121,10 @@ func (b *Bot) handleSifangSendMoneyCallback(ctx context.Context, botInstance *bo
 		}
 	}
 
+	if result != nil && result.Finished {
+		b.deleteSendMoneyExpiration(token)
+	}
+
 	if result != nil {
 		b.answerCallback(ctx, botInstance, query.ID, result.Answer, result.ShowAlert)
 	} else {
@@ -946,7 +1167,7 @@ func (b *Bot) handleOrderCascadeCallback(ctx context.Context, botInstance *bot.B
 	}
 
 	if _, err := b.sendMessageWithMarkupAndMessage(ctx, state.MerchantChatID, feedback, nil, replyTo...); err != nil {
-		logger.L().Errorf("Failed to relay cascade feedback: merchant_chat=%d order_no=%s err=%v",
+		logger.Ctx(ctx).Errorf("Failed to relay cascade feedback: merchant_chat=%d order_no=%s err=%v",
 			state.MerchantChatID, state.OrderNo, err)
 		b.answerCallback(ctx, botInstance, query.ID, "反馈发送失败", true)
 		return
@@ -957,6 +1178,9 @@ func (b *Bot) handleOrderCascadeCallback(ctx context.Context, botInstance *bot.B
 		cascadeMsg = query.Message.Message
 	}
 	b.editCascadeMessage(ctx, state, cascadeMsg, action, &query.From, now)
+	if isOrderCascadeFinalAction(action) {
+		b.deleteOrderCascadeState(state.Token)
+	}
 	b.answerCallback(ctx, botInstance, query.ID, "反馈已同步", false)
 }
 
@@ -996,8 +1220,21 @@ func (b *Bot) tryScheduleSifangSendMoneyExpiration(sentMsg *botModels.Message, m
 }
 
 func (b *Bot) scheduleSifangSendMoneyExpiration(chatID int64, messageID int, token string) {
+	ttl, review := b.sifangFeature.PendingTTL(token)
+	now := time.Now()
+	record := &models.SendMoneyExpiration{
+		Token:     token,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Review:    review,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	// 持久化过期时间，重启后由扫描器兜底；内存定时器作为快路径
+	b.saveSendMoneyExpiration(record)
+
 	go func() {
-		timer := time.NewTimer(sifangfeature.SendMoneyConfirmTTL)
+		timer := time.NewTimer(ttl)
 		defer timer.Stop()
 
 		<-timer.C
@@ -1006,14 +1243,10 @@ func (b *Bot) scheduleSifangSendMoneyExpiration(chatID int64, messageID int, tok
 			return
 		}
 
-		if !b.sifangFeature.ExpirePending(token) {
-			return
-		}
-
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		b.editMessage(ctx, chatID, messageID, "⚠️ 由于 60 秒内没有操作，下发请求已失效，请重新下发。", nil)
+		b.expireSendMoneyMessage(ctx, record)
 	}()
 }
 
@@ -1034,19 +1267,24 @@ func (b *Bot) handleMediaMessage(ctx context.Context, botInstance *bot.Bot, upda
 	}
 
 	b.registerUserFromTelegram(ctx, msg.From)
-	var messageType, fileID, mimeType string
+	var messageType, fileID, fileUniqueID, mimeType string
 	var fileSize int64
 	var fileNames []string
 
 	// 判断媒体类型并提取信息
+	// 去重使用 file_unique_id 而不是 file_id：file_id 是下载凭证，同一文件在不同消息、不同 Bot 下可能不同；
+	// file_unique_id 对同一文件稳定。photo 的每个尺寸是独立文件，统一取最大尺寸的 file_unique_id，
+	// 转发或复制同一张图时保持一致；用户重新上传（客户端重新压缩）会生成新文件，不视为重复
 	if len(msg.Photo) > 0 {
 		messageType = models.MessageTypePhoto
 		photo := msg.Photo[len(msg.Photo)-1] // 取最大尺寸
 		fileID = photo.FileID
+		fileUniqueID = photo.FileUniqueID
 		fileSize = int64(photo.FileSize)
 	} else if msg.Video != nil {
 		messageType = models.MessageTypeVideo
 		fileID = msg.Video.FileID
+		fileUniqueID = msg.Video.FileUniqueID
 		fileSize = int64(msg.Video.FileSize)
 		mimeType = msg.Video.MimeType
 		if msg.Video.FileName != "" {
@@ -1055,6 +1293,7 @@ func (b *Bot) handleMediaMessage(ctx context.Context, botInstance *bot.Bot, upda
 	} else if msg.Document != nil {
 		messageType = models.MessageTypeDocument
 		fileID = msg.Document.FileID
+		fileUniqueID = msg.Document.FileUniqueID
 		fileSize = int64(msg.Document.FileSize)
 		mimeType = msg.Document.MimeType
 		if msg.Document.FileName != "" {
@@ -1063,11 +1302,13 @@ func (b *Bot) handleMediaMessage(ctx context.Context, botInstance *bot.Bot, upda
 	} else if msg.Voice != nil {
 		messageType = models.MessageTypeVoice
 		fileID = msg.Voice.FileID
+		fileUniqueID = msg.Voice.FileUniqueID
 		fileSize = int64(msg.Voice.FileSize)
 		mimeType = msg.Voice.MimeType
 	} else if msg.Audio != nil {
 		messageType = models.MessageTypeAudio
 		fileID = msg.Audio.FileID
+		fileUniqueID = msg.Audio.FileUniqueID
 		fileSize = int64(msg.Audio.FileSize)
 		mimeType = msg.Audio.MimeType
 		if msg.Audio.FileName != "" {
@@ -1076,10 +1317,12 @@ func (b *Bot) handleMediaMessage(ctx context.Context, botInstance *bot.Bot, upda
 	} else if msg.Sticker != nil {
 		messageType = models.MessageTypeSticker
 		fileID = msg.Sticker.FileID
+		fileUniqueID = msg.Sticker.FileUniqueID
 		fileSize = int64(msg.Sticker.FileSize)
 	} else if msg.Animation != nil {
 		messageType = models.MessageTypeAnimation
 		fileID = msg.Animation.FileID
+		fileUniqueID = msg.Animation.FileUniqueID
 		fileSize = int64(msg.Animation.FileSize)
 		mimeType = msg.Animation.MimeType
 		if msg.Animation.FileName != "" {
@@ -1097,17 +1340,38 @@ func (b *Bot) handleMediaMessage(ctx context.Context, botInstance *bot.Bot, upda
 		MessageType:       messageType,
 		Caption:           msg.Caption,
 		MediaFileID:       fileID,
+		MediaFileUniqueID: fileUniqueID,
 		MediaFileSize:     fileSize,
 		MediaMimeType:     mimeType,
 		SentAt:            time.Unix(int64(msg.Date), 0),
 	}
 
 	// 记录消息
-	if err := b.messageService.HandleMediaMessage(ctx, mediaMsg); err != nil {
-		logger.L().Errorf("Failed to handle media message: %v", err)
+	stored, err := b.messageService.HandleMediaMessage(ctx, mediaMsg)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to handle media message: %v", err)
 	}
 
+	b.checkMediaAlert(ctx, msg, fileSize, mimeType, fileNames)
 	b.tryTriggerSifangAutoLookup(ctx, msg, fileNames...)
+
+	archiveJob := mediaArchiveJob{
+		ChatID:       msg.Chat.ID,
+		MessageID:    int64(msg.ID),
+		MessageType:  messageType,
+		FileID:       fileID,
+		FileUniqueID: fileUniqueID,
+		MimeType:     mimeType,
+		FileSize:     fileSize,
+		SentAt:       mediaMsg.SentAt,
+	}
+	if len(fileNames) > 0 {
+		archiveJob.FileName = fileNames[0]
+	}
+	// 去重跳过或记录失败的消息没有记录可回写归档地址，不再提交归档
+	if stored {
+		b.enqueueMediaArchive(ctx, msg, archiveJob)
+	}
 }
 
 // handleEditedMessage 处理消息编辑事件
@@ -1121,7 +1385,7 @@ func (b *Bot) handleEditedMessage(ctx context.Context, botInstance *bot.Bot, upd
 
 	// 更新消息编辑信息
 	if err := b.messageService.HandleEditedMessage(ctx, int64(msg.ID), msg.Chat.ID, msg.Text, editedAt); err != nil {
-		logger.L().Errorf("Failed to handle edited message: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to handle edited message: %v", err)
 	}
 }
 
@@ -1156,18 +1420,19 @@ func (b *Bot) handleChannelPost(ctx context.Context, botInstance *bot.Bot, updat
 
 	// 记录频道消息
 	if err := b.messageService.RecordChannelPost(ctx, channelPost); err != nil {
-		logger.L().Errorf("Failed to handle channel post: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to handle channel post: %v", err)
 	}
 
 	// 触发转发功能
 	if b.forwardService != nil {
 		if err := b.forwardService.HandleChannelMessage(ctx, botInstance, update); err != nil {
-			logger.L().Errorf("Failed to handle channel message for forwarding: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to handle channel message for forwarding: %v", err)
 		}
 	}
 }
 
 // handleEditedChannelPost 处理编辑的频道消息
+// 只更新消息记录，不重新转发：转发出去的是独立副本，重新转发会在每个群多出一条；如需更正请撤回后重新发布
 func (b *Bot) handleEditedChannelPost(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.EditedChannelPost == nil || update.EditedChannelPost.Text == "" {
 		return
@@ -1178,7 +1443,7 @@ func (b *Bot) handleEditedChannelPost(ctx context.Context, botInstance *bot.Bot,
 
 	// 更新频道消息编辑信息
 	if err := b.messageService.HandleEditedMessage(ctx, int64(post.ID), post.Chat.ID, post.Text, editedAt); err != nil {
-		logger.L().Errorf("Failed to handle edited channel post: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to handle edited channel post: %v", err)
 	}
 }
 
@@ -1188,15 +1453,55 @@ func (b *Bot) handleNewChatMembers(ctx context.Context, botInstance *bot.Bot, up
 		return
 	}
 
-	for i := range update.Message.NewChatMembers {
-		member := update.Message.NewChatMembers[i]
+	msg := update.Message
+	settings, verify := b.joinVerifySettings(ctx, msg)
+
+	// 成员数包含其他 Bot（与 Telegram 计数一致），Bot 自身入群由 MyChatMember 校准
+	joined := 0
+	for i := range msg.NewChatMembers {
+		if msg.NewChatMembers[i].ID != botInstance.ID() {
+			joined++
+		}
+	}
+	b.trackMemberChange(ctx, botInstance, msg.Chat, joined, 0)
+
+	for i := range msg.NewChatMembers {
+		member := msg.NewChatMembers[i]
 		if member.IsBot {
 			continue
 		}
 		b.registerUserFromTelegram(ctx, &member)
+		b.recordMemberEvent(ctx, msg, &member, true)
+
+		if verify {
+			b.startJoinVerification(ctx, msg.Chat, member, settings)
+		}
 	}
 }
 
+// joinVerifySettings 判断本次入群是否需要验证：群组已开启且不是管理员拉人入群
+func (b *Bot) joinVerifySettings(ctx context.Context, msg *botModels.Message) (models.GroupSettings, bool) {
+	if !isGroupChatType(msg.Chat.Type) {
+		return models.GroupSettings{}, false
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil || group == nil || !group.Settings.JoinVerifyEnabled {
+		return models.GroupSettings{}, false
+	}
+
+	if msg.From != nil {
+		invitedByOther := len(msg.NewChatMembers) > 1 || (len(msg.NewChatMembers) == 1 && msg.NewChatMembers[0].ID != msg.From.ID)
+		if invitedByOther {
+			if isAdmin, err := b.userService.CheckAdminPermission(ctx, msg.From.ID, msg.Chat.ID); err == nil && isAdmin {
+				return models.GroupSettings{}, false
+			}
+		}
+	}
+
+	return group.Settings, true
+}
+
 // handleLeftChatMember 处理成员离开系统消息
 func (b *Bot) handleLeftChatMember(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.Message == nil || update.Message.LeftChatMember == nil {
@@ -1207,13 +1512,45 @@ func (b *Bot) handleLeftChatMember(ctx context.Context, botInstance *bot.Bot, up
 	leftMember := msg.LeftChatMember
 
 	// 记录日志
-	logger.L().Infof("Member left: chat_id=%d, user_id=%d, username=%s",
+	logger.Ctx(ctx).Infof("Member left: chat_id=%d, user_id=%d, username=%s",
 		msg.Chat.ID, leftMember.ID, leftMember.Username)
 
-	// 这里可以添加更多逻辑，例如：
-	// - 发送离别消息
-	// - 更新成员统计
-	// - 记录离开事件到数据库
+	// Bot 自身被移除由 MyChatMember 处理，其他 Bot 计入成员数但不计入成员变动记录
+	if leftMember.ID == botInstance.ID() {
+		return
+	}
+	b.trackMemberChange(ctx, botInstance, msg.Chat, 0, 1)
+	if leftMember.IsBot {
+		return
+	}
+
+	b.recordMemberEvent(ctx, msg, leftMember, false)
+}
+
+// recordMemberEvent 记录成员入群/退群事件，失败仅记录日志
+func (b *Bot) recordMemberEvent(ctx context.Context, msg *botModels.Message, member *botModels.User, joined bool) {
+	if b.memberEvents == nil || member == nil {
+		return
+	}
+
+	info := &service.TelegramUserInfo{
+		TelegramID: member.ID,
+		Username:   member.Username,
+		FirstName:  member.FirstName,
+		LastName:   member.LastName,
+	}
+	at := time.Unix(int64(msg.Date), 0)
+
+	var err error
+	if joined {
+		err = b.memberEvents.RecordJoin(ctx, msg.Chat.ID, info, at)
+	} else {
+		err = b.memberEvents.RecordLeave(ctx, msg.Chat.ID, info, at)
+	}
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to record member event: chat_id=%d user_id=%d joined=%v err=%v",
+			msg.Chat.ID, member.ID, joined, err)
+	}
 }
 
 // handleRecallCallback 处理转发撤回回调
@@ -1228,7 +1565,7 @@ func (b *Bot) handleRecallCallback(ctx context.Context, botInstance *bot.Bot, up
 	// 获取 forwardService（类型断言为具体类型以访问 Handler 方法）
 	forwardSvc, ok := b.forwardService.(*forward.Service)
 	if !ok {
-		logger.L().Error("Failed to cast forwardService to *forward.Service")
+		logger.Ctx(ctx).Error("Failed to cast forwardService to *forward.Service")
 		return
 	}
 
@@ -1261,7 +1598,7 @@ func (b *Bot) registerUserFromTelegram(ctx context.Context, tgUser *botModels.Us
 	}
 
 	if err := b.userService.RegisterOrUpdateUser(ctx, userInfo); err != nil {
-		logger.L().Warnf("Failed to auto register user %d: %v", tgUser.ID, err)
+		logger.Ctx(ctx).Warnf("Failed to auto register user %d: %v", tgUser.ID, err)
 	}
 }
 
@@ -1291,34 +1628,53 @@ func (b *Bot) handleAccountingInput(ctx context.Context, botInstance *bot.Bot, u
 	}
 
 	// 检查用户权限（仅管理员）
-	isAdmin, err := b.userService.CheckAdminPermission(ctx, userID)
+	isAdmin, err := b.userService.CheckAdminPermission(ctx, userID, chatID)
 	if err != nil || !isAdmin {
 		return false
 	}
 
 	// 尝试添加记账记录
-	if err := b.accountingService.AddRecord(ctx, chatID, userID, text); err != nil {
+	result, err := b.accountingService.AddRecord(ctx, chatID, userID, text)
+	if err != nil {
 		// 如果是格式错误，返回 false（让后续 handler 处理）
 		if strings.Contains(err.Error(), "输入格式错误") {
 			return false
 		}
 		// 其他错误，显示错误消息
-		b.sendErrorMessage(ctx, chatID, err.Error())
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
 		return true
 	}
 
-	// 添加成功，自动查询并显示最新账单
-	report, err := b.accountingService.QueryRecords(ctx, chatID)
+	// 添加成功，自动查询并显示最新账单（带账本标识时显示该账本）
+	var report string
+	if book := result.Record.Book; book != "" {
+		report, err = b.accountingService.QueryBookRecords(ctx, chatID, book)
+	} else {
+		report, err = b.accountingService.QueryRecords(ctx, chatID)
+	}
 	if err != nil {
 		b.sendErrorMessage(ctx, chatID, "记录成功，但查询账单失败")
 		return true
 	}
 
+	if warning := formatAccountingAnomalyWarning(result); warning != "" {
+		report = warning + "\n\n" + report
+	}
 	b.sendMessage(ctx, chatID, report)
 	return true
 }
 
-// handleQueryAccounting 处理"查询记账"命令
+// formatAccountingAnomalyWarning 金额异常软提醒，未命中时返回空字符串
+func formatAccountingAnomalyWarning(result *service.AccountingAddResult) string {
+	if result == nil || !result.Anomalous || result.Record == nil {
+		return ""
+	}
+	return fmt.Sprintf("⚠️ <b>金额异常，请核对</b>：本笔 %s 超过近期均值 %s 的 %d 倍（已记录，如有误请发送「删除记账记录」删除）",
+		formatRecordAmount(result.Record.Amount, result.Record.Currency),
+		strconv.FormatFloat(models.RoundAccountingAmount(result.Average), 'f', -1, 64), result.Ratio)
+}
+
+// handleQueryAccounting 处理"查询记账"命令，「查询记账 #公账」只查询指定账本
 func (b *Bot) handleQueryAccounting(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.Message == nil {
 		return
@@ -1327,6 +1683,12 @@ func (b *Bot) handleQueryAccounting(ctx context.Context, botInstance *bot.Bot, u
 	chatID := update.Message.Chat.ID
 	chat := update.Message.Chat
 
+	book, filtered, err := parseAccountingBookArg(update.Message.Text, accountingQueryCommand)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
+		return
+	}
+
 	// 获取或创建群组记录
 	chatInfo := &service.TelegramChatInfo{
 		ChatID:   chat.ID,
@@ -1346,9 +1708,14 @@ func (b *Bot) handleQueryAccounting(ctx context.Context, botInstance *bot.Bot, u
 	}
 
 	// 查询账单
-	report, err := b.accountingService.QueryRecords(ctx, chatID)
+	var report string
+	if filtered {
+		report, err = b.accountingService.QueryBookRecords(ctx, chatID, book)
+	} else {
+		report, err = b.accountingService.QueryRecords(ctx, chatID)
+	}
 	if err != nil {
-		b.sendErrorMessage(ctx, chatID, err.Error())
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
 		return
 	}
 
@@ -1385,7 +1752,7 @@ func (b *Bot) handleDeleteAccounting(ctx context.Context, botInstance *bot.Bot,
 	// 获取最近2天的记录
 	records, err := b.accountingService.GetRecentRecordsForDeletion(ctx, chatID)
 	if err != nil {
-		b.sendErrorMessage(ctx, chatID, err.Error())
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
 		return
 	}
 
@@ -1401,6 +1768,9 @@ func (b *Bot) handleDeleteAccounting(ctx context.Context, botInstance *bot.Bot,
 		dateStr := record.RecordedAt.Format("01-02 15:04")
 		amountStr := formatRecordAmount(record.Amount, record.Currency)
 		buttonText := fmt.Sprintf("%s | %s", dateStr, amountStr)
+		if record.Book != "" {
+			buttonText += " | " + record.Book
+		}
 
 		keyboard = append(keyboard, []botModels.InlineKeyboardButton{
 			{
@@ -1418,9 +1788,12 @@ func (b *Bot) handleDeleteAccounting(ctx context.Context, botInstance *bot.Bot,
 		},
 	}
 
-	if _, err := botInstance.SendMessage(ctx, params); err != nil {
-		logger.L().Errorf("Failed to send delete menu: %v", err)
+	sent, err := botInstance.SendMessage(ctx, params)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to send delete menu: %v", err)
+		return
 	}
+	b.recordSentMessage(sent)
 }
 
 // formatRecordAmount 格式化记录金额（用于删除界面）
@@ -1432,6 +1805,7 @@ func formatRecordAmount(amount float64, currency string) string {
 		currencySymbol = "Y"
 	}
 
+	amount = models.RoundAccountingAmount(amount)
 	if amount == float64(int64(amount)) {
 		// 整数
 		if amount >= 0 {
@@ -1471,7 +1845,7 @@ func (b *Bot) handleAccountingDeleteCallback(ctx context.Context, botInstance *b
 			Text:            "删除失败",
 			ShowAlert:       true,
 		}); err != nil {
-			logger.L().Errorf("Failed to answer callback query: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 		}
 		return
 	}
@@ -1481,7 +1855,7 @@ func (b *Bot) handleAccountingDeleteCallback(ctx context.Context, botInstance *b
 		CallbackQueryID: query.ID,
 		Text:            "删除成功",
 	}); err != nil {
-		logger.L().Errorf("Failed to answer callback query: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 	}
 
 	// 删除成功，自动发送最新账单
@@ -1494,7 +1868,7 @@ func (b *Bot) handleAccountingDeleteCallback(ctx context.Context, botInstance *b
 	b.sendMessage(ctx, chatID, report)
 }
 
-// handleClearAccounting 处理"清零记账"命令
+// handleClearAccounting 处理"清零记账"命令，「清零记账 #公账」只清空指定账本
 func (b *Bot) handleClearAccounting(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
 	if update.Message == nil {
 		return
@@ -1503,6 +1877,12 @@ func (b *Bot) handleClearAccounting(ctx context.Context, botInstance *bot.Bot, u
 	chatID := update.Message.Chat.ID
 	chat := update.Message.Chat
 
+	book, filtered, err := parseAccountingBookArg(update.Message.Text, accountingClearCommand)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
+		return
+	}
+
 	// 获取或创建群组记录
 	chatInfo := &service.TelegramChatInfo{
 		ChatID:   chat.ID,
@@ -1521,10 +1901,20 @@ func (b *Bot) handleClearAccounting(ctx context.Context, botInstance *bot.Bot, u
 		return
 	}
 
+	if filtered {
+		count, err := b.accountingService.ClearBookRecords(ctx, chatID, book)
+		if err != nil {
+			b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
+			return
+		}
+		b.sendSuccessMessage(ctx, chatID, fmt.Sprintf("已清空「%s」%d 条记账记录", safeHTML(models.AccountingBookLabel(book)), count))
+		return
+	}
+
 	// 清空所有记录
 	count, err := b.accountingService.ClearAllRecords(ctx, chatID)
 	if err != nil {
-		b.sendErrorMessage(ctx, chatID, err.Error())
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()))
 		return
 	}
 
@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"go_bot/internal/telegram/models"
+)
+
+const (
+	accountingQueryCommand = "查询记账"
+	accountingClearCommand = "清零记账"
+)
+
+// parseAccountingBookArg 解析「查询记账 #公账」「清零记账 #公账」中的账本参数，
+// 未带参数时 filtered 为 false（汇总/清空全部账本），「#默认账本」对应空账本名
+func parseAccountingBookArg(text, command string) (book string, filtered bool, err error) {
+	arg := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), command))
+	if arg == "" {
+		return "", false, nil
+	}
+	if !strings.HasPrefix(arg, "#") || len(strings.Fields(arg)) != 1 {
+		return "", false, fmt.Errorf("用法：%s 或 %s #账本名", command, command)
+	}
+	book, err = models.NormalizeAccountingBook(arg)
+	if err != nil {
+		return "", false, err
+	}
+	return book, true, nil
+}
@@ -0,0 +1,26 @@
+package telegram
+
+import "testing"
+
+func TestParseAccountingBookArg(t *testing.T) {
+	cases := []struct {
+		text     string
+		book     string
+		filtered bool
+	}{
+		{"查询记账", "", false},
+		{"查询记账 #公账", "公账", true},
+		{"查询记账  #默认账本 ", "", true},
+	}
+	for _, tc := range cases {
+		book, filtered, err := parseAccountingBookArg(tc.text, accountingQueryCommand)
+		if err != nil || book != tc.book || filtered != tc.filtered {
+			t.Fatalf("parseAccountingBookArg(%q) = %q, %t, %v; want %q, %t", tc.text, book, filtered, err, tc.book, tc.filtered)
+		}
+	}
+	for _, text := range []string{"查询记账 公账", "查询记账 #公账 #私账", "查询记账明细", "查询记账 #"} {
+		if _, _, err := parseAccountingBookArg(text, accountingQueryCommand); err == nil {
+			t.Fatalf("expected %q to be rejected", text)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	accountingDateDeleteCommand        = "删除记账"
+	accountingDateDeleteCallbackPrefix = "accdeldate:"
+	accountingDateLayout               = "2006-01-02"
+	// accountingDateDeletePendingTTL 按日期删除确认的有效期
+	accountingDateDeletePendingTTL  = 2 * time.Minute
+	accountingDateDeleteExpiredText = "⌛ 删除确认已超时，未删除任何记录"
+)
+
+var accountingDateDeleteUsage = fmt.Sprintf("用法：%s 2024-01-05，删除该日（按群组时区）的全部记账记录", accountingDateDeleteCommand)
+
+// pendingAccountingDateDelete 等待管理员确认的按日期删除记账请求
+type pendingAccountingDateDelete struct {
+	chatID      int64
+	initiatorID int64
+	date        time.Time
+}
+
+// isAccountingDateDeleteCommand 判断是否为「删除记账 <日期>」命令，「删除记账记录」由精确匹配的 handler 处理
+func isAccountingDateDeleteCommand(text string) bool {
+	fields := strings.Fields(text)
+	return len(fields) > 0 && fields[0] == accountingDateDeleteCommand
+}
+
+// parseAccountingDeleteDate 解析「删除记账 2024-01-05」中的日期
+func parseAccountingDeleteDate(text string) (time.Time, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return time.Time{}, fmt.Errorf("%s", accountingDateDeleteUsage)
+	}
+	date, err := time.Parse(accountingDateLayout, fields[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误，%s", accountingDateDeleteUsage)
+	}
+	return date, nil
+}
+
+// handleDeleteAccountingByDate 处理「删除记账 2024-01-05」命令（Admin+），列出当日记录并在确认后删除
+func (b *Bot) handleDeleteAccountingByDate(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	date, err := parseAccountingDeleteDate(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+
+	chatInfo := &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	}
+	group, err := b.groupService.GetOrCreateGroup(ctx, chatInfo)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "查询失败", msg.ID)
+		return
+	}
+	if !group.Settings.AccountingEnabled {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "收支记账功能未启用", msg.ID)
+		return
+	}
+
+	records, err := b.accountingService.GetRecordsByDate(ctx, msg.Chat.ID, date)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+	if len(records) == 0 {
+		b.sendMessage(ctx, msg.Chat.ID, fmt.Sprintf("%s 没有记账记录", date.Format(accountingDateLayout)), msg.ID)
+		return
+	}
+
+	pending := &pendingAccountingDateDelete{
+		chatID:      msg.Chat.ID,
+		initiatorID: msg.From.ID,
+		date:        date,
+	}
+	token := b.pendingAccountingDateDeletes.put(pending, accountingDateDeletePendingTTL)
+
+	prompt := formatAccountingDateDeletePrompt(date, records, models.GroupLocation(group.Settings))
+	sent, err := b.sendMessageWithMarkupAndMessage(ctx, msg.Chat.ID, prompt, buildAccountingDateDeleteKeyboard(token), msg.ID)
+	if err != nil || sent == nil {
+		b.pendingAccountingDateDeletes.take(token)
+		return
+	}
+
+	time.AfterFunc(accountingDateDeletePendingTTL, func() {
+		if b.pendingAccountingDateDeletes.take(token) {
+			b.editMessage(context.Background(), pending.chatID, sent.ID, accountingDateDeleteExpiredText, nil)
+		}
+	})
+}
+
+// handleDeleteAccountingByDateCallback 处理按日期删除的确认/取消按钮，仅发起命令的管理员可操作
+func (b *Bot) handleDeleteAccountingByDateCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	action, token, ok := parseAccountingDateDeleteCallback(query.Data)
+	if !ok || query.Message.Message == nil {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的请求", true)
+		return
+	}
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, exists := b.pendingAccountingDateDeletes.get(token)
+	if !exists || pending.chatID != chatID {
+		b.answerCallback(ctx, botInstance, query.ID, "确认已失效，请重新发送删除命令", true)
+		b.editMessage(ctx, chatID, messageID, accountingDateDeleteExpiredText, nil)
+		return
+	}
+	if pending.initiatorID != query.From.ID {
+		b.answerCallback(ctx, botInstance, query.ID, "只有发起删除的管理员可以操作", true)
+		return
+	}
+	if !b.pendingAccountingDateDeletes.take(token) {
+		b.answerCallback(ctx, botInstance, query.ID, "请求已处理", true)
+		return
+	}
+
+	dateText := pending.date.Format(accountingDateLayout)
+	if action == "cancel" {
+		b.answerCallback(ctx, botInstance, query.ID, "已取消", false)
+		b.editMessage(ctx, chatID, messageID, fmt.Sprintf("🚫 已取消，%s 的记录未删除", dateText), nil)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "正在删除", false)
+	count, err := b.accountingService.DeleteByDate(ctx, chatID, pending.date)
+	if err != nil {
+		b.editMessage(ctx, chatID, messageID, "❌ "+safeHTML(err.Error()), nil)
+		return
+	}
+	logger.Ctx(ctx).Infof("Accounting records deleted by date: chat_id=%d date=%s count=%d admin=%d", chatID, dateText, count, query.From.ID)
+	b.editMessage(ctx, chatID, messageID, fmt.Sprintf("🗑️ 已删除 %s 的 %d 条记账记录", dateText, count), nil)
+
+	// 删除后回显剩余账单
+	report, err := b.accountingService.QueryRecords(ctx, chatID)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, "删除成功，但查询账单失败")
+		return
+	}
+	b.sendMessage(ctx, chatID, report)
+}
+
+// formatAccountingDateDeletePrompt 删除确认提示：当日记录条数与明细
+func formatAccountingDateDeletePrompt(date time.Time, records []*models.AccountingRecord, loc *time.Location) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("⚠️ <b>确认删除 %s 的全部记账记录？</b>\n\n共 <b>%d</b> 条：\n", date.Format(accountingDateLayout), len(records)))
+	for _, record := range records {
+		text.WriteString(fmt.Sprintf("• %s | %s\n", record.RecordedAt.In(loc).Format("15:04"), formatRecordAmount(record.Amount, record.Currency)))
+	}
+	text.WriteString(fmt.Sprintf("\n删除后无法恢复。%s 内未确认将自动取消。", formatDuration(accountingDateDeletePendingTTL)))
+	return text.String()
+}
+
+func buildAccountingDateDeleteKeyboard(token string) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{
+				{Text: "🗑️ 确认删除", CallbackData: accountingDateDeleteCallbackPrefix + "confirm:" + token},
+				{Text: "❌ 取消", CallbackData: accountingDateDeleteCallbackPrefix + "cancel:" + token},
+			},
+		},
+	}
+}
+
+// parseAccountingDateDeleteCallback 解析回调数据：accdeldate:<confirm|cancel>:<token>
+func parseAccountingDateDeleteCallback(data string) (string, string, bool) {
+	payload := strings.TrimPrefix(data, accountingDateDeleteCallbackPrefix)
+	action, token, found := strings.Cut(payload, ":")
+	if !found || token == "" {
+		return "", "", false
+	}
+	if action != "confirm" && action != "cancel" {
+		return "", "", false
+	}
+	return action, token, true
+}
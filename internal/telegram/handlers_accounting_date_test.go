@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestParseAccountingDeleteDate(t *testing.T) {
+	date, err := parseAccountingDeleteDate("删除记账  2024-01-05")
+	if err != nil || date.Format(accountingDateLayout) != "2024-01-05" {
+		t.Fatalf("expected 2024-01-05, got %v err=%v", date, err)
+	}
+	for _, text := range []string{"删除记账", "删除记账 2024-1-5", "删除记账 2024-02-30", "删除记账 2024-01-05 1"} {
+		if _, err := parseAccountingDeleteDate(text); err == nil {
+			t.Fatalf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestIsAccountingDateDeleteCommand(t *testing.T) {
+	cases := map[string]bool{
+		"删除记账 2024-01-05": true,
+		"删除记账":            true,
+		"删除记账记录":          false,
+		"查询记账":            false,
+	}
+	for text, want := range cases {
+		if got := isAccountingDateDeleteCommand(text); got != want {
+			t.Fatalf("isAccountingDateDeleteCommand(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestFormatAccountingDateDeletePrompt(t *testing.T) {
+	loc := time.UTC
+	records := []*models.AccountingRecord{
+		{Amount: 100, Currency: models.CurrencyUSD, RecordedAt: time.Date(2024, 1, 5, 9, 30, 0, 0, loc)},
+		{Amount: -50.5, Currency: models.CurrencyCNY, RecordedAt: time.Date(2024, 1, 5, 18, 0, 0, 0, loc)},
+	}
+
+	text := formatAccountingDateDeletePrompt(time.Date(2024, 1, 5, 0, 0, 0, 0, loc), records, loc)
+	for _, want := range []string{"确认删除 2024-01-05 的全部记账记录", "共 <b>2</b> 条", "09:30 | +100U", "18:00 | -50.50Y"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected prompt to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestParseAccountingDateDeleteCallback(t *testing.T) {
+	action, token, ok := parseAccountingDateDeleteCallback(accountingDateDeleteCallbackPrefix + "confirm:abc")
+	if !ok || action != "confirm" || token != "abc" {
+		t.Fatalf("unexpected parse result: %q %q %v", action, token, ok)
+	}
+	for _, data := range []string{accountingDateDeleteCallbackPrefix + "confirm:", accountingDateDeleteCallbackPrefix + "drop:abc"} {
+		if _, _, ok := parseAccountingDateDeleteCallback(data); ok {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
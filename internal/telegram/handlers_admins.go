@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	adminListCallbackPrefix = "admins:"
+	adminListPageSize       = 10
+	adminListAllRoles       = "all" // 回调数据中表示不按角色筛选
+)
+
+const adminListUsage = "用法：/admins [owner|super|admin]，不带参数列出全部管理员"
+
+// handleListAdmins 处理 /admins [角色] 命令（列出管理员，支持按角色筛选与翻页）
+func (b *Bot) handleListAdmins(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	_, arg, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	role, err := parseAdminRoleFilter(arg)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	text, markup, err := b.buildAdminListPage(ctx, role, 1)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "查询失败", msg.ID)
+		return
+	}
+	if _, err := b.sendMessageWithMarkupAndMessage(ctx, msg.Chat.ID, text, markup, msg.ID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to send admin list: chat_id=%d err=%v", msg.Chat.ID, err)
+	}
+}
+
+// handleListAdminsCallback 处理管理员列表翻页
+func (b *Bot) handleListAdminsCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	role, page, ok := parseAdminListCallback(query.Data)
+	if !ok {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的翻页请求", true)
+		return
+	}
+
+	msg := query.Message.Message
+	text, markup, err := b.buildAdminListPage(ctx, role, page)
+	if err != nil {
+		b.answerCallback(ctx, botInstance, query.ID, "查询失败", true)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "", false)
+	b.editMessage(ctx, msg.Chat.ID, msg.ID, text, markup)
+}
+
+// buildAdminListPage 查询并渲染管理员列表的指定页
+func (b *Bot) buildAdminListPage(ctx context.Context, role string, page int) (string, botModels.ReplyMarkup, error) {
+	result, err := b.userService.ListAdminsPage(ctx, role, page, adminListPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatAdminListPage(result, role), buildAdminListKeyboard(role, result.Page, result.TotalPages), nil
+}
+
+// formatAdminListPage 渲染管理员列表，底部显示总数与页码
+func formatAdminListPage(result *service.AdminPage, role string) string {
+	title := "👥 管理员列表"
+	if role != "" {
+		title = fmt.Sprintf("👥 管理员列表（%s）", models.RoleLabel(role))
+	}
+	if result.Total == 0 {
+		return title + "\n\n📝 暂无管理员"
+	}
+
+	var text strings.Builder
+	text.WriteString(title + "\n\n")
+	start := (result.Page - 1) * adminListPageSize
+	for i, admin := range result.Admins {
+		text.WriteString(safeHTMLf("%d. %s %s - ID: %d - %s\n",
+			start+i+1,
+			adminRoleEmoji(admin.Role),
+			adminDisplayName(admin),
+			admin.TelegramID,
+			models.RoleLabel(admin.Role),
+		))
+	}
+	text.WriteString(fmt.Sprintf("\n共 %d 位 · 第 %d/%d 页", result.Total, result.Page, result.TotalPages))
+	return text.String()
+}
+
+// adminDisplayName 展示名：名字与 @username，均缺失时展示 ID
+func adminDisplayName(user *models.User) string {
+	name := strings.TrimSpace(user.FirstName)
+	switch {
+	case name != "" && user.Username != "":
+		return fmt.Sprintf("%s (@%s)", name, user.Username)
+	case user.Username != "":
+		return "@" + user.Username
+	case name != "":
+		return name
+	default:
+		return strconv.FormatInt(user.TelegramID, 10)
+	}
+}
+
+func adminRoleEmoji(role string) string {
+	switch role {
+	case models.RoleOwner:
+		return "👑"
+	case models.RoleSuperAdmin:
+		return "🌟"
+	default:
+		return "👤"
+	}
+}
+
+// parseAdminRoleFilter 解析 /admins 的角色参数，空表示全部管理角色
+func parseAdminRoleFilter(arg string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "", adminListAllRoles, "全部":
+		return "", nil
+	case "owner":
+		return models.RoleOwner, nil
+	case "super", "super_admin", "超级管理员":
+		return models.RoleSuperAdmin, nil
+	case "admin", "管理员":
+		return models.RoleAdmin, nil
+	default:
+		return "", fmt.Errorf("未知角色：%s\n%s", strings.TrimSpace(arg), adminListUsage)
+	}
+}
+
+// buildAdminListKeyboard 构建翻页按钮，只有一页时不显示
+func buildAdminListKeyboard(role string, page, totalPages int) botModels.ReplyMarkup {
+	if totalPages <= 1 {
+		return nil
+	}
+	if role == "" {
+		role = adminListAllRoles
+	}
+
+	var row []botModels.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, botModels.InlineKeyboardButton{
+			Text:         "⬅️ 上一页",
+			CallbackData: fmt.Sprintf("%s%s:%d", adminListCallbackPrefix, role, page-1),
+		})
+	}
+	if page < totalPages {
+		row = append(row, botModels.InlineKeyboardButton{
+			Text:         "下一页 ➡️",
+			CallbackData: fmt.Sprintf("%s%s:%d", adminListCallbackPrefix, role, page+1),
+		})
+	}
+	return &botModels.InlineKeyboardMarkup{InlineKeyboard: [][]botModels.InlineKeyboardButton{row}}
+}
+
+// parseAdminListCallback 解析翻页回调：admins:<role|all>:<page>
+func parseAdminListCallback(data string) (string, int, bool) {
+	payload := strings.TrimPrefix(data, adminListCallbackPrefix)
+	roleText, pageText, found := strings.Cut(payload, ":")
+	if !found {
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(pageText)
+	if err != nil || page < 1 {
+		return "", 0, false
+	}
+	role, err := parseAdminRoleFilter(roleText)
+	if err != nil {
+		return "", 0, false
+	}
+	return role, page, true
+}
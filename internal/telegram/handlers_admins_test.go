@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseAdminRoleFilter(t *testing.T) {
+	cases := map[string]string{
+		"":            "",
+		"all":         "",
+		"Owner":       models.RoleOwner,
+		"super":       models.RoleSuperAdmin,
+		"super_admin": models.RoleSuperAdmin,
+		"管理员":         models.RoleAdmin,
+	}
+	for arg, want := range cases {
+		if got, err := parseAdminRoleFilter(arg); err != nil || got != want {
+			t.Errorf("parseAdminRoleFilter(%q) = %q, %v; want %q", arg, got, err, want)
+		}
+	}
+	if _, err := parseAdminRoleFilter("user"); err == nil {
+		t.Fatal("expected error for non-admin role")
+	}
+}
+
+func TestAdminListCallbackRoundTrip(t *testing.T) {
+	markup, ok := buildAdminListKeyboard(models.RoleSuperAdmin, 2, 3).(*botModels.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard[0]) != 2 {
+		t.Fatalf("expected prev and next buttons, got %#v", markup)
+	}
+	role, page, ok := parseAdminListCallback(markup.InlineKeyboard[0][1].CallbackData)
+	if !ok || role != models.RoleSuperAdmin || page != 3 {
+		t.Fatalf("unexpected callback parse: role=%q page=%d ok=%v", role, page, ok)
+	}
+
+	markup, _ = buildAdminListKeyboard("", 1, 2).(*botModels.InlineKeyboardMarkup)
+	if role, page, ok := parseAdminListCallback(markup.InlineKeyboard[0][0].CallbackData); !ok || role != "" || page != 2 {
+		t.Fatalf("unexpected callback parse for all roles: role=%q page=%d ok=%v", role, page, ok)
+	}
+
+	if buildAdminListKeyboard("", 1, 1) != nil {
+		t.Fatal("single page should not have keyboard")
+	}
+	if _, _, ok := parseAdminListCallback("admins:all:0"); ok {
+		t.Fatal("page 0 should be rejected")
+	}
+}
+
+func TestFormatAdminListPage(t *testing.T) {
+	text := formatAdminListPage(&service.AdminPage{
+		Admins: []*models.User{
+			{TelegramID: 1, Role: models.RoleAdmin, FirstName: "Alice", Username: "alice"},
+			{TelegramID: 42, Role: models.RoleAdmin},
+		},
+		Total:      12,
+		Page:       2,
+		TotalPages: 2,
+	}, models.RoleAdmin)
+
+	for _, want := range []string{"管理员列表（管理员）", "11. 👤 Alice (@alice) - ID: 1", "12. 👤 42 - ID: 42", "共 12 位 · 第 2/2 页"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+}
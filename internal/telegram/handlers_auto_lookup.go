@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// sifangAutoLookupEnableCommand 群内开启四方自动查单的快捷命令
+	sifangAutoLookupEnableCommand = "开启自动查单"
+	// sifangAutoLookupDisableCommand 群内关闭四方自动查单的快捷命令
+	sifangAutoLookupDisableCommand = "关闭自动查单"
+)
+
+// handleSifangAutoLookupToggle 处理「开启自动查单」「关闭自动查单」命令（Admin+），
+// 与 /configs 菜单的「🔍 四方自动查单」共用 settings.sifang_auto_lookup_enabled
+func (b *Bot) handleSifangAutoLookupToggle(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+	enable := strings.TrimSpace(msg.Text) == sifangAutoLookupEnableCommand
+
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	})
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return
+	}
+
+	if models.NormalizeGroupTier(group.Tier) != models.GroupTierMerchant {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "四方自动查单仅在商户群可用", msg.ID)
+		return
+	}
+
+	settings := group.Settings
+	reply, changed := applySifangAutoLookupToggle(&settings, enable)
+	if changed {
+		if err := b.groupService.UpdateGroupSettings(ctx, group.TelegramID, settings); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to toggle sifang auto lookup: chat_id=%d enable=%t err=%v", group.TelegramID, enable, err)
+			b.sendErrorMessage(ctx, msg.Chat.ID, "更新配置失败", msg.ID)
+			return
+		}
+		logger.Ctx(ctx).Infof("Sifang auto lookup toggled by command: chat_id=%d user_id=%d enabled=%t", group.TelegramID, msg.From.ID, enable)
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, reply, msg.ID)
+}
+
+// applySifangAutoLookupToggle 按命令修改自动查单开关，返回回显文本与是否需要写库
+// 开启需先开启四方支付（与 /configs 菜单的限制一致）；已是目标状态时只回显不写库
+func applySifangAutoLookupToggle(settings *models.GroupSettings, enable bool) (string, bool) {
+	if enable && !settings.SifangEnabled {
+		return "⚠️ 需先在 /configs 中开启四方支付，才能开启自动查单", false
+	}
+
+	if settings.SifangAutoLookupEnabled == enable {
+		if enable {
+			return "ℹ️ 四方自动查单已是开启状态" + sifangAutoLookupMerchantHint(settings), false
+		}
+		return "ℹ️ 四方自动查单已是关闭状态", false
+	}
+
+	settings.SifangAutoLookupEnabled = enable
+	if enable {
+		return "✅ 四方自动查单已开启" + sifangAutoLookupMerchantHint(settings), true
+	}
+	return "✅ 四方自动查单已关闭", true
+}
+
+// sifangAutoLookupMerchantHint 未绑定商户号时提示自动查单不会触发
+func sifangAutoLookupMerchantHint(settings *models.GroupSettings) string {
+	if settings.MerchantID > 0 {
+		return ""
+	}
+	return "\n⚠️ 本群尚未绑定商户号，绑定后才会自动查单"
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestApplySifangAutoLookupToggle(t *testing.T) {
+	cases := []struct {
+		name        string
+		settings    models.GroupSettings
+		enable      bool
+		wantEnabled bool
+		wantChanged bool
+		wantReply   string
+	}{
+		{"开启需先开启四方支付", models.GroupSettings{}, true, false, false, "需先在 /configs 中开启四方支付"},
+		{"开启", models.GroupSettings{SifangEnabled: true, MerchantID: 1001}, true, true, true, "✅ 四方自动查单已开启"},
+		{"开启但未绑定商户号", models.GroupSettings{SifangEnabled: true}, true, true, true, "尚未绑定商户号"},
+		{"重复开启不写库", models.GroupSettings{SifangEnabled: true, SifangAutoLookupEnabled: true, MerchantID: 1001}, true, true, false, "已是开启状态"},
+		{"关闭", models.GroupSettings{SifangEnabled: true, SifangAutoLookupEnabled: true}, false, false, true, "✅ 四方自动查单已关闭"},
+		{"四方支付关闭时仍可关闭冲突开关", models.GroupSettings{SifangAutoLookupEnabled: true}, false, false, true, "已关闭"},
+		{"重复关闭不写库", models.GroupSettings{SifangEnabled: true}, false, false, false, "已是关闭状态"},
+	}
+	for _, tc := range cases {
+		settings := tc.settings
+		reply, changed := applySifangAutoLookupToggle(&settings, tc.enable)
+		if changed != tc.wantChanged || settings.SifangAutoLookupEnabled != tc.wantEnabled {
+			t.Fatalf("%s: expected changed=%t enabled=%t, got changed=%t enabled=%t", tc.name, tc.wantChanged, tc.wantEnabled, changed, settings.SifangAutoLookupEnabled)
+		}
+		if !strings.Contains(reply, tc.wantReply) {
+			t.Fatalf("%s: expected reply containing %q, got %q", tc.name, tc.wantReply, reply)
+		}
+	}
+}
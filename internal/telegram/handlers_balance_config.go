@@ -0,0 +1,313 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	balanceConfigCommand        = "/balance_config"
+	balanceConfigCallbackPrefix = "balcfg:"
+	// balanceConfigStatePrefix 余额配置输入态的 Action 前缀，与 /configs 的 "input:" 区分
+	balanceConfigStatePrefix = "balance:"
+
+	balanceConfigMinBalance = "min"
+	balanceConfigAlertLimit = "limit"
+	balanceConfigTarget     = "target"
+	balanceConfigRefresh    = "refresh"
+	balanceConfigClose      = "close"
+	balanceConfigCancel     = "cancel"
+)
+
+// parseMinBalanceInput 解析最低余额（CNY，>=0）
+func parseMinBalanceInput(text string) (float64, error) {
+	threshold, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("请输入合法的金额（>=0）")
+	}
+	return threshold, nil
+}
+
+// parseAlertLimitInput 解析每小时告警次数（>0）
+func parseAlertLimitInput(text string) (int, error) {
+	limit, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("请输入大于 0 的整数")
+	}
+	return limit, nil
+}
+
+// handleBalanceConfig 处理 /balance_config 命令（上游群 + Admin+），打开余额告警配置菜单
+func (b *Bot) handleBalanceConfig(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	group, result, err := b.loadBalanceConfig(ctx, msg.Chat)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, html.EscapeString(err.Error()), msg.ID)
+		return
+	}
+
+	if _, err := b.sendMessageWithMarkupAndMessage(ctx, msg.Chat.ID, b.formatBalanceConfigText(group, result), b.buildBalanceConfigKeyboard(group), msg.ID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to send balance config menu: chat_id=%d err=%v", msg.Chat.ID, err)
+		return
+	}
+	logger.Ctx(ctx).Infof("Balance config menu sent: chat_id=%d user_id=%d", msg.Chat.ID, msg.From.ID)
+}
+
+// handleBalanceConfigCallback 处理余额配置菜单按钮：输入项进入 configMenuService 的输入态，告警目标循环切换
+func (b *Bot) handleBalanceConfigCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+	chat := query.Message.Message.Chat
+	messageID := query.Message.Message.ID
+	userID := query.From.ID
+	action := strings.TrimPrefix(query.Data, balanceConfigCallbackPrefix)
+
+	isAdmin, err := b.userService.CheckAdminPermission(ctx, userID, chat.ID)
+	if err != nil || !isAdmin {
+		b.answerCallback(ctx, botInstance, query.ID, "⚠️ 只有管理员可以操作配置", false)
+		return
+	}
+
+	switch {
+	case action == balanceConfigClose:
+		b.answerCallback(ctx, botInstance, query.ID, "✅ 配置菜单已关闭", false)
+		if _, err := botInstance.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: chat.ID, MessageID: messageID}); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to delete balance config menu: %v", err)
+		}
+		return
+
+	case strings.HasPrefix(action, balanceConfigCancel+":"):
+		ownerID, _ := strconv.ParseInt(strings.TrimPrefix(action, balanceConfigCancel+":"), 10, 64)
+		if ownerID != userID {
+			b.answerCallback(ctx, botInstance, query.ID, "⚠️ 只有发起输入的管理员可以取消", false)
+			return
+		}
+		b.configMenuService.ClearUserState(chat.ID, userID)
+		b.answerCallback(ctx, botInstance, query.ID, "🚫 已取消输入", false)
+
+	case action == balanceConfigMinBalance || action == balanceConfigAlertLimit:
+		now := time.Now()
+		b.configMenuService.SetUserState(chat.ID, userID, &models.UserState{
+			UserID:    userID,
+			ChatID:    chat.ID,
+			Action:    balanceConfigStatePrefix + action,
+			MessageID: messageID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(service.UserStateTTL).Unix(),
+		})
+		prompt := "💰 请输入最低余额（CNY）\n\n余额低于该值时触发告警，输入 0 表示不告警"
+		if action == balanceConfigAlertLimit {
+			prompt = "🔔 请输入每小时最多告警次数（大于 0 的整数）"
+		}
+		b.answerCallback(ctx, botInstance, query.ID, "📝 请发送新的配置值", false)
+		b.editMessage(ctx, chat.ID, messageID, fmt.Sprintf("📝 %s\n\n请在 %d 分钟内发送文本消息：", prompt, int(service.UserStateTTL.Minutes())),
+			&botModels.InlineKeyboardMarkup{InlineKeyboard: [][]botModels.InlineKeyboardButton{
+				{{Text: "❌ 取消输入", CallbackData: fmt.Sprintf("%s%s:%d", balanceConfigCallbackPrefix, balanceConfigCancel, userID)}},
+			}})
+		return
+
+	case action == balanceConfigTarget:
+		group, err := b.groupService.GetGroupInfo(ctx, chat.ID)
+		if err != nil || group == nil {
+			b.answerCallback(ctx, botInstance, query.ID, "❌ 获取群组信息失败", false)
+			return
+		}
+		before := group.Settings.BalanceAlertTarget
+		next := b.nextBalanceAlertTarget(before)
+		group.Settings.BalanceAlertTarget = next
+		if err := b.groupService.UpdateGroupSettings(ctx, chat.ID, group.Settings); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to update balance alert target: chat_id=%d err=%v", chat.ID, err)
+			b.answerCallback(ctx, botInstance, query.ID, "❌ 更新配置失败", false)
+			return
+		}
+		logger.Ctx(ctx).Infof("Config audit: chat_id=%d operator=%d config=balance_alert_target before=%q after=%q", chat.ID, userID, before, next)
+		b.answerCallback(ctx, botInstance, query.ID, "✅ 告警目标已设置为："+models.BalanceAlertTargetLabel(next), false)
+
+	case action == balanceConfigRefresh:
+		b.answerCallback(ctx, botInstance, query.ID, "🔄 菜单已刷新", false)
+
+	default:
+		b.answerCallback(ctx, botInstance, query.ID, "❌ 未知的操作", false)
+		return
+	}
+
+	b.refreshBalanceConfigMenu(ctx, chat, messageID)
+}
+
+// processBalanceConfigInput 处理余额配置输入态下的文本，返回 true 表示已消费该消息
+func (b *Bot) processBalanceConfigInput(ctx context.Context, msg *botModels.Message, state *models.UserState) bool {
+	if state == nil || !strings.HasPrefix(state.Action, balanceConfigStatePrefix) {
+		return false
+	}
+	chatID, userID := msg.Chat.ID, msg.From.ID
+	action := strings.TrimPrefix(state.Action, balanceConfigStatePrefix)
+
+	var (
+		threshold float64
+		limit     int
+		err       error
+	)
+	switch action {
+	case balanceConfigMinBalance:
+		threshold, err = parseMinBalanceInput(msg.Text)
+	case balanceConfigAlertLimit:
+		limit, err = parseAlertLimitInput(msg.Text)
+	default:
+		b.configMenuService.ClearUserState(chatID, userID)
+		return true
+	}
+	if err != nil {
+		// 与 /configs 输入一致：校验失败可重试，超过次数后退出输入态
+		state.RetryCount++
+		if state.RetryCount >= service.MaxInputRetries {
+			b.configMenuService.ClearUserState(chatID, userID)
+			b.sendErrorMessage(ctx, chatID, fmt.Sprintf("输入验证失败次数过多\n\n错误: %s", err.Error()), msg.ID)
+			b.refreshBalanceConfigMenu(ctx, msg.Chat, state.MessageID)
+			return true
+		}
+		b.configMenuService.SetUserState(chatID, userID, state)
+		b.sendErrorMessage(ctx, chatID, fmt.Sprintf("%s\n\n剩余尝试次数: %d\n请重新输入：", err.Error(), service.MaxInputRetries-state.RetryCount), msg.ID)
+		return true
+	}
+
+	b.configMenuService.ClearUserState(chatID, userID)
+	var (
+		result  *service.UpstreamBalanceResult
+		summary string
+	)
+	if action == balanceConfigMinBalance {
+		if result, err = b.balanceService.SetMinBalance(ctx, chatID, threshold, userID); err == nil {
+			summary = fmt.Sprintf("最低余额已更新为 %.2f CNY", result.MinBalance)
+		}
+	} else {
+		if result, err = b.balanceService.SetAlertLimit(ctx, chatID, limit, userID); err == nil {
+			summary = fmt.Sprintf("告警频率已更新为 每小时 %d 次", result.AlertLimitPerHour)
+		}
+	}
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Balance config input failed: chat_id=%d config=%s err=%v", chatID, action, err)
+		b.sendErrorMessage(ctx, chatID, "设置失败", msg.ID)
+	} else {
+		logger.Ctx(ctx).Infof("Balance config input updated: chat_id=%d user_id=%d config=%s", chatID, userID, action)
+		b.sendSuccessMessage(ctx, chatID, fmt.Sprintf("%s\n当前余额：%.2f CNY", summary, result.Balance), msg.ID)
+	}
+	b.refreshBalanceConfigMenu(ctx, msg.Chat, state.MessageID)
+	return true
+}
+
+// loadBalanceConfig 读取群组与余额记录，非上游群或未绑定接口时返回可展示的错误
+func (b *Bot) loadBalanceConfig(ctx context.Context, chat botModels.Chat) (*models.Group, *service.UpstreamBalanceResult, error) {
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   chat.ID,
+		Type:     string(chat.Type),
+		Title:    chat.Title,
+		Username: chat.Username,
+	})
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get/create group for balance config: chat_id=%d err=%v", chat.ID, err)
+		return nil, nil, fmt.Errorf("获取群组信息失败")
+	}
+	result, err := b.balanceService.Get(ctx, chat.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, result, nil
+}
+
+// refreshBalanceConfigMenu 把指定消息重新渲染为余额配置菜单
+func (b *Bot) refreshBalanceConfigMenu(ctx context.Context, chat botModels.Chat, messageID int) {
+	if messageID == 0 {
+		return
+	}
+	group, result, err := b.loadBalanceConfig(ctx, chat)
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to refresh balance config menu: chat_id=%d err=%v", chat.ID, err)
+		return
+	}
+	b.editMessage(ctx, chat.ID, messageID, b.formatBalanceConfigText(group, result), b.buildBalanceConfigKeyboard(group))
+}
+
+// balanceAlertTargetOptions 可选的告警目标：告警群与 owner 私聊只在全局配置了对应接收方时提供
+func (b *Bot) balanceAlertTargetOptions() []string {
+	options := []string{models.BalanceAlertTargetDefault, models.BalanceAlertTargetGroup}
+	if b.balanceAlertChatID != 0 {
+		options = append(options, models.BalanceAlertTargetChat)
+	}
+	if len(b.ownerIDs) > 0 {
+		options = append(options, models.BalanceAlertTargetOwners)
+	}
+	return options
+}
+
+// nextBalanceAlertTarget 循环切换到下一个可选的告警目标
+func (b *Bot) nextBalanceAlertTarget(current string) string {
+	options := b.balanceAlertTargetOptions()
+	index := slices.Index(options, current)
+	return options[(index+1)%len(options)]
+}
+
+// describeBalanceAlertDelivery 描述告警实际的接收方
+func (b *Bot) describeBalanceAlertDelivery(group *models.Group) string {
+	targets, routed := b.balanceAlertTargets(group.TelegramID, group.Settings.BalanceAlertTarget)
+	switch {
+	case !routed:
+		return "本群"
+	case len(targets) == 1 && targets[0] == b.balanceAlertChatID:
+		return fmt.Sprintf("告警群 <code>%d</code>", b.balanceAlertChatID)
+	default:
+		return fmt.Sprintf("私聊 Owner（%d 人）", len(targets))
+	}
+}
+
+// formatBalanceConfigText 余额配置菜单正文，展示各项当前值
+func (b *Bot) formatBalanceConfigText(group *models.Group, result *service.UpstreamBalanceResult) string {
+	limit := result.AlertLimitPerHour
+	if limit <= 0 {
+		limit = monitorDefaultAlertLimit
+	}
+
+	var text strings.Builder
+	text.WriteString("💰 <b>上游余额配置</b>\n\n")
+	text.WriteString(fmt.Sprintf("当前余额：%.2f CNY\n", result.Balance))
+	text.WriteString(fmt.Sprintf("最低余额：%.2f CNY\n", result.MinBalance))
+	text.WriteString(fmt.Sprintf("告警限频：每小时 %d 次\n", limit))
+	text.WriteString(fmt.Sprintf("告警目标：%s（实际发往：%s）\n", models.BalanceAlertTargetLabel(group.Settings.BalanceAlertTarget), b.describeBalanceAlertDelivery(group)))
+	if !models.IsBalanceMonitorEnabled(group.Settings) {
+		text.WriteString("⚠️ 余额轮询告警已在 /configs 中关闭\n")
+	}
+	text.WriteString("\n快捷命令：<code>/set_min_balance 金额</code>、<code>/set_balance_alert_limit 次数</code>")
+	return text.String()
+}
+
+// buildBalanceConfigKeyboard 余额配置菜单按钮
+func (b *Bot) buildBalanceConfigKeyboard(group *models.Group) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{{Text: "💰 最低余额 ✏️", CallbackData: balanceConfigCallbackPrefix + balanceConfigMinBalance}},
+			{{Text: "🔔 告警限频 ✏️", CallbackData: balanceConfigCallbackPrefix + balanceConfigAlertLimit}},
+			{{Text: "🎯 告警目标：" + models.BalanceAlertTargetLabel(group.Settings.BalanceAlertTarget) + " 🔄", CallbackData: balanceConfigCallbackPrefix + balanceConfigTarget}},
+			{
+				{Text: "🔄 刷新", CallbackData: balanceConfigCallbackPrefix + balanceConfigRefresh},
+				{Text: "❌ 关闭", CallbackData: balanceConfigCallbackPrefix + balanceConfigClose},
+			},
+		},
+	}
+}
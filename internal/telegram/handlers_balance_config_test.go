@@ -0,0 +1,76 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+func TestNextBalanceAlertTargetCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		bot  *Bot
+		want []string
+	}{
+		{name: "no global receivers", bot: &Bot{}, want: []string{models.BalanceAlertTargetGroup, models.BalanceAlertTargetDefault}},
+		{
+			name: "alert chat and owners",
+			bot:  &Bot{balanceAlertChatID: -900, ownerIDs: []int64{7}},
+			want: []string{models.BalanceAlertTargetGroup, models.BalanceAlertTargetChat, models.BalanceAlertTargetOwners, models.BalanceAlertTargetDefault},
+		},
+	}
+
+	for _, tt := range tests {
+		current := models.BalanceAlertTargetDefault
+		for i, want := range tt.want {
+			current = tt.bot.nextBalanceAlertTarget(current)
+			if current != want {
+				t.Fatalf("%s: step %d expected %q, got %q", tt.name, i, want, current)
+			}
+		}
+	}
+
+	// 全局配置移除后，已保存的目标切换时回到第一项
+	if next := (&Bot{}).nextBalanceAlertTarget(models.BalanceAlertTargetOwners); next != models.BalanceAlertTargetDefault {
+		t.Fatalf("expected fallback to default, got %q", next)
+	}
+}
+
+func TestParseBalanceConfigInputs(t *testing.T) {
+	if v, err := parseMinBalanceInput(" 1500.5 "); err != nil || v != 1500.5 {
+		t.Fatalf("unexpected min balance %v err=%v", v, err)
+	}
+	for _, input := range []string{"-1", "abc", ""} {
+		if _, err := parseMinBalanceInput(input); err == nil {
+			t.Fatalf("expected error for min balance %q", input)
+		}
+	}
+	if v, err := parseAlertLimitInput("5"); err != nil || v != 5 {
+		t.Fatalf("unexpected alert limit %v err=%v", v, err)
+	}
+	for _, input := range []string{"0", "1.5", "x"} {
+		if _, err := parseAlertLimitInput(input); err == nil {
+			t.Fatalf("expected error for alert limit %q", input)
+		}
+	}
+}
+
+func TestFormatBalanceConfigTextShowsCurrentValues(t *testing.T) {
+	b := &Bot{balanceAlertChatID: -900}
+	group := &models.Group{TelegramID: -1, Settings: models.GroupSettings{BalanceAlertTarget: models.BalanceAlertTargetChat}}
+	result := &service.UpstreamBalanceResult{Balance: 800, MinBalance: 1000, AlertLimitPerHour: 2}
+
+	text := b.formatBalanceConfigText(group, result)
+	for _, want := range []string{"当前余额：800.00 CNY", "最低余额：1000.00 CNY", "每小时 2 次", "告警目标：告警群", "<code>-900</code>"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in menu text:\n%s", want, text)
+		}
+	}
+
+	keyboard := b.buildBalanceConfigKeyboard(group)
+	if data := keyboard.InlineKeyboard[2][0].CallbackData; data != balanceConfigCallbackPrefix+balanceConfigTarget {
+		t.Fatalf("unexpected target button data %q", data)
+	}
+}
@@ -0,0 +1,222 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const balancesUsage = "用法：/balances 查看全部上游群余额，/balances csv 导出 CSV 对账文件"
+
+// balanceReportRow 对账报表中的一行（一个上游群）
+type balanceReportRow struct {
+	GroupID    int64
+	Title      string
+	Balance    float64
+	MinBalance float64
+	UpdatedAt  time.Time
+}
+
+// Low 是否低于最低余额
+func (r balanceReportRow) Low() bool {
+	return r.Balance < r.MinBalance
+}
+
+// handleBalanceExport 处理 /balances 命令（Owner），汇总所有上游群余额与阈值，支持导出 CSV
+func (b *Bot) handleBalanceExport(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	asCSV := false
+	if len(fields) > 1 {
+		if !strings.EqualFold(fields[1], "csv") {
+			b.sendErrorMessage(ctx, msg.Chat.ID, balancesUsage, msg.ID)
+			return
+		}
+		asCSV = true
+	}
+
+	rows, err := b.buildBalanceReportRows(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Balance export failed: %v", err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取余额列表失败", msg.ID)
+		return
+	}
+	if len(rows) == 0 {
+		b.sendMessage(ctx, msg.Chat.ID, "📭 暂无上游群余额记录", msg.ID)
+		return
+	}
+
+	loc := models.DefaultLocation()
+	if !asCSV {
+		b.sendMessage(ctx, msg.Chat.ID, formatBalanceReport(rows, loc), msg.ID)
+		return
+	}
+
+	data, err := encodeBalanceReportCSV(rows, loc)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Balance export encode failed: %v", err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "生成 CSV 失败", msg.ID)
+		return
+	}
+
+	filename := fmt.Sprintf("upstream_balances_%s.csv", time.Now().In(loc).Format("20060102_1504"))
+	b.sendDocument(ctx, msg.Chat.ID, filename, data, formatBalanceReportSummary(rows), msg.ID)
+}
+
+// buildBalanceReportRows 基于余额列表组装报表，群名从 groups 集合补全；低于阈值的群排在前面
+func (b *Bot) buildBalanceReportRows(ctx context.Context) ([]balanceReportRow, error) {
+	balances, err := b.balanceService.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[int64]string)
+	if groups, err := b.groupService.ListActiveGroups(ctx); err == nil {
+		for _, group := range groups {
+			titles[group.TelegramID] = group.Title
+		}
+	} else {
+		logger.Ctx(ctx).Warnf("Balance export failed to list groups: %v", err)
+	}
+
+	rows := make([]balanceReportRow, 0, len(balances))
+	for _, balance := range balances {
+		if balance == nil {
+			continue
+		}
+		title, ok := titles[balance.GroupID]
+		if !ok {
+			// Bot 已离开的群不在活跃列表中，单独查一次
+			if group, err := b.groupService.GetGroupInfo(ctx, balance.GroupID); err == nil && group != nil {
+				title = group.Title
+			}
+		}
+		rows = append(rows, balanceReportRow{
+			GroupID:    balance.GroupID,
+			Title:      title,
+			Balance:    balance.Balance,
+			MinBalance: balance.MinBalance,
+			UpdatedAt:  balance.UpdatedAt,
+		})
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Low() != rows[j].Low() {
+			return rows[i].Low()
+		}
+		return rows[i].GroupID < rows[j].GroupID
+	})
+	return rows, nil
+}
+
+// formatBalanceReport 文本版对账报表
+func formatBalanceReport(rows []balanceReportRow, loc *time.Location) string {
+	var text strings.Builder
+	text.WriteString("💰 <b>上游群余额对账</b>\n")
+	text.WriteString(formatBalanceReportSummary(rows))
+	text.WriteString("\n\n")
+
+	for i, row := range rows {
+		marker := "✅"
+		if row.Low() {
+			marker = "⚠️"
+		}
+		text.WriteString(fmt.Sprintf("%s %s（<code>%d</code>）\n", marker, html.EscapeString(balanceReportTitle(row.Title)), row.GroupID))
+		text.WriteString(fmt.Sprintf("余额 %s / 最低 %s CNY", formatAmount(row.Balance), formatAmount(row.MinBalance)))
+		if row.Low() {
+			text.WriteString(fmt.Sprintf("，需补 %s", formatAmount(row.MinBalance-row.Balance)))
+		}
+		text.WriteString("\n更新于 " + formatBalanceUpdatedAt(row.UpdatedAt, loc))
+		if i < len(rows)-1 {
+			text.WriteString("\n\n")
+		}
+	}
+	return text.String()
+}
+
+// formatBalanceReportSummary 报表汇总行：群数与低于阈值的群数
+func formatBalanceReportSummary(rows []balanceReportRow) string {
+	low := 0
+	for _, row := range rows {
+		if row.Low() {
+			low++
+		}
+	}
+	return fmt.Sprintf("共 %d 个上游群，⚠️ 低于阈值 %d 个", len(rows), low)
+}
+
+// encodeBalanceReportCSV 生成 CSV（带 UTF-8 BOM，Excel 直接打开不乱码）
+func encodeBalanceReportCSV(rows []balanceReportRow, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"群ID", "群名", "当前余额", "最低余额", "低于阈值", "最后更新时间"}); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		low := "否"
+		if row.Low() {
+			low = "是"
+		}
+		if err := writer.Write([]string{
+			strconv.FormatInt(row.GroupID, 10),
+			csvSafeCell(balanceReportTitle(row.Title)),
+			formatAmount(row.Balance),
+			formatAmount(row.MinBalance),
+			low,
+			formatBalanceUpdatedAt(row.UpdatedAt, loc),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvSafeCell 防止 CSV 公式注入：以 = + - @ 开头的文本前加单引号，Excel 按文本显示而不执行；
+// 负数等纯数值保持原样
+func csvSafeCell(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@", rune(value[0])) {
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + value
+}
+
+// balanceReportTitle 群名缺失时的占位
+func balanceReportTitle(title string) string {
+	if strings.TrimSpace(title) == "" {
+		return "未知群组"
+	}
+	return title
+}
+
+// formatBalanceUpdatedAt 格式化最后更新时间，从未更新时显示「-」
+func formatBalanceUpdatedAt(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestEncodeBalanceReportCSV(t *testing.T) {
+	updatedAt := time.Date(2024, 11, 20, 1, 30, 0, 0, time.UTC)
+	rows := []balanceReportRow{
+		{GroupID: -1001, Title: "上游,A", Balance: 50, MinBalance: 100, UpdatedAt: updatedAt},
+		{GroupID: -1002, Balance: 300, MinBalance: 100},
+	}
+
+	data, err := encodeBalanceReportCSV(rows, models.DefaultLocation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "\ufeff") {
+		t.Fatalf("expected UTF-8 BOM prefix")
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(data), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse csv: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d", len(records))
+	}
+	want := []string{"-1001", "上游,A", "50.00", "100.00", "是", "2024-11-20 09:30:00"}
+	if strings.Join(records[1], "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected low row: %v", records[1])
+	}
+	if records[2][1] != "未知群组" || records[2][4] != "否" || records[2][5] != "-" {
+		t.Fatalf("unexpected normal row: %v", records[2])
+	}
+}
+
+func TestCSVSafeCell(t *testing.T) {
+	cases := map[string]string{
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1+cmd":            "'+1+cmd",
+		"-2+3":              "'-2+3",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"-50.00":            "-50.00",
+		"上游群":               "上游群",
+		"":                  "",
+	}
+	for input, want := range cases {
+		if got := csvSafeCell(input); got != want {
+			t.Fatalf("csvSafeCell(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestFormatBalanceReportMarksLowGroups(t *testing.T) {
+	rows := []balanceReportRow{
+		{GroupID: -1001, Title: "<上游A>", Balance: 50, MinBalance: 100},
+		{GroupID: -1002, Title: "上游B", Balance: 300, MinBalance: 100},
+	}
+
+	text := formatBalanceReport(rows, models.DefaultLocation())
+	for _, want := range []string{
+		"共 2 个上游群，⚠️ 低于阈值 1 个",
+		"⚠️ &lt;上游A&gt;（<code>-1001</code>）",
+		"余额 50.00 / 最低 100.00 CNY，需补 50.00",
+		"✅ 上游B（<code>-1002</code>）",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in report:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"go_bot/internal/logger"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// handleResendBills 处理 /resend_bills 命令（Owner），立即补发最近几天推送失败的每日账单
+func (b *Bot) handleResendBills(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	if b.dailySummaryScheduler == nil || b.dailySummaryScheduler.repo == nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "每日账单推送未启用，无需补发", msg.ID)
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	result := b.dailySummaryScheduler.catchUp(runCtx)
+	logger.Ctx(ctx).Infof("Daily bill manual catch-up by %d: total=%d sent=%d", msg.From.ID, result.total, result.sent)
+	if result.total == 0 && len(result.failures) == 0 {
+		b.sendMessage(ctx, msg.Chat.ID, "📭 没有待补发的账单", msg.ID)
+		return
+	}
+	b.sendMessage(ctx, msg.Chat.ID, "📮 "+safeHTML(result.summary()), msg.ID)
+}
@@ -0,0 +1,351 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/forward"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	broadcastCallbackPrefix = "broadcast:"
+	// broadcastPendingTTL 待确认广播的有效期
+	broadcastPendingTTL = 5 * time.Minute
+	// broadcastConcurrency 并发发送的群组数
+	broadcastConcurrency = 4
+	// broadcastRatePerSecond 全局发送速率，低于 Telegram 每秒 30 条的上限
+	broadcastRatePerSecond = 20
+	// broadcastFailureDetailLimit 汇报中展示的失败群组数量上限
+	broadcastFailureDetailLimit = 10
+)
+
+// broadcastRetryPolicy 广播允许比普通发送更长的等待，429 的 retry_after 不超过 1 分钟都会等待重试
+var broadcastRetryPolicy = sendRetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	MaxTotal:    2 * time.Minute,
+}
+
+// pendingBroadcast 等待 Owner 二次确认的广播
+type pendingBroadcast struct {
+	ownerID int64
+	text    string
+	tiers   []models.GroupTier
+	tags    []string
+}
+
+// broadcastFailure 单个群组的发送失败记录
+type broadcastFailure struct {
+	chatID int64
+	title  string
+	err    error
+}
+
+// broadcastResult 广播发送结果
+type broadcastResult struct {
+	total    int
+	success  int
+	failures []broadcastFailure
+	duration time.Duration
+}
+
+// handleBroadcast 处理 /broadcast 命令（Owner），生成预览并等待二次确认
+func (b *Bot) handleBroadcast(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	chatID := msg.Chat.ID
+
+	tiers, tags, text, err := parseBroadcastCommand(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	targetCount := 0
+	err = b.groupService.IterateActiveGroups(ctx, func(groups []*models.Group) error {
+		targetCount += len(filterBroadcastTargets(groups, tiers, tags))
+		return nil
+	})
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list groups for broadcast: %v", err)
+		b.sendErrorMessage(ctx, chatID, "获取群组列表失败", msg.ID)
+		return
+	}
+	if targetCount == 0 {
+		b.sendErrorMessage(ctx, chatID, "没有符合条件的目标群组", msg.ID)
+		return
+	}
+
+	pending := &pendingBroadcast{
+		ownerID: msg.From.ID,
+		text:    text,
+		tiers:   tiers,
+		tags:    tags,
+	}
+	token := b.pendingBroadcasts.put(pending, broadcastPendingTTL)
+
+	preview := fmt.Sprintf("📢 <b>群发广播确认</b>\n\n目标：%s（%d 个群）\n有效期：%s\n\n%s\n\n请确认是否发送。",
+		formatBroadcastTargetLabel(tiers, tags), targetCount, formatDuration(broadcastPendingTTL), html.EscapeString(text))
+	if _, err := b.sendMessageWithMarkupAndMessage(ctx, chatID, preview, buildBroadcastKeyboard(token), msg.ID); err != nil {
+		b.pendingBroadcasts.take(token)
+	}
+}
+
+// handleBroadcastCallback 处理广播确认/取消按钮
+func (b *Bot) handleBroadcastCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	action, token, ok := parseBroadcastCallback(query.Data)
+	if !ok {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的请求", true)
+		return
+	}
+
+	if !b.checkPermission(ctx, botInstance, update, models.PermBroadcast) {
+		return
+	}
+
+	pending, exists := b.pendingBroadcasts.get(token)
+	if !exists {
+		b.answerCallback(ctx, botInstance, query.ID, "广播已失效，请重新发起", true)
+		return
+	}
+	if pending.ownerID != query.From.ID {
+		b.answerCallback(ctx, botInstance, query.ID, "只有发起人可以确认此广播", true)
+		return
+	}
+	if !b.pendingBroadcasts.take(token) {
+		b.answerCallback(ctx, botInstance, query.ID, "广播已处理", true)
+		return
+	}
+
+	var chatID int64
+	var messageID int
+	if query.Message.Message != nil {
+		chatID = query.Message.Message.Chat.ID
+		messageID = query.Message.Message.ID
+	}
+
+	if action == "cancel" {
+		b.answerCallback(ctx, botInstance, query.ID, "已取消", false)
+		if messageID != 0 {
+			b.editMessage(ctx, chatID, messageID, "🚫 群发广播已取消", nil)
+		}
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "开始发送", false)
+	if messageID != 0 {
+		b.editMessage(ctx, chatID, messageID, "⏳ 群发广播发送中...", nil)
+	}
+
+	result, err := b.runBroadcast(ctx, pending)
+	var report string
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Broadcast aborted: owner=%d err=%v", pending.ownerID, err)
+		report = fmt.Sprintf("❌ 群发广播失败：%s", html.EscapeString(err.Error()))
+	} else {
+		report = formatBroadcastReport(result)
+	}
+
+	if messageID != 0 {
+		b.editMessage(ctx, chatID, messageID, report, nil)
+	} else {
+		b.sendMessage(ctx, query.From.ID, report)
+	}
+}
+
+// runBroadcast 分批获取目标群组并限流发送广播，遍历中途失败时已发送的批次仍计入结果
+func (b *Bot) runBroadcast(ctx context.Context, pending *pendingBroadcast) (broadcastResult, error) {
+	limiter := forward.NewRateLimiter(broadcastRatePerSecond)
+	defer limiter.Close()
+
+	start := time.Now()
+	text := html.EscapeString(pending.text)
+	var result broadcastResult
+	err := b.groupService.IterateActiveGroups(ctx, func(groups []*models.Group) error {
+		targets := filterBroadcastTargets(groups, pending.tiers, pending.tags)
+		if len(targets) == 0 {
+			return nil
+		}
+		result.merge(broadcastToGroups(ctx, targets, limiter, func(ctx context.Context, chatID int64) error {
+			_, err := b.sendMessageWithMarkupAndMessage(ctx, chatID, text, nil)
+			return err
+		}))
+		return nil
+	})
+	result.duration = time.Since(start)
+	if err != nil && result.total == 0 {
+		return broadcastResult{}, fmt.Errorf("获取群组列表失败: %w", err)
+	}
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Broadcast group listing interrupted: attempted=%d err=%v", result.total, err)
+	}
+
+	logger.Ctx(ctx).Infof("Broadcast finished: owner=%d total=%d success=%d failed=%d duration=%s",
+		pending.ownerID, result.total, result.success, len(result.failures), result.duration)
+	return result, nil
+}
+
+// merge 累加另一批次的发送结果（耗时由调用方统一计算）
+func (r *broadcastResult) merge(other broadcastResult) {
+	r.total += other.total
+	r.success += other.success
+	r.failures = append(r.failures, other.failures...)
+}
+
+// broadcastToGroups 使用 errgroup 并发 + 令牌桶限流向目标群组发送消息
+func broadcastToGroups(ctx context.Context, targets []*models.Group, limiter *forward.RateLimiter, send func(ctx context.Context, chatID int64) error) broadcastResult {
+	start := time.Now()
+	result := broadcastResult{total: len(targets)}
+
+	var mu sync.Mutex
+	runner, runCtx := errgroup.WithContext(ctx)
+	runner.SetLimit(broadcastConcurrency)
+
+	for _, group := range targets {
+		runner.Go(func() error {
+			err := sendWithRetry(runCtx, broadcastRetryPolicy, func(ctx context.Context) error {
+				if err := limiter.Wait(ctx); err != nil {
+					return err
+				}
+				return send(ctx, group.TelegramID)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger.Ctx(ctx).Warnf("Broadcast failed: chat_id=%d err=%v", group.TelegramID, err)
+				result.failures = append(result.failures, broadcastFailure{chatID: group.TelegramID, title: group.Title, err: err})
+				return nil
+			}
+			result.success++
+			return nil
+		})
+	}
+	_ = runner.Wait()
+
+	result.duration = time.Since(start)
+	return result
+}
+
+// parseBroadcastCommand 解析 /broadcast [tier=merchant,upstream] [tag=vip,test] <文本>
+func parseBroadcastCommand(text string) ([]models.GroupTier, []string, string, error) {
+	payload := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "/broadcast"))
+	if payload == "" {
+		return nil, nil, "", errors.New("用法：/broadcast [tier=basic,merchant,upstream] [tag=标签1,标签2] <文本>")
+	}
+
+	// 开头的 tier=/tag= 选项与频道默认投递目标同一套解析，其余为广播正文
+	var target forward.Target
+	for {
+		option, rest, _ := strings.Cut(payload, " ")
+		ok, err := target.ParseOption(option)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if !ok {
+			break
+		}
+		payload = strings.TrimSpace(rest)
+	}
+
+	if payload == "" {
+		return nil, nil, "", errors.New("广播内容不能为空")
+	}
+	return target.Tiers, target.Tags, payload, nil
+}
+
+// filterBroadcastTargets 保留群组类型、等级与标签均匹配的目标群，标签满足任一即可
+func filterBroadcastTargets(groups []*models.Group, tiers []models.GroupTier, tags []string) []*models.Group {
+	targets := make([]*models.Group, 0, len(groups))
+	for _, group := range groups {
+		if group == nil || group.TelegramID == 0 {
+			continue
+		}
+		if group.Type != "group" && group.Type != "supergroup" {
+			continue
+		}
+		tier := group.Tier
+		if tier == "" {
+			tier = models.GroupTierBasic
+		}
+		if !models.IsTierAllowed(tier, tiers) || !group.HasAnyTag(tags) {
+			continue
+		}
+		targets = append(targets, group)
+	}
+	return targets
+}
+
+// formatBroadcastTargetLabel 描述广播目标的筛选条件
+func formatBroadcastTargetLabel(tiers []models.GroupTier, tags []string) string {
+	label := models.FormatAllowedTierList(tiers)
+	if len(tags) > 0 {
+		label += "，标签 " + html.EscapeString(models.FormatGroupTags(tags))
+	}
+	return label
+}
+
+func formatBroadcastReport(result broadcastResult) string {
+	var sb strings.Builder
+	sb.WriteString("📢 <b>群发广播完成</b>\n\n")
+	sb.WriteString(fmt.Sprintf("目标群组：%d\n", result.total))
+	sb.WriteString(fmt.Sprintf("成功：%d\n", result.success))
+	sb.WriteString(fmt.Sprintf("失败：%d\n", len(result.failures)))
+	sb.WriteString(fmt.Sprintf("耗时：%s", result.duration.Round(time.Millisecond)))
+
+	if len(result.failures) > 0 {
+		sb.WriteString("\n\n失败详情：")
+		for i, failure := range result.failures {
+			if i >= broadcastFailureDetailLimit {
+				sb.WriteString(fmt.Sprintf("\n… 其余 %d 个群省略", len(result.failures)-broadcastFailureDetailLimit))
+				break
+			}
+			title := failure.title
+			if title == "" {
+				title = fmt.Sprintf("%d", failure.chatID)
+			}
+			sb.WriteString(fmt.Sprintf("\n• %s：%s", html.EscapeString(title), html.EscapeString(failure.err.Error())))
+		}
+	}
+	return sb.String()
+}
+
+func buildBroadcastKeyboard(token string) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{
+				{Text: "✅ 确认发送", CallbackData: broadcastCallbackPrefix + "confirm:" + token},
+				{Text: "❌ 取消", CallbackData: broadcastCallbackPrefix + "cancel:" + token},
+			},
+		},
+	}
+}
+
+// parseBroadcastCallback 解析回调数据：broadcast:<confirm|cancel>:<token>
+func parseBroadcastCallback(data string) (string, string, bool) {
+	payload := strings.TrimPrefix(data, broadcastCallbackPrefix)
+	action, token, found := strings.Cut(payload, ":")
+	if !found || token == "" {
+		return "", "", false
+	}
+	if action != "confirm" && action != "cancel" {
+		return "", "", false
+	}
+	return action, token, true
+}
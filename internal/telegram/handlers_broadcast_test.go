@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"go_bot/internal/telegram/forward"
+	"go_bot/internal/telegram/models"
+)
+
+func TestParseBroadcastCommand(t *testing.T) {
+	tiers, tags, text, err := parseBroadcastCommand("/broadcast tier=Merchant,upstream,merchant 今晚 22:00 维护")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(tiers, []models.GroupTier{models.GroupTierMerchant, models.GroupTierUpstream}) {
+		t.Fatalf("unexpected tiers: %v", tiers)
+	}
+	if text != "今晚 22:00 维护" || tags != nil {
+		t.Fatalf("unexpected text=%q tags=%v", text, tags)
+	}
+
+	tiers, tags, text, err = parseBroadcastCommand("/broadcast 大家好")
+	if err != nil || tiers != nil || tags != nil || text != "大家好" {
+		t.Fatalf("expected untiered broadcast, got tiers=%v tags=%v text=%q err=%v", tiers, tags, text, err)
+	}
+
+	tiers, tags, text, err = parseBroadcastCommand("/broadcast tag=#VIP,测试,vip tier=merchant 通知")
+	if err != nil || !slices.Equal(tags, []string{"vip", "测试"}) || !slices.Equal(tiers, []models.GroupTier{models.GroupTierMerchant}) || text != "通知" {
+		t.Fatalf("unexpected tagged broadcast: tiers=%v tags=%v text=%q err=%v", tiers, tags, text, err)
+	}
+
+	for _, input := range []string{"/broadcast", "/broadcast   ", "/broadcast tier=merchant", "/broadcast tier=vip 你好", "/broadcast tag= 你好"} {
+		if _, _, _, err := parseBroadcastCommand(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestFilterBroadcastTargets(t *testing.T) {
+	groups := []*models.Group{
+		{TelegramID: -1, Type: "supergroup", Tier: models.GroupTierMerchant, Tags: []string{"vip"}},
+		{TelegramID: -2, Type: "group", Tier: ""},
+		{TelegramID: -3, Type: "channel", Tier: models.GroupTierMerchant},
+		{TelegramID: -4, Type: "supergroup", Tier: models.GroupTierUpstream},
+		nil,
+	}
+
+	ids := func(targets []*models.Group) []int64 {
+		result := make([]int64, 0, len(targets))
+		for _, group := range targets {
+			result = append(result, group.TelegramID)
+		}
+		return result
+	}
+
+	if got := ids(filterBroadcastTargets(groups, nil, nil)); !slices.Equal(got, []int64{-1, -2, -4}) {
+		t.Fatalf("unexpected targets without tier filter: %v", got)
+	}
+	if got := ids(filterBroadcastTargets(groups, []models.GroupTier{models.GroupTierBasic}, nil)); !slices.Equal(got, []int64{-2}) {
+		t.Fatalf("expected empty tier to count as basic, got %v", got)
+	}
+	if got := ids(filterBroadcastTargets(groups, []models.GroupTier{models.GroupTierMerchant, models.GroupTierUpstream}, nil)); !slices.Equal(got, []int64{-1, -4}) {
+		t.Fatalf("unexpected tier-filtered targets: %v", got)
+	}
+	if got := ids(filterBroadcastTargets(groups, nil, []string{"VIP"})); !slices.Equal(got, []int64{-1}) {
+		t.Fatalf("unexpected tag-filtered targets: %v", got)
+	}
+}
+
+func TestBroadcastToGroupsCountsResults(t *testing.T) {
+	targets := []*models.Group{
+		{TelegramID: -1, Title: "A"},
+		{TelegramID: -2, Title: "B"},
+		{TelegramID: -3, Title: "C"},
+	}
+
+	limiter := forward.NewRateLimiter(100)
+	defer limiter.Close()
+
+	var mu sync.Mutex
+	sent := make([]int64, 0, len(targets))
+	result := broadcastToGroups(context.Background(), targets, limiter, func(_ context.Context, chatID int64) error {
+		if chatID == -2 {
+			return errors.New("chat not found")
+		}
+		mu.Lock()
+		sent = append(sent, chatID)
+		mu.Unlock()
+		return nil
+	})
+
+	if result.total != 3 || result.success != 2 || len(result.failures) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.failures[0].chatID != -2 || result.failures[0].title != "B" {
+		t.Fatalf("unexpected failure: %+v", result.failures[0])
+	}
+	slices.Sort(sent)
+	if !slices.Equal(sent, []int64{-3, -1}) {
+		t.Fatalf("unexpected sent chats: %v", sent)
+	}
+}
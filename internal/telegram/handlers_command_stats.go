@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// commandStatsDefaultDays /command_stats 默认统计天数
+	commandStatsDefaultDays = 7
+	// commandStatsMaxDays 统计天数上限（与命令使用统计保留期一致）
+	commandStatsMaxDays = 90
+	// commandStatsLimit 排行展示条数
+	commandStatsLimit = 20
+)
+
+// handleCommandStats 处理 /command_stats [天数] 命令（仅 Owner），输出近 N 天（默认 7 天）命令使用排行
+func (b *Bot) handleCommandStats(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	if b.commandUsage == nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "命令使用统计未启用", msg.ID)
+		return
+	}
+
+	days, err := parseCommandStatsDays(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+
+	report, err := b.commandUsage.TopCommands(ctx, days, commandStatsLimit)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to load command stats: days=%d err=%v", days, err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatCommandStats(days, report), msg.ID)
+}
+
+// parseCommandStatsDays 解析 /command_stats 的天数参数，缺省为 7 天
+func parseCommandStatsDays(text string) (int, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return commandStatsDefaultDays, nil
+	}
+	days, err := strconv.Atoi(fields[1])
+	if err != nil || days <= 0 || days > commandStatsMaxDays {
+		return 0, fmt.Errorf("天数需为 1-%d 的整数，例如：/command_stats 7", commandStatsMaxDays)
+	}
+	return days, nil
+}
+
+// formatCommandStats 将命令使用排行格式化为文本
+func formatCommandStats(days int, report *service.CommandUsageReport) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📈 <b>命令使用排行（近 %d 天）</b>\n", days))
+	sb.WriteString(fmt.Sprintf("统计起始：%s，共 %d 次调用\n", report.Since, report.Total))
+	if len(report.Ranks) == 0 {
+		sb.WriteString("\n暂无命令调用记录")
+		return sb.String()
+	}
+
+	sb.WriteString("\n")
+	for i, rank := range report.Ranks {
+		share := 0.0
+		if report.Total > 0 {
+			share = float64(rank.Count) * 100 / float64(report.Total)
+		}
+		sb.WriteString(fmt.Sprintf("%d. <code>%s</code>：%d（%.1f%%）\n", i+1, html.EscapeString(rank.Command), rank.Count, share))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,47 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+func TestParseCommandStatsDays(t *testing.T) {
+	cases := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{"/command_stats", commandStatsDefaultDays, false},
+		{"/command_stats 30", 30, false},
+		{"/command_stats 0", 0, true},
+		{"/command_stats 91", 0, true},
+		{"/command_stats abc", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parseCommandStatsDays(tc.text)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Fatalf("%q: expected %d (err=%t), got %d (err=%v)", tc.text, tc.want, tc.wantErr, got, err)
+		}
+	}
+}
+
+func TestFormatCommandStats(t *testing.T) {
+	text := formatCommandStats(7, &service.CommandUsageReport{
+		Since: "2026-03-04",
+		Total: 4,
+		Ranks: []models.CommandUsageRank{{Command: "/ping", Count: 3}, {Command: "<x>", Count: 1}},
+	})
+	for _, want := range []string{"近 7 天", "共 4 次调用", "1. <code>/ping</code>：3（75.0%）", "2. <code>&lt;x&gt;</code>：1（25.0%）"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	empty := formatCommandStats(7, &service.CommandUsageReport{Since: "2026-03-04"})
+	if !strings.Contains(empty, "暂无命令调用记录") {
+		t.Fatalf("expected empty hint, got:\n%s", empty)
+	}
+}
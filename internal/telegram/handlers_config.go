@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html"
 	"strings"
+	"time"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/models"
@@ -25,12 +26,6 @@ func (b *Bot) handleConfigs(ctx context.Context, botInstance *bot.Bot, update *b
 	chatID := update.Message.Chat.ID
 	chat := update.Message.Chat
 
-	// 检查聊天类型：只能在群组中使用
-	if chat.Type != "group" && chat.Type != "supergroup" {
-		b.sendErrorMessage(ctx, chatID, "此命令只能在群组中使用")
-		return
-	}
-
 	// 获取或创建群组记录（智能处理不存在的群组）
 	chatInfo := &service.TelegramChatInfo{
 		ChatID:   chat.ID,
@@ -40,7 +35,7 @@ func (b *Bot) handleConfigs(ctx context.Context, botInstance *bot.Bot, update *b
 	}
 	group, err := b.groupService.GetOrCreateGroup(ctx, chatInfo)
 	if err != nil {
-		logger.L().Errorf("Failed to get/create group: chat_id=%d, error=%v", chatID, err)
+		logger.Ctx(ctx).Errorf("Failed to get/create group: chat_id=%d, error=%v", chatID, err)
 		b.sendErrorMessage(ctx, chatID, "❌ 获取群组信息失败，请稍后重试")
 		return
 	}
@@ -51,7 +46,7 @@ func (b *Bot) handleConfigs(ctx context.Context, botInstance *bot.Bot, update *b
 	// 构建菜单
 	keyboard, err := b.configMenuService.BuildMainMenu(ctx, group, items)
 	if err != nil {
-		logger.L().Errorf("Failed to build config menu: chat_id=%d, error=%v", chatID, err)
+		logger.Ctx(ctx).Errorf("Failed to build config menu: chat_id=%d, error=%v", chatID, err)
 		b.sendErrorMessage(ctx, chatID, "❌ 构建配置菜单失败，请稍后重试")
 		return
 	}
@@ -59,7 +54,7 @@ func (b *Bot) handleConfigs(ctx context.Context, botInstance *bot.Bot, update *b
 	// 发送菜单
 	menuText := b.buildConfigMenuText(ctx, group)
 
-	_, err = botInstance.SendMessage(ctx, &bot.SendMessageParams{
+	sent, err := botInstance.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      chatID,
 		Text:        menuText,
 		ParseMode:   botModels.ParseModeHTML,
@@ -67,10 +62,11 @@ func (b *Bot) handleConfigs(ctx context.Context, botInstance *bot.Bot, update *b
 	})
 
 	if err != nil {
-		logger.L().Errorf("Failed to send config menu: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to send config menu: %v", err)
 		b.sendErrorMessage(ctx, chatID, "❌ 发送配置菜单失败")
 	} else {
-		logger.L().Infof("Config menu sent: chat_id=%d, user_id=%d", chatID, update.Message.From.ID)
+		b.recordSentMessage(sent)
+		logger.Ctx(ctx).Infof("Config menu sent: chat_id=%d, user_id=%d", chatID, update.Message.From.ID)
 	}
 }
 
@@ -85,7 +81,7 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 
 	// 检查消息是否可访问
 	if query.Message.Message == nil {
-		logger.L().Warn("Callback query message is inaccessible")
+		logger.Ctx(ctx).Warn("Callback query message is inaccessible")
 		return
 	}
 
@@ -96,10 +92,10 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 	callbackData := query.Data
 
 	// 权限检查：只有管理员可以操作
-	user, err := b.userService.GetUserInfo(ctx, userID)
-	if err != nil || !user.IsAdmin() {
+	isAdmin, err := b.userService.CheckAdminPermission(ctx, userID, chatID)
+	if err != nil || !isAdmin {
 		b.answerCallback(ctx, botInstance, query.ID, "⚠️ 只有管理员可以操作配置", false)
-		logger.L().Warnf("Non-admin user %d attempted to use config callback in chat %d", userID, chatID)
+		logger.Ctx(ctx).Warnf("Non-admin user %d attempted to use config callback in chat %d", userID, chatID)
 		return
 	}
 
@@ -112,7 +108,7 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 	}
 	group, err := b.groupService.GetOrCreateGroup(ctx, chatInfo)
 	if err != nil {
-		logger.L().Errorf("Failed to get/create group: chat_id=%d, error=%v", chatID, err)
+		logger.Ctx(ctx).Errorf("Failed to get/create group: chat_id=%d, error=%v", chatID, err)
 		b.answerCallback(ctx, botInstance, query.ID, "❌ 获取群组信息失败", false)
 		return
 	}
@@ -120,14 +116,27 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 	// 获取配置项定义（按群等级过滤）
 	items := filterConfigItemsByTier(b.getConfigItems(), group.Tier)
 
-	// 处理回调
+	// 处理回调（保留修改前的快照用于对比变更）
+	before := *group
 	message, shouldUpdateMenu, err := b.configMenuService.HandleCallback(ctx, group, userID, callbackData, items)
 
 	if err != nil {
-		logger.L().Errorf("Failed to handle config callback: data=%s, error=%v", callbackData, err)
+		logger.Ctx(ctx).Errorf("Failed to handle config callback: data=%s, error=%v", callbackData, err)
 		b.answerCallback(ctx, botInstance, query.ID, "❌ 操作失败", false)
 		return
 	}
+	b.announceConfigChanges(ctx, chatID, query.From, diffConfigItems(items, &before, group))
+
+	// 进入输入状态：把菜单替换为输入提示，并附带取消按钮
+	if strings.HasPrefix(callbackData, "config:"+string(models.ConfigTypeInput)+":") {
+		if state := b.configMenuService.GetUserState(chatID, userID); state != nil {
+			state.MessageID = messageID
+			b.configMenuService.SetUserState(chatID, userID, state)
+			b.answerCallback(ctx, botInstance, query.ID, "📝 请发送新的配置值", false)
+			b.editMessage(ctx, chatID, messageID, html.EscapeString(message), b.configMenuService.BuildInputCancelKeyboard(userID))
+			return
+		}
+	}
 
 	// 回应回调查询（显示提示消息）
 	if message != "" {
@@ -136,25 +145,7 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 
 	// 如果需要更新菜单，重新构建并编辑消息
 	if shouldUpdateMenu {
-		keyboard, err := b.configMenuService.BuildMainMenu(ctx, group, items)
-		if err != nil {
-			logger.L().Errorf("Failed to rebuild config menu: %v", err)
-			return
-		}
-
-		menuText := b.buildConfigMenuText(ctx, group)
-
-		_, err = botInstance.EditMessageText(ctx, &bot.EditMessageTextParams{
-			ChatID:      chatID,
-			MessageID:   messageID,
-			Text:        menuText,
-			ParseMode:   botModels.ParseModeHTML,
-			ReplyMarkup: keyboard,
-		})
-
-		if err != nil {
-			logger.L().Errorf("Failed to update config menu: %v", err)
-		}
+		b.refreshConfigMenu(ctx, group, items, chatID, messageID)
 	}
 
 	// 处理特殊操作：关闭菜单
@@ -164,11 +155,124 @@ func (b *Bot) handleConfigCallback(ctx context.Context, botInstance *bot.Bot, up
 			MessageID: messageID,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to delete config menu: %v", err)
+			logger.Ctx(ctx).Errorf("Failed to delete config menu: %v", err)
 		}
 	}
 }
 
+// configChange 单个配置项的变更
+type configChange struct {
+	item   models.ConfigItem
+	before string
+	after  string
+}
+
+// diffConfigItems 对比修改前后的群组配置，只返回展示值真正变化的配置项
+func diffConfigItems(items []models.ConfigItem, before, after *models.Group) []configChange {
+	var changes []configChange
+	for _, item := range items {
+		oldValue, newValue := describeConfigValue(item, before), describeConfigValue(item, after)
+		if oldValue != newValue {
+			changes = append(changes, configChange{item: item, before: oldValue, after: newValue})
+		}
+	}
+	return changes
+}
+
+// describeConfigValue 返回配置项当前值的可读描述，动作型配置没有值
+func describeConfigValue(item models.ConfigItem, group *models.Group) string {
+	switch item.Type {
+	case models.ConfigTypeToggle:
+		if item.ToggleGetter == nil {
+			return ""
+		}
+		if item.ToggleGetter(group) {
+			return "已开启"
+		}
+		return "已关闭"
+	case models.ConfigTypeSelect:
+		if item.SelectGetter == nil {
+			return ""
+		}
+		value := item.SelectGetter(group)
+		for _, opt := range item.SelectOptions {
+			if opt.Value == value {
+				return opt.Label
+			}
+		}
+		return value
+	case models.ConfigTypeInput:
+		if item.InputGetter == nil {
+			return ""
+		}
+		return item.InputGetter(group)
+	default:
+		return ""
+	}
+}
+
+// formatConfigChangeNotice 构建配置变更回显：开关展示「已开启/已关闭」，其他类型展示新旧值
+func formatConfigChangeNotice(changes []configChange, operator botModels.User) string {
+	var sb strings.Builder
+	sb.WriteString("⚙️ <b>配置已变更</b>\n")
+	for _, change := range changes {
+		if change.item.Type == models.ConfigTypeToggle {
+			sb.WriteString(fmt.Sprintf("%s %s %s\n", change.item.Icon, html.EscapeString(change.item.Name), change.after))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s %s：%s → %s\n", change.item.Icon, html.EscapeString(change.item.Name),
+			html.EscapeString(change.before), html.EscapeString(change.after)))
+	}
+	sb.WriteString("操作人：" + userMention(operator))
+	return sb.String()
+}
+
+// announceConfigChanges 在群内回显配置变更并写入审计日志，无变化时不发送
+func (b *Bot) announceConfigChanges(ctx context.Context, chatID int64, operator botModels.User, changes []configChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		logger.Ctx(ctx).Infof("Config audit: chat_id=%d operator=%d config=%s before=%q after=%q",
+			chatID, operator.ID, change.item.ID, change.before, change.after)
+	}
+	b.sendMessage(ctx, chatID, formatConfigChangeNotice(changes, operator))
+}
+
+// messageRetentionApplyTimeout 重算群组已有消息过期时间的超时
+const messageRetentionApplyTimeout = 2 * time.Minute
+
+// applyMessageRetention 群组消息保留期变更后在后台重算已有消息的过期时间，消息量大时可能耗时较久
+func (b *Bot) applyMessageRetention(ctx context.Context, chatID int64) {
+	go func() {
+		applyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), messageRetentionApplyTimeout)
+		defer cancel()
+		if _, _, err := b.messageService.ApplyChatRetention(applyCtx, chatID); err != nil {
+			logger.Ctx(ctx).Warnf("Failed to apply message retention: chat_id=%d err=%v", chatID, err)
+		}
+	}()
+}
+
+// refreshConfigMenu 将指定消息重新渲染为配置主菜单
+func (b *Bot) refreshConfigMenu(ctx context.Context, group *models.Group, items []models.ConfigItem, chatID int64, messageID int) {
+	keyboard, err := b.configMenuService.BuildMainMenu(ctx, group, items)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to rebuild config menu: %v", err)
+		return
+	}
+
+	_, err = b.bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        b.buildConfigMenuText(ctx, group),
+		ParseMode:   botModels.ParseModeHTML,
+		ReplyMarkup: keyboard,
+	})
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to update config menu: %v", err)
+	}
+}
+
 // answerCallback 回应 callback query（显示顶部提示）
 func (b *Bot) answerCallback(ctx context.Context, botInstance *bot.Bot, callbackQueryID, text string, showAlert bool) {
 	params := &bot.AnswerCallbackQueryParams{
@@ -182,7 +286,7 @@ func (b *Bot) answerCallback(ctx context.Context, botInstance *bot.Bot, callback
 	}
 	_, err := botInstance.AnswerCallbackQuery(ctx, params)
 	if err != nil {
-		logger.L().Errorf("Failed to answer callback query: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
 	}
 }
 
@@ -192,8 +296,16 @@ func (b *Bot) buildConfigMenuText(ctx context.Context, group *models.Group) stri
 
 	menuText += fmt.Sprintf("当前群等级：%s\n", formatGroupTierLabel(group.Tier))
 
+	if group.Note != "" || len(group.Tags) > 0 {
+		menuText += fmt.Sprintf("🏷 %s\n", formatGroupNoteSuffix(group.Note, group.Tags))
+	}
+
 	if group.Settings.MerchantID != 0 {
-		menuText += fmt.Sprintf("🏪 商户号: <code>%d</code>\n", group.Settings.MerchantID)
+		menuText += fmt.Sprintf("🏪 商户号: <code>%d</code>", group.Settings.MerchantID)
+		if bound := models.BoundMerchantIDs(group.Settings); len(bound) > 1 {
+			menuText += fmt.Sprintf("（共绑定 %d 个）", len(bound))
+		}
+		menuText += "\n"
 	}
 
 	if len(group.Settings.InterfaceBindings) > 0 {
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestDiffConfigItemsReportsOnlyChangedItems(t *testing.T) {
+	items := (&Bot{}).getConfigItems()
+	before := &models.Group{Settings: models.GroupSettings{CalculatorEnabled: true, CryptoFloatRate: 0.12, Timezone: "Asia/Shanghai"}}
+	after := *before
+	after.Settings.CalculatorEnabled = false
+	after.Settings.CryptoFloatRate = 0.08
+
+	changes := diffConfigItems(items, before, &after)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+	if changes[0].item.ID != "calculator_enabled" || changes[0].after != "已关闭" {
+		t.Fatalf("unexpected toggle change: %+v", changes[0])
+	}
+	if changes[1].item.ID != "crypto_float_rate" || changes[1].before != "0.12" || changes[1].after != "0.08" {
+		t.Fatalf("unexpected select change: %+v", changes[1])
+	}
+
+	if diffConfigItems(items, before, before) != nil {
+		t.Fatal("expected no changes for identical settings")
+	}
+
+	notice := formatConfigChangeNotice(changes, botModels.User{ID: 42, FirstName: "<Alice>"})
+	for _, want := range []string{"计算器功能 已关闭", "USDT浮动费率：0.12 → 0.08", `<a href="tg://user?id=42">&lt;Alice&gt;</a>`} {
+		if !strings.Contains(notice, want) {
+			t.Fatalf("expected notice to contain %q, got %q", want, notice)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// deletedMessagesListLimit /deleted 命令展示的最大条数
+const deletedMessagesListLimit = 20
+
+// Telegram 限制说明：
+// Bot API 不会向普通 Bot 推送群组/私聊中的消息删除事件，只有通过 Telegram Business
+// 连接到商家账号的 Bot 才会收到 deleted_business_messages。因此删除留档仅覆盖 Business
+// 会话：Bot 先记录 business_message 到 messages，收到删除事件时再从 messages 还原内容。
+// 普通群组中被删除的消息无法被感知，请使用群组「最近操作」日志（保留 48 小时）取证。
+
+// handleBusinessMessage 记录 Business 连接中的消息，供删除后留档还原
+func (b *Bot) handleBusinessMessage(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.BusinessMessage
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	sentAt := time.Unix(int64(msg.Date), 0)
+	if msg.Text != "" {
+		textInfo := &service.TextMessageInfo{
+			TelegramMessageID: int64(msg.ID),
+			ChatID:            msg.Chat.ID,
+			UserID:            msg.From.ID,
+			Text:              msg.Text,
+			Entities:          messageEntities(msg.Entities),
+			SentAt:            sentAt,
+		}
+		if msg.ReplyToMessage != nil {
+			textInfo.ReplyToMessageID = int64(msg.ReplyToMessage.ID)
+		}
+		if err := b.messageService.HandleTextMessage(ctx, textInfo); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to record business message: chat_id=%d message_id=%d err=%v", msg.Chat.ID, msg.ID, err)
+		}
+		return
+	}
+
+	messageType, fileID := businessMediaInfo(msg)
+	if messageType == "" {
+		return
+	}
+	mediaInfo := &service.MediaMessageInfo{
+		TelegramMessageID: int64(msg.ID),
+		ChatID:            msg.Chat.ID,
+		UserID:            msg.From.ID,
+		MessageType:       messageType,
+		Caption:           msg.Caption,
+		MediaFileID:       fileID,
+		SentAt:            sentAt,
+	}
+	if _, err := b.messageService.HandleMediaMessage(ctx, mediaInfo); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to record business media message: chat_id=%d message_id=%d err=%v", msg.Chat.ID, msg.ID, err)
+	}
+}
+
+// handleDeletedBusinessMessages 处理 Business 连接推送的消息删除事件
+func (b *Bot) handleDeletedBusinessMessages(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	deleted := update.DeletedBusinessMessages
+	if deleted == nil || len(deleted.MessageIDs) == 0 {
+		return
+	}
+
+	messageIDs := make([]int64, 0, len(deleted.MessageIDs))
+	for _, id := range deleted.MessageIDs {
+		messageIDs = append(messageIDs, int64(id))
+	}
+
+	archived, err := b.messageService.ArchiveDeletedMessages(ctx, deleted.Chat.ID, messageIDs,
+		models.DeletedMessageSourceBusiness, time.Now())
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to archive deleted business messages: chat_id=%d err=%v", deleted.Chat.ID, err)
+		return
+	}
+	logger.Ctx(ctx).Infof("Deleted business messages handled: chat_id=%d reported=%d archived=%d",
+		deleted.Chat.ID, len(messageIDs), archived)
+}
+
+// handleDeletedMessages 处理 /deleted 命令：群组内查看本群留档，私聊查看全部留档
+// 注意：管理员权限由 RequireAdmin 中间件完成；私聊的全局视图跨群可见，额外要求系统权限（PermSystem）
+func (b *Bot) handleDeletedMessages(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	chatID := msg.Chat.ID
+	scopeChatID := chatID
+	if msg.Chat.Type == botModels.ChatTypePrivate {
+		if !b.checkPermission(ctx, botInstance, update, models.PermSystem) {
+			return
+		}
+		scopeChatID = 0
+	}
+
+	records, err := b.messageService.ListDeletedMessages(ctx, scopeChatID, deletedMessagesListLimit)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	loc := models.DefaultLocation()
+	if scopeChatID != 0 {
+		if group, err := b.groupService.GetGroupInfo(ctx, chatID); err == nil && group != nil {
+			loc = models.GroupLocation(group.Settings)
+		}
+	}
+
+	senders := make(map[int64]string)
+	for _, record := range records {
+		if _, ok := senders[record.UserID]; ok || record.UserID == 0 {
+			continue
+		}
+		senders[record.UserID] = b.lookupUserDisplayName(ctx, record.UserID)
+	}
+
+	b.sendMessage(ctx, chatID, formatDeletedMessages(records, senders, scopeChatID == 0, loc), msg.ID)
+}
+
+// formatDeletedMessages 格式化被删除消息留档列表
+func formatDeletedMessages(records []*models.DeletedMessage, senders map[int64]string, showChat bool, loc *time.Location) string {
+	if len(records) == 0 {
+		return "🗑 暂无被删除消息留档\n\nℹ️ 受 Telegram 限制，仅能留档通过 Business 连接收到删除事件的消息"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🗑 最近被删除的消息（%d 条）\n", len(records)))
+
+	for i, record := range records {
+		sender := senders[record.UserID]
+		if sender == "" {
+			sender = fmt.Sprintf("用户 %d", record.UserID)
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%d. %s 删除 · %s · %s\n",
+			i+1,
+			record.DeletedAt.In(loc).Format("01-02 15:04"),
+			html.EscapeString(sender),
+			messageTypeLabel(record.MessageType)))
+		if showChat {
+			sb.WriteString(fmt.Sprintf("聊天：<code>%d</code>\n", record.ChatID))
+		}
+		sb.WriteString(fmt.Sprintf("发送于 %s\n", record.SentAt.In(loc).Format("01-02 15:04")))
+
+		content := record.Text
+		if content == "" {
+			content = record.Caption
+		}
+		if content != "" {
+			sb.WriteString(messageSearchSnippet(content, "", 200) + "\n")
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// businessMediaInfo 提取 Business 媒体消息的类型与文件 ID
+func businessMediaInfo(msg *botModels.Message) (string, string) {
+	switch {
+	case len(msg.Photo) > 0:
+		return models.MessageTypePhoto, msg.Photo[len(msg.Photo)-1].FileID
+	case msg.Video != nil:
+		return models.MessageTypeVideo, msg.Video.FileID
+	case msg.Document != nil:
+		return models.MessageTypeDocument, msg.Document.FileID
+	case msg.Voice != nil:
+		return models.MessageTypeVoice, msg.Voice.FileID
+	case msg.Audio != nil:
+		return models.MessageTypeAudio, msg.Audio.FileID
+	case msg.Sticker != nil:
+		return models.MessageTypeSticker, msg.Sticker.FileID
+	case msg.Animation != nil:
+		return models.MessageTypeAnimation, msg.Animation.FileID
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestFormatDeletedMessages(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	records := []*models.DeletedMessage{
+		{
+			ChatID:      -100,
+			UserID:      7,
+			MessageType: models.MessageTypeText,
+			Text:        "<违规> 内容",
+			SentAt:      time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC),
+			DeletedAt:   time.Date(2024, 11, 20, 1, 5, 0, 0, time.UTC),
+		},
+		{
+			ChatID:      -100,
+			UserID:      8,
+			MessageType: models.MessageTypePhoto,
+			Caption:     "截图",
+			SentAt:      time.Date(2024, 11, 20, 2, 0, 0, 0, time.UTC),
+			DeletedAt:   time.Date(2024, 11, 20, 2, 1, 0, 0, time.UTC),
+		},
+	}
+
+	text := formatDeletedMessages(records, map[int64]string{7: "Alice"}, true, loc)
+	for _, want := range []string{
+		"最近被删除的消息（2 条）",
+		"1. 11-20 09:05 删除 · Alice · 文本",
+		"聊天：<code>-100</code>",
+		"&lt;违规&gt; 内容",
+		"2. 11-20 10:01 删除 · 用户 8 · 图片",
+		"截图",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output, got %s", want, text)
+		}
+	}
+
+	if empty := formatDeletedMessages(nil, nil, false, loc); !strings.Contains(empty, "Business") {
+		t.Fatalf("expected limitation hint for empty list, got %s", empty)
+	}
+}
+
+// deletedListMessageService 记录 ListDeletedMessages 的查询范围
+type deletedListMessageService struct {
+	service.MessageService
+	scopes []int64
+}
+
+func (s *deletedListMessageService) ListDeletedMessages(ctx context.Context, chatID int64, limit int) ([]*models.DeletedMessage, error) {
+	s.scopes = append(s.scopes, chatID)
+	return nil, nil
+}
+
+func TestDeletedMessagesPrivateViewRequiresOwner(t *testing.T) {
+	tgBot, sent := newRecordingTelegramBot(t)
+	messages := &deletedListMessageService{}
+	b := &Bot{
+		bot:               tgBot,
+		messageService:    messages,
+		userService:       &permissionUserService{admins: map[int64]bool{1: true}, owners: map[int64]bool{2: true}},
+		permissionDenials: newPermissionDenialTracker(time.Hour, 5),
+	}
+	private := func(userID int64) *botModels.Update {
+		return &botModels.Update{Message: &botModels.Message{
+			ID:   10,
+			Chat: botModels.Chat{ID: userID, Type: botModels.ChatTypePrivate},
+			From: &botModels.User{ID: userID},
+			Text: "/deleted",
+		}}
+	}
+
+	// 普通管理员在私聊中不能查看跨群留档
+	b.handleDeletedMessages(context.Background(), tgBot, private(1))
+	if len(messages.scopes) != 0 || b.permissionDenials.stats().Total != 1 {
+		t.Fatalf("expected plain admin to be refused, scopes=%v", messages.scopes)
+	}
+	if texts := sent(); len(texts) != 1 || strings.Contains(texts[0], "被删除") {
+		t.Fatalf("expected a single permission denied reply, got %q", texts)
+	}
+
+	b.handleDeletedMessages(context.Background(), tgBot, private(2))
+	if len(messages.scopes) != 1 || messages.scopes[0] != 0 {
+		t.Fatalf("expected owner to list all chats, scopes=%v", messages.scopes)
+	}
+}
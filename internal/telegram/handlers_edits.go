@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// handleMessageEdits 处理 /edits 命令：引用消息或指定消息 ID，查看其编辑历史
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleMessageEdits(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	messageID, ok := parseMessageEditsTarget(msg)
+	if !ok {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "用法：引用目标消息发送 /edits，或 /edits <消息ID>", msg.ID)
+		return
+	}
+
+	record, err := b.messageService.GetMessageEditHistory(ctx, msg.Chat.ID, messageID)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	loc := models.DefaultLocation()
+	if group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil && group != nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+
+	sender := ""
+	if record.UserID != 0 {
+		sender = b.lookupUserDisplayName(ctx, record.UserID)
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatMessageEditHistory(record, sender, loc), msg.ID)
+}
+
+// parseMessageEditsTarget 解析目标消息 ID：优先使用参数，其次使用引用的消息
+func parseMessageEditsTarget(msg *botModels.Message) (int64, bool) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) > 1 {
+		id, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || id <= 0 {
+			return 0, false
+		}
+		return id, true
+	}
+	if msg.ReplyToMessage != nil {
+		return int64(msg.ReplyToMessage.ID), true
+	}
+	return 0, false
+}
+
+// formatMessageEditHistory 格式化消息编辑历史
+func formatMessageEditHistory(record *models.Message, sender string, loc *time.Location) string {
+	if sender == "" {
+		sender = fmt.Sprintf("用户 %d", record.UserID)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✏️ 消息编辑历史（消息 ID %d）\n", record.TelegramMessageID))
+	sb.WriteString(fmt.Sprintf("发送人：%s\n", html.EscapeString(sender)))
+	sb.WriteString(fmt.Sprintf("发送时间：%s\n", record.SentAt.In(loc).Format("2006-01-02 15:04:05")))
+
+	if len(record.EditHistory) == 0 {
+		sb.WriteString("\n该消息没有编辑记录")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("共编辑 %d 次", len(record.EditHistory)))
+	if len(record.EditHistory) >= models.MaxMessageEditHistory {
+		sb.WriteString(fmt.Sprintf("（仅保留最近 %d 次）", models.MaxMessageEditHistory))
+	}
+	sb.WriteString("\n")
+
+	for i, entry := range record.EditHistory {
+		sb.WriteString(fmt.Sprintf("\n%d. %s 修改前：\n%s\n",
+			i+1, entry.EditedAt.In(loc).Format("01-02 15:04:05"), html.EscapeString(entry.Text)))
+	}
+
+	sb.WriteString("\n当前内容")
+	if record.EditedAt != nil {
+		sb.WriteString(fmt.Sprintf("（最后编辑于 %s）", record.EditedAt.In(loc).Format("01-02 15:04:05")))
+	}
+	sb.WriteString("：\n" + html.EscapeString(record.Text))
+
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseMessageEditsTarget(t *testing.T) {
+	if id, ok := parseMessageEditsTarget(&botModels.Message{Text: "/edits 123"}); !ok || id != 123 {
+		t.Fatalf("expected id from argument, got %d ok=%v", id, ok)
+	}
+	reply := &botModels.Message{Text: "/edits", ReplyToMessage: &botModels.Message{ID: 456}}
+	if id, ok := parseMessageEditsTarget(reply); !ok || id != 456 {
+		t.Fatalf("expected id from reply, got %d ok=%v", id, ok)
+	}
+	if _, ok := parseMessageEditsTarget(&botModels.Message{Text: "/edits abc"}); ok {
+		t.Fatalf("expected invalid argument to be rejected")
+	}
+	if _, ok := parseMessageEditsTarget(&botModels.Message{Text: "/edits"}); ok {
+		t.Fatalf("expected missing target to be rejected")
+	}
+}
+
+func TestFormatMessageEditHistory(t *testing.T) {
+	loc := time.UTC
+	lastEdit := time.Date(2024, 11, 20, 10, 10, 0, 0, loc)
+	record := &models.Message{
+		TelegramMessageID: 88,
+		UserID:            7,
+		Text:              "最终 <版本>",
+		SentAt:            time.Date(2024, 11, 20, 10, 0, 0, 0, loc),
+		EditedAt:          &lastEdit,
+		EditHistory: []models.MessageEdit{
+			{Text: "原始内容", EditedAt: time.Date(2024, 11, 20, 10, 5, 0, 0, loc)},
+			{Text: "第二版", EditedAt: lastEdit},
+		},
+	}
+
+	text := formatMessageEditHistory(record, "Alice", loc)
+	for _, want := range []string{
+		"消息 ID 88",
+		"发送人：Alice",
+		"共编辑 2 次",
+		"1. 11-20 10:05:00 修改前：\n原始内容",
+		"2. 11-20 10:10:00 修改前：\n第二版",
+		"当前内容（最后编辑于 11-20 10:10:00）：\n最终 &lt;版本&gt;",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output, got %s", want, text)
+		}
+	}
+
+	record.EditHistory = nil
+	if text := formatMessageEditHistory(record, "", loc); !strings.Contains(text, "没有编辑记录") || !strings.Contains(text, "用户 7") {
+		t.Fatalf("unexpected output for unedited message: %s", text)
+	}
+}
@@ -0,0 +1,110 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/features"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// featureStatus 单个功能在当前群组中的生效状态
+type featureStatus struct {
+	Name         string
+	Priority     int
+	Enabled      bool
+	TierMatched  bool
+	AllowedTiers []models.GroupTier
+}
+
+// Active 功能是否会在当前群组中处理消息
+func (s featureStatus) Active() bool {
+	return s.Enabled && s.TierMatched
+}
+
+// handleFeatures 处理 /features 命令，列出所有注册功能在本群的生效情况
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleFeatures(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to load group for /features: chat_id=%d err=%v", msg.Chat.ID, err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败，请稍后重试")
+		return
+	}
+
+	statuses := collectFeatureStatuses(ctx, b.featureManager, group)
+	b.sendMessage(ctx, msg.Chat.ID, formatFeatureStatuses(group, statuses), msg.ID)
+}
+
+// collectFeatureStatuses 遍历功能管理器，计算每个功能的启用与等级匹配状态
+func collectFeatureStatuses(ctx context.Context, manager *features.Manager, group *models.Group) []featureStatus {
+	tier := models.NormalizeGroupTier(group.Tier)
+	statuses := make([]featureStatus, 0)
+
+	manager.ForEach(func(feature features.Feature) bool {
+		status := featureStatus{
+			Name:        feature.Name(),
+			Priority:    feature.Priority(),
+			Enabled:     feature.Enabled(ctx, group),
+			TierMatched: true,
+		}
+		if tierAware, ok := feature.(features.TierAwareFeature); ok {
+			status.AllowedTiers = tierAware.AllowedGroupTiers()
+			status.TierMatched = models.IsTierAllowed(tier, status.AllowedTiers)
+		}
+		statuses = append(statuses, status)
+		return true
+	})
+
+	return statuses
+}
+
+// formatFeatureStatuses 格式化功能列表
+func formatFeatureStatuses(group *models.Group, statuses []featureStatus) string {
+	tier := models.NormalizeGroupTier(group.Tier)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧩 <b>功能列表</b>（当前群类型：%s）\n", models.GroupTierDisplayName(tier)))
+
+	if len(statuses) == 0 {
+		sb.WriteString("\n暂无已注册的功能")
+		return sb.String()
+	}
+
+	active := 0
+	for _, status := range statuses {
+		if status.Active() {
+			active++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("生效 %d / 共 %d\n", active, len(statuses)))
+
+	for _, status := range statuses {
+		sb.WriteString(fmt.Sprintf("\n%s <code>%s</code>（优先级 %d）\n",
+			statusIcon(status.Active()), html.EscapeString(status.Name), status.Priority))
+		sb.WriteString(fmt.Sprintf("   启用：%s  等级：%s（%s）\n",
+			statusIcon(status.Enabled),
+			statusIcon(status.TierMatched),
+			models.FormatAllowedTierList(status.AllowedTiers)))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func statusIcon(ok bool) string {
+	if ok {
+		return "✅"
+	}
+	return "❌"
+}
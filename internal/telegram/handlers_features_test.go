@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/features"
+	"go_bot/internal/telegram/features/calculator"
+	"go_bot/internal/telegram/features/merchant"
+	"go_bot/internal/telegram/features/upstream"
+	"go_bot/internal/telegram/models"
+)
+
+func TestCollectFeatureStatuses(t *testing.T) {
+	manager := features.NewManager(nil)
+	manager.Register(calculator.New())
+	manager.Register(merchant.New(nil, nil, nil))
+	manager.Register(upstream.NewSummaryFeature(nil))
+
+	group := &models.Group{
+		Tier: models.GroupTierMerchant,
+		Settings: models.GroupSettings{
+			CalculatorEnabled: true,
+			MerchantID:        1001,
+		},
+	}
+
+	statuses := collectFeatureStatuses(context.Background(), manager, group)
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(statuses))
+	}
+
+	byName := make(map[string]featureStatus, len(statuses))
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+
+	if status := byName["calculator"]; !status.Active() {
+		t.Fatalf("expected calculator to be active, got %+v", status)
+	}
+	if status := byName["upstream_summary"]; status.Enabled || status.TierMatched {
+		t.Fatalf("expected upstream summary to be disabled and tier mismatched, got %+v", status)
+	}
+
+	text := formatFeatureStatuses(group, statuses)
+	for _, want := range []string{"当前群类型：商户群", "生效 2 / 共 3", "❌ <code>upstream_summary</code>"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected output to contain %q, got %q", want, text)
+		}
+	}
+}
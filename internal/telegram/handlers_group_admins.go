@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const groupAdminUsage = "用法：/add_group_admin <user_id>、/del_group_admin <user_id>，也可回复对方消息发送命令"
+
+// handleAddGroupAdmin 处理 /add_group_admin 命令：授予本群管理员（仅群主或全局管理员）
+func (b *Bot) handleAddGroupAdmin(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	b.updateGroupAdmin(ctx, botInstance, update, true)
+}
+
+// handleRemoveGroupAdmin 处理 /del_group_admin 命令：撤销本群管理员（仅群主或全局管理员）
+func (b *Bot) handleRemoveGroupAdmin(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	b.updateGroupAdmin(ctx, botInstance, update, false)
+}
+
+func (b *Bot) updateGroupAdmin(ctx context.Context, botInstance *bot.Bot, update *botModels.Update, add bool) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	targetID, err := parseGroupAdminTarget(msg)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	if err := b.canManageGroupAdmins(ctx, botInstance, msg.Chat.ID, msg.From.ID, targetID, add); err != nil {
+		logger.Ctx(ctx).Infof("Permission denied: perm=group_admin user_id=%d username=%s chat_id=%d chat=%q command=%q",
+			msg.From.ID, msg.From.Username, msg.Chat.ID, msg.Chat.Title, truncateForDisplay(msg.Text, permissionCommandMaxRunes))
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	// 确保群组记录存在，新群首次设置管理员时自动创建
+	if _, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	}); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return
+	}
+
+	if add {
+		err = b.groupService.AddGroupAdmin(ctx, msg.Chat.ID, targetID)
+	} else {
+		err = b.groupService.RemoveGroupAdmin(ctx, msg.Chat.ID, targetID)
+	}
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	logger.Ctx(ctx).Infof("Group admin updated: chat_id=%d target=%d add=%t operator=%d", msg.Chat.ID, targetID, add, msg.From.ID)
+	if add {
+		b.sendSuccessMessage(ctx, msg.Chat.ID, fmt.Sprintf("已将用户 %d 设为本群管理员（仅在本群生效）", targetID), msg.ID)
+		return
+	}
+	b.sendSuccessMessage(ctx, msg.Chat.ID, fmt.Sprintf("已撤销用户 %d 的本群管理员", targetID), msg.ID)
+}
+
+// handleListGroupAdmins 处理 /group_admins 命令（Admin+），列出本群的群级管理员
+func (b *Bot) handleListGroupAdmins(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return
+	}
+	if len(group.Settings.AdminIDs) == 0 {
+		b.sendMessage(ctx, msg.Chat.ID, "📝 本群暂无群级管理员\n"+safeHTML(groupAdminUsage), msg.ID)
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("👥 <b>本群管理员</b>（仅在本群生效）\n\n")
+	for i, id := range group.Settings.AdminIDs {
+		name := "未知用户"
+		if user, err := b.userService.GetUserInfo(ctx, id); err == nil && user != nil {
+			name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			if user.Username != "" {
+				name += " (@" + user.Username + ")"
+			}
+		}
+		text.WriteString(safeHTMLf("%d. %s - ID: %d\n", i+1, name, id))
+	}
+	b.sendMessage(ctx, msg.Chat.ID, text.String(), msg.ID)
+}
+
+// canManageGroupAdmins 群主（Telegram 群组创建者）或全局管理员可以设置本群管理员，群级管理员不能再授权他人；
+// 群主本身不具备管理员权限，不能把自己设为群级管理员（避免自我授权）
+func (b *Bot) canManageGroupAdmins(ctx context.Context, botInstance *bot.Bot, chatID, userID, targetID int64, add bool) error {
+	denied := fmt.Errorf("只有群主或全局管理员可以设置本群管理员")
+	if isAdmin, err := b.userService.CheckAdminPermission(ctx, userID, 0); err == nil && isAdmin {
+		return nil
+	}
+	if botInstance == nil {
+		return denied
+	}
+
+	member, err := botInstance.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: chatID, UserID: userID})
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to get chat member for group admin check: chat_id=%d user_id=%d err=%v", chatID, userID, err)
+		return denied
+	}
+	if member.Type != botModels.ChatMemberTypeOwner {
+		return denied
+	}
+	if add && targetID == userID {
+		return fmt.Errorf("群主不能将自己设为本群管理员，请联系全局管理员")
+	}
+	return nil
+}
+
+// parseGroupAdminTarget 从命令参数或被回复消息中解析目标用户 ID
+func parseGroupAdminTarget(msg *botModels.Message) (int64, error) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) >= 2 {
+		targetID, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || targetID <= 0 {
+			return 0, fmt.Errorf("无效的用户 ID")
+		}
+		return targetID, nil
+	}
+
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil {
+		if reply.From.IsBot {
+			return 0, fmt.Errorf("不能将机器人设为本群管理员")
+		}
+		return reply.From.ID, nil
+	}
+	return 0, fmt.Errorf("%s", groupAdminUsage)
+}
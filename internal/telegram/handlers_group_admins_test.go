@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"testing"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseGroupAdminTarget(t *testing.T) {
+	reply := &botModels.Message{From: &botModels.User{ID: 77}}
+	tests := []struct {
+		name    string
+		msg     *botModels.Message
+		want    int64
+		wantErr bool
+	}{
+		{"explicit id", &botModels.Message{Text: "/add_group_admin 123"}, 123, false},
+		{"explicit id wins over reply", &botModels.Message{Text: "/add_group_admin 123", ReplyToMessage: reply}, 123, false},
+		{"reply target", &botModels.Message{Text: "/add_group_admin", ReplyToMessage: reply}, 77, false},
+		{"invalid id", &botModels.Message{Text: "/del_group_admin abc"}, 0, true},
+		{"bot reply", &botModels.Message{Text: "/add_group_admin", ReplyToMessage: &botModels.Message{From: &botModels.User{ID: 9, IsBot: true}}}, 0, true},
+		{"missing target", &botModels.Message{Text: "/add_group_admin"}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseGroupAdminTarget(tt.msg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %d err=%v", tt.name, got, err)
+		}
+	}
+}
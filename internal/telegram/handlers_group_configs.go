@@ -0,0 +1,253 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	exportConfigsCommand = "/export_configs"
+	importConfigsCommand = "/import_configs"
+	// importConfigsMaxBytes 导入文件大小上限
+	importConfigsMaxBytes = 5 << 20
+	// importConfigsDownloadTimeout 从 Telegram 下载导入文件的超时
+	importConfigsDownloadTimeout = 30 * time.Second
+	// importConfigsPreviewLimit 结果中逐条列出的群组数量上限
+	importConfigsPreviewLimit = 20
+	importConfigsUsage        = "用法：发送导出的 JSON 文件并附带说明 /import_configs（或回复该文件发送），默认仅预览差异；确认无误后使用 /import_configs apply 写入。群级管理员不会导入，商户号绑定默认保留当前值，需导入时追加 merchants"
+)
+
+// handleExportConfigs 处理 /export_configs 命令（system），把全部群组配置导出为 JSON 文件
+func (b *Bot) handleExportConfigs(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	export, err := b.groupService.ExportGroupConfigs(ctx)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to marshal group config export: %v", err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "导出配置失败", msg.ID)
+		return
+	}
+
+	filename := fmt.Sprintf("group_configs_%s.json", export.ExportedAt.In(models.DefaultLocation()).Format("20060102_150405"))
+	caption := fmt.Sprintf("📦 已导出 %d 个群组配置（不含凭据、备注与统计数据）\n导入：发送该文件并附带说明 <code>%s</code>", len(export.Groups), importConfigsCommand)
+	logger.Ctx(ctx).Infof("Group configs export sent: user_id=%d groups=%d bytes=%d", msg.From.ID, len(export.Groups), len(data))
+	b.sendDocument(ctx, msg.Chat.ID, filename, data, caption, msg.ID)
+}
+
+// handleImportConfigs 处理 /import_configs 命令（仅 Owner），默认 dry-run 预览差异，带 apply 时写入
+func (b *Bot) handleImportConfigs(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+	// 导入会批量改写全部群的配置，比导出（system）要求更高
+	operator, err := b.userService.GetUserInfo(ctx, msg.From.ID)
+	if err != nil || operator == nil || !operator.IsOwner() {
+		logger.Ctx(ctx).Infof("Group configs import denied: user_id=%d", msg.From.ID)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "仅 Owner 可以导入群组配置", msg.ID)
+		return
+	}
+
+	apply, merchants, ok := parseImportConfigsArgs(commandText(msg))
+	if !ok {
+		b.sendErrorMessage(ctx, msg.Chat.ID, html.EscapeString(importConfigsUsage), msg.ID)
+		return
+	}
+	document := msg.Document
+	if document == nil && msg.ReplyToMessage != nil {
+		document = msg.ReplyToMessage.Document
+	}
+	if document == nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, html.EscapeString(importConfigsUsage), msg.ID)
+		return
+	}
+	if document.FileSize > importConfigsMaxBytes {
+		b.sendErrorMessage(ctx, msg.Chat.ID, fmt.Sprintf("文件过大（上限 %d MB）", importConfigsMaxBytes>>20), msg.ID)
+		return
+	}
+
+	data, err := b.downloadTelegramFile(ctx, botInstance, document.FileID, importConfigsMaxBytes, importConfigsDownloadTimeout)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to download import file: file_id=%s, error=%v", document.FileID, err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "下载配置文件失败", msg.ID)
+		return
+	}
+	export, err := models.ParseGroupConfigExport(data)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	result, err := b.groupService.ImportGroupConfigs(ctx, export, service.GroupConfigImportOptions{
+		DryRun:           !apply,
+		IncludeMerchants: merchants,
+		OperatorID:       msg.From.ID,
+		OperatorUsername: msg.From.Username,
+		OperatorName:     strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName),
+	})
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	logger.Ctx(ctx).Infof("Group configs import handled: user_id=%d apply=%t merchants=%t changed=%d applied=%d", msg.From.ID, apply, merchants, len(result.Changes), result.Applied)
+	b.sendMessage(ctx, msg.Chat.ID, formatGroupConfigImportResult(result), msg.ID)
+}
+
+// isImportConfigsCommand 判断消息是否为 /import_configs（可写在文本中，也可作为文件的说明）
+func isImportConfigsCommand(msg *botModels.Message) bool {
+	if msg == nil {
+		return false
+	}
+	fields := strings.Fields(commandText(msg))
+	return len(fields) > 0 && fields[0] == importConfigsCommand
+}
+
+// commandText 返回消息文本，纯文件消息返回其说明
+func commandText(msg *botModels.Message) string {
+	if msg.Text != "" {
+		return msg.Text
+	}
+	return msg.Caption
+}
+
+// parseImportConfigsArgs 解析 /import_configs [apply|dry-run] [merchants]，ok 为 false 表示参数无效
+// merchants 表示确认同时导入商户号绑定
+func parseImportConfigsArgs(text string) (apply, merchants, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 3 {
+		return false, false, false
+	}
+	var mode bool
+	for _, field := range fields[1:] {
+		switch strings.ToLower(field) {
+		case "apply":
+			if mode {
+				return false, false, false
+			}
+			apply, mode = true, true
+		case "dry-run", "dryrun", "preview":
+			if mode {
+				return false, false, false
+			}
+			mode = true
+		case "merchants":
+			if merchants {
+				return false, false, false
+			}
+			merchants = true
+		default:
+			return false, false, false
+		}
+	}
+	return apply, merchants, true
+}
+
+// downloadTelegramFile 通过 getFile 获取下载地址并读取文件内容，超过 maxBytes 时返回错误；timeout 限制下载耗时
+func (b *Bot) downloadTelegramFile(ctx context.Context, botInstance *bot.Bot, fileID string, maxBytes int64, timeout time.Duration) ([]byte, error) {
+	file, err := botInstance.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, botInstance.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// formatGroupConfigImportResult 渲染导入预览/结果：差异群组与字段、跳过的群 ID、写入失败原因
+func formatGroupConfigImportResult(result *service.GroupConfigImportResult) string {
+	var text strings.Builder
+	if result.DryRun {
+		text.WriteString("🔍 <b>配置导入预览（未写入）</b>\n")
+	} else {
+		text.WriteString("📥 <b>配置导入结果</b>\n")
+	}
+	text.WriteString(fmt.Sprintf("文件群组：%d ｜ 有差异：%d ｜ 无变化：%d ｜ 当前环境不存在：%d\n",
+		result.Total, len(result.Changes), result.Unchanged, len(result.Missing)))
+	if !result.DryRun {
+		text.WriteString(fmt.Sprintf("写入成功：%d ｜ 失败：%d\n", result.Applied, len(result.Failed)))
+	}
+
+	if len(result.Changes) > 0 {
+		text.WriteString("\n<b>差异</b>\n")
+		for i, change := range result.Changes {
+			if i >= importConfigsPreviewLimit {
+				text.WriteString(fmt.Sprintf("… 另有 %d 个群组\n", len(result.Changes)-importConfigsPreviewLimit))
+				break
+			}
+			title := change.Title
+			if title == "" {
+				title = "(未命名群组)"
+			}
+			text.WriteString(fmt.Sprintf("• %s（<code>%d</code>）：%s\n",
+				html.EscapeString(title), change.GroupID, html.EscapeString(strings.Join(change.Fields, "、"))))
+		}
+	}
+
+	if len(result.Missing) > 0 {
+		ids := make([]string, 0, min(len(result.Missing), importConfigsPreviewLimit))
+		for _, id := range result.Missing[:min(len(result.Missing), importConfigsPreviewLimit)] {
+			ids = append(ids, fmt.Sprintf("<code>%d</code>", id))
+		}
+		text.WriteString("\n<b>已跳过（当前环境不存在）</b>\n")
+		text.WriteString(strings.Join(ids, "、"))
+		if len(result.Missing) > importConfigsPreviewLimit {
+			text.WriteString(fmt.Sprintf(" 等 %d 个", len(result.Missing)))
+		}
+		text.WriteString("\n")
+	}
+
+	if len(result.Failed) > 0 {
+		text.WriteString("\n<b>写入失败</b>\n")
+		for _, failure := range result.Failed {
+			text.WriteString(fmt.Sprintf("• <code>%d</code>：%s\n", failure.GroupID, html.EscapeString(failure.Reason)))
+		}
+	}
+
+	if result.DryRun && len(result.Changes) > 0 {
+		text.WriteString(fmt.Sprintf("\n确认无误后对同一文件发送 <code>%s apply</code> 写入", importConfigsCommand))
+	}
+	return strings.TrimRight(text.String(), "\n")
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseImportConfigsArgs(t *testing.T) {
+	tests := []struct {
+		text      string
+		apply     bool
+		merchants bool
+		ok        bool
+	}{
+		{text: "/import_configs", apply: false, ok: true},
+		{text: "/import_configs apply", apply: true, ok: true},
+		{text: "/import_configs dry-run", apply: false, ok: true},
+		{text: "/import_configs apply merchants", apply: true, merchants: true, ok: true},
+		{text: "/import_configs merchants", merchants: true, ok: true},
+		{text: "/import_configs now", ok: false},
+		{text: "/import_configs apply extra", ok: false},
+		{text: "/import_configs apply dry-run", ok: false},
+	}
+	for _, tt := range tests {
+		apply, merchants, ok := parseImportConfigsArgs(tt.text)
+		if apply != tt.apply || merchants != tt.merchants || ok != tt.ok {
+			t.Fatalf("parseImportConfigsArgs(%q) = %t, %t, %t; want %t, %t, %t", tt.text, apply, merchants, ok, tt.apply, tt.merchants, tt.ok)
+		}
+	}
+}
+
+func TestIsImportConfigsCommand(t *testing.T) {
+	if !isImportConfigsCommand(&botModels.Message{Caption: "/import_configs apply", Document: &botModels.Document{FileID: "f"}}) {
+		t.Fatal("expected caption command to match")
+	}
+	if !isImportConfigsCommand(&botModels.Message{Text: "/import_configs"}) {
+		t.Fatal("expected text command to match")
+	}
+	if isImportConfigsCommand(&botModels.Message{Text: "/import_configsx"}) || isImportConfigsCommand(nil) {
+		t.Fatal("unexpected match")
+	}
+}
+
+func TestFormatGroupConfigImportResult(t *testing.T) {
+	preview := formatGroupConfigImportResult(&service.GroupConfigImportResult{
+		DryRun:    true,
+		Total:     3,
+		Unchanged: 1,
+		Changes:   []service.GroupConfigChange{{GroupID: -100, Title: "<A>", Fields: []string{"CryptoFloatRate", "Timezone"}}},
+		Missing:   []int64{-300},
+	})
+	for _, want := range []string{"预览（未写入）", "有差异：1", "&lt;A&gt;（<code>-100</code>）：CryptoFloatRate、Timezone", "<code>-300</code>", "/import_configs apply"} {
+		if !strings.Contains(preview, want) {
+			t.Fatalf("expected preview to contain %q, got %q", want, preview)
+		}
+	}
+
+	applied := formatGroupConfigImportResult(&service.GroupConfigImportResult{
+		Total:   1,
+		Changes: []service.GroupConfigChange{{GroupID: -100, Fields: []string{"Timezone"}}},
+		Failed:  []service.GroupConfigFailure{{GroupID: -100, Reason: "更新群组配置失败"}},
+	})
+	for _, want := range []string{"配置导入结果", "写入成功：0 ｜ 失败：1", "更新群组配置失败"} {
+		if !strings.Contains(applied, want) {
+			t.Fatalf("expected result to contain %q, got %q", want, applied)
+		}
+	}
+	if strings.Contains(applied, "apply") {
+		t.Fatalf("applied result should not suggest apply again: %q", applied)
+	}
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	groupStatsRefreshKeyword = "刷新"
+	// memberCountSyncTimeout 拉取 Telegram 实际成员数的超时
+	memberCountSyncTimeout = 5 * time.Second
+)
+
+// handleGroupStats 处理 /groupstats [刷新] 命令，查看群成员数与消息统计
+// 尚未校准或带「刷新」参数时先通过 GetChatMemberCount 拉取实际成员数
+func (b *Bot) handleGroupStats(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	refresh, ok := parseGroupStatsRefresh(msg.Text)
+	if !ok {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "用法：/groupstats [刷新]", msg.ID)
+		return
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	if err != nil || group == nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return
+	}
+
+	if refresh || group.Stats.MemberCountSyncedAt.IsZero() {
+		if _, err := b.syncMemberCount(ctx, botInstance, msg.Chat.ID); err != nil {
+			b.sendErrorMessage(ctx, msg.Chat.ID, "拉取群成员数失败，请稍后重试", msg.ID)
+			return
+		}
+		if refreshed, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil && refreshed != nil {
+			group = refreshed
+		}
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatGroupStats(group, models.GroupLocation(group.Settings)), msg.ID)
+}
+
+// parseGroupStatsRefresh 解析 /groupstats 参数，返回是否强制刷新
+func parseGroupStatsRefresh(text string) (bool, bool) {
+	fields := strings.Fields(text)
+	switch {
+	case len(fields) <= 1:
+		return false, true
+	case len(fields) == 2 && fields[1] == groupStatsRefreshKeyword:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// formatGroupStats 格式化群组统计
+func formatGroupStats(group *models.Group, loc *time.Location) string {
+	stats := group.Stats
+	var sb strings.Builder
+	sb.WriteString("📊 <b>群组统计</b>\n\n")
+
+	sb.WriteString(fmt.Sprintf("👥 成员数：%d", group.MemberCount))
+	if !stats.MemberCountSyncedAt.IsZero() {
+		sb.WriteString(fmt.Sprintf("（%s 校准）", stats.MemberCountSyncedAt.In(loc).Format("2006-01-02 15:04")))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("➕ 累计入群：%d 人\n", stats.MemberJoins))
+	sb.WriteString(fmt.Sprintf("➖ 累计退群：%d 人\n", stats.MemberLeaves))
+	sb.WriteString(fmt.Sprintf("💬 累计消息：%d 条\n", stats.TotalMessages))
+	if !stats.LastMessageAt.IsZero() {
+		sb.WriteString(fmt.Sprintf("🕒 最后消息：%s\n", stats.LastMessageAt.In(loc).Format("2006-01-02 15:04")))
+	}
+	sb.WriteString(fmt.Sprintf("\n成员数随入群/退群事件增减，发送「/groupstats %s」按 Telegram 实际人数重新校准", groupStatsRefreshKeyword))
+	return sb.String()
+}
+
+// trackMemberChange 入群/退群后维护群成员数，尚未校准过的群顺带拉取实际人数作为基准
+func (b *Bot) trackMemberChange(ctx context.Context, botInstance *bot.Bot, chat botModels.Chat, joined, left int) {
+	if !isGroupChatType(chat.Type) || (joined == 0 && left == 0) {
+		return
+	}
+
+	if err := b.groupService.RecordMemberChange(ctx, chat.ID, joined, left); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to record member change: chat_id=%d joined=%d left=%d err=%v", chat.ID, joined, left, err)
+		return
+	}
+
+	group, err := b.groupService.GetGroupInfo(ctx, chat.ID)
+	if err != nil || group == nil || !group.Stats.MemberCountSyncedAt.IsZero() {
+		return
+	}
+	if _, err := b.syncMemberCount(ctx, botInstance, chat.ID); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to sync member count baseline: chat_id=%d err=%v", chat.ID, err)
+	}
+}
+
+// syncMemberCount 通过 GetChatMemberCount 拉取实际成员数并写入群组记录
+func (b *Bot) syncMemberCount(ctx context.Context, botInstance *bot.Bot, chatID int64) (int, error) {
+	if botInstance == nil {
+		return 0, fmt.Errorf("bot instance unavailable")
+	}
+
+	syncCtx, cancel := context.WithTimeout(ctx, memberCountSyncTimeout)
+	defer cancel()
+
+	count, err := botInstance.GetChatMemberCount(syncCtx, &bot.GetChatMemberCountParams{ChatID: chatID})
+	if err != nil {
+		return 0, fmt.Errorf("get chat member count: %w", err)
+	}
+	if err := b.groupService.SyncMemberCount(ctx, chatID, count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,54 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestParseGroupStatsRefresh(t *testing.T) {
+	cases := []struct {
+		text    string
+		refresh bool
+		ok      bool
+	}{
+		{"/groupstats", false, true},
+		{"/groupstats 刷新", true, true},
+		{"/groupstats refresh", false, false},
+		{"/groupstats 刷新 1", false, false},
+	}
+	for _, tc := range cases {
+		refresh, ok := parseGroupStatsRefresh(tc.text)
+		if refresh != tc.refresh || ok != tc.ok {
+			t.Fatalf("%q: expected (%v,%v), got (%v,%v)", tc.text, tc.refresh, tc.ok, refresh, ok)
+		}
+	}
+}
+
+func TestFormatGroupStats(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	group := &models.Group{
+		MemberCount: 128,
+		Stats: models.GroupStats{
+			TotalMessages:       5000,
+			LastMessageAt:       time.Date(2025, 3, 1, 2, 30, 0, 0, time.UTC),
+			MemberJoins:         30,
+			MemberLeaves:        7,
+			MemberCountSyncedAt: time.Date(2025, 3, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}
+
+	text := formatGroupStats(group, loc)
+	for _, want := range []string{"成员数：128（2025-03-01 09:00 校准）", "累计入群：30 人", "累计退群：7 人", "累计消息：5000 条", "最后消息：2025-03-01 10:30"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	group.Stats.MemberCountSyncedAt = time.Time{}
+	if text := formatGroupStats(group, loc); strings.Contains(text, "校准）") {
+		t.Fatalf("unsynced count should not show sync time:\n%s", text)
+	}
+}
@@ -0,0 +1,26 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// handleInterfaceUnbindCallback 处理「解绑接口」多项匹配时的选择/取消按钮（Admin+），结果替换原候选消息
+func (b *Bot) handleInterfaceUnbindCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	msg := query.Message.Message
+	if msg == nil || b.upstreamFeature == nil {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的请求", true)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "", false)
+	text := b.upstreamFeature.HandleUnbindCallback(ctx, msg.Chat.ID, query.From.ID, query.Data)
+	b.editMessage(ctx, msg.Chat.ID, msg.ID, text, nil)
+}
@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	noteUsage = "用法：/note <备注>、/note clear\n不带参数时查看当前备注与标签"
+	tagUsage  = "用法：/tag add 标签1 标签2、/tag del 标签、/tag clear\n不带参数时查看当前备注与标签"
+)
+
+// handleGroupNote 处理 /note 命令（Owner），设置或清除本群的管理备注
+func (b *Bot) handleGroupNote(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	group, ok := b.loadLabelGroup(ctx, msg)
+	if !ok {
+		return
+	}
+
+	_, arg, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		b.sendMessage(ctx, msg.Chat.ID, formatGroupLabels(group)+"\n\n"+html.EscapeString(noteUsage), msg.ID)
+		return
+	}
+
+	note := arg
+	if strings.EqualFold(arg, "clear") {
+		note = ""
+	}
+	if err := b.groupService.UpdateGroupLabels(ctx, group.TelegramID, note, group.Tags); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	if note == "" {
+		b.sendSuccessMessage(ctx, msg.Chat.ID, "已清除本群备注", msg.ID)
+		return
+	}
+	b.sendSuccessMessage(ctx, msg.Chat.ID, safeHTMLf("已更新本群备注：%s", strings.TrimSpace(note)), msg.ID)
+}
+
+// handleGroupTags 处理 /tag 命令（Owner），增删本群的管理标签
+func (b *Bot) handleGroupTags(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	group, ok := b.loadLabelGroup(ctx, msg)
+	if !ok {
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) < 2 {
+		b.sendMessage(ctx, msg.Chat.ID, formatGroupLabels(group)+"\n\n"+html.EscapeString(tagUsage), msg.ID)
+		return
+	}
+
+	tags, err := applyTagCommand(group.Tags, strings.ToLower(fields[1]), fields[2:])
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+	if err := b.groupService.UpdateGroupLabels(ctx, group.TelegramID, group.Note, tags); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	normalized, _ := models.NormalizeGroupTags(tags)
+	if len(normalized) == 0 {
+		b.sendSuccessMessage(ctx, msg.Chat.ID, "本群已无标签", msg.ID)
+		return
+	}
+	b.sendSuccessMessage(ctx, msg.Chat.ID, safeHTMLf("本群标签：%s", models.FormatGroupTags(normalized)), msg.ID)
+}
+
+// applyTagCommand 根据子命令计算新的标签列表，长度与数量校验由 service 统一完成
+func applyTagCommand(current []string, action string, args []string) ([]string, error) {
+	switch action {
+	case "add":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s", tagUsage)
+		}
+		return append(slices.Clone(current), args...), nil
+	case "del", "rm", "remove":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s", tagUsage)
+		}
+		removed := make([]string, 0, len(args))
+		for _, arg := range args {
+			removed = append(removed, models.NormalizeGroupTag(arg))
+		}
+		tags := make([]string, 0, len(current))
+		for _, tag := range current {
+			if !slices.Contains(removed, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		return tags, nil
+	case "clear":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s", tagUsage)
+	}
+}
+
+// loadLabelGroup 获取（必要时创建）当前群组记录；仅限群聊已由注册时的命令作用域保证
+func (b *Bot) loadLabelGroup(ctx context.Context, msg *botModels.Message) (*models.Group, bool) {
+	if msg == nil {
+		return nil, false
+	}
+
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	})
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get group for labels: chat_id=%d, error=%v", msg.Chat.ID, err)
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return nil, false
+	}
+	return group, true
+}
+
+// formatGroupLabels 展示群组备注与标签
+func formatGroupLabels(group *models.Group) string {
+	note := "（未设置）"
+	if group.Note != "" {
+		note = html.EscapeString(group.Note)
+	}
+	tags := "（未设置）"
+	if len(group.Tags) > 0 {
+		tags = html.EscapeString(models.FormatGroupTags(group.Tags))
+	}
+	return fmt.Sprintf("🏷 <b>群组备注</b>\n备注：%s\n标签：%s", note, tags)
+}
+
+// formatGroupNoteSuffix 在群组名称后附加的备注/标签说明，均为空时返回空字符串
+func formatGroupNoteSuffix(note string, tags []string) string {
+	parts := make([]string, 0, 2)
+	if note != "" {
+		parts = append(parts, note)
+	}
+	if len(tags) > 0 {
+		parts = append(parts, models.FormatGroupTags(tags))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "【" + html.EscapeString(strings.Join(parts, " ")) + "】"
+}
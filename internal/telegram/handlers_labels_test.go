@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApplyTagCommand(t *testing.T) {
+	current := []string{"vip", "测试"}
+
+	added, err := applyTagCommand(current, "add", []string{"新客", "vip"})
+	if err != nil || !slices.Equal(added, []string{"vip", "测试", "新客", "vip"}) {
+		t.Fatalf("unexpected add result: %v err=%v", added, err)
+	}
+	if !slices.Equal(current, []string{"vip", "测试"}) {
+		t.Fatalf("current tags must not be mutated: %v", current)
+	}
+
+	removed, err := applyTagCommand(current, "del", []string{"#VIP"})
+	if err != nil || !slices.Equal(removed, []string{"测试"}) {
+		t.Fatalf("unexpected del result: %v err=%v", removed, err)
+	}
+
+	cleared, err := applyTagCommand(current, "clear", nil)
+	if err != nil || cleared != nil {
+		t.Fatalf("unexpected clear result: %v err=%v", cleared, err)
+	}
+
+	for _, action := range []string{"add", "del", "set"} {
+		if _, err := applyTagCommand(current, action, nil); err == nil {
+			t.Fatalf("expected usage error for %q", action)
+		}
+	}
+}
+
+func TestFormatGroupNoteSuffix(t *testing.T) {
+	if got := formatGroupNoteSuffix("", nil); got != "" {
+		t.Fatalf("expected empty suffix, got %q", got)
+	}
+	if got := formatGroupNoteSuffix("<张三>", []string{"vip"}); got != "【&lt;张三&gt; #vip】" {
+		t.Fatalf("unexpected suffix: %q", got)
+	}
+}
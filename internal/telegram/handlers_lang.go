@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"go_bot/internal/telegram/i18n"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// langAutoOption /lang 恢复自动选择的参数
+const langAutoOption = "auto"
+
+// handleLang 处理 /lang 命令：查看或切换个人偏好语言，偏好写入用户记录后用于私聊消息
+func (b *Bot) handleLang(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	fields := strings.Fields(msg.Text)
+	if len(fields) < 2 {
+		b.sendMessage(ctx, msg.Chat.ID, b.buildLangStatus(ctx, msg), msg.ID)
+		return
+	}
+
+	option := strings.ToLower(fields[1])
+	if option != langAutoOption && !i18n.IsSupported(option) {
+		lang := b.preferredLang(ctx, msg.From)
+		b.sendErrorMessage(ctx, msg.Chat.ID, i18n.T(lang, "lang.invalid", html.EscapeString(fields[1]), formatSupportedLangs()), msg.ID)
+		return
+	}
+
+	// 偏好写在用户记录上，未 /start 过的用户先登记
+	if err := b.userService.RegisterOrUpdateUser(ctx, &service.TelegramUserInfo{
+		TelegramID:   msg.From.ID,
+		Username:     msg.From.Username,
+		FirstName:    msg.From.FirstName,
+		LastName:     msg.From.LastName,
+		LanguageCode: msg.From.LanguageCode,
+		IsPremium:    msg.From.IsPremium,
+	}); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, i18n.T(userLang(msg.From), "lang.failed"), msg.ID)
+		return
+	}
+
+	preference := option
+	if option == langAutoOption {
+		preference = ""
+	}
+	if err := b.userService.SetPreferredLanguage(ctx, msg.From.ID, preference); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, i18n.T(userLang(msg.From), "lang.failed"), msg.ID)
+		return
+	}
+
+	if preference == "" {
+		b.sendSuccessMessage(ctx, msg.Chat.ID, i18n.T(userLang(msg.From), "lang.reset"), msg.ID)
+		return
+	}
+	lang := i18n.Lang(preference)
+	b.sendSuccessMessage(ctx, msg.Chat.ID, i18n.T(lang, "lang.updated", i18n.DisplayName(lang)), msg.ID)
+}
+
+// buildLangStatus 展示当前偏好语言与可选项，群内额外提示群组语言设置
+func (b *Bot) buildLangStatus(ctx context.Context, msg *botModels.Message) string {
+	lang := userLang(msg.From)
+	source := "lang.source.auto"
+	if stored, err := b.userService.GetPreferredLanguage(ctx, msg.From.ID); err == nil && stored != "" {
+		lang = i18n.Normalize(stored)
+		source = "lang.source.preferred"
+	}
+
+	text := i18n.T(lang, "lang.current", i18n.DisplayName(lang), i18n.T(lang, source), formatSupportedLangs())
+	if isGroupChatType(msg.Chat.Type) {
+		text += "\n" + i18n.T(lang, "lang.group_hint")
+	}
+	return text
+}
+
+// formatSupportedLangs 列出可选语言，例如 "zh（中文）、en（English）"
+func formatSupportedLangs() string {
+	options := make([]string, 0, len(i18n.Supported()))
+	for _, lang := range i18n.Supported() {
+		options = append(options, string(lang)+"（"+i18n.DisplayName(lang)+"）")
+	}
+	return strings.Join(options, "、")
+}
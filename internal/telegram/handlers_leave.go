@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	leaveCallbackPrefix = "leave:"
+	// leavePendingTTL 离群确认的有效期，超时后 Bot 保持在群
+	leavePendingTTL = 60 * time.Second
+	// leaveExpiredText 离群确认超时后的提示
+	leaveExpiredText = "⌛ 离群确认已超时，Bot 继续留在本群"
+)
+
+// pendingLeave 等待发起管理员确认的离群请求
+type pendingLeave struct {
+	chatID  int64
+	adminID int64
+}
+
+// handleLeave 处理 /leave 命令（让 Bot 离开群组），先发送确认按钮，确认后才真正退群
+func (b *Bot) handleLeave(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+
+	msg := update.Message
+	chatID := msg.Chat.ID
+
+	token := b.pendingLeaves.put(&pendingLeave{chatID: chatID, adminID: msg.From.ID}, leavePendingTTL)
+
+	prompt := fmt.Sprintf("⚠️ <b>确认让 Bot 离开本群？</b>\n\n离开后本群的商户号、接口绑定和功能配置会保留 %s，期间重新邀请 Bot 入群可自动恢复。\n仅 %s 可以确认，%s 内未确认将自动取消。",
+		formatDuration(models.GroupArchiveRetention), userMention(*msg.From), formatDuration(leavePendingTTL))
+	sent, err := b.sendMessageWithMarkupAndMessage(ctx, chatID, prompt, buildLeaveKeyboard(token), msg.ID)
+	if err != nil || sent == nil {
+		b.pendingLeaves.take(token)
+		return
+	}
+
+	// 超时未确认时收起按钮，明确告知 Bot 仍留在群内
+	time.AfterFunc(leavePendingTTL, func() {
+		if b.pendingLeaves.take(token) {
+			b.editMessage(context.Background(), chatID, sent.ID, leaveExpiredText, nil)
+		}
+	})
+}
+
+// handleLeaveCallback 处理离群确认/取消按钮
+func (b *Bot) handleLeaveCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	action, token, ok := parseLeaveCallback(query.Data)
+	if !ok || query.Message.Message == nil {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的请求", true)
+		return
+	}
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, exists := b.pendingLeaves.get(token)
+	if !exists || pending.chatID != chatID {
+		b.answerCallback(ctx, botInstance, query.ID, "确认已失效，Bot 将继续留在本群", true)
+		b.editMessage(ctx, chatID, messageID, leaveExpiredText, nil)
+		return
+	}
+	if pending.adminID != query.From.ID {
+		b.answerCallback(ctx, botInstance, query.ID, "只有发起 /leave 的管理员可以操作", true)
+		return
+	}
+	if !b.pendingLeaves.take(token) {
+		b.answerCallback(ctx, botInstance, query.ID, "请求已处理", true)
+		return
+	}
+
+	if action == "cancel" {
+		b.answerCallback(ctx, botInstance, query.ID, "已取消", false)
+		b.editMessage(ctx, chatID, messageID, "🚫 已取消离群，Bot 继续留在本群", nil)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "正在离开", false)
+	b.editMessage(ctx, chatID, messageID, "👋 再见！我将离开这个群组。", nil)
+	logger.Ctx(ctx).Infof("Leaving group on admin confirmation: chat_id=%d admin=%d", chatID, query.From.ID)
+
+	// 存档配置并标记 Bot 离开，保留期后由清理任务删除群组记录
+	if err := b.groupService.LeaveGroup(ctx, chatID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to mark group as left: chat_id=%d, error=%v", chatID, err)
+	}
+
+	// 让 Bot 离开群组
+	if _, err := botInstance.LeaveChat(ctx, &bot.LeaveChatParams{ChatID: chatID}); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to leave chat: chat_id=%d, error=%v", chatID, err)
+	}
+}
+
+func buildLeaveKeyboard(token string) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{
+				{Text: "✅ 确认离开", CallbackData: leaveCallbackPrefix + "confirm:" + token},
+				{Text: "❌ 取消", CallbackData: leaveCallbackPrefix + "cancel:" + token},
+			},
+		},
+	}
+}
+
+// parseLeaveCallback 解析回调数据：leave:<confirm|cancel>:<token>
+func parseLeaveCallback(data string) (string, string, bool) {
+	payload := strings.TrimPrefix(data, leaveCallbackPrefix)
+	action, token, found := strings.Cut(payload, ":")
+	if !found || token == "" {
+		return "", "", false
+	}
+	if action != "confirm" && action != "cancel" {
+		return "", "", false
+	}
+	return action, token, true
+}
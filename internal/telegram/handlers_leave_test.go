@@ -0,0 +1,15 @@
+package telegram
+
+import "testing"
+
+func TestParseLeaveCallback(t *testing.T) {
+	action, token, ok := parseLeaveCallback(leaveCallbackPrefix + "confirm:abc123")
+	if !ok || action != "confirm" || token != "abc123" {
+		t.Fatalf("unexpected parse: action=%q token=%q ok=%v", action, token, ok)
+	}
+	for _, data := range []string{"leave:confirm:", "leave:drop:abc", "leave:abc", leaveCallbackPrefix} {
+		if _, _, ok := parseLeaveCallback(data); ok {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	memberStatsDefaultDays = 7
+	memberStatsMaxDays     = 90
+	memberStatsLeaveLimit  = 20
+)
+
+// handleMemberStats 处理 /members [天数] 命令，统计近期成员变动
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleMemberStats(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	days, err := parseMemberStatsDays(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	loc := models.DefaultLocation()
+	if group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil && group != nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+
+	// 统计窗口包含今天：从 (days-1) 天前的零点开始
+	now := time.Now().In(loc)
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -(days - 1))
+
+	stats, err := b.memberEvents.GetMemberStats(ctx, msg.Chat.ID, since, memberStatsLeaveLimit)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatMemberStats(stats, days, loc), msg.ID)
+}
+
+// parseMemberStatsDays 解析统计天数参数，缺省为 7 天
+func parseMemberStatsDays(text string) (int, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return memberStatsDefaultDays, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(fields[1], "天"))
+	if err != nil || days < 1 || days > memberStatsMaxDays {
+		return 0, fmt.Errorf("天数需为 1-%d 之间的整数", memberStatsMaxDays)
+	}
+	return days, nil
+}
+
+// formatMemberStats 格式化成员变动统计
+func formatMemberStats(stats *service.MemberStats, days int, loc *time.Location) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("👥 近 %d 天成员变动（自 %s 起）\n\n", days, stats.Since.In(loc).Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("➕ 入群：%d 人\n", stats.Joined))
+	sb.WriteString(fmt.Sprintf("➖ 退群：%d 人\n", stats.Left))
+	sb.WriteString(fmt.Sprintf("📈 净增长：%+d 人\n", stats.Net))
+
+	if len(stats.RecentLeaves) == 0 {
+		sb.WriteString("\n期间无成员退群")
+		return sb.String()
+	}
+
+	sb.WriteString("\n<b>最近退群名单</b>")
+	if stats.Left > int64(len(stats.RecentLeaves)) {
+		sb.WriteString(fmt.Sprintf("（显示最近 %d 人）", len(stats.RecentLeaves)))
+	}
+	sb.WriteString("\n")
+	for i, event := range stats.RecentLeaves {
+		sb.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, event.OccurredAt.In(loc).Format("01-02 15:04"), memberEventDisplayName(event)))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func memberEventDisplayName(event *models.MemberEvent) string {
+	name := strings.TrimSpace(event.FirstName + " " + event.LastName)
+	if name == "" {
+		name = fmt.Sprintf("用户 %d", event.UserID)
+	}
+	name = html.EscapeString(name)
+	if event.Username != "" {
+		name += " (@" + html.EscapeString(event.Username) + ")"
+	}
+	return name
+}
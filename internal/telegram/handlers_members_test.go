@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+func TestParseMemberStatsDays(t *testing.T) {
+	cases := map[string]int{
+		"/members":     memberStatsDefaultDays,
+		"/members 30":  30,
+		"/members 3天":  3,
+		"/members  90": 90,
+	}
+	for text, want := range cases {
+		got, err := parseMemberStatsDays(text)
+		if err != nil || got != want {
+			t.Fatalf("parse %q: expected %d, got %d err=%v", text, want, got, err)
+		}
+	}
+
+	for _, text := range []string{"/members 0", "/members 91", "/members abc"} {
+		if _, err := parseMemberStatsDays(text); err == nil {
+			t.Fatalf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestFormatMemberStats(t *testing.T) {
+	loc := time.UTC
+	stats := &service.MemberStats{
+		Since:  time.Date(2024, 11, 14, 0, 0, 0, 0, loc),
+		Joined: 2,
+		Left:   5,
+		Net:    -3,
+		RecentLeaves: []*models.MemberEvent{
+			{UserID: 1, FirstName: "Bob", Username: "bob", OccurredAt: time.Date(2024, 11, 20, 9, 30, 0, 0, loc)},
+			{UserID: 2, OccurredAt: time.Date(2024, 11, 19, 8, 0, 0, 0, loc)},
+		},
+	}
+
+	text := formatMemberStats(stats, 7, loc)
+	for _, want := range []string{
+		"近 7 天成员变动（自 2024-11-14 起）",
+		"➕ 入群：2 人",
+		"➖ 退群：5 人",
+		"📈 净增长：-3 人",
+		"（显示最近 2 人）",
+		"1. 11-20 09:30 Bob (@bob)",
+		"2. 11-19 08:00 用户 2",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output, got %s", want, text)
+		}
+	}
+
+	stats.Net = 2
+	stats.RecentLeaves = nil
+	if text := formatMemberStats(stats, 7, loc); !strings.Contains(text, "净增长：+2 人") || !strings.Contains(text, "期间无成员退群") {
+		t.Fatalf("unexpected output: %s", text)
+	}
+}
@@ -0,0 +1,150 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	mentionsCommand        = "/mentions"
+	mentionsCallbackPrefix = "mentions:"
+)
+
+// mentionUsernamePattern Telegram 用户名格式（5-32 位字母、数字、下划线，放宽到 3 位兼容旧用户名）
+var mentionUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// mentionTarget 被提及的用户：用户名与无用户名提及时的用户 ID，任一可为空值
+type mentionTarget struct {
+	username string
+	userID   int64
+}
+
+// handleMentions 处理 /mentions [@用户名] 命令（Admin+）：列出本群提及指定用户的消息，不带参数时查询提及自己的消息
+func (b *Bot) handleMentions(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	target, err := parseMentionsTarget(msg.Text, msg.From)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+
+	text, markup, err := b.buildMentionsPage(ctx, msg.Chat.ID, target, 1)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	if _, err := b.sendMessageWithMarkupAndMessage(ctx, msg.Chat.ID, text, markup, msg.ID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to send mentions result: chat_id=%d err=%v", msg.Chat.ID, err)
+	}
+}
+
+// handleMentionsCallback 处理提及列表翻页
+func (b *Bot) handleMentionsCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	if !b.checkPermission(ctx, botInstance, update, models.PermAdmin) {
+		return
+	}
+
+	page, target, ok := parseMentionsCallback(query.Data)
+	if !ok {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的翻页请求", true)
+		return
+	}
+
+	msg := query.Message.Message
+	text, markup, err := b.buildMentionsPage(ctx, msg.Chat.ID, target, page)
+	if err != nil {
+		b.answerCallback(ctx, botInstance, query.ID, err.Error(), true)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "", false)
+	b.editMessage(ctx, msg.Chat.ID, msg.ID, text, markup)
+}
+
+// buildMentionsPage 查询提及消息并渲染指定页
+func (b *Bot) buildMentionsPage(ctx context.Context, chatID int64, target mentionTarget, page int) (string, botModels.ReplyMarkup, error) {
+	messages, err := b.messageService.ListMentionMessages(ctx, chatID, target.username, target.userID, messageSearchMaxResults+1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result := newMessageSearchResult(b.mentionTargetLabel(ctx, target), messages)
+	result.Mention = true
+	text, totalPages := b.renderMessageSearchPage(ctx, chatID, result, page)
+	return text, buildMessagePageKeyboard(page, totalPages, func(targetPage int) string {
+		return formatMentionsCallback(targetPage, target)
+	}), nil
+}
+
+// mentionTargetLabel 被提及用户的展示名：优先 @用户名，否则查已登记用户的名称
+func (b *Bot) mentionTargetLabel(ctx context.Context, target mentionTarget) string {
+	if target.username != "" {
+		return "@" + target.username
+	}
+	if name := b.lookupUserDisplayName(ctx, target.userID); name != "" {
+		return name
+	}
+	return fmt.Sprintf("用户 %d", target.userID)
+}
+
+// parseMentionsTarget 解析 /mentions 参数：@用户名或用户名；不带参数时为发起人自己（用户名 + 用户 ID）
+func parseMentionsTarget(text string, from *botModels.User) (mentionTarget, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return mentionTarget{username: models.NormalizeMention(from.Username), userID: from.ID}, nil
+	}
+	if len(fields) > 2 {
+		return mentionTarget{}, fmt.Errorf("用法：%s [@用户名]", mentionsCommand)
+	}
+
+	username := strings.TrimPrefix(fields[1], "@")
+	if !mentionUsernamePattern.MatchString(username) {
+		return mentionTarget{}, fmt.Errorf("用户名格式不正确，用法：%s [@用户名]", mentionsCommand)
+	}
+	return mentionTarget{username: models.NormalizeMention(username)}, nil
+}
+
+// formatMentionsCallback 生成翻页回调：mentions:<page>:<user_id>:<username>
+func formatMentionsCallback(page int, target mentionTarget) string {
+	return fmt.Sprintf("%s%d:%d:%s", mentionsCallbackPrefix, page, target.userID, target.username)
+}
+
+// parseMentionsCallback 解析翻页回调
+func parseMentionsCallback(data string) (int, mentionTarget, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(data, mentionsCallbackPrefix), ":", 3)
+	if len(parts) != 3 {
+		return 0, mentionTarget{}, false
+	}
+	page, err := strconv.Atoi(parts[0])
+	if err != nil || page < 1 {
+		return 0, mentionTarget{}, false
+	}
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, mentionTarget{}, false
+	}
+	target := mentionTarget{username: parts[2], userID: userID}
+	if target.username == "" && target.userID == 0 {
+		return 0, mentionTarget{}, false
+	}
+	return page, target, true
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseMentionsTarget(t *testing.T) {
+	from := &botModels.User{ID: 7, Username: "Alice"}
+	cases := []struct {
+		text    string
+		want    mentionTarget
+		wantErr bool
+	}{
+		{text: "/mentions", want: mentionTarget{username: "alice", userID: 7}},
+		{text: "/mentions @Bob_Bot", want: mentionTarget{username: "bob_bot"}},
+		{text: "/mentions carol", want: mentionTarget{username: "carol"}},
+		{text: "/mentions @a-b", wantErr: true},
+		{text: "/mentions @bob extra", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseMentionsTarget(tc.text, from)
+		if (err != nil) != tc.wantErr || (!tc.wantErr && got != tc.want) {
+			t.Fatalf("parse %q: expected %+v (err=%v), got %+v err=%v", tc.text, tc.want, tc.wantErr, got, err)
+		}
+	}
+
+	// 没有用户名的用户查询自己时只按用户 ID 匹配
+	got, err := parseMentionsTarget("/mentions", &botModels.User{ID: 9})
+	if err != nil || got != (mentionTarget{userID: 9}) {
+		t.Fatalf("expected user id only target, got %+v err=%v", got, err)
+	}
+}
+
+func TestMentionsCallbackRoundTrip(t *testing.T) {
+	for _, target := range []mentionTarget{
+		{username: "alice", userID: 7},
+		{username: "a_very_long_username_of_32_chars"},
+		{userID: 123456789012},
+	} {
+		data := formatMentionsCallback(3, target)
+		if len(data) > 64 {
+			t.Fatalf("callback data too long: %q", data)
+		}
+		page, got, ok := parseMentionsCallback(data)
+		if !ok || page != 3 || got != target {
+			t.Fatalf("round trip %+v: got page=%d target=%+v ok=%v", target, page, got, ok)
+		}
+	}
+
+	for _, data := range []string{"mentions:0:1:a", "mentions:1:x:a", "mentions:1:0:", "mentions:1"} {
+		if _, _, ok := parseMentionsCallback(data); ok {
+			t.Fatalf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestFormatMentionsPage(t *testing.T) {
+	messages := []*models.Message{
+		{UserID: 42, Text: "请 @alice 看一下订单", SentAt: time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)},
+	}
+	result := newMessageSearchResult("@alice", messages)
+	result.Mention = true
+	text, pages := formatMessageSearchPage(result, 1, map[int64]string{42: "Bob"}, func(m *models.Message) string {
+		return m.SentAt.Format("01-02 15:04")
+	})
+	if pages != 1 || !strings.Contains(text, "📣 提及 @alice 的消息共 1 条") || !strings.Contains(text, "<b>@alice</b>") || !strings.Contains(text, "Bob") {
+		t.Fatalf("unexpected mentions page (%d pages):\n%s", pages, text)
+	}
+
+	empty, _ := formatMessageSearchPage(messageSearchResult{Keyword: "@alice", Mention: true}, 1, nil, nil)
+	if empty != "📣 本群暂无提及 @alice 的消息" {
+		t.Fatalf("unexpected empty text: %q", empty)
+	}
+}
@@ -0,0 +1,156 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// messageTypeOrder 统计输出中消息类型的展示顺序
+var messageTypeOrder = []string{
+	models.MessageTypeText,
+	models.MessageTypePhoto,
+	models.MessageTypeVideo,
+	models.MessageTypeDocument,
+	models.MessageTypeVoice,
+	models.MessageTypeAudio,
+	models.MessageTypeSticker,
+	models.MessageTypeAnimation,
+	models.MessageTypeChannelPost,
+}
+
+// messageTypeLabels 消息类型中文名称
+var messageTypeLabels = map[string]string{
+	models.MessageTypeText:        "文本",
+	models.MessageTypePhoto:       "图片",
+	models.MessageTypeVideo:       "视频",
+	models.MessageTypeDocument:    "文件",
+	models.MessageTypeVoice:       "语音",
+	models.MessageTypeAudio:       "音频",
+	models.MessageTypeSticker:     "贴纸",
+	models.MessageTypeAnimation:   "动图",
+	models.MessageTypeChannelPost: "频道消息",
+}
+
+// messageStatsPeriod 一个时间维度的统计结果
+type messageStatsPeriod struct {
+	Label  string
+	Counts map[string]int64
+}
+
+// handleMessageStats 处理 /msgstats 命令，按类型与时间维度统计本群消息
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleMessageStats(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	loc := models.DefaultLocation()
+	if group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil && group != nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+
+	todayStart, weekStart := messageStatsBoundaries(time.Now(), loc)
+	periods := []messageStatsPeriod{
+		{Label: "今日"},
+		{Label: "本周"},
+		{Label: "全部"},
+	}
+	sinces := []time.Time{todayStart, weekStart, {}}
+
+	for i := range periods {
+		counts, err := b.messageService.GetMessageTypeStats(ctx, msg.Chat.ID, sinces[i])
+		if err != nil {
+			logger.Ctx(ctx).Errorf("Failed to load message stats: chat_id=%d period=%s err=%v", msg.Chat.ID, periods[i].Label, err)
+			b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()))
+			return
+		}
+		periods[i].Counts = counts
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatMessageStats(msg.Chat.Title, periods), msg.ID)
+}
+
+// messageStatsBoundaries 返回群组时区下今日零点与本周一零点
+func messageStatsBoundaries(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	todayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	offset := (int(local.Weekday()) + 6) % 7 // 周一为一周开始
+	weekStart := todayStart.AddDate(0, 0, -offset)
+	return todayStart, weekStart
+}
+
+// formatMessageStats 将各时间维度统计格式化为文本
+func formatMessageStats(title string, periods []messageStatsPeriod) string {
+	var sb strings.Builder
+	sb.WriteString("📊 消息统计")
+	if title != "" {
+		sb.WriteString(" - " + html.EscapeString(title))
+	}
+	sb.WriteString("\n")
+
+	for _, period := range periods {
+		var total int64
+		for _, count := range period.Counts {
+			total += count
+		}
+
+		sb.WriteString(fmt.Sprintf("\n<b>【%s】</b>共 %d 条\n", period.Label, total))
+		if total == 0 {
+			sb.WriteString("暂无消息\n")
+			continue
+		}
+
+		for _, msgType := range sortedMessageTypes(period.Counts) {
+			count := period.Counts[msgType]
+			if count == 0 {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s：%d（%.1f%%）\n",
+				messageTypeLabel(msgType), count, float64(count)*100/float64(total)))
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// sortedMessageTypes 按固定顺序排列已知类型，未知类型按名称追加在后
+func sortedMessageTypes(counts map[string]int64) []string {
+	types := make([]string, 0, len(counts))
+	known := make(map[string]struct{}, len(messageTypeOrder))
+	for _, msgType := range messageTypeOrder {
+		known[msgType] = struct{}{}
+		if _, ok := counts[msgType]; ok {
+			types = append(types, msgType)
+		}
+	}
+
+	var extra []string
+	for msgType := range counts {
+		if _, ok := known[msgType]; !ok {
+			extra = append(extra, msgType)
+		}
+	}
+	sort.Strings(extra)
+	return append(types, extra...)
+}
+
+func messageTypeLabel(msgType string) string {
+	if label, ok := messageTypeLabels[msgType]; ok {
+		return label
+	}
+	if msgType == "" {
+		return "未知"
+	}
+	return html.EscapeString(msgType)
+}
@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestMessageStatsBoundaries(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2024-11-20 为周三；UTC 17:30 对应东八区次日 01:30（周四）
+	now := time.Date(2024, 11, 20, 17, 30, 0, 0, time.UTC)
+
+	today, week := messageStatsBoundaries(now, loc)
+	if !today.Equal(time.Date(2024, 11, 21, 0, 0, 0, 0, loc)) {
+		t.Fatalf("unexpected today start: %s", today)
+	}
+	if !week.Equal(time.Date(2024, 11, 18, 0, 0, 0, 0, loc)) {
+		t.Fatalf("unexpected week start: %s", week)
+	}
+
+	sunday := time.Date(2024, 11, 24, 12, 0, 0, 0, loc)
+	_, week = messageStatsBoundaries(sunday, loc)
+	if !week.Equal(time.Date(2024, 11, 18, 0, 0, 0, 0, loc)) {
+		t.Fatalf("expected sunday to belong to the week starting monday, got %s", week)
+	}
+}
+
+func TestFormatMessageStats(t *testing.T) {
+	periods := []messageStatsPeriod{
+		{Label: "今日", Counts: map[string]int64{}},
+		{Label: "全部", Counts: map[string]int64{
+			models.MessageTypePhoto: 1,
+			models.MessageTypeText:  3,
+			"poll":                  0,
+		}},
+	}
+
+	text := formatMessageStats("测试群", periods)
+	if !strings.Contains(text, "<b>【今日】</b>共 0 条\n暂无消息") {
+		t.Fatalf("expected empty period hint, got %s", text)
+	}
+	if !strings.Contains(text, "<b>【全部】</b>共 4 条\n文本：3（75.0%）\n图片：1（25.0%）") {
+		t.Fatalf("expected ordered type breakdown, got %s", text)
+	}
+	if strings.Contains(text, "poll") {
+		t.Fatalf("expected zero-count types to be skipped, got %s", text)
+	}
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+var permUsage = "用法：/perm &lt;user_id&gt; [+权限 -权限 ...]\n不带权限参数时查看该用户的角色与生效权限\n可选权限：" + permissionNameList()
+
+// handleUserPermissions 处理 /perm 命令：查看或增减管理员的额外权限（需「管理员授权」权限）
+func (b *Bot) handleUserPermissions(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	targetID, add, remove, err := parsePermCommand(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+
+	var user *models.User
+	if len(add) == 0 && len(remove) == 0 {
+		user, err = b.userService.GetUserInfo(ctx, targetID)
+		if err != nil || user == nil {
+			b.sendErrorMessage(ctx, msg.Chat.ID, "用户不存在或查询失败", msg.ID)
+			return
+		}
+	} else {
+		user, err = b.userService.UpdateUserPermissions(ctx, targetID, add, remove, msg.From.ID)
+		if err != nil {
+			b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+			return
+		}
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatUserPermissions(user), msg.ID)
+}
+
+// parsePermCommand 解析 /perm <user_id> [+perm -perm ...]，不带符号的权限视为增加
+func parsePermCommand(text string) (int64, []string, []string, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return 0, nil, nil, fmt.Errorf("%s", permUsage)
+	}
+
+	targetID, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || targetID <= 0 {
+		return 0, nil, nil, fmt.Errorf("无效的用户 ID")
+	}
+
+	var add, remove []string
+	for _, field := range fields[2:] {
+		target := &add
+		switch {
+		case strings.HasPrefix(field, "+"):
+			field = field[1:]
+		case strings.HasPrefix(field, "-"):
+			field = field[1:]
+			target = &remove
+		}
+		perm, err := models.ParsePermission(field)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("%s\n可选权限：%s", html.EscapeString(err.Error()), permissionNameList())
+		}
+		*target = append(*target, perm)
+	}
+	return targetID, add, remove, nil
+}
+
+// parseGrantRole 解析 /grant 的角色参数，缺省为管理员
+func parseGrantRole(arg string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "", "admin", "管理员":
+		return models.RoleAdmin, nil
+	case "super", "super_admin", "超级管理员":
+		return models.RoleSuperAdmin, nil
+	default:
+		return "", fmt.Errorf("未知角色：%s，可选 admin 或 super", arg)
+	}
+}
+
+// formatUserPermissions 展示用户角色、生效权限及其中额外授予的部分
+func formatUserPermissions(user *models.User) string {
+	extra := make(map[string]bool, len(user.Permissions))
+	for _, perm := range user.Permissions {
+		extra[perm] = true
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🔐 <b>用户权限</b>\n\nID：<code>%d</code>\n角色：%s\n", user.TelegramID, models.RoleLabel(user.Role)))
+
+	effective := user.EffectivePermissions()
+	if len(effective) == 0 {
+		text.WriteString("生效权限：无")
+		return text.String()
+	}
+
+	text.WriteString("生效权限：\n")
+	for _, perm := range effective {
+		suffix := ""
+		if extra[perm] {
+			suffix = "（额外授予）"
+		}
+		text.WriteString(fmt.Sprintf("• %s <code>%s</code>%s\n", models.PermissionLabel(perm), perm, suffix))
+	}
+	return strings.TrimRight(text.String(), "\n")
+}
+
+// permissionNameList 可选权限列表，用于用法提示
+func permissionNameList() string {
+	names := make([]string, 0, len(models.AllPermissions))
+	for _, info := range models.AllPermissions {
+		names = append(names, fmt.Sprintf("%s（%s）", info.Name, info.Label))
+	}
+	return strings.Join(names, "、")
+}
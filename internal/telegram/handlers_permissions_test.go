@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/i18n"
+	"go_bot/internal/telegram/models"
+)
+
+func TestParsePermCommand(t *testing.T) {
+	id, add, remove, err := parsePermCommand("/perm 42 +finance -Broadcast system")
+	if err != nil || id != 42 {
+		t.Fatalf("unexpected result: id=%d err=%v", id, err)
+	}
+	if !reflect.DeepEqual(add, []string{models.PermFinance, models.PermSystem}) || !reflect.DeepEqual(remove, []string{models.PermBroadcast}) {
+		t.Fatalf("unexpected changes: add=%v remove=%v", add, remove)
+	}
+
+	if _, add, remove, err := parsePermCommand("/perm 42"); err != nil || len(add)+len(remove) != 0 {
+		t.Fatalf("expected view-only command, got add=%v remove=%v err=%v", add, remove, err)
+	}
+	for _, text := range []string{"/perm", "/perm abc", "/perm 42 +root"} {
+		if _, _, _, err := parsePermCommand(text); err == nil {
+			t.Fatalf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestParseGrantRole(t *testing.T) {
+	cases := map[string]string{"admin": models.RoleAdmin, "SUPER": models.RoleSuperAdmin, "超级管理员": models.RoleSuperAdmin}
+	for arg, want := range cases {
+		if got, err := parseGrantRole(arg); err != nil || got != want {
+			t.Fatalf("parseGrantRole(%q) = %q, %v; want %q", arg, got, err, want)
+		}
+	}
+	if _, err := parseGrantRole("owner"); err == nil {
+		t.Fatal("expected owner role to be rejected")
+	}
+}
+
+func TestFormatUserPermissions(t *testing.T) {
+	text := formatUserPermissions(&models.User{TelegramID: 7, Role: models.RoleAdmin, Permissions: []string{models.PermFinance}})
+	for _, want := range []string{"角色：管理员", "通用管理 <code>admin</code>", "财务数据 <code>finance</code>（额外授予）"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+	if text := formatUserPermissions(&models.User{TelegramID: 8, Role: models.RoleUser}); !strings.Contains(text, "生效权限：无") {
+		t.Fatalf("expected no permissions for regular user, got:\n%s", text)
+	}
+}
+
+func TestPermissionDeniedText(t *testing.T) {
+	if got := permissionDeniedText(i18n.LangZH, models.PermAdmin); got != i18n.T(i18n.LangZH, "middleware.admin_only") {
+		t.Fatalf("unexpected admin denial text: %q", got)
+	}
+	if got := permissionDeniedText(i18n.LangZH, models.PermFinance); got != "此命令需要「财务数据」权限" {
+		t.Fatalf("unexpected zh denial text: %q", got)
+	}
+	if got := permissionDeniedText(i18n.LangEN, models.PermFinance); !strings.Contains(got, `"finance"`) {
+		t.Fatalf("unexpected en denial text: %q", got)
+	}
+}
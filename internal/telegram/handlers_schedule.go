@@ -0,0 +1,192 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	scheduleAddCommand    = "/schedule_add"
+	scheduleDeleteCommand = "/schedule_del"
+	scheduleCronFields    = 5
+	scheduleSnippetLength = 40
+	scheduleAddUsage      = "用法：\n/schedule_add 09:00 &lt;内容&gt; - 每天定时发送\n/schedule_add cron 0 9 * * 1-5 &lt;内容&gt; - 按 cron 表达式（分 时 日 月 周）发送"
+)
+
+// handleScheduleAdd 处理 /schedule_add 命令，注册定时消息
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleScheduleAdd(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	kind, spec, text, ok := parseScheduleAddCommand(msg.Text)
+	if !ok {
+		b.sendMessage(ctx, msg.Chat.ID, scheduleAddUsage, msg.ID)
+		return
+	}
+
+	timezone := models.DefaultGroupTimezone
+	if group, err := b.groupService.GetGroupInfo(ctx, msg.Chat.ID); err == nil && group != nil {
+		timezone = models.GroupLocation(group.Settings).String()
+	}
+
+	var createdBy int64
+	if msg.From != nil {
+		createdBy = msg.From.ID
+	}
+
+	scheduled, err := b.scheduledMessages.Create(ctx, &service.ScheduledMessageInput{
+		ChatID:    msg.Chat.ID,
+		CreatedBy: createdBy,
+		Kind:      kind,
+		Spec:      spec,
+		Text:      text,
+		Timezone:  timezone,
+	})
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, html.EscapeString(err.Error()), msg.ID)
+		return
+	}
+	b.scheduledMessageScheduler.notify()
+
+	b.sendSuccessMessage(ctx, msg.Chat.ID, fmt.Sprintf("定时消息已注册\nID：<code>%s</code>\n规则：%s\n下次发送：%s",
+		scheduled.ID.Hex(), formatScheduleRule(scheduled),
+		scheduledMessageLocalTime(scheduled, scheduled.NextRunAt)), msg.ID)
+}
+
+// handleScheduleList 处理 /schedules 命令，列出本群定时消息
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleScheduleList(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	messages, err := b.scheduledMessages.ListByChat(ctx, msg.Chat.ID)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+
+	b.sendMessage(ctx, msg.Chat.ID, formatScheduledMessages(messages), msg.ID)
+}
+
+// handleScheduleDelete 处理 /schedule_del <ID> 命令，删除定时消息
+// 注意：权限检查由 RequireAdmin 中间件完成
+func (b *Bot) handleScheduleDelete(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(msg.Text, scheduleDeleteCommand))
+	if len(fields) != 1 {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "用法：/schedule_del &lt;ID&gt;（ID 可通过 /schedules 查看）", msg.ID)
+		return
+	}
+
+	if err := b.scheduledMessages.Delete(ctx, msg.Chat.ID, fields[0]); err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, safeHTML(err.Error()), msg.ID)
+		return
+	}
+	b.scheduledMessageScheduler.notify()
+
+	b.sendSuccessMessage(ctx, msg.Chat.ID, "定时消息已删除", msg.ID)
+}
+
+// parseScheduleAddCommand 解析 /schedule_add <HH:MM|cron 分 时 日 月 周> <内容>，内容保留原有换行
+func parseScheduleAddCommand(text string) (kind, spec, content string, ok bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, scheduleAddCommand) {
+		return "", "", "", false
+	}
+	rest := strings.TrimPrefix(trimmed, scheduleAddCommand)
+	if rest != "" && !unicode.IsSpace([]rune(rest)[0]) {
+		return "", "", "", false
+	}
+
+	first, rest := cutScheduleField(rest)
+	switch {
+	case first == "":
+		return "", "", "", false
+	case strings.EqualFold(first, models.ScheduleKindCron):
+		fields := make([]string, 0, scheduleCronFields)
+		for i := 0; i < scheduleCronFields; i++ {
+			var field string
+			field, rest = cutScheduleField(rest)
+			if field == "" {
+				return "", "", "", false
+			}
+			fields = append(fields, field)
+		}
+		kind, spec = models.ScheduleKindCron, strings.Join(fields, " ")
+	default:
+		kind, spec = models.ScheduleKindDaily, strings.Replace(first, "：", ":", 1)
+	}
+
+	content = strings.TrimSpace(rest)
+	if content == "" {
+		return "", "", "", false
+	}
+	return kind, spec, content, true
+}
+
+// cutScheduleField 切出第一个以空白分隔的字段，返回字段与剩余内容
+func cutScheduleField(text string) (string, string) {
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	end := strings.IndexFunc(trimmed, unicode.IsSpace)
+	if end < 0 {
+		return trimmed, ""
+	}
+	return trimmed[:end], trimmed[end:]
+}
+
+// formatScheduledMessages 格式化本群定时消息列表
+func formatScheduledMessages(messages []*models.ScheduledMessage) string {
+	if len(messages) == 0 {
+		return "📅 本群暂无定时消息\n\n使用 /schedule_add 注册"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📅 本群定时消息（%d/%d）\n", len(messages), service.MaxScheduledMessagesPerChat))
+	for i, message := range messages {
+		sb.WriteString(fmt.Sprintf("\n%d. <code>%s</code>\n", i+1, message.ID.Hex()))
+		sb.WriteString(fmt.Sprintf("⏰ %s\n", formatScheduleRule(message)))
+		sb.WriteString(fmt.Sprintf("⏭ 下次：%s\n", scheduledMessageLocalTime(message, message.NextRunAt)))
+		if message.LastRunAt != nil {
+			sb.WriteString(fmt.Sprintf("✅ 上次：%s\n", scheduledMessageLocalTime(message, *message.LastRunAt)))
+		}
+		sb.WriteString(fmt.Sprintf("📝 %s\n", html.EscapeString(truncateForDisplay(strings.ReplaceAll(message.Text, "\n", " "), scheduleSnippetLength))))
+	}
+	sb.WriteString("\n删除：/schedule_del &lt;ID&gt;")
+	return sb.String()
+}
+
+// formatScheduleRule 描述定时消息的触发规则
+func formatScheduleRule(message *models.ScheduledMessage) string {
+	timezone := message.Timezone
+	if timezone == "" {
+		timezone = models.DefaultGroupTimezone
+	}
+	if message.Kind == models.ScheduleKindDaily {
+		return fmt.Sprintf("每天 %s（%s）", html.EscapeString(message.Spec), html.EscapeString(timezone))
+	}
+	return fmt.Sprintf("cron <code>%s</code>（%s）", html.EscapeString(message.Spec), html.EscapeString(timezone))
+}
+
+// scheduledMessageLocalTime 将时间转换为定时消息时区下的显示格式
+func scheduledMessageLocalTime(message *models.ScheduledMessage, at time.Time) string {
+	return at.In(message.Location()).Format("2006-01-02 15:04")
+}
@@ -0,0 +1,74 @@
+package telegram
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const groupStatusCommand = "状态"
+
+// handleGroupStatus 处理「状态」命令（Admin+）：汇总群等级、已启用功能及各功能的状态片段
+func (b *Bot) handleGroupStatus(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil {
+		return
+	}
+
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	})
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, "获取群组信息失败", msg.ID)
+		return
+	}
+
+	enabled := enabledFeatureNames(filterConfigItemsByTier(b.getConfigItems(), group.Tier), group)
+	b.sendMessage(ctx, msg.Chat.ID, formatGroupStatus(group, enabled, b.featureManager.StatusLines(ctx, group)), msg.ID)
+}
+
+// enabledFeatureNames 列出已开启的开关型配置（图标 + 名称）
+func enabledFeatureNames(items []models.ConfigItem, group *models.Group) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type != models.ConfigTypeToggle || item.ToggleGetter == nil || !item.ToggleGetter(group) {
+			continue
+		}
+		names = append(names, item.Icon+" "+item.Name)
+	}
+	return names
+}
+
+// formatGroupStatus 拼装状态消息：基础信息、已启用功能、各功能状态片段
+func formatGroupStatus(group *models.Group, enabled, featureLines []string) string {
+	var text strings.Builder
+	text.WriteString("📋 <b>群组状态</b>\n\n")
+	if title := strings.TrimSpace(group.Title); title != "" {
+		text.WriteString("群组：" + html.EscapeString(title) + "\n")
+	}
+	text.WriteString("群等级：" + formatGroupTierLabel(group.Tier) + "\n")
+	if suffix := formatGroupNoteSuffix(group.Note, group.Tags); suffix != "" {
+		text.WriteString("🏷 " + suffix + "\n")
+	}
+
+	text.WriteString("\n<b>✅ 已启用功能</b>\n")
+	if len(enabled) == 0 {
+		text.WriteString("无\n")
+	} else {
+		text.WriteString(html.EscapeString(strings.Join(enabled, "、")) + "\n")
+	}
+
+	if len(featureLines) > 0 {
+		text.WriteString("\n" + strings.Join(featureLines, "\n"))
+	}
+	return strings.TrimRight(text.String(), "\n")
+}
@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestFormatGroupStatus(t *testing.T) {
+	group := &models.Group{
+		Title:    "测试<群>",
+		Tier:     models.GroupTierUpstream,
+		Settings: models.GroupSettings{CalculatorEnabled: true, AccountingEnabled: true},
+	}
+	items := []models.ConfigItem{
+		{Name: "计算器功能", Icon: "🧮", Type: models.ConfigTypeToggle, ToggleGetter: func(g *models.Group) bool { return g.Settings.CalculatorEnabled }},
+		{Name: "USDT价格查询", Icon: "💰", Type: models.ConfigTypeToggle, ToggleGetter: func(g *models.Group) bool { return g.Settings.CryptoEnabled }},
+		{Name: "收支记账", Icon: "📒", Type: models.ConfigTypeToggle, ToggleGetter: func(g *models.Group) bool { return g.Settings.AccountingEnabled }},
+		{Name: "时区", Icon: "🕐", Type: models.ConfigTypeSelect},
+	}
+
+	enabled := enabledFeatureNames(items, group)
+	if strings.Join(enabled, ",") != "🧮 计算器功能,📒 收支记账" {
+		t.Fatalf("unexpected enabled features: %v", enabled)
+	}
+
+	text := formatGroupStatus(group, enabled, []string{"<b>🔌 接口绑定</b>", "• 支付宝 (ID: <code>123</code>)"})
+	for _, want := range []string{"群组：测试&lt;群&gt;", "群等级：上游群", "🧮 计算器功能、📒 收支记账", "<b>🔌 接口绑定</b>\n• 支付宝"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	if text := formatGroupStatus(&models.Group{}, nil, nil); !strings.Contains(text, "已启用功能</b>\n无") {
+		t.Fatalf("expected empty feature placeholder, got:\n%s", text)
+	}
+}
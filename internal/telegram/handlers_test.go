@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/i18n"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestBuildHelpText(t *testing.T) {
+	group := &models.Group{
+		TelegramID: 1,
+		Settings: models.GroupSettings{
+			AccountingEnabled: true,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		group       *models.Group
+		perms       []string
+		features    []string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "private chat shows generic help",
+			group:       nil,
+			contains:    []string{"/help", "请在对应群组内发送 /help"},
+			notContains: []string{"高级管理命令", "/configs", "收支记账"},
+		},
+		{
+			name:     "owner sees privileged section",
+			group:    nil,
+			perms:    models.RolePermissions(models.RoleOwner),
+			contains: []string{"高级管理命令", "/health", "/grant", "/perm"},
+		},
+		{
+			name:        "super admin sees privileged commands except admin management",
+			group:       nil,
+			perms:       models.RolePermissions(models.RoleSuperAdmin),
+			contains:    []string{"高级管理命令", "/health", "/broadcast", "/note"},
+			notContains: []string{"/grant", "/revoke", "/perm"},
+		},
+		{
+			name:        "group lists enabled features only",
+			group:       group,
+			features:    []string{"<b>计算器</b>", "直接发送数学表达式"},
+			contains:    []string{"/configs", "<b>计算器</b>", "收支记账"},
+			notContains: []string{"高级管理命令", "四方自动查单"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			user := &models.User{Role: models.RoleAdmin, Permissions: tc.perms}
+			text := buildHelpText(i18n.LangZH, tc.group, user.HasPermission, tc.features)
+			for _, want := range tc.contains {
+				if !strings.Contains(text, want) {
+					t.Fatalf("expected help to contain %q, got %q", want, text)
+				}
+			}
+			for _, unwanted := range tc.notContains {
+				if strings.Contains(text, unwanted) {
+					t.Fatalf("did not expect help to contain %q, got %q", unwanted, text)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildHelpTextLocalized(t *testing.T) {
+	group := &models.Group{TelegramID: 1, Settings: models.GroupSettings{Language: "en"}}
+	b := &Bot{userService: &stubLangUserService{preferred: "zh"}}
+
+	lang := b.resolveLang(context.Background(), group, &botModels.User{ID: 7, LanguageCode: "zh-hans"})
+	if lang != i18n.LangEN {
+		t.Fatalf("expected group language to override user preference, got %q", lang)
+	}
+
+	owner := &models.User{Role: models.RoleOwner}
+	text := buildHelpText(lang, group, owner.HasPermission, nil)
+	for _, want := range []string{"Admin Help", "Privileged commands", "/configs - Open the group settings menu"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected english help to contain %q, got %q", want, text)
+		}
+	}
+	if strings.Contains(text, "管理员帮助总览") {
+		t.Fatalf("did not expect chinese title in english help: %q", text)
+	}
+}
+
+func TestPreferredLang(t *testing.T) {
+	user := &botModels.User{ID: 7, LanguageCode: "en-US"}
+
+	b := &Bot{userService: &stubLangUserService{}}
+	if got := b.resolveLang(context.Background(), nil, user); got != i18n.LangEN {
+		t.Fatalf("expected telegram language without preference, got %q", got)
+	}
+
+	b = &Bot{userService: &stubLangUserService{preferred: "zh"}}
+	if got := b.resolveLang(context.Background(), nil, user); got != i18n.LangZH {
+		t.Fatalf("expected preferred language in private chat, got %q", got)
+	}
+	if got := b.resolveLang(context.Background(), &models.Group{}, user); got != i18n.LangZH {
+		t.Fatalf("expected preference when group language unset, got %q", got)
+	}
+}
+
+type stubLangUserService struct {
+	service.UserService
+	preferred string
+}
+
+func (s *stubLangUserService) GetPreferredLanguage(ctx context.Context, telegramID int64) (string, error) {
+	return s.preferred, nil
+}
+
+func TestParseStartPayload(t *testing.T) {
+	cases := map[string]string{
+		"/start":             "",
+		"/start ref_123":     "ref_123",
+		"/start  invite-1  ": "invite-1",
+		"/start ref_1 extra": "ref_1",
+	}
+	for text, want := range cases {
+		if got := parseStartPayload(text); got != want {
+			t.Fatalf("parseStartPayload(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFormatAccountingAnomalyWarning(t *testing.T) {
+	if got := formatAccountingAnomalyWarning(&service.AccountingAddResult{Record: &models.AccountingRecord{Amount: 100}}); got != "" {
+		t.Fatalf("expected no warning for normal amount, got %q", got)
+	}
+
+	result := &service.AccountingAddResult{
+		Record:    &models.AccountingRecord{Amount: 100000, Currency: models.CurrencyUSD},
+		Anomalous: true,
+		Average:   100.005,
+		Ratio:     10,
+	}
+	got := formatAccountingAnomalyWarning(result)
+	if !strings.Contains(got, "金额异常，请核对") || !strings.Contains(got, "本笔 +100000U 超过近期均值 100.01 的 10 倍") {
+		t.Fatalf("unexpected warning: %q", got)
+	}
+}
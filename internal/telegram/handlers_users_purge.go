@@ -0,0 +1,201 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	purgeUsersCallbackPrefix = "purgeusers:"
+	// purgeUsersPendingTTL 清理确认的有效期
+	purgeUsersPendingTTL = 2 * time.Minute
+	// purgeUsersPreviewLimit 确认提示中列出的用户数量上限
+	purgeUsersPreviewLimit = 10
+	purgeUsersExpiredText  = "⌛ 清理确认已超时，未删除任何用户"
+)
+
+var purgeUsersUsage = fmt.Sprintf("用法：/purge_users [天数]，清理超过指定天数无活跃的普通用户（默认 %d 天，最少 %d 天）",
+	models.DefaultUserInactiveDays, models.MinUserInactiveDays)
+
+// pendingUserPurge 等待 Owner 确认的不活跃用户清理请求，cutoff 在预览时固定，确认时按同一条件清理
+type pendingUserPurge struct {
+	chatID  int64
+	ownerID int64
+	days    int
+	cutoff  time.Time
+}
+
+// handlePurgeUsers 处理 /purge_users 命令（Owner），列出不活跃普通用户并在确认后存档清理
+func (b *Bot) handlePurgeUsers(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+
+	days, err := parsePurgeUsersDays(msg.Text)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+
+	cutoff := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	count, users, err := b.userService.PreviewInactiveUsers(ctx, cutoff, purgeUsersPreviewLimit)
+	if err != nil {
+		b.sendErrorMessage(ctx, msg.Chat.ID, err.Error(), msg.ID)
+		return
+	}
+	if count == 0 {
+		b.sendMessage(ctx, msg.Chat.ID, fmt.Sprintf("✅ 没有超过 %d 天无活跃的普通用户，无需清理", days), msg.ID)
+		return
+	}
+
+	pending := &pendingUserPurge{
+		chatID:  msg.Chat.ID,
+		ownerID: msg.From.ID,
+		days:    days,
+		cutoff:  cutoff,
+	}
+	token := b.pendingUserPurges.put(pending, purgeUsersPendingTTL)
+
+	prompt := formatPurgeUsersPrompt(days, count, users, models.DefaultLocation())
+	sent, err := b.sendMessageWithMarkupAndMessage(ctx, msg.Chat.ID, prompt, buildPurgeUsersKeyboard(token), msg.ID)
+	if err != nil || sent == nil {
+		b.pendingUserPurges.take(token)
+		return
+	}
+
+	time.AfterFunc(purgeUsersPendingTTL, func() {
+		if b.pendingUserPurges.take(token) {
+			b.editMessage(context.Background(), pending.chatID, sent.ID, purgeUsersExpiredText, nil)
+		}
+	})
+}
+
+// handlePurgeUsersCallback 处理清理确认/取消按钮，仅发起命令的 Owner 可操作
+func (b *Bot) handlePurgeUsersCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	action, token, ok := parsePurgeUsersCallback(query.Data)
+	if !ok || query.Message.Message == nil {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的请求", true)
+		return
+	}
+	chatID := query.Message.Message.Chat.ID
+	messageID := query.Message.Message.ID
+
+	pending, exists := b.pendingUserPurges.get(token)
+	if !exists || pending.chatID != chatID {
+		b.answerCallback(ctx, botInstance, query.ID, "确认已失效，请重新发送 /purge_users", true)
+		b.editMessage(ctx, chatID, messageID, purgeUsersExpiredText, nil)
+		return
+	}
+	if pending.ownerID != query.From.ID {
+		b.answerCallback(ctx, botInstance, query.ID, "只有发起 /purge_users 的 Owner 可以操作", true)
+		return
+	}
+	if !b.pendingUserPurges.take(token) {
+		b.answerCallback(ctx, botInstance, query.ID, "请求已处理", true)
+		return
+	}
+
+	if action == "cancel" {
+		b.answerCallback(ctx, botInstance, query.ID, "已取消", false)
+		b.editMessage(ctx, chatID, messageID, "🚫 已取消清理，未删除任何用户", nil)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "正在清理", false)
+	purged, err := b.userService.PurgeInactiveUsers(ctx, pending.cutoff, query.From.ID)
+	if err != nil {
+		b.editMessage(ctx, chatID, messageID, "❌ "+err.Error(), nil)
+		return
+	}
+	b.editMessage(ctx, chatID, messageID,
+		fmt.Sprintf("🧹 已清理 %d 个超过 %d 天无活跃的普通用户，记录已存档到 purged_users", purged, pending.days), nil)
+}
+
+// parsePurgeUsersDays 解析 /purge_users [天数]
+func parsePurgeUsersDays(text string) (int, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return models.DefaultUserInactiveDays, nil
+	}
+	if len(fields) > 2 {
+		return 0, fmt.Errorf("%s", purgeUsersUsage)
+	}
+
+	days, err := strconv.Atoi(strings.TrimSuffix(fields[1], "天"))
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("%s", purgeUsersUsage)
+	}
+	if days < models.MinUserInactiveDays {
+		return 0, fmt.Errorf("天数不能少于 %d 天", models.MinUserInactiveDays)
+	}
+	return days, nil
+}
+
+// formatPurgeUsersPrompt 清理确认提示：数量与最久未活跃的部分用户
+func formatPurgeUsersPrompt(days int, count int64, users []*models.User, loc *time.Location) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("⚠️ <b>确认清理不活跃用户？</b>\n\n超过 %d 天无活跃的普通用户共 <b>%d</b> 个（管理员与 Owner 不会被清理）", days, count))
+	if len(users) > 0 {
+		text.WriteString("，最久未活跃的：\n")
+		for _, user := range users {
+			name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+			if user.Username != "" {
+				name += " @" + user.Username
+			}
+			if name == "" {
+				name = "-"
+			}
+			lastActive := "-"
+			if !user.LastActiveAt.IsZero() {
+				lastActive = user.LastActiveAt.In(loc).Format("2006-01-02")
+			}
+			text.WriteString(fmt.Sprintf("• <code>%d</code> %s（%s）\n", user.TelegramID, html.EscapeString(name), lastActive))
+		}
+		if int64(len(users)) < count {
+			text.WriteString(fmt.Sprintf("… 另有 %d 个\n", count-int64(len(users))))
+		}
+	} else {
+		text.WriteString("\n")
+	}
+	text.WriteString(fmt.Sprintf("\n清理前会将完整记录存档到 purged_users 集合。%s 内未确认将自动取消。", formatDuration(purgeUsersPendingTTL)))
+	return text.String()
+}
+
+func buildPurgeUsersKeyboard(token string) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{
+				{Text: "🧹 确认清理", CallbackData: purgeUsersCallbackPrefix + "confirm:" + token},
+				{Text: "❌ 取消", CallbackData: purgeUsersCallbackPrefix + "cancel:" + token},
+			},
+		},
+	}
+}
+
+// parsePurgeUsersCallback 解析回调数据：purgeusers:<confirm|cancel>:<token>
+func parsePurgeUsersCallback(data string) (string, string, bool) {
+	payload := strings.TrimPrefix(data, purgeUsersCallbackPrefix)
+	action, token, found := strings.Cut(payload, ":")
+	if !found || token == "" {
+		return "", "", false
+	}
+	if action != "confirm" && action != "cancel" {
+		return "", "", false
+	}
+	return action, token, true
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestParsePurgeUsersDays(t *testing.T) {
+	if days, err := parsePurgeUsersDays("/purge_users"); err != nil || days != models.DefaultUserInactiveDays {
+		t.Fatalf("expected default days, got %d err=%v", days, err)
+	}
+	if days, err := parsePurgeUsersDays("/purge_users 90天"); err != nil || days != 90 {
+		t.Fatalf("expected 90 days, got %d err=%v", days, err)
+	}
+	for _, text := range []string{"/purge_users 7", "/purge_users abc", "/purge_users -1", "/purge_users 90 1"} {
+		if _, err := parsePurgeUsersDays(text); err == nil {
+			t.Fatalf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestFormatPurgeUsersPrompt(t *testing.T) {
+	loc := time.UTC
+	users := []*models.User{
+		{TelegramID: 1001, FirstName: "<Tom>", Username: "tom", LastActiveAt: time.Date(2024, 1, 2, 0, 0, 0, 0, loc)},
+		{TelegramID: 1002},
+	}
+
+	text := formatPurgeUsersPrompt(180, 5, users, loc)
+	for _, want := range []string{"超过 180 天无活跃的普通用户共 <b>5</b> 个", "<code>1001</code> &lt;Tom&gt; @tom（2024-01-02）", "<code>1002</code> -（-）", "… 另有 3 个"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected prompt to contain %q, got:\n%s", want, text)
+		}
+	}
+}
+
+func TestParsePurgeUsersCallback(t *testing.T) {
+	action, token, ok := parsePurgeUsersCallback(purgeUsersCallbackPrefix + "confirm:abc")
+	if !ok || action != "confirm" || token != "abc" {
+		t.Fatalf("unexpected parse result: %s %s %v", action, token, ok)
+	}
+	if _, _, ok := parsePurgeUsersCallback(purgeUsersCallbackPrefix + "delete:abc"); ok {
+		t.Fatalf("expected unknown action rejected")
+	}
+}
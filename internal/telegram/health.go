@@ -0,0 +1,243 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+)
+
+const (
+	healthCheckTimeout        = 5 * time.Second
+	workerQueueWarnPercentage = 80
+)
+
+// healthStatus 单项检查状态
+type healthStatus int
+
+const (
+	healthStatusOK healthStatus = iota
+	healthStatusWarn
+	healthStatusFail
+	healthStatusDisabled
+)
+
+// healthCheck 描述一项健康检查
+type healthCheck struct {
+	name string
+	run  func(ctx context.Context) (healthStatus, string)
+}
+
+// healthCheckResult 单项健康检查结果
+type healthCheckResult struct {
+	name     string
+	status   healthStatus
+	detail   string
+	duration time.Duration
+}
+
+// buildHealthReport 执行全部健康检查并汇总为一条报告
+func (b *Bot) buildHealthReport(ctx context.Context) string {
+	start := time.Now()
+	results := runHealthChecks(ctx, b.healthChecks(), healthCheckTimeout)
+	return formatHealthReport(results, time.Since(start))
+}
+
+// healthChecks 返回需要执行的健康检查项
+func (b *Bot) healthChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: "MongoDB", run: b.checkDatabaseHealth},
+		{name: "Telegram API", run: b.checkTelegramHealth},
+		{name: "四方支付", run: b.checkPaymentHealth},
+		{name: "工作池", run: b.checkWorkerPoolHealth},
+		{name: "越权尝试", run: b.checkPermissionDenialHealth},
+	}
+	return append(checks, b.backgroundTaskChecks()...)
+}
+
+// backgroundTaskChecks 返回各后台调度器的运行状态检查，/health 与 /uptime 共用
+func (b *Bot) backgroundTaskChecks() []healthCheck {
+	return []healthCheck{
+		{name: "每日账单推送", run: schedulerHealth(b.dailySummaryScheduler.isRunning, b.dailySummaryScheduler != nil)},
+		{name: "上游日结调度", run: schedulerHealth(b.upstreamScheduler.isRunning, b.upstreamScheduler != nil)},
+		{name: "上游余额监控", run: schedulerHealth(b.balanceMonitor.isRunning, b.balanceMonitor != nil)},
+		{name: "下发过期扫描", run: schedulerHealth(b.sendMoneySweeper.isRunning, b.sendMoneySweeper != nil)},
+		{name: "费率缓存预热", run: schedulerHealth(b.channelRateWarmer.isRunning, b.channelRateWarmer != nil)},
+		{name: "定时消息调度", run: schedulerHealth(b.scheduledMessageScheduler.isRunning, b.scheduledMessageScheduler != nil)},
+		{name: "离群存档清理", run: schedulerHealth(b.groupArchivePurger.isRunning, b.groupArchivePurger != nil)},
+		{name: "过期状态清理", run: schedulerHealth(b.pendingStateSweeper.isRunning, b.pendingStateSweeper != nil)},
+	}
+}
+
+// runHealthChecks 并发执行检查，单项失败或超时不影响其余检查
+func runHealthChecks(ctx context.Context, checks []healthCheck, timeout time.Duration) []healthCheckResult {
+	results := make([]healthCheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(idx int, check healthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := healthCheckResult{name: check.name}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						result.status = healthStatusFail
+						result.detail = fmt.Sprintf("检查异常: %v", r)
+					}
+				}()
+				result.status, result.detail = check.run(checkCtx)
+			}()
+			result.duration = time.Since(start)
+			results[idx] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// formatHealthReport 将检查结果格式化为报告文本
+func formatHealthReport(results []healthCheckResult, total time.Duration) string {
+	failures := 0
+	for _, result := range results {
+		if result.status == healthStatusFail {
+			failures++
+		}
+	}
+
+	var sb strings.Builder
+	if failures == 0 {
+		sb.WriteString("🩺 健康检查：✅ 全部正常\n\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("🩺 健康检查：❌ %d 项异常\n\n", failures))
+	}
+
+	for _, result := range results {
+		sb.WriteString(fmt.Sprintf("%s %s：%s（%s）\n",
+			healthStatusIcon(result.status),
+			html.EscapeString(result.name),
+			html.EscapeString(result.detail),
+			result.duration.Round(time.Millisecond)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n⏱ 总耗时：%s", total.Round(time.Millisecond)))
+	return sb.String()
+}
+
+func healthStatusIcon(status healthStatus) string {
+	switch status {
+	case healthStatusOK:
+		return "🟢"
+	case healthStatusWarn:
+		return "🟡"
+	case healthStatusDisabled:
+		return "⚪"
+	default:
+		return "🔴"
+	}
+}
+
+func (b *Bot) checkDatabaseHealth(ctx context.Context) (healthStatus, string) {
+	if b.db == nil {
+		return healthStatusFail, "未配置"
+	}
+	if err := b.db.Client().Ping(ctx, nil); err != nil {
+		return healthStatusFail, fmt.Sprintf("连接失败: %v", err)
+	}
+	return healthStatusOK, "正常"
+}
+
+func (b *Bot) checkTelegramHealth(ctx context.Context) (healthStatus, string) {
+	if b.bot == nil {
+		return healthStatusFail, "未初始化"
+	}
+	me, err := b.bot.GetMe(ctx)
+	if err != nil {
+		return healthStatusFail, fmt.Sprintf("请求失败: %v", err)
+	}
+	return healthStatusOK, fmt.Sprintf("正常（@%s）", me.Username)
+}
+
+func (b *Bot) checkPaymentHealth(ctx context.Context) (healthStatus, string) {
+	if b.paymentService == nil {
+		return healthStatusDisabled, "未配置"
+	}
+	pinger, ok := b.paymentService.(paymentservice.Pinger)
+	if !ok {
+		return healthStatusWarn, "当前实现不支持连通性检测"
+	}
+	if err := pinger.Ping(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return healthStatusFail, "请求超时"
+		}
+		return healthStatusFail, fmt.Sprintf("不可达: %v", err)
+	}
+	return healthStatusOK, "可达"
+}
+
+func (b *Bot) checkWorkerPoolHealth(ctx context.Context) (healthStatus, string) {
+	if b.workerPool == nil {
+		return healthStatusFail, "未初始化"
+	}
+	stats := b.workerPool.Stats()
+	return workerPoolHealth(stats)
+}
+
+// workerPoolHealth 根据队列水位判断工作池状态
+func workerPoolHealth(stats WorkerPoolStats) (healthStatus, string) {
+	detail := fmt.Sprintf("%s，队列 %d/%d", stats.workersLabel(), stats.QueueLength, stats.QueueCapacity)
+	if stats.QueueCapacity <= 0 {
+		return healthStatusOK, detail
+	}
+
+	usage := stats.QueueLength * 100 / stats.QueueCapacity
+	switch {
+	case stats.QueueLength >= stats.QueueCapacity:
+		return healthStatusFail, detail + "（队列已满）"
+	case usage >= workerQueueWarnPercentage:
+		return healthStatusWarn, fmt.Sprintf("%s（水位 %d%%）", detail, usage)
+	default:
+		return healthStatusOK, detail
+	}
+}
+
+// checkPermissionDenialHealth 统计近期权限拒绝，有用户反复越权时告警
+func (b *Bot) checkPermissionDenialHealth(ctx context.Context) (healthStatus, string) {
+	if b.permissionDenials == nil {
+		return healthStatusDisabled, "未启用"
+	}
+	return permissionDenialHealth(b.permissionDenials.stats())
+}
+
+// permissionDenialHealth 根据窗口内的拒绝统计判断状态
+func permissionDenialHealth(stats permissionDenialStats) (healthStatus, string) {
+	detail := fmt.Sprintf("近 %s %d 次 / %d 人，累计 %d 次", formatDuration(permissionDenialWindow), stats.Recent, stats.Users, stats.Total)
+	if stats.Suspicious > 0 {
+		return healthStatusWarn, fmt.Sprintf("%s（%d 人反复越权）", detail, stats.Suspicious)
+	}
+	return healthStatusOK, detail
+}
+
+// schedulerHealth 构建调度器运行状态检查
+func schedulerHealth(isRunning func() bool, configured bool) func(context.Context) (healthStatus, string) {
+	return func(context.Context) (healthStatus, string) {
+		if !configured {
+			return healthStatusDisabled, "未启用"
+		}
+		if !isRunning() {
+			return healthStatusFail, "已停止"
+		}
+		return healthStatusOK, "运行中"
+	}
+}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+)
+
+const (
+	// healthProbeTimeout /readyz 单次就绪检查超时，需小于探针自身的超时
+	healthProbeTimeout = 2 * time.Second
+	// healthServerReadHeaderTimeout 读取请求头超时，防止慢连接占用
+	healthServerReadHeaderTimeout = 5 * time.Second
+)
+
+// healthServer 健康探针 HTTP 服务：/healthz 表示进程存活，/readyz 检查数据库与 Bot 是否已启动，/metrics 暴露 Prometheus 指标
+type healthServer struct {
+	server *http.Server
+	wg     sync.WaitGroup
+}
+
+func newHealthServer(port int, ready func(ctx context.Context) error, metrics http.Handler) *healthServer {
+	return &healthServer{
+		server: &http.Server{
+			Addr:              net.JoinHostPort("", strconv.Itoa(port)),
+			Handler:           newHealthHandler(ready, metrics),
+			ReadHeaderTimeout: healthServerReadHeaderTimeout,
+		},
+	}
+}
+
+// newHealthHandler 构建探针路由，ready 返回 nil 表示就绪，metrics 为 nil 时不注册 /metrics
+func newHealthHandler(ready func(ctx context.Context) error, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	if metrics != nil {
+		mux.Handle("/metrics", metrics)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeProbeResult(w, http.StatusOK, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+
+		if err := ready(ctx); err != nil {
+			writeProbeResult(w, http.StatusServiceUnavailable, "not ready: "+err.Error())
+			return
+		}
+		writeProbeResult(w, http.StatusOK, "ready")
+	})
+	return mux
+}
+
+func writeProbeResult(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body + "\n"))
+}
+
+// start 监听端口并在后台提供服务，端口占用等错误同步返回
+func (s *healthServer) start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen health server on %s: %w", s.server.Addr, err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.L().Errorf("Health server stopped unexpectedly: %v", err)
+		}
+	}()
+
+	logger.L().Infof("Health server listening on %s (/healthz, /readyz, /metrics)", listener.Addr())
+	return nil
+}
+
+// stop 优雅关闭：等待进行中的探针请求结束，超过 ctx 期限后强制关闭
+func (s *healthServer) stop(ctx context.Context) {
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.Ctx(ctx).Warnf("Health server shutdown: %v", err)
+		_ = s.server.Close()
+	}
+	s.wg.Wait()
+	logger.Ctx(ctx).Info("Health server stopped")
+}
+
+// checkReadiness 就绪检查：Bot 已开始接收更新且数据库可用
+func (b *Bot) checkReadiness(ctx context.Context) error {
+	if !b.running.Load() {
+		return errors.New("bot not started")
+	}
+	if status, detail := b.checkDatabaseHealth(ctx); status != healthStatusOK {
+		return fmt.Errorf("mongodb %s", detail)
+	}
+	return nil
+}
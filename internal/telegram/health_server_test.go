@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthHandlerProbes(t *testing.T) {
+	var readyErr error
+	handler := newHealthHandler(func(context.Context) error { return readyErr }, nil)
+
+	probe := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected /healthz 200, got %d", code)
+	}
+	if code, _ := probe("/readyz"); code != http.StatusOK {
+		t.Fatalf("expected /readyz 200 when ready, got %d", code)
+	}
+
+	readyErr = errors.New("bot not started")
+	code, body := probe("/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "bot not started") {
+		t.Fatalf("expected /readyz 503 with reason, got %d %q", code, body)
+	}
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected /healthz to stay 200 while not ready, got %d", code)
+	}
+}
+
+func TestCheckReadinessRequiresStartedBot(t *testing.T) {
+	b := &Bot{}
+	if err := b.checkReadiness(context.Background()); err == nil {
+		t.Fatal("expected not ready before Start")
+	}
+
+	b.running.Store(true)
+	if err := b.checkReadiness(context.Background()); err == nil || !strings.Contains(err.Error(), "mongodb") {
+		t.Fatalf("expected database check failure without db, got %v", err)
+	}
+}
+
+func TestHealthServerStartStop(t *testing.T) {
+	server := newHealthServer(0, func(context.Context) error { return nil }, nil)
+	if err := server.start(); err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+	server.stop(context.Background())
+}
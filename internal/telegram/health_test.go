@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/version"
+)
+
+func TestRunHealthChecksContinuesAfterFailure(t *testing.T) {
+	checks := []healthCheck{
+		{name: "panic", run: func(context.Context) (healthStatus, string) { panic("boom") }},
+		{name: "fail", run: func(context.Context) (healthStatus, string) { return healthStatusFail, "down" }},
+		{name: "timeout", run: func(ctx context.Context) (healthStatus, string) {
+			<-ctx.Done()
+			return healthStatusFail, "请求超时"
+		}},
+		{name: "ok", run: func(context.Context) (healthStatus, string) { return healthStatusOK, "正常" }},
+	}
+
+	results := runHealthChecks(context.Background(), checks, 20*time.Millisecond)
+	if len(results) != len(checks) {
+		t.Fatalf("expected %d results, got %d", len(checks), len(results))
+	}
+
+	expected := []healthStatus{healthStatusFail, healthStatusFail, healthStatusFail, healthStatusOK}
+	for i, result := range results {
+		if result.name != checks[i].name {
+			t.Fatalf("expected result %d to be %s, got %s", i, checks[i].name, result.name)
+		}
+		if result.status != expected[i] {
+			t.Fatalf("expected %s status %d, got %d", result.name, expected[i], result.status)
+		}
+	}
+}
+
+func TestFormatHealthReport(t *testing.T) {
+	results := []healthCheckResult{
+		{name: "MongoDB", status: healthStatusOK, detail: "正常", duration: 3 * time.Millisecond},
+		{name: "四方支付", status: healthStatusFail, detail: "不可达: <nil>", duration: time.Second},
+		{name: "上游日结调度", status: healthStatusDisabled, detail: "未启用"},
+	}
+
+	report := formatHealthReport(results, 1500*time.Millisecond)
+
+	expectedLines := []string{
+		"🩺 健康检查：❌ 1 项异常",
+		"🟢 MongoDB：正常（3ms）",
+		"🔴 四方支付：不可达: &lt;nil&gt;（1s）",
+		"⚪ 上游日结调度：未启用（0s）",
+		"⏱ 总耗时：1.5s",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(report, line) {
+			t.Fatalf("expected report to contain %q, got %q", line, report)
+		}
+	}
+}
+
+func TestWorkerPoolHealth(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats WorkerPoolStats
+		want  healthStatus
+	}{
+		{name: "idle", stats: WorkerPoolStats{Workers: 10, QueueLength: 0, QueueCapacity: 100}, want: healthStatusOK},
+		{name: "high watermark", stats: WorkerPoolStats{Workers: 10, QueueLength: 85, QueueCapacity: 100}, want: healthStatusWarn},
+		{name: "full", stats: WorkerPoolStats{Workers: 10, QueueLength: 100, QueueCapacity: 100}, want: healthStatusFail},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := workerPoolHealth(tc.stats)
+			if got != tc.want {
+				t.Fatalf("expected status %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildUptimeMessage(t *testing.T) {
+	original := version.Version
+	version.Version = "v1.2.3"
+	defer func() { version.Version = original }()
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, models.DefaultLocation())
+	b := &Bot{startTime: start, pendingStateSweeper: &pendingStateSweeper{}}
+	text := b.buildUptimeMessage(context.Background(), start.Add(26*time.Hour+3*time.Minute))
+
+	for _, want := range []string{
+		"运行时长：1天 2小时 3分钟",
+		"启动时间：2026-01-02 03:04:05",
+		"版本：v1.2.3",
+		"Commit：",
+		"⚪ 每日账单推送：未启用",
+		"🔴 过期状态清理：已停止",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected uptime message to contain %q, got:\n%s", want, text)
+		}
+	}
+	if got := strings.Count(text, "\n🔴 ") + strings.Count(text, "\n⚪ "); got != len(b.backgroundTaskChecks()) {
+		t.Fatalf("expected one line per background task, got %d:\n%s", got, text)
+	}
+}
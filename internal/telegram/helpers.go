@@ -1,13 +1,20 @@
 package telegram
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-telegram/bot"
 	botModels "github.com/go-telegram/bot/models"
 
 	"go_bot/internal/logger"
+	"go_bot/internal/telegram/i18n"
+	"go_bot/internal/telegram/models"
 )
 
 const (
@@ -15,36 +22,166 @@ const (
 	temporaryDeleteTimeout   = 5 * time.Second
 )
 
+// userLang 按发送者的 Telegram 语言代码选择语言包
+func userLang(user *botModels.User) i18n.Lang {
+	if user == nil {
+		return i18n.DefaultLang
+	}
+	return i18n.Normalize(user.LanguageCode)
+}
+
+// groupLang 返回群组配置的语言，未配置时返回 false
+func groupLang(group *models.Group) (i18n.Lang, bool) {
+	if group == nil || group.Settings.Language == "" {
+		return "", false
+	}
+	return i18n.Normalize(group.Settings.Language), true
+}
+
+// preferredLang 用户通过 /lang 设置过偏好时使用偏好，否则按 Telegram 语言代码
+func (b *Bot) preferredLang(ctx context.Context, user *botModels.User) i18n.Lang {
+	if user == nil {
+		return i18n.DefaultLang
+	}
+	if b.userService != nil {
+		if stored, err := b.userService.GetPreferredLanguage(ctx, user.ID); err == nil && stored != "" {
+			return i18n.Normalize(stored)
+		}
+	}
+	return userLang(user)
+}
+
+// resolveLang 群内优先使用群组语言设置，否则按用户偏好语言
+func (b *Bot) resolveLang(ctx context.Context, group *models.Group, user *botModels.User) i18n.Lang {
+	if lang, ok := groupLang(group); ok {
+		return lang
+	}
+	return b.preferredLang(ctx, user)
+}
+
+// messageLang 返回回复该消息时使用的语言；群聊读取群组语言设置，私聊按用户偏好语言
+func (b *Bot) messageLang(ctx context.Context, msg *botModels.Message) i18n.Lang {
+	if msg == nil {
+		return i18n.DefaultLang
+	}
+	var group *models.Group
+	if isGroupChatType(msg.Chat.Type) && b.groupService != nil {
+		group, _ = b.groupService.GetGroupInfo(ctx, msg.Chat.ID)
+	}
+	return b.resolveLang(ctx, group, msg.From)
+}
+
+// messageEntities 将 Telegram 消息实体转换为存储模型
+func messageEntities(entities []botModels.MessageEntity) []models.MessageEntity {
+	if len(entities) == 0 {
+		return nil
+	}
+	result := make([]models.MessageEntity, 0, len(entities))
+	for _, entity := range entities {
+		item := models.MessageEntity{
+			Type:   string(entity.Type),
+			Offset: entity.Offset,
+			Length: entity.Length,
+			URL:    entity.URL,
+		}
+		if entity.User != nil {
+			item.UserID = entity.User.ID
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
 // sendMessage 发送消息（统一错误处理，使用 HTML 格式）
 func (b *Bot) sendMessage(ctx context.Context, chatID int64, text string, replyTo ...int) {
 	_, _ = b.sendMessageWithMarkupAndMessage(ctx, chatID, text, nil, replyTo...)
 }
 
+// sendOptions 发送选项
+type sendOptions struct {
+	replyTo int  // 引用的消息 ID，0 表示不引用
+	silent  bool // 静默发送（disable_notification），接收方不响铃
+}
+
 // sendMessageWithMarkupAndMessage 发送消息并返回 Telegram Message
 func (b *Bot) sendMessageWithMarkupAndMessage(ctx context.Context, chatID int64, text string, markup botModels.ReplyMarkup, replyTo ...int) (*botModels.Message, error) {
-	params := &bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      text,
-		ParseMode: botModels.ParseModeHTML,
+	var opts sendOptions
+	if len(replyTo) > 0 {
+		opts.replyTo = replyTo[0]
 	}
+	return b.sendMessageWithOptions(ctx, chatID, text, markup, opts)
+}
 
-	if len(replyTo) > 0 && replyTo[0] > 0 {
-		params.ReplyParameters = &botModels.ReplyParameters{
-			MessageID: replyTo[0],
+// sendScheduledPush 发送调度类推送（日结、日账、余额告警），按来源群的配置决定是否静默
+func (b *Bot) sendScheduledPush(ctx context.Context, group *models.Group, chatID int64, text string) (*botModels.Message, error) {
+	return b.sendMessageWithOptions(ctx, chatID, text, nil, sendOptions{silent: group != nil && group.Settings.SilentScheduledPush})
+}
+
+// sendMessageWithOptions 按选项发送消息并返回 Telegram Message
+// 超过 Telegram 长度上限时按换行自动分片发送：仅第一片引用原消息，仅最后一片附加键盘，返回最后一片
+func (b *Bot) sendMessageWithOptions(ctx context.Context, chatID int64, text string, markup botModels.ReplyMarkup, opts sendOptions) (*botModels.Message, error) {
+	chunks := splitHTMLMessage(text, telegramMessageLimit)
+
+	var msg *botModels.Message
+	for i, chunk := range chunks {
+		params := &bot.SendMessageParams{
+			ChatID:              chatID,
+			Text:                chunk,
+			ParseMode:           botModels.ParseModeHTML,
+			DisableNotification: opts.silent,
+		}
+
+		if i == 0 && opts.replyTo > 0 {
+			params.ReplyParameters = &botModels.ReplyParameters{
+				MessageID: opts.replyTo,
+			}
+		}
+
+		if markup != nil && i == len(chunks)-1 {
+			params.ReplyMarkup = markup
 		}
+
+		var sent *botModels.Message
+		err := sendWithRetry(ctx, defaultSendRetryPolicy, func(ctx context.Context) error {
+			var sendErr error
+			sent, sendErr = b.bot.SendMessage(ctx, params)
+			return sendErr
+		})
+		if err != nil {
+			b.metrics.recordSendError("sendMessage", err)
+			logger.Ctx(ctx).Errorf("Failed to send message to chat %d (part %d/%d): %v", chatID, i+1, len(chunks), err)
+			return nil, err
+		}
+		b.recordSentMessage(sent)
+		msg = sent
 	}
 
-	if markup != nil {
-		params.ReplyMarkup = markup
+	return msg, nil
+}
+
+// sendDocument 发送文件（HTML 格式的 caption），失败时在群内提示
+func (b *Bot) sendDocument(ctx context.Context, chatID int64, filename string, data []byte, caption string, replyTo ...int) {
+	params := &bot.SendDocumentParams{
+		ChatID: chatID,
+		Document: &botModels.InputFileUpload{
+			Filename: filename,
+			Data:     bytes.NewReader(data),
+		},
+		Caption:   caption,
+		ParseMode: botModels.ParseModeHTML,
+	}
+	if len(replyTo) > 0 && replyTo[0] > 0 {
+		params.ReplyParameters = &botModels.ReplyParameters{MessageID: replyTo[0]}
 	}
 
-	msg, err := b.bot.SendMessage(ctx, params)
+	sent, err := b.bot.SendDocument(ctx, params)
 	if err != nil {
-		logger.L().Errorf("Failed to send message to chat %d: %v", chatID, err)
-		return nil, err
+		b.metrics.recordSendError("sendDocument", err)
+		logger.Ctx(ctx).Errorf("Failed to send document %s to chat %d: %v", filename, chatID, err)
+		b.sendErrorMessage(ctx, chatID, "发送文件失败", replyTo...)
+		return
 	}
-
-	return msg, nil
+	b.recordSentMessage(sent)
 }
 
 // sendErrorMessage 发送错误消息
@@ -85,7 +222,7 @@ func (b *Bot) sendTemporaryMessageWithMarkup(ctx context.Context, chatID int64,
 
 		select {
 		case <-timer.C:
-			logger.L().Infof("Attempting to delete temporary message: chat_id=%d message_id=%d", chatID, messageID)
+			logger.Ctx(ctx).Infof("Attempting to delete temporary message: chat_id=%d message_id=%d", chatID, messageID)
 
 			ctx, cancel := context.WithTimeout(deleteCtx, temporaryDeleteTimeout)
 			defer cancel()
@@ -94,8 +231,10 @@ func (b *Bot) sendTemporaryMessageWithMarkup(ctx context.Context, chatID int64,
 				ChatID:    chatID,
 				MessageID: messageID,
 			}); err != nil {
-				logger.L().Errorf("Failed to delete temporary message: chat_id=%d message_id=%d err=%v", chatID, messageID, err)
+				logger.Ctx(ctx).Errorf("Failed to delete temporary message: chat_id=%d message_id=%d err=%v", chatID, messageID, err)
+				return
 			}
+			b.sentMessages.forget(chatID, messageID)
 		case <-deleteCtx.Done():
 			return
 		}
@@ -115,6 +254,19 @@ func (b *Bot) editMessage(ctx context.Context, chatID int64, messageID int, text
 		params.ReplyMarkup = markup
 	}
 	if _, err := b.bot.EditMessageText(ctx, params); err != nil {
-		logger.L().Errorf("Failed to edit message %d in chat %d: %v", messageID, chatID, err)
+		b.metrics.recordSendError("editMessageText", err)
+		logger.Ctx(ctx).Errorf("Failed to edit message %d in chat %d: %v", messageID, chatID, err)
+	}
+}
+
+// userMention 返回可点击的用户提及（HTML），依次使用姓名、用户名、ID 作为显示文本
+func userMention(user botModels.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.Username
+	}
+	if name == "" {
+		name = strconv.FormatInt(user.ID, 10)
 	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, user.ID, html.EscapeString(name))
 }
@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+)
+
+// safeHTML 转义用户可控文本（群名、用户名、错误信息等），用于拼接到 HTML 消息中
+func safeHTML(text string) string {
+	return html.EscapeString(text)
+}
+
+// safeHTMLf 按格式拼接 HTML 消息：格式串视为可信 HTML，参数中的字符串（含自定义字符串类型）和 error 一律转义
+// 数字、时间等其他类型原样交给 fmt 处理
+func safeHTMLf(format string, args ...any) string {
+	escaped := make([]any, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case nil:
+			escaped[i] = arg
+		case string:
+			escaped[i] = html.EscapeString(value)
+		case error:
+			escaped[i] = html.EscapeString(value.Error())
+		default:
+			if v := reflect.ValueOf(arg); v.Kind() == reflect.String {
+				escaped[i] = html.EscapeString(v.String())
+			} else {
+				escaped[i] = arg
+			}
+		}
+	}
+	return fmt.Sprintf(format, escaped...)
+}
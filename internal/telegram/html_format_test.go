@@ -0,0 +1,26 @@
+package telegram
+
+import (
+	"errors"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestSafeHTMLf(t *testing.T) {
+	got := safeHTMLf("<b>%s</b> %s %d %v %v",
+		`Tom & "Jerry" <script>`,
+		models.GroupTier("<admin>"),
+		42,
+		errors.New("bad <tag>"),
+		nil,
+	)
+	want := `<b>Tom &amp; &#34;Jerry&#34; &lt;script&gt;</b> &lt;admin&gt; 42 bad &lt;tag&gt; <nil>`
+	if got != want {
+		t.Fatalf("unexpected output:\n got: %s\nwant: %s", got, want)
+	}
+
+	if safeHTML("a<b>&c") != "a&lt;b&gt;&amp;c" {
+		t.Fatalf("expected safeHTML to escape special characters")
+	}
+}
@@ -0,0 +1,87 @@
+// Package i18n 提供 Bot 文案的本地化
+//
+// 文案 key 命名约定：<模块>.<场景>[.<细分>]，全部小写，单词间用下划线，例如：
+//   - common.group_only      多个命令共用的通用文案
+//   - start.welcome          /start 命令
+//   - help.section.owner     /help 的分区标题
+//   - middleware.admin_only  中间件拦截提示
+//
+// 新增文案时先在中文语言包（messages_zh.go）登记 key，再补充其他语言；
+// 缺失的翻译会回退到中文，中文也缺失时直接返回 key 便于排查。
+// 文案统一按 HTML 格式书写，需要动态插入用户输入时由调用方先转义。
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang 语言代码
+type Lang string
+
+const (
+	LangZH Lang = "zh" // 中文（默认）
+	LangEN Lang = "en" // 英文
+)
+
+// DefaultLang 缺失翻译或无法识别语言时使用的语言
+const DefaultLang = LangZH
+
+// bundles 各语言的文案包
+var bundles = map[Lang]map[string]string{
+	LangZH: messagesZH,
+	LangEN: messagesEN,
+}
+
+// displayNames 各语言的自称，供选择列表展示（不随界面语言变化）
+var displayNames = map[Lang]string{
+	LangZH: "中文",
+	LangEN: "English",
+}
+
+// DisplayName 返回语言的展示名称
+func DisplayName(lang Lang) string {
+	if name, ok := displayNames[lang]; ok {
+		return name
+	}
+	return string(lang)
+}
+
+// Supported 返回支持的语言列表（默认语言在前）
+func Supported() []Lang {
+	return []Lang{LangZH, LangEN}
+}
+
+// Normalize 将 Telegram language_code（如 en-US、zh-hans）或配置值转换为受支持的语言，无法识别时返回默认语言
+func Normalize(code string) Lang {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if base, _, found := strings.Cut(code, "-"); found {
+		code = base
+	}
+	lang := Lang(code)
+	if _, ok := bundles[lang]; ok {
+		return lang
+	}
+	return DefaultLang
+}
+
+// IsSupported 是否为受支持的语言代码（不做前缀归一化）
+func IsSupported(code string) bool {
+	_, ok := bundles[Lang(code)]
+	return ok
+}
+
+// T 返回指定语言的文案，args 非空时按 fmt.Sprintf 格式化
+func T(lang Lang, key string, args ...any) string {
+	text, ok := bundles[lang][key]
+	if !ok {
+		text, ok = bundles[DefaultLang][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
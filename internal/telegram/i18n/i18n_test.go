@@ -0,0 +1,43 @@
+package i18n
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]Lang{
+		"":        LangZH,
+		"zh-hans": LangZH,
+		"en":      LangEN,
+		"EN-us":   LangEN,
+		"ru":      LangZH,
+	}
+	for code, want := range tests {
+		if got := Normalize(code); got != want {
+			t.Fatalf("Normalize(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestTFallsBackToChinese(t *testing.T) {
+	if got := T(LangEN, "middleware.rate_limited", 3); got != "Too many requests, please retry in 3 seconds" {
+		t.Fatalf("unexpected english text: %q", got)
+	}
+
+	messagesZH["test.only_zh"] = "仅中文"
+	defer delete(messagesZH, "test.only_zh")
+	if got := T(LangEN, "test.only_zh"); got != "仅中文" {
+		t.Fatalf("expected fallback to chinese, got %q", got)
+	}
+	if got := T(LangEN, "test.missing"); got != "test.missing" {
+		t.Fatalf("expected key for missing text, got %q", got)
+	}
+}
+
+func TestBundlesHaveNoExtraKeys(t *testing.T) {
+	for lang, bundle := range bundles {
+		for key := range bundle {
+			if _, ok := messagesZH[key]; !ok {
+				t.Fatalf("key %q in %s bundle is missing from the chinese bundle", key, lang)
+			}
+		}
+	}
+}
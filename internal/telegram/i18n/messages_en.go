@@ -0,0 +1,81 @@
+package i18n
+
+// messagesEN 英文语言包，缺失的 key 回退到中文
+var messagesEN = map[string]string{
+	// 通用
+	"common.group_only":        "This command can only be used in groups",
+	"common.private_only":      "This command can only be used in private chat",
+	"common.group_load_failed": "Failed to load group info, please try again later",
+
+	// /start
+	"start.register_failed": "Registration failed, please try again later",
+	"start.welcome":         "👋 Hello, %s!\n\nWelcome to this bot.\n\nAvailable commands:\n/start - Start\n/ping - Check connectivity\n/admins - List admins (admin only)",
+
+	// /lang
+	"lang.current":          "🌐 Current language: %s (%s)\nAvailable: %s\n\nUsage: /lang &lt;zh|en&gt;, /lang auto to follow your Telegram language",
+	"lang.source.preferred": "set manually",
+	"lang.source.auto":      "follows Telegram language",
+	"lang.updated":          "Language switched to %s",
+	"lang.reset":            "Language now follows your Telegram setting",
+	"lang.invalid":          "Unsupported language: %s\nAvailable: %s",
+	"lang.failed":           "Failed to update language, please try again later",
+	"lang.group_hint":       "ℹ️ Group messages use the group language (/configs → “🌐 群组语言”), or each member's preference if unset",
+
+	// 中间件
+	"middleware.perm_required":   "This command requires the \"%s\" permission",
+	"middleware.admin_only":      "This command requires admin permission",
+	"middleware.tier_restricted": "⚠️ This command is only available in: %s\nCurrent group type: %s",
+	"middleware.rate_limited":    "Too many requests, please retry in %d seconds",
+
+	// /help
+	"help.title":               "<b>🆘 Admin Help</b>",
+	"help.section.common":      "<b>General commands (everyone)</b>",
+	"help.start":               "/start - Start a session and register your profile",
+	"help.ping":                "/ping - Check bot connectivity",
+	"help.lang":                "/lang [zh|en|auto] - Show or switch your preferred language",
+	"help.section.admin":       "<b>Admin commands (Admin+)</b>",
+	"help.help":                "/help - Show this help",
+	"help.admins":              "/admins [owner|super|admin] - List admins (optionally by role)",
+	"help.userinfo":            "/userinfo &lt;user_id&gt; - Show user details",
+	"help.group_admins":        "/group_admins - List this group's admins; the group owner or a global admin can use /add_group_admin, /del_group_admin &lt;user_id&gt; (this group only)",
+	"help.group_status":        "状态 - Show this group's full status: tier, enabled features, merchant IDs, interface bindings and balance",
+	"help.leave":               "/leave - Make the bot leave this group (confirmation required)",
+	"help.configs":             "/configs - Open the group settings menu",
+	"help.features":            "/features - Show which feature plugins are active here",
+	"help.msgstats":            "/msgstats - Message statistics by type (today / this week / all time)",
+	"help.deleted":             "/deleted - Show recently deleted messages",
+	"help.mentions":            "/mentions [@username] - Messages in this group mentioning a user (default: yourself)",
+	"help.edits":               "/edits [message ID] - Show edit history (or reply to the message)",
+	"help.members":             "/members [days] - Recent joins, leaves and net growth",
+	"help.groupstats":          "/groupstats [刷新] - Member count, total joins/leaves and message stats; 刷新 re-syncs the member count",
+	"help.schedule_add":        "/schedule_add &lt;HH:MM|cron expression&gt; &lt;text&gt; - Register a scheduled message",
+	"help.schedules":           "/schedules - List scheduled messages",
+	"help.schedule_del":        "/schedule_del &lt;ID&gt; - Delete a scheduled message",
+	"help.alias":               "/alias &lt;alias&gt; &lt;command&gt; - Add a group alias for a built-in command (/alias to list, /unalias &lt;alias&gt; to remove)",
+	"help.recall":              "撤回 - Reply “撤回” to a bot message to delete it",
+	"help.section.owner":       "<b>Privileged commands</b>",
+	"help.grant":               "/grant &lt;user_id&gt; [super] - Grant admin (super for super admin)",
+	"help.revoke":              "/revoke &lt;user_id&gt; - Revoke admin permission",
+	"help.perm":                "/perm &lt;user_id&gt; [+perm -perm] - Show or change an admin's extra permissions",
+	"help.validate":            "/validate - Validate stored group settings",
+	"help.repair":              "/repair - Repair detectable group setting issues (e.g. missing tier)",
+	"help.health":              "/health - Full health check (database, payment service, worker pool, schedulers)",
+	"help.uptime":              "/uptime - Instance uptime, start time, build version and background task status",
+	"help.command_stats":       "/command_stats [days] - Top commands over the last N days (default 7)",
+	"help.broadcast":           "/broadcast [tier=merchant,upstream] [tag=label] &lt;text&gt; - Broadcast to groups (confirmation required)",
+	"help.note":                "/note [text|clear] - Set an admin note for this group",
+	"help.tag":                 "/tag add|del|clear labels - Manage tags for this group",
+	"help.balances":            "/balances [csv] - Export balances of all upstream groups (groups below the minimum first)",
+	"help.resend_bills":        "/resend_bills - Resend daily bills that failed in the last 7 days (dates already sent are skipped)",
+	"help.purge_users":         "/purge_users [days] - Archive and remove regular users inactive for the given days (default 180), confirmation required",
+	"help.configs_transfer":    "/export_configs - Export all group configs as a JSON file (private chat); send the file with caption /import_configs to preview the diff, /import_configs apply to write it",
+	"help.private_hint":        "ℹ️ Send /help inside a group to see group features (accounting, payments, interfaces, etc.)",
+	"help.section.auto_lookup": "<b>Automatic order lookup</b>",
+	"help.auto_lookup":         "Order numbers in text, photo or video captions are detected and looked up automatically; admins can send “关闭自动查单” / “开启自动查单” or use “🔍 四方自动查单” in /configs to toggle it",
+	"help.section.accounting":  "<b>Accounting (Admin+ only)</b>",
+	"help.accounting_query":    "查询记账 [#book] - Show today's ledger (all books by default)",
+	"help.accounting_delete":   "删除记账记录 - Open the delete menu for recent records",
+	"help.accounting_by_date":  "删除记账 2024-01-05 - Delete all records of a date (with confirmation)",
+	"help.accounting_clear":    "清零记账 [#book] - Clear all records or one book",
+	"help.accounting_format":   "Input examples: <code>+100U</code>, <code>-50Y</code>, <code>入100*7.2</code>, <code>出50/2Y</code>; separate books: <code>#公账 +100U</code>",
+}
@@ -0,0 +1,81 @@
+package i18n
+
+// messagesZH 中文语言包（默认语言，所有 key 必须在此登记）
+var messagesZH = map[string]string{
+	// 通用
+	"common.group_only":        "此命令只能在群组中使用",
+	"common.private_only":      "此命令只能在私聊中使用",
+	"common.group_load_failed": "获取群组信息失败，请稍后再试",
+
+	// /start
+	"start.register_failed": "注册失败，请稍后重试",
+	"start.welcome":         "👋 你好, %s!\n\n欢迎使用本 Bot。\n\n可用命令:\n/start - 开始\n/ping - 测试连接\n/admins - 查看管理员列表（需要管理员权限）",
+
+	// /lang
+	"lang.current":          "🌐 当前语言：%s（%s）\n可选：%s\n\n用法：/lang &lt;zh|en&gt;，/lang auto 恢复按 Telegram 语言自动选择",
+	"lang.source.preferred": "手动设置",
+	"lang.source.auto":      "按 Telegram 语言自动选择",
+	"lang.updated":          "语言已切换为 %s",
+	"lang.reset":            "已恢复按 Telegram 语言自动选择",
+	"lang.invalid":          "不支持的语言：%s\n可选：%s",
+	"lang.failed":           "设置语言失败，请稍后重试",
+	"lang.group_hint":       "ℹ️ 群内消息使用群组语言设置（/configs →「🌐 群组语言」），未设置时按各自偏好",
+
+	// 中间件
+	"middleware.perm_required":   "此命令需要「%s」权限",
+	"middleware.admin_only":      "此命令需要管理员权限",
+	"middleware.tier_restricted": "⚠️ 此命令仅适用于：%s\n当前群类型：%s",
+	"middleware.rate_limited":    "操作过于频繁，请 %d 秒后再试",
+
+	// /help
+	"help.title":               "<b>🆘 管理员帮助总览</b>",
+	"help.section.common":      "<b>通用命令（所有成员）</b>",
+	"help.start":               "/start - 与机器人建立会话并登记用户信息",
+	"help.ping":                "/ping - 测试机器人连接状态",
+	"help.lang":                "/lang [zh|en|auto] - 查看或切换个人偏好语言",
+	"help.section.admin":       "<b>管理员命令（Admin+）</b>",
+	"help.help":                "/help - 查看本帮助",
+	"help.admins":              "/admins [owner|super|admin] - 查看管理员列表（可按角色筛选）",
+	"help.userinfo":            "/userinfo &lt;user_id&gt; - 查询指定用户信息",
+	"help.group_admins":        "/group_admins - 查看本群管理员；群主或全局管理员可用 /add_group_admin、/del_group_admin &lt;user_id&gt; 增删（仅在本群生效）",
+	"help.group_status":        "状态 - 群内查看完整配置快照：群等级、已启用功能、商户号、接口绑定与余额",
+	"help.leave":               "/leave - 让机器人离开当前群组（需按钮二次确认）",
+	"help.configs":             "/configs - 打开群组功能配置菜单",
+	"help.features":            "/features - 查看本群各功能插件是否生效",
+	"help.msgstats":            "/msgstats - 按类型统计本群今日/本周/全部消息",
+	"help.deleted":             "/deleted - 查看本群最近被删除消息的留档",
+	"help.mentions":            "/mentions [@用户名] - 查看本群提及指定用户（默认自己）的消息",
+	"help.edits":               "/edits [消息ID] - 查看消息编辑历史（可引用目标消息）",
+	"help.members":             "/members [天数] - 查看近期入群/退群人数、净增长与退群名单",
+	"help.groupstats":          "/groupstats [刷新] - 查看群成员数与累计入群/退群、消息统计，「刷新」重新校准成员数",
+	"help.schedule_add":        "/schedule_add &lt;HH:MM|cron 表达式&gt; &lt;内容&gt; - 注册定时消息",
+	"help.schedules":           "/schedules - 查看本群定时消息",
+	"help.schedule_del":        "/schedule_del &lt;ID&gt; - 删除定时消息",
+	"help.alias":               "/alias &lt;别名&gt; &lt;命令&gt; - 为内置命令添加本群别名（/alias 查看，/unalias 别名 删除）",
+	"help.recall":              "撤回 - 引用机器人的消息发送“撤回”以删除该消息",
+	"help.section.owner":       "<b>高级管理命令</b>",
+	"help.grant":               "/grant &lt;user_id&gt; [super] - 授予管理员（super 为超级管理员）",
+	"help.revoke":              "/revoke &lt;user_id&gt; - 撤销管理员权限",
+	"help.perm":                "/perm &lt;user_id&gt; [+权限 -权限] - 查看或增减管理员的额外权限",
+	"help.validate":            "/validate - 校验数据库中的群组配置状态",
+	"help.repair":              "/repair - 自动修复可识别的群组配置问题（例如缺少 tier）",
+	"help.health":              "/health - 完整健康检查（数据库、支付服务、工作池、调度器）",
+	"help.uptime":              "/uptime - 查看实例运行时长、启动时间、版本与后台任务状态",
+	"help.command_stats":       "/command_stats [天数] - 查看近 N 天（默认 7 天）命令使用排行",
+	"help.broadcast":           "/broadcast [tier=merchant,upstream] [tag=标签] &lt;文本&gt; - 群发广播（发送前二次确认）",
+	"help.note":                "/note [备注|clear] - 设置本群管理备注",
+	"help.tag":                 "/tag add|del|clear 标签 - 管理本群标签",
+	"help.balances":            "/balances [csv] - 导出全部上游群余额对账（低于阈值的群排在前面）",
+	"help.resend_bills":        "/resend_bills - 补发最近 7 天推送失败的每日账单（已推送的日期不会重复发送）",
+	"help.purge_users":         "/purge_users [天数] - 存档并清理超过指定天数（默认 180）无活跃的普通用户，需按钮确认",
+	"help.configs_transfer":    "/export_configs - 私聊导出全部群组配置为 JSON 文件；发送该文件并附带说明 /import_configs 预览差异，/import_configs apply 写入",
+	"help.private_hint":        "ℹ️ 群组功能命令（记账、四方支付、接口管理等）请在对应群组内发送 /help 查看",
+	"help.section.auto_lookup": "<b>四方自动查单</b>",
+	"help.auto_lookup":         "自动识别文字/图片/视频标题中的订单号并异步查询，管理员可发送「关闭自动查单」/「开启自动查单」或在 /configs 的“🔍 四方自动查单”中切换",
+	"help.section.accounting":  "<b>收支记账（仅 Admin+）</b>",
+	"help.accounting_query":    "查询记账 [#账本] - 查看今日账单（默认汇总全部账本）",
+	"help.accounting_delete":   "删除记账记录 - 打开最近记录删除菜单",
+	"help.accounting_by_date":  "删除记账 2024-01-05 - 删除指定日期的全部记录（需确认）",
+	"help.accounting_clear":    "清零记账 [#账本] - 清空所有记录或指定账本",
+	"help.accounting_format":   "记账输入格式示例：<code>+100U</code>、<code>-50Y</code>、<code>入100*7.2</code>、<code>出50/2Y</code>，分账本：<code>#公账 +100U</code>",
+}
@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/features/calculator"
+	"go_bot/internal/telegram/features/crypto"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// inlineQueryCacheTime inline 结果在 Telegram 侧的缓存秒数，U 价随行情变化，不宜过长
+	inlineQueryCacheTime = 10
+	// inlineQueryMaxLength 超过该长度的查询直接返回占位结果，避免解析超长输入
+	inlineQueryMaxLength = 64
+	// inlineQuoteCacheTTL 相同 U 价查询在该时间内复用上次结果；inline 查询随输入逐字触发，避免每次都请求欧易
+	inlineQuoteCacheTTL = 5 * time.Second
+	// inlineQuoteRateWindow / inlineQuoteRateLimit 单个用户在窗口内最多发起的 U 价查询次数（命中缓存不计）
+	inlineQuoteRateWindow = time.Minute
+	inlineQuoteRateLimit  = 20
+	// inlineQuoteRateKey inline U 价查询在限流器中的命令名
+	inlineQuoteRateKey = "inline_quote"
+
+	inlineUsageText = "🤖 <b>inline 用法</b>\n" +
+		"在任意聊天输入 @机器人 后接：\n" +
+		"• 数学表达式，例如 <code>100*7.2+50</code>\n" +
+		"• U 价查询，例如 <code>z3 100</code>（a=全部、z=支付宝、k=银行卡、w=微信）\n" +
+		"• 换算，例如 <code>1000元换U</code>、<code>100U换元</code>"
+)
+
+// handleInlineQuery 处理 inline 查询：在任意聊天 @bot 计算表达式或查询 U 价
+// 不依赖群组配置，U 价使用默认浮动费率 crypto.DefaultFloatRate
+func (b *Bot) handleInlineQuery(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.InlineQuery
+	if query == nil {
+		return
+	}
+
+	userID := query.From.ID
+	quote := func(ctx context.Context, text string, floatRate float64) (string, bool, error) {
+		if !crypto.IsQuoteCommand(text) {
+			return "", false, nil
+		}
+		return b.inlineQuotes.quote(ctx, text, floatRate, func() bool {
+			return b.inlineLimiter.allow(userID, inlineQuoteRateKey).Allowed
+		})
+	}
+
+	results := buildInlineResults(ctx, query.Query, quote)
+	if _, err := botInstance.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineQueryCacheTime,
+	}); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to answer inline query: query_id=%s, error=%v", query.ID, err)
+	}
+}
+
+// inlineQuoteFunc 与 crypto.Quote 签名一致，便于测试替换行情查询
+type inlineQuoteFunc func(ctx context.Context, text string, floatRate float64) (string, bool, error)
+
+// buildInlineResults 按查询内容生成 inline 结果：表达式走计算器、U 价/换算走 crypto，空查询与无法识别的输入返回用法占位
+func buildInlineResults(ctx context.Context, query string, quote inlineQuoteFunc) []botModels.InlineQueryResult {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []botModels.InlineQueryResult{inlineArticle("usage", "输入表达式或 U 价查询", "例如 100*7.2、z3 100、1000元换U", inlineUsageText)}
+	}
+	if len([]rune(query)) > inlineQueryMaxLength {
+		return []botModels.InlineQueryResult{inlineArticle("invalid", "输入过长", fmt.Sprintf("最多 %d 个字符", inlineQueryMaxLength), inlineUsageText)}
+	}
+
+	if calculator.IsMathExpression(query) {
+		result, err := calculator.Calculate(query)
+		if err != nil {
+			message := fmt.Sprintf("❌ 计算错误: %s", html.EscapeString(err.Error()))
+			return []botModels.InlineQueryResult{inlineArticle("calc_error", "计算错误", err.Error(), message)}
+		}
+		title := fmt.Sprintf("%s = %g", query, result)
+		return []botModels.InlineQueryResult{inlineArticle("calc", title, "点击发送计算结果", calculator.FormatResult(query, result))}
+	}
+
+	if quote != nil {
+		text, ok, err := quote(ctx, query, crypto.DefaultFloatRate)
+		if ok {
+			if err != nil {
+				return []botModels.InlineQueryResult{inlineArticle("crypto_error", "查询失败", err.Error(), "❌ "+html.EscapeString(err.Error()))}
+			}
+			return []botModels.InlineQueryResult{inlineArticle("crypto", "USDT 价格："+query, "欧易 OTC 实时价格，点击发送", text)}
+		}
+	}
+
+	return []botModels.InlineQueryResult{inlineArticle("invalid", "无法识别的输入", "支持数学表达式、U 价查询与换算", inlineUsageText)}
+}
+
+// errInlineQuoteRateLimited 用户 inline U 价查询超过频率限制
+var errInlineQuoteRateLimited = errors.New("查询过于频繁，请稍后再试")
+
+// inlineQuoteCache 按查询文本短期缓存 U 价结果（仅缓存成功的结果），并发安全
+type inlineQuoteCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	fetch   inlineQuoteFunc
+	entries map[inlineQuoteCacheKey]inlineQuoteCacheEntry
+	now     func() time.Time
+}
+
+type inlineQuoteCacheKey struct {
+	text      string
+	floatRate float64
+}
+
+type inlineQuoteCacheEntry struct {
+	text      string
+	expiresAt time.Time
+}
+
+func newInlineQuoteCache(ttl time.Duration, fetch inlineQuoteFunc) *inlineQuoteCache {
+	return &inlineQuoteCache{
+		ttl:     ttl,
+		fetch:   fetch,
+		entries: make(map[inlineQuoteCacheKey]inlineQuoteCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// quote 命中缓存直接返回；未命中时先经 allow 判定是否允许请求行情（nil 表示不限制），再查询并缓存结果
+func (c *inlineQuoteCache) quote(ctx context.Context, text string, floatRate float64, allow func() bool) (string, bool, error) {
+	key := inlineQuoteCacheKey{text: strings.ToLower(strings.TrimSpace(text)), floatRate: floatRate}
+	now := c.now()
+
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	c.mu.Unlock()
+	if found && now.Before(entry.expiresAt) {
+		return entry.text, true, nil
+	}
+
+	if allow != nil && !allow() {
+		return "", true, errInlineQuoteRateLimited
+	}
+	result, ok, err := c.fetch(ctx, text, floatRate)
+	if !ok || err != nil {
+		return result, ok, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = inlineQuoteCacheEntry{text: result, expiresAt: now.Add(c.ttl)}
+	return result, true, nil
+}
+
+// inlineArticle 构造 inline 文章结果，点选后以 HTML 发送 message
+func inlineArticle(id, title, description, message string) *botModels.InlineQueryResultArticle {
+	return &botModels.InlineQueryResultArticle{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		InputMessageContent: botModels.InputTextMessageContent{
+			MessageText: message,
+			ParseMode:   botModels.ParseModeHTML,
+		},
+	}
+}
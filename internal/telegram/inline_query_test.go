@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestBuildInlineResults(t *testing.T) {
+	quote := func(ctx context.Context, text string, floatRate float64) (string, bool, error) {
+		switch text {
+		case "z3 100":
+			return "<b>OTC商家实时价格</b>", true, nil
+		case "z9":
+			return "", true, errors.New("商家序号超出范围（最多 5 个）")
+		}
+		return "", false, nil
+	}
+
+	tests := []struct {
+		query   string
+		id      string
+		message string
+	}{
+		{query: "  ", id: "usage", message: "inline 用法"},
+		{query: "100*7.2+50", id: "calc", message: "🧮 100*7.2+50 = 770"},
+		{query: "1/0", id: "calc_error", message: "❌ 计算错误"},
+		{query: "z3 100", id: "crypto", message: "OTC商家实时价格"},
+		{query: "z9", id: "crypto_error", message: "❌ 商家序号超出范围"},
+		{query: "你好", id: "invalid", message: "inline 用法"},
+		{query: strings.Repeat("1", inlineQueryMaxLength+1), id: "invalid", message: "inline 用法"},
+	}
+	for _, tt := range tests {
+		results := buildInlineResults(context.Background(), tt.query, quote)
+		if len(results) != 1 {
+			t.Fatalf("query %q: expected 1 result, got %d", tt.query, len(results))
+		}
+		article, ok := results[0].(*botModels.InlineQueryResultArticle)
+		if !ok {
+			t.Fatalf("query %q: unexpected result type %T", tt.query, results[0])
+		}
+		content, ok := article.InputMessageContent.(botModels.InputTextMessageContent)
+		if !ok {
+			t.Fatalf("query %q: unexpected content type %T", tt.query, article.InputMessageContent)
+		}
+		if article.ID != tt.id || !strings.Contains(content.MessageText, tt.message) {
+			t.Fatalf("query %q: got id=%s message=%q, want id=%s containing %q", tt.query, article.ID, content.MessageText, tt.id, tt.message)
+		}
+	}
+}
+
+func TestInlineQuoteCache(t *testing.T) {
+	calls := 0
+	cache := newInlineQuoteCache(time.Minute, func(ctx context.Context, text string, floatRate float64) (string, bool, error) {
+		calls++
+		return "price", true, nil
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	limiter := newCommandRateLimiter(time.Minute, 1, nil)
+	limiter.now = cache.now
+	allow := func() bool { return limiter.allow(1, inlineQuoteRateKey).Allowed }
+
+	for range 3 {
+		if text, ok, err := cache.quote(context.Background(), "z3 100", 0, allow); !ok || err != nil || text != "price" {
+			t.Fatalf("unexpected quote: text=%q ok=%v err=%v", text, ok, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected cached quote to fetch once, got %d", calls)
+	}
+
+	if _, ok, err := cache.quote(context.Background(), "z1", 0, allow); !ok || !errors.Is(err, errInlineQuoteRateLimited) {
+		t.Fatalf("expected rate limit error, got ok=%v err=%v", ok, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, _, err := cache.quote(context.Background(), "z1", 0, allow); err != nil || calls != 2 {
+		t.Fatalf("expected fetch after window: calls=%d err=%v", calls, err)
+	}
+}
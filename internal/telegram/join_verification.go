@@ -0,0 +1,310 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	mathrand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	joinVerifyCallbackPrefix = "join_verify:"
+	joinVerifyOptionCount    = 4
+	// joinVerifyStoreTimeout 读写验证状态的超时
+	joinVerifyStoreTimeout = 3 * time.Second
+	// joinVerifyRestoreBatch 启动时恢复的验证状态上限
+	joinVerifyRestoreBatch = 500
+	// joinVerifyExpiryRetryDelay 超时处理读写状态失败后的重试间隔
+	joinVerifyExpiryRetryDelay = time.Minute
+)
+
+// joinVerifyQuestion 入群验证算术题
+type joinVerifyQuestion struct {
+	Text    string
+	Answer  int
+	Options []int
+}
+
+// startJoinVerification 对新成员启用入群验证：禁言、发送验证题并在超时后踢出
+// 验证状态持久化到 join_verifications，Bot 重启后仍能完成验证，超时处理由 restoreJoinVerifications 接续
+func (b *Bot) startJoinVerification(ctx context.Context, chat botModels.Chat, member botModels.User, settings models.GroupSettings) {
+	if b.joinVerifyRepo == nil {
+		logger.Ctx(ctx).Warnf("Join verification skipped, store unavailable: chat_id=%d user_id=%d", chat.ID, member.ID)
+		return
+	}
+	timeout := models.JoinVerifyTimeoutDuration(settings)
+
+	if _, err := b.bot.RestrictChatMember(ctx, &bot.RestrictChatMemberParams{
+		ChatID:      chat.ID,
+		UserID:      member.ID,
+		Permissions: &botModels.ChatPermissions{},
+	}); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to restrict new member for verification: chat_id=%d user_id=%d err=%v", chat.ID, member.ID, err)
+		return
+	}
+
+	question := newJoinVerifyQuestion()
+	verification := &models.JoinVerification{
+		Token:     newPendingToken(),
+		ChatID:    chat.ID,
+		UserID:    member.ID,
+		Answer:    question.Answer,
+		ExpiresAt: time.Now().Add(timeout),
+	}
+
+	text := fmt.Sprintf("👋 欢迎 %s 加入！\n\n为防止广告机器人，请在 %s 内点击正确答案完成验证：\n<b>%s = ?</b>\n\n超时未验证将被移出群组。",
+		userMention(member), formatDuration(timeout), question.Text)
+	sent, err := b.sendMessageWithMarkupAndMessage(ctx, chat.ID, text, buildJoinVerifyKeyboard(verification.Token, question.Options))
+	if err != nil || sent == nil {
+		logger.Ctx(ctx).Errorf("Failed to send join verification message: chat_id=%d user_id=%d err=%v", chat.ID, member.ID, err)
+		b.liftJoinRestriction(ctx, chat.ID, member.ID)
+		return
+	}
+	verification.MessageID = sent.ID
+
+	// 状态写入失败时无法完成验证，直接放行并收回验证题，避免成员一直被禁言
+	saveCtx, cancel := context.WithTimeout(ctx, joinVerifyStoreTimeout)
+	err = b.joinVerifyRepo.Save(saveCtx, verification)
+	cancel()
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to save join verification: chat_id=%d user_id=%d err=%v", chat.ID, member.ID, err)
+		b.liftJoinRestriction(ctx, chat.ID, member.ID)
+		b.deleteJoinVerifyMessage(ctx, verification)
+		return
+	}
+
+	logger.Ctx(ctx).Infof("Join verification started: chat_id=%d user_id=%d timeout=%s", chat.ID, member.ID, timeout)
+	b.scheduleJoinVerifyExpiry(verification)
+}
+
+// scheduleJoinVerifyExpiry 到期后踢出仍未验证的成员；已到期的立即处理
+func (b *Bot) scheduleJoinVerifyExpiry(verification *models.JoinVerification) {
+	b.expireJoinVerificationAfter(verification, time.Until(verification.ExpiresAt))
+}
+
+func (b *Bot) expireJoinVerificationAfter(verification *models.JoinVerification, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		expireCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		claimed, err := b.claimJoinVerification(expireCtx, verification.Token)
+		if err != nil {
+			// 状态读写失败时稍后重试，不能让成员一直处于禁言状态
+			b.expireJoinVerificationAfter(verification, joinVerifyExpiryRetryDelay)
+			return
+		}
+		if claimed {
+			b.kickUnverifiedMember(expireCtx, verification)
+		}
+	})
+}
+
+// restoreJoinVerifications 启动时接续持久化的验证：未超时的重新安排超时处理，重启期间已超时的按超时踢出
+func (b *Bot) restoreJoinVerifications(ctx context.Context) {
+	if b.joinVerifyRepo == nil {
+		return
+	}
+	listCtx, cancel := context.WithTimeout(ctx, joinVerifyStoreTimeout)
+	verifications, err := b.joinVerifyRepo.List(listCtx, joinVerifyRestoreBatch)
+	cancel()
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to restore join verifications: %v", err)
+		return
+	}
+
+	for _, verification := range verifications {
+		b.scheduleJoinVerifyExpiry(verification)
+	}
+	if len(verifications) > 0 {
+		logger.Ctx(ctx).Infof("Join verifications restored: %d", len(verifications))
+	}
+}
+
+// handleJoinVerifyCallback 处理入群验证按钮
+func (b *Bot) handleJoinVerifyCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil {
+		return
+	}
+
+	token, choice, ok := parseJoinVerifyCallback(query.Data)
+	if !ok {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的验证请求", true)
+		return
+	}
+
+	verification, err := b.getJoinVerification(ctx, token)
+	if err != nil {
+		b.answerCallback(ctx, botInstance, query.ID, "验证服务暂不可用，请稍后重试", true)
+		return
+	}
+	if verification == nil || time.Now().After(verification.ExpiresAt) {
+		b.answerCallback(ctx, botInstance, query.ID, "验证已失效", true)
+		return
+	}
+	if query.From.ID != verification.UserID {
+		b.answerCallback(ctx, botInstance, query.ID, "这不是你的验证题", true)
+		return
+	}
+	if choice != verification.Answer {
+		b.answerCallback(ctx, botInstance, query.ID, "❌ 答案错误，请重新选择", true)
+		return
+	}
+	claimed, err := b.claimJoinVerification(ctx, token)
+	if err != nil {
+		b.answerCallback(ctx, botInstance, query.ID, "验证服务暂不可用，请稍后重试", true)
+		return
+	}
+	if !claimed {
+		b.answerCallback(ctx, botInstance, query.ID, "验证已失效", true)
+		return
+	}
+
+	b.liftJoinRestriction(ctx, verification.ChatID, verification.UserID)
+	b.answerCallback(ctx, botInstance, query.ID, "✅ 验证通过，欢迎加入", false)
+	b.deleteJoinVerifyMessage(ctx, verification)
+	logger.Ctx(ctx).Infof("Join verification passed: chat_id=%d user_id=%d", verification.ChatID, verification.UserID)
+}
+
+// deleteJoinVerifyMessage 删除验证题消息
+func (b *Bot) deleteJoinVerifyMessage(ctx context.Context, verification *models.JoinVerification) {
+	if _, err := b.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+		ChatID:    verification.ChatID,
+		MessageID: verification.MessageID,
+	}); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to delete join verification message: chat_id=%d msg=%d err=%v",
+			verification.ChatID, verification.MessageID, err)
+	}
+}
+
+// liftJoinRestriction 解除入群验证禁言，恢复为群组默认权限
+func (b *Bot) liftJoinRestriction(ctx context.Context, chatID, userID int64) {
+	permissions := &botModels.ChatPermissions{
+		CanSendMessages:       true,
+		CanSendAudios:         true,
+		CanSendDocuments:      true,
+		CanSendPhotos:         true,
+		CanSendVideos:         true,
+		CanSendVideoNotes:     true,
+		CanSendVoiceNotes:     true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+	}
+	if chat, err := b.bot.GetChat(ctx, &bot.GetChatParams{ChatID: chatID}); err == nil && chat.Permissions != nil {
+		permissions = chat.Permissions
+	}
+
+	if _, err := b.bot.RestrictChatMember(ctx, &bot.RestrictChatMemberParams{
+		ChatID:      chatID,
+		UserID:      userID,
+		Permissions: permissions,
+	}); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to lift join restriction: chat_id=%d user_id=%d err=%v", chatID, userID, err)
+	}
+}
+
+// kickUnverifiedMember 踢出超时未验证的成员（封禁后立即解封，允许其重新申请入群）
+func (b *Bot) kickUnverifiedMember(ctx context.Context, verification *models.JoinVerification) {
+	if _, err := b.bot.BanChatMember(ctx, &bot.BanChatMemberParams{
+		ChatID: verification.ChatID,
+		UserID: verification.UserID,
+	}); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to kick unverified member: chat_id=%d user_id=%d err=%v",
+			verification.ChatID, verification.UserID, err)
+		return
+	}
+	if _, err := b.bot.UnbanChatMember(ctx, &bot.UnbanChatMemberParams{
+		ChatID:       verification.ChatID,
+		UserID:       verification.UserID,
+		OnlyIfBanned: true,
+	}); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to unban kicked member: chat_id=%d user_id=%d err=%v",
+			verification.ChatID, verification.UserID, err)
+	}
+
+	b.editMessage(ctx, verification.ChatID, verification.MessageID, "⏰ 入群验证超时，该成员已被移出群组。", nil)
+	logger.Ctx(ctx).Infof("Unverified member kicked: chat_id=%d user_id=%d", verification.ChatID, verification.UserID)
+}
+
+// getJoinVerification 查询验证状态，不存在时返回 nil
+func (b *Bot) getJoinVerification(ctx context.Context, token string) (*models.JoinVerification, error) {
+	getCtx, cancel := context.WithTimeout(ctx, joinVerifyStoreTimeout)
+	defer cancel()
+	verification, err := b.joinVerifyRepo.Get(getCtx, token)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to get join verification: token=%s err=%v", token, err)
+	}
+	return verification, err
+}
+
+// claimJoinVerification 删除验证状态，返回是否由本次调用删除（验证通过与超时、多实例之间只处理一次）
+func (b *Bot) claimJoinVerification(ctx context.Context, token string) (bool, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, joinVerifyStoreTimeout)
+	defer cancel()
+	claimed, err := b.joinVerifyRepo.Delete(claimCtx, token)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to claim join verification: token=%s err=%v", token, err)
+	}
+	return claimed, err
+}
+
+// newJoinVerifyQuestion 生成一位数加法题，选项包含正确答案并打乱顺序
+func newJoinVerifyQuestion() joinVerifyQuestion {
+	a := mathrand.IntN(9) + 1
+	c := mathrand.IntN(9) + 1
+	answer := a + c
+
+	seen := map[int]struct{}{answer: {}}
+	options := []int{answer}
+	for len(options) < joinVerifyOptionCount {
+		candidate := answer + mathrand.IntN(9) - 4
+		if candidate < 2 {
+			continue
+		}
+		if _, dup := seen[candidate]; dup {
+			continue
+		}
+		seen[candidate] = struct{}{}
+		options = append(options, candidate)
+	}
+	mathrand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+
+	return joinVerifyQuestion{
+		Text:    fmt.Sprintf("%d + %d", a, c),
+		Answer:  answer,
+		Options: options,
+	}
+}
+
+func buildJoinVerifyKeyboard(token string, options []int) *botModels.InlineKeyboardMarkup {
+	row := make([]botModels.InlineKeyboardButton, 0, len(options))
+	for _, option := range options {
+		row = append(row, botModels.InlineKeyboardButton{
+			Text:         strconv.Itoa(option),
+			CallbackData: fmt.Sprintf("%s%s:%d", joinVerifyCallbackPrefix, token, option),
+		})
+	}
+	return &botModels.InlineKeyboardMarkup{InlineKeyboard: [][]botModels.InlineKeyboardButton{row}}
+}
+
+// parseJoinVerifyCallback 解析回调数据：join_verify:<token>:<answer>
+func parseJoinVerifyCallback(data string) (string, int, bool) {
+	payload := strings.TrimPrefix(data, joinVerifyCallbackPrefix)
+	token, choiceText, found := strings.Cut(payload, ":")
+	if !found || token == "" {
+		return "", 0, false
+	}
+	choice, err := strconv.Atoi(choiceText)
+	if err != nil {
+		return "", 0, false
+	}
+	return token, choice, true
+}
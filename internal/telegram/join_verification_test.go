@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+func TestNewJoinVerifyQuestion(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		question := newJoinVerifyQuestion()
+		if len(question.Options) != joinVerifyOptionCount {
+			t.Fatalf("expected %d options, got %v", joinVerifyOptionCount, question.Options)
+		}
+
+		seen := make(map[int]struct{})
+		hasAnswer := false
+		for _, option := range question.Options {
+			if _, dup := seen[option]; dup {
+				t.Fatalf("duplicate option in %v", question.Options)
+			}
+			seen[option] = struct{}{}
+			if option == question.Answer {
+				hasAnswer = true
+			}
+		}
+		if !hasAnswer {
+			t.Fatalf("answer %d missing from options %v", question.Answer, question.Options)
+		}
+	}
+}
+
+func TestJoinVerifyCallbackRoundTrip(t *testing.T) {
+	keyboard := buildJoinVerifyKeyboard("abc123", []int{7, 12})
+	data := keyboard.InlineKeyboard[0][1].CallbackData
+	if !strings.HasPrefix(data, joinVerifyCallbackPrefix) {
+		t.Fatalf("unexpected callback data: %s", data)
+	}
+
+	token, choice, ok := parseJoinVerifyCallback(data)
+	if !ok || token != "abc123" || choice != 12 {
+		t.Fatalf("unexpected parse result: token=%q choice=%d ok=%v", token, choice, ok)
+	}
+
+	if _, _, ok := parseJoinVerifyCallback(joinVerifyCallbackPrefix + "abc123:x"); ok {
+		t.Fatalf("expected invalid answer to be rejected")
+	}
+}
+
+// memoryJoinVerificationRepository 内存版入群验证状态，按 token 存储
+type memoryJoinVerificationRepository struct {
+	repository.JoinVerificationRepository
+	records map[string]*models.JoinVerification
+}
+
+func (r *memoryJoinVerificationRepository) Get(ctx context.Context, token string) (*models.JoinVerification, error) {
+	return r.records[token], nil
+}
+
+func (r *memoryJoinVerificationRepository) Delete(ctx context.Context, token string) (bool, error) {
+	_, ok := r.records[token]
+	delete(r.records, token)
+	return ok, nil
+}
+
+func TestClaimJoinVerificationOnlyOnce(t *testing.T) {
+	repo := &memoryJoinVerificationRepository{records: map[string]*models.JoinVerification{
+		"t1": {Token: "t1", UserID: 1},
+	}}
+	b := &Bot{joinVerifyRepo: repo}
+	ctx := context.Background()
+
+	if verification, err := b.getJoinVerification(ctx, "t1"); err != nil || verification == nil {
+		t.Fatalf("expected stored verification, got %+v err=%v", verification, err)
+	}
+	if claimed, err := b.claimJoinVerification(ctx, "t1"); err != nil || !claimed {
+		t.Fatalf("expected first claim to succeed, claimed=%v err=%v", claimed, err)
+	}
+	if claimed, _ := b.claimJoinVerification(ctx, "t1"); claimed {
+		t.Fatalf("expected second claim to fail so pass and timeout never both run")
+	}
+}
@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// mediaAlertNotifyInterval 同一用户在同一群的告警提醒间隔，期间的告警只记日志
+const mediaAlertNotifyInterval = 10 * time.Minute
+
+// checkMediaAlert 按群组配置检查超大文件与可疑类型：命中时记录日志，开启提醒时在群内回复提醒管理员
+// 未配置任何规则的群直接跳过，正常媒体不会产生提醒
+func (b *Bot) checkMediaAlert(ctx context.Context, msg *botModels.Message, fileSize int64, mimeType string, fileNames []string) {
+	if b.groupService == nil || msg == nil || msg.From == nil {
+		return
+	}
+	if !isGroupChatType(msg.Chat.Type) {
+		return
+	}
+
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	})
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to load group for media alert: chat_id=%d err=%v", msg.Chat.ID, err)
+		return
+	}
+	if !models.HasMediaAlertRules(group.Settings) {
+		return
+	}
+
+	reasons := models.MediaAlertReasons(group.Settings, fileSize, mimeType, fileNames)
+	if len(reasons) == 0 {
+		return
+	}
+
+	logger.Ctx(ctx).Warnf("Media alert: chat_id=%d, message_id=%d, user_id=%d, size=%d, mime=%q, files=%q, reasons=%q",
+		msg.Chat.ID, msg.ID, msg.From.ID, fileSize, mimeType, fileNames, reasons)
+
+	if !group.Settings.MediaAlertNotify || !b.allowMediaAlertNotify(msg.Chat.ID, msg.From.ID, time.Now()) {
+		return
+	}
+	b.sendMessage(ctx, msg.Chat.ID, formatMediaAlert(*msg.From, fileNames, reasons), msg.ID)
+}
+
+// allowMediaAlertNotify 同一群同一用户在间隔内只提醒一次，连续发送多个文件时不刷屏
+func (b *Bot) allowMediaAlertNotify(chatID, userID int64, now time.Time) bool {
+	b.mediaAlertMu.Lock()
+	defer b.mediaAlertMu.Unlock()
+	if b.mediaAlertSentAt == nil {
+		b.mediaAlertSentAt = make(map[string]time.Time)
+	}
+
+	key := fmt.Sprintf("%d:%d", chatID, userID)
+	if last, ok := b.mediaAlertSentAt[key]; ok && now.Sub(last) < mediaAlertNotifyInterval {
+		return false
+	}
+
+	// 顺带清理已过间隔的记录，避免 map 无限增长
+	for k, last := range b.mediaAlertSentAt {
+		if now.Sub(last) >= mediaAlertNotifyInterval {
+			delete(b.mediaAlertSentAt, k)
+		}
+	}
+	b.mediaAlertSentAt[key] = now
+	return true
+}
+
+// formatMediaAlert 群内提醒文案
+func formatMediaAlert(sender botModels.User, fileNames []string, reasons []string) string {
+	var text strings.Builder
+	text.WriteString("⚠️ <b>媒体告警</b>\n")
+	text.WriteString(fmt.Sprintf("发送人：%s\n", userMention(sender)))
+	if len(fileNames) > 0 {
+		text.WriteString(fmt.Sprintf("文件：%s\n", html.EscapeString(strings.Join(fileNames, "、"))))
+	}
+	text.WriteString("原因：\n")
+	for _, reason := range reasons {
+		text.WriteString("• " + html.EscapeString(reason) + "\n")
+	}
+	text.WriteString("请管理员留意")
+	return text.String()
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestAllowMediaAlertNotifyThrottlesPerUser(t *testing.T) {
+	b := &Bot{}
+	now := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+
+	if !b.allowMediaAlertNotify(-1, 7, now) {
+		t.Fatalf("expected first alert allowed")
+	}
+	if b.allowMediaAlertNotify(-1, 7, now.Add(time.Minute)) {
+		t.Fatalf("expected repeated alert within interval suppressed")
+	}
+	if !b.allowMediaAlertNotify(-1, 8, now.Add(time.Minute)) {
+		t.Fatalf("expected other user allowed")
+	}
+	if !b.allowMediaAlertNotify(-1, 7, now.Add(mediaAlertNotifyInterval+time.Minute)) {
+		t.Fatalf("expected alert allowed after interval")
+	}
+	if len(b.mediaAlertSentAt) != 1 {
+		t.Fatalf("expected expired entries pruned, got %d", len(b.mediaAlertSentAt))
+	}
+}
+
+func TestFormatMediaAlertEscapesFileNames(t *testing.T) {
+	text := formatMediaAlert(botModels.User{ID: 7, FirstName: "Tom"}, []string{"<a>.exe"}, []string{"扩展名 .exe 在黑名单中"})
+	if !strings.Contains(text, "&lt;a&gt;.exe") || !strings.Contains(text, "tg://user?id=7") || !strings.Contains(text, "• 扩展名 .exe 在黑名单中") {
+		t.Fatalf("unexpected alert text: %s", text)
+	}
+}
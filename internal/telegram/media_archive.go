@@ -0,0 +1,251 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go_bot/internal/archive"
+	"go_bot/internal/config"
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// mediaArchiveQueueSize 待归档队列容量，积压超过后新任务直接丢弃（记警告日志）
+	mediaArchiveQueueSize = 100
+	// mediaArchiveDownloadTimeout 单次从 Telegram 下载文件的超时
+	mediaArchiveDownloadTimeout = 60 * time.Second
+	// mediaArchiveRetryBase 失败重试的初始等待，之后每次翻倍
+	mediaArchiveRetryBase = 2 * time.Second
+)
+
+// mediaArchiveJob 单个待归档的媒体文件
+type mediaArchiveJob struct {
+	ChatID       int64
+	MessageID    int64
+	MessageType  string
+	FileID       string
+	FileUniqueID string
+	FileName     string
+	MimeType     string
+	FileSize     int64
+	SentAt       time.Time
+}
+
+// mediaArchiveDownloadFunc 按 file_id 下载文件，超过 maxBytes 时返回错误
+type mediaArchiveDownloadFunc func(ctx context.Context, fileID string, maxBytes int64) ([]byte, error)
+
+// mediaArchiveRecordFunc 把归档地址写回消息文档
+type mediaArchiveRecordFunc func(ctx context.Context, chatID, messageID int64, archiveURL string) error
+
+// mediaArchiver 媒体归档工作池：固定数量的 worker 从有界队列取任务，下载后写入存储并记录归档地址，失败按指数退避重试有限次
+type mediaArchiver struct {
+	storage     archive.Storage
+	download    mediaArchiveDownloadFunc
+	record      mediaArchiveRecordFunc
+	maxBytes    int64
+	maxAttempts int
+	retryBase   time.Duration
+
+	jobs   chan mediaArchiveJob
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newMediaArchiver(storage archive.Storage, download mediaArchiveDownloadFunc, record mediaArchiveRecordFunc, maxBytes int64, maxAttempts int) *mediaArchiver {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &mediaArchiver{
+		storage:     storage,
+		download:    download,
+		record:      record,
+		maxBytes:    maxBytes,
+		maxAttempts: maxAttempts,
+		retryBase:   mediaArchiveRetryBase,
+		jobs:        make(chan mediaArchiveJob, mediaArchiveQueueSize),
+	}
+}
+
+func (a *mediaArchiver) start(concurrency int) {
+	if a == nil || a.cancel != nil {
+		return
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	for range concurrency {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-a.jobs:
+					a.process(ctx, job)
+				}
+			}
+		}()
+	}
+	logger.L().Infof("Media archiver started: concurrency=%d max_bytes=%d max_attempts=%d", concurrency, a.maxBytes, a.maxAttempts)
+}
+
+// stop 取消进行中的任务并等待 worker 退出，队列中尚未处理的任务直接丢弃
+func (a *mediaArchiver) stop() {
+	if a == nil || a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+	a.cancel = nil
+	if pending := len(a.jobs); pending > 0 {
+		logger.L().Warnf("Media archiver stopped with %d pending jobs dropped", pending)
+	}
+	logger.L().Info("Media archiver stopped")
+}
+
+// enqueue 非阻塞地提交任务：超过大小上限或队列已满时返回 false，不影响消息处理
+func (a *mediaArchiver) enqueue(job mediaArchiveJob) bool {
+	if job.FileSize > a.maxBytes {
+		logger.L().Infof("Media archive skipped, file too large: chat_id=%d message_id=%d size=%d max=%d",
+			job.ChatID, job.MessageID, job.FileSize, a.maxBytes)
+		return false
+	}
+	select {
+	case a.jobs <- job:
+		return true
+	default:
+		logger.L().Warnf("Media archive queue full, dropping job: chat_id=%d message_id=%d", job.ChatID, job.MessageID)
+		return false
+	}
+}
+
+// process 执行单个任务，失败按 retryBase、2×retryBase… 退避后重试，最多 maxAttempts 次；消息未记录的错误不重试
+func (a *mediaArchiver) process(ctx context.Context, job mediaArchiveJob) {
+	var err error
+	for attempt := 1; attempt <= a.maxAttempts; attempt++ {
+		var location string
+		if location, err = a.archiveOnce(ctx, job); err == nil {
+			logger.L().Infof("Media archived: chat_id=%d message_id=%d type=%s size=%d attempt=%d url=%s",
+				job.ChatID, job.MessageID, job.MessageType, job.FileSize, attempt, location)
+			return
+		}
+		// 消息未记录时重试也无处回写，直接放弃
+		if attempt == a.maxAttempts || errors.Is(err, service.ErrMessageNotRecorded) {
+			break
+		}
+		logger.L().Warnf("Media archive attempt failed: chat_id=%d message_id=%d attempt=%d err=%v", job.ChatID, job.MessageID, attempt, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.retryBase << (attempt - 1)):
+		}
+	}
+	if !errors.Is(err, context.Canceled) {
+		logger.L().Errorf("Media archive failed: chat_id=%d message_id=%d err=%v", job.ChatID, job.MessageID, err)
+	}
+}
+
+func (a *mediaArchiver) archiveOnce(ctx context.Context, job mediaArchiveJob) (string, error) {
+	data, err := a.download(ctx, job.FileID, a.maxBytes)
+	if err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+	location, err := a.storage.Put(ctx, mediaArchiveKey(job), data, job.MimeType)
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	if err := a.record(ctx, job.ChatID, job.MessageID, location); err != nil {
+		return "", fmt.Errorf("record: %w", err)
+	}
+	return location, nil
+}
+
+// mediaArchiveKey 归档路径：<chat_id>/<发送日期>/<message_id>_<file_unique_id><扩展名>，同一消息重试时覆盖同一对象
+func mediaArchiveKey(job mediaArchiveJob) string {
+	date := job.SentAt.In(models.DefaultLocation()).Format("2006-01-02")
+	name := fmt.Sprintf("%d_%s%s", job.MessageID, sanitizeArchiveName(job.FileUniqueID), mediaArchiveExt(job))
+	return fmt.Sprintf("%d/%s/%s", job.ChatID, date, name)
+}
+
+// mediaArchiveExt 优先取原文件名的扩展名，其次按 MIME 推断，图片默认 .jpg
+func mediaArchiveExt(job mediaArchiveJob) string {
+	ext := strings.ToLower(filepath.Ext(job.FileName))
+	if ext == "" && job.MimeType != "" {
+		if exts, err := mime.ExtensionsByType(job.MimeType); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	if ext == "" && job.MessageType == models.MessageTypePhoto {
+		ext = ".jpg"
+	}
+	ext = "." + sanitizeArchiveName(strings.TrimPrefix(ext, "."))
+	if ext == "." || len(ext) > 10 {
+		return ""
+	}
+	return ext
+}
+
+// sanitizeArchiveName 只保留字母、数字、下划线与中划线，避免对象 key 需要转义或逃逸目录
+func sanitizeArchiveName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// initMediaArchiver 按配置创建归档存储与工作池，未配置后端时不启用
+func (b *Bot) initMediaArchiver(cfg config.MediaArchiveConfig) error {
+	storage, err := archive.NewStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to init media archive storage: %w", err)
+	}
+	if storage == nil {
+		return nil
+	}
+
+	download := func(ctx context.Context, fileID string, maxBytes int64) ([]byte, error) {
+		return b.downloadTelegramFile(ctx, b.bot, fileID, maxBytes, mediaArchiveDownloadTimeout)
+	}
+	b.mediaArchiver = newMediaArchiver(storage, download, b.messageService.RecordMediaArchive, cfg.MaxBytes, cfg.MaxAttempts)
+	b.mediaArchiver.start(cfg.Concurrency)
+	logger.L().Infof("Media archive enabled: backend=%s", cfg.Backend)
+	return nil
+}
+
+// enqueueMediaArchive 群组配置了该媒体类型的归档时提交归档任务
+func (b *Bot) enqueueMediaArchive(ctx context.Context, msg *botModels.Message, job mediaArchiveJob) {
+	if b.mediaArchiver == nil || b.groupService == nil || msg == nil || !isGroupChatType(msg.Chat.Type) {
+		return
+	}
+
+	group, err := b.groupService.GetOrCreateGroup(ctx, &service.TelegramChatInfo{
+		ChatID:   msg.Chat.ID,
+		Type:     string(msg.Chat.Type),
+		Title:    msg.Chat.Title,
+		Username: msg.Chat.Username,
+	})
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to load group for media archive: chat_id=%d err=%v", msg.Chat.ID, err)
+		return
+	}
+	if !models.ShouldArchiveMedia(group.Settings, job.MessageType) {
+		return
+	}
+	b.mediaArchiver.enqueue(job)
+}
@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+// memoryArchiveStorage 记录写入的 key，前 failures 次写入返回错误
+type memoryArchiveStorage struct {
+	mu       sync.Mutex
+	failures int
+	puts     []string
+}
+
+func (s *memoryArchiveStorage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return "", errors.New("storage unavailable")
+	}
+	s.puts = append(s.puts, key)
+	return "mem://" + key, nil
+}
+
+func TestMediaArchiverRetriesAndRecords(t *testing.T) {
+	storage := &memoryArchiveStorage{failures: 1}
+	recorded := make(chan string, 1)
+	archiver := newMediaArchiver(storage,
+		func(ctx context.Context, fileID string, maxBytes int64) ([]byte, error) { return []byte("data"), nil },
+		func(ctx context.Context, chatID, messageID int64, archiveURL string) error {
+			recorded <- archiveURL
+			return nil
+		}, 1024, 3)
+	archiver.retryBase = time.Millisecond
+	archiver.start(1)
+	defer archiver.stop()
+
+	job := mediaArchiveJob{
+		ChatID:       -100,
+		MessageID:    42,
+		MessageType:  models.MessageTypeDocument,
+		FileID:       "file",
+		FileUniqueID: "uniq",
+		FileName:     "report.PDF",
+		FileSize:     4,
+		SentAt:       time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC),
+	}
+	if !archiver.enqueue(job) {
+		t.Fatal("expected job to be enqueued")
+	}
+
+	select {
+	case url := <-recorded:
+		if url != "mem://-100/2024-01-02/42_uniq.pdf" {
+			t.Fatalf("unexpected archive url: %s", url)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected archive to succeed after retry")
+	}
+}
+
+func TestMediaArchiverGivesUpAfterMaxAttempts(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	done := make(chan struct{})
+	archiver := newMediaArchiver(&memoryArchiveStorage{},
+		func(ctx context.Context, fileID string, maxBytes int64) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts == 2 {
+				close(done)
+			}
+			return nil, errors.New("download failed")
+		},
+		func(ctx context.Context, chatID, messageID int64, archiveURL string) error {
+			t.Error("record should not be called when download fails")
+			return nil
+		}, 1024, 2)
+	archiver.retryBase = time.Millisecond
+	archiver.start(1)
+
+	archiver.enqueue(mediaArchiveJob{ChatID: -100, MessageID: 1, FileSize: 10})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected two download attempts")
+	}
+	archiver.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Fatalf("expected exactly 2 attempts, got %d", attempts)
+	}
+}
+
+func TestMediaArchiverDoesNotRetryUnrecordedMessage(t *testing.T) {
+	var mu sync.Mutex
+	records := 0
+	done := make(chan struct{})
+	archiver := newMediaArchiver(&memoryArchiveStorage{},
+		func(ctx context.Context, fileID string, maxBytes int64) ([]byte, error) { return []byte("data"), nil },
+		func(ctx context.Context, chatID, messageID int64, archiveURL string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			records++
+			if records == 1 {
+				close(done)
+			}
+			return service.ErrMessageNotRecorded
+		}, 1024, 3)
+	archiver.retryBase = time.Millisecond
+	archiver.start(1)
+
+	archiver.enqueue(mediaArchiveJob{ChatID: -100, MessageID: 1, FileSize: 4})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected record to be attempted")
+	}
+	// 留出足够时间让错误的重试发生
+	time.Sleep(20 * time.Millisecond)
+	archiver.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if records != 1 {
+		t.Fatalf("expected a single record attempt, got %d", records)
+	}
+}
+
+func TestMediaArchiverSkipsOversizedFiles(t *testing.T) {
+	archiver := newMediaArchiver(&memoryArchiveStorage{}, nil, nil, 1024, 1)
+	if archiver.enqueue(mediaArchiveJob{FileSize: 2048}) {
+		t.Fatal("expected oversized file to be skipped")
+	}
+	if len(archiver.jobs) != 0 {
+		t.Fatalf("expected empty queue, got %d", len(archiver.jobs))
+	}
+}
+
+func TestMediaArchiveKey(t *testing.T) {
+	sentAt := time.Date(2024, 1, 2, 20, 0, 0, 0, time.UTC) // 北京时间 1 月 3 日
+	cases := []struct {
+		job  mediaArchiveJob
+		want string
+	}{
+		{job: mediaArchiveJob{ChatID: -1, MessageID: 5, FileUniqueID: "AbC", MessageType: models.MessageTypePhoto, SentAt: sentAt}, want: "-1/2024-01-03/5_AbC.jpg"},
+		{job: mediaArchiveJob{ChatID: -1, MessageID: 6, FileUniqueID: "a/../b", FileName: "x.tar.gz", SentAt: sentAt}, want: "-1/2024-01-03/6_ab.gz"},
+		{job: mediaArchiveJob{ChatID: -1, MessageID: 7, FileUniqueID: "v", MimeType: "application/pdf", SentAt: sentAt}, want: "-1/2024-01-03/7_v.pdf"},
+		{job: mediaArchiveJob{ChatID: -1, MessageID: 8, FileUniqueID: "v", FileName: "noext", SentAt: sentAt}, want: "-1/2024-01-03/8_v"},
+	}
+	for _, tc := range cases {
+		if got := mediaArchiveKey(tc.job); got != tc.want {
+			t.Fatalf("mediaArchiveKey(%+v) = %s, want %s", tc.job, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	messageSearchCommand        = "搜索消息"
+	messageSearchCallbackPrefix = "msg_search:"
+	messageSearchPageSize       = 10
+	messageSearchMaxResults     = 50
+	messageSearchMaxKeyword     = 48 // 关键词最大字节数（约 16 个汉字），保证回调数据不超过 64 字节
+	messageSearchSnippetRunes   = 40
+	messageSearchURLPrefix      = "url:" // 「搜索消息 url:域名」按链接检索
+)
+
+// messageSearchResult 一次搜索的结果（已截断到 messageSearchMaxResults）
+type messageSearchResult struct {
+	Keyword   string
+	Messages  []*models.Message
+	Truncated bool
+	Mention   bool // 提及查询（/mentions），Keyword 为被提及的用户
+}
+
+// handleMessageSearch 处理"搜索消息 关键词"命令
+func (b *Bot) handleMessageSearch(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	keyword, ok := parseMessageSearchKeyword(update.Message.Text)
+	if !ok {
+		return
+	}
+	if keyword == "" {
+		b.sendErrorMessage(ctx, chatID, "用法：搜索消息 关键词", update.Message.ID)
+		return
+	}
+	if len(keyword) > messageSearchMaxKeyword {
+		b.sendErrorMessage(ctx, chatID, fmt.Sprintf("关键词过长，最多 %d 字节（约 %d 个汉字）", messageSearchMaxKeyword, messageSearchMaxKeyword/3), update.Message.ID)
+		return
+	}
+
+	text, markup, err := b.buildMessageSearchPage(ctx, chatID, keyword, 1)
+	if err != nil {
+		b.sendErrorMessage(ctx, chatID, safeHTML(err.Error()), update.Message.ID)
+		return
+	}
+
+	if _, err := b.sendMessageWithMarkupAndMessage(ctx, chatID, text, markup, update.Message.ID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to send message search result: chat_id=%d err=%v", chatID, err)
+	}
+}
+
+// handleMessageSearchCallback 处理搜索结果翻页
+func (b *Bot) handleMessageSearchCallback(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+	query := update.CallbackQuery
+	if query == nil || query.Message.Message == nil {
+		return
+	}
+
+	if !b.checkPermission(ctx, botInstance, update, models.PermAdmin) {
+		return
+	}
+
+	page, keyword, ok := parseMessageSearchCallback(query.Data)
+	if !ok {
+		b.answerCallback(ctx, botInstance, query.ID, "无效的翻页请求", true)
+		return
+	}
+
+	msg := query.Message.Message
+	text, markup, err := b.buildMessageSearchPage(ctx, msg.Chat.ID, keyword, page)
+	if err != nil {
+		b.answerCallback(ctx, botInstance, query.ID, err.Error(), true)
+		return
+	}
+
+	b.answerCallback(ctx, botInstance, query.ID, "", false)
+	b.editMessage(ctx, msg.Chat.ID, msg.ID, text, markup)
+}
+
+// buildMessageSearchPage 执行搜索并渲染指定页
+func (b *Bot) buildMessageSearchPage(ctx context.Context, chatID int64, keyword string, page int) (string, botModels.ReplyMarkup, error) {
+	messages, err := b.searchMessages(ctx, chatID, keyword)
+	if err != nil {
+		return "", nil, err
+	}
+
+	text, totalPages := b.renderMessageSearchPage(ctx, chatID, newMessageSearchResult(keyword, messages), page)
+	return text, buildMessageSearchKeyboard(keyword, page, totalPages), nil
+}
+
+// searchMessages 按关键词检索：@用户名、#话题、url: 先按实体检索，没有命中时（例如实体入库前的旧消息、
+// 正文里手打的 #、@ 未被 Telegram 识别为实体）回退到正文关键词检索
+func (b *Bot) searchMessages(ctx context.Context, chatID int64, keyword string) ([]*models.Message, error) {
+	if kind, value, ok := parseMessageEntityQuery(keyword); ok {
+		messages, err := b.messageService.SearchMessagesByEntity(ctx, chatID, kind, value, messageSearchMaxResults+1)
+		if err != nil || len(messages) > 0 {
+			return messages, err
+		}
+	}
+	return b.messageService.SearchMessages(ctx, chatID, keyword, messageSearchMaxResults+1)
+}
+
+// newMessageSearchResult 按 messageSearchMaxResults 截断查询结果（查询时多取 1 条用于判断是否截断）
+func newMessageSearchResult(keyword string, messages []*models.Message) messageSearchResult {
+	result := messageSearchResult{Keyword: keyword, Messages: messages}
+	if len(result.Messages) > messageSearchMaxResults {
+		result.Messages = result.Messages[:messageSearchMaxResults]
+		result.Truncated = true
+	}
+	return result
+}
+
+// renderMessageSearchPage 按群组时区渲染结果页，返回文本与总页数
+func (b *Bot) renderMessageSearchPage(ctx context.Context, chatID int64, result messageSearchResult, page int) (string, int) {
+	loc := models.DefaultLocation()
+	if group, err := b.groupService.GetGroupInfo(ctx, chatID); err == nil && group != nil {
+		loc = models.GroupLocation(group.Settings)
+	}
+
+	senders := b.resolveMessageSenders(ctx, result.Messages, page)
+	return formatMessageSearchPage(result, page, senders, func(m *models.Message) string {
+		return m.SentAt.In(loc).Format("01-02 15:04")
+	})
+}
+
+// resolveMessageSenders 查询当前页消息的发送人名称
+func (b *Bot) resolveMessageSenders(ctx context.Context, messages []*models.Message, page int) map[int64]string {
+	senders := make(map[int64]string)
+	start, end := messageSearchPageBounds(len(messages), page)
+	for _, msg := range messages[start:end] {
+		if msg.UserID == 0 {
+			continue
+		}
+		if _, ok := senders[msg.UserID]; ok {
+			continue
+		}
+		if name := b.lookupUserDisplayName(ctx, msg.UserID); name != "" {
+			senders[msg.UserID] = name
+		}
+	}
+	return senders
+}
+
+// lookupUserDisplayName 查询已登记用户的展示名称（姓名 + @用户名），未登记时返回空字符串
+func (b *Bot) lookupUserDisplayName(ctx context.Context, userID int64) string {
+	user, err := b.userService.GetUserInfo(ctx, userID)
+	if err != nil || user == nil {
+		return ""
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if user.Username == "" {
+		return name
+	}
+	if name == "" {
+		return "@" + user.Username
+	}
+	return fmt.Sprintf("%s (@%s)", name, user.Username)
+}
+
+// parseMessageSearchKeyword 解析命令中的关键词，ok=false 表示不是搜索命令
+func parseMessageSearchKeyword(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, messageSearchCommand) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(text, messageSearchCommand)
+	if rest != "" && !unicode.IsSpace([]rune(rest)[0]) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// parseMessageEntityQuery 识别按实体检索的关键词：@用户名、#话题、url:链接片段
+func parseMessageEntityQuery(keyword string) (string, string, bool) {
+	switch {
+	case len(keyword) > 1 && strings.HasPrefix(keyword, "@"):
+		return models.MessageEntitySearchMention, keyword, true
+	case len(keyword) > 1 && strings.HasPrefix(keyword, "#"):
+		return models.MessageEntitySearchHashtag, keyword, true
+	case len(keyword) > len(messageSearchURLPrefix) && strings.EqualFold(keyword[:len(messageSearchURLPrefix)], messageSearchURLPrefix):
+		return models.MessageEntitySearchURL, strings.TrimSpace(keyword[len(messageSearchURLPrefix):]), true
+	default:
+		return "", "", false
+	}
+}
+
+// messageSearchHighlight 返回结果片段中需要加粗的内容：链接检索时为链接片段，其余为关键词本身
+func messageSearchHighlight(keyword string) string {
+	if kind, value, ok := parseMessageEntityQuery(keyword); ok && kind == models.MessageEntitySearchURL {
+		return value
+	}
+	return keyword
+}
+
+// parseMessageSearchCallback 解析翻页回调：msg_search:<page>:<keyword>
+func parseMessageSearchCallback(data string) (int, string, bool) {
+	payload := strings.TrimPrefix(data, messageSearchCallbackPrefix)
+	pageText, keyword, found := strings.Cut(payload, ":")
+	if !found || keyword == "" {
+		return 0, "", false
+	}
+	page, err := strconv.Atoi(pageText)
+	if err != nil || page < 1 {
+		return 0, "", false
+	}
+	return page, keyword, true
+}
+
+// messageSearchPageBounds 计算指定页在结果中的区间
+func messageSearchPageBounds(total, page int) (int, int) {
+	start := (page - 1) * messageSearchPageSize
+	if start > total {
+		start = total
+	}
+	end := start + messageSearchPageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// formatMessageSearchPage 渲染搜索结果页，返回文本与总页数
+func formatMessageSearchPage(result messageSearchResult, page int, senders map[int64]string, formatTime func(*models.Message) string) (string, int) {
+	keyword := html.EscapeString(result.Keyword)
+	total := len(result.Messages)
+	if total == 0 {
+		if result.Mention {
+			return fmt.Sprintf("📣 本群暂无提及 %s 的消息", keyword), 0
+		}
+		return fmt.Sprintf("🔍 未找到包含「%s」的消息", keyword), 0
+	}
+
+	totalPages := (total + messageSearchPageSize - 1) / messageSearchPageSize
+	if page > totalPages {
+		page = totalPages
+	}
+	start, end := messageSearchPageBounds(total, page)
+
+	var sb strings.Builder
+	countText := strconv.Itoa(total)
+	if result.Truncated {
+		countText += "+"
+	}
+	if result.Mention {
+		sb.WriteString(fmt.Sprintf("📣 提及 %s 的消息共 %s 条（第 %d/%d 页）\n\n", keyword, countText, page, totalPages))
+	} else {
+		sb.WriteString(fmt.Sprintf("🔍 搜索「%s」共 %s 条结果（第 %d/%d 页）\n\n", keyword, countText, page, totalPages))
+	}
+
+	for i, msg := range result.Messages[start:end] {
+		sender, ok := senders[msg.UserID]
+		if !ok {
+			sender = fmt.Sprintf("用户 %d", msg.UserID)
+			if msg.UserID == 0 {
+				sender = "频道"
+			}
+		}
+		content := msg.Text
+		if content == "" {
+			content = msg.Caption
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s\n%s\n\n",
+			start+i+1,
+			formatTime(msg),
+			html.EscapeString(sender),
+			messageSearchSnippet(content, messageSearchHighlight(result.Keyword), messageSearchSnippetRunes)))
+	}
+
+	switch {
+	case result.Truncated && result.Mention:
+		sb.WriteString(fmt.Sprintf("⚠️ 仅显示最近 %d 条提及", messageSearchMaxResults))
+	case result.Truncated:
+		sb.WriteString(fmt.Sprintf("⚠️ 仅显示最近 %d 条结果，请使用更精确的关键词缩小范围", messageSearchMaxResults))
+	}
+
+	return strings.TrimRight(sb.String(), "\n"), totalPages
+}
+
+// messageSearchSnippet 截取关键词附近的片段并加粗关键词（已做 HTML 转义）
+func messageSearchSnippet(content, keyword string, width int) string {
+	runes := []rune(strings.ReplaceAll(content, "\n", " "))
+	keyRunes := []rune(keyword)
+
+	index := indexRunesFold(runes, keyRunes)
+	if index < 0 {
+		if len(runes) > width {
+			return html.EscapeString(string(runes[:width])) + "…"
+		}
+		return html.EscapeString(string(runes))
+	}
+
+	start := index - (width-len(keyRunes))/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+		if start < 0 {
+			start = 0
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(html.EscapeString(string(runes[start:index])))
+	sb.WriteString("<b>")
+	sb.WriteString(html.EscapeString(string(runes[index : index+len(keyRunes)])))
+	sb.WriteString("</b>")
+	matchEnd := index + len(keyRunes)
+	if end < matchEnd {
+		end = matchEnd
+	}
+	sb.WriteString(html.EscapeString(string(runes[matchEnd:end])))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+// indexRunesFold 不区分大小写查找子串位置（按 rune 计）
+func indexRunesFold(runes, sub []rune) int {
+	if len(sub) == 0 || len(sub) > len(runes) {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(runes); i++ {
+		matched := true
+		for j, r := range sub {
+			if unicode.ToLower(runes[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// buildMessageSearchKeyboard 构建翻页按钮，只有一页时不显示
+func buildMessageSearchKeyboard(keyword string, page, totalPages int) botModels.ReplyMarkup {
+	return buildMessagePageKeyboard(page, totalPages, func(target int) string {
+		return fmt.Sprintf("%s%d:%s", messageSearchCallbackPrefix, target, keyword)
+	})
+}
+
+// buildMessagePageKeyboard 构建上一页/下一页按钮，callbackData 生成目标页的回调数据
+func buildMessagePageKeyboard(page, totalPages int, callbackData func(page int) string) botModels.ReplyMarkup {
+	if totalPages <= 1 {
+		return nil
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	var row []botModels.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, botModels.InlineKeyboardButton{
+			Text:         "⬅️ 上一页",
+			CallbackData: callbackData(page - 1),
+		})
+	}
+	if page < totalPages {
+		row = append(row, botModels.InlineKeyboardButton{
+			Text:         "下一页 ➡️",
+			CallbackData: callbackData(page + 1),
+		})
+	}
+
+	return &botModels.InlineKeyboardMarkup{InlineKeyboard: [][]botModels.InlineKeyboardButton{row}}
+}
@@ -0,0 +1,169 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestParseMessageSearchKeyword(t *testing.T) {
+	cases := []struct {
+		text    string
+		keyword string
+		ok      bool
+	}{
+		{text: "搜索消息 退款", keyword: "退款", ok: true},
+		{text: "  搜索消息   订单 123  ", keyword: "订单 123", ok: true},
+		{text: "搜索消息", keyword: "", ok: true},
+		{text: "搜索消息记录", ok: false},
+		{text: "查询记账", ok: false},
+	}
+
+	for _, tc := range cases {
+		keyword, ok := parseMessageSearchKeyword(tc.text)
+		if ok != tc.ok || keyword != tc.keyword {
+			t.Fatalf("parse %q: expected (%q, %v), got (%q, %v)", tc.text, tc.keyword, tc.ok, keyword, ok)
+		}
+	}
+}
+
+func TestParseMessageEntityQuery(t *testing.T) {
+	cases := []struct {
+		keyword string
+		kind    string
+		value   string
+		ok      bool
+	}{
+		{keyword: "@alice", kind: models.MessageEntitySearchMention, value: "@alice", ok: true},
+		{keyword: "#日报", kind: models.MessageEntitySearchHashtag, value: "#日报", ok: true},
+		{keyword: "URL:example.com", kind: models.MessageEntitySearchURL, value: "example.com", ok: true},
+		{keyword: "@", ok: false},
+		{keyword: "url:", ok: false},
+		{keyword: "退款", ok: false},
+	}
+
+	for _, tc := range cases {
+		kind, value, ok := parseMessageEntityQuery(tc.keyword)
+		if ok != tc.ok || kind != tc.kind || value != tc.value {
+			t.Fatalf("parse %q: expected (%q, %q, %v), got (%q, %q, %v)", tc.keyword, tc.kind, tc.value, tc.ok, kind, value, ok)
+		}
+	}
+	if got := messageSearchHighlight("url:example.com"); got != "example.com" {
+		t.Fatalf("expected url search to highlight the link fragment, got %q", got)
+	}
+}
+
+func TestMessageSearchCallbackRoundTrip(t *testing.T) {
+	markup := buildMessageSearchKeyboard("退款申请", 2, 3)
+	keyboard, ok := markup.(*botModels.InlineKeyboardMarkup)
+	if !ok || len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 2 {
+		t.Fatalf("expected prev/next buttons, got %+v", markup)
+	}
+
+	next := keyboard.InlineKeyboard[0][1].CallbackData
+	page, keyword, ok := parseMessageSearchCallback(next)
+	if !ok || page != 3 || keyword != "退款申请" {
+		t.Fatalf("unexpected callback parse: page=%d keyword=%q ok=%v", page, keyword, ok)
+	}
+
+	for _, keyword := range []string{strings.Repeat("退", messageSearchMaxKeyword/3), strings.Repeat("🔥", messageSearchMaxKeyword/4)} {
+		longest := buildMessageSearchKeyboard(keyword, 10, 11)
+		for _, btn := range longest.(*botModels.InlineKeyboardMarkup).InlineKeyboard[0] {
+			if len(btn.CallbackData) > 64 {
+				t.Fatalf("callback data exceeds telegram limit: %d bytes", len(btn.CallbackData))
+			}
+		}
+	}
+
+	if buildMessageSearchKeyboard("退款", 1, 1) != nil {
+		t.Fatalf("expected no keyboard for a single page")
+	}
+}
+
+func TestMessageSearchSnippet(t *testing.T) {
+	snippet := messageSearchSnippet("请帮忙处理 Refund <订单> 谢谢", "refund", 40)
+	if snippet != "请帮忙处理 <b>Refund</b> &lt;订单&gt; 谢谢" {
+		t.Fatalf("unexpected snippet: %s", snippet)
+	}
+
+	long := strings.Repeat("甲", 30) + "关键词" + strings.Repeat("乙", 30)
+	snippet = messageSearchSnippet(long, "关键词", 10)
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "<b>关键词</b>") {
+		t.Fatalf("expected trimmed snippet around keyword, got %s", snippet)
+	}
+}
+
+func TestFormatMessageSearchPageTruncated(t *testing.T) {
+	base := time.Date(2024, 11, 20, 10, 0, 0, 0, time.UTC)
+	messages := make([]*models.Message, messageSearchMaxResults)
+	for i := range messages {
+		messages[i] = &models.Message{UserID: 42, Text: fmt.Sprintf("第 %d 条 退款", i+1), SentAt: base.Add(-time.Duration(i) * time.Minute)}
+	}
+
+	result := messageSearchResult{Keyword: "退款", Messages: messages, Truncated: true}
+	text, pages := formatMessageSearchPage(result, 2, map[int64]string{42: "Alice"}, func(m *models.Message) string {
+		return m.SentAt.Format("15:04")
+	})
+
+	if pages != messageSearchMaxResults/messageSearchPageSize {
+		t.Fatalf("unexpected page count: %d", pages)
+	}
+	if !strings.Contains(text, "共 50+ 条结果（第 2/5 页）") {
+		t.Fatalf("expected header with truncated count, got %s", text)
+	}
+	if !strings.Contains(text, "11. 09:50 Alice") {
+		t.Fatalf("expected second page to start at item 11, got %s", text)
+	}
+	if !strings.Contains(text, "缩小范围") {
+		t.Fatalf("expected truncation hint, got %s", text)
+	}
+
+	empty, pages := formatMessageSearchPage(messageSearchResult{Keyword: "<x>"}, 1, nil, nil)
+	if pages != 0 || !strings.Contains(empty, "未找到包含「&lt;x&gt;」的消息") {
+		t.Fatalf("unexpected empty result: %s", empty)
+	}
+}
+
+// searchMessageService 记录两种检索的调用，实体检索返回 entityHits
+type searchMessageService struct {
+	service.MessageService
+	entityHits []*models.Message
+	calls      []string
+}
+
+func (s *searchMessageService) SearchMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int) ([]*models.Message, error) {
+	s.calls = append(s.calls, "entity:"+value)
+	return s.entityHits, nil
+}
+
+func (s *searchMessageService) SearchMessages(ctx context.Context, chatID int64, keyword string, limit int) ([]*models.Message, error) {
+	s.calls = append(s.calls, "text:"+keyword)
+	return []*models.Message{{Text: keyword}}, nil
+}
+
+func TestSearchMessagesFallsBackToText(t *testing.T) {
+	messages := &searchMessageService{}
+	b := &Bot{messageService: messages}
+
+	// 实体检索无结果：回退正文检索
+	if result, err := b.searchMessages(context.Background(), -1, "#日报"); err != nil || len(result) != 1 {
+		t.Fatalf("expected text fallback result, got %v err=%v", result, err)
+	}
+	if strings.Join(messages.calls, ",") != "entity:#日报,text:#日报" {
+		t.Fatalf("unexpected calls %v", messages.calls)
+	}
+
+	// 实体检索有结果：不再正文检索
+	messages.calls = nil
+	messages.entityHits = []*models.Message{{Text: "#日报 今日"}}
+	if _, err := b.searchMessages(context.Background(), -1, "#日报"); err != nil || strings.Join(messages.calls, ",") != "entity:#日报" {
+		t.Fatalf("expected entity hits without fallback, calls=%v err=%v", messages.calls, err)
+	}
+}
@@ -0,0 +1,208 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// telegramMessageLimit Telegram 单条消息文本上限（按 UTF-16 计数）
+const telegramMessageLimit = 4096
+
+// htmlTag 分片时跟踪的未闭合标签
+type htmlTag struct {
+	name string // 小写标签名，用于生成闭合标签
+	raw  string // 原始开标签（含属性），用于在下一片重新打开
+}
+
+// htmlChunker 按长度上限拼装 HTML 分片，保证每片内标签成对闭合
+type htmlChunker struct {
+	limit     int
+	chunks    []string
+	buf       strings.Builder
+	size      int // buf 的 UTF-16 长度
+	prefixLen int // buf 中重新打开标签部分的字节长度
+	open      []htmlTag
+}
+
+// splitHTMLMessage 将超长 HTML 消息切分为多片，优先在换行处切分
+// 单行超长时退化为按字符切分，但不会切开标签和 HTML 实体；跨片的标签在片尾闭合、下一片开头重新打开
+func splitHTMLMessage(text string, limit int) []string {
+	if limit <= 0 || utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	chunker := &htmlChunker{limit: limit}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if chunker.add(line) {
+			continue
+		}
+		for _, token := range tokenizeHTML(line) {
+			chunker.addToken(token)
+		}
+	}
+	chunker.flush()
+
+	if len(chunker.chunks) == 0 {
+		return []string{text}
+	}
+	return chunker.chunks
+}
+
+// add 尝试把 piece 整体放入当前分片，放不下时先结束当前分片再试一次
+func (c *htmlChunker) add(piece string) bool {
+	if c.fits(piece) {
+		c.write(piece)
+		return true
+	}
+	if !c.hasBody() {
+		return false
+	}
+	c.flush()
+	if c.fits(piece) {
+		c.write(piece)
+		return true
+	}
+	return false
+}
+
+// addToken 放入不可再分的 token，若单个 token 仍超限则强制写入
+func (c *htmlChunker) addToken(token string) {
+	if !c.add(token) {
+		c.write(token)
+	}
+}
+
+// fits 判断写入 piece 并补齐闭合标签后是否仍在上限内
+func (c *htmlChunker) fits(piece string) bool {
+	return c.size+utf16Len(piece)+closingTagsLen(applyHTMLTags(c.open, piece)) <= c.limit
+}
+
+// write 写入 piece 并更新未闭合标签栈
+func (c *htmlChunker) write(piece string) {
+	c.buf.WriteString(piece)
+	c.size += utf16Len(piece)
+	c.open = applyHTMLTags(c.open, piece)
+}
+
+// hasBody 当前分片除重新打开的标签外是否已有内容
+func (c *htmlChunker) hasBody() bool {
+	return strings.TrimSpace(c.buf.String()[c.prefixLen:]) != ""
+}
+
+// flush 结束当前分片：补齐闭合标签，并在下一片开头重新打开
+func (c *htmlChunker) flush() {
+	if c.hasBody() {
+		var sb strings.Builder
+		sb.WriteString(strings.TrimRight(c.buf.String(), "\n"))
+		for i := len(c.open) - 1; i >= 0; i-- {
+			sb.WriteString("</" + c.open[i].name + ">")
+		}
+		c.chunks = append(c.chunks, sb.String())
+	}
+
+	c.buf.Reset()
+	for _, tag := range c.open {
+		c.buf.WriteString(tag.raw)
+	}
+	c.prefixLen = c.buf.Len()
+	c.size = utf16Len(c.buf.String())
+}
+
+// tokenizeHTML 将文本拆成标签、实体和单个字符
+func tokenizeHTML(text string) []string {
+	tokens := make([]string, 0, len(text))
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				tokens = append(tokens, text[i:i+end+1])
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(text[i:], ';'); end > 0 && end <= 10 {
+				tokens = append(tokens, text[i:i+end+1])
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		tokens = append(tokens, text[i:i+size])
+		i += size
+	}
+	return tokens
+}
+
+// applyHTMLTags 返回处理完 piece 中所有标签后的未闭合标签栈
+func applyHTMLTags(open []htmlTag, piece string) []htmlTag {
+	if !strings.Contains(piece, "<") {
+		return open
+	}
+
+	stack := append([]htmlTag(nil), open...)
+	for rest := piece; ; {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '>')
+		if end < 0 {
+			break
+		}
+		raw := rest[start : start+end+1]
+		rest = rest[start+end+1:]
+
+		name, closing := htmlTagName(raw)
+		if name == "" {
+			continue
+		}
+
+		if !closing {
+			stack = append(stack, htmlTag{name: name, raw: raw})
+			continue
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].name == name {
+				stack = stack[:i]
+				break
+			}
+		}
+	}
+	return stack
+}
+
+// htmlTagName 解析标签名，closing 表示是否为闭合标签
+func htmlTagName(raw string) (string, bool) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+	closing := strings.HasPrefix(inner, "/")
+	inner = strings.TrimPrefix(inner, "/")
+	if end := strings.IndexAny(inner, " \t\n/"); end >= 0 {
+		inner = inner[:end]
+	}
+	return strings.ToLower(inner), closing
+}
+
+// closingTagsLen 返回闭合全部标签所需的长度
+func closingTagsLen(open []htmlTag) int {
+	total := 0
+	for _, tag := range open {
+		total += len(tag.name) + 3
+	}
+	return total
+}
+
+// utf16Len 按 Telegram 的计数方式（UTF-16 码元）计算长度
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitHTMLMessageShortText(t *testing.T) {
+	chunks := splitHTMLMessage("<b>hi</b>\nthere", telegramMessageLimit)
+	if len(chunks) != 1 || chunks[0] != "<b>hi</b>\nthere" {
+		t.Fatalf("expected short text untouched, got %q", chunks)
+	}
+}
+
+func TestSplitHTMLMessageByLines(t *testing.T) {
+	var lines []string
+	for i := 0; i < 30; i++ {
+		lines = append(lines, strings.Repeat("行", 9))
+	}
+	text := strings.Join(lines, "\n")
+
+	chunks := splitHTMLMessage(text, 50)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if utf16Len(chunk) > 50 {
+			t.Fatalf("chunk exceeds limit: %d", utf16Len(chunk))
+		}
+		for _, line := range strings.Split(chunk, "\n") {
+			if line != lines[0] {
+				t.Fatalf("expected chunks to break on line boundaries, got %q", line)
+			}
+		}
+	}
+	if got := strings.Join(chunks, "\n"); got != text {
+		t.Fatalf("expected chunks to reassemble to the original text")
+	}
+}
+
+func TestSplitHTMLMessageKeepsTagsBalanced(t *testing.T) {
+	text := "<b>标题</b>\n<pre>" + strings.Repeat("0123456789\n", 12) + "</pre>\n<a href=\"https://t.me\">链接</a>"
+
+	chunks := splitHTMLMessage(text, 60)
+	if len(chunks) < 3 {
+		t.Fatalf("expected at least 3 chunks, got %d", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if utf16Len(chunk) > 60 {
+			t.Fatalf("chunk exceeds limit: %q", chunk)
+		}
+		if open := applyHTMLTags(nil, chunk); len(open) != 0 {
+			t.Fatalf("expected balanced tags, got open %v in %q", open, chunk)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "<pre>") {
+		t.Fatalf("expected <pre> to be reopened in next chunk, got %q", chunks[1])
+	}
+}
+
+func TestSplitHTMLMessageLongLine(t *testing.T) {
+	text := "<code>" + strings.Repeat("a&amp;b😀", 20) + "</code>"
+
+	chunks := splitHTMLMessage(text, 40)
+	for _, chunk := range chunks {
+		if utf16Len(chunk) > 40 {
+			t.Fatalf("chunk exceeds limit: %q", chunk)
+		}
+		if !strings.HasPrefix(chunk, "<code>") || !strings.HasSuffix(chunk, "</code>") {
+			t.Fatalf("expected every chunk to be wrapped in code tag, got %q", chunk)
+		}
+		body := strings.TrimSuffix(strings.TrimPrefix(chunk, "<code>"), "</code>")
+		if strings.Count(body, "&") != strings.Count(body, "&amp;") {
+			t.Fatalf("expected entities to stay intact, got %q", chunk)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+// 指标以 Prometheus 文本格式（0.0.4）暴露，命名遵循 <namespace>_<name>_<unit>，counter 以 _total 结尾
+const (
+	metricsNamespace   = "go_bot"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// handlerDurationBuckets handler 耗时直方图的桶边界（秒）
+var handlerDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// botMetrics Bot 运行指标，并发安全；为 nil 时所有记录方法为空操作
+type botMetrics struct {
+	mu              sync.Mutex
+	handlerRequests map[string]uint64             // command -> 处理次数
+	handlerDuration map[string]*durationHistogram // command -> 处理耗时
+	sendErrors      map[sendErrorKey]uint64       // method + reason -> 发送失败次数
+	pendingStates   map[string]int                // kind -> 内存中待处理状态数（最近一次清理后）
+	pendingSwept    map[string]uint64             // kind -> 累计清理的过期状态数
+}
+
+// durationHistogram 累积直方图，counts[i] 为耗时 <= buckets[i] 的次数
+type durationHistogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// sendErrorKey 发送失败计数的标签
+type sendErrorKey struct {
+	method string
+	reason string
+}
+
+func newBotMetrics() *botMetrics {
+	return &botMetrics{
+		handlerRequests: make(map[string]uint64),
+		handlerDuration: make(map[string]*durationHistogram),
+		sendErrors:      make(map[sendErrorKey]uint64),
+		pendingStates:   make(map[string]int),
+		pendingSwept:    make(map[string]uint64),
+	}
+}
+
+// observeHandler 记录一次 handler 执行（次数与耗时）
+func (m *botMetrics) observeHandler(command string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlerRequests[command]++
+	hist, ok := m.handlerDuration[command]
+	if !ok {
+		hist = &durationHistogram{counts: make([]uint64, len(handlerDurationBuckets))}
+		m.handlerDuration[command] = hist
+	}
+	seconds := duration.Seconds()
+	for i, bound := range handlerDurationBuckets {
+		if seconds <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.count++
+	hist.sum += seconds
+}
+
+// recordSendError 记录一次 Telegram API 调用最终失败（重试耗尽后）
+func (m *botMetrics) recordSendError(method string, err error) {
+	if m == nil || err == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendErrors[sendErrorKey{method: method, reason: sendErrorReason(err)}]++
+}
+
+// recordPendingSweep 记录一轮过期状态清理：剩余数量与本轮回收数
+func (m *botMetrics) recordPendingSweep(kind string, removed, remaining int) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pendingStates[kind] = remaining
+	m.pendingSwept[kind] += uint64(removed)
+}
+
+// sendErrorReason 将发送错误归类为有限的几种原因，避免标签基数失控
+func sendErrorReason(err error) string {
+	var tooMany *bot.TooManyRequestsError
+	switch {
+	case errors.As(err, &tooMany):
+		return "too_many_requests"
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, bot.ErrorForbidden):
+		return "forbidden"
+	case errors.Is(err, bot.ErrorBadRequest):
+		return "bad_request"
+	case errors.Is(err, bot.ErrorNotFound):
+		return "not_found"
+	case isRetryableSendError(err):
+		return "server_or_network"
+	default:
+		return "other"
+	}
+}
+
+// writeTo 以 Prometheus 文本格式输出全部指标，工作池指标取自抓取时的 pool 快照
+func (m *botMetrics) writeTo(w io.Writer, pool WorkerPoolStats) {
+	if m == nil {
+		m = newBotMetrics()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := metricName("handler_requests_total")
+	writeMetricHeader(w, requests, "counter", "Number of updates processed by handlers, labeled by command or update type.")
+	for _, command := range sortedKeys(m.handlerRequests) {
+		fmt.Fprintf(w, "%s{command=%s} %d\n", requests, quoteLabel(command), m.handlerRequests[command])
+	}
+
+	duration := metricName("handler_duration_seconds")
+	writeMetricHeader(w, duration, "histogram", "Time spent executing handlers in the worker pool.")
+	for _, command := range sortedKeys(m.handlerDuration) {
+		hist := m.handlerDuration[command]
+		label := quoteLabel(command)
+		for i, bound := range handlerDurationBuckets {
+			fmt.Fprintf(w, "%s_bucket{command=%s,le=\"%s\"} %d\n", duration, label, formatFloat(bound), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{command=%s,le=\"+Inf\"} %d\n", duration, label, hist.count)
+		fmt.Fprintf(w, "%s_sum{command=%s} %s\n", duration, label, formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count{command=%s} %d\n", duration, label, hist.count)
+	}
+
+	sendErrors := metricName("telegram_send_errors_total")
+	writeMetricHeader(w, sendErrors, "counter", "Number of Telegram API calls that failed after retries, labeled by method and reason.")
+	keys := make([]sendErrorKey, 0, len(m.sendErrors))
+	for key := range m.sendErrors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].reason < keys[j].reason
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{method=%s,reason=%s} %d\n", sendErrors, quoteLabel(key.method), quoteLabel(key.reason), m.sendErrors[key])
+	}
+
+	pendingStates := metricName("pending_states")
+	writeMetricHeader(w, pendingStates, "gauge", "Number of in-memory pending states after the last sweep, labeled by kind.")
+	for _, kind := range sortedKeys(m.pendingStates) {
+		fmt.Fprintf(w, "%s{kind=%s} %d\n", pendingStates, quoteLabel(kind), m.pendingStates[kind])
+	}
+
+	pendingSwept := metricName("pending_states_swept_total")
+	writeMetricHeader(w, pendingSwept, "counter", "Number of expired in-memory pending states removed by the periodic sweeper, labeled by kind.")
+	for _, kind := range sortedKeys(m.pendingSwept) {
+		fmt.Fprintf(w, "%s{kind=%s} %d\n", pendingSwept, quoteLabel(kind), m.pendingSwept[kind])
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value int
+	}{
+		{"worker_pool_workers", "Number of worker goroutines.", pool.Workers},
+		{"worker_pool_min_workers", "Lower bound of the adaptive worker count.", pool.MinWorkers},
+		{"worker_pool_max_workers", "Upper bound of the adaptive worker count.", pool.MaxWorkers},
+		{"worker_pool_active_workers", "Number of workers currently executing a handler.", pool.Active},
+		{"worker_pool_queue_length", "Number of tasks waiting in the worker pool queue.", pool.QueueLength},
+		{"worker_pool_queue_capacity", "Capacity of the worker pool queue.", pool.QueueCapacity},
+	}
+	for _, gauge := range gauges {
+		name := metricName(gauge.name)
+		writeMetricHeader(w, name, "gauge", gauge.help)
+		fmt.Fprintf(w, "%s %d\n", name, gauge.value)
+	}
+
+	dropped := metricName("worker_pool_dropped_tasks_total")
+	writeMetricHeader(w, dropped, "counter", "Number of tasks dropped because the worker pool queue was full.")
+	fmt.Fprintf(w, "%s %d\n", dropped, pool.Dropped)
+}
+
+// metricsHandler /metrics 端点
+func (b *Bot) metricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		b.metrics.writeTo(w, b.workerPool.Stats())
+	}
+}
+
+// handlerMetricLabel 确定 handler 指标的 command 标签：经过 RateLimit 的命令使用注册时的命令名，
+// 其余按更新类型归类（回调按数据前缀），保证标签取值有限
+func handlerMetricLabel(ctx context.Context, update *botModels.Update) string {
+	if command, ok := ctx.Value(metricsCommandKey{}).(string); ok && command != "" {
+		return command
+	}
+	if update == nil {
+		return "unknown"
+	}
+
+	switch {
+	case update.CallbackQuery != nil:
+		prefix, _, _ := strings.Cut(update.CallbackQuery.Data, ":")
+		return "callback:" + prefix
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.BusinessMessage != nil:
+		return "business_message"
+	case update.DeletedBusinessMessages != nil:
+		return "deleted_business_messages"
+	default:
+		return "other"
+	}
+}
+
+// metricsCommandKey context 中保存命令名的键，由 RateLimit 写入
+type metricsCommandKey struct{}
+
+// withMetricsCommand 在 context 中记录命令名，供 asyncHandler 作为指标标签
+func withMetricsCommand(ctx context.Context, command string) context.Context {
+	return context.WithValue(ctx, metricsCommandKey{}, command)
+}
+
+func metricName(name string) string {
+	return metricsNamespace + "_" + name
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// quoteLabel 按 Prometheus 文本格式转义标签值
+func quoteLabel(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
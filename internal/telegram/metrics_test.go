@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestBotMetricsExposition(t *testing.T) {
+	m := newBotMetrics()
+	m.observeHandler("/ping", 30*time.Millisecond)
+	m.observeHandler("/ping", 2*time.Second)
+	m.observeHandler(`we"ird`, time.Millisecond)
+	m.recordSendError("sendMessage", fmt.Errorf("wrap: %w", bot.ErrorForbidden))
+	m.recordSendError("sendMessage", &bot.TooManyRequestsError{RetryAfter: 3})
+
+	var sb strings.Builder
+	m.writeTo(&sb, WorkerPoolStats{Workers: 4, Active: 1, QueueLength: 2, QueueCapacity: 100, Dropped: 5})
+	out := sb.String()
+
+	for _, want := range []string{
+		"# TYPE go_bot_handler_requests_total counter",
+		`go_bot_handler_requests_total{command="/ping"} 2`,
+		`go_bot_handler_requests_total{command="we\"ird"} 1`,
+		"# TYPE go_bot_handler_duration_seconds histogram",
+		`go_bot_handler_duration_seconds_bucket{command="/ping",le="0.025"} 0`,
+		`go_bot_handler_duration_seconds_bucket{command="/ping",le="0.05"} 1`,
+		`go_bot_handler_duration_seconds_bucket{command="/ping",le="2.5"} 2`,
+		`go_bot_handler_duration_seconds_bucket{command="/ping",le="+Inf"} 2`,
+		`go_bot_handler_duration_seconds_count{command="/ping"} 2`,
+		`go_bot_telegram_send_errors_total{method="sendMessage",reason="forbidden"} 1`,
+		`go_bot_telegram_send_errors_total{method="sendMessage",reason="too_many_requests"} 1`,
+		"go_bot_worker_pool_active_workers 1",
+		"go_bot_worker_pool_queue_length 2",
+		"go_bot_worker_pool_dropped_tasks_total 5",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected exposition to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestHandlerMetricLabel(t *testing.T) {
+	cases := []struct {
+		ctx    context.Context
+		update *botModels.Update
+		want   string
+	}{
+		{withMetricsCommand(context.Background(), "/ping"), &botModels.Update{Message: &botModels.Message{Text: "/ping"}}, "/ping"},
+		{context.Background(), &botModels.Update{Message: &botModels.Message{Text: "/whatever"}}, "message"},
+		{context.Background(), &botModels.Update{CallbackQuery: &botModels.CallbackQuery{Data: "config:toggle:x"}}, "callback:config"},
+		{context.Background(), &botModels.Update{ChannelPost: &botModels.Message{}}, "channel_post"},
+		{context.Background(), &botModels.Update{}, "other"},
+	}
+	for _, tc := range cases {
+		if got := handlerMetricLabel(tc.ctx, tc.update); got != tc.want {
+			t.Fatalf("expected label %q, got %q", tc.want, got)
+		}
+	}
+}
+
+func TestSendErrorReason(t *testing.T) {
+	cases := map[string]error{
+		"bad_request":       bot.ErrorBadRequest,
+		"timeout":           context.DeadlineExceeded,
+		"server_or_network": errors.New("error do request for method sendMessage, dial tcp: i/o timeout"),
+		"other":             errors.New("boom"),
+	}
+	for want, err := range cases {
+		if got := sendErrorReason(err); got != want {
+			t.Fatalf("sendErrorReason(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestAsyncHandlerRecordsMetrics(t *testing.T) {
+	pool := NewWorkerPool(1, 4)
+	b := &Bot{workerPool: pool, metrics: newBotMetrics()}
+
+	handler := b.RateLimit("/ping", b.asyncHandler(func(context.Context, *bot.Bot, *botModels.Update) {}))
+	handler(context.Background(), nil, &botModels.Update{})
+	pool.Shutdown(0)
+
+	rec := httptest.NewRecorder()
+	b.metricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `go_bot_handler_requests_total{command="/ping"} 1`) {
+		t.Fatalf("expected /ping to be counted, got:\n%s", rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+}
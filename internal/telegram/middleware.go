@@ -2,51 +2,176 @@ package telegram
 
 import (
 	"context"
-	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"go_bot/internal/logger"
+	"go_bot/internal/telegram/i18n"
 	"go_bot/internal/telegram/models"
 
 	"github.com/go-telegram/bot"
 	botModels "github.com/go-telegram/bot/models"
 )
 
-// RequireOwner 中间件：仅允许 Owner 执行
-func (b *Bot) RequireOwner(next bot.HandlerFunc) bot.HandlerFunc {
-	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
-		if update.Message == nil || update.Message.From == nil {
-			return
-		}
+const (
+	// permissionCommandMaxRunes 日志中记录的命令文本长度上限
+	permissionCommandMaxRunes = 64
+	// rateLimitNoticeTimeout 限流提示的发送超时；提示在 handler 返回后异步发送，不能沿用请求 ctx
+	rateLimitNoticeTimeout = 10 * time.Second
+)
 
-		// 使用 Service 检查权限
-		isOwner, err := b.userService.CheckOwnerPermission(ctx, update.Message.From.ID)
-		if err != nil || !isOwner {
-			logger.L().Warnf("Non-owner user %d attempted to use owner command", update.Message.From.ID)
-			b.sendErrorMessage(ctx, update.Message.Chat.ID, "此命令仅限 Bot Owner 使用")
+// permissionAttempt 一次需要权限的操作：发起人、所在群与尝试的命令
+type permissionAttempt struct {
+	userID    int64
+	username  string
+	chatID    int64
+	chatTitle string
+	command   string
+	message   *botModels.Message
+	callback  *botModels.CallbackQuery
+}
+
+// RequirePermission 中间件：需要指定权限位（Owner 拥有全部权限，其余角色见 models.RolePermissions）
+func (b *Bot) RequirePermission(perm string, next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		if !b.checkPermission(ctx, botInstance, update, perm) {
 			return
 		}
-
 		next(ctx, botInstance, update)
 	}
 }
 
-// RequireAdmin 中间件：需要管理员权限（Admin 或 Owner）
+// RequireAdmin 中间件：需要管理员权限（Admin、超级管理员、Owner，或当前群的群级管理员）
 func (b *Bot) RequireAdmin(next bot.HandlerFunc) bot.HandlerFunc {
+	return b.RequirePermission(models.PermAdmin, next)
+}
+
+// RequireGlobalAdmin 中间件：需要全局管理员角色（Admin、超级管理员、Owner），
+// 用于不限定群组的命令（如 /userinfo、/admins），群级管理员不能通过
+func (b *Bot) RequireGlobalAdmin(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
-		if update.Message == nil || update.Message.From == nil {
+		if !b.authorize(ctx, botInstance, update, models.PermAdmin, false) {
 			return
 		}
+		next(ctx, botInstance, update)
+	}
+}
+
+// checkPermission 检查 update 发起人是否具备指定权限，拒绝时统一提示并记录
+// 支持消息与回调按钮，无法识别发起人的 update 直接忽略
+func (b *Bot) checkPermission(ctx context.Context, botInstance *bot.Bot, update *botModels.Update, perm string) bool {
+	return b.authorize(ctx, botInstance, update, perm, true)
+}
+
+// authorize 权限检查实现；groupScoped 为 true 时 PermAdmin 同时认可当前群的群级管理员
+func (b *Bot) authorize(ctx context.Context, botInstance *bot.Bot, update *botModels.Update, perm string, groupScoped bool) bool {
+	attempt := newPermissionAttempt(update)
+	if attempt == nil {
+		return false
+	}
+
+	var allowed bool
+	var err error
+	if perm == models.PermAdmin && groupScoped {
+		// 通用管理命令同时认可本群的群级管理员
+		allowed, err = b.userService.CheckAdminPermission(ctx, attempt.userID, attempt.chatID)
+	} else {
+		allowed, err = b.userService.HasPermission(ctx, attempt.userID, perm)
+	}
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to check %s permission: user_id=%d err=%v", perm, attempt.userID, err)
+	}
+	if err == nil && allowed {
+		return true
+	}
+
+	b.denyPermission(ctx, botInstance, attempt, perm)
+	return false
+}
+
+// denyPermission 记录越权尝试并回复标准提示：消息回复到原消息，回调按钮以弹窗提示
+func (b *Bot) denyPermission(ctx context.Context, botInstance *bot.Bot, attempt *permissionAttempt, perm string) {
+	logger.Ctx(ctx).Infof("Permission denied: perm=%s user_id=%d username=%s chat_id=%d chat=%q command=%q",
+		perm, attempt.userID, attempt.username, attempt.chatID, attempt.chatTitle, attempt.command)
 
-		// 使用 Service 检查权限
-		isAdmin, err := b.userService.CheckAdminPermission(ctx, update.Message.From.ID)
-		if err != nil || !isAdmin {
-			logger.L().Warnf("Non-admin user %d attempted to use admin command", update.Message.From.ID)
-			b.sendErrorMessage(ctx, update.Message.Chat.ID, "此命令需要管理员权限")
+	if count, alert := b.permissionDenials.record(attempt.userID); alert {
+		logger.Ctx(ctx).Warnf("Repeated permission denials: user_id=%d username=%s count=%d window=%s last_command=%q",
+			attempt.userID, attempt.username, count, permissionDenialWindow, attempt.command)
+	}
+
+	if query := attempt.callback; query != nil {
+		if botInstance == nil {
 			return
 		}
+		if _, err := botInstance.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            permissionDeniedText(b.preferredLang(ctx, &query.From), perm),
+			ShowAlert:       true,
+		}); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to answer callback query: %v", err)
+		}
+		return
+	}
 
-		next(ctx, botInstance, update)
+	msg := attempt.message
+	b.sendErrorMessage(ctx, msg.Chat.ID, permissionDeniedText(b.messageLang(ctx, msg), perm), msg.ID)
+}
+
+// permissionDeniedText 拒绝提示：通用管理权限沿用「需要管理员权限」，其余提示缺少的权限（中文显示说明，其他语言显示权限名）
+func permissionDeniedText(lang i18n.Lang, perm string) string {
+	if perm == models.PermAdmin {
+		return i18n.T(lang, "middleware.admin_only")
+	}
+	name := perm
+	if lang == i18n.LangZH {
+		name = models.PermissionLabel(perm)
 	}
+	return i18n.T(lang, "middleware.perm_required", name)
+}
+
+// newPermissionAttempt 从消息或回调按钮中提取发起人信息，其他类型的 update 返回 nil
+func newPermissionAttempt(update *botModels.Update) *permissionAttempt {
+	if update == nil {
+		return nil
+	}
+
+	if msg := update.Message; msg != nil {
+		if msg.From == nil {
+			return nil
+		}
+		command := ""
+		if fields := strings.Fields(msg.Text); len(fields) > 0 {
+			command = fields[0]
+		}
+		return &permissionAttempt{
+			userID:    msg.From.ID,
+			username:  msg.From.Username,
+			chatID:    msg.Chat.ID,
+			chatTitle: msg.Chat.Title,
+			command:   truncateForDisplay(command, permissionCommandMaxRunes),
+			message:   msg,
+		}
+	}
+
+	if query := update.CallbackQuery; query != nil && query.From.ID != 0 {
+		attempt := &permissionAttempt{
+			userID:   query.From.ID,
+			username: query.From.Username,
+			command:  "callback:" + truncateForDisplay(query.Data, permissionCommandMaxRunes),
+			callback: query,
+		}
+		if source := query.Message.Message; source != nil {
+			attempt.chatID = source.Chat.ID
+			attempt.chatTitle = source.Chat.Title
+		} else if source := query.Message.InaccessibleMessage; source != nil {
+			attempt.chatID = source.Chat.ID
+			attempt.chatTitle = source.Chat.Title
+		}
+		return attempt
+	}
+
+	return nil
 }
 
 // RequireGroupTier 中间件：限制命令只能在指定群等级执行
@@ -62,19 +187,19 @@ func (b *Bot) RequireGroupTier(allowed []models.GroupTier, next bot.HandlerFunc)
 		chatID := update.Message.Chat.ID
 		group, err := b.groupService.GetGroupInfo(ctx, chatID)
 		if err != nil {
-			logger.L().Warnf("Failed to load group for tier guard: chat_id=%d err=%v", chatID, err)
-			b.sendTemporaryErrorMessage(ctx, chatID, "获取群组信息失败，请稍后再试")
+			logger.Ctx(ctx).Warnf("Failed to load group for tier guard: chat_id=%d err=%v", chatID, err)
+			b.sendTemporaryErrorMessage(ctx, chatID, i18n.T(b.preferredLang(ctx, update.Message.From), "common.group_load_failed"))
 			return
 		}
 
 		tier := models.NormalizeGroupTier(group.Tier)
 		if !models.IsTierAllowed(tier, allowedCopy) {
-			logger.L().Infof("Command blocked due to tier mismatch: chat_id=%d tier=%s text=%q allowed=%v",
+			logger.Ctx(ctx).Infof("Command blocked due to tier mismatch: chat_id=%d tier=%s text=%q allowed=%v",
 				chatID, tier, update.Message.Text, allowedCopy)
-			notice := fmt.Sprintf("⚠️ 此命令仅适用于：%s\n当前群类型：%s",
+			notice := i18n.T(b.resolveLang(ctx, group, update.Message.From), "middleware.tier_restricted",
 				models.FormatAllowedTierList(allowedCopy), models.GroupTierDisplayName(tier))
 			if _, err := b.sendTemporaryMessageWithMarkup(ctx, chatID, notice, nil); err != nil {
-				logger.L().Errorf("Failed to send tier restriction notice: chat_id=%d err=%v", chatID, err)
+				logger.Ctx(ctx).Errorf("Failed to send tier restriction notice: chat_id=%d err=%v", chatID, err)
 			}
 			return
 		}
@@ -82,3 +207,87 @@ func (b *Bot) RequireGroupTier(allowed []models.GroupTier, next bot.HandlerFunc)
 		next(ctx, botInstance, update)
 	}
 }
+
+// traceUpdate 全局中间件：为每个 update 生成 trace id 写入 context，同一 update 引发的全部日志可按 trace_id 过滤
+// 已带 trace id 的 context（如测试或重放）保持不变
+func traceUpdate(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		if logger.TraceID(ctx) == "" {
+			ctx = logger.WithTraceID(ctx, logger.NewTraceID())
+		}
+		if update != nil {
+			logger.Ctx(ctx).Debugf("Update received: update_id=%d", update.ID)
+		}
+		next(ctx, botInstance, update)
+	}
+}
+
+// RateLimit 中间件：按用户 + 命令限流，超限调用直接丢弃，本轮首次超限时回复提示
+// 需包在 asyncHandler 外层，被限流的请求不会进入 worker pool
+func (b *Bot) RateLimit(command string, next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		// 命令名随 context 传给 asyncHandler 作为指标标签
+		ctx = withMetricsCommand(ctx, command)
+		if update.Message == nil || update.Message.From == nil {
+			next(ctx, botInstance, update)
+			return
+		}
+
+		if !b.allowCommandRate(ctx, update.Message, command) {
+			return
+		}
+
+		b.recordCommandUsage(command)
+		next(ctx, botInstance, update)
+	}
+}
+
+// rateLimitedFeatures 默认限流的功能插件：只有价格查询会打到外部行情接口，
+// 四方、上游等业务功能不受默认阈值约束，需要时通过 COMMAND_RATE_LIMIT_OVERRIDES 单独配置
+var rateLimitedFeatures = map[string]bool{
+	"crypto": true,
+}
+
+// guardFeature 功能插件执行前拦截：按用户限流，放行的调用计入命令使用统计
+func (b *Bot) guardFeature(ctx context.Context, msg *botModels.Message, feature string) bool {
+	limited := rateLimitedFeatures[feature] || b.commandLimiter.hasOverride(feature)
+	if limited && !b.allowCommandRate(ctx, msg, feature) {
+		return false
+	}
+	b.recordCommandUsage(feature)
+	return true
+}
+
+// recordCommandUsage 记录一次命令调用（仅内存累加），未启用统计时忽略
+func (b *Bot) recordCommandUsage(command string) {
+	if b.commandUsage != nil {
+		b.commandUsage.Record(command)
+	}
+}
+
+// allowCommandRate 检查用户调用命令（或功能）的频率，超限时异步发送临时提示
+func (b *Bot) allowCommandRate(ctx context.Context, msg *botModels.Message, command string) bool {
+	if b.commandLimiter == nil || msg == nil || msg.From == nil {
+		return true
+	}
+
+	decision := b.commandLimiter.allow(msg.From.ID, command)
+	if decision.Allowed {
+		return true
+	}
+
+	logger.Ctx(ctx).Infof("Command rate limited: user_id=%d chat_id=%d command=%s retry_after=%s",
+		msg.From.ID, msg.Chat.ID, command, decision.RetryAfter.Round(time.Second))
+	if decision.Notify {
+		seconds := int(math.Ceil(decision.RetryAfter.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		go func() {
+			noticeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rateLimitNoticeTimeout)
+			defer cancel()
+			b.sendTemporaryErrorMessage(noticeCtx, msg.Chat.ID, i18n.T(b.messageLang(noticeCtx, msg), "middleware.rate_limited", seconds), msg.ID)
+		}()
+	}
+	return false
+}
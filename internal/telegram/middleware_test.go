@@ -0,0 +1,45 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"go_bot/internal/logger"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestTraceUpdateAssignsTraceID(t *testing.T) {
+	var seen []string
+	handler := traceUpdate(func(ctx context.Context, _ *bot.Bot, _ *botModels.Update) {
+		seen = append(seen, logger.TraceID(ctx))
+	})
+
+	handler(context.Background(), nil, &botModels.Update{ID: 1})
+	handler(context.Background(), nil, &botModels.Update{ID: 2})
+	handler(logger.WithTraceID(context.Background(), "fixed"), nil, &botModels.Update{ID: 3})
+
+	if seen[0] == "" || seen[1] == "" || seen[0] == seen[1] {
+		t.Fatalf("expected distinct trace ids per update, got %q", seen)
+	}
+	if seen[2] != "fixed" {
+		t.Fatalf("expected existing trace id to be kept, got %q", seen[2])
+	}
+}
+
+func TestTraceIDSurvivesWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	b := &Bot{workerPool: pool, metrics: newBotMetrics()}
+
+	var got string
+	handler := traceUpdate(b.asyncHandler(func(ctx context.Context, _ *bot.Bot, _ *botModels.Update) {
+		got = logger.TraceID(ctx)
+	}))
+	handler(context.Background(), nil, &botModels.Update{ID: 1})
+	pool.Shutdown(0)
+
+	if got == "" {
+		t.Fatal("expected trace id to reach the handler executed by the worker pool")
+	}
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -15,13 +20,60 @@ const (
 // AccountingRecord 收支记账记录
 type AccountingRecord struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	ChatID       int64              `bson:"chat_id"`        // 群组 Chat ID
-	UserID       int64              `bson:"user_id"`        // 操作用户 ID
-	Amount       float64            `bson:"amount"`         // 金额（正数为收入，负数为支出）
-	Currency     string             `bson:"currency"`       // 货币类型：USD/CNY
-	OriginalExpr string             `bson:"original_expr"`  // 原始表达式（如 "100*7.2"）
-	RecordedAt   time.Time          `bson:"recorded_at"`    // 记录时间（容器时区：Asia/Shanghai）
-	CreatedAt    time.Time          `bson:"created_at"`     // 数据库创建时间
+	ChatID       int64              `bson:"chat_id"`                // 群组 Chat ID
+	UserID       int64              `bson:"user_id"`                // 操作用户 ID
+	Amount       float64            `bson:"amount"`                 // 金额（正数为收入，负数为支出）
+	Currency     string             `bson:"currency"`               // 货币类型：USD/CNY
+	OriginalExpr string             `bson:"original_expr"`          // 原始表达式（如 "100*7.2"）
+	RecordedAt   time.Time          `bson:"recorded_at"`            // 记录时间（容器时区：Asia/Shanghai）
+	CreatedAt    time.Time          `bson:"created_at"`             // 数据库创建时间
+	OperationID  string             `bson:"operation_id,omitempty"` // 幂等键（如四方下发联动记账的下发单号），非空时同一 ID 只记一次
+	Book         string             `bson:"book,omitempty"`         // 账本名称，空值为默认账本（分账本前的历史记录均无该字段）
+}
+
+// 账本相关常量
+const (
+	AccountingDefaultBookName = "默认账本" // 默认账本展示名称，输入「#默认账本」等同于不带账本标识
+	AccountingBookMaxLength   = 16     // 账本名称最大字符数
+)
+
+// AccountingBookLabel 账本展示名称，空值显示为默认账本
+func AccountingBookLabel(book string) string {
+	if book == "" {
+		return AccountingDefaultBookName
+	}
+	return book
+}
+
+// SplitAccountingBook 拆分「#公账 +100」开头的账本标识，返回去掉 # 的账本名与剩余文本；
+// 未以 # 开头时 name 为空、rest 为原文本，名称合法性由 NormalizeAccountingBook 校验
+func SplitAccountingBook(text string) (name, rest string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "#") {
+		return "", text
+	}
+	text = text[1:]
+	end := strings.IndexFunc(text, unicode.IsSpace)
+	if end < 0 {
+		return text, ""
+	}
+	return text[:end], strings.TrimSpace(text[end:])
+}
+
+// NormalizeAccountingBook 校验并规范化账本名称，「默认账本」与「默认」归为空值（默认账本）
+func NormalizeAccountingBook(name string) (string, error) {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	switch {
+	case name == "":
+		return "", fmt.Errorf("账本名称不能为空")
+	case name == AccountingDefaultBookName || name == "默认":
+		return "", nil
+	case len([]rune(name)) > AccountingBookMaxLength:
+		return "", fmt.Errorf("账本名称最多 %d 个字", AccountingBookMaxLength)
+	case strings.ContainsAny(name, "#:"):
+		return "", fmt.Errorf("账本名称不能包含 # 或冒号")
+	}
+	return name, nil
 }
 
 // IsIncome 是否为收入记录
@@ -33,3 +85,38 @@ func (r *AccountingRecord) IsIncome() bool {
 func (r *AccountingRecord) IsExpense() bool {
 	return r.Amount < 0
 }
+
+// AccountingCents 金额换算为整数分，按十进制四舍五入（先取浮点数的最短十进制表示再舍入），
+// 使 100*7.2=720.0000000000001 记为 72000 分、2.01/2=1.005 记为 101 分
+func AccountingCents(amount float64) int64 {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0
+	}
+
+	digits := strconv.FormatFloat(math.Abs(amount), 'f', -1, 64)
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	fracPart = (fracPart + "000")[:3]
+
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || whole > math.MaxInt64/100-1 {
+		return int64(math.Round(amount * 100))
+	}
+	cents := whole*100 + int64(fracPart[0]-'0')*10 + int64(fracPart[1]-'0')
+	if fracPart[2] >= '5' {
+		cents++
+	}
+	if amount < 0 {
+		return -cents
+	}
+	return cents
+}
+
+// RoundAccountingAmount 金额按 2 位小数四舍五入，记账存储、汇总与展示统一使用
+func RoundAccountingAmount(amount float64) float64 {
+	return CentsToAmount(AccountingCents(amount))
+}
+
+// CentsToAmount 整数分换算为金额
+func CentsToAmount(cents int64) float64 {
+	return float64(cents) / 100
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 记账金额异常提醒的参数
+const (
+	DefaultAccountingAnomalyRatio = 10   // 未单独配置时，单笔金额超过近期均值的倍数即提醒
+	MinAccountingAnomalyRatio     = 2    // 倍数下限，过小会频繁误报
+	MaxAccountingAnomalyRatio     = 1000 // 倍数上限
+	AccountingAnomalySampleSize   = 20   // 计算均值时取同币种最近的记录条数
+	AccountingAnomalyMinSamples   = 3    // 历史记录少于该条数时不判断，避免新账本误报
+	accountingAnomalyDisabled     = -1   // 群组关闭异常提醒时保存的值
+)
+
+// AccountingAnomalyRatio 返回群组生效的异常倍数，0 表示已关闭提醒
+func AccountingAnomalyRatio(settings GroupSettings) int {
+	switch {
+	case settings.AccountingAnomalyRatio < 0:
+		return 0
+	case settings.AccountingAnomalyRatio == 0:
+		return DefaultAccountingAnomalyRatio
+	default:
+		return settings.AccountingAnomalyRatio
+	}
+}
+
+// ParseAccountingAnomalyRatio 解析异常倍数配置：0 表示使用默认倍数，「关闭」表示不提醒
+func ParseAccountingAnomalyRatio(input string) (int, error) {
+	input = strings.TrimSpace(input)
+	if input == "关闭" || strings.EqualFold(input, "off") {
+		return accountingAnomalyDisabled, nil
+	}
+
+	input = strings.TrimSpace(strings.TrimSuffix(input, "倍"))
+	ratio, err := strconv.Atoi(input)
+	if err != nil || (ratio != 0 && (ratio < MinAccountingAnomalyRatio || ratio > MaxAccountingAnomalyRatio)) {
+		return 0, fmt.Errorf("请输入 %d~%d 之间的整数倍数，0 表示默认（%d 倍），输入「关闭」不提醒",
+			MinAccountingAnomalyRatio, MaxAccountingAnomalyRatio, DefaultAccountingAnomalyRatio)
+	}
+	return ratio, nil
+}
+
+// AccountingAmountAverage 计算历史记录金额绝对值的均值，样本不足时返回 false
+func AccountingAmountAverage(records []*AccountingRecord) (float64, bool) {
+	if len(records) < AccountingAnomalyMinSamples {
+		return 0, false
+	}
+
+	var totalCents int64
+	for _, record := range records {
+		cents := AccountingCents(record.Amount)
+		if cents < 0 {
+			cents = -cents
+		}
+		totalCents += cents
+	}
+	if totalCents == 0 {
+		return 0, false
+	}
+	return CentsToAmount(totalCents) / float64(len(records)), true
+}
+
+// IsAccountingAmountAnomalous 单笔金额（绝对值）是否超过均值的 ratio 倍，ratio <= 0 表示不判断
+func IsAccountingAmountAnomalous(amount, average float64, ratio int) bool {
+	if ratio <= 0 || average <= 0 {
+		return false
+	}
+	return math.Abs(amount) > average*float64(ratio)
+}
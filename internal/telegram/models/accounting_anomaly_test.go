@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestParseAccountingAnomalyRatio(t *testing.T) {
+	cases := map[string]int{
+		"0":     0,
+		"5":     5,
+		"20倍":   20,
+		"关闭":    accountingAnomalyDisabled,
+		" off ": accountingAnomalyDisabled,
+	}
+	for input, want := range cases {
+		got, err := ParseAccountingAnomalyRatio(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseAccountingAnomalyRatio(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"1", "-1", "1001", "abc", ""} {
+		if _, err := ParseAccountingAnomalyRatio(input); err == nil {
+			t.Fatalf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestAccountingAnomalyRatio(t *testing.T) {
+	if got := AccountingAnomalyRatio(GroupSettings{}); got != DefaultAccountingAnomalyRatio {
+		t.Fatalf("expected default ratio, got %d", got)
+	}
+	if got := AccountingAnomalyRatio(GroupSettings{AccountingAnomalyRatio: 5}); got != 5 {
+		t.Fatalf("expected configured ratio, got %d", got)
+	}
+	if got := AccountingAnomalyRatio(GroupSettings{AccountingAnomalyRatio: accountingAnomalyDisabled}); got != 0 {
+		t.Fatalf("expected disabled ratio, got %d", got)
+	}
+}
+
+func TestAccountingAmountAverage(t *testing.T) {
+	records := []*AccountingRecord{{Amount: 100}, {Amount: -50.5}}
+	if _, ok := AccountingAmountAverage(records); ok {
+		t.Fatal("expected too few samples to be ignored")
+	}
+
+	records = append(records, &AccountingRecord{Amount: 0.1 + 0.2}, &AccountingRecord{Amount: -1.2})
+	average, ok := AccountingAmountAverage(records)
+	if !ok || average != 38 {
+		t.Fatalf("expected average 38, got %v ok=%v", average, ok)
+	}
+
+	if !IsAccountingAmountAnomalous(-380.01, average, 10) || IsAccountingAmountAnomalous(380, average, 10) {
+		t.Fatal("unexpected anomaly result around 10x average")
+	}
+	if IsAccountingAmountAnomalous(1e9, average, 0) {
+		t.Fatal("disabled ratio should never flag anomaly")
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import "testing"
+
+func TestAccountingCents(t *testing.T) {
+	cases := []struct {
+		amount float64
+		want   int64
+	}{
+		{100 * 7.2, 72000},  // 720.0000000000001
+		{0.1 + 0.2, 30},     // 0.30000000000000004
+		{2.01 / 2, 101},     // 1.005，按十进制四舍五入
+		{1.15 * 100, 11500}, // 114.99999999999999
+		{2.675, 268},
+		{-33.335, -3334},
+		{0.004, 0},
+		{1e9 + 0.015, 100000000002},
+	}
+	for _, tc := range cases {
+		if got := AccountingCents(tc.amount); got != tc.want {
+			t.Fatalf("AccountingCents(%v) = %d, want %d", tc.amount, got, tc.want)
+		}
+	}
+}
+
+func TestRoundAccountingAmount(t *testing.T) {
+	for amount, want := range map[float64]float64{100 * 7.2: 720, 0.1 + 0.2: 0.3, 1.15 * 100: 115, -0.001: 0} {
+		if got := RoundAccountingAmount(amount); got != want {
+			t.Fatalf("RoundAccountingAmount(%v) = %v, want %v", amount, got, want)
+		}
+	}
+}
+
+func TestSplitAndNormalizeAccountingBook(t *testing.T) {
+	name, rest := SplitAccountingBook("  #公账 +100U ")
+	if name != "公账" || rest != "+100U" {
+		t.Fatalf("SplitAccountingBook = %q, %q", name, rest)
+	}
+	if name, rest := SplitAccountingBook("+100U"); name != "" || rest != "+100U" {
+		t.Fatalf("expected no book, got %q, %q", name, rest)
+	}
+
+	cases := map[string]string{"公账": "公账", "#私账": "私账", "默认账本": "", "默认": ""}
+	for input, want := range cases {
+		if got, err := NormalizeAccountingBook(input); err != nil || got != want {
+			t.Fatalf("NormalizeAccountingBook(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "#", "a:b", "一二三四五六七八九十一二三四五六七"} {
+		if _, err := NormalizeAccountingBook(input); err == nil {
+			t.Fatalf("expected %q to be rejected", input)
+		}
+	}
+	if AccountingBookLabel("") != AccountingDefaultBookName || AccountingBookLabel("公账") != "公账" {
+		t.Fatal("unexpected book label")
+	}
+}
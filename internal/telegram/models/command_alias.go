@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 群级命令别名的数量与长度限制
+const (
+	MaxCommandAliases     = 30 // 单个群最多别名数
+	MaxCommandAliasLength = 16 // 单个别名最多字符数
+)
+
+// CommandAlias 群级命令别名：消息首词命中 Alias 时按 Command 对应的内置命令处理
+type CommandAlias struct {
+	Alias   string `bson:"alias"`   // 别名（已规范化为小写、无空白）
+	Command string `bson:"command"` // 目标内置命令，例如 "查询记账"、"/余额"
+}
+
+// NormalizeCommandAlias 清理别名首尾空白并统一小写，校验长度且不允许包含空白
+func NormalizeCommandAlias(alias string) (string, error) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if alias == "" {
+		return "", fmt.Errorf("别名不能为空")
+	}
+	if strings.IndexFunc(alias, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("别名不能包含空格")
+	}
+	if n := utf8.RuneCountInString(alias); n > MaxCommandAliasLength {
+		return "", fmt.Errorf("别名最多 %d 个字符，当前为 %d 个", MaxCommandAliasLength, n)
+	}
+	return alias, nil
+}
+
+// FindCommandAlias 查找本群的别名（大小写不敏感）
+func (s GroupSettings) FindCommandAlias(alias string) (CommandAlias, bool) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if alias == "" {
+		return CommandAlias{}, false
+	}
+	for _, item := range s.CommandAliases {
+		if item.Alias == alias {
+			return item, true
+		}
+	}
+	return CommandAlias{}, false
+}
+
+// ResolveCommandAlias 将消息首词按别名替换为内置命令，其余参数原样保留；未命中别名时返回 false
+func (s GroupSettings) ResolveCommandAlias(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || len(s.CommandAliases) == 0 {
+		return "", false
+	}
+	first, rest := text, ""
+	if idx := strings.IndexFunc(text, unicode.IsSpace); idx >= 0 {
+		first, rest = text[:idx], text[idx:]
+	}
+	item, ok := s.FindCommandAlias(first)
+	if !ok {
+		return "", false
+	}
+	return item.Command + rest, true
+}
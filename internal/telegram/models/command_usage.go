@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommandUsageDateLayout 命令使用统计的日期格式（按默认时区）
+const CommandUsageDateLayout = "2006-01-02"
+
+// CommandUsage 命令（或功能）按天的调用次数
+type CommandUsage struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Date      string             `bson:"date"`       // 统计日期（默认时区，YYYY-MM-DD）
+	Command   string             `bson:"command"`    // 命令名（如 /health）或功能名
+	Count     int64              `bson:"count"`      // 调用次数
+	UpdatedAt time.Time          `bson:"updated_at"` // 最近一次累加时间
+}
+
+// CommandUsageRank 一段时间内命令调用次数汇总
+type CommandUsageRank struct {
+	Command string `bson:"_id"`
+	Count   int64  `bson:"count"`
+}
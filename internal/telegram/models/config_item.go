@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 )
 
 // ConfigItemType 配置项类型
@@ -72,7 +73,13 @@ type UserState struct {
 	ChatID     int64           // 聊天 ID
 	Action     string          // 动作标识，如 "input:welcome_text"
 	MessageID  int             // 菜单消息 ID
+	CreatedAt  time.Time       // 创建时间（进入输入状态的时间）
 	ExpiresAt  int64           // 过期时间（Unix 时间戳）
 	RetryCount int             // 重试次数（用于限制验证失败重试）
 	Context    context.Context // 上下文（用于取消操作）
 }
+
+// IsExpired 输入状态是否已过期，过期状态应视为不存在
+func (s *UserState) IsExpired(now time.Time) bool {
+	return s == nil || now.Unix() > s.ExpiresAt
+}
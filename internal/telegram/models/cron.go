@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit 计算下一次触发时间时最多向后搜索的时长
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronField 描述 cron 单个字段的取值范围
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = [5]cronField{
+	{name: "分钟", min: 0, max: 59},
+	{name: "小时", min: 0, max: 23},
+	{name: "日", min: 1, max: 31},
+	{name: "月", min: 1, max: 12},
+	{name: "星期", min: 0, max: 7},
+}
+
+// CronSchedule 解析后的 5 段 cron 表达式（分 时 日 月 周）
+type CronSchedule struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool // 日字段为 *，与星期字段按「且」匹配
+	dowStar bool // 星期字段为 *，与日字段按「且」匹配
+}
+
+// ParseCronSchedule 解析标准 5 段 cron 表达式，支持 *、数字、范围（a-b）、步长（*/n、a-b/n）与逗号列表；
+// 星期字段 0 和 7 均表示周日
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron 表达式需要 5 段（分 时 日 月 周），当前为 %d 段", len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		value, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = value
+	}
+
+	// 星期 7 视为周日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &CronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+// DailyCronExpr 将 HH:MM 格式的每日时刻转换为 cron 表达式
+func DailyCronExpr(clock string) (string, error) {
+	hourText, minuteText, found := strings.Cut(strings.TrimSpace(clock), ":")
+	if !found {
+		return "", fmt.Errorf("时间格式应为 HH:MM")
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil || hour < 0 || hour > 23 {
+		return "", fmt.Errorf("小时必须在 0-23 之间")
+	}
+	minute, err := strconv.Atoi(minuteText)
+	if err != nil || minute < 0 || minute > 59 || len(minuteText) != 2 {
+		return "", fmt.Errorf("分钟必须为 00-59 的两位数字")
+	}
+	return fmt.Sprintf("%d %d * * *", minute, hour), nil
+}
+
+// Next 返回严格晚于 after 的下一次触发时间（按 after 所在时区计算），找不到时返回零值
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	deadline := after.Add(cronSearchLimit)
+
+	for t.Before(deadline) {
+		if !hasBit(c.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !hasBit(c.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !hasBit(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches 日与星期字段均被限定时任一匹配即可（与标准 cron 一致）
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := hasBit(c.dom, t.Day())
+	dowMatch := hasBit(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func parseCronField(text string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(text, ",") {
+		if item == "" {
+			return 0, fmt.Errorf("%s字段格式错误: %s", field.name, text)
+		}
+
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			value, err := strconv.Atoi(stepText)
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("%s字段步长无效: %s", field.name, item)
+			}
+			step = value
+		}
+
+		start, end := field.min, field.max
+		switch {
+		case rangeText == "*":
+		case strings.Contains(rangeText, "-"):
+			lowText, highText, _ := strings.Cut(rangeText, "-")
+			low, err := parseCronValue(lowText, field)
+			if err != nil {
+				return 0, err
+			}
+			high, err := parseCronValue(highText, field)
+			if err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("%s字段范围无效: %s", field.name, item)
+			}
+			start, end = low, high
+		default:
+			value, err := parseCronValue(rangeText, field)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			if hasStep {
+				end = field.max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(text string, field cronField) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s字段取值必须在 %d-%d 之间: %s", field.name, field.min, field.max, text)
+	}
+	return value, nil
+}
+
+func hasBit(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	// 2024-11-20 是周三
+	base := time.Date(2024, 11, 20, 9, 0, 30, 0, loc)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "daily next day when passed", expr: "0 9 * * *", want: time.Date(2024, 11, 21, 9, 0, 0, 0, loc)},
+		{name: "every 15 minutes", expr: "*/15 * * * *", want: time.Date(2024, 11, 20, 9, 15, 0, 0, loc)},
+		{name: "weekdays range", expr: "30 8 * * 1-5", want: time.Date(2024, 11, 21, 8, 30, 0, 0, loc)},
+		{name: "sunday as 7", expr: "0 10 * * 7", want: time.Date(2024, 11, 24, 10, 0, 0, 0, loc)},
+		{name: "list of hours", expr: "0 8,12,18 * * *", want: time.Date(2024, 11, 20, 12, 0, 0, 0, loc)},
+		{name: "month rollover", expr: "0 0 1 * *", want: time.Date(2024, 12, 1, 0, 0, 0, 0, loc)},
+		{name: "dom or dow when both restricted", expr: "0 9 1 * 5", want: time.Date(2024, 11, 22, 9, 0, 0, 0, loc)},
+		{name: "leap day", expr: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.expr, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Fatalf("Next(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1,,2 * * * *"} {
+		if _, err := ParseCronSchedule(expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+
+	schedule, err := ParseCronSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("expected impossible schedule to return zero, got %s", next)
+	}
+}
+
+func TestDailyCronExpr(t *testing.T) {
+	expr, err := DailyCronExpr("09:05")
+	if err != nil || expr != "5 9 * * *" {
+		t.Fatalf("unexpected result: expr=%q err=%v", expr, err)
+	}
+	for _, clock := range []string{"9", "24:00", "09:60", "09:5", "ab:cd"} {
+		if _, err := DailyCronExpr(clock); err == nil {
+			t.Fatalf("expected error for %q", clock)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 每日账单推送状态
+const (
+	DailyBillPushSent    = "sent"    // 已推送，不再重复推送
+	DailyBillPushPending = "pending" // 推送失败，等待补发
+)
+
+// DailyBillPush 记录单个群组某一天的账单推送结果，用于失败补发与防重复推送
+type DailyBillPush struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID     int64              `bson:"chat_id"`              // Telegram 群组 ID
+	MerchantID int64              `bson:"merchant_id"`          // 推送时绑定的商户号
+	Date       string             `bson:"date"`                 // 账单日期（群组时区，YYYY-MM-DD）
+	Status     string             `bson:"status"`               // sent / pending
+	Attempts   int                `bson:"attempts"`             // 累计失败次数（含自动重试后的整轮失败）
+	LastError  string             `bson:"last_error,omitempty"` // 最近一次失败原因
+	CreatedAt  time.Time          `bson:"created_at"`           // 创建时间
+	UpdatedAt  time.Time          `bson:"updated_at"`           // 更新时间
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 被删除消息的来源
+const (
+	DeletedMessageSourceBusiness = "business" // Telegram Business 连接推送的 deleted_business_messages 事件
+)
+
+// DeletedMessage 被删除消息的留档（内容取自删除前已记录的 messages）
+type DeletedMessage struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID            int64              `bson:"chat_id"`                 // 所属聊天 ID
+	TelegramMessageID int64              `bson:"telegram_message_id"`     // Telegram 消息 ID
+	UserID            int64              `bson:"user_id"`                 // 发送者 ID
+	MessageType       string             `bson:"message_type"`            // 消息类型
+	Text              string             `bson:"text,omitempty"`          // 文本内容
+	Caption           string             `bson:"caption,omitempty"`       // 媒体说明文字
+	MediaFileID       string             `bson:"media_file_id,omitempty"` // 文件 ID（可用于重新发送留档）
+	Source            string             `bson:"source"`                  // 删除事件来源
+	SentAt            time.Time          `bson:"sent_at"`                 // 原消息发送时间
+	DeletedAt         time.Time          `bson:"deleted_at"`              // 删除时间（收到事件的时间）
+	CreatedAt         time.Time          `bson:"created_at"`              // 记录创建时间
+}
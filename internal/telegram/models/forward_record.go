@@ -21,3 +21,13 @@ const (
 	ForwardStatusSuccess = "success"
 	ForwardStatusFailed  = "failed"
 )
+
+// ForwardRecordTTL 转发记录与频道消息处理标记的保留时长
+const ForwardRecordTTL = 48 * time.Hour
+
+// ProcessedChannelPost 已处理的频道消息（转发幂等标记，同一条频道消息只转发一次）
+type ProcessedChannelPost struct {
+	ChannelID   int64     `bson:"channel_id"`   // 源频道ID
+	MessageID   int64     `bson:"message_id"`   // 频道消息ID
+	ProcessedAt time.Time `bson:"processed_at"` // 处理时间（TTL索引）
+}
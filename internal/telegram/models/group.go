@@ -2,9 +2,12 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -18,6 +21,9 @@ const (
 	GroupTierUpstream GroupTier = "upstream"
 )
 
+// DefaultGroupTimezone 未配置或配置无效时使用的默认时区
+const DefaultGroupTimezone = "Asia/Shanghai"
+
 // Bot 状态常量
 const (
 	BotStatusActive = "active" // Bot 在群组中活跃
@@ -33,8 +39,10 @@ type Group struct {
 	Title       string             `bson:"title"`                 // 群组名称
 	Username    string             `bson:"username,omitempty"`    // 公开群组的 @username
 	Description string             `bson:"description,omitempty"` // 群组描述
-	MemberCount int                `bson:"member_count"`          // 成员数量（定期更新）
+	MemberCount int                `bson:"member_count"`          // 成员数量（入群/退群事件增减，定期按 Telegram 实际人数校准）
 	Tier        GroupTier          `bson:"tier"`                  // 群组等级：basic/merchant/upstream
+	Note        string             `bson:"note,omitempty"`        // 管理备注（Owner 维护，便于辨认群归属）
+	Tags        []string           `bson:"tags,omitempty"`        // 管理标签（小写去重，可用于按标签筛选群）
 
 	// Bot 状态
 	BotStatus   string     `bson:"bot_status"`            // Bot 状态：active/kicked/left
@@ -44,6 +52,9 @@ type Group struct {
 	// 群组配置
 	Settings GroupSettings `bson:"settings"` // 群组功能配置
 
+	// 离群存档：Bot 被移出时保存配置，保留期内重新入群可恢复
+	ArchivedSettings *GroupSettings `bson:"archived_settings,omitempty"` // 离群前的群组配置（含绑定），恢复时按配置重新推导群等级
+
 	// 统计信息
 	Stats GroupStats `bson:"stats"` // 群组统计数据
 
@@ -53,20 +64,38 @@ type Group struct {
 
 // GroupSettings 群组配置
 type GroupSettings struct {
-	CalculatorEnabled        bool               `bson:"calculator_enabled"`           // 是否启用计算器功能
-	CryptoEnabled            bool               `bson:"crypto_enabled"`               // 是否启用加密货币价格查询功能
-	CryptoFloatRate          float64            `bson:"crypto_float_rate"`            // 加密货币价格浮动费率（默认 0.12）
-	ForwardEnabled           bool               `bson:"forward_enabled"`              // 是否接收频道转发消息
-	AccountingEnabled        bool               `bson:"accounting_enabled"`           // 是否启用收支记账功能
-	MerchantID               int32              `bson:"merchant_id"`                  // 商户号（数字类型，0 表示未绑定）
-	InterfaceBindings        []InterfaceBinding `bson:"interface_bindings,omitempty"` // 接口绑定信息
-	SifangEnabled            bool               `bson:"sifang_enabled"`               // 是否启用四方支付功能
-	SifangAutoLookupEnabled  bool               `bson:"sifang_auto_lookup_enabled"`   // 是否启用四方支付自动查单
-	CascadeForwardEnabled    bool               `bson:"cascade_forward_enabled"`      // 是否启用订单联动转发
-	CascadeForwardConfigured bool               `bson:"cascade_forward_configured"`   // 是否已手动配置转单开关
-	BalanceMonitorEnabled    bool               `bson:"balance_monitor_enabled"`      // 是否启用上游余额轮询告警
-	BalanceMonitorConfigured bool               `bson:"balance_monitor_configured"`   // 是否已手动配置轮询告警
-	BalanceMonitorInterval   int                `bson:"balance_monitor_interval"`     // 轮询间隔（分钟），0 表示使用默认
+	CalculatorEnabled        bool               `bson:"calculator_enabled"`                    // 是否启用计算器功能
+	CryptoEnabled            bool               `bson:"crypto_enabled"`                        // 是否启用加密货币价格查询功能
+	CryptoFloatRate          float64            `bson:"crypto_float_rate"`                     // 加密货币价格浮动费率（默认 0.12）
+	ForwardEnabled           bool               `bson:"forward_enabled"`                       // 是否接收频道转发消息
+	AccountingEnabled        bool               `bson:"accounting_enabled"`                    // 是否启用收支记账功能
+	MerchantID               int32              `bson:"merchant_id"`                           // 当前商户号（数字类型，0 表示未绑定）
+	MerchantIDs              []int32            `bson:"merchant_ids,omitempty"`                // 已绑定的全部商户号（含当前商户号），旧数据仅有 merchant_id
+	InterfaceBindings        []InterfaceBinding `bson:"interface_bindings,omitempty"`          // 接口绑定信息
+	SifangEnabled            bool               `bson:"sifang_enabled"`                        // 是否启用四方支付功能
+	SifangAutoLookupEnabled  bool               `bson:"sifang_auto_lookup_enabled"`            // 是否启用四方支付自动查单
+	CascadeForwardEnabled    bool               `bson:"cascade_forward_enabled"`               // 是否启用订单联动转发
+	CascadeForwardConfigured bool               `bson:"cascade_forward_configured"`            // 是否已手动配置转单开关
+	BalanceMonitorEnabled    bool               `bson:"balance_monitor_enabled"`               // 是否启用上游余额轮询告警
+	BalanceMonitorConfigured bool               `bson:"balance_monitor_configured"`            // 是否已手动配置轮询告警
+	BalanceMonitorInterval   int                `bson:"balance_monitor_interval"`              // 轮询间隔（分钟），0 表示使用默认
+	BalanceAlertTarget       string             `bson:"balance_alert_target,omitempty"`        // 低余额告警目标（group/alert_chat/owners），空表示跟随全局配置
+	SilentScheduledPush      bool               `bson:"silent_scheduled_push,omitempty"`       // 日结、日账、余额告警等调度推送是否静默发送（不响铃）
+	Timezone                 string             `bson:"timezone,omitempty"`                    // 群组时区（IANA 名称），空表示 Asia/Shanghai
+	Language                 string             `bson:"language,omitempty"`                    // 群组语言（zh/en），空表示跟随发送者的 Telegram 语言
+	SendMoneyDailyLimit      float64            `bson:"send_money_daily_limit,omitempty"`      // 四方下发每日限额（元），0 表示不限
+	SendMoneyReviewThreshold float64            `bson:"send_money_review_threshold,omitempty"` // 大额下发复核阈值（元），超过需两位管理员确认，0 表示关闭
+	SendMoneyAccountingLink  bool               `bson:"send_money_accounting_link,omitempty"`  // 四方下发成功后是否联动写一笔出账记账（按下发单号幂等）
+	JoinVerifyEnabled        bool               `bson:"join_verify_enabled"`                   // 是否启用入群验证
+	JoinVerifyTimeout        int                `bson:"join_verify_timeout,omitempty"`         // 入群验证超时（秒），0 表示使用默认
+	MediaSizeLimitMB         int                `bson:"media_size_limit_mb,omitempty"`         // 大文件告警阈值（MB），0 表示关闭
+	MediaBlockedTypes        []string           `bson:"media_blocked_types,omitempty"`         // 可疑文件类型黑名单（.exe 等扩展名或 MIME）
+	MediaAlertNotify         bool               `bson:"media_alert_notify"`                    // 命中媒体告警时是否在群内提醒管理员（关闭时仅记录日志）
+	MediaArchiveTypes        []string           `bson:"media_archive_types,omitempty"`         // 自动归档的媒体类型（photo/document 等），空表示不归档
+	MessageRetentionDays     int                `bson:"message_retention_days,omitempty"`      // 消息保留天数，0 表示跟随群等级默认值
+	AccountingAnomalyRatio   int                `bson:"accounting_anomaly_ratio,omitempty"`    // 记账金额异常提醒倍数，0 表示默认倍数，-1 表示关闭
+	AdminIDs                 []int64            `bson:"admin_ids,omitempty"`                   // 群级管理员（仅管本群，由群主或全局管理员设置）
+	CommandAliases           []CommandAlias     `bson:"command_aliases,omitempty"`             // 群级命令别名（别名 → 内置命令）
 }
 
 // InterfaceBinding 描述单个上游接口绑定
@@ -76,10 +105,130 @@ type InterfaceBinding struct {
 	Rate string `bson:"rate,omitempty"` // 费率描述，例如 "7%"
 }
 
-// GroupStats 群组统计信息
+// GroupStats 群组统计信息（均由原子更新维护，避免并发覆盖）
 type GroupStats struct {
-	TotalMessages int64     `bson:"total_messages"`  // 总消息数
-	LastMessageAt time.Time `bson:"last_message_at"` // 最后一条消息时间
+	TotalMessages       int64     `bson:"total_messages"`                   // 总消息数
+	LastMessageAt       time.Time `bson:"last_message_at"`                  // 最后一条消息时间
+	MemberJoins         int64     `bson:"member_joins"`                     // 累计入群人数（按入群事件统计）
+	MemberLeaves        int64     `bson:"member_leaves"`                    // 累计退群人数（按退群事件统计）
+	MemberCountSyncedAt time.Time `bson:"member_count_synced_at,omitempty"` // 成员数最近一次按 Telegram 实际人数校准的时间
+}
+
+// Clone 深拷贝群组记录（含切片与存档指针），修改副本不会影响原记录
+func (g *Group) Clone() *Group {
+	if g == nil {
+		return nil
+	}
+	clone := *g
+	clone.Tags = slices.Clone(g.Tags)
+	clone.Settings = g.Settings.Clone()
+	if g.BotLeftAt != nil {
+		leftAt := *g.BotLeftAt
+		clone.BotLeftAt = &leftAt
+	}
+	if g.ArchivedSettings != nil {
+		archived := g.ArchivedSettings.Clone()
+		clone.ArchivedSettings = &archived
+	}
+	return &clone
+}
+
+// Clone 深拷贝群组配置中的切片字段
+func (s GroupSettings) Clone() GroupSettings {
+	s.MerchantIDs = slices.Clone(s.MerchantIDs)
+	s.InterfaceBindings = slices.Clone(s.InterfaceBindings)
+	s.MediaBlockedTypes = slices.Clone(s.MediaBlockedTypes)
+	s.MediaArchiveTypes = slices.Clone(s.MediaArchiveTypes)
+	s.AdminIDs = slices.Clone(s.AdminIDs)
+	s.CommandAliases = slices.Clone(s.CommandAliases)
+	return s
+}
+
+// GroupArchiveRetention Bot 离群后配置存档的保留期，超过后群组记录被彻底清理
+const GroupArchiveRetention = 7 * 24 * time.Hour
+
+// HasRestorableArchive 是否存在保留期内可恢复的离群存档
+func (g *Group) HasRestorableArchive(now time.Time) bool {
+	if g == nil || g.ArchivedSettings == nil || g.BotLeftAt == nil {
+		return false
+	}
+	return now.Sub(*g.BotLeftAt) <= GroupArchiveRetention
+}
+
+// 群组备注/标签的长度限制
+const (
+	MaxGroupNoteLength = 100 // 备注最多字符数
+	MaxGroupTagLength  = 20  // 单个标签最多字符数
+	MaxGroupTags       = 10  // 单个群最多标签数
+)
+
+// NormalizeGroupNote 清理备注首尾空白并校验长度
+func NormalizeGroupNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if n := utf8.RuneCountInString(note); n > MaxGroupNoteLength {
+		return "", fmt.Errorf("备注最多 %d 个字符，当前为 %d 个", MaxGroupNoteLength, n)
+	}
+	return note, nil
+}
+
+// NormalizeGroupTag 规范化单个标签：去掉首尾空白和前导 #，统一小写
+func NormalizeGroupTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// NormalizeGroupTags 规范化标签列表：保持原有顺序去重，并校验单个标签长度与标签总数
+func NormalizeGroupTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag := NormalizeGroupTag(raw)
+		if tag == "" {
+			continue
+		}
+		if strings.ContainsAny(tag, " \t\n,，=") {
+			return nil, fmt.Errorf("标签 %q 不能包含空格、逗号或等号", tag)
+		}
+		if n := utf8.RuneCountInString(tag); n > MaxGroupTagLength {
+			return nil, fmt.Errorf("标签 %q 过长（最多 %d 个字符）", tag, MaxGroupTagLength)
+		}
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	if len(result) > MaxGroupTags {
+		return nil, fmt.Errorf("每个群最多 %d 个标签，当前为 %d 个", MaxGroupTags, len(result))
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// HasAnyTag 群组是否带有任一指定标签，tags 为空时视为匹配
+func (g *Group) HasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	if g == nil {
+		return false
+	}
+	for _, tag := range tags {
+		if slices.Contains(g.Tags, NormalizeGroupTag(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatGroupTags 以 #tag 形式展示标签，无标签时返回空字符串
+func FormatGroupTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	labels := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		labels = append(labels, "#"+tag)
+	}
+	return strings.Join(labels, " ")
 }
 
 // IsActive Bot 是否在群组中活跃
@@ -140,6 +289,31 @@ func NormalizeInterfaceBindings(bindings []InterfaceBinding) []InterfaceBinding
 	return clean
 }
 
+// BoundMerchantIDs 返回群组已绑定的全部商户号（保持绑定顺序）
+// 兼容旧数据：仅有 merchant_id 时视为绑定了一个商户号；当前商户号为 0 时视为全部未绑定
+func BoundMerchantIDs(settings GroupSettings) []int32 {
+	return NormalizeMerchantIDs(settings.MerchantID, settings.MerchantIDs)
+}
+
+// NormalizeMerchantIDs 去重并过滤非法商户号，当前商户号不在列表中时补到首位
+func NormalizeMerchantIDs(current int32, ids []int32) []int32 {
+	if current <= 0 {
+		return nil
+	}
+
+	clean := make([]int32, 0, len(ids)+1)
+	for _, id := range ids {
+		if id <= 0 || slices.Contains(clean, id) {
+			continue
+		}
+		clean = append(clean, id)
+	}
+	if !slices.Contains(clean, current) {
+		clean = append([]int32{current}, clean...)
+	}
+	return clean
+}
+
 // NormalizeGroupTier 确保群等级始终有效
 func NormalizeGroupTier(tier GroupTier) GroupTier {
 	if tier == "" {
@@ -164,6 +338,89 @@ func BalanceMonitorIntervalMinutes(settings GroupSettings) time.Duration {
 	return 10 * time.Minute
 }
 
+// DefaultJoinVerifyTimeout 入群验证默认超时
+const DefaultJoinVerifyTimeout = 2 * time.Minute
+
+// JoinVerifyTimeoutDuration 返回入群验证超时，未配置时使用默认值
+func JoinVerifyTimeoutDuration(settings GroupSettings) time.Duration {
+	if settings.JoinVerifyTimeout > 0 {
+		return time.Duration(settings.JoinVerifyTimeout) * time.Second
+	}
+	return DefaultJoinVerifyTimeout
+}
+
+var (
+	locationCache   sync.Map
+	defaultLocation = loadDefaultLocation()
+)
+
+func loadDefaultLocation() *time.Location {
+	loc, err := time.LoadLocation(DefaultGroupTimezone)
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}
+
+// DefaultLocation 返回默认时区（Asia/Shanghai）
+func DefaultLocation() *time.Location {
+	return defaultLocation
+}
+
+// ParseTimezone 校验并加载 IANA 时区名称
+func ParseTimezone(name string) (*time.Location, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return nil, errors.New("时区不能为空")
+	}
+	// time.LoadLocation 会把 "Local" 解析为服务器时区，这里不接受
+	if strings.EqualFold(trimmed, "local") {
+		return nil, fmt.Errorf("无效的时区: %s", trimmed)
+	}
+	if cached, ok := locationCache.Load(trimmed); ok {
+		return cached.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", trimmed)
+	}
+	locationCache.Store(trimmed, loc)
+	return loc, nil
+}
+
+// GroupLocation 返回群组配置的时区，空值或无效值回退到 Asia/Shanghai
+func GroupLocation(settings GroupSettings) *time.Location {
+	if strings.TrimSpace(settings.Timezone) == "" {
+		return defaultLocation
+	}
+	loc, err := ParseTimezone(settings.Timezone)
+	if err != nil {
+		return defaultLocation
+	}
+	return loc
+}
+
+// DayStart 返回 loc 时区下指定日期的第一个时刻，day 越界时按 time.Date 规则顺延（如 0 日为上月末）。
+// 夏令时在午夜切换的时区（如 America/Sao_Paulo）当天 00:00 不存在，time.Date 会落到前一天 23:00，
+// 此时改取时区切换的时刻作为当天起点，保证结果仍在目标日期内
+func DayStart(year int, month time.Month, day int, loc *time.Location) time.Time {
+	// 正午不受午夜切换影响，先用它把越界的日期规范化
+	noon := time.Date(year, month, day, 12, 0, 0, 0, loc)
+	start := time.Date(noon.Year(), noon.Month(), noon.Day(), 0, 0, 0, 0, loc)
+	if start.Day() != noon.Day() {
+		if _, end := start.ZoneBounds(); !end.IsZero() {
+			start = end
+		}
+	}
+	return start
+}
+
+// PreviousBillingDate 返回 now 在 loc 时区下上一账单日（前一天）的起点，日结、上游结算及其调度器统一使用
+func PreviousBillingDate(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	return DayStart(local.Year(), local.Month(), local.Day()-1, loc)
+}
+
 // IsTierAllowed 判断当前群等级是否在允许列表中
 func IsTierAllowed(current GroupTier, allowed []GroupTier) bool {
 	if len(allowed) == 0 {
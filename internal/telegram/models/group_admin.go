@@ -0,0 +1,16 @@
+package models
+
+import "slices"
+
+// MaxGroupAdmins 单个群组可设置的群级管理员上限
+const MaxGroupAdmins = 20
+
+// IsGroupAdmin 是否为本群的群级管理员
+func (s GroupSettings) IsGroupAdmin(userID int64) bool {
+	return userID != 0 && slices.Contains(s.AdminIDs, userID)
+}
+
+// IsGroupChatID 是否为群组的 Chat ID（群组与超级群组 ID 均为负数，私聊为用户 ID）
+func IsGroupChatID(chatID int64) bool {
+	return chatID < 0
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// GroupConfigExportVersion 群组配置导出文件的格式版本，导入时必须一致
+const GroupConfigExportVersion = 1
+
+// GroupConfigExport 群组配置导出文件（JSON），用于备份与跨环境迁移
+// 只包含群 ID、群名与 GroupSettings：Bot Token、四方/上游 API 密钥等凭据只存在于环境变量，不会出现在导出文件中；
+// 备注、标签、统计与离群存档等运行数据也不导出
+type GroupConfigExport struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Groups     []GroupConfigEntry `json:"groups"`
+}
+
+// GroupConfigEntry 单个群组的导出配置
+type GroupConfigEntry struct {
+	TelegramID int64         `json:"telegram_id"`
+	Title      string        `json:"title,omitempty"` // 仅供阅读，导入时不会覆盖群名
+	Settings   GroupSettings `json:"settings"`
+}
+
+// NewGroupConfigEntry 从群组记录生成导出条目（深拷贝配置）
+func NewGroupConfigEntry(group *Group) GroupConfigEntry {
+	return GroupConfigEntry{
+		TelegramID: group.TelegramID,
+		Title:      group.Title,
+		Settings:   group.Settings.Clone(),
+	}
+}
+
+// ParseGroupConfigExport 解析并校验导出文件：版本一致、不含未知字段、群 ID 非零且不重复
+func ParseGroupConfigExport(data []byte) (*GroupConfigExport, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var export GroupConfigExport
+	if err := decoder.Decode(&export); err != nil {
+		return nil, fmt.Errorf("配置文件格式错误: %v", err)
+	}
+	if export.Version != GroupConfigExportVersion {
+		return nil, fmt.Errorf("不支持的配置文件版本 %d（当前版本 %d）", export.Version, GroupConfigExportVersion)
+	}
+	if len(export.Groups) == 0 {
+		return nil, fmt.Errorf("配置文件中没有群组")
+	}
+
+	seen := make(map[int64]struct{}, len(export.Groups))
+	for i, entry := range export.Groups {
+		if entry.TelegramID == 0 {
+			return nil, fmt.Errorf("第 %d 个群组缺少 telegram_id", i+1)
+		}
+		if _, ok := seen[entry.TelegramID]; ok {
+			return nil, fmt.Errorf("群组 %d 在配置文件中重复出现", entry.TelegramID)
+		}
+		seen[entry.TelegramID] = struct{}{}
+	}
+	return &export, nil
+}
+
+// DiffGroupSettings 逐字段比较两份配置，返回有差异的字段名（按结构体声明顺序）；nil 与空切片视为相同
+func DiffGroupSettings(current, incoming GroupSettings) []string {
+	currentValue := reflect.ValueOf(current)
+	incomingValue := reflect.ValueOf(incoming)
+	settingsType := currentValue.Type()
+
+	var fields []string
+	for i := range settingsType.NumField() {
+		a, b := currentValue.Field(i), incomingValue.Field(i)
+		if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			fields = append(fields, settingsType.Field(i).Name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseGroupConfigExport(t *testing.T) {
+	valid := `{"version":1,"exported_at":"2026-01-02T03:04:05Z","groups":[{"telegram_id":-100,"title":"A","settings":{"CalculatorEnabled":true}}]}`
+	export, err := ParseGroupConfigExport([]byte(valid))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(export.Groups) != 1 || export.Groups[0].TelegramID != -100 || !export.Groups[0].Settings.CalculatorEnabled {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "malformed", data: `{"version":1,`, want: "格式错误"},
+		{name: "unknown field", data: `{"version":1,"groups":[{"telegram_id":-100,"token":"x"}]}`, want: "格式错误"},
+		{name: "version", data: `{"version":2,"groups":[{"telegram_id":-100}]}`, want: "版本"},
+		{name: "empty", data: `{"version":1,"groups":[]}`, want: "没有群组"},
+		{name: "missing id", data: `{"version":1,"groups":[{"title":"A"}]}`, want: "缺少 telegram_id"},
+		{name: "duplicate", data: `{"version":1,"groups":[{"telegram_id":-100},{"telegram_id":-100}]}`, want: "重复"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGroupConfigExport([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDiffGroupSettings(t *testing.T) {
+	current := GroupSettings{CalculatorEnabled: true, MerchantIDs: nil, Timezone: "Asia/Shanghai"}
+	incoming := GroupSettings{CalculatorEnabled: true, MerchantIDs: []int32{}, Timezone: "UTC", AdminIDs: []int64{42}}
+
+	got := DiffGroupSettings(current, incoming)
+	if want := []string{"Timezone", "AdminIDs"}; !slices.Equal(got, want) {
+		t.Fatalf("DiffGroupSettings = %v, want %v", got, want)
+	}
+	if diff := DiffGroupSettings(current, current.Clone()); len(diff) != 0 {
+		t.Fatalf("expected no diff for identical settings, got %v", diff)
+	}
+}
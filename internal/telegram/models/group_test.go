@@ -1,6 +1,11 @@
 package models
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestDetermineGroupTier(t *testing.T) {
 	tests := []struct {
@@ -102,3 +107,158 @@ func TestTierHelpers(t *testing.T) {
 		t.Fatalf("expected %s, got %s", expected, list)
 	}
 }
+
+func TestGroupLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{name: "empty falls back to default", timezone: "", want: DefaultGroupTimezone},
+		{name: "invalid falls back to default", timezone: "Mars/Olympus", want: DefaultGroupTimezone},
+		{name: "local is rejected", timezone: "Local", want: DefaultGroupTimezone},
+		{name: "valid timezone", timezone: "America/New_York", want: "America/New_York"},
+		{name: "trims spaces", timezone: " Europe/London ", want: "Europe/London"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupLocation(GroupSettings{Timezone: tt.timezone})
+			if got.String() != tt.want {
+				t.Fatalf("expected location %s, got %s", tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestPreviousBillingDate(t *testing.T) {
+	shanghai := DefaultLocation()
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		loc  *time.Location
+		want string
+	}{
+		{name: "same month", now: time.Date(2024, 10, 2, 0, 0, 10, 0, shanghai), loc: shanghai, want: "2024-10-01"},
+		{name: "cross month", now: time.Date(2024, 5, 1, 8, 0, 0, 0, shanghai), loc: shanghai, want: "2024-04-30"},
+		{name: "leap year", now: time.Date(2024, 3, 1, 8, 0, 0, 0, shanghai), loc: shanghai, want: "2024-02-29"},
+		{name: "non leap year", now: time.Date(2023, 3, 1, 0, 0, 5, 0, shanghai), loc: shanghai, want: "2023-02-28"},
+		{name: "cross year", now: time.Date(2025, 1, 1, 0, 0, 5, 0, shanghai), loc: shanghai, want: "2024-12-31"},
+		// UTC 仍在 12-31，群组时区已到 1 月 1 日
+		{name: "now in another zone", now: time.Date(2024, 12, 31, 16, 30, 0, 0, time.UTC), loc: shanghai, want: "2024-12-31"},
+		{name: "day after midnight DST start", now: time.Date(2018, 11, 5, 8, 0, 0, 0, saoPaulo), loc: saoPaulo, want: "2018-11-04"},
+		{name: "day after spring forward", now: time.Date(2024, 3, 11, 0, 0, 5, 0, newYork), loc: newYork, want: "2024-03-10"},
+		{name: "day after fall back", now: time.Date(2024, 11, 4, 0, 0, 5, 0, newYork), loc: newYork, want: "2024-11-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PreviousBillingDate(tt.now, tt.loc)
+			if got.Format("2006-01-02") != tt.want || got.Location() != tt.loc {
+				t.Fatalf("expected %s in %s, got %v", tt.want, tt.loc, got)
+			}
+			if want := DayStart(got.Year(), got.Month(), got.Day(), tt.loc); !got.Equal(want) {
+				t.Fatalf("expected start of day %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDayStart(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 00:00 不存在时取时区切换时刻
+	got := DayStart(2018, 11, 4, saoPaulo)
+	if want := time.Date(2018, 11, 4, 3, 0, 0, 0, time.UTC); !got.Equal(want) || got.Day() != 4 {
+		t.Fatalf("expected %v, got %v", want.In(saoPaulo), got)
+	}
+	// 切换当天只有 23 小时
+	if length := DayStart(2018, 11, 5, saoPaulo).Sub(got); length != 23*time.Hour {
+		t.Fatalf("expected 23h day, got %v", length)
+	}
+	// 越界日期顺延
+	if got := DayStart(2025, 1, 0, DefaultLocation()); got.Format("2006-01-02 15:04") != "2024-12-31 00:00" {
+		t.Fatalf("expected 2024-12-31 00:00, got %v", got)
+	}
+}
+
+func TestBoundMerchantIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings GroupSettings
+		want     []int32
+	}{
+		{name: "none bound", settings: GroupSettings{}, want: nil},
+		{name: "legacy single merchant", settings: GroupSettings{MerchantID: 1001}, want: []int32{1001}},
+		{name: "keeps binding order", settings: GroupSettings{MerchantID: 1002, MerchantIDs: []int32{1001, 1002, 1001, 0}}, want: []int32{1001, 1002}},
+		{name: "current missing from list", settings: GroupSettings{MerchantID: 1003, MerchantIDs: []int32{1001}}, want: []int32{1003, 1001}},
+		{name: "cleared current unbinds all", settings: GroupSettings{MerchantIDs: []int32{1001, 1002}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BoundMerchantIDs(tt.settings)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGroupSettingsIsGroupAdmin(t *testing.T) {
+	settings := GroupSettings{AdminIDs: []int64{42, 7}}
+	if !settings.IsGroupAdmin(42) || settings.IsGroupAdmin(8) || settings.IsGroupAdmin(0) {
+		t.Fatalf("unexpected group admin check for %v", settings.AdminIDs)
+	}
+	if !IsGroupChatID(-1001) || IsGroupChatID(42) || IsGroupChatID(0) {
+		t.Fatal("unexpected group chat id check")
+	}
+}
+
+func TestGroupSettingsResolveCommandAlias(t *testing.T) {
+	settings := GroupSettings{CommandAliases: []CommandAlias{
+		{Alias: "查账", Command: "查询记账"},
+		{Alias: "/ye", Command: "/余额"},
+	}}
+
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{text: "查账", want: "查询记账", ok: true},
+		{text: " /YE  2024-01-05", want: "/余额  2024-01-05", ok: true},
+		{text: "查账吧", ok: false},
+		{text: "查询记账", ok: false},
+		{text: "", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := settings.ResolveCommandAlias(tt.text)
+		if ok != tt.ok || got != tt.want {
+			t.Fatalf("ResolveCommandAlias(%q) = %q, %t; want %q, %t", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeCommandAlias(t *testing.T) {
+	if alias, err := NormalizeCommandAlias("  /CX "); err != nil || alias != "/cx" {
+		t.Fatalf("unexpected normalize result: %q, %v", alias, err)
+	}
+	for _, bad := range []string{"", "  ", "查 账", strings.Repeat("账", MaxCommandAliasLength+1)} {
+		if _, err := NormalizeCommandAlias(bad); err == nil {
+			t.Fatalf("expected error for alias %q", bad)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JoinVerification 新成员入群验证状态，持久化后 Bot 重启仍能完成验证或处理超时
+type JoinVerification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string             `bson:"token"`      // 回调 token（唯一）
+	ChatID    int64              `bson:"chat_id"`    // 群组 ID
+	UserID    int64              `bson:"user_id"`    // 待验证成员
+	MessageID int                `bson:"message_id"` // 验证题消息 ID
+	Answer    int                `bson:"answer"`     // 正确答案
+	ExpiresAt time.Time          `bson:"expires_at"` // 超时时间，超时未验证踢出
+	CreatedAt time.Time          `bson:"created_at"` // 创建时间
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 媒体告警规则的限制
+const (
+	MaxMediaSizeLimitMB       = 4000 // 大文件阈值上限（MB），超过 Telegram 单文件上限没有意义
+	MaxMediaBlockedTypes      = 20   // 类型黑名单最多条目数
+	maxMediaBlockedTypeLength = 64   // 单个条目最多字符数
+)
+
+// HasMediaAlertRules 群组是否配置了任一媒体告警规则（大小阈值或类型黑名单）
+func HasMediaAlertRules(settings GroupSettings) bool {
+	return settings.MediaSizeLimitMB > 0 || len(settings.MediaBlockedTypes) > 0
+}
+
+// ParseMediaSizeLimit 解析大文件阈值（MB），0 表示关闭
+func ParseMediaSizeLimit(input string) (int, error) {
+	input = strings.TrimSpace(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(input)), "MB"))
+	limit, err := strconv.Atoi(input)
+	if err != nil || limit < 0 || limit > MaxMediaSizeLimitMB {
+		return 0, fmt.Errorf("请输入 0~%d 之间的整数（单位 MB），0 表示关闭", MaxMediaSizeLimitMB)
+	}
+	return limit, nil
+}
+
+// NormalizeMediaBlockedTypes 解析类型黑名单，条目用逗号或空格分隔：
+// 含 "/" 的视为 MIME（支持 application/* 通配子类型），其余视为扩展名（自动补全前导点），统一小写并去重
+func NormalizeMediaBlockedTypes(input string) ([]string, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	result := make([]string, 0, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		entry := strings.ToLower(strings.TrimSpace(field))
+		if entry == "" {
+			continue
+		}
+		if len(entry) > maxMediaBlockedTypeLength {
+			return nil, fmt.Errorf("类型 %s 过长", field)
+		}
+		if strings.Contains(entry, "/") {
+			major, minor, _ := strings.Cut(entry, "/")
+			if major == "" || minor == "" || strings.ContainsAny(major, "*/") || (minor != "*" && strings.ContainsAny(minor, "*/")) {
+				return nil, fmt.Errorf("无效的 MIME 类型：%s", field)
+			}
+		} else {
+			entry = "." + strings.TrimLeft(entry, ".")
+			if entry == "." || strings.ContainsAny(entry, "*?") {
+				return nil, fmt.Errorf("无效的扩展名：%s", field)
+			}
+		}
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		result = append(result, entry)
+	}
+
+	if len(result) > MaxMediaBlockedTypes {
+		return nil, fmt.Errorf("类型黑名单最多 %d 项，当前为 %d 项", MaxMediaBlockedTypes, len(result))
+	}
+	return result, nil
+}
+
+// MediaAlertReasons 按群组规则检查媒体文件，返回触发的告警原因，未触发时返回 nil
+func MediaAlertReasons(settings GroupSettings, fileSize int64, mimeType string, fileNames []string) []string {
+	var reasons []string
+
+	if limit := settings.MediaSizeLimitMB; limit > 0 && fileSize > int64(limit)*1024*1024 {
+		reasons = append(reasons, fmt.Sprintf("文件大小 %.1f MB 超过 %d MB", float64(fileSize)/1024/1024, limit))
+	}
+
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	for _, entry := range settings.MediaBlockedTypes {
+		if strings.Contains(entry, "/") {
+			if mimeType != "" && matchMimeType(entry, mimeType) {
+				reasons = append(reasons, fmt.Sprintf("MIME 类型 %s 在黑名单中", mimeType))
+			}
+			continue
+		}
+		for _, name := range fileNames {
+			if strings.EqualFold(path.Ext(name), entry) {
+				reasons = append(reasons, fmt.Sprintf("扩展名 %s 在黑名单中", entry))
+				break
+			}
+		}
+	}
+
+	return reasons
+}
+
+// matchMimeType 判断 MIME 是否命中黑名单条目，条目子类型为 * 时匹配整个大类
+func matchMimeType(pattern, mimeType string) bool {
+	if major, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mimeType, major+"/")
+	}
+	return pattern == mimeType
+}
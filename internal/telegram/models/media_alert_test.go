@@ -0,0 +1,59 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeMediaBlockedTypes(t *testing.T) {
+	types, err := NormalizeMediaBlockedTypes("exe, .APK，application/x-msdownload  .exe application/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{".exe", ".apk", "application/x-msdownload", "application/*"}
+	if !slices.Equal(types, want) {
+		t.Fatalf("expected %v, got %v", want, types)
+	}
+
+	for _, input := range []string{"application/", "/zip", "*.exe", ".", "application/x-*", "*/*"} {
+		if _, err := NormalizeMediaBlockedTypes(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestParseMediaSizeLimit(t *testing.T) {
+	for input, want := range map[string]int{"50": 50, " 100mb ": 100, "0": 0} {
+		got, err := ParseMediaSizeLimit(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseMediaSizeLimit(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"-1", "abc", "5000"} {
+		if _, err := ParseMediaSizeLimit(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestMediaAlertReasons(t *testing.T) {
+	settings := GroupSettings{
+		MediaSizeLimitMB:  10,
+		MediaBlockedTypes: []string{".exe", "application/x-msdownload", "video/*"},
+	}
+
+	if reasons := MediaAlertReasons(settings, 5*1024*1024, "image/jpeg", nil); len(reasons) != 0 {
+		t.Fatalf("expected normal media to pass, got %v", reasons)
+	}
+	if reasons := MediaAlertReasons(GroupSettings{}, 500*1024*1024, "application/x-msdownload", []string{"a.exe"}); len(reasons) != 0 {
+		t.Fatalf("expected no reasons without rules, got %v", reasons)
+	}
+
+	reasons := MediaAlertReasons(settings, 20*1024*1024, "application/x-msdownload", []string{"Setup.EXE"})
+	if len(reasons) != 3 {
+		t.Fatalf("expected size, MIME and extension reasons, got %v", reasons)
+	}
+	if reasons := MediaAlertReasons(settings, 0, "video/mp4", nil); len(reasons) != 1 {
+		t.Fatalf("expected video/* to match, got %v", reasons)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// mediaArchiveTypeAliases 媒体归档类型的输入别名（英文类型名或中文名）
+var mediaArchiveTypeAliases = map[string]string{
+	MessageTypePhoto:     MessageTypePhoto,
+	"图片":                 MessageTypePhoto,
+	MessageTypeVideo:     MessageTypeVideo,
+	"视频":                 MessageTypeVideo,
+	MessageTypeDocument:  MessageTypeDocument,
+	"文件":                 MessageTypeDocument,
+	MessageTypeVoice:     MessageTypeVoice,
+	"语音":                 MessageTypeVoice,
+	MessageTypeAudio:     MessageTypeAudio,
+	"音频":                 MessageTypeAudio,
+	MessageTypeAnimation: MessageTypeAnimation,
+	"动图":                 MessageTypeAnimation,
+}
+
+// ArchivableMediaTypes 可归档的媒体类型（展示顺序）
+var ArchivableMediaTypes = []string{
+	MessageTypePhoto, MessageTypeVideo, MessageTypeDocument,
+	MessageTypeVoice, MessageTypeAudio, MessageTypeAnimation,
+}
+
+// NormalizeMediaArchiveTypes 解析归档类型，条目用逗号或空格分隔，支持英文类型名、中文名与 all/全部，按展示顺序去重
+func NormalizeMediaArchiveTypes(input string) ([]string, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	selected := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		entry := strings.ToLower(strings.TrimSpace(field))
+		if entry == "all" || entry == "全部" {
+			return slices.Clone(ArchivableMediaTypes), nil
+		}
+		messageType, ok := mediaArchiveTypeAliases[entry]
+		if !ok {
+			return nil, fmt.Errorf("不支持的媒体类型：%s（可选 %s）", field, strings.Join(ArchivableMediaTypes, "/"))
+		}
+		selected[messageType] = struct{}{}
+	}
+
+	result := make([]string, 0, len(selected))
+	for _, messageType := range ArchivableMediaTypes {
+		if _, ok := selected[messageType]; ok {
+			result = append(result, messageType)
+		}
+	}
+	return result, nil
+}
+
+// ShouldArchiveMedia 群组是否配置了归档该类型的媒体
+func ShouldArchiveMedia(settings GroupSettings, messageType string) bool {
+	return slices.Contains(settings.MediaArchiveTypes, messageType)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeMediaArchiveTypes(t *testing.T) {
+	got, err := NormalizeMediaArchiveTypes("文件, photo 图片，DOCUMENT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(got, []string{MessageTypePhoto, MessageTypeDocument}) {
+		t.Fatalf("expected deduplicated types in display order, got %v", got)
+	}
+
+	got, err = NormalizeMediaArchiveTypes("video all")
+	if err != nil || !slices.Equal(got, ArchivableMediaTypes) {
+		t.Fatalf("expected all types, got %v err=%v", got, err)
+	}
+
+	if _, err := NormalizeMediaArchiveTypes("photo sticker"); err == nil {
+		t.Fatal("expected unsupported type error")
+	}
+}
+
+func TestShouldArchiveMedia(t *testing.T) {
+	settings := GroupSettings{MediaArchiveTypes: []string{MessageTypeDocument}}
+	if !ShouldArchiveMedia(settings, MessageTypeDocument) || ShouldArchiveMedia(settings, MessageTypePhoto) {
+		t.Fatalf("unexpected archive decision for %v", settings.MediaArchiveTypes)
+	}
+	if ShouldArchiveMedia(GroupSettings{}, MessageTypeDocument) {
+		t.Fatal("expected no archive without configured types")
+	}
+}
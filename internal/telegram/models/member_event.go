@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 成员变动事件类型
+const (
+	MemberEventJoin  = "join"
+	MemberEventLeave = "leave"
+)
+
+// MemberEvent 群成员入群/退群事件
+type MemberEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID     int64              `bson:"chat_id"`              // 群组 ID
+	UserID     int64              `bson:"user_id"`              // 成员 Telegram ID
+	Username   string             `bson:"username,omitempty"`   // 事件发生时的用户名
+	FirstName  string             `bson:"first_name,omitempty"` // 事件发生时的名字
+	LastName   string             `bson:"last_name,omitempty"`  // 事件发生时的姓氏
+	EventType  string             `bson:"event_type"`           // join / leave
+	OccurredAt time.Time          `bson:"occurred_at"`          // 事件时间（系统消息发送时间）
+	CreatedAt  time.Time          `bson:"created_at"`           // 记录创建时间
+}
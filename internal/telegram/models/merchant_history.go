@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 商户号变更动作
+const (
+	MerchantHistoryBind   = "bind"
+	MerchantHistoryUnbind = "unbind"
+	MerchantHistorySwitch = "switch"
+	// MerchantHistoryRestore Bot 重新入群时从离群存档恢复绑定
+	MerchantHistoryRestore = "restore"
+	// MerchantHistoryImport /import_configs 导入配置时改动了绑定
+	MerchantHistoryImport = "import"
+)
+
+// MerchantHistorySystemOperator 系统自动变更（离群自动解绑、存档恢复）记录的操作人名称，OperatorID 为 0
+const MerchantHistorySystemOperator = "系统"
+
+// MerchantHistory 群组商户号变更记录（绑定/解绑/切换），保存变更前后的商户号列表与当前商户号
+type MerchantHistory struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID           int64              `bson:"chat_id"`                     // 群组 ID
+	Action           string             `bson:"action"`                      // bind / unbind / switch
+	OldMerchantIDs   []int32            `bson:"old_merchant_ids,omitempty"`  // 变更前已绑定商户号
+	NewMerchantIDs   []int32            `bson:"new_merchant_ids,omitempty"`  // 变更后已绑定商户号
+	OldCurrent       int32              `bson:"old_current,omitempty"`       // 变更前当前商户号
+	NewCurrent       int32              `bson:"new_current,omitempty"`       // 变更后当前商户号
+	OperatorID       int64              `bson:"operator_id"`                 // 操作人 Telegram ID
+	OperatorUsername string             `bson:"operator_username,omitempty"` // 操作时的用户名
+	OperatorName     string             `bson:"operator_name,omitempty"`     // 操作时的显示名
+	CreatedAt        time.Time          `bson:"created_at"`                  // 操作时间
+}
@@ -8,17 +8,26 @@ import (
 
 // 消息类型常量
 const (
-	MessageTypeText      = "text"
-	MessageTypePhoto     = "photo"
-	MessageTypeVideo     = "video"
-	MessageTypeDocument  = "document"
-	MessageTypeVoice     = "voice"
-	MessageTypeAudio     = "audio"
-	MessageTypeSticker   = "sticker"
-	MessageTypeAnimation = "animation"
+	MessageTypeText        = "text"
+	MessageTypePhoto       = "photo"
+	MessageTypeVideo       = "video"
+	MessageTypeDocument    = "document"
+	MessageTypeVoice       = "voice"
+	MessageTypeAudio       = "audio"
+	MessageTypeSticker     = "sticker"
+	MessageTypeAnimation   = "animation"
 	MessageTypeChannelPost = "channel_post"
 )
 
+// MaxMessageEditHistory 单条消息保留的编辑历史上限，超出后丢弃最早的版本
+const MaxMessageEditHistory = 20
+
+// MessageEdit 消息的一次历史版本（被编辑前的内容）
+type MessageEdit struct {
+	Text     string    `bson:"text"`      // 编辑前的文本
+	EditedAt time.Time `bson:"edited_at"` // 被替换的时间
+}
+
 // Message 消息模型
 type Message struct {
 	ID                primitive.ObjectID `bson:"_id,omitempty"`
@@ -27,9 +36,16 @@ type Message struct {
 	UserID            int64              `bson:"user_id"`             // 发送者 ID（频道消息可能为 0）
 
 	// 消息内容
-	MessageType string `bson:"message_type"`         // 消息类型
-	Text        string `bson:"text,omitempty"`       // 文本内容
-	Caption     string `bson:"caption,omitempty"`    // 媒体说明文字
+	MessageType string `bson:"message_type"`      // 消息类型
+	Text        string `bson:"text,omitempty"`    // 文本内容
+	Caption     string `bson:"caption,omitempty"` // 媒体说明文字
+
+	// 消息实体（链接、@提及、话题标签等，旧数据缺省为空）
+	Entities       []MessageEntity `bson:"entities,omitempty"`         // Telegram 原始实体（类型 + UTF-16 偏移 + 长度）
+	Mentions       []string        `bson:"mentions,omitempty"`         // @提及的用户名（小写、不含 @）
+	MentionUserIDs []int64         `bson:"mention_user_ids,omitempty"` // text_mention 提及的用户 ID
+	Hashtags       []string        `bson:"hashtags,omitempty"`         // 话题标签（小写、不含 #）
+	URLs           []string        `bson:"urls,omitempty"`             // 文本链接与文字超链接地址
 
 	// 媒体信息
 	MediaFileID      string `bson:"media_file_id,omitempty"`      // 文件 ID
@@ -37,19 +53,34 @@ type Message struct {
 	MediaMimeType    string `bson:"media_mime_type,omitempty"`    // MIME 类型
 	MediaThumbnailID string `bson:"media_thumbnail_id,omitempty"` // 缩略图 ID
 
+	// 媒体去重（按 file_unique_id 识别同一文件）
+	MediaFileUniqueID    string `bson:"media_file_unique_id,omitempty"`    // 文件唯一 ID（跨消息、跨 Bot 不变）
+	DuplicateOfMessageID int64  `bson:"duplicate_of_message_id,omitempty"` // 重复媒体指向的首条消息 ID
+	DuplicateCount       int    `bson:"duplicate_count,omitempty"`         // 首条消息被重复发送的次数
+
+	// 媒体归档（按群配置的类型转存到本地目录或 S3 兼容存储）
+	ArchiveURL string     `bson:"archive_url,omitempty"` // 归档地址（本地绝对路径或对象 URL）
+	ArchivedAt *time.Time `bson:"archived_at,omitempty"` // 归档完成时间
+
 	// 关联信息
-	ReplyToMessageID     int64 `bson:"reply_to_message_id,omitempty"`      // 回复的消息 ID
-	ForwardFromChatID    int64 `bson:"forward_from_chat_id,omitempty"`     // 转发来源聊天 ID
-	ForwardFromMessageID int64 `bson:"forward_from_message_id,omitempty"`  // 转发来源消息 ID
+	ReplyToMessageID     int64 `bson:"reply_to_message_id,omitempty"`     // 回复的消息 ID
+	ForwardFromChatID    int64 `bson:"forward_from_chat_id,omitempty"`    // 转发来源聊天 ID
+	ForwardFromMessageID int64 `bson:"forward_from_message_id,omitempty"` // 转发来源消息 ID
 
 	// 编辑信息
-	IsEdited bool       `bson:"is_edited"`           // 是否被编辑过
-	EditedAt *time.Time `bson:"edited_at,omitempty"` // 编辑时间
+	IsEdited    bool          `bson:"is_edited"`              // 是否被编辑过
+	EditedAt    *time.Time    `bson:"edited_at,omitempty"`    // 编辑时间
+	EditHistory []MessageEdit `bson:"edit_history,omitempty"` // 编辑历史（按时间正序，最多 MaxMessageEditHistory 条）
+
+	// 删除信息（仅能感知 Business 连接推送的删除事件）
+	IsDeleted bool       `bson:"is_deleted,omitempty"` // 是否已被删除
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"` // 删除时间
 
 	// 时间信息
-	SentAt    time.Time `bson:"sent_at"`    // 发送时间
-	CreatedAt time.Time `bson:"created_at"` // 记录创建时间
-	UpdatedAt time.Time `bson:"updated_at"` // 记录更新时间
+	SentAt    time.Time  `bson:"sent_at"`             // 发送时间
+	ExpireAt  *time.Time `bson:"expire_at,omitempty"` // 过期时间（按群组保留期计算，由 TTL 索引清理）
+	CreatedAt time.Time  `bson:"created_at"`          // 记录创建时间
+	UpdatedAt time.Time  `bson:"updated_at"`          // 记录更新时间
 }
 
 // IsMediaMessage 是否为媒体消息
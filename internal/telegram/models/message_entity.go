@@ -0,0 +1,99 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// Telegram 消息实体类型（仅列出用于检索的类型，其余类型原样保存）
+const (
+	MessageEntityMention     = "mention"      // @username
+	MessageEntityTextMention = "text_mention" // 无用户名用户的提及，携带 user_id
+	MessageEntityHashtag     = "hashtag"      // #话题
+	MessageEntityURL         = "url"          // 文本中的链接
+	MessageEntityTextLink    = "text_link"    // 文字超链接，地址在 url 字段
+)
+
+// 消息实体检索方式
+const (
+	MessageEntitySearchMention = "mention" // 按 @提及的用户名精确匹配（不区分大小写）
+	MessageEntitySearchHashtag = "hashtag" // 按话题标签精确匹配（不区分大小写）
+	MessageEntitySearchURL     = "url"     // 按链接片段模糊匹配（不区分大小写）
+)
+
+// MessageEntity Telegram 消息实体，Offset/Length 为 UTF-16 码元（与 Bot API 一致）
+type MessageEntity struct {
+	Type   string `bson:"type"`
+	Offset int    `bson:"offset"`
+	Length int    `bson:"length"`
+	URL    string `bson:"url,omitempty"`     // text_link 的目标地址
+	UserID int64  `bson:"user_id,omitempty"` // text_mention 指向的用户
+}
+
+// ApplyEntities 保存消息实体，并从文本中解析出 @提及、话题标签与链接写入检索字段
+// 用户名与话题统一小写并去掉前缀符号，结果去重且保持出现顺序；越界的实体只保存不解析
+func (m *Message) ApplyEntities(entities []MessageEntity) {
+	m.Entities = nil
+	m.Mentions = nil
+	m.MentionUserIDs = nil
+	m.Hashtags = nil
+	m.URLs = nil
+	if len(entities) == 0 {
+		return
+	}
+
+	m.Entities = append([]MessageEntity(nil), entities...)
+	units := utf16.Encode([]rune(m.Text))
+	for _, entity := range entities {
+		switch entity.Type {
+		case MessageEntityTextMention:
+			if entity.UserID != 0 && !slices.Contains(m.MentionUserIDs, entity.UserID) {
+				m.MentionUserIDs = append(m.MentionUserIDs, entity.UserID)
+			}
+			continue
+		case MessageEntityTextLink:
+			m.URLs = appendUnique(m.URLs, strings.TrimSpace(entity.URL))
+			continue
+		}
+
+		value, ok := entityText(units, entity)
+		if !ok {
+			continue
+		}
+		switch entity.Type {
+		case MessageEntityMention:
+			m.Mentions = appendUnique(m.Mentions, NormalizeMention(value))
+		case MessageEntityHashtag:
+			m.Hashtags = appendUnique(m.Hashtags, NormalizeHashtag(value))
+		case MessageEntityURL:
+			m.URLs = appendUnique(m.URLs, value)
+		}
+	}
+}
+
+// NormalizeMention 统一 @提及的存储与检索格式：去掉 @ 并转小写
+func NormalizeMention(value string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "@"))
+}
+
+// NormalizeHashtag 统一话题标签的存储与检索格式：去掉 # 并转小写
+func NormalizeHashtag(value string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "#"))
+}
+
+// entityText 按 UTF-16 偏移截取实体对应的文本
+func entityText(units []uint16, entity MessageEntity) (string, bool) {
+	end := entity.Offset + entity.Length
+	if entity.Offset < 0 || entity.Length <= 0 || end > len(units) {
+		return "", false
+	}
+	return string(utf16.Decode(units[entity.Offset:end])), true
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMessageApplyEntities(t *testing.T) {
+	// 😀 占两个 UTF-16 码元，后续实体的偏移需按 UTF-16 计算
+	msg := &Message{Text: "😀 @Alice 看 https://Example.com/a #Daily @alice 文档"}
+	msg.ApplyEntities([]MessageEntity{
+		{Type: MessageEntityMention, Offset: 3, Length: 6},
+		{Type: MessageEntityURL, Offset: 12, Length: 21},
+		{Type: MessageEntityHashtag, Offset: 34, Length: 6},
+		{Type: MessageEntityMention, Offset: 41, Length: 6},
+		{Type: MessageEntityTextLink, Offset: 48, Length: 2, URL: "https://docs.example.com"},
+		{Type: MessageEntityTextMention, Offset: 0, Length: 2, UserID: 42},
+		{Type: "bold", Offset: 0, Length: 2},
+		{Type: MessageEntityMention, Offset: 100, Length: 5},
+	})
+
+	if len(msg.Entities) != 8 {
+		t.Fatalf("expected all entities stored, got %d", len(msg.Entities))
+	}
+	if !slices.Equal(msg.Mentions, []string{"alice"}) {
+		t.Fatalf("unexpected mentions: %v", msg.Mentions)
+	}
+	if !slices.Equal(msg.Hashtags, []string{"daily"}) {
+		t.Fatalf("unexpected hashtags: %v", msg.Hashtags)
+	}
+	if !slices.Equal(msg.URLs, []string{"https://Example.com/a", "https://docs.example.com"}) {
+		t.Fatalf("unexpected urls: %v", msg.URLs)
+	}
+	if !slices.Equal(msg.MentionUserIDs, []int64{42}) {
+		t.Fatalf("unexpected mention user ids: %v", msg.MentionUserIDs)
+	}
+
+	// 无实体时清空检索字段，旧数据保持缺省
+	msg.ApplyEntities(nil)
+	if msg.Entities != nil || msg.Mentions != nil || msg.URLs != nil || msg.Hashtags != nil || msg.MentionUserIDs != nil {
+		t.Fatalf("expected entity fields cleared, got %+v", msg)
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxMessageRetentionDays 群组单独配置的消息保留天数上限
+const MaxMessageRetentionDays = 365
+
+// MessageRetentionPolicy 消息保留期策略，优先级：群组单独配置 > 群等级默认值 > 全局默认值
+// 过期时间写入消息的 expire_at 字段，由集合上的单一 TTL 索引统一清理，因此不同群可以有不同保留期
+type MessageRetentionPolicy struct {
+	DefaultDays int               // 全局默认保留天数（MESSAGE_RETENTION_DAYS），<= 0 表示不写入过期时间
+	TierDays    map[GroupTier]int // 按群等级覆盖的保留天数
+}
+
+// TierDefaultDays 返回群等级的默认保留天数（未单独配置时使用全局默认值）
+func (p MessageRetentionPolicy) TierDefaultDays(tier GroupTier) int {
+	if days, ok := p.TierDays[NormalizeGroupTier(tier)]; ok && days > 0 {
+		return days
+	}
+	return p.DefaultDays
+}
+
+// DaysFor 返回群组生效的保留天数，group 为 nil（私聊、频道或群组记录不存在）时使用全局默认值
+func (p MessageRetentionPolicy) DaysFor(group *Group) int {
+	if group == nil {
+		return p.DefaultDays
+	}
+	if days := group.Settings.MessageRetentionDays; days > 0 {
+		return days
+	}
+	return p.TierDefaultDays(group.Tier)
+}
+
+// MessageExpireAt 按保留天数计算消息过期时间，days <= 0 时返回 nil（不过期）
+func MessageExpireAt(sentAt time.Time, days int) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+	expireAt := sentAt.Add(time.Duration(days) * 24 * time.Hour)
+	return &expireAt
+}
+
+// ParseMessageRetentionDays 解析群组消息保留天数，0 表示跟随群等级默认值
+func ParseMessageRetentionDays(input string) (int, error) {
+	input = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(input), "天"))
+	days, err := strconv.Atoi(input)
+	if err != nil || days < 0 || days > MaxMessageRetentionDays {
+		return 0, fmt.Errorf("请输入 0~%d 之间的整数（单位：天），0 表示跟随群等级默认值", MaxMessageRetentionDays)
+	}
+	return days, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageRetentionPolicyDaysFor(t *testing.T) {
+	policy := MessageRetentionPolicy{DefaultDays: 7, TierDays: map[GroupTier]int{GroupTierMerchant: 30}}
+
+	cases := []struct {
+		name  string
+		group *Group
+		want  int
+	}{
+		{name: "no group", group: nil, want: 7},
+		{name: "basic tier", group: &Group{Tier: GroupTierBasic}, want: 7},
+		{name: "merchant tier", group: &Group{Tier: GroupTierMerchant}, want: 30},
+		{name: "group override", group: &Group{Tier: GroupTierMerchant, Settings: GroupSettings{MessageRetentionDays: 90}}, want: 90},
+	}
+	for _, tc := range cases {
+		if got := policy.DaysFor(tc.group); got != tc.want {
+			t.Fatalf("%s: expected %d days, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestMessageExpireAt(t *testing.T) {
+	sentAt := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	if got := MessageExpireAt(sentAt, 0); got != nil {
+		t.Fatalf("expected nil expiry when retention disabled, got %v", got)
+	}
+	got := MessageExpireAt(sentAt, 3)
+	if got == nil || !got.Equal(sentAt.Add(72*time.Hour)) {
+		t.Fatalf("unexpected expiry: %v", got)
+	}
+}
+
+func TestParseMessageRetentionDays(t *testing.T) {
+	for input, want := range map[string]int{"30": 30, " 90天 ": 90, "0": 0} {
+		got, err := ParseMessageRetentionDays(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseMessageRetentionDays(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"-1", "abc", "366"} {
+		if _, err := ParseMessageRetentionDays(input); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
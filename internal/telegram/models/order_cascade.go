@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OrderCascadeState 订单联动转发状态（商户群订单转发到上游群后等待反馈）
+type OrderCascadeState struct {
+	Token              string    `bson:"token"`                          // 回调按钮 token（唯一）
+	MerchantChatID     int64     `bson:"merchant_chat_id"`               // 商户群 Chat ID
+	MerchantMessageID  int       `bson:"merchant_message_id"`            // 商户群原始消息 ID
+	UpstreamChatID     int64     `bson:"upstream_chat_id"`               // 上游群 Chat ID
+	UpstreamMessageID  int       `bson:"upstream_message_id"`            // 上游群转单消息 ID
+	OrderNo            string    `bson:"order_no"`                       // 订单号（大写）
+	HasMedia           bool      `bson:"has_media"`                      // 转单消息是否为图片/视频
+	MerchantOrderFull  string    `bson:"merchant_order_full,omitempty"`  // 完整商户订单号
+	InterfaceID        string    `bson:"interface_id"`                   // 接口 ID
+	InterfaceName      string    `bson:"interface_name,omitempty"`       // 接口名称
+	ChannelName        string    `bson:"channel_name,omitempty"`         // 通道名称
+	ChannelCode        string    `bson:"channel_code,omitempty"`         // 通道编码
+	SourceGroupTitle   string    `bson:"source_group_title,omitempty"`   // 商户群名称
+	UpstreamGroupTitle string    `bson:"upstream_group_title,omitempty"` // 上游群名称
+	BaseMessageText    string    `bson:"base_message_text"`              // 转单消息原始文本
+	CreatedAt          time.Time `bson:"created_at"`                     // 创建时间
+	ExpiresAt          time.Time `bson:"expires_at"`                     // 过期时间（TTL 索引）
+}
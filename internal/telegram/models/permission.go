@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 权限位：中间件与业务逻辑按权限而非角色判断，角色只是预设的权限组合
+const (
+	PermAdmin        = "admin"         // 通用管理命令（原 Admin+ 命令：/configs、记账、搜索消息等）
+	PermManageGroups = "manage_groups" // 群组数据维护：/validate、/repair、/note、/tag
+	PermManageAdmins = "manage_admins" // 管理员授权：/grant、/revoke、/perm
+	PermBroadcast    = "broadcast"     // 群发广播：/broadcast
+	PermFinance      = "finance"       // 财务数据：/balances、/resend_bills
+	PermSystem       = "system"        // 系统运维：/health、/purge_users、私聊 /deleted
+)
+
+// AdminRoles 具备管理权限的角色（不参与不活跃用户清理）
+var AdminRoles = []string{RoleOwner, RoleSuperAdmin, RoleAdmin}
+
+// PermissionInfo 权限说明（展示用）
+type PermissionInfo struct {
+	Name  string
+	Label string
+}
+
+// AllPermissions 全部权限位，顺序即展示顺序
+var AllPermissions = []PermissionInfo{
+	{Name: PermAdmin, Label: "通用管理"},
+	{Name: PermManageGroups, Label: "群组维护"},
+	{Name: PermManageAdmins, Label: "管理员授权"},
+	{Name: PermBroadcast, Label: "群发广播"},
+	{Name: PermFinance, Label: "财务数据"},
+	{Name: PermSystem, Label: "系统运维"},
+}
+
+// rolePermissions 角色预设的权限组合，Owner 拥有全部权限不在此列出
+var rolePermissions = map[string][]string{
+	RoleSuperAdmin: {PermAdmin, PermManageGroups, PermBroadcast, PermFinance, PermSystem},
+	RoleAdmin:      {PermAdmin},
+}
+
+// RolePermissions 返回角色预设的权限组合
+func RolePermissions(role string) []string {
+	if role == RoleOwner {
+		names := make([]string, 0, len(AllPermissions))
+		for _, info := range AllPermissions {
+			names = append(names, info.Name)
+		}
+		return names
+	}
+	return append([]string(nil), rolePermissions[role]...)
+}
+
+// HasPermission 是否拥有指定权限：Owner 拥有全部权限，其余为角色预设与额外授予权限的并集
+// 额外权限仅对管理员生效，撤销管理员后不再保留
+func (u *User) HasPermission(perm string) bool {
+	if u == nil {
+		return false
+	}
+	if u.IsOwner() {
+		return true
+	}
+	if !u.IsAdmin() {
+		return false
+	}
+	for _, name := range rolePermissions[u.Role] {
+		if name == perm {
+			return true
+		}
+	}
+	for _, name := range u.Permissions {
+		if name == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectivePermissions 返回用户生效的全部权限（按 AllPermissions 顺序）
+func (u *User) EffectivePermissions() []string {
+	var names []string
+	for _, info := range AllPermissions {
+		if u.HasPermission(info.Name) {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// ParsePermission 校验权限名（不区分大小写）
+func ParsePermission(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, info := range AllPermissions {
+		if info.Name == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("未知权限：%s", name)
+}
+
+// PermissionLabel 返回权限的中文说明，未知权限原样返回
+func PermissionLabel(perm string) string {
+	for _, info := range AllPermissions {
+		if info.Name == perm {
+			return info.Label
+		}
+	}
+	return perm
+}
+
+// RoleLabel 返回角色的中文名称
+func RoleLabel(role string) string {
+	switch role {
+	case RoleOwner:
+		return "Owner"
+	case RoleSuperAdmin:
+		return "超级管理员"
+	case RoleAdmin:
+		return "管理员"
+	default:
+		return "普通用户"
+	}
+}
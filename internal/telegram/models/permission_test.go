@@ -0,0 +1,45 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUserHasPermission(t *testing.T) {
+	owner := &User{Role: RoleOwner}
+	superAdmin := &User{Role: RoleSuperAdmin}
+	admin := &User{Role: RoleAdmin, Permissions: []string{PermFinance}}
+	user := &User{Role: RoleUser, Permissions: []string{PermFinance}}
+
+	for _, info := range AllPermissions {
+		if !owner.HasPermission(info.Name) {
+			t.Fatalf("owner should have %s", info.Name)
+		}
+	}
+	if !superAdmin.HasPermission(PermManageGroups) || !superAdmin.HasPermission(PermAdmin) || superAdmin.HasPermission(PermManageAdmins) {
+		t.Fatalf("unexpected super admin permissions: %v", superAdmin.EffectivePermissions())
+	}
+	if !admin.HasPermission(PermAdmin) || !admin.HasPermission(PermFinance) || admin.HasPermission(PermBroadcast) {
+		t.Fatalf("unexpected admin permissions: %v", admin.EffectivePermissions())
+	}
+	if user.HasPermission(PermFinance) || user.HasPermission(PermAdmin) {
+		t.Fatal("extra permissions must not apply to regular users")
+	}
+	var nilUser *User
+	if nilUser.HasPermission(PermAdmin) {
+		t.Fatal("nil user must not have permissions")
+	}
+
+	if got, want := admin.EffectivePermissions(), []string{PermAdmin, PermFinance}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("EffectivePermissions() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePermission(t *testing.T) {
+	if perm, err := ParsePermission(" Finance "); err != nil || perm != PermFinance {
+		t.Fatalf("expected finance, got %q err=%v", perm, err)
+	}
+	if _, err := ParsePermission("root"); err == nil {
+		t.Fatal("expected unknown permission to be rejected")
+	}
+}
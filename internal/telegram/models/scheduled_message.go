@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 定时消息触发方式
+const (
+	ScheduleKindDaily = "daily" // 每日固定时刻（HH:MM）
+	ScheduleKindCron  = "cron"  // 5 段 cron 表达式
+)
+
+// ScheduledMessage 群组定时消息
+type ScheduledMessage struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID    int64              `bson:"chat_id"`               // 目标群组 ID
+	Text      string             `bson:"text"`                  // 消息内容（纯文本）
+	Kind      string             `bson:"kind"`                  // daily / cron
+	Spec      string             `bson:"spec"`                  // daily 为 HH:MM，cron 为原始表达式
+	Timezone  string             `bson:"timezone"`              // 注册时群组的时区
+	CreatedBy int64              `bson:"created_by"`            // 注册人 Telegram ID
+	NextRunAt time.Time          `bson:"next_run_at"`           // 下一次触发时间
+	LastRunAt *time.Time         `bson:"last_run_at,omitempty"` // 上一次成功发送时间
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// ScheduledMessageRun 定时消息单次触发的认领记录，(message_id, run_at) 唯一，多实例部署时只有认领成功的实例发送
+type ScheduledMessageRun struct {
+	MessageID primitive.ObjectID `bson:"message_id"`
+	ChatID    int64              `bson:"chat_id"`
+	RunAt     time.Time          `bson:"run_at"` // 本次触发时间（认领时的 next_run_at）
+	ClaimedAt time.Time          `bson:"claimed_at"`
+}
+
+// CronSchedule 解析定时消息的触发规则
+func (m *ScheduledMessage) CronSchedule() (*CronSchedule, error) {
+	switch m.Kind {
+	case ScheduleKindDaily:
+		expr, err := DailyCronExpr(m.Spec)
+		if err != nil {
+			return nil, err
+		}
+		return ParseCronSchedule(expr)
+	case ScheduleKindCron:
+		return ParseCronSchedule(m.Spec)
+	default:
+		return nil, fmt.Errorf("未知的触发方式: %s", m.Kind)
+	}
+}
+
+// Location 返回定时消息使用的时区，无效时回退到默认时区
+func (m *ScheduledMessage) Location() *time.Location {
+	return GroupLocation(GroupSettings{Timezone: m.Timezone})
+}
+
+// NextRunAfter 计算 after 之后的下一次触发时间
+func (m *ScheduledMessage) NextRunAfter(after time.Time) (time.Time, error) {
+	schedule, err := m.CronSchedule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := schedule.Next(after.In(m.Location()))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("触发规则永远不会命中: %s", m.Spec)
+	}
+	return next, nil
+}
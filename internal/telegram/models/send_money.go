@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SendMoneyDailyTotal 记录单个群组某一天的四方下发累计金额
+type SendMoneyDailyTotal struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID    int64              `bson:"chat_id"`    // Telegram 群组 ID
+	Date      string             `bson:"date"`       // 群组时区下的日期（YYYY-MM-DD）
+	Total     float64            `bson:"total"`      // 当天已下发总额
+	Count     int                `bson:"count"`      // 当天下发笔数
+	CreatedAt time.Time          `bson:"created_at"` // 创建时间
+	UpdatedAt time.Time          `bson:"updated_at"` // 更新时间
+}
+
+// SendMoneyExpiration 记录待确认下发消息的过期时间，用于重启后兜底将按钮置为失效
+type SendMoneyExpiration struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string             `bson:"token"`      // 下发确认 token（唯一）
+	ChatID    int64              `bson:"chat_id"`    // 确认消息所在群组
+	MessageID int                `bson:"message_id"` // 确认消息 ID
+	Review    bool               `bson:"review"`     // 是否为大额复核请求
+	ExpiresAt time.Time          `bson:"expires_at"` // 过期时间
+	CreatedAt time.Time          `bson:"created_at"` // 创建时间
+}
@@ -17,6 +17,28 @@ const (
 	BalanceOpAlertLimit    BalanceOperationType = "set_alert_limit"
 )
 
+// 群级低余额告警目标，覆盖全局 BALANCE_ALERT_TARGET
+const (
+	BalanceAlertTargetDefault = ""           // 跟随全局配置
+	BalanceAlertTargetGroup   = "group"      // 发在本群
+	BalanceAlertTargetChat    = "alert_chat" // 发到全局配置的告警群
+	BalanceAlertTargetOwners  = "owners"     // 私聊 owner
+)
+
+// BalanceAlertTargetLabel 返回告警目标的可读名称
+func BalanceAlertTargetLabel(target string) string {
+	switch target {
+	case BalanceAlertTargetGroup:
+		return "本群"
+	case BalanceAlertTargetChat:
+		return "告警群"
+	case BalanceAlertTargetOwners:
+		return "私聊 Owner"
+	default:
+		return "跟随全局配置"
+	}
+}
+
 // UpstreamBalance 表示单个上游群的余额与阈值
 type UpstreamBalance struct {
 	ID                primitive.ObjectID `bson:"_id,omitempty"`
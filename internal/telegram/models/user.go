@@ -8,27 +8,31 @@ import (
 
 // 角色常量
 const (
-	RoleOwner = "owner" // 最高权限，由 BOT_OWNER_IDS 配置
-	RoleAdmin = "admin" // 管理员权限
-	RoleUser  = "user"  // 普通用户
+	RoleOwner      = "owner"       // 最高权限，由 BOT_OWNER_IDS 配置
+	RoleSuperAdmin = "super_admin" // 超级管理员：可维护群组、广播、财务与运维，不能授权其他管理员
+	RoleAdmin      = "admin"       // 管理员权限
+	RoleUser       = "user"        // 普通用户
 )
 
 // User 用户模型
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	TelegramID   int64              `bson:"telegram_id"`              // Telegram 用户 ID（唯一）
-	Username     string             `bson:"username,omitempty"`       // @username
-	FirstName    string             `bson:"first_name"`               // 名字
-	LastName     string             `bson:"last_name,omitempty"`      // 姓氏
-	LanguageCode string             `bson:"language_code,omitempty"`  // 语言代码
-	IsPremium    bool               `bson:"is_premium"`               // 是否 Telegram Premium 用户
-	Role         string             `bson:"role"`                     // 角色：owner/admin/user
-	Permissions  []string           `bson:"permissions,omitempty"`    // 自定义权限列表（预留扩展）
-	GrantedBy    int64              `bson:"granted_by,omitempty"`     // 权限授予者的 TelegramID
-	GrantedAt    *time.Time         `bson:"granted_at,omitempty"`     // 权限授予时间
-	CreatedAt    time.Time          `bson:"created_at"`               // 创建时间
-	UpdatedAt    time.Time          `bson:"updated_at"`               // 更新时间
-	LastActiveAt time.Time          `bson:"last_active_at"`           // 最后活跃时间
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	TelegramID        int64              `bson:"telegram_id"`                  // Telegram 用户 ID（唯一）
+	Username          string             `bson:"username,omitempty"`           // @username
+	FirstName         string             `bson:"first_name"`                   // 名字
+	LastName          string             `bson:"last_name,omitempty"`          // 姓氏
+	LanguageCode      string             `bson:"language_code,omitempty"`      // 语言代码
+	PreferredLanguage string             `bson:"preferred_language,omitempty"` // 用户通过 /lang 设置的偏好语言（zh/en），空表示按语言代码
+	IsPremium         bool               `bson:"is_premium"`                   // 是否 Telegram Premium 用户
+	Role              string             `bson:"role"`                         // 角色：owner/admin/user
+	Permissions       []string           `bson:"permissions,omitempty"`        // 在角色预设之外额外授予的权限（仅管理员生效）
+	GrantedBy         int64              `bson:"granted_by,omitempty"`         // 权限授予者的 TelegramID
+	GrantedAt         *time.Time         `bson:"granted_at,omitempty"`         // 权限授予时间
+	CreatedAt         time.Time          `bson:"created_at"`                   // 创建时间
+	UpdatedAt         time.Time          `bson:"updated_at"`                   // 更新时间
+	LastActiveAt      time.Time          `bson:"last_active_at"`               // 最后活跃时间
+	StartSource       string             `bson:"start_source,omitempty"`       // 首次通过 /start 深链接进入时携带的来源参数（如 ref_123）
+	StartSourceAt     *time.Time         `bson:"start_source_at,omitempty"`    // 来源参数记录时间
 }
 
 // IsOwner 是否为 Owner
@@ -36,9 +40,9 @@ func (u *User) IsOwner() bool {
 	return u.Role == RoleOwner
 }
 
-// IsAdmin 是否为管理员（包括 Owner）
+// IsAdmin 是否为管理员（包括超级管理员与 Owner）
 func (u *User) IsAdmin() bool {
-	return u.Role == RoleAdmin || u.Role == RoleOwner
+	return u.Role == RoleAdmin || u.Role == RoleSuperAdmin || u.Role == RoleOwner
 }
 
 // CanManageUsers 是否可以管理用户
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultUserInactiveDays /purge_users 未指定天数时的不活跃阈值
+	DefaultUserInactiveDays = 180
+	// MinUserInactiveDays 不活跃阈值下限，避免误清理近期用户
+	MinUserInactiveDays = 30
+)
+
+// PurgedUser 被清理的不活跃用户存档（审计用，长期保留）
+type PurgedUser struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	User     User               `bson:"user"`      // 清理前的完整用户记录
+	Cutoff   time.Time          `bson:"cutoff"`    // 本次清理的不活跃截止时间
+	PurgedBy int64              `bson:"purged_by"` // 确认清理的 Owner
+	PurgedAt time.Time          `bson:"purged_at"` // 清理时间
+}
@@ -23,6 +23,7 @@ const (
 	orderCascadeLookupTimeout = 8 * time.Second
 	orderCascadeSendTimeout   = 5 * time.Second
 	orderCascadeStateTTL      = 2 * time.Hour
+	orderCascadeStoreTimeout  = 3 * time.Second
 )
 
 const (
@@ -54,26 +55,6 @@ var orderCascadeActions = map[string]struct {
 	},
 }
 
-type orderCascadeState struct {
-	Token              string
-	MerchantChatID     int64
-	MerchantMessageID  int
-	UpstreamChatID     int64
-	UpstreamMessageID  int
-	OrderNo            string
-	HasMedia           bool
-	MerchantOrderFull  string
-	InterfaceID        string
-	InterfaceName      string
-	ChannelName        string
-	ChannelCode        string
-	SourceGroupTitle   string
-	UpstreamGroupTitle string
-	BaseMessageText    string
-	CreatedAt          time.Time
-	ExpiresAt          time.Time
-}
-
 type orderCascadeMessagePayload struct {
 	MerchantOrderNoFull string
 	OrderNo             string
@@ -189,8 +170,12 @@ func (b *Bot) startOrderCascadeWorkflow(group *models.Group, msg *botModels.Mess
 				upstreamGroup.TelegramID, orderUpper, err)
 			continue
 		}
+		if stateHasMedia {
+			// 纯文本已由 sendMessageWithMarkupAndMessage 记录
+			b.recordSentMessage(sent)
+		}
 
-		state := &orderCascadeState{
+		state := &models.OrderCascadeState{
 			Token:              token,
 			MerchantChatID:     msg.Chat.ID,
 			MerchantMessageID:  msg.ID,
@@ -309,16 +294,15 @@ func generateOrderCascadeToken() string {
 	return hex.EncodeToString(buffer)
 }
 
-func (b *Bot) saveOrderCascadeState(state *orderCascadeState) {
+// saveOrderCascadeState 写入内存并同步持久化，保证重启后回调仍能找到状态
+func (b *Bot) saveOrderCascadeState(state *models.OrderCascadeState) {
 	if state == nil || state.Token == "" {
 		return
 	}
 
 	b.orderCascadeMu.Lock()
-	defer b.orderCascadeMu.Unlock()
-
 	if b.orderCascadeStates == nil {
-		b.orderCascadeStates = make(map[string]*orderCascadeState)
+		b.orderCascadeStates = make(map[string]*models.OrderCascadeState)
 	}
 
 	now := time.Now()
@@ -329,9 +313,21 @@ func (b *Bot) saveOrderCascadeState(state *orderCascadeState) {
 	}
 
 	b.orderCascadeStates[state.Token] = state
+	b.orderCascadeMu.Unlock()
+
+	if b.orderCascadeRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), orderCascadeStoreTimeout)
+	defer cancel()
+	if err := b.orderCascadeRepo.Save(ctx, state); err != nil {
+		logger.L().Errorf("Failed to persist order cascade state: token=%s order_no=%s err=%v", state.Token, state.OrderNo, err)
+	}
 }
 
-func (b *Bot) getOrderCascadeState(token string) (*orderCascadeState, bool) {
+// getOrderCascadeState 先查内存，未命中时回源数据库并回填内存
+func (b *Bot) getOrderCascadeState(token string) (*models.OrderCascadeState, bool) {
 	if strings.TrimSpace(token) == "" {
 		return nil, false
 	}
@@ -339,21 +335,70 @@ func (b *Bot) getOrderCascadeState(token string) (*orderCascadeState, bool) {
 	b.orderCascadeMu.RLock()
 	state, ok := b.orderCascadeStates[token]
 	b.orderCascadeMu.RUnlock()
+
+	if (!ok || state == nil) && b.orderCascadeRepo != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), orderCascadeStoreTimeout)
+		stored, err := b.orderCascadeRepo.Get(ctx, token)
+		cancel()
+		if err != nil {
+			logger.L().Warnf("Failed to load order cascade state: token=%s err=%v", token, err)
+		}
+		if stored != nil {
+			state, ok = stored, true
+			b.orderCascadeMu.Lock()
+			if b.orderCascadeStates == nil {
+				b.orderCascadeStates = make(map[string]*models.OrderCascadeState)
+			}
+			b.orderCascadeStates[token] = stored
+			b.orderCascadeMu.Unlock()
+		}
+	}
+
 	if !ok || state == nil {
 		return nil, false
 	}
 
 	if time.Now().After(state.ExpiresAt) {
-		b.orderCascadeMu.Lock()
-		delete(b.orderCascadeStates, token)
-		b.orderCascadeMu.Unlock()
+		b.deleteOrderCascadeState(token)
 		return nil, false
 	}
 
 	return state, true
 }
 
-func buildOrderCascadeFeedbackMessage(state *orderCascadeState, action string, actor *botModels.User, timestamp time.Time) string {
+// sweepOrderCascadeStates 移除内存中已过期的联动状态，返回移除数与剩余数
+// 数据库中的记录由 TTL 索引回收，这里只释放内存
+func (b *Bot) sweepOrderCascadeStates(now time.Time) (removed, remaining int) {
+	b.orderCascadeMu.Lock()
+	defer b.orderCascadeMu.Unlock()
+
+	for token, state := range b.orderCascadeStates {
+		if state == nil || now.After(state.ExpiresAt) {
+			delete(b.orderCascadeStates, token)
+			removed++
+		}
+	}
+	return removed, len(b.orderCascadeStates)
+}
+
+// deleteOrderCascadeState 清理已完成或已过期的联动状态（内存与数据库）
+func (b *Bot) deleteOrderCascadeState(token string) {
+	b.orderCascadeMu.Lock()
+	delete(b.orderCascadeStates, token)
+	b.orderCascadeMu.Unlock()
+
+	if b.orderCascadeRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), orderCascadeStoreTimeout)
+	defer cancel()
+	if err := b.orderCascadeRepo.Delete(ctx, token); err != nil {
+		logger.L().Warnf("Failed to delete order cascade state: token=%s err=%v", token, err)
+	}
+}
+
+func buildOrderCascadeFeedbackMessage(state *models.OrderCascadeState, action string, actor *botModels.User, timestamp time.Time) string {
 	if state == nil {
 		return ""
 	}
@@ -361,6 +406,11 @@ func buildOrderCascadeFeedbackMessage(state *orderCascadeState, action string, a
 	return orderCascadeActionLabel(action)
 }
 
+// isOrderCascadeFinalAction 判断反馈是否结束联动流程（结束后清理状态）
+func isOrderCascadeFinalAction(action string) bool {
+	return action == orderCascadeActionCompleted
+}
+
 func orderCascadeActionLabel(action string) string {
 	if info, ok := orderCascadeActions[action]; ok {
 		return info.label
@@ -382,7 +432,7 @@ func formatCascadeActor(user *botModels.User) string {
 	return fmt.Sprintf("#%d", user.ID)
 }
 
-func (b *Bot) editCascadeMessage(ctx context.Context, state *orderCascadeState, originalMsg *botModels.Message, action string, actor *botModels.User, timestamp time.Time) {
+func (b *Bot) editCascadeMessage(ctx context.Context, state *models.OrderCascadeState, originalMsg *botModels.Message, action string, actor *botModels.User, timestamp time.Time) {
 	if state == nil || state.UpstreamChatID == 0 || state.UpstreamMessageID == 0 {
 		return
 	}
@@ -398,7 +448,11 @@ func (b *Bot) editCascadeMessage(ctx context.Context, state *orderCascadeState,
 	builder.WriteString("\n\n<b>最新反馈</b>\n")
 	builder.WriteString(fmt.Sprintf("%s · %s · %s", actionLabel, actorText, timestamp.Format("2006-01-02 15:04:05")))
 
-	markup := buildOrderCascadeKeyboard(state.Token)
+	// 已补单视为流程结束，移除按钮
+	var markup botModels.ReplyMarkup
+	if !isOrderCascadeFinalAction(action) {
+		markup = buildOrderCascadeKeyboard(state.Token)
+	}
 
 	useCaption := state.HasMedia
 	if originalMsg != nil {
@@ -416,7 +470,7 @@ func (b *Bot) editCascadeMessage(ctx context.Context, state *orderCascadeState,
 			ReplyMarkup: markup,
 		})
 		if err != nil {
-			logger.L().Errorf("Failed to edit cascade caption: chat_id=%d message_id=%d err=%v",
+			logger.Ctx(ctx).Errorf("Failed to edit cascade caption: chat_id=%d message_id=%d err=%v",
 				state.UpstreamChatID, state.UpstreamMessageID, err)
 		}
 		return
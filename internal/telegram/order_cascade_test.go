@@ -1,10 +1,13 @@
 package telegram
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
 
+	"go_bot/internal/telegram/models"
+
 	botModels "github.com/go-telegram/bot/models"
 )
 
@@ -28,7 +31,7 @@ func TestBuildOrderCascadeMessageIncludesFields(t *testing.T) {
 }
 
 func TestBuildOrderCascadeFeedbackMessage(t *testing.T) {
-	state := &orderCascadeState{
+	state := &models.OrderCascadeState{
 		SourceGroupTitle:   "商户群",
 		UpstreamGroupTitle: "上游群",
 		InterfaceID:        "123",
@@ -45,3 +48,63 @@ func TestBuildOrderCascadeFeedbackMessage(t *testing.T) {
 		t.Fatalf("unexpected feedback text: %s", text)
 	}
 }
+
+func TestOrderCascadeStateFallsBackToRepository(t *testing.T) {
+	repo := &memoryOrderCascadeRepository{states: make(map[string]*models.OrderCascadeState)}
+	now := time.Now()
+	repo.states["persisted"] = &models.OrderCascadeState{Token: "persisted", OrderNo: "ORD-1", ExpiresAt: now.Add(time.Hour)}
+	repo.states["stale"] = &models.OrderCascadeState{Token: "stale", OrderNo: "ORD-2", ExpiresAt: now.Add(-time.Minute)}
+
+	// 模拟重启：内存为空，仅数据库中有状态
+	b := &Bot{orderCascadeRepo: repo}
+
+	state, ok := b.getOrderCascadeState("persisted")
+	if !ok || state.OrderNo != "ORD-1" {
+		t.Fatalf("expected state loaded from repository, got ok=%v state=%+v", ok, state)
+	}
+	if _, cached := b.orderCascadeStates["persisted"]; !cached {
+		t.Fatalf("expected loaded state cached in memory")
+	}
+
+	if _, ok := b.getOrderCascadeState("stale"); ok {
+		t.Fatalf("expected expired state to be rejected")
+	}
+	if _, exists := repo.states["stale"]; exists {
+		t.Fatalf("expected expired state removed from repository")
+	}
+
+	b.saveOrderCascadeState(&models.OrderCascadeState{Token: "fresh", ExpiresAt: now.Add(time.Hour)})
+	if _, exists := repo.states["fresh"]; !exists {
+		t.Fatalf("expected new state written to repository")
+	}
+
+	b.deleteOrderCascadeState("fresh")
+	if _, exists := repo.states["fresh"]; exists {
+		t.Fatalf("expected completed state removed from repository")
+	}
+	if _, ok := b.getOrderCascadeState("fresh"); ok {
+		t.Fatalf("expected completed state removed from memory")
+	}
+}
+
+type memoryOrderCascadeRepository struct {
+	states map[string]*models.OrderCascadeState
+}
+
+func (r *memoryOrderCascadeRepository) Save(ctx context.Context, state *models.OrderCascadeState) error {
+	r.states[state.Token] = state
+	return nil
+}
+
+func (r *memoryOrderCascadeRepository) Get(ctx context.Context, token string) (*models.OrderCascadeState, error) {
+	return r.states[token], nil
+}
+
+func (r *memoryOrderCascadeRepository) Delete(ctx context.Context, token string) error {
+	delete(r.states, token)
+	return nil
+}
+
+func (r *memoryOrderCascadeRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+
+	"go_bot/internal/logger"
+)
+
+const (
+	panicReplyText        = "系统繁忙，请稍后再试"
+	panicReplyTimeout     = 10 * time.Second
+	panicAlertInterval    = 10 * time.Minute // 同一 handler 的告警间隔
+	panicAlertErrorLength = 300
+	panicSummaryLength    = 64
+)
+
+// updateSummary 用于定位 panic 的 update 摘要
+type updateSummary struct {
+	UpdateID  int64
+	Kind      string // message / callback_query / ...
+	ChatID    int64
+	UserID    int64
+	MessageID int
+	Content   string // 命令文本或回调数据（截断）
+}
+
+// panicAlertThrottle 按 handler 节流 owner 告警，被合并的次数在下一次告警中带上
+type panicAlertThrottle struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+}
+
+// newPanicAlertThrottle 创建告警节流器
+func newPanicAlertThrottle(interval time.Duration) *panicAlertThrottle {
+	return &panicAlertThrottle{
+		interval:   interval,
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow 判断该 handler 本次是否发送告警，放行时返回此前被合并的次数
+func (t *panicAlertThrottle) allow(handler string, now time.Time) (bool, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.lastSent[handler]; ok && now.Sub(last) < t.interval {
+		t.suppressed[handler]++
+		return false, 0
+	}
+
+	suppressed := t.suppressed[handler]
+	t.lastSent[handler] = now
+	delete(t.suppressed, handler)
+	return true, suppressed
+}
+
+// handleWorkerPanic worker pool 的 panic 回调：记录堆栈、回复用户，并按配置告警 owner
+func (b *Bot) handleWorkerPanic(task HandlerTask, report HandlerPanic) {
+	summary := summarizeUpdate(task.Update)
+	logger.Ctx(task.Ctx).Errorf("Handler panic recovered: handler=%s worker=%d update_id=%d type=%s chat_id=%d user_id=%d content=%q panic=%v\n%s",
+		report.Handler, report.Worker, summary.UpdateID, summary.Kind, summary.ChatID, summary.UserID,
+		summary.Content, report.Recovered, report.Stack)
+
+	// handler 的 ctx 可能已随关闭流程取消，回复与告警使用独立超时
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(task.Ctx), panicReplyTimeout)
+	defer cancel()
+
+	b.replyPanic(ctx, task.Update, summary)
+
+	if b.panicAlerts == nil {
+		return
+	}
+	allowed, suppressed := b.panicAlerts.allow(report.Handler, time.Now())
+	if !allowed {
+		return
+	}
+	text := formatPanicAlert(report, summary, suppressed)
+	for _, ownerID := range b.ownerIDs {
+		b.sendMessage(ctx, ownerID, text)
+	}
+}
+
+// replyPanic 告知触发 panic 的用户稍后重试，仅处理消息和回调
+func (b *Bot) replyPanic(ctx context.Context, update *botModels.Update, summary updateSummary) {
+	if update == nil {
+		return
+	}
+
+	switch {
+	case update.Message != nil:
+		b.sendErrorMessage(ctx, summary.ChatID, panicReplyText, summary.MessageID)
+	case update.CallbackQuery != nil:
+		if _, err := b.bot.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            panicReplyText,
+			ShowAlert:       true,
+		}); err != nil {
+			logger.Ctx(ctx).Warnf("Failed to answer callback after panic: %v", err)
+		}
+	}
+}
+
+// summarizeUpdate 提取 update 的定位信息
+func summarizeUpdate(update *botModels.Update) updateSummary {
+	if update == nil {
+		return updateSummary{Kind: "unknown"}
+	}
+
+	summary := updateSummary{UpdateID: update.ID}
+	fillMessage := func(kind string, msg *botModels.Message) {
+		summary.Kind = kind
+		summary.ChatID = msg.Chat.ID
+		summary.MessageID = msg.ID
+		if msg.From != nil {
+			summary.UserID = msg.From.ID
+		}
+		summary.Content = truncateForDisplay(strings.ReplaceAll(msg.Text, "\n", " "), panicSummaryLength)
+	}
+
+	switch {
+	case update.Message != nil:
+		fillMessage("message", update.Message)
+	case update.EditedMessage != nil:
+		fillMessage("edited_message", update.EditedMessage)
+	case update.ChannelPost != nil:
+		fillMessage("channel_post", update.ChannelPost)
+	case update.EditedChannelPost != nil:
+		fillMessage("edited_channel_post", update.EditedChannelPost)
+	case update.BusinessMessage != nil:
+		fillMessage("business_message", update.BusinessMessage)
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		summary.Kind = "callback_query"
+		summary.UserID = query.From.ID
+		summary.Content = truncateForDisplay(query.Data, panicSummaryLength)
+		if query.Message.Message != nil {
+			summary.ChatID = query.Message.Message.Chat.ID
+			summary.MessageID = query.Message.Message.ID
+		}
+	case update.MyChatMember != nil:
+		summary.Kind = "my_chat_member"
+		summary.ChatID = update.MyChatMember.Chat.ID
+		summary.UserID = update.MyChatMember.From.ID
+	case update.DeletedBusinessMessages != nil:
+		summary.Kind = "deleted_business_messages"
+		summary.ChatID = update.DeletedBusinessMessages.Chat.ID
+	default:
+		summary.Kind = "other"
+	}
+	return summary
+}
+
+// formatPanicAlert 构造发送给 owner 的 panic 告警
+func formatPanicAlert(report HandlerPanic, summary updateSummary, suppressed int) string {
+	var sb strings.Builder
+	sb.WriteString("🚨 <b>Handler panic</b>\n\n")
+	sb.WriteString(fmt.Sprintf("处理器：<code>%s</code>\n", html.EscapeString(report.Handler)))
+	sb.WriteString(fmt.Sprintf("Update：<code>%d</code>（%s）\n", summary.UpdateID, summary.Kind))
+	if summary.ChatID != 0 {
+		sb.WriteString(fmt.Sprintf("会话：<code>%d</code>\n", summary.ChatID))
+	}
+	if summary.UserID != 0 {
+		sb.WriteString(fmt.Sprintf("用户：<code>%d</code>\n", summary.UserID))
+	}
+	if summary.Content != "" {
+		sb.WriteString(fmt.Sprintf("内容：%s\n", html.EscapeString(summary.Content)))
+	}
+	sb.WriteString(fmt.Sprintf("错误：<code>%s</code>\n", html.EscapeString(truncateForDisplay(fmt.Sprint(report.Recovered), panicAlertErrorLength))))
+	if suppressed > 0 {
+		sb.WriteString(fmt.Sprintf("\n此前 %s 内另有 %d 次同类 panic 未单独告警\n", panicAlertInterval, suppressed))
+	}
+	sb.WriteString("\n完整堆栈见服务日志")
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestWorkerPoolRecoversHandlerPanic(t *testing.T) {
+	pool := NewWorkerPool(1, 4)
+	defer pool.Shutdown(0)
+
+	reports := make(chan HandlerPanic, 1)
+	pool.SetPanicHandler(func(task HandlerTask, report HandlerPanic) {
+		reports <- report
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	update := &botModels.Update{ID: 42}
+	pool.Submit(HandlerTask{Ctx: context.Background(), Update: update, Handler: wrapForPanicTest(handlePanicForTest)})
+	pool.Submit(HandlerTask{Ctx: context.Background(), Update: update, Handler: func(ctx context.Context, b *bot.Bot, u *botModels.Update) {
+		wg.Done()
+	}})
+
+	select {
+	case report := <-reports:
+		if report.Handler != "handlePanicForTest" {
+			t.Fatalf("expected handler name from stack, got %q", report.Handler)
+		}
+		if report.Recovered != "boom" || !strings.Contains(string(report.Stack), "handlePanicForTest") {
+			t.Fatalf("unexpected report: %v\n%s", report.Recovered, report.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected panic to be reported")
+	}
+	wg.Wait()
+}
+
+func TestPanicAlertThrottle(t *testing.T) {
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	throttle := newPanicAlertThrottle(10 * time.Minute)
+
+	if ok, _ := throttle.allow("handleStart", now); !ok {
+		t.Fatalf("expected first alert to pass")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := throttle.allow("handleStart", now.Add(time.Minute)); ok {
+			t.Fatalf("expected repeated alert to be throttled")
+		}
+	}
+	if ok, _ := throttle.allow("handlePing", now.Add(time.Minute)); !ok {
+		t.Fatalf("expected other handlers to be unaffected")
+	}
+	ok, suppressed := throttle.allow("handleStart", now.Add(10*time.Minute))
+	if !ok || suppressed != 3 {
+		t.Fatalf("expected alert after interval with 3 suppressed, got ok=%v suppressed=%d", ok, suppressed)
+	}
+}
+
+func TestSummarizeUpdateAndFormatPanicAlert(t *testing.T) {
+	update := &botModels.Update{
+		ID: 7,
+		CallbackQuery: &botModels.CallbackQuery{
+			From: botModels.User{ID: 1001},
+			Data: "config:toggle:<x>",
+			Message: botModels.MaybeInaccessibleMessage{
+				Message: &botModels.Message{ID: 55, Chat: botModels.Chat{ID: -100}},
+			},
+		},
+	}
+	summary := summarizeUpdate(update)
+	if summary.Kind != "callback_query" || summary.ChatID != -100 || summary.UserID != 1001 || summary.MessageID != 55 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	text := formatPanicAlert(HandlerPanic{Handler: "handleConfigCallback", Recovered: "nil map"}, summary, 2)
+	for _, want := range []string{"handleConfigCallback", "<code>7</code>（callback_query）", "<code>-100</code>", "config:toggle:&lt;x&gt;", "另有 2 次"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in alert:\n%s", want, text)
+		}
+	}
+
+	if summarizeUpdate(nil).Kind != "unknown" {
+		t.Fatalf("expected nil update to be summarized as unknown")
+	}
+}
+
+// wrapForPanicTest 模拟中间件包装出的闭包
+func wrapForPanicTest(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *botModels.Update) {
+		next(ctx, b, update)
+	}
+}
+
+func handlePanicForTest(ctx context.Context, b *bot.Bot, update *botModels.Update) {
+	panic("boom")
+}
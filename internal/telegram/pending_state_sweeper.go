@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_bot/internal/logger"
+)
+
+const (
+	defaultPendingStateSweepInterval = time.Minute
+	// pendingStateSweepGrace 下发待确认过期后保留的宽限期，留给确认超时定时器编辑过期提示
+	pendingStateSweepGrace = time.Minute
+)
+
+// 内存状态类别，作为 /metrics 的 kind 标签
+const (
+	pendingStateOrderCascade = "order_cascade"
+	pendingStateSendMoney    = "sifang_send_money"
+	pendingStateSentMessages = "sent_messages"
+)
+
+// pendingStateSweeper 周期清理内存中已过期的待处理状态（订单联动、四方待确认下发、Bot 发出消息记录），
+// 回调时的过期判断只覆盖被点击的状态，无人点击的记录需要由这里回收
+type pendingStateSweeper struct {
+	sweep    func(now time.Time)
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	running  atomic.Bool
+}
+
+func newPendingStateSweeper(interval time.Duration, sweep func(now time.Time)) *pendingStateSweeper {
+	if interval <= 0 {
+		interval = defaultPendingStateSweepInterval
+	}
+	return &pendingStateSweeper{
+		sweep:    sweep,
+		interval: interval,
+	}
+}
+
+func (s *pendingStateSweeper) start() {
+	if s == nil || s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+
+	s.running.Store(true)
+	logger.L().Infof("Pending state sweeper started: interval=%s", s.interval)
+}
+
+// isRunning 清理任务是否处于运行状态
+func (s *pendingStateSweeper) isRunning() bool {
+	return s != nil && s.running.Load()
+}
+
+func (s *pendingStateSweeper) stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.cancel = nil
+	s.running.Store(false)
+	logger.L().Info("Pending state sweeper stopped")
+}
+
+func (s *pendingStateSweeper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sweep(now)
+		}
+	}
+}
+
+// sweepPendingStates 清理一轮过期状态，记录剩余数量到指标；有回收时输出 Info 日志，便于观察是否持续增长
+func (b *Bot) sweepPendingStates(now time.Time) {
+	cascadeRemoved, cascadeRemaining := b.sweepOrderCascadeStates(now)
+	b.metrics.recordPendingSweep(pendingStateOrderCascade, cascadeRemoved, cascadeRemaining)
+
+	sendMoneyRemoved, sendMoneyRemaining := 0, 0
+	if b.sifangFeature != nil {
+		sendMoneyRemoved, sendMoneyRemaining = b.sifangFeature.SweepExpiredPending(now, pendingStateSweepGrace)
+		b.metrics.recordPendingSweep(pendingStateSendMoney, sendMoneyRemoved, sendMoneyRemaining)
+	}
+
+	sentRemoved, sentRemaining := b.sentMessages.sweep(now)
+	b.metrics.recordPendingSweep(pendingStateSentMessages, sentRemoved, sentRemaining)
+
+	format := "Pending states swept: order_cascade=%d (removed %d) sifang_send_money=%d (removed %d) sent_messages=%d (removed %d)"
+	args := []any{cascadeRemaining, cascadeRemoved, sendMoneyRemaining, sendMoneyRemoved, sentRemaining, sentRemoved}
+	if cascadeRemoved+sendMoneyRemoved+sentRemoved > 0 {
+		logger.L().Infof(format, args...)
+		return
+	}
+	logger.L().Debugf(format, args...)
+}
+
+func (b *Bot) initPendingStateSweeper(interval time.Duration) {
+	sweeper := newPendingStateSweeper(interval, b.sweepPendingStates)
+	b.pendingStateSweeper = sweeper
+	sweeper.start()
+}
@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestSweepPendingStatesRemovesExpiredCascadeStates(t *testing.T) {
+	now := time.Now()
+	b := &Bot{
+		metrics: newBotMetrics(),
+		orderCascadeStates: map[string]*models.OrderCascadeState{
+			"expired": {Token: "expired", ExpiresAt: now.Add(-time.Second)},
+			"active":  {Token: "active", ExpiresAt: now.Add(time.Hour)},
+			"nil":     nil,
+		},
+	}
+
+	b.sweepPendingStates(now)
+
+	if len(b.orderCascadeStates) != 1 || b.orderCascadeStates["active"] == nil {
+		t.Fatalf("expected only the active state to remain, got %v", b.orderCascadeStates)
+	}
+
+	var sb strings.Builder
+	b.metrics.writeTo(&sb, WorkerPoolStats{})
+	out := sb.String()
+	for _, want := range []string{
+		`go_bot_pending_states{kind="order_cascade"} 1`,
+		`go_bot_pending_states_swept_total{kind="order_cascade"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected exposition to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestPendingStateSweeperDefaultsInterval(t *testing.T) {
+	if sweeper := newPendingStateSweeper(0, func(time.Time) {}); sweeper.interval != defaultPendingStateSweepInterval {
+		t.Fatalf("expected default interval, got %s", sweeper.interval)
+	}
+
+	calls := make(chan time.Time, 1)
+	sweeper := newPendingStateSweeper(10*time.Millisecond, func(now time.Time) {
+		select {
+		case calls <- now:
+		default:
+		}
+	})
+	sweeper.start()
+	defer sweeper.stop()
+
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatalf("expected sweeper to run within one second")
+	}
+	if !sweeper.isRunning() {
+		t.Fatalf("expected sweeper to report running")
+	}
+}
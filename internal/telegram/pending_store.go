@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pendingStore 按 token 保存等待按钮确认的内存状态（广播、离群、清理用户、按日期删除记账等），并发安全，零值可用。
+// 过期的状态不再由 get 返回，但在 pendingStateSweepGrace 内仍可被 take 认领，留给确认超时定时器编辑过期提示；
+// 超过宽限期的状态在下一次 put 时回收
+type pendingStore[T any] struct {
+	mu      sync.Mutex
+	entries map[string]pendingEntry[T]
+}
+
+type pendingEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// put 生成 token 保存状态，ttl 后过期
+func (s *pendingStore[T]) put(value T, ttl time.Duration) string {
+	token := newPendingToken()
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]pendingEntry[T])
+	}
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt.Add(pendingStateSweepGrace)) {
+			delete(s.entries, key)
+		}
+	}
+	s.entries[token] = pendingEntry[T]{value: value, expiresAt: now.Add(ttl)}
+	return token
+}
+
+// get 返回未过期的状态
+func (s *pendingStore[T]) get(token string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[token]
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// take 移除状态，返回是否由本次调用移除（避免重复点击、确认与超时并发处理）
+func (s *pendingStore[T]) take(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[token]; !ok {
+		return false
+	}
+	delete(s.entries, token)
+	return true
+}
+
+// newPendingToken 生成回调数据中使用的随机 token（12 位十六进制）
+func newPendingToken() string {
+	buffer := make([]byte, 6)
+	if _, err := rand.Read(buffer); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buffer)
+}
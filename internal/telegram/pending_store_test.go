@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPendingStoreTakenOnce(t *testing.T) {
+	var store pendingStore[*pendingLeave]
+	token := store.put(&pendingLeave{chatID: -1, adminID: 7}, leavePendingTTL)
+
+	pending, ok := store.get(token)
+	if !ok || pending.adminID != 7 {
+		t.Fatalf("expected live request, got %+v ok=%v", pending, ok)
+	}
+	if !store.take(token) || store.take(token) {
+		t.Fatalf("expected request to be taken exactly once")
+	}
+}
+
+func TestPendingStoreExpiry(t *testing.T) {
+	var store pendingStore[*pendingLeave]
+	expired := store.put(&pendingLeave{}, -time.Second)
+	stale := store.put(&pendingLeave{}, -pendingStateSweepGrace-time.Second)
+
+	if _, ok := store.get(expired); ok {
+		t.Fatalf("expected expired request to be hidden from get")
+	}
+	// 宽限期内仍可认领，留给超时定时器编辑过期提示
+	if !store.take(expired) {
+		t.Fatalf("expected expired request to be claimable within grace period")
+	}
+
+	store.put(&pendingLeave{}, leavePendingTTL)
+	if store.take(stale) {
+		t.Fatalf("expected request past grace period to be swept on put")
+	}
+}
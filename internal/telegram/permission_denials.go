@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// permissionDenialWindow 越权尝试的统计窗口
+	permissionDenialWindow = time.Hour
+	// permissionDenialAlertThreshold 窗口内同一用户被拒绝达到该次数时记录告警日志
+	permissionDenialAlertThreshold = 5
+)
+
+// permissionDenialTracker 按用户统计权限拒绝次数，用于发现反复越权尝试，并发安全，仅保存在内存
+type permissionDenialTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	threshold int
+	entries   map[int64][]time.Time // 用户在窗口内被拒绝的时间（升序）
+	total     int64                 // 启动以来的拒绝总数
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// permissionDenialStats 窗口内的越权尝试概况
+type permissionDenialStats struct {
+	Total      int64 // 启动以来的拒绝总数
+	Recent     int   // 窗口内的拒绝次数
+	Users      int   // 窗口内被拒绝的用户数
+	Suspicious int   // 窗口内达到告警阈值的用户数
+}
+
+func newPermissionDenialTracker(window time.Duration, threshold int) *permissionDenialTracker {
+	return &permissionDenialTracker{
+		window:    window,
+		threshold: threshold,
+		entries:   make(map[int64][]time.Time),
+		now:       time.Now,
+	}
+}
+
+// record 记录一次拒绝，返回窗口内该用户的拒绝次数，以及本次是否恰好达到告警阈值（每轮只告警一次）
+func (t *permissionDenialTracker) record(userID int64) (int, bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweepLocked(now)
+	t.total++
+	hits := append(t.pruneLocked(t.entries[userID], now), now)
+	t.entries[userID] = hits
+	return len(hits), t.threshold > 0 && len(hits) == t.threshold
+}
+
+// stats 返回当前窗口的统计，并顺带清理过期记录
+func (t *permissionDenialTracker) stats() permissionDenialStats {
+	if t == nil {
+		return permissionDenialStats{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	result := permissionDenialStats{Total: t.total}
+	for userID, hits := range t.entries {
+		hits = t.pruneLocked(hits, now)
+		if len(hits) == 0 {
+			delete(t.entries, userID)
+			continue
+		}
+		t.entries[userID] = hits
+		result.Recent += len(hits)
+		result.Users++
+		if t.threshold > 0 && len(hits) >= t.threshold {
+			result.Suspicious++
+		}
+	}
+	return result
+}
+
+// sweepLocked 每个窗口周期清理一次已无拒绝记录的用户，防止内存无限增长
+func (t *permissionDenialTracker) sweepLocked(now time.Time) {
+	if now.Sub(t.lastSweep) < t.window {
+		return
+	}
+	t.lastSweep = now
+
+	for userID, hits := range t.entries {
+		if hits = t.pruneLocked(hits, now); len(hits) == 0 {
+			delete(t.entries, userID)
+		} else {
+			t.entries[userID] = hits
+		}
+	}
+}
+
+// pruneLocked 丢弃滑出窗口的记录，调用方需持有锁
+func (t *permissionDenialTracker) pruneLocked(hits []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-t.window)
+	idx := 0
+	for idx < len(hits) && !hits[idx].After(cutoff) {
+		idx++
+	}
+	return hits[idx:]
+}
@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestPermissionDenialTrackerAlertsOncePerWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker := newPermissionDenialTracker(time.Hour, 3)
+	tracker.now = func() time.Time { return now }
+
+	var alerts int
+	for i := 0; i < 5; i++ {
+		if _, alert := tracker.record(1); alert {
+			alerts++
+		}
+		now = now.Add(time.Minute)
+	}
+	tracker.record(2)
+	if alerts != 1 {
+		t.Fatalf("expected exactly one alert, got %d", alerts)
+	}
+
+	stats := tracker.stats()
+	if stats.Total != 6 || stats.Recent != 6 || stats.Users != 2 || stats.Suspicious != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	now = now.Add(time.Hour)
+	if count, alert := tracker.record(1); count != 1 || alert {
+		t.Fatalf("expected window reset, got count=%d alert=%v", count, alert)
+	}
+	if stats := tracker.stats(); stats.Total != 7 || stats.Users != 1 || stats.Suspicious != 0 {
+		t.Fatalf("unexpected stats after window: %+v", stats)
+	}
+}
+
+func TestNewPermissionAttempt(t *testing.T) {
+	msg := &botModels.Message{
+		ID:   7,
+		Chat: botModels.Chat{ID: -100, Title: "测试群"},
+		From: &botModels.User{ID: 42, Username: "alice"},
+		Text: "/broadcast 大家好",
+	}
+	attempt := newPermissionAttempt(&botModels.Update{Message: msg})
+	if attempt == nil || attempt.userID != 42 || attempt.chatID != -100 || attempt.chatTitle != "测试群" || attempt.command != "/broadcast" {
+		t.Fatalf("unexpected message attempt: %+v", attempt)
+	}
+
+	query := &botModels.CallbackQuery{
+		ID:      "q1",
+		From:    botModels.User{ID: 43, Username: "bob"},
+		Data:    "broadcast:confirm:abc",
+		Message: botModels.MaybeInaccessibleMessage{Message: msg},
+	}
+	attempt = newPermissionAttempt(&botModels.Update{CallbackQuery: query})
+	if attempt == nil || attempt.userID != 43 || attempt.chatID != -100 || !strings.HasPrefix(attempt.command, "callback:broadcast:") {
+		t.Fatalf("unexpected callback attempt: %+v", attempt)
+	}
+
+	for _, update := range []*botModels.Update{
+		nil,
+		{},
+		{Message: &botModels.Message{Chat: botModels.Chat{ID: -100}}},
+		{ChannelPost: &botModels.Message{Chat: botModels.Chat{ID: -100}}},
+	} {
+		if attempt := newPermissionAttempt(update); attempt != nil {
+			t.Fatalf("expected nil attempt for %+v, got %+v", update, attempt)
+		}
+	}
+}
+
+// permissionUserService 按 ID 判定权限，owners 拥有全部权限，groupAdmins 只是群级管理员
+type permissionUserService struct {
+	stubLangUserService
+	admins      map[int64]bool
+	owners      map[int64]bool
+	groupAdmins map[int64]bool
+}
+
+func (s *permissionUserService) CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	return s.admins[telegramID] || s.groupAdmins[telegramID], nil
+}
+
+func (s *permissionUserService) HasPermission(ctx context.Context, telegramID int64, perm string) (bool, error) {
+	return s.owners[telegramID] || (perm == models.PermAdmin && s.admins[telegramID]), nil
+}
+
+func (s *permissionUserService) GetUserInfo(ctx context.Context, telegramID int64) (*models.User, error) {
+	return nil, nil
+}
+
+func (s *permissionUserService) GetPreferredLanguage(ctx context.Context, telegramID int64) (string, error) {
+	return "", nil
+}
+
+func TestRequireAdminIgnoresUpdatesWithoutSender(t *testing.T) {
+	b := &Bot{
+		userService:       &permissionUserService{admins: map[int64]bool{1: true}},
+		permissionDenials: newPermissionDenialTracker(time.Hour, 5),
+	}
+	called := 0
+	handler := b.RequireAdmin(func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		called++
+	})
+
+	handler(context.Background(), nil, &botModels.Update{ChannelPost: &botModels.Message{Chat: botModels.Chat{ID: -100}}})
+	handler(context.Background(), nil, &botModels.Update{Message: &botModels.Message{Chat: botModels.Chat{ID: -100}}})
+	if called != 0 || b.permissionDenials.stats().Total != 0 {
+		t.Fatalf("updates without sender must be ignored silently, called=%d", called)
+	}
+
+	handler(context.Background(), nil, &botModels.Update{Message: &botModels.Message{
+		Chat: botModels.Chat{ID: -100},
+		From: &botModels.User{ID: 1},
+		Text: "/search 关键词",
+	}})
+	if called != 1 {
+		t.Fatalf("expected admin to pass, called=%d", called)
+	}
+
+	// 无法回复的回调拒绝仍需计数
+	handler(context.Background(), nil, &botModels.Update{CallbackQuery: &botModels.CallbackQuery{
+		ID:   "q1",
+		From: botModels.User{ID: 2},
+		Data: "msgsearch:2",
+	}})
+	if called != 1 || b.permissionDenials.stats().Total != 1 {
+		t.Fatalf("expected callback from non-admin to be denied and counted, called=%d", called)
+	}
+}
+
+func TestRequireGlobalAdminRejectsGroupAdmins(t *testing.T) {
+	tgBot, sent := newRecordingTelegramBot(t)
+	b := &Bot{
+		bot:               tgBot,
+		userService:       &permissionUserService{admins: map[int64]bool{1: true}, groupAdmins: map[int64]bool{2: true}},
+		permissionDenials: newPermissionDenialTracker(time.Hour, 5),
+	}
+	var called []int64
+	next := func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		called = append(called, update.Message.From.ID)
+	}
+	update := func(userID int64) *botModels.Update {
+		return &botModels.Update{Message: &botModels.Message{
+			ID:   5,
+			Chat: botModels.Chat{ID: -100, Type: botModels.ChatTypeSupergroup},
+			From: &botModels.User{ID: userID},
+			Text: "/userinfo 42",
+		}}
+	}
+
+	// 群级管理员可以使用本群的管理命令，但不能使用全局命令
+	b.RequireAdmin(next)(context.Background(), tgBot, update(2))
+	global := b.RequireGlobalAdmin(next)
+	global(context.Background(), tgBot, update(2))
+	global(context.Background(), tgBot, update(1))
+
+	if len(called) != 2 || called[0] != 2 || called[1] != 1 {
+		t.Fatalf("expected group admin to pass only RequireAdmin, called=%v", called)
+	}
+	if b.permissionDenials.stats().Total != 1 || len(sent()) != 1 {
+		t.Fatalf("expected group admin to be refused once, denials=%d replies=%q", b.permissionDenials.stats().Total, sent())
+	}
+}
@@ -43,6 +43,20 @@ func (r *MongoAccountingRepository) CreateRecord(ctx context.Context, record *mo
 	return nil
 }
 
+// CreateRecordOnce 按 OperationID 幂等创建记账记录，同一 ID 已存在时返回 false 且不重复写入
+func (r *MongoAccountingRepository) CreateRecordOnce(ctx context.Context, record *models.AccountingRecord) (bool, error) {
+	if record.OperationID == "" {
+		return false, fmt.Errorf("operation id is required")
+	}
+	if err := r.CreateRecord(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // GetRecordsByDateRange 按日期范围查询记录
 func (r *MongoAccountingRepository) GetRecordsByDateRange(ctx context.Context, chatID int64, startTime, endTime time.Time, currency string) ([]*models.AccountingRecord, error) {
 	filter := bson.M{
@@ -75,6 +89,30 @@ func (r *MongoAccountingRepository) GetRecordsByDateRange(ctx context.Context, c
 	return records, nil
 }
 
+// GetLatestRecords 获取指定币种最近的 limit 条记录（按记账时间倒序）
+func (r *MongoAccountingRepository) GetLatestRecords(ctx context.Context, chatID int64, currency string, limit int64) ([]*models.AccountingRecord, error) {
+	filter := bson.M{
+		"chat_id":  chatID,
+		"currency": currency,
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "recorded_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest accounting records: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []*models.AccountingRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode accounting records: %w", err)
+	}
+
+	return records, nil
+}
+
 // GetRecentRecords 获取最近N天的记录（用于删除界面）
 func (r *MongoAccountingRepository) GetRecentRecords(ctx context.Context, chatID int64, days int) ([]*models.AccountingRecord, error) {
 	startTime := time.Now().AddDate(0, 0, -days)
@@ -123,6 +161,24 @@ func (r *MongoAccountingRepository) DeleteRecord(ctx context.Context, recordID s
 	return nil
 }
 
+// DeleteRecordsByIDs 删除群组内指定 ID 的记录，限定 chat_id 防止误删其他群的数据
+func (r *MongoAccountingRepository) DeleteRecordsByIDs(ctx context.Context, chatID int64, ids []primitive.ObjectID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	filter := bson.M{
+		"chat_id": chatID,
+		"_id":     bson.M{"$in": ids},
+	}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete accounting records: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
 // DeleteAllByChatID 清空群组所有记录
 func (r *MongoAccountingRepository) DeleteAllByChatID(ctx context.Context, chatID int64) (int64, error) {
 	filter := bson.M{"chat_id": chatID}
@@ -134,6 +190,20 @@ func (r *MongoAccountingRepository) DeleteAllByChatID(ctx context.Context, chatI
 	return result.DeletedCount, nil
 }
 
+// DeleteAllByBook 清空群组指定账本的记录，book 为空时清空默认账本（含无 book 字段的历史记录）
+func (r *MongoAccountingRepository) DeleteAllByBook(ctx context.Context, chatID int64, book string) (int64, error) {
+	filter := bson.M{"chat_id": chatID, "book": book}
+	if book == "" {
+		filter["book"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete accounting book records: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
 // EnsureIndexes 确保索引存在
 func (r *MongoAccountingRepository) EnsureIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
@@ -149,6 +219,11 @@ func (r *MongoAccountingRepository) EnsureIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "chat_id", Value: 1}},
 		},
+		// 唯一稀疏索引：operation_id（联动记账幂等，手工记账不带该字段）
+		{
+			Keys:    bson.D{{Key: "operation_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
 	}
 
 	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
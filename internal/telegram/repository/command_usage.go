@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// commandUsageRetention 命令使用统计保留时长
+const commandUsageRetention = 90 * 24 * time.Hour
+
+// MongoCommandUsageRepository 命令使用统计数据访问层（MongoDB 实现）
+type MongoCommandUsageRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoCommandUsageRepository 创建命令使用统计 Repository
+func NewMongoCommandUsageRepository(db *mongo.Database) CommandUsageRepository {
+	return &MongoCommandUsageRepository{
+		collection: db.Collection("command_usage"),
+	}
+}
+
+// IncrementBatch 按（日期, 命令）批量累加调用次数，Count 为本批增量
+func (r *MongoCommandUsageRepository) IncrementBatch(ctx context.Context, usages []*models.CommandUsage) error {
+	if len(usages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(usages))
+	for _, usage := range usages {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"date": usage.Date, "command": usage.Command}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"count": usage.Count},
+				"$set": bson.M{"updated_at": now},
+			}).
+			SetUpsert(true))
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to increment command usage: %w", err)
+	}
+	return nil
+}
+
+// SumSince 汇总指定日期（含）之后各命令的调用次数，按次数降序
+func (r *MongoCommandUsageRepository) SumSince(ctx context.Context, sinceDate string) ([]models.CommandUsageRank, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"date": bson.M{"$gte": sinceDate}}},
+		{"$group": bson.M{
+			"_id":   "$command",
+			"count": bson.M{"$sum": "$count"},
+		}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum command usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var ranks []models.CommandUsageRank
+	if err := cursor.All(ctx, &ranks); err != nil {
+		return nil, fmt.Errorf("failed to decode command usage: %w", err)
+	}
+	return ranks, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoCommandUsageRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "date", Value: 1}, {Key: "command", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "updated_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(commandUsageRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create command usage indexes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dailyBillPushRetention 推送记录保留时长，超过后由 TTL 索引清理
+const dailyBillPushRetention = 30 * 24 * time.Hour
+
+// MongoDailyBillPushRepository 每日账单推送记录数据访问层（MongoDB 实现）
+type MongoDailyBillPushRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDailyBillPushRepository 创建仓储实例
+func NewMongoDailyBillPushRepository(db *mongo.Database) DailyBillPushRepository {
+	return &MongoDailyBillPushRepository{
+		collection: db.Collection("daily_bill_pushes"),
+	}
+}
+
+// IsSent 某群某天的账单是否已推送
+func (r *MongoDailyBillPushRepository) IsSent(ctx context.Context, chatID int64, date string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"chat_id": chatID,
+		"date":    date,
+		"status":  models.DailyBillPushSent,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check daily bill push: %w", err)
+	}
+	return count > 0, nil
+}
+
+// MarkSent 标记已推送
+func (r *MongoDailyBillPushRepository) MarkSent(ctx context.Context, chatID, merchantID int64, date string) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"merchant_id": merchantID,
+			"status":      models.DailyBillPushSent,
+			"updated_at":  now,
+		},
+		"$unset":       bson.M{"last_error": ""},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	filter := bson.M{"chat_id": chatID, "date": date}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to mark daily bill push sent: %w", err)
+	}
+	return nil
+}
+
+// MarkPending 登记推送失败，已推送的记录保持不变
+func (r *MongoDailyBillPushRepository) MarkPending(ctx context.Context, chatID, merchantID int64, date string, reason string) error {
+	now := time.Now()
+	filter := bson.M{
+		"chat_id": chatID,
+		"date":    date,
+		"status":  bson.M{"$ne": models.DailyBillPushSent},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"merchant_id": merchantID,
+			"status":      models.DailyBillPushPending,
+			"last_error":  reason,
+			"updated_at":  now,
+		},
+		"$inc":         bson.M{"attempts": 1},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		// 记录已是 sent 时过滤不到，upsert 会撞唯一索引，说明其他流程已推送成功
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to mark daily bill push pending: %w", err)
+	}
+	return nil
+}
+
+// ListPending 列出 date >= sinceDate 的待补发记录，按日期升序
+func (r *MongoDailyBillPushRepository) ListPending(ctx context.Context, sinceDate string, limit int64) ([]*models.DailyBillPush, error) {
+	filter := bson.M{
+		"status": models.DailyBillPushPending,
+		"date":   bson.M{"$gte": sinceDate},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "chat_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending daily bill pushes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []*models.DailyBillPush
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode daily bill pushes: %w", err)
+	}
+	return records, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoDailyBillPushRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "chat_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "date", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "updated_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(dailyBillPushRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("create daily bill push indexes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDeletedMessageRepository 被删除消息留档数据访问层（MongoDB 实现）
+type MongoDeletedMessageRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDeletedMessageRepository 创建被删除消息 Repository
+func NewMongoDeletedMessageRepository(db *mongo.Database) DeletedMessageRepository {
+	return &MongoDeletedMessageRepository{
+		collection: db.Collection("deleted_messages"),
+	}
+}
+
+// Create 写入留档记录，同一条消息重复上报时只保留第一次
+func (r *MongoDeletedMessageRepository) Create(ctx context.Context, message *models.DeletedMessage) error {
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+
+	filter := bson.M{
+		"chat_id":             message.ChatID,
+		"telegram_message_id": message.TelegramMessageID,
+	}
+	update := bson.M{"$setOnInsert": message}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := r.collection.UpdateOne(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to create deleted message: %w", err)
+	}
+	return nil
+}
+
+// ListRecent 按删除时间倒序列出留档记录，chatID 为 0 时不限聊天
+func (r *MongoDeletedMessageRepository) ListRecent(ctx context.Context, chatID int64, limit int64) ([]*models.DeletedMessage, error) {
+	filter := bson.M{}
+	if chatID != 0 {
+		filter["chat_id"] = chatID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.DeletedMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode deleted messages: %w", err)
+	}
+	return messages, nil
+}
+
+// EnsureIndexes 创建需要的索引（留档作为证据长期保存，不设置 TTL）
+func (r *MongoDeletedMessageRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "telegram_message_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "deleted_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: -1}},
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create deleted message indexes: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go_bot/internal/telegram/models"
 
@@ -13,12 +14,14 @@ import (
 
 type forwardRecordRepository struct {
 	collection *mongo.Collection
+	processed  *mongo.Collection // 已处理的频道消息（幂等标记）
 }
 
 // NewForwardRecordRepository 创建转发记录仓储实例
 func NewForwardRecordRepository(db *mongo.Database) ForwardRecordRepository {
 	return &forwardRecordRepository{
 		collection: db.Collection("forward_records"),
+		processed:  db.Collection("forward_processed_posts"),
 	}
 }
 
@@ -82,6 +85,22 @@ func (r *forwardRecordRepository) DeleteRecordsByTaskID(ctx context.Context, tas
 	return nil
 }
 
+// MarkChannelPostProcessed 写入频道消息处理标记，依赖唯一索引保证并发下只有一次返回 true
+func (r *forwardRecordRepository) MarkChannelPostProcessed(ctx context.Context, channelID, messageID int64, processedAt time.Time) (bool, error) {
+	_, err := r.processed.InsertOne(ctx, &models.ProcessedChannelPost{
+		ChannelID:   channelID,
+		MessageID:   messageID,
+		ProcessedAt: processedAt,
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to mark channel post processed: %w", err)
+	}
+	return true, nil
+}
+
 // EnsureIndexes 确保索引存在
 func (r *forwardRecordRepository) EnsureIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
@@ -92,7 +111,7 @@ func (r *forwardRecordRepository) EnsureIndexes(ctx context.Context) error {
 		// TTL 索引（48小时自动删除）
 		{
 			Keys:    bson.D{{Key: "created_at", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(48 * 3600),
+			Options: options.Index().SetExpireAfterSeconds(int32(models.ForwardRecordTTL.Seconds())),
 		},
 		// 复合唯一索引（防止重复转发）
 		{
@@ -109,5 +128,25 @@ func (r *forwardRecordRepository) EnsureIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create indexes for forward_records: %w", err)
 	}
 
+	processedIndexes := []mongo.IndexModel{
+		// 唯一索引（同一条频道消息只标记一次）
+		{
+			Keys: bson.D{
+				{Key: "channel_id", Value: 1},
+				{Key: "message_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		// TTL 索引（与转发记录同样保留48小时）
+		{
+			Keys:    bson.D{{Key: "processed_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(models.ForwardRecordTTL.Seconds())),
+		},
+	}
+
+	if _, err := r.processed.Indexes().CreateMany(ctx, processedIndexes); err != nil {
+		return fmt.Errorf("failed to create indexes for forward_processed_posts: %w", err)
+	}
+
 	return nil
 }
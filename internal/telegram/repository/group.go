@@ -36,13 +36,12 @@ func (r *MongoGroupRepository) CreateOrUpdate(ctx context.Context, group *models
 	filter := bson.M{"telegram_id": group.TelegramID}
 
 	setFields := bson.M{
-		"type":         group.Type,
-		"title":        group.Title,
-		"username":     group.Username,
-		"description":  group.Description,
-		"member_count": group.MemberCount,
-		"bot_status":   group.BotStatus,
-		"updated_at":   group.UpdatedAt,
+		"type":        group.Type,
+		"title":       group.Title,
+		"username":    group.Username,
+		"description": group.Description,
+		"bot_status":  group.BotStatus,
+		"updated_at":  group.UpdatedAt,
 	}
 
 	if group.Tier != "" {
@@ -59,34 +58,43 @@ func (r *MongoGroupRepository) CreateOrUpdate(ctx context.Context, group *models
 		setFields["bot_left_at"] = group.BotLeftAt
 	}
 
-	update := bson.M{
-		"$set": setFields,
-		"$setOnInsert": bson.M{
-			"bot_joined_at": now,
-			"created_at":    now,
-			"tier":          models.GroupTierBasic,
-			"settings": models.GroupSettings{
-				CalculatorEnabled:        true,  // 新群组默认启用计算器功能
-				CryptoEnabled:            true,  // 新群组默认启用加密货币功能
-				CryptoFloatRate:          0.12,  // 新群组默认浮动费率 0.12
-				ForwardEnabled:           true,  // 新群组默认接收频道转发消息
-				AccountingEnabled:        false, // 新群组默认关闭收支记账功能
-				InterfaceBindings:        nil,   // 初始不绑定接口
-				SifangEnabled:            true,  // 新群组默认启用四方支付功能
-				SifangAutoLookupEnabled:  true,  // 新群组默认启用四方自动查单
-				CascadeForwardEnabled:    true,  // 新群组默认启用订单联动
-				CascadeForwardConfigured: true,
-				BalanceMonitorEnabled:    true,
-				BalanceMonitorConfigured: true,
-				BalanceMonitorInterval:   10,
-			},
-			"stats": models.GroupStats{
-				TotalMessages: 0,
-				LastMessageAt: now,
-			},
+	insertFields := bson.M{
+		"bot_joined_at": now,
+		"created_at":    now,
+		"tier":          models.GroupTierBasic,
+		"settings": models.GroupSettings{
+			CalculatorEnabled:        true,  // 新群组默认启用计算器功能
+			CryptoEnabled:            true,  // 新群组默认启用加密货币功能
+			CryptoFloatRate:          0.12,  // 新群组默认浮动费率 0.12
+			ForwardEnabled:           true,  // 新群组默认接收频道转发消息
+			AccountingEnabled:        false, // 新群组默认关闭收支记账功能
+			InterfaceBindings:        nil,   // 初始不绑定接口
+			SifangEnabled:            true,  // 新群组默认启用四方支付功能
+			SifangAutoLookupEnabled:  true,  // 新群组默认启用四方自动查单
+			CascadeForwardEnabled:    true,  // 新群组默认启用订单联动
+			CascadeForwardConfigured: true,
+			BalanceMonitorEnabled:    true,
+			BalanceMonitorConfigured: true,
+			BalanceMonitorInterval:   10,
+		},
+		"stats": models.GroupStats{
+			TotalMessages: 0,
+			LastMessageAt: now,
 		},
 	}
 
+	// 成员数由入群/退群事件维护并定期校准，未指定时不覆盖已有值
+	if group.MemberCount > 0 {
+		setFields["member_count"] = group.MemberCount
+	} else {
+		insertFields["member_count"] = 0
+	}
+
+	update := bson.M{
+		"$set":         setFields,
+		"$setOnInsert": insertFields,
+	}
+
 	opts := options.Update().SetUpsert(true)
 	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
@@ -175,6 +183,131 @@ func (r *MongoGroupRepository) DeleteGroup(ctx context.Context, telegramID int64
 	return nil
 }
 
+// UpdateArchive 保存离群配置存档，settings 为 nil 时清除存档
+func (r *MongoGroupRepository) UpdateArchive(ctx context.Context, telegramID int64, settings *models.GroupSettings) error {
+	filter := bson.M{"telegram_id": telegramID}
+	update := bson.M{
+		"$set": bson.M{
+			"archived_settings": settings,
+			"updated_at":        time.Now(),
+		},
+	}
+	if settings == nil {
+		update = bson.M{
+			"$unset": bson.M{"archived_settings": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update group archive: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// UpdateLabels 更新群组的管理备注与标签
+func (r *MongoGroupRepository) UpdateLabels(ctx context.Context, telegramID int64, note string, tags []string) error {
+	filter := bson.M{"telegram_id": telegramID}
+	update := bson.M{
+		"$set": bson.M{
+			"note":       note,
+			"tags":       tags,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update group labels: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// AddAdmin 添加群级管理员，使用 $addToSet 保证不重复
+func (r *MongoGroupRepository) AddAdmin(ctx context.Context, telegramID, userID int64) error {
+	return r.updateAdmins(ctx, telegramID, bson.M{
+		"$addToSet": bson.M{"settings.admin_ids": userID},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+}
+
+// RemoveAdmin 移除群级管理员
+func (r *MongoGroupRepository) RemoveAdmin(ctx context.Context, telegramID, userID int64) error {
+	return r.updateAdmins(ctx, telegramID, bson.M{
+		"$pull": bson.M{"settings.admin_ids": userID},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *MongoGroupRepository) updateAdmins(ctx context.Context, telegramID int64, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"telegram_id": telegramID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update group admins: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// AddCommandAlias 追加群级命令别名；别名已存在或已有 maxAliases 个别名时不写入并返回 false
+// 重复与数量校验放在更新条件中，并发添加不会写入重复别名或超过上限
+func (r *MongoGroupRepository) AddCommandAlias(ctx context.Context, telegramID int64, alias models.CommandAlias, maxAliases int) (bool, error) {
+	filter := bson.M{
+		"telegram_id":                    telegramID,
+		"settings.command_aliases.alias": bson.M{"$ne": alias.Alias},
+		fmt.Sprintf("settings.command_aliases.%d", maxAliases-1): bson.M{"$exists": false},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$push": bson.M{"settings.command_aliases": alias},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add command alias: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RemoveCommandAlias 按别名移除群级命令别名
+func (r *MongoGroupRepository) RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error {
+	return r.updateCommandAliases(ctx, telegramID, bson.M{
+		"$pull": bson.M{"settings.command_aliases": bson.M{"alias": alias}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *MongoGroupRepository) updateCommandAliases(ctx context.Context, telegramID int64, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"telegram_id": telegramID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update command aliases: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// DeleteInactiveBefore 删除 Bot 已离开且离开时间早于 cutoff 的群组
+func (r *MongoGroupRepository) DeleteInactiveBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	filter := bson.M{
+		"bot_status":  bson.M{"$in": []string{models.BotStatusKicked, models.BotStatusLeft}},
+		"bot_left_at": bson.M{"$lt": cutoff},
+	}
+
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete inactive groups: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
 // ListAllGroups 列出所有群组
 func (r *MongoGroupRepository) ListAllGroups(ctx context.Context) ([]*models.Group, error) {
 	cursor, err := r.collection.Find(ctx, bson.D{})
@@ -208,6 +341,47 @@ func (r *MongoGroupRepository) ListActiveGroups(ctx context.Context) ([]*models.
 	return groups, nil
 }
 
+// activeGroupBatchSize 分批遍历活跃群组时每批的数量
+const activeGroupBatchSize = 200
+
+// IterateActiveGroups 按 telegram_id 升序分批遍历活跃群组
+// 以上一批最后一个 telegram_id 作为游标（keyset 分页）而不是 skip，遍历期间新增或删除群组不会导致重复或报错，
+// 只是本次遍历可能看不到游标之前新增的群
+func (r *MongoGroupRepository) IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "telegram_id", Value: 1}}).
+		SetLimit(activeGroupBatchSize)
+
+	var cursorID *int64
+	for {
+		filter := bson.M{"bot_status": models.BotStatusActive}
+		if cursorID != nil {
+			filter["telegram_id"] = bson.M{"$gt": *cursorID}
+		}
+
+		cursor, err := r.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return fmt.Errorf("failed to iterate active groups: %w", err)
+		}
+		var groups []*models.Group
+		if err := cursor.All(ctx, &groups); err != nil {
+			return fmt.Errorf("failed to decode groups: %w", err)
+		}
+		if len(groups) == 0 {
+			return nil
+		}
+
+		if err := fn(groups); err != nil {
+			return err
+		}
+		if len(groups) < activeGroupBatchSize {
+			return nil
+		}
+		lastID := groups[len(groups)-1].TelegramID
+		cursorID = &lastID
+	}
+}
+
 // UpdateSettings 更新群组配置
 func (r *MongoGroupRepository) UpdateSettings(ctx context.Context, telegramID int64, settings models.GroupSettings, tier models.GroupTier) error {
 	filter := bson.M{"telegram_id": telegramID}
@@ -229,19 +403,68 @@ func (r *MongoGroupRepository) UpdateSettings(ctx context.Context, telegramID in
 	return nil
 }
 
-// UpdateStats 更新群组统计信息
-func (r *MongoGroupRepository) UpdateStats(ctx context.Context, telegramID int64, stats models.GroupStats) error {
+// IncrementMessageStats 原子累加群组消息数，最后消息时间仅在更晚时更新
+func (r *MongoGroupRepository) IncrementMessageStats(ctx context.Context, telegramID int64, count int64, lastMessageAt time.Time) error {
 	filter := bson.M{"telegram_id": telegramID}
 	update := bson.M{
-		"$set": bson.M{
-			"stats":      stats,
+		"$inc": bson.M{"stats.total_messages": count},
+		"$max": bson.M{"stats.last_message_at": lastMessageAt},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to increment message stats: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// AdjustMemberCount 原子调整成员数并累加入群/退群人数，成员数不会低于 0
+func (r *MongoGroupRepository) AdjustMemberCount(ctx context.Context, telegramID int64, joined, left int) error {
+	filter := bson.M{"telegram_id": telegramID}
+	// 使用聚合管道更新，在同一次写入中完成增减与下限保护，并发事件不会相互覆盖
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"member_count": bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$member_count", 0}}, joined - left,
+			}}}},
+			"stats.member_joins": bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$stats.member_joins", 0}}, joined,
+			}},
+			"stats.member_leaves": bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$stats.member_leaves", 0}}, left,
+			}},
 			"updated_at": time.Now(),
+		}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to adjust member count: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("group not found: %d", telegramID)
+	}
+	return nil
+}
+
+// SetMemberCount 按 Telegram 实际人数校准成员数
+func (r *MongoGroupRepository) SetMemberCount(ctx context.Context, telegramID int64, count int, syncedAt time.Time) error {
+	filter := bson.M{"telegram_id": telegramID}
+	update := bson.M{
+		"$set": bson.M{
+			"member_count":                 count,
+			"stats.member_count_synced_at": syncedAt,
+			"updated_at":                   time.Now(),
 		},
 	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update stats: %w", err)
+		return fmt.Errorf("failed to set member count: %w", err)
 	}
 	if result.MatchedCount == 0 {
 		return fmt.Errorf("group not found: %d", telegramID)
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserRepository 用户数据访问接口
@@ -18,18 +20,42 @@ type UserRepository interface {
 	// UpdateLastActive 更新用户最后活跃时间
 	UpdateLastActive(ctx context.Context, telegramID int64) error
 
+	// UpdatePreferredLanguage 更新用户偏好语言，lang 为空表示恢复按语言代码自动选择
+	UpdatePreferredLanguage(ctx context.Context, telegramID int64, lang string) error
+
+	// SetStartSource 记录用户首次深链接来源，已有来源时不覆盖，返回是否写入
+	SetStartSource(ctx context.Context, telegramID int64, source string) (bool, error)
+
 	// GrantAdmin 授予管理员权限
 	GrantAdmin(ctx context.Context, telegramID int64, grantedBy int64) error
 
-	// RevokeAdmin 撤销管理员权限
+	// GrantRole 设置管理角色（admin/super_admin）
+	GrantRole(ctx context.Context, telegramID int64, role string, grantedBy int64) error
+
+	// RevokeAdmin 撤销管理员权限（同时清除额外授予的权限）
 	RevokeAdmin(ctx context.Context, telegramID int64) error
 
+	// UpdatePermissions 覆盖用户额外授予的权限列表
+	UpdatePermissions(ctx context.Context, telegramID int64, permissions []string) error
+
 	// ListAdmins 列出所有管理员
 	ListAdmins(ctx context.Context) ([]*models.User, error)
 
+	// ListAdminsPage 分页列出指定角色的管理员（Owner 在前，其余按授予时间），同时返回总数
+	ListAdminsPage(ctx context.Context, roles []string, skip, limit int64) ([]*models.User, int64, error)
+
 	// GetUserInfo 获取用户完整信息
 	GetUserInfo(ctx context.Context, telegramID int64) (*models.User, error)
 
+	// CountInactiveBefore 统计最后活跃早于 cutoff 的普通用户数量（不含管理员与 Owner）
+	CountInactiveBefore(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// ListInactiveBefore 按最后活跃时间升序列出不活跃的普通用户，limit 为上限
+	ListInactiveBefore(ctx context.Context, cutoff time.Time, limit int64) ([]*models.User, error)
+
+	// PurgeInactiveBefore 将不活跃的普通用户分批存档到 purged_users 后删除，返回删除数量（失败时为已删除的数量）
+	PurgeInactiveBefore(ctx context.Context, cutoff time.Time, purgedBy int64) (int64, error)
+
 	// EnsureIndexes 确保索引存在
 	EnsureIndexes(ctx context.Context, ttlSeconds int32) error
 }
@@ -51,17 +77,47 @@ type GroupRepository interface {
 	// DeleteGroup 删除群组（Bot 离开时）
 	DeleteGroup(ctx context.Context, telegramID int64) error
 
+	// UpdateArchive 保存离群配置存档，settings 为 nil 时清除存档
+	UpdateArchive(ctx context.Context, telegramID int64, settings *models.GroupSettings) error
+
+	// UpdateLabels 更新群组的管理备注与标签
+	UpdateLabels(ctx context.Context, telegramID int64, note string, tags []string) error
+
+	// AddAdmin 添加群级管理员（$addToSet，重复添加不产生副作用）
+	AddAdmin(ctx context.Context, telegramID, userID int64) error
+
+	// RemoveAdmin 移除群级管理员
+	RemoveAdmin(ctx context.Context, telegramID, userID int64) error
+
+	// AddCommandAlias 追加群级命令别名，别名已存在或数量已达 maxAliases 时不写入并返回 false
+	AddCommandAlias(ctx context.Context, telegramID int64, alias models.CommandAlias, maxAliases int) (bool, error)
+
+	// RemoveCommandAlias 按别名移除群级命令别名
+	RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error
+
+	// DeleteInactiveBefore 删除 Bot 已离开且离开时间早于 cutoff 的群组，返回删除数量
+	DeleteInactiveBefore(ctx context.Context, cutoff time.Time) (int64, error)
+
 	// ListAllGroups 列出所有群组（包含非活跃）
 	ListAllGroups(ctx context.Context) ([]*models.Group, error)
 
 	// ListActiveGroups 列出所有活跃群组
 	ListActiveGroups(ctx context.Context) ([]*models.Group, error)
 
+	// IterateActiveGroups 按 telegram_id 分批遍历活跃群组，fn 返回错误时停止遍历并返回该错误
+	IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error
+
 	// UpdateSettings 更新群组配置
 	UpdateSettings(ctx context.Context, telegramID int64, settings models.GroupSettings, tier models.GroupTier) error
 
-	// UpdateStats 更新群组统计信息
-	UpdateStats(ctx context.Context, telegramID int64, stats models.GroupStats) error
+	// IncrementMessageStats 原子累加群组消息数并更新最后消息时间
+	IncrementMessageStats(ctx context.Context, telegramID int64, count int64, lastMessageAt time.Time) error
+
+	// AdjustMemberCount 原子调整成员数（joined - left，不低于 0）并累加入群/退群人数
+	AdjustMemberCount(ctx context.Context, telegramID int64, joined, left int) error
+
+	// SetMemberCount 按 Telegram 实际人数校准成员数
+	SetMemberCount(ctx context.Context, telegramID int64, count int, syncedAt time.Time) error
 
 	// EnsureIndexes 确保索引存在
 	EnsureIndexes(ctx context.Context, ttlSeconds int32) error
@@ -72,22 +128,121 @@ type MessageRepository interface {
 	// CreateMessage 创建消息记录
 	CreateMessage(ctx context.Context, message *models.Message) error
 
+	// BulkCreateMessages 批量创建消息记录（性能优化）
+	BulkCreateMessages(ctx context.Context, messages []*models.Message) error
+
 	// GetByTelegramID 根据 Telegram 消息 ID 和聊天 ID 获取消息
 	GetByTelegramID(ctx context.Context, telegramMessageID, chatID int64) (*models.Message, error)
 
-	// UpdateMessageEdit 更新消息编辑信息
+	// UpdateMessageEdit 更新消息编辑信息（旧文本追加到 edit_history，长度受 MaxMessageEditHistory 限制）
 	UpdateMessageEdit(ctx context.Context, telegramMessageID, chatID int64, newText string, editedAt time.Time) error
 
 	// ListMessagesByChat 列出聊天消息历史（分页）
 	ListMessagesByChat(ctx context.Context, chatID int64, limit, offset int64) ([]*models.Message, error)
 
+	// SearchMessages 按关键词搜索聊天消息（匹配文本与媒体说明，按发送时间倒序）
+	SearchMessages(ctx context.Context, chatID int64, keyword string, limit int64) ([]*models.Message, error)
+
+	// FindMessagesByEntity 按消息实体检索（kind 见 models.MessageEntitySearch*，按发送时间倒序）
+	FindMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int64) ([]*models.Message, error)
+
+	// FindMessagesMentioning 查找提及指定用户的消息：@用户名或 text_mention 指向该用户 ID（任一为空值时忽略该条件）
+	FindMessagesMentioning(ctx context.Context, chatID int64, username string, userID int64, limit int64) ([]*models.Message, error)
+
 	// CountMessagesByType 按类型统计消息数量
 	CountMessagesByType(ctx context.Context, chatID int64) (map[string]int64, error)
 
-	// EnsureIndexes 确保索引存在（ttlSeconds 用于 Message TTL 索引）
+	// CountMessagesByTypeSince 按类型统计指定时间（含）之后发送的消息数量
+	CountMessagesByTypeSince(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error)
+
+	// MarkDeleted 标记消息已被删除
+	MarkDeleted(ctx context.Context, telegramMessageID, chatID int64, deletedAt time.Time) error
+
+	// FindFirstMediaSince 查找聊天内 since 之后首条记录的同一文件（按 file_unique_id），没有时返回 nil
+	FindFirstMediaSince(ctx context.Context, chatID int64, fileUniqueID string, since time.Time) (*models.Message, error)
+
+	// IncrementDuplicateCount 首条媒体消息的重复次数 +1
+	IncrementDuplicateCount(ctx context.Context, telegramMessageID, chatID int64) error
+
+	// SetArchiveURL 记录媒体消息的归档地址，消息不存在时返回 false
+	SetArchiveURL(ctx context.Context, telegramMessageID, chatID int64, archiveURL string, archivedAt time.Time) (bool, error)
+
+	// UpdateChatExpiry 按保留天数重算本群全部消息的过期时间，返回更新数量
+	UpdateChatExpiry(ctx context.Context, chatID int64, days int) (int64, error)
+
+	// EnsureIndexes 确保索引存在（ttlSeconds 为全局默认保留期，用于为缺少 expire_at 的旧消息回填过期时间）
 	EnsureIndexes(ctx context.Context, ttlSeconds int32) error
 }
 
+// MemberEventRepository 成员入群/退群事件数据访问接口
+type MemberEventRepository interface {
+	// Create 写入成员事件
+	Create(ctx context.Context, event *models.MemberEvent) error
+
+	// CountByType 统计指定时间（含）之后各类型事件数量
+	CountByType(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error)
+
+	// ListRecent 按时间倒序列出指定类型的事件
+	ListRecent(ctx context.Context, chatID int64, eventType string, since time.Time, limit int64) ([]*models.MemberEvent, error)
+
+	// EnsureIndexes 创建需要的索引
+	EnsureIndexes(ctx context.Context) error
+}
+
+// MerchantHistoryRepository 商户号变更记录数据访问接口
+type MerchantHistoryRepository interface {
+	// Create 写入一条变更记录
+	Create(ctx context.Context, entry *models.MerchantHistory) error
+
+	// ListRecent 按时间倒序列出群组最近的变更记录
+	ListRecent(ctx context.Context, chatID int64, limit int64) ([]*models.MerchantHistory, error)
+
+	// EnsureIndexes 创建需要的索引
+	EnsureIndexes(ctx context.Context) error
+}
+
+// ScheduledMessageRepository 定时消息数据访问接口
+type ScheduledMessageRepository interface {
+	// Create 写入定时消息
+	Create(ctx context.Context, message *models.ScheduledMessage) error
+
+	// ListByChat 按创建时间列出群组的定时消息
+	ListByChat(ctx context.Context, chatID int64) ([]*models.ScheduledMessage, error)
+
+	// CountByChat 统计群组的定时消息数量
+	CountByChat(ctx context.Context, chatID int64) (int64, error)
+
+	// Delete 删除群组内指定的定时消息，返回是否存在
+	Delete(ctx context.Context, chatID int64, id string) (bool, error)
+
+	// ListDue 按触发时间升序列出已到期的定时消息
+	ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error)
+
+	// NextRunAt 返回最早的下一次触发时间，没有定时消息时返回 nil
+	NextRunAt(ctx context.Context) (*time.Time, error)
+
+	// UpdateRun 更新下一次触发时间，lastRunAt 非空时同时记录本次发送时间
+	UpdateRun(ctx context.Context, id string, nextRunAt time.Time, lastRunAt *time.Time) error
+
+	// ClaimRun 认领定时消息的本次触发（按当前 next_run_at），已被认领时返回 false
+	ClaimRun(ctx context.Context, message *models.ScheduledMessage, claimedAt time.Time) (bool, error)
+
+	// EnsureIndexes 创建需要的索引
+	EnsureIndexes(ctx context.Context) error
+}
+
+// DeletedMessageRepository 被删除消息留档数据访问接口
+type DeletedMessageRepository interface {
+	// Create 写入留档记录（同一条消息重复上报时忽略）
+	Create(ctx context.Context, message *models.DeletedMessage) error
+
+	// ListRecent 按删除时间倒序列出留档记录，chatID 为 0 时不限聊天
+	ListRecent(ctx context.Context, chatID int64, limit int64) ([]*models.DeletedMessage, error)
+
+	// EnsureIndexes 创建需要的索引
+	EnsureIndexes(ctx context.Context) error
+}
+
 // ForwardRecordRepository 转发记录数据访问接口
 type ForwardRecordRepository interface {
 	// CreateRecord 创建转发记录
@@ -102,6 +257,9 @@ type ForwardRecordRepository interface {
 	// DeleteRecordsByTaskID 删除转发记录（撤回后清理）
 	DeleteRecordsByTaskID(ctx context.Context, taskID string) error
 
+	// MarkChannelPostProcessed 标记频道消息已处理，首次标记返回 true，重复到达返回 false
+	MarkChannelPostProcessed(ctx context.Context, channelID, messageID int64, processedAt time.Time) (bool, error)
+
 	// EnsureIndexes 确保索引存在
 	EnsureIndexes(ctx context.Context) error
 }
@@ -111,18 +269,30 @@ type AccountingRepository interface {
 	// CreateRecord 创建记账记录
 	CreateRecord(ctx context.Context, record *models.AccountingRecord) error
 
+	// CreateRecordOnce 按 OperationID 幂等创建记账记录，同一 ID 已存在时返回 false 且不重复写入
+	CreateRecordOnce(ctx context.Context, record *models.AccountingRecord) (bool, error)
+
 	// GetRecordsByDateRange 按日期范围查询记录
 	GetRecordsByDateRange(ctx context.Context, chatID int64, startTime, endTime time.Time, currency string) ([]*models.AccountingRecord, error)
 
+	// GetLatestRecords 获取指定币种最近的 limit 条记录（按记账时间倒序）
+	GetLatestRecords(ctx context.Context, chatID int64, currency string, limit int64) ([]*models.AccountingRecord, error)
+
 	// GetRecentRecords 获取最近N天的记录（用于删除界面）
 	GetRecentRecords(ctx context.Context, chatID int64, days int) ([]*models.AccountingRecord, error)
 
 	// DeleteRecord 删除单条记录
 	DeleteRecord(ctx context.Context, recordID string) error
 
+	// DeleteRecordsByIDs 删除群组内指定 ID 的记录
+	DeleteRecordsByIDs(ctx context.Context, chatID int64, ids []primitive.ObjectID) (int64, error)
+
 	// DeleteAllByChatID 清空群组所有记录
 	DeleteAllByChatID(ctx context.Context, chatID int64) (int64, error)
 
+	// DeleteAllByBook 清空群组指定账本的记录，book 为空时清空默认账本
+	DeleteAllByBook(ctx context.Context, chatID int64, book string) (int64, error)
+
 	// EnsureIndexes 确保索引存在
 	EnsureIndexes(ctx context.Context) error
 }
@@ -135,8 +305,8 @@ type UpstreamBalanceRepository interface {
 	// Adjust 调整余额（正为加款，负为扣款），同时写入日志
 	Adjust(ctx context.Context, groupID int64, delta float64, operatorID int64, remark string, opType models.BalanceOperationType, operationID string, metadata map[string]string) (*models.UpstreamBalance, error)
 
-	// SetMinBalance 设置最低余额阈值并记录日志
-	SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64) (*models.UpstreamBalance, error)
+	// SetMinBalance 设置最低余额阈值并记录日志，operationID 非空时同一 ID 只写一条日志
+	SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64, operationID string) (*models.UpstreamBalance, error)
 
 	// SetAlertLimit 设置告警频率限制
 	SetAlertLimit(ctx context.Context, groupID int64, limit int, operatorID int64) (*models.UpstreamBalance, error)
@@ -147,3 +317,98 @@ type UpstreamBalanceRepository interface {
 	// EnsureIndexes 确保索引存在
 	EnsureIndexes(ctx context.Context) error
 }
+
+// SendMoneyRepository 四方下发每日累计数据访问接口
+type SendMoneyRepository interface {
+	// GetDailyTotal 获取群组某天的下发累计，不存在时返回 nil
+	GetDailyTotal(ctx context.Context, chatID int64, date string) (*models.SendMoneyDailyTotal, error)
+
+	// ReserveDaily 原子地累加下发金额；limit > 0 时累加后超过限额则不写入并返回 false
+	ReserveDaily(ctx context.Context, chatID int64, date string, amount, limit float64) (*models.SendMoneyDailyTotal, bool, error)
+
+	// ReleaseDaily 回退已预占的下发金额（下发失败时调用）
+	ReleaseDaily(ctx context.Context, chatID int64, date string, amount float64) error
+
+	// EnsureIndexes 确保索引存在
+	EnsureIndexes(ctx context.Context) error
+}
+
+// OrderCascadeRepository 订单联动状态数据访问接口
+type OrderCascadeRepository interface {
+	// Save 按 token 写入或覆盖联动状态
+	Save(ctx context.Context, state *models.OrderCascadeState) error
+
+	// Get 根据 token 获取联动状态，不存在时返回 nil
+	Get(ctx context.Context, token string) (*models.OrderCascadeState, error)
+
+	// Delete 删除联动状态（已完成或已过期）
+	Delete(ctx context.Context, token string) error
+
+	// EnsureIndexes 确保索引存在
+	EnsureIndexes(ctx context.Context) error
+}
+
+// DailyBillPushRepository 每日账单推送记录数据访问接口（失败补发与防重复推送）
+type DailyBillPushRepository interface {
+	// IsSent 某群某天的账单是否已推送
+	IsSent(ctx context.Context, chatID int64, date string) (bool, error)
+
+	// MarkSent 标记已推送，之后不会再次推送或补发
+	MarkSent(ctx context.Context, chatID, merchantID int64, date string) error
+
+	// MarkPending 登记推送失败并累加失败次数，已推送的记录保持不变
+	MarkPending(ctx context.Context, chatID, merchantID int64, date string, reason string) error
+
+	// ListPending 列出 date >= sinceDate 的待补发记录，按日期升序
+	ListPending(ctx context.Context, sinceDate string, limit int64) ([]*models.DailyBillPush, error)
+
+	// EnsureIndexes 确保索引存在
+	EnsureIndexes(ctx context.Context) error
+}
+
+// SendMoneyExpirationRepository 待确认下发过期记录数据访问接口
+type SendMoneyExpirationRepository interface {
+	// Save 按 token 写入或覆盖过期记录
+	Save(ctx context.Context, record *models.SendMoneyExpiration) error
+
+	// Delete 删除过期记录（下发已完成、取消或已处理过期），返回记录删除前是否存在；
+	// 过期处理以此认领记录，记录已被删除说明请求已结束或已被处理
+	Delete(ctx context.Context, token string) (bool, error)
+
+	// ListDue 列出 expires_at 早于 now 的记录，按过期时间升序
+	ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.SendMoneyExpiration, error)
+
+	// EnsureIndexes 确保索引存在
+	EnsureIndexes(ctx context.Context) error
+}
+
+// JoinVerificationRepository 入群验证状态数据访问接口
+type JoinVerificationRepository interface {
+	// Save 按 token 写入或覆盖验证状态
+	Save(ctx context.Context, verification *models.JoinVerification) error
+
+	// Get 按 token 查询验证状态，不存在时返回 nil
+	Get(ctx context.Context, token string) (*models.JoinVerification, error)
+
+	// Delete 删除验证状态，返回记录删除前是否存在；
+	// 验证通过与超时处理以此认领记录，避免重复放行或踢出
+	Delete(ctx context.Context, token string) (bool, error)
+
+	// List 按超时时间升序列出全部验证状态（启动时恢复超时处理）
+	List(ctx context.Context, limit int64) ([]*models.JoinVerification, error)
+
+	// EnsureIndexes 确保索引存在
+	EnsureIndexes(ctx context.Context) error
+}
+
+// CommandUsageRepository 命令使用统计数据访问接口
+type CommandUsageRepository interface {
+	// IncrementBatch 按（日期, 命令）批量累加调用次数，Count 为本批增量
+	IncrementBatch(ctx context.Context, usages []*models.CommandUsage) error
+
+	// SumSince 汇总指定日期（含）之后各命令的调用次数，按次数降序
+	SumSince(ctx context.Context, sinceDate string) ([]models.CommandUsageRank, error)
+
+	// EnsureIndexes 创建需要的索引
+	EnsureIndexes(ctx context.Context) error
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// joinVerificationRetention 验证记录在超时后仍保留的时长，留给重启后的超时处理，之后由 TTL 索引清理
+const joinVerificationRetention = 7 * 24 * time.Hour
+
+// MongoJoinVerificationRepository 入群验证状态数据访问层（MongoDB 实现）
+type MongoJoinVerificationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoJoinVerificationRepository 创建仓储实例
+func NewMongoJoinVerificationRepository(db *mongo.Database) JoinVerificationRepository {
+	return &MongoJoinVerificationRepository{
+		collection: db.Collection("join_verifications"),
+	}
+}
+
+// Save 按 token 写入或覆盖验证状态
+func (r *MongoJoinVerificationRepository) Save(ctx context.Context, verification *models.JoinVerification) error {
+	if verification == nil || verification.Token == "" {
+		return errors.New("join verification token is required")
+	}
+	if verification.CreatedAt.IsZero() {
+		verification.CreatedAt = time.Now()
+	}
+
+	filter := bson.M{"token": verification.Token}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.collection.ReplaceOne(ctx, filter, verification, opts); err != nil {
+		return fmt.Errorf("failed to save join verification: %w", err)
+	}
+	return nil
+}
+
+// Get 按 token 查询验证状态，不存在时返回 nil
+func (r *MongoJoinVerificationRepository) Get(ctx context.Context, token string) (*models.JoinVerification, error) {
+	var verification models.JoinVerification
+	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&verification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join verification: %w", err)
+	}
+	return &verification, nil
+}
+
+// Delete 删除验证状态，返回记录删除前是否存在
+func (r *MongoJoinVerificationRepository) Delete(ctx context.Context, token string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"token": token})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete join verification: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// List 按超时时间升序列出全部验证状态
+func (r *MongoJoinVerificationRepository) List(ctx context.Context, limit int64) ([]*models.JoinVerification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list join verifications: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var verifications []*models.JoinVerification
+	if err := cursor.All(ctx, &verifications); err != nil {
+		return nil, fmt.Errorf("failed to decode join verifications: %w", err)
+	}
+	return verifications, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoJoinVerificationRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(joinVerificationRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("create join verification indexes: %w", err)
+	}
+	return nil
+}
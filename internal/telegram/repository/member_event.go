@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memberEventRetention 成员事件保留时长
+const memberEventRetention = 180 * 24 * time.Hour
+
+// MongoMemberEventRepository 成员变动事件数据访问层（MongoDB 实现）
+type MongoMemberEventRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMemberEventRepository 创建成员事件 Repository
+func NewMongoMemberEventRepository(db *mongo.Database) MemberEventRepository {
+	return &MongoMemberEventRepository{
+		collection: db.Collection("member_events"),
+	}
+}
+
+// Create 写入成员事件
+func (r *MongoMemberEventRepository) Create(ctx context.Context, event *models.MemberEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("failed to create member event: %w", err)
+	}
+	return nil
+}
+
+// CountByType 统计指定时间（含）之后各类型事件数量
+func (r *MongoMemberEventRepository) CountByType(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"chat_id":     chatID,
+			"occurred_at": bson.M{"$gte": since},
+		}},
+		{"$group": bson.M{
+			"_id":   "$event_type",
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count member events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode member event counts: %w", err)
+	}
+
+	result := make(map[string]int64, len(docs))
+	for _, doc := range docs {
+		result[doc.ID] = doc.Count
+	}
+	return result, nil
+}
+
+// ListRecent 按时间倒序列出指定类型的事件
+func (r *MongoMemberEventRepository) ListRecent(ctx context.Context, chatID int64, eventType string, since time.Time, limit int64) ([]*models.MemberEvent, error) {
+	filter := bson.M{
+		"chat_id":     chatID,
+		"event_type":  eventType,
+		"occurred_at": bson.M{"$gte": since},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []*models.MemberEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode member events: %w", err)
+	}
+	return events, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoMemberEventRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "event_type", Value: 1},
+				{Key: "occurred_at", Value: -1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "occurred_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(memberEventRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create member event indexes: %w", err)
+	}
+	return nil
+}
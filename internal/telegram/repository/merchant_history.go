@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMerchantHistoryRepository 商户号变更记录数据访问层（MongoDB 实现）
+type MongoMerchantHistoryRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMerchantHistoryRepository 创建商户号变更记录 Repository
+func NewMongoMerchantHistoryRepository(db *mongo.Database) MerchantHistoryRepository {
+	return &MongoMerchantHistoryRepository{
+		collection: db.Collection("merchant_histories"),
+	}
+}
+
+// Create 写入一条变更记录
+func (r *MongoMerchantHistoryRepository) Create(ctx context.Context, entry *models.MerchantHistory) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if _, err := r.collection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to create merchant history: %w", err)
+	}
+	return nil
+}
+
+// ListRecent 按时间倒序列出群组最近的变更记录
+func (r *MongoMerchantHistoryRepository) ListRecent(ctx context.Context, chatID int64, limit int64) ([]*models.MerchantHistory, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"chat_id": chatID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merchant history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.MerchantHistory
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode merchant history: %w", err)
+	}
+	return entries, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoMerchantHistoryRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create merchant history indexes: %w", err)
+	}
+	return nil
+}
@@ -3,15 +3,20 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"go_bot/internal/telegram/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// messageExpireIndexName expire_at TTL 索引名
+const messageExpireIndexName = "message_expire_at_ttl"
+
 // MongoMessageRepository 消息数据访问层（MongoDB 实现）
 type MongoMessageRepository struct {
 	collection *mongo.Collection
@@ -26,32 +31,70 @@ func NewMongoMessageRepository(db *mongo.Database) MessageRepository {
 
 // CreateMessage 创建消息记录
 func (r *MongoMessageRepository) CreateMessage(ctx context.Context, message *models.Message) error {
+	filter, update := messageUpsert(message, time.Now())
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+
+	return nil
+}
+
+// BulkCreateMessages 批量创建消息记录（性能优化），与 CreateMessage 一样按 chat_id + telegram_message_id upsert，
+// 使用无序写入，单条失败不影响其余消息
+func (r *MongoMessageRepository) BulkCreateMessages(ctx context.Context, messages []*models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
 	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(messages))
+	for _, message := range messages {
+		filter, update := messageUpsert(message, now)
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("failed to bulk create messages: %w", err)
+	}
+	return nil
+}
+
+// messageUpsert 构建消息 upsert 的过滤条件与更新文档（使用 Upsert 模式，避免重复插入）
+func messageUpsert(message *models.Message, now time.Time) (bson.M, bson.M) {
 	message.CreatedAt = now
 	message.UpdatedAt = now
 
-	// 使用 Upsert 模式，避免重复插入
 	filter := bson.M{
 		"telegram_message_id": message.TelegramMessageID,
 		"chat_id":             message.ChatID,
 	}
 
 	setFields := bson.M{
-		"user_id":                  message.UserID,
-		"message_type":             message.MessageType,
-		"text":                     message.Text,
-		"caption":                  message.Caption,
-		"media_file_id":            message.MediaFileID,
-		"media_file_size":          message.MediaFileSize,
-		"media_mime_type":          message.MediaMimeType,
-		"media_thumbnail_id":       message.MediaThumbnailID,
-		"reply_to_message_id":      message.ReplyToMessageID,
-		"forward_from_chat_id":     message.ForwardFromChatID,
-		"forward_from_message_id":  message.ForwardFromMessageID,
-		"is_edited":                message.IsEdited,
-		"edited_at":                message.EditedAt,
-		"sent_at":                  message.SentAt,
-		"updated_at":               message.UpdatedAt,
+		"user_id":                 message.UserID,
+		"message_type":            message.MessageType,
+		"text":                    message.Text,
+		"caption":                 message.Caption,
+		"media_file_id":           message.MediaFileID,
+		"media_file_size":         message.MediaFileSize,
+		"media_mime_type":         message.MediaMimeType,
+		"media_thumbnail_id":      message.MediaThumbnailID,
+		"media_file_unique_id":    message.MediaFileUniqueID,
+		"duplicate_of_message_id": message.DuplicateOfMessageID,
+		"reply_to_message_id":     message.ReplyToMessageID,
+		"forward_from_chat_id":    message.ForwardFromChatID,
+		"forward_from_message_id": message.ForwardFromMessageID,
+		"is_edited":               message.IsEdited,
+		"edited_at":               message.EditedAt,
+		"sent_at":                 message.SentAt,
+		"updated_at":              message.UpdatedAt,
+	}
+
+	if message.ExpireAt != nil {
+		setFields["expire_at"] = message.ExpireAt
 	}
 
 	setOnInsert := bson.M{
@@ -63,13 +106,7 @@ func (r *MongoMessageRepository) CreateMessage(ctx context.Context, message *mod
 		"$setOnInsert": setOnInsert,
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
-	if err != nil {
-		return fmt.Errorf("failed to create message: %w", err)
-	}
-
-	return nil
+	return filter, update
 }
 
 // GetByTelegramID 根据 Telegram 消息 ID 和聊天 ID 获取消息
@@ -91,20 +128,34 @@ func (r *MongoMessageRepository) GetByTelegramID(ctx context.Context, telegramMe
 	return &message, nil
 }
 
-// UpdateMessageEdit 更新消息编辑信息
+// UpdateMessageEdit 更新消息编辑信息，旧文本追加到编辑历史而不是直接覆盖
 func (r *MongoMessageRepository) UpdateMessageEdit(ctx context.Context, telegramMessageID, chatID int64, newText string, editedAt time.Time) error {
 	filter := bson.M{
 		"telegram_message_id": telegramMessageID,
 		"chat_id":             chatID,
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"text":       newText,
+	// 使用聚合管道更新：先把旧文本追加到 edit_history（截断到上限），再写入新文本
+	// newText 用 $literal 包裹，避免以 $ 开头的文本被解析为字段路径
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"edit_history": bson.M{
+				"$slice": bson.A{
+					bson.M{"$concatArrays": bson.A{
+						bson.M{"$ifNull": bson.A{"$edit_history", bson.A{}}},
+						bson.A{bson.M{
+							"text":      bson.M{"$ifNull": bson.A{"$text", ""}},
+							"edited_at": editedAt,
+						}},
+					}},
+					-models.MaxMessageEditHistory,
+				},
+			},
+			"text":       bson.M{"$literal": newText},
 			"is_edited":  true,
 			"edited_at":  editedAt,
 			"updated_at": time.Now(),
-		},
+		}}},
 	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
@@ -119,6 +170,92 @@ func (r *MongoMessageRepository) UpdateMessageEdit(ctx context.Context, telegram
 	return nil
 }
 
+// MarkDeleted 标记消息已被删除
+func (r *MongoMessageRepository) MarkDeleted(ctx context.Context, telegramMessageID, chatID int64, deletedAt time.Time) error {
+	filter := bson.M{
+		"telegram_message_id": telegramMessageID,
+		"chat_id":             chatID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"deleted_at": deletedAt,
+			"updated_at": time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to mark message deleted: %w", err)
+	}
+
+	return nil
+}
+
+// FindFirstMediaSince 查找聊天内 since 之后首条记录的同一文件（不含已标记为重复的记录），没有时返回 nil
+func (r *MongoMessageRepository) FindFirstMediaSince(ctx context.Context, chatID int64, fileUniqueID string, since time.Time) (*models.Message, error) {
+	filter := bson.M{
+		"chat_id":                 chatID,
+		"media_file_unique_id":    fileUniqueID,
+		"sent_at":                 bson.M{"$gte": since},
+		"duplicate_of_message_id": bson.M{"$in": bson.A{nil, 0}},
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "sent_at", Value: 1}})
+
+	var message models.Message
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&message); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find media message: %w", err)
+	}
+
+	return &message, nil
+}
+
+// IncrementDuplicateCount 首条媒体消息的重复次数 +1
+func (r *MongoMessageRepository) IncrementDuplicateCount(ctx context.Context, telegramMessageID, chatID int64) error {
+	filter := bson.M{
+		"telegram_message_id": telegramMessageID,
+		"chat_id":             chatID,
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"duplicate_count": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to increment duplicate count: %w", err)
+	}
+
+	return nil
+}
+
+// SetArchiveURL 记录媒体消息的归档地址，消息不存在时返回 false
+func (r *MongoMessageRepository) SetArchiveURL(ctx context.Context, telegramMessageID, chatID int64, archiveURL string, archivedAt time.Time) (bool, error) {
+	filter := bson.M{
+		"telegram_message_id": telegramMessageID,
+		"chat_id":             chatID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"archive_url": archiveURL,
+			"archived_at": archivedAt,
+			"updated_at":  time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to set archive url: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
 // ListMessagesByChat 列出聊天消息历史（分页）
 func (r *MongoMessageRepository) ListMessagesByChat(ctx context.Context, chatID int64, limit, offset int64) ([]*models.Message, error) {
 	filter := bson.M{"chat_id": chatID}
@@ -143,11 +280,120 @@ func (r *MongoMessageRepository) ListMessagesByChat(ctx context.Context, chatID
 	return messages, nil
 }
 
+// SearchMessages 在指定聊天中按关键词搜索文本与媒体说明（不区分大小写，按发送时间倒序）
+func (r *MongoMessageRepository) SearchMessages(ctx context.Context, chatID int64, keyword string, limit int64) ([]*models.Message, error) {
+	// 中文没有分词，使用转义后的正则匹配，chat_id + sent_at 索引负责缩小扫描范围
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(keyword), Options: "i"}
+	filter := bson.M{
+		"chat_id": chatID,
+		"$or": []bson.M{
+			{"text": pattern},
+			{"caption": pattern},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sent_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// FindMessagesByEntity 按消息实体检索：@提及与话题标签精确匹配（value 需已归一化），链接按片段不区分大小写匹配
+func (r *MongoMessageRepository) FindMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int64) ([]*models.Message, error) {
+	filter := bson.M{"chat_id": chatID}
+	switch kind {
+	case models.MessageEntitySearchMention:
+		filter["mentions"] = value
+	case models.MessageEntitySearchHashtag:
+		filter["hashtags"] = value
+	case models.MessageEntitySearchURL:
+		filter["urls"] = primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}
+	default:
+		return nil, fmt.Errorf("unsupported entity search kind: %s", kind)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sent_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find messages by entity: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// FindMessagesMentioning 查找提及指定用户的消息（username 需已归一化），按发送时间倒序
+// 两个条件分别命中 chat_id + mentions / chat_id + mention_user_ids 多键索引
+func (r *MongoMessageRepository) FindMessagesMentioning(ctx context.Context, chatID int64, username string, userID int64, limit int64) ([]*models.Message, error) {
+	var conditions []bson.M
+	if username != "" {
+		conditions = append(conditions, bson.M{"chat_id": chatID, "mentions": username})
+	}
+	if userID != 0 {
+		conditions = append(conditions, bson.M{"chat_id": chatID, "mention_user_ids": userID})
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sent_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": conditions}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find mentioning messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	return messages, nil
+}
+
 // CountMessagesByType 按类型统计消息数量
 func (r *MongoMessageRepository) CountMessagesByType(ctx context.Context, chatID int64) (map[string]int64, error) {
+	return r.countMessagesByType(ctx, bson.M{"chat_id": chatID})
+}
+
+// CountMessagesByTypeSince 按类型统计指定时间（含）之后发送的消息数量
+func (r *MongoMessageRepository) CountMessagesByTypeSince(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error) {
+	return r.countMessagesByType(ctx, bson.M{
+		"chat_id": chatID,
+		"sent_at": bson.M{"$gte": since},
+	})
+}
+
+func (r *MongoMessageRepository) countMessagesByType(ctx context.Context, match bson.M) (map[string]int64, error) {
 	pipeline := []bson.M{
 		{
-			"$match": bson.M{"chat_id": chatID},
+			"$match": match,
 		},
 		{
 			"$group": bson.M{
@@ -182,7 +428,31 @@ func (r *MongoMessageRepository) CountMessagesByType(ctx context.Context, chatID
 	return result, nil
 }
 
-// EnsureIndexes 确保索引存在
+// UpdateChatExpiry 按新的保留天数重算本群全部消息的过期时间（expire_at = sent_at + days）
+func (r *MongoMessageRepository) UpdateChatExpiry(ctx context.Context, chatID int64, days int) (int64, error) {
+	if days <= 0 {
+		return 0, fmt.Errorf("invalid retention days: %d", days)
+	}
+
+	result, err := r.collection.UpdateMany(ctx, bson.M{"chat_id": chatID}, expireAtPipeline(days))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update chat expiry: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// expireAtPipeline 以 sent_at 为基准计算 expire_at 的聚合管道更新
+func expireAtPipeline(days int) mongo.Pipeline {
+	retention := int64(days) * 24 * int64(time.Hour/time.Millisecond)
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"expire_at": bson.M{"$add": bson.A{"$sent_at", retention}},
+		}}},
+	}
+}
+
+// EnsureIndexes 确保索引存在，并把旧版按 sent_at 计算的 TTL 平滑迁移到 expire_at
+// ttlSeconds 为全局默认保留期：缺少 expire_at 的旧消息按 sent_at + ttlSeconds 回填，与迁移前的过期时间一致
 func (r *MongoMessageRepository) EnsureIndexes(ctx context.Context, ttlSeconds int32) error {
 	indexes := []mongo.IndexModel{
 		{
@@ -208,9 +478,39 @@ func (r *MongoMessageRepository) EnsureIndexes(ctx context.Context, ttlSeconds i
 			Keys: bson.D{{Key: "message_type", Value: 1}},
 		},
 		{
-			// TTL 索引：消息在 sent_at + ttlSeconds 后自动过期删除
-			Keys:    bson.D{{Key: "sent_at", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(ttlSeconds),
+			// 媒体去重：按文件唯一 ID 查找本群近期的首条记录
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "media_file_unique_id", Value: 1},
+				{Key: "sent_at", Value: 1},
+			},
+		},
+		{
+			// 实体检索：按 @提及、话题标签查找本群消息（多键索引，旧数据缺少字段不占索引项）
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "mentions", Value: 1},
+				{Key: "sent_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "mention_user_ids", Value: 1},
+				{Key: "sent_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "hashtags", Value: 1},
+				{Key: "sent_at", Value: -1},
+			},
+		},
+		{
+			// TTL 索引：消息到达 expire_at 后自动删除，保留期按群组差异化写入 expire_at
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName(messageExpireIndexName),
 		},
 	}
 
@@ -219,5 +519,45 @@ func (r *MongoMessageRepository) EnsureIndexes(ctx context.Context, ttlSeconds i
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
-	return nil
+	return r.migrateLegacyTTL(ctx, ttlSeconds)
+}
+
+// migrateLegacyTTL 为缺少 expire_at 的旧消息回填过期时间，回填成功后再删除旧的 sent_at TTL 索引，
+// 保证迁移过程中任何消息都不会提前删除或永不过期
+func (r *MongoMessageRepository) migrateLegacyTTL(ctx context.Context, ttlSeconds int32) error {
+	if ttlSeconds <= 0 {
+		return nil
+	}
+
+	days := int((time.Duration(ttlSeconds) * time.Second).Hours() / 24)
+	if days <= 0 {
+		days = 1
+	}
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"expire_at": nil}, expireAtPipeline(days)); err != nil {
+		return fmt.Errorf("failed to backfill message expire_at: %w", err)
+	}
+
+	cursor, err := r.collection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list message indexes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var index struct {
+			Name               string `bson:"name"`
+			Key                bson.D `bson:"key"`
+			ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+		}
+		if err := cursor.Decode(&index); err != nil {
+			continue
+		}
+		if index.ExpireAfterSeconds == nil || len(index.Key) != 1 || index.Key[0].Key != "sent_at" {
+			continue
+		}
+		if _, err := r.collection.Indexes().DropOne(ctx, index.Name); err != nil {
+			return fmt.Errorf("failed to drop legacy TTL index %s: %w", index.Name, err)
+		}
+	}
+	return cursor.Err()
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoOrderCascadeRepository 订单联动状态数据访问层（MongoDB 实现）
+type MongoOrderCascadeRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoOrderCascadeRepository 创建仓储实例
+func NewMongoOrderCascadeRepository(db *mongo.Database) OrderCascadeRepository {
+	return &MongoOrderCascadeRepository{
+		collection: db.Collection("order_cascade_states"),
+	}
+}
+
+// Save 按 token 写入或覆盖联动状态
+func (r *MongoOrderCascadeRepository) Save(ctx context.Context, state *models.OrderCascadeState) error {
+	if state == nil || state.Token == "" {
+		return errors.New("order cascade state token is required")
+	}
+
+	filter := bson.M{"token": state.Token}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.collection.ReplaceOne(ctx, filter, state, opts); err != nil {
+		return fmt.Errorf("failed to save order cascade state: %w", err)
+	}
+	return nil
+}
+
+// Get 根据 token 获取联动状态
+func (r *MongoOrderCascadeRepository) Get(ctx context.Context, token string) (*models.OrderCascadeState, error) {
+	var state models.OrderCascadeState
+	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&state)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get order cascade state: %w", err)
+	}
+	return &state, nil
+}
+
+// Delete 删除联动状态
+func (r *MongoOrderCascadeRepository) Delete(ctx context.Context, token string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"token": token}); err != nil {
+		return fmt.Errorf("failed to delete order cascade state: %w", err)
+	}
+	return nil
+}
+
+// EnsureIndexes 创建需要的索引，expires_at 到期后由 MongoDB 自动清理
+func (r *MongoOrderCascadeRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("create order cascade indexes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scheduledMessageRunRetention 触发认领记录保留时长，远超补发窗口即可，超过后由 TTL 索引清理
+const scheduledMessageRunRetention = 7 * 24 * time.Hour
+
+// MongoScheduledMessageRepository 定时消息数据访问层（MongoDB 实现）
+type MongoScheduledMessageRepository struct {
+	collection *mongo.Collection
+	runs       *mongo.Collection
+}
+
+// NewMongoScheduledMessageRepository 创建定时消息 Repository
+func NewMongoScheduledMessageRepository(db *mongo.Database) ScheduledMessageRepository {
+	return &MongoScheduledMessageRepository{
+		collection: db.Collection("scheduled_messages"),
+		runs:       db.Collection("scheduled_message_runs"),
+	}
+}
+
+// Create 写入定时消息
+func (r *MongoScheduledMessageRepository) Create(ctx context.Context, message *models.ScheduledMessage) error {
+	now := time.Now()
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = now
+	}
+	message.UpdatedAt = now
+
+	result, err := r.collection.InsertOne(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled message: %w", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		message.ID = id
+	}
+	return nil
+}
+
+// ListByChat 按创建时间列出群组的定时消息
+func (r *MongoScheduledMessageRepository) ListByChat(ctx context.Context, chatID int64) ([]*models.ScheduledMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"chat_id": chatID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.ScheduledMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled messages: %w", err)
+	}
+	return messages, nil
+}
+
+// CountByChat 统计群组的定时消息数量
+func (r *MongoScheduledMessageRepository) CountByChat(ctx context.Context, chatID int64) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"chat_id": chatID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count scheduled messages: %w", err)
+	}
+	return count, nil
+}
+
+// Delete 删除群组内指定的定时消息，返回是否存在
+func (r *MongoScheduledMessageRepository) Delete(ctx context.Context, chatID int64, id string) (bool, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid scheduled message ID: %w", err)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objID, "chat_id": chatID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete scheduled message: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ListDue 按触发时间升序列出已到期的定时消息
+func (r *MongoScheduledMessageRepository) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "next_run_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"next_run_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var messages []*models.ScheduledMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode due scheduled messages: %w", err)
+	}
+	return messages, nil
+}
+
+// NextRunAt 返回最早的下一次触发时间，没有定时消息时返回 nil
+func (r *MongoScheduledMessageRepository) NextRunAt(ctx context.Context) (*time.Time, error) {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "next_run_at", Value: 1}}).
+		SetProjection(bson.M{"next_run_at": 1})
+
+	var doc struct {
+		NextRunAt time.Time `bson:"next_run_at"`
+	}
+	if err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find next scheduled run: %w", err)
+	}
+	return &doc.NextRunAt, nil
+}
+
+// UpdateRun 更新下一次触发时间，lastRunAt 非空时同时记录本次发送时间
+func (r *MongoScheduledMessageRepository) UpdateRun(ctx context.Context, id string, nextRunAt time.Time, lastRunAt *time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid scheduled message ID: %w", err)
+	}
+
+	set := bson.M{
+		"next_run_at": nextRunAt,
+		"updated_at":  time.Now(),
+	}
+	if lastRunAt != nil {
+		set["last_run_at"] = *lastRunAt
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, objID, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("failed to update scheduled message run: %w", err)
+	}
+	return nil
+}
+
+// ClaimRun 认领定时消息的本次触发（按当前 next_run_at），依赖唯一索引保证并发下只有一个实例返回 true
+func (r *MongoScheduledMessageRepository) ClaimRun(ctx context.Context, message *models.ScheduledMessage, claimedAt time.Time) (bool, error) {
+	_, err := r.runs.InsertOne(ctx, &models.ScheduledMessageRun{
+		MessageID: message.ID,
+		ChatID:    message.ChatID,
+		RunAt:     message.NextRunAt,
+		ClaimedAt: claimedAt,
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim scheduled message run: %w", err)
+	}
+	return true, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoScheduledMessageRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "next_run_at", Value: 1}},
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("failed to create scheduled message indexes: %w", err)
+	}
+
+	runIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "message_id", Value: 1}, {Key: "run_at", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "claimed_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(scheduledMessageRunRetention.Seconds())),
+		},
+	}
+	if _, err := r.runs.Indexes().CreateMany(ctx, runIndexes); err != nil {
+		return fmt.Errorf("failed to create scheduled message run indexes: %w", err)
+	}
+	return nil
+}
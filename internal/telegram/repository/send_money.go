@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// sendMoneyAmountEpsilon 浮点比较容差，避免 0.1+0.2 之类的精度误差误判超限
+	sendMoneyAmountEpsilon = 1e-6
+	// sendMoneyTotalRetention 每日累计记录保留时长
+	sendMoneyTotalRetention = 90 * 24 * time.Hour
+)
+
+// MongoSendMoneyRepository 四方下发每日累计数据访问层（MongoDB 实现）
+type MongoSendMoneyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSendMoneyRepository 创建仓储实例
+func NewMongoSendMoneyRepository(db *mongo.Database) SendMoneyRepository {
+	return &MongoSendMoneyRepository{
+		collection: db.Collection("send_money_daily_totals"),
+	}
+}
+
+// GetDailyTotal 获取群组某天的下发累计
+func (r *MongoSendMoneyRepository) GetDailyTotal(ctx context.Context, chatID int64, date string) (*models.SendMoneyDailyTotal, error) {
+	var total models.SendMoneyDailyTotal
+	err := r.collection.FindOne(ctx, bson.M{"chat_id": chatID, "date": date}).Decode(&total)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get send money daily total: %w", err)
+	}
+	return &total, nil
+}
+
+// ReserveDaily 原子地累加下发金额
+//
+// 通过在过滤条件中限定 total 上限实现「检查 + 累加」的原子性：
+// 已有记录不满足条件时 upsert 会因唯一索引冲突失败，视为超出限额。
+func (r *MongoSendMoneyRepository) ReserveDaily(ctx context.Context, chatID int64, date string, amount, limit float64) (*models.SendMoneyDailyTotal, bool, error) {
+	if limit > 0 && amount > limit+sendMoneyAmountEpsilon {
+		current, err := r.GetDailyTotal(ctx, chatID, date)
+		return current, false, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"chat_id": chatID, "date": date}
+	if limit > 0 {
+		filter["total"] = bson.M{"$lte": limit - amount + sendMoneyAmountEpsilon}
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			"total": amount,
+			"count": 1,
+		},
+		"$set": bson.M{
+			"updated_at": now,
+		},
+		"$setOnInsert": bson.M{
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var total models.SendMoneyDailyTotal
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&total)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			current, getErr := r.GetDailyTotal(ctx, chatID, date)
+			return current, false, getErr
+		}
+		return nil, false, fmt.Errorf("failed to reserve send money daily total: %w", err)
+	}
+
+	return &total, true, nil
+}
+
+// ReleaseDaily 回退已预占的下发金额
+func (r *MongoSendMoneyRepository) ReleaseDaily(ctx context.Context, chatID int64, date string, amount float64) error {
+	filter := bson.M{"chat_id": chatID, "date": date}
+	update := bson.M{
+		"$inc": bson.M{
+			"total": -amount,
+			"count": -1,
+		},
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release send money daily total: %w", err)
+	}
+	return nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoSendMoneyRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chat_id", Value: 1},
+				{Key: "date", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "updated_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(sendMoneyTotalRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("create send money indexes: %w", err)
+	}
+	return nil
+}
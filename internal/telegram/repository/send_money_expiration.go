@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sendMoneyExpirationRetention 过期记录在到期后仍保留的时长，超过后由 TTL 索引清理
+const sendMoneyExpirationRetention = 24 * time.Hour
+
+// MongoSendMoneyExpirationRepository 待确认下发过期记录数据访问层（MongoDB 实现）
+type MongoSendMoneyExpirationRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoSendMoneyExpirationRepository 创建仓储实例
+func NewMongoSendMoneyExpirationRepository(db *mongo.Database) SendMoneyExpirationRepository {
+	return &MongoSendMoneyExpirationRepository{
+		collection: db.Collection("send_money_expirations"),
+	}
+}
+
+// Save 按 token 写入或覆盖过期记录
+func (r *MongoSendMoneyExpirationRepository) Save(ctx context.Context, record *models.SendMoneyExpiration) error {
+	if record == nil || record.Token == "" {
+		return errors.New("send money expiration token is required")
+	}
+
+	filter := bson.M{"token": record.Token}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.collection.ReplaceOne(ctx, filter, record, opts); err != nil {
+		return fmt.Errorf("failed to save send money expiration: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除过期记录，返回记录删除前是否存在
+func (r *MongoSendMoneyExpirationRepository) Delete(ctx context.Context, token string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"token": token})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete send money expiration: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// ListDue 列出已到期的记录
+func (r *MongoSendMoneyExpirationRepository) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.SendMoneyExpiration, error) {
+	filter := bson.M{"expires_at": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due send money expirations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []*models.SendMoneyExpiration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode send money expirations: %w", err)
+	}
+	return records, nil
+}
+
+// EnsureIndexes 创建需要的索引
+func (r *MongoSendMoneyExpirationRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(sendMoneyExpirationRetention.Seconds())),
+		},
+	}
+
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("create send money expiration indexes: %w", err)
+	}
+	return nil
+}
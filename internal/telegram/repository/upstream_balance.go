@@ -131,6 +131,10 @@ func (r *MongoUpstreamBalanceRepository) Adjust(
 		if isTransactionNotSupported(err) {
 			return r.adjustWithoutTransaction(ctx, groupID, delta, operatorID, remark, opType, operationID, metadata)
 		}
+		// 并发的同一 operation_id：另一笔已提交，本事务回滚，按已处理返回当前余额
+		if operationID != "" && mongo.IsDuplicateKeyError(err) {
+			return r.Get(ctx, groupID)
+		}
 		return nil, fmt.Errorf("balance adjust transaction failed: %w", err)
 	}
 
@@ -195,6 +199,13 @@ func (r *MongoUpstreamBalanceRepository) adjustWithoutTransaction(
 	}
 
 	if _, err := r.logColl.InsertOne(ctx, logEntry); err != nil {
+		// 无事务时并发的同一 operation_id 会都通过前置检查，由唯一索引兜底：撤销本次增量
+		if operationID != "" && mongo.IsDuplicateKeyError(err) {
+			if _, revertErr := r.balanceColl.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"balance": -delta}}); revertErr != nil {
+				return nil, fmt.Errorf("revert duplicate balance adjust failed (non-txn): %w", revertErr)
+			}
+			return r.Get(ctx, groupID)
+		}
 		return nil, fmt.Errorf("insert balance log failed (non-txn): %w", err)
 	}
 
@@ -202,16 +213,17 @@ func (r *MongoUpstreamBalanceRepository) adjustWithoutTransaction(
 }
 
 // SetMinBalance 更新最低余额阈值并写入日志
-func (r *MongoUpstreamBalanceRepository) SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64) (*models.UpstreamBalance, error) {
-	return r.updateSettings(ctx, groupID, bson.M{"min_balance": threshold}, operatorID, models.BalanceOpSetMinBalance, fmt.Sprintf("设置最低余额 %.2f", threshold))
+func (r *MongoUpstreamBalanceRepository) SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64, operationID string) (*models.UpstreamBalance, error) {
+	return r.updateSettings(ctx, groupID, bson.M{"min_balance": threshold}, operatorID, models.BalanceOpSetMinBalance, fmt.Sprintf("设置最低余额 %.2f", threshold), operationID)
 }
 
 // SetAlertLimit 更新告警频率并写入日志
 func (r *MongoUpstreamBalanceRepository) SetAlertLimit(ctx context.Context, groupID int64, limit int, operatorID int64) (*models.UpstreamBalance, error) {
-	return r.updateSettings(ctx, groupID, bson.M{"alert_limit_per_hour": limit}, operatorID, models.BalanceOpAlertLimit, fmt.Sprintf("设置告警频率 %d/h", limit))
+	return r.updateSettings(ctx, groupID, bson.M{"alert_limit_per_hour": limit}, operatorID, models.BalanceOpAlertLimit, fmt.Sprintf("设置告警频率 %d/h", limit), "")
 }
 
-func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, groupID int64, setFields bson.M, operatorID int64, opType models.BalanceOperationType, remark string) (*models.UpstreamBalance, error) {
+// updateSettings 更新阈值类字段并写日志；operationID 对应的日志已存在时只更新字段、不再重复写日志
+func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, groupID int64, setFields bson.M, operatorID int64, opType models.BalanceOperationType, remark string, operationID string) (*models.UpstreamBalance, error) {
 	client := r.balanceColl.Database().Client()
 	session, err := client.StartSession()
 	if err != nil {
@@ -221,6 +233,13 @@ func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, gro
 
 	txnOpts := options.Transaction().SetWriteConcern(writeconcern.Majority())
 	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		logged := false
+		if operationID != "" {
+			if existing, err := r.findLogByOperation(sc, groupID, operationID); err == nil && existing != nil {
+				logged = true
+			}
+		}
+
 		now := time.Now()
 		filter := balanceFilter(groupID)
 		update := bson.M{
@@ -241,15 +260,19 @@ func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, gro
 		if err := r.balanceColl.FindOneAndUpdate(sc, filter, update, opts).Decode(&balance); err != nil {
 			return nil, fmt.Errorf("update balance settings failed: %w", err)
 		}
+		if logged {
+			return &balance, nil
+		}
 
 		logEntry := &models.UpstreamBalanceLog{
-			GroupID:    groupID,
-			OperatorID: operatorID,
-			Delta:      0,
-			Balance:    balance.Balance,
-			Type:       opType,
-			Remark:     remark,
-			CreatedAt:  now,
+			GroupID:     groupID,
+			OperatorID:  operatorID,
+			Delta:       0,
+			Balance:     balance.Balance,
+			Type:        opType,
+			Remark:      remark,
+			OperationID: operationID,
+			CreatedAt:   now,
 		}
 		if _, err := r.logColl.InsertOne(sc, logEntry); err != nil {
 			return nil, fmt.Errorf("insert balance log failed: %w", err)
@@ -260,7 +283,10 @@ func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, gro
 
 	if err != nil {
 		if isTransactionNotSupported(err) {
-			return r.updateSettingsWithoutTransaction(ctx, groupID, setFields, operatorID, opType, remark)
+			return r.updateSettingsWithoutTransaction(ctx, groupID, setFields, operatorID, opType, remark, operationID)
+		}
+		if operationID != "" && mongo.IsDuplicateKeyError(err) {
+			return r.Get(ctx, groupID)
 		}
 		return nil, fmt.Errorf("balance settings transaction failed: %w", err)
 	}
@@ -272,7 +298,14 @@ func (r *MongoUpstreamBalanceRepository) updateSettings(ctx context.Context, gro
 	return balance, nil
 }
 
-func (r *MongoUpstreamBalanceRepository) updateSettingsWithoutTransaction(ctx context.Context, groupID int64, setFields bson.M, operatorID int64, opType models.BalanceOperationType, remark string) (*models.UpstreamBalance, error) {
+func (r *MongoUpstreamBalanceRepository) updateSettingsWithoutTransaction(ctx context.Context, groupID int64, setFields bson.M, operatorID int64, opType models.BalanceOperationType, remark string, operationID string) (*models.UpstreamBalance, error) {
+	logged := false
+	if operationID != "" {
+		if existing, err := r.findLogByOperation(ctx, groupID, operationID); err == nil && existing != nil {
+			logged = true
+		}
+	}
+
 	now := time.Now()
 	filter := balanceFilter(groupID)
 	update := bson.M{
@@ -293,17 +326,25 @@ func (r *MongoUpstreamBalanceRepository) updateSettingsWithoutTransaction(ctx co
 	if err := r.balanceColl.FindOneAndUpdate(ctx, filter, update, opts).Decode(&balance); err != nil {
 		return nil, fmt.Errorf("update balance settings failed (non-txn): %w", err)
 	}
+	if logged {
+		return &balance, nil
+	}
 
 	logEntry := &models.UpstreamBalanceLog{
-		GroupID:    groupID,
-		OperatorID: operatorID,
-		Delta:      0,
-		Balance:    balance.Balance,
-		Type:       opType,
-		Remark:     remark,
-		CreatedAt:  now,
+		GroupID:     groupID,
+		OperatorID:  operatorID,
+		Delta:       0,
+		Balance:     balance.Balance,
+		Type:        opType,
+		Remark:      remark,
+		OperationID: operationID,
+		CreatedAt:   now,
 	}
 	if _, err := r.logColl.InsertOne(ctx, logEntry); err != nil {
+		// 设置类字段重复写入同值无副作用，只需丢弃重复日志
+		if operationID != "" && mongo.IsDuplicateKeyError(err) {
+			return &balance, nil
+		}
 		return nil, fmt.Errorf("insert balance log failed (non-txn): %w", err)
 	}
 
@@ -15,12 +15,14 @@ import (
 // MongoUserRepository 用户数据访问层（MongoDB 实现）
 type MongoUserRepository struct {
 	collection *mongo.Collection
+	purged     *mongo.Collection
 }
 
 // NewMongoUserRepository 创建用户 Repository
 func NewMongoUserRepository(db *mongo.Database) UserRepository {
 	return &MongoUserRepository{
 		collection: db.Collection("users"),
+		purged:     db.Collection("purged_users"),
 	}
 }
 
@@ -105,13 +107,60 @@ func (r *MongoUserRepository) UpdateLastActive(ctx context.Context, telegramID i
 	return nil
 }
 
+// UpdatePreferredLanguage 更新用户偏好语言
+func (r *MongoUserRepository) UpdatePreferredLanguage(ctx context.Context, telegramID int64, lang string) error {
+	filter := bson.M{"telegram_id": telegramID}
+	update := bson.M{
+		"$set": bson.M{
+			"preferred_language": lang,
+			"updated_at":         time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update preferred language: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found: %d", telegramID)
+	}
+	return nil
+}
+
+// SetStartSource 记录首次深链接来源（仅在尚未记录时写入，保留首次归因）
+func (r *MongoUserRepository) SetStartSource(ctx context.Context, telegramID int64, source string) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"telegram_id":  telegramID,
+		"start_source": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"start_source":    source,
+			"start_source_at": now,
+			"updated_at":      now,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to set start source: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
 // GrantAdmin 授予管理员权限
 func (r *MongoUserRepository) GrantAdmin(ctx context.Context, telegramID int64, grantedBy int64) error {
+	return r.GrantRole(ctx, telegramID, models.RoleAdmin, grantedBy)
+}
+
+// GrantRole 设置管理角色（admin/super_admin）并记录授予者
+func (r *MongoUserRepository) GrantRole(ctx context.Context, telegramID int64, role string, grantedBy int64) error {
 	now := time.Now()
 	filter := bson.M{"telegram_id": telegramID}
 	update := bson.M{
 		"$set": bson.M{
-			"role":       models.RoleAdmin,
+			"role":       role,
 			"granted_by": grantedBy,
 			"granted_at": now,
 			"updated_at": now,
@@ -120,7 +169,7 @@ func (r *MongoUserRepository) GrantAdmin(ctx context.Context, telegramID int64,
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to grant admin: %w", err)
+		return fmt.Errorf("failed to grant role %s: %w", role, err)
 	}
 	if result.MatchedCount == 0 {
 		return fmt.Errorf("user not found: %d", telegramID)
@@ -137,8 +186,9 @@ func (r *MongoUserRepository) RevokeAdmin(ctx context.Context, telegramID int64)
 			"updated_at": time.Now(),
 		},
 		"$unset": bson.M{
-			"granted_by": "",
-			"granted_at": "",
+			"granted_by":  "",
+			"granted_at":  "",
+			"permissions": "",
 		},
 	}
 
@@ -152,11 +202,32 @@ func (r *MongoUserRepository) RevokeAdmin(ctx context.Context, telegramID int64)
 	return nil
 }
 
+// UpdatePermissions 覆盖用户额外授予的权限列表，为空时删除字段
+func (r *MongoUserRepository) UpdatePermissions(ctx context.Context, telegramID int64, permissions []string) error {
+	filter := bson.M{"telegram_id": telegramID}
+	set := bson.M{"updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if len(permissions) == 0 {
+		update["$unset"] = bson.M{"permissions": ""}
+	} else {
+		set["permissions"] = permissions
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update permissions: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found: %d", telegramID)
+	}
+	return nil
+}
+
 // ListAdmins 列出所有管理员
 func (r *MongoUserRepository) ListAdmins(ctx context.Context) ([]*models.User, error) {
 	filter := bson.M{
 		"role": bson.M{
-			"$in": []string{models.RoleOwner, models.RoleAdmin},
+			"$in": models.AdminRoles,
 		},
 	}
 
@@ -174,11 +245,146 @@ func (r *MongoUserRepository) ListAdmins(ctx context.Context) ([]*models.User, e
 	return admins, nil
 }
 
+// ListAdminsPage 分页列出指定角色的管理员；Owner 没有授予时间，按 granted_at 升序时排在最前
+func (r *MongoUserRepository) ListAdminsPage(ctx context.Context, roles []string, skip, limit int64) ([]*models.User, int64, error) {
+	filter := bson.M{"role": bson.M{"$in": roles}}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "granted_at", Value: 1}, {Key: "telegram_id", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list admins: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var admins []*models.User
+	if err := cursor.All(ctx, &admins); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode admins: %w", err)
+	}
+	return admins, total, nil
+}
+
 // GetUserInfo 获取用户完整信息（同 GetByTelegramID，用于语义区分）
 func (r *MongoUserRepository) GetUserInfo(ctx context.Context, telegramID int64) (*models.User, error) {
 	return r.GetByTelegramID(ctx, telegramID)
 }
 
+// inactiveUserFilter 最后活跃早于 cutoff 的普通用户，管理员与 Owner 永不命中
+func inactiveUserFilter(cutoff time.Time) bson.M {
+	return bson.M{
+		"role":           bson.M{"$nin": models.AdminRoles},
+		"last_active_at": bson.M{"$lt": cutoff},
+	}
+}
+
+// CountInactiveBefore 统计不活跃的普通用户数量
+func (r *MongoUserRepository) CountInactiveBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, inactiveUserFilter(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count inactive users: %w", err)
+	}
+	return count, nil
+}
+
+// ListInactiveBefore 列出不活跃的普通用户（最久未活跃的在前）
+func (r *MongoUserRepository) ListInactiveBefore(ctx context.Context, cutoff time.Time, limit int64) ([]*models.User, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_active_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, inactiveUserFilter(cutoff), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inactive users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode inactive users: %w", err)
+	}
+	return users, nil
+}
+
+// purgeInactiveBatchSize 清理不活跃用户时每批存档并删除的用户数
+const purgeInactiveBatchSize = 500
+
+// PurgeInactiveBefore 按游标分批清理不活跃用户：每批先写入 purged_users 存档，再按存档成功的 ID 删除，
+// 用户量很大时也不会一次性加载到内存；删除时再次带上角色条件，期间被授予管理员的用户不会被删除。
+// 中途失败时返回已清理的数量与错误，已完成的批次不回滚
+func (r *MongoUserRepository) PurgeInactiveBefore(ctx context.Context, cutoff time.Time, purgedBy int64) (int64, error) {
+	opts := options.Find().SetBatchSize(purgeInactiveBatchSize)
+	cursor, err := r.collection.Find(ctx, inactiveUserFilter(cutoff), opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list inactive users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	var purged int64
+	batch := make([]models.User, 0, purgeInactiveBatchSize)
+	for cursor.Next(ctx) {
+		var user models.User
+		if err := cursor.Decode(&user); err != nil {
+			return purged, fmt.Errorf("failed to decode inactive user: %w", err)
+		}
+		batch = append(batch, user)
+		if len(batch) < purgeInactiveBatchSize {
+			continue
+		}
+		deleted, err := r.purgeUserBatch(ctx, batch, cutoff, purgedBy, now)
+		purged += deleted
+		if err != nil {
+			return purged, err
+		}
+		batch = batch[:0]
+	}
+	if err := cursor.Err(); err != nil {
+		return purged, fmt.Errorf("failed to iterate inactive users: %w", err)
+	}
+
+	deleted, err := r.purgeUserBatch(ctx, batch, cutoff, purgedBy, now)
+	return purged + deleted, err
+}
+
+// purgeUserBatch 存档并删除一批用户，返回实际删除的数量
+func (r *MongoUserRepository) purgeUserBatch(ctx context.Context, users []models.User, cutoff time.Time, purgedBy int64, now time.Time) (int64, error) {
+	if len(users) == 0 {
+		return 0, nil
+	}
+
+	archives := make([]interface{}, 0, len(users))
+	ids := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		archives = append(archives, models.PurgedUser{
+			User:     user,
+			Cutoff:   cutoff,
+			PurgedBy: purgedBy,
+			PurgedAt: now,
+		})
+		ids = append(ids, user.ID)
+	}
+
+	if _, err := r.purged.InsertMany(ctx, archives); err != nil {
+		return 0, fmt.Errorf("failed to archive inactive users: %w", err)
+	}
+
+	filter := inactiveUserFilter(cutoff)
+	filter["_id"] = bson.M{"$in": ids}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete inactive users: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
 // EnsureIndexes 确保索引存在（ttlSeconds 参数保留用于接口一致性，User 不需要 TTL）
 func (r *MongoUserRepository) EnsureIndexes(ctx context.Context, ttlSeconds int32) error {
 	indexes := []mongo.IndexModel{
@@ -192,6 +398,10 @@ func (r *MongoUserRepository) EnsureIndexes(ctx context.Context, ttlSeconds int3
 		{
 			Keys: bson.D{{Key: "last_active_at", Value: -1}},
 		},
+		{
+			Keys:    bson.D{{Key: "start_source", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
@@ -199,5 +409,14 @@ func (r *MongoUserRepository) EnsureIndexes(ctx context.Context, ttlSeconds int3
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	// 清理存档作为审计记录长期保存，不设 TTL
+	_, err = r.purged.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user.telegram_id", Value: 1}}},
+		{Keys: bson.D{{Key: "purged_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create purged user indexes: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,228 @@
+package telegram
+
+import (
+	"context"
+	"html"
+	"sync/atomic"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+)
+
+const (
+	// scheduledMessageMaxWait 调度器单次最长等待时间，兜底感知其他实例写入的定时消息
+	scheduledMessageMaxWait = 10 * time.Minute
+	// scheduledMessageBatchSize 单次处理的到期消息数量
+	scheduledMessageBatchSize = 50
+	// scheduledMessageTimeout 单次数据库/发送操作超时
+	scheduledMessageTimeout = 10 * time.Second
+)
+
+// scheduledMessageScheduler 按库中记录的下一次触发时间发送群组定时消息，重启后从库恢复调度
+type scheduledMessageScheduler struct {
+	service service.ScheduledMessageService
+	send    func(ctx context.Context, message *models.ScheduledMessage) error
+	// unadvanced 已发送但推进失败的消息 ID → 已发送的触发时间，重试推进时不再重复发送；只在调度协程内访问
+	unadvanced map[string]time.Time
+	cancel     context.CancelFunc
+	done       chan struct{}
+	wake       chan struct{}
+	running    atomic.Bool
+}
+
+func newScheduledMessageScheduler(svc service.ScheduledMessageService, send func(ctx context.Context, message *models.ScheduledMessage) error) *scheduledMessageScheduler {
+	return &scheduledMessageScheduler{
+		service:    svc,
+		send:       send,
+		unadvanced: make(map[string]time.Time),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (s *scheduledMessageScheduler) start() {
+	if s == nil || s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	s.running.Store(true)
+	go s.run(ctx)
+	logger.L().Info("Scheduled message scheduler started")
+}
+
+// isRunning 调度器是否处于运行状态
+func (s *scheduledMessageScheduler) isRunning() bool {
+	return s != nil && s.running.Load()
+}
+
+func (s *scheduledMessageScheduler) stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+	s.running.Store(false)
+	logger.L().Info("Scheduled message scheduler stopped")
+}
+
+// notify 定时消息新增或删除后唤醒调度器重新计算等待时间
+func (s *scheduledMessageScheduler) notify() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduledMessageScheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		s.dispatch(ctx, time.Now())
+
+		wait := s.nextWait(ctx, time.Now())
+		timer := time.NewTimer(wait)
+		logger.Ctx(ctx).Debugf("Scheduled message scheduler waiting %s", wait.String())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// nextWait 计算距离最早一条定时消息的等待时间，最长不超过 scheduledMessageMaxWait
+func (s *scheduledMessageScheduler) nextWait(ctx context.Context, now time.Time) time.Duration {
+	queryCtx, cancel := context.WithTimeout(ctx, scheduledMessageTimeout)
+	defer cancel()
+
+	next, err := s.service.NextRunAt(queryCtx)
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Scheduled message scheduler failed to query next run: %v", err)
+		return time.Minute
+	}
+	if next == nil {
+		return scheduledMessageMaxWait
+	}
+
+	wait := next.Sub(now)
+	if wait <= 0 {
+		return time.Second
+	}
+	if wait > scheduledMessageMaxWait {
+		return scheduledMessageMaxWait
+	}
+	return wait
+}
+
+// dispatch 发送所有已到期的定时消息并推进下一次触发时间；
+// 超过 scheduleDueWindow 的积压（例如 Bot 停机期间错过的）只推进不补发；
+// 发送前在库中认领本次触发，多实例部署时已被其他实例认领的只推进不发送；
+// 发送成功但推进失败的消息记入 unadvanced，下一轮只重试推进，避免同一次触发重复发送
+func (s *scheduledMessageScheduler) dispatch(ctx context.Context, now time.Time) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, scheduledMessageTimeout)
+	due, err := s.service.ListDue(listCtx, now, scheduledMessageBatchSize)
+	cancel()
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Scheduled message scheduler failed to list due messages: %v", err)
+		return 0
+	}
+
+	s.pruneUnadvanced(now)
+
+	sentCount := 0
+	for _, message := range due {
+		if ctx.Err() != nil {
+			return sentCount
+		}
+
+		id := message.ID.Hex()
+		sent := false
+		if sentAt, ok := s.unadvanced[id]; ok && sentAt.Equal(message.NextRunAt) {
+			sent = true
+			logger.Ctx(ctx).Infof("Scheduled message already sent, retrying advance: chat_id=%d id=%s", message.ChatID, id)
+		} else if now.Sub(message.NextRunAt) > scheduleDueWindow {
+			logger.Ctx(ctx).Warnf("Scheduled message skipped (missed window): chat_id=%d id=%s due=%s",
+				message.ChatID, message.ID.Hex(), message.NextRunAt.Format(time.RFC3339))
+		} else if claimed, err := s.claim(ctx, message, now); err != nil {
+			// 认领失败时不发送也不推进，下一轮重试
+			logger.Ctx(ctx).Errorf("Scheduled message failed to claim run: chat_id=%d id=%s err=%v", message.ChatID, id, err)
+			continue
+		} else if !claimed {
+			logger.Ctx(ctx).Infof("Scheduled message run claimed by another instance, skipping send: chat_id=%d id=%s", message.ChatID, id)
+		} else {
+			sendCtx, cancelSend := context.WithTimeout(ctx, scheduledMessageTimeout)
+			if err := s.send(sendCtx, message); err != nil {
+				logger.Ctx(ctx).Errorf("Scheduled message send failed: chat_id=%d id=%s err=%v", message.ChatID, message.ID.Hex(), err)
+			} else {
+				sent = true
+				sentCount++
+			}
+			cancelSend()
+		}
+
+		advanceCtx, cancelAdvance := context.WithTimeout(ctx, scheduledMessageTimeout)
+		if err := s.service.Advance(advanceCtx, message, now, sent); err != nil {
+			logger.Ctx(ctx).Errorf("Scheduled message failed to advance: chat_id=%d id=%s err=%v", message.ChatID, id, err)
+			if sent {
+				s.unadvanced[id] = message.NextRunAt
+			}
+		} else {
+			delete(s.unadvanced, id)
+		}
+		cancelAdvance()
+	}
+
+	if len(due) > 0 {
+		logger.Ctx(ctx).Infof("Scheduled message dispatch processed %d messages (sent=%d)", len(due), sentCount)
+	}
+	return sentCount
+}
+
+// claim 在库中认领本次触发
+func (s *scheduledMessageScheduler) claim(ctx context.Context, message *models.ScheduledMessage, now time.Time) (bool, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, scheduledMessageTimeout)
+	defer cancel()
+	return s.service.ClaimRun(claimCtx, message, now)
+}
+
+// pruneUnadvanced 丢弃超出补发窗口的推进失败记录：此后到期消息只推进不补发，无需再防重复
+func (s *scheduledMessageScheduler) pruneUnadvanced(now time.Time) {
+	for id, sentAt := range s.unadvanced {
+		if now.Sub(sentAt) > scheduleDueWindow {
+			delete(s.unadvanced, id)
+		}
+	}
+}
+
+// sendScheduledMessage 以纯文本发送定时消息
+func (b *Bot) sendScheduledMessage(ctx context.Context, message *models.ScheduledMessage) error {
+	_, err := b.sendMessageWithMarkupAndMessage(ctx, message.ChatID, html.EscapeString(message.Text), nil)
+	return err
+}
+
+func (b *Bot) initScheduledMessageScheduler() {
+	if b.scheduledMessages == nil {
+		logger.L().Warn("Scheduled message scheduler not started: service unavailable")
+		return
+	}
+	scheduler := newScheduledMessageScheduler(b.scheduledMessages, b.sendScheduledMessage)
+	b.scheduledMessageScheduler = scheduler
+	scheduler.start()
+}
@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScheduledMessageSchedulerDispatch(t *testing.T) {
+	now := time.Date(2024, 11, 20, 9, 0, 20, 0, time.UTC)
+	onTime := &models.ScheduledMessage{ID: primitive.NewObjectID(), ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "09:00", Timezone: "UTC", NextRunAt: now.Add(-20 * time.Second)}
+	missed := &models.ScheduledMessage{ID: primitive.NewObjectID(), ChatID: -2, Kind: models.ScheduleKindDaily, Spec: "08:00", Timezone: "UTC", NextRunAt: now.Add(-time.Hour)}
+	failing := &models.ScheduledMessage{ID: primitive.NewObjectID(), ChatID: -3, Kind: models.ScheduleKindDaily, Spec: "09:00", Timezone: "UTC", NextRunAt: now.Add(-20 * time.Second)}
+	svc := &fakeScheduledMessageService{due: []*models.ScheduledMessage{onTime, missed, failing}}
+
+	var sentTo []int64
+	scheduler := newScheduledMessageScheduler(svc, func(ctx context.Context, message *models.ScheduledMessage) error {
+		if message.ChatID == -3 {
+			return errors.New("chat not found")
+		}
+		sentTo = append(sentTo, message.ChatID)
+		return nil
+	})
+
+	if sent := scheduler.dispatch(context.Background(), now); sent != 1 {
+		t.Fatalf("expected 1 message sent, got %d", sent)
+	}
+	if len(sentTo) != 1 || sentTo[0] != -1 {
+		t.Fatalf("expected only the on-time message to be sent, got %v", sentTo)
+	}
+	if len(svc.advanced) != 3 {
+		t.Fatalf("expected every due message to be advanced, got %d", len(svc.advanced))
+	}
+	if !svc.advanced[onTime.ID.Hex()] || svc.advanced[missed.ID.Hex()] || svc.advanced[failing.ID.Hex()] {
+		t.Fatalf("unexpected sent flags: %v", svc.advanced)
+	}
+}
+
+func TestScheduledMessageSchedulerDoesNotResendWhenAdvanceFails(t *testing.T) {
+	now := time.Date(2024, 11, 20, 9, 0, 20, 0, time.UTC)
+	message := &models.ScheduledMessage{ID: primitive.NewObjectID(), ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "09:00", Timezone: "UTC", NextRunAt: now.Add(-20 * time.Second)}
+	svc := &fakeScheduledMessageService{due: []*models.ScheduledMessage{message}, advanceErr: errors.New("db down")}
+
+	sends := 0
+	scheduler := newScheduledMessageScheduler(svc, func(ctx context.Context, message *models.ScheduledMessage) error {
+		sends++
+		return nil
+	})
+
+	// 推进失败时消息仍到期，后续轮次只重试推进
+	for i := 0; i < 3; i++ {
+		scheduler.dispatch(context.Background(), now.Add(time.Duration(i)*time.Second))
+	}
+	if sends != 1 {
+		t.Fatalf("expected a single send while advance keeps failing, got %d", sends)
+	}
+
+	svc.advanceErr = nil
+	scheduler.dispatch(context.Background(), now.Add(5*time.Second))
+	if sends != 1 || !svc.advanced[message.ID.Hex()] {
+		t.Fatalf("expected retried advance to report sent without resending, sends=%d advanced=%v", sends, svc.advanced)
+	}
+	if len(scheduler.unadvanced) != 0 {
+		t.Fatalf("expected pending advance to be cleared, got %v", scheduler.unadvanced)
+	}
+
+	// 下一次触发照常发送
+	message.NextRunAt = now.Add(24*time.Hour - 20*time.Second)
+	scheduler.dispatch(context.Background(), now.Add(24*time.Hour))
+	if sends != 2 {
+		t.Fatalf("expected next occurrence to be sent, got %d sends", sends)
+	}
+}
+
+func TestScheduledMessageSchedulerSkipsRunClaimedElsewhere(t *testing.T) {
+	now := time.Date(2024, 11, 20, 9, 0, 20, 0, time.UTC)
+	message := &models.ScheduledMessage{ID: primitive.NewObjectID(), ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "09:00", Timezone: "UTC", NextRunAt: now.Add(-20 * time.Second)}
+	svc := &fakeScheduledMessageService{due: []*models.ScheduledMessage{message}, claimErr: errors.New("db down")}
+
+	sends := 0
+	scheduler := newScheduledMessageScheduler(svc, func(ctx context.Context, message *models.ScheduledMessage) error {
+		sends++
+		return nil
+	})
+
+	// 认领失败：不发送也不推进，留给下一轮
+	scheduler.dispatch(context.Background(), now)
+	if sends != 0 || len(svc.advanced) != 0 {
+		t.Fatalf("expected no send or advance when claim fails, sends=%d advanced=%v", sends, svc.advanced)
+	}
+
+	// 其他实例已认领：只推进不发送
+	svc.claimErr = nil
+	svc.claimed = map[string]bool{message.ID.Hex() + "@" + message.NextRunAt.Format(time.RFC3339): true}
+	scheduler.dispatch(context.Background(), now)
+	if sends != 0 {
+		t.Fatalf("expected run claimed elsewhere not to be sent, got %d sends", sends)
+	}
+	if sent, ok := svc.advanced[message.ID.Hex()]; !ok || sent {
+		t.Fatalf("expected message to be advanced without sent flag, got %v", svc.advanced)
+	}
+}
+
+func TestScheduledMessageSchedulerNextWait(t *testing.T) {
+	now := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	svc := &fakeScheduledMessageService{}
+	scheduler := newScheduledMessageScheduler(svc, nil)
+
+	if wait := scheduler.nextWait(context.Background(), now); wait != scheduledMessageMaxWait {
+		t.Fatalf("expected max wait without schedules, got %s", wait)
+	}
+
+	next := now.Add(90 * time.Second)
+	svc.next = &next
+	if wait := scheduler.nextWait(context.Background(), now); wait != 90*time.Second {
+		t.Fatalf("expected wait until next run, got %s", wait)
+	}
+
+	past := now.Add(-time.Minute)
+	svc.next = &past
+	if wait := scheduler.nextWait(context.Background(), now); wait != time.Second {
+		t.Fatalf("expected short wait for overdue run, got %s", wait)
+	}
+}
+
+func TestParseScheduleAddCommand(t *testing.T) {
+	kind, spec, text, ok := parseScheduleAddCommand("/schedule_add 09：30 早安\n今日要点")
+	if !ok || kind != models.ScheduleKindDaily || spec != "09:30" || text != "早安\n今日要点" {
+		t.Fatalf("unexpected daily parse: kind=%q spec=%q text=%q ok=%v", kind, spec, text, ok)
+	}
+
+	kind, spec, text, ok = parseScheduleAddCommand("/schedule_add cron 0  9 * * 1-5 周报提醒")
+	if !ok || kind != models.ScheduleKindCron || spec != "0 9 * * 1-5" || text != "周报提醒" {
+		t.Fatalf("unexpected cron parse: kind=%q spec=%q text=%q ok=%v", kind, spec, text, ok)
+	}
+
+	for _, input := range []string{"/schedule_add", "/schedule_add 09:00", "/schedule_add cron 0 9 * *", "/schedule_addx 09:00 hi"} {
+		if _, _, _, ok := parseScheduleAddCommand(input); ok {
+			t.Fatalf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestFormatScheduledMessages(t *testing.T) {
+	if text := formatScheduledMessages(nil); !strings.Contains(text, "暂无定时消息") {
+		t.Fatalf("unexpected empty text: %s", text)
+	}
+
+	last := time.Date(2024, 11, 20, 1, 0, 0, 0, time.UTC)
+	messages := []*models.ScheduledMessage{
+		{ID: primitive.NewObjectID(), Kind: models.ScheduleKindDaily, Spec: "09:00", Timezone: "Asia/Shanghai", Text: "早报 <b>", NextRunAt: time.Date(2024, 11, 21, 1, 0, 0, 0, time.UTC), LastRunAt: &last},
+		{ID: primitive.NewObjectID(), Kind: models.ScheduleKindCron, Spec: "0 9 * * 1-5", Timezone: "UTC", Text: "周报", NextRunAt: time.Date(2024, 11, 21, 9, 0, 0, 0, time.UTC)},
+	}
+	text := formatScheduledMessages(messages)
+	for _, want := range []string{
+		"（2/20）",
+		messages[0].ID.Hex(),
+		"每天 09:00（Asia/Shanghai）",
+		"下次：2024-11-21 09:00",
+		"上次：2024-11-20 09:00",
+		"早报 &lt;b&gt;",
+		"cron <code>0 9 * * 1-5</code>（UTC）",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+}
+
+type fakeScheduledMessageService struct {
+	service.ScheduledMessageService
+	due        []*models.ScheduledMessage
+	next       *time.Time
+	advanced   map[string]bool
+	advanceErr error
+	claimed    map[string]bool // 已被认领的 ID@触发时间
+	claimErr   error
+}
+
+func (s *fakeScheduledMessageService) ClaimRun(ctx context.Context, message *models.ScheduledMessage, now time.Time) (bool, error) {
+	if s.claimErr != nil {
+		return false, s.claimErr
+	}
+	key := message.ID.Hex() + "@" + message.NextRunAt.Format(time.RFC3339)
+	if s.claimed[key] {
+		return false, nil
+	}
+	if s.claimed == nil {
+		s.claimed = make(map[string]bool)
+	}
+	s.claimed[key] = true
+	return true, nil
+}
+
+func (s *fakeScheduledMessageService) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error) {
+	return s.due, nil
+}
+
+func (s *fakeScheduledMessageService) NextRunAt(ctx context.Context) (*time.Time, error) {
+	return s.next, nil
+}
+
+func (s *fakeScheduledMessageService) Advance(ctx context.Context, message *models.ScheduledMessage, now time.Time, sent bool) error {
+	if s.advanceErr != nil {
+		return s.advanceErr
+	}
+	if s.advanced == nil {
+		s.advanced = make(map[string]bool)
+	}
+	s.advanced[message.ID.Hex()] = sent
+	return nil
+}
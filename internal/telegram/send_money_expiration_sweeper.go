@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_bot/internal/logger"
+	sifangfeature "go_bot/internal/telegram/features/sifang"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+const (
+	sendMoneySweepInterval  = 30 * time.Second
+	sendMoneySweepBatchSize = 50
+	sendMoneyStoreTimeout   = 3 * time.Second
+)
+
+// sendMoneyExpirationSweeper 周期扫描持久化的下发过期记录，兜底处理重启后丢失的内存定时器
+type sendMoneyExpirationSweeper struct {
+	repo     repository.SendMoneyExpirationRepository
+	expire   func(ctx context.Context, record *models.SendMoneyExpiration)
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	running  atomic.Bool
+}
+
+func newSendMoneyExpirationSweeper(repo repository.SendMoneyExpirationRepository, expire func(ctx context.Context, record *models.SendMoneyExpiration)) *sendMoneyExpirationSweeper {
+	return &sendMoneyExpirationSweeper{
+		repo:     repo,
+		expire:   expire,
+		interval: sendMoneySweepInterval,
+	}
+}
+
+func (s *sendMoneyExpirationSweeper) start() {
+	if s == nil || s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+
+	s.running.Store(true)
+	logger.L().Info("Send money expiration sweeper started")
+}
+
+// isRunning 扫描器是否处于运行状态
+func (s *sendMoneyExpirationSweeper) isRunning() bool {
+	return s != nil && s.running.Load()
+}
+
+func (s *sendMoneyExpirationSweeper) stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.cancel = nil
+	s.running.Store(false)
+	logger.L().Info("Send money expiration sweeper stopped")
+}
+
+func (s *sendMoneyExpirationSweeper) run(ctx context.Context) {
+	// 启动时立即扫描一次，处理重启期间到期的请求
+	s.sweep(ctx, time.Now())
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sweep(ctx, now)
+		}
+	}
+}
+
+// sweep 处理所有已到期的记录：清理内存状态、将消息编辑为失效并删除记录
+func (s *sendMoneyExpirationSweeper) sweep(ctx context.Context, now time.Time) int {
+	listCtx, cancel := context.WithTimeout(ctx, sendMoneyStoreTimeout)
+	records, err := s.repo.ListDue(listCtx, now, sendMoneySweepBatchSize)
+	cancel()
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Send money expiration sweep failed to list records: %v", err)
+		return 0
+	}
+
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return 0
+		}
+		s.expire(ctx, record)
+	}
+
+	if len(records) > 0 {
+		logger.Ctx(ctx).Infof("Send money expiration sweep processed %d records", len(records))
+	}
+	return len(records)
+}
+
+// expireSendMoneyMessage 将待确认下发标记为失效并删除持久化记录。
+// 只有请求确实过期时才编辑提示：仍在有效期内时保留记录待下次扫描，已被确认或取消时只删除记录，
+// 避免覆盖确认提示或「下发成功」结果；内存中没有该请求（重启后丢失）时以持久化记录是否仍存在为准
+func (b *Bot) expireSendMoneyMessage(ctx context.Context, record *models.SendMoneyExpiration) {
+	if record == nil {
+		return
+	}
+
+	if b.sifangFeature != nil && !b.sifangFeature.ExpirePending(record.Token) {
+		if b.sifangFeature.HasPending(record.Token) {
+			return
+		}
+		if b.sifangFeature.IsSettled(record.Token) {
+			b.deleteSendMoneyExpiration(record.Token)
+			return
+		}
+	}
+
+	// 认领持久化记录：记录已被删除说明下发已结束（或已被另一路过期处理），不再编辑
+	if !b.claimSendMoneyExpiration(record.Token) {
+		return
+	}
+
+	editCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	b.editMessage(editCtx, record.ChatID, record.MessageID, sendMoneyExpiredText(record.Review), nil)
+	cancel()
+}
+
+// saveSendMoneyExpiration 持久化待确认下发的过期时间
+func (b *Bot) saveSendMoneyExpiration(record *models.SendMoneyExpiration) {
+	if b.sendMoneyExpirationRepo == nil || record == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendMoneyStoreTimeout)
+	defer cancel()
+	if err := b.sendMoneyExpirationRepo.Save(ctx, record); err != nil {
+		logger.L().Errorf("Failed to persist send money expiration: token=%s err=%v", record.Token, err)
+	}
+}
+
+// deleteSendMoneyExpiration 删除过期记录（下发已结束）
+func (b *Bot) deleteSendMoneyExpiration(token string) {
+	b.claimSendMoneyExpiration(token)
+}
+
+// claimSendMoneyExpiration 删除过期记录并返回记录是否仍存在；未配置存储或删除失败时按存在处理，
+// 保证过期提示不因存储故障丢失
+func (b *Bot) claimSendMoneyExpiration(token string) bool {
+	if b.sendMoneyExpirationRepo == nil || token == "" {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendMoneyStoreTimeout)
+	defer cancel()
+	existed, err := b.sendMoneyExpirationRepo.Delete(ctx, token)
+	if err != nil {
+		logger.L().Warnf("Failed to delete send money expiration: token=%s err=%v", token, err)
+		return true
+	}
+	return existed
+}
+
+// sendMoneyExpiredText 返回下发请求失效提示
+func sendMoneyExpiredText(review bool) string {
+	if review {
+		return fmt.Sprintf("⚠️ 大额下发在 %s 内未集齐两位管理员确认，请求已失效，请重新下发。", formatDuration(sifangfeature.SendMoneyReviewTTL))
+	}
+	return "⚠️ 由于 60 秒内没有操作，下发请求已失效，请重新下发。"
+}
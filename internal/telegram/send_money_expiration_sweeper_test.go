@@ -0,0 +1,142 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sifangfeature "go_bot/internal/telegram/features/sifang"
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestSendMoneyExpirationSweepExpiresDueRecords(t *testing.T) {
+	now := time.Date(2024, 11, 20, 10, 0, 0, 0, time.UTC)
+	repo := &memorySendMoneyExpirationRepository{records: map[string]*models.SendMoneyExpiration{
+		"due":    {Token: "due", ChatID: 1, MessageID: 10, ExpiresAt: now.Add(-time.Second)},
+		"review": {Token: "review", ChatID: 1, MessageID: 11, Review: true, ExpiresAt: now},
+		"later":  {Token: "later", ChatID: 1, MessageID: 12, ExpiresAt: now.Add(time.Minute)},
+	}}
+
+	var expired []string
+	sweeper := newSendMoneyExpirationSweeper(repo, func(ctx context.Context, record *models.SendMoneyExpiration) {
+		expired = append(expired, record.Token)
+		delete(repo.records, record.Token)
+	})
+
+	if got := sweeper.sweep(context.Background(), now); got != 2 {
+		t.Fatalf("expected 2 records swept, got %d", got)
+	}
+	if strings.Join(expired, ",") != "due,review" {
+		t.Fatalf("unexpected expired tokens: %v", expired)
+	}
+	if _, ok := repo.records["later"]; !ok {
+		t.Fatalf("expected future record to be kept")
+	}
+}
+
+func TestSendMoneyExpiredText(t *testing.T) {
+	if text := sendMoneyExpiredText(false); !strings.Contains(text, "60 秒") {
+		t.Fatalf("unexpected text: %s", text)
+	}
+	if text := sendMoneyExpiredText(true); !strings.Contains(text, "5分钟") {
+		t.Fatalf("unexpected review text: %s", text)
+	}
+}
+
+func TestExpireSendMoneyMessageOnlyEditsUnclaimedRecords(t *testing.T) {
+	tgBot, sent := newRecordingTelegramBot(t)
+	repo := &memorySendMoneyExpirationRepository{records: map[string]*models.SendMoneyExpiration{
+		"restart": {Token: "restart", ChatID: 1, MessageID: 10},
+	}}
+	// 重启后内存为空：以持久化记录是否仍存在为准
+	b := &Bot{
+		bot:                     tgBot,
+		sendMoneyExpirationRepo: repo,
+		sifangFeature:           sifangfeature.New(nil, nil, nil, nil),
+	}
+
+	b.expireSendMoneyMessage(context.Background(), &models.SendMoneyExpiration{Token: "restart", ChatID: 1, MessageID: 10})
+	if texts := sent(); len(texts) != 1 || !strings.Contains(texts[0], "已失效") {
+		t.Fatalf("expected persisted record to be expired once, got %q", texts)
+	}
+	if _, ok := repo.records["restart"]; ok {
+		t.Fatalf("expected expiration record to be claimed")
+	}
+
+	// 记录已被确认流程删除（或已被另一路过期处理）时不再编辑
+	b.expireSendMoneyMessage(context.Background(), &models.SendMoneyExpiration{Token: "restart", ChatID: 1, MessageID: 10})
+	if texts := sent(); len(texts) != 1 {
+		t.Fatalf("expected completed record to be left untouched, got %q", texts)
+	}
+}
+
+// newRecordingTelegramBot 创建指向本地假 Telegram API 的 Bot，记录 sendMessage / editMessageText 的文本
+func newRecordingTelegramBot(t *testing.T) (*bot.Bot, func() []string) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		texts []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if method := path.Base(r.URL.Path); method == "sendMessage" || method == "editMessageText" {
+			mu.Lock()
+			texts = append(texts, r.FormValue("text"))
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	tgBot, err := bot.New("test-token", bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatalf("create bot: %v", err)
+	}
+	return tgBot, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), texts...)
+	}
+}
+
+type memorySendMoneyExpirationRepository struct {
+	records map[string]*models.SendMoneyExpiration
+}
+
+func (r *memorySendMoneyExpirationRepository) Save(ctx context.Context, record *models.SendMoneyExpiration) error {
+	r.records[record.Token] = record
+	return nil
+}
+
+func (r *memorySendMoneyExpirationRepository) Delete(ctx context.Context, token string) (bool, error) {
+	_, existed := r.records[token]
+	delete(r.records, token)
+	return existed, nil
+}
+
+func (r *memorySendMoneyExpirationRepository) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.SendMoneyExpiration, error) {
+	var due []*models.SendMoneyExpiration
+	for _, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			due = append(due, record)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ExpiresAt.Before(due[j].ExpiresAt) })
+	if limit > 0 && int64(len(due)) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *memorySendMoneyExpirationRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestSendScheduledPushFollowsGroupSilentSetting(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		silent []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "sendMessage" {
+			mu.Lock()
+			silent = append(silent, r.FormValue("disable_notification"))
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":-1,"type":"group"}}}`)
+	}))
+	defer srv.Close()
+
+	tgBot, err := bot.New("test-token", bot.WithServerURL(srv.URL), bot.WithSkipGetMe())
+	if err != nil {
+		t.Fatalf("create bot: %v", err)
+	}
+	b := &Bot{bot: tgBot}
+	ctx := t.Context()
+
+	loud := &models.Group{TelegramID: -1}
+	quiet := &models.Group{TelegramID: -1, Settings: models.GroupSettings{SilentScheduledPush: true}}
+	for _, group := range []*models.Group{loud, quiet} {
+		if _, err := b.sendScheduledPush(ctx, group, group.TelegramID, "日结报告"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// 普通回复不受群配置影响
+	b.sendMessage(ctx, -1, "普通消息", 5)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"", "true", ""}
+	if len(silent) != len(want) {
+		t.Fatalf("expected %d sends, got %v", len(want), silent)
+	}
+	for i := range want {
+		if silent[i] != want[i] {
+			t.Fatalf("send %d: expected disable_notification=%q, got %q", i, want[i], silent[i])
+		}
+	}
+}
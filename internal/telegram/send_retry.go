@@ -0,0 +1,118 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+
+	"go_bot/internal/logger"
+)
+
+// sendRetryPolicy Telegram 发送重试策略
+type sendRetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次）
+	BaseDelay   time.Duration // 非 429 可重试错误的首次退避间隔，之后指数增长
+	MaxDelay    time.Duration // 单次等待上限，429 要求的 retry_after 超过该值时直接放弃
+	MaxTotal    time.Duration // 累计等待上限，避免调用方（可能持有锁）被长时间阻塞
+}
+
+// defaultSendRetryPolicy 统一发送封装使用的重试策略
+var defaultSendRetryPolicy = sendRetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	MaxTotal:    15 * time.Second,
+}
+
+// telegramServerErrorPattern 匹配 bot 库对未知错误码的包装，用于识别 5xx
+var telegramServerErrorPattern = regexp.MustCompile(`error response from telegram for method \S+, (\d{3}) `)
+
+// sendWithRetry 执行发送，429 按 retry_after 等待、网络错误与 5xx 按指数退避重试
+// 等待时间超过单次上限、累计预算或 ctx 剩余时间时不再等待，直接返回最后一次错误
+func sendWithRetry(ctx context.Context, policy sendRetryPolicy, send func(ctx context.Context) error) error {
+	var (
+		err    error
+		waited time.Duration
+	)
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		err = send(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt == policy.MaxAttempts {
+			return err
+		}
+
+		wait, retryable := sendRetryDelay(err, policy, attempt)
+		if !retryable || wait > policy.MaxDelay || waited+wait > policy.MaxTotal {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		logger.Ctx(ctx).Debugf("Telegram send failed (attempt %d/%d), retrying in %s: %v", attempt, policy.MaxAttempts, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		waited += wait
+	}
+	return err
+}
+
+// sendRetryDelay 判断错误是否可重试并给出等待时间
+func sendRetryDelay(err error, policy sendRetryPolicy, attempt int) (time.Duration, bool) {
+	var tooMany *bot.TooManyRequestsError
+	if errors.As(err, &tooMany) {
+		wait := time.Duration(tooMany.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = time.Second
+		}
+		return wait, true
+	}
+
+	if !isRetryableSendError(err) {
+		return 0, false
+	}
+	wait := policy.BaseDelay << (attempt - 1)
+	if wait > policy.MaxDelay {
+		wait = policy.MaxDelay
+	}
+	return wait, true
+}
+
+// isRetryableSendError 网络错误和 Telegram 5xx 可重试；4xx（无权限、参数错误等）和 ctx 取消不重试
+func isRetryableSendError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var migrate *bot.MigrateError
+	if errors.As(err, &migrate) {
+		return false
+	}
+	for _, target := range []error{bot.ErrorForbidden, bot.ErrorBadRequest, bot.ErrorUnauthorized, bot.ErrorNotFound, bot.ErrorConflict} {
+		if errors.Is(err, target) {
+			return false
+		}
+	}
+
+	message := err.Error()
+	if match := telegramServerErrorPattern.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code >= 500
+	}
+	return strings.Contains(message, "error do request")
+}
@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+var testSendRetryPolicy = sendRetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    time.Second,
+	MaxTotal:    2 * time.Second,
+}
+
+func TestSendWithRetry(t *testing.T) {
+	attempts := 0
+	err := sendWithRetry(context.Background(), testSendRetryPolicy, func(context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 0}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("expected success after one retry, got attempts=%d err=%v", attempts, err)
+	}
+
+	attempts = 0
+	serverErr := errors.New("error response from telegram for method sendMessage, 502 Bad Gateway")
+	err = sendWithRetry(context.Background(), testSendRetryPolicy, func(context.Context) error {
+		attempts++
+		return serverErr
+	})
+	if !errors.Is(err, serverErr) || attempts != 3 {
+		t.Fatalf("expected 5xx to be retried until exhausted, got attempts=%d err=%v", attempts, err)
+	}
+
+	attempts = 0
+	forbidden := fmt.Errorf("%w, bot was kicked from the group chat", bot.ErrorForbidden)
+	err = sendWithRetry(context.Background(), testSendRetryPolicy, func(context.Context) error {
+		attempts++
+		return forbidden
+	})
+	if !errors.Is(err, forbidden) || attempts != 1 {
+		t.Fatalf("expected 4xx error to fail fast, got attempts=%d err=%v", attempts, err)
+	}
+}
+
+func TestSendWithRetryBoundedWait(t *testing.T) {
+	attempts := 0
+	err := sendWithRetry(context.Background(), testSendRetryPolicy, func(context.Context) error {
+		attempts++
+		return &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 30}
+	})
+	if !bot.IsTooManyRequestsError(err) || attempts != 1 {
+		t.Fatalf("expected retry_after beyond max delay to give up immediately, got attempts=%d err=%v", attempts, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	attempts = 0
+	err = sendWithRetry(ctx, testSendRetryPolicy, func(context.Context) error {
+		attempts++
+		return &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 1}
+	})
+	if !bot.IsTooManyRequestsError(err) || attempts != 1 {
+		t.Fatalf("expected wait beyond ctx deadline to give up immediately, got attempts=%d err=%v", attempts, err)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	err = sendWithRetry(canceled, testSendRetryPolicy, func(context.Context) error {
+		return &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 1}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation to stop retries, got %v", err)
+	}
+}
+
+func TestIsRetryableSendError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("error do request for method sendMessage, dial tcp: i/o timeout"), true},
+		{errors.New("error response from telegram for method sendMessage, 500 Internal Server Error"), true},
+		{errors.New("error response from telegram for method sendMessage, 420 Flood"), false},
+		{fmt.Errorf("%w, message is too long", bot.ErrorBadRequest), false},
+		{&bot.MigrateError{Message: "migrated", MigrateToChatID: -100}, false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tc := range cases {
+		if got := isRetryableSendError(tc.err); got != tc.want {
+			t.Fatalf("isRetryableSendError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+const (
+	// sentMessageTTL Bot 发出消息的记录保留时长，与 Telegram 允许 Bot 删除消息的 48 小时窗口一致
+	sentMessageTTL = 48 * time.Hour
+	// sentMessageLimitPerChat 每个群最多保留的记录条数，超出时丢弃最早的记录
+	sentMessageLimitPerChat = 200
+)
+
+// sentMessage 一条 Bot 发出的消息
+type sentMessage struct {
+	id     int
+	sentAt time.Time
+}
+
+// sentMessageLog 按群记录 Bot 通过统一发送封装发出的消息 ID（内存，带 TTL），
+// 供撤回、清理、编辑等判断「是否为本 Bot 的消息」以及查找最近的 Bot 消息
+type sentMessageLog struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	limit int
+	chats map[int64][]sentMessage // 每群按发送时间升序
+}
+
+func newSentMessageLog(ttl time.Duration, limit int) *sentMessageLog {
+	return &sentMessageLog{
+		ttl:   ttl,
+		limit: limit,
+		chats: make(map[int64][]sentMessage),
+	}
+}
+
+// record 记录一条发出的消息
+func (l *sentMessageLog) record(chatID int64, messageID int, now time.Time) {
+	if l == nil || messageID == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := append(l.pruneLocked(chatID, now), sentMessage{id: messageID, sentAt: now})
+	if len(list) > l.limit {
+		list = append([]sentMessage(nil), list[len(list)-l.limit:]...)
+	}
+	l.chats[chatID] = list
+}
+
+// contains 消息是否由 Bot 发出且仍在保留期内
+func (l *sentMessageLog) contains(chatID int64, messageID int, now time.Time) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, msg := range l.pruneLocked(chatID, now) {
+		if msg.id == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+// recent 返回本群最近 n 条 Bot 消息 ID（最新在前）
+func (l *sentMessageLog) recent(chatID int64, n int, now time.Time) []int {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := l.pruneLocked(chatID, now)
+	ids := make([]int, 0, min(n, len(list)))
+	for i := len(list) - 1; i >= 0 && len(ids) < n; i-- {
+		ids = append(ids, list[i].id)
+	}
+	return ids
+}
+
+// forget 删除一条记录（消息已被删除时调用）
+func (l *sentMessageLog) forget(chatID int64, messageID int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := l.chats[chatID]
+	for i, msg := range list {
+		if msg.id == messageID {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(l.chats, chatID)
+		return
+	}
+	l.chats[chatID] = list
+}
+
+// sweep 清理所有群的过期记录，返回清理条数与剩余条数
+func (l *sentMessageLog) sweep(now time.Time) (removed, remaining int) {
+	if l == nil {
+		return 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for chatID, list := range l.chats {
+		kept := l.pruneLocked(chatID, now)
+		removed += len(list) - len(kept)
+		remaining += len(kept)
+	}
+	return removed, remaining
+}
+
+// pruneLocked 丢弃本群已过期的记录并返回剩余列表，调用方需持有锁
+func (l *sentMessageLog) pruneLocked(chatID int64, now time.Time) []sentMessage {
+	list := l.chats[chatID]
+	cutoff := now.Add(-l.ttl)
+	idx := 0
+	for idx < len(list) && !list[idx].sentAt.After(cutoff) {
+		idx++
+	}
+	if idx == 0 {
+		return list
+	}
+	if idx == len(list) {
+		delete(l.chats, chatID)
+		return nil
+	}
+	list = append([]sentMessage(nil), list[idx:]...)
+	l.chats[chatID] = list
+	return list
+}
+
+// recordSentMessage 记录统一发送封装发出的消息
+func (b *Bot) recordSentMessage(msg *botModels.Message) {
+	if msg == nil {
+		return
+	}
+	b.sentMessages.record(msg.Chat.ID, msg.ID, time.Now())
+}
+
+// isBotMessage 判断消息是否由本 Bot 发出且仍在发送记录的保留期内（重启前发出的消息没有记录）
+func (b *Bot) isBotMessage(msg *botModels.Message) bool {
+	if msg == nil {
+		return false
+	}
+	return b.sentMessages.contains(msg.Chat.ID, msg.ID, time.Now())
+}
+
+// latestBotMessageID 返回本群最近一条仍在发送记录中的 Bot 消息 ID
+func (b *Bot) latestBotMessageID(chatID int64) (int, bool) {
+	ids := b.sentMessages.recent(chatID, 1, time.Now())
+	if len(ids) == 0 {
+		return 0, false
+	}
+	return ids[0], true
+}
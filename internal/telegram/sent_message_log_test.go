@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestSentMessageLogRecordsPerChatWithTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := newSentMessageLog(time.Hour, 3)
+
+	log.record(-1, 10, now)
+	log.record(-1, 11, now.Add(30*time.Minute))
+	log.record(-2, 20, now)
+
+	if !log.contains(-1, 10, now) || log.contains(-2, 10, now) {
+		t.Fatalf("expected records to be isolated per chat")
+	}
+	if got := log.recent(-1, 5, now.Add(30*time.Minute)); !slices.Equal(got, []int{11, 10}) {
+		t.Fatalf("expected newest first, got %v", got)
+	}
+
+	later := now.Add(time.Hour)
+	if log.contains(-1, 10, later) {
+		t.Fatalf("expected record to expire after ttl")
+	}
+	if !log.contains(-1, 11, later) {
+		t.Fatalf("expected newer record to remain")
+	}
+
+	removed, remaining := log.sweep(later)
+	if removed != 1 || remaining != 1 {
+		t.Fatalf("expected sweep to drop chat -2 record, got removed=%d remaining=%d", removed, remaining)
+	}
+
+	log.forget(-1, 11)
+	if log.contains(-1, 11, later) {
+		t.Fatalf("expected forgotten record to be removed")
+	}
+}
+
+func TestSentMessageLogKeepsLimitPerChat(t *testing.T) {
+	now := time.Now()
+	log := newSentMessageLog(time.Hour, 3)
+	for id := 1; id <= 5; id++ {
+		log.record(-1, id, now)
+	}
+	if got := log.recent(-1, 10, now); !slices.Equal(got, []int{5, 4, 3}) {
+		t.Fatalf("expected only the latest 3 records, got %v", got)
+	}
+}
+
+func TestIsBotMessageUsesSentLog(t *testing.T) {
+	b := &Bot{sentMessages: newSentMessageLog(sentMessageTTL, sentMessageLimitPerChat)}
+	msg := &botModels.Message{ID: 7, Chat: botModels.Chat{ID: -1}}
+	if b.isBotMessage(msg) {
+		t.Fatalf("expected unknown message not to be treated as bot message")
+	}
+	if _, ok := b.latestBotMessageID(-1); ok {
+		t.Fatalf("expected no latest bot message before recording")
+	}
+
+	b.recordSentMessage(msg)
+	b.recordSentMessage(&botModels.Message{ID: 9, Chat: botModels.Chat{ID: -1}})
+	if !b.isBotMessage(msg) {
+		t.Fatalf("expected recorded message to be treated as bot message")
+	}
+	if id, ok := b.latestBotMessageID(-1); !ok || id != 9 {
+		t.Fatalf("expected latest bot message 9, got %d ok=%v", id, ok)
+	}
+
+	var nilLog *sentMessageLog
+	nilLog.record(-1, 1, time.Now())
+	if nilLog.contains(-1, 1, time.Now()) {
+		t.Fatalf("expected nil log to be a no-op")
+	}
+}
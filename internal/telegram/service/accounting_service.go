@@ -3,14 +3,20 @@ package service
 import (
 	"context"
 	"fmt"
+	"html"
+	"math"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/features/calculator"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // 正则表达式
@@ -21,40 +27,74 @@ var (
 	chinesePattern = regexp.MustCompile(`^(入|出)((?:\d+(?:\.\d+)?)(?:[\+\-\*/]\d+(?:\.\d+)?)*)([UY])?$`)
 )
 
+const (
+	// accountingRateTimeout 账单折算时获取汇率的超时，超时按无汇率处理
+	accountingRateTimeout = 3 * time.Second
+	// accountingRateCacheTTL 汇率缓存时长，多群同时查账时共用一次 OKX 请求
+	accountingRateCacheTTL = time.Minute
+)
+
+// ExchangeRateFunc 返回 1 USDT 折合的人民币金额
+type ExchangeRateFunc func(ctx context.Context) (float64, error)
+
 // AccountingServiceImpl 收支记账服务实现
 type AccountingServiceImpl struct {
 	accountingRepo repository.AccountingRepository
 	groupRepo      repository.GroupRepository
+	rateFunc       ExchangeRateFunc
+
+	rateMu        sync.Mutex
+	cachedRate    float64
+	rateExpiresAt time.Time
 }
 
-// NewAccountingService 创建记账服务
-func NewAccountingService(accountingRepo repository.AccountingRepository, groupRepo repository.GroupRepository) AccountingService {
+// NewAccountingService 创建记账服务，rateFunc 为 nil 时账单不做汇率折算
+func NewAccountingService(accountingRepo repository.AccountingRepository, groupRepo repository.GroupRepository, rateFunc ExchangeRateFunc) AccountingService {
 	return &AccountingServiceImpl{
 		accountingRepo: accountingRepo,
 		groupRepo:      groupRepo,
+		rateFunc:       rateFunc,
 	}
 }
 
-// AddRecord 添加记账记录
-func (s *AccountingServiceImpl) AddRecord(ctx context.Context, chatID, userID int64, input string) error {
-	// 解析输入
+// AddRecord 添加记账记录，单笔金额明显偏离近期均值时在结果中标记（仍然记录）
+func (s *AccountingServiceImpl) AddRecord(ctx context.Context, chatID, userID int64, input string) (*AccountingAddResult, error) {
+	// 解析输入，「#公账 +100」开头的账本标识可选，不带时归默认账本
+	bookName, input := models.SplitAccountingBook(input)
 	isIncome, expression, currency, err := s.parseInput(input)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var book string
+	if bookName != "" {
+		if book, err = models.NormalizeAccountingBook(bookName); err != nil {
+			return nil, err
+		}
 	}
 
 	// 计算表达式
 	amount, err := calculator.Calculate(expression)
 	if err != nil {
-		logger.L().Errorf("Failed to calculate expression %s: %v", expression, err)
-		return fmt.Errorf("计算失败: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to calculate expression %s: %v", expression, err)
+		return nil, fmt.Errorf("计算失败: %v", err)
+	}
+
+	// 按 2 位小数四舍五入后存储，避免 100*7.2 之类的浮点尾数进入账单
+	rounded := models.RoundAccountingAmount(amount)
+	if rounded == 0 && amount != 0 {
+		return nil, fmt.Errorf("金额过小，最小记账单位为 0.01")
 	}
+	amount = rounded
 
 	// 如果是支出，金额为负数
 	if !isIncome {
 		amount = -amount
 	}
 
+	// 写入前取近期均值，避免本笔金额拉高均值
+	result := &AccountingAddResult{}
+	result.Average, result.Ratio = s.anomalyBaseline(ctx, chatID, currency)
+
 	// 创建记录
 	record := &models.AccountingRecord{
 		ChatID:       chatID,
@@ -63,15 +103,84 @@ func (s *AccountingServiceImpl) AddRecord(ctx context.Context, chatID, userID in
 		Currency:     currency,
 		OriginalExpr: expression,
 		RecordedAt:   time.Now(),
+		Book:         book,
 	}
 
 	if err := s.accountingRepo.CreateRecord(ctx, record); err != nil {
-		logger.L().Errorf("Failed to create accounting record: %v", err)
-		return fmt.Errorf("记录保存失败")
+		logger.Ctx(ctx).Errorf("Failed to create accounting record: %v", err)
+		return nil, fmt.Errorf("记录保存失败")
 	}
 
-	logger.L().Infof("Accounting record created: chat_id=%d, user_id=%d, amount=%.2f, currency=%s", chatID, userID, amount, currency)
-	return nil
+	logger.Ctx(ctx).Infof("Accounting record created: chat_id=%d, user_id=%d, amount=%.2f, currency=%s, book=%q", chatID, userID, amount, currency, book)
+
+	result.Record = record
+	result.Anomalous = models.IsAccountingAmountAnomalous(amount, result.Average, result.Ratio)
+	if result.Anomalous {
+		logger.Ctx(ctx).Warnf("Accounting amount anomaly: chat_id=%d user_id=%d amount=%.2f average=%.2f ratio=%d",
+			chatID, userID, amount, result.Average, result.Ratio)
+	}
+	return result, nil
+}
+
+// AddLinkedExpense 联动写入一笔出账记录（如四方下发成功后），按 operationID 幂等，重复调用返回 created=false
+func (s *AccountingServiceImpl) AddLinkedExpense(ctx context.Context, chatID, userID int64, amount float64, currency, remark, operationID string) (bool, error) {
+	if operationID == "" {
+		return false, fmt.Errorf("联动记账缺少幂等键")
+	}
+	amount = models.RoundAccountingAmount(math.Abs(amount))
+	if amount == 0 {
+		return false, fmt.Errorf("联动记账金额不能为 0")
+	}
+
+	record := &models.AccountingRecord{
+		ChatID:       chatID,
+		UserID:       userID,
+		Amount:       -amount,
+		Currency:     currency,
+		OriginalExpr: remark,
+		RecordedAt:   time.Now(),
+		OperationID:  operationID,
+	}
+	created, err := s.accountingRepo.CreateRecordOnce(ctx, record)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to create linked accounting record: chat_id=%d, operation_id=%s, error=%v", chatID, operationID, err)
+		return false, fmt.Errorf("联动记账保存失败")
+	}
+	if !created {
+		logger.Ctx(ctx).Infof("Linked accounting record already exists: chat_id=%d, operation_id=%s", chatID, operationID)
+		return false, nil
+	}
+
+	logger.Ctx(ctx).Infof("Linked accounting record created: chat_id=%d, user_id=%d, amount=%.2f, currency=%s, operation_id=%s", chatID, userID, -amount, currency, operationID)
+	return true, nil
+}
+
+// anomalyBaseline 返回同币种近期记录的金额均值与群组生效的异常倍数，
+// 关闭提醒、样本不足或查询失败时均值为 0（不提醒，不影响记账）
+func (s *AccountingServiceImpl) anomalyBaseline(ctx context.Context, chatID int64, currency string) (float64, int) {
+	ratio := models.DefaultAccountingAnomalyRatio
+	if s.groupRepo != nil {
+		group, err := s.groupRepo.GetByTelegramID(ctx, chatID)
+		if err != nil {
+			logger.Ctx(ctx).Warnf("Failed to load group anomaly ratio, using default: chat_id=%d err=%v", chatID, err)
+		} else {
+			ratio = models.AccountingAnomalyRatio(group.Settings)
+		}
+	}
+	if ratio <= 0 {
+		return 0, 0
+	}
+
+	records, err := s.accountingRepo.GetLatestRecords(ctx, chatID, currency, models.AccountingAnomalySampleSize)
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to load recent records for anomaly check: chat_id=%d err=%v", chatID, err)
+		return 0, ratio
+	}
+	average, ok := models.AccountingAmountAverage(records)
+	if !ok {
+		return 0, ratio
+	}
+	return average, ratio
 }
 
 // parseInput 解析记账输入
@@ -117,113 +226,293 @@ func parseCurrency(code string) string {
 	return models.CurrencyCNY
 }
 
-// QueryRecords 查询并格式化账单
+// QueryRecords 查询并格式化账单：USDT 与人民币分区展示各自的收入、支出、净额与余额，
+// 能取到汇率时再按人民币口径折算合计，取不到时只分币种展示；汇总全部账本，存在非默认账本时附分账本小计
 func (s *AccountingServiceImpl) QueryRecords(ctx context.Context, chatID int64) (string, error) {
-	now := time.Now()
+	return s.queryReport(ctx, chatID, "", true)
+}
+
+// QueryBookRecords 查询并格式化指定账本的账单，格式与 QueryRecords 一致
+func (s *AccountingServiceImpl) QueryBookRecords(ctx context.Context, chatID int64, book string) (string, error) {
+	return s.queryReport(ctx, chatID, book, false)
+}
+
+// queryReport 生成账单，allBooks 为 true 时汇总全部账本，否则只统计 book 账本
+func (s *AccountingServiceImpl) queryReport(ctx context.Context, chatID int64, book string, allBooks bool) (string, error) {
+	now := time.Now().In(s.groupLocation(ctx, chatID))
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	todayEnd := todayStart.Add(24 * time.Hour)
 	yesterdayStart := todayStart.Add(-24 * time.Hour)
 
-	// 查询昨日结余（历史累计）
-	usdYesterdayBalance, err := s.calculateBalance(ctx, chatID, time.Time{}, yesterdayStart, models.CurrencyUSD)
-	if err != nil {
-		return "", err
+	sections := make([]accountingCurrencySection, 0, 2)
+	books := newAccountingBookTotals()
+	for _, currency := range []string{models.CurrencyUSD, models.CurrencyCNY} {
+		// 查询昨日结余（历史累计）
+		historyRecords, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, time.Time{}, yesterdayStart, currency)
+		if err != nil {
+			logger.Ctx(ctx).Errorf("Failed to query %s history records: %v", currency, err)
+			return "", fmt.Errorf("查询失败")
+		}
+
+		// 查询今日明细
+		todayRecords, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, todayStart, todayEnd, currency)
+		if err != nil {
+			logger.Ctx(ctx).Errorf("Failed to query %s records: %v", currency, err)
+			return "", fmt.Errorf("查询失败")
+		}
+
+		if allBooks {
+			books.add(currency, historyRecords)
+			books.add(currency, todayRecords)
+		} else {
+			historyRecords = filterAccountingBook(historyRecords, book)
+			todayRecords = filterAccountingBook(todayRecords, book)
+		}
+		sections = append(sections, newAccountingCurrencySection(currency, s.sumRecords(historyRecords), todayRecords))
 	}
 
-	cnyYesterdayBalance, err := s.calculateBalance(ctx, chatID, time.Time{}, yesterdayStart, models.CurrencyCNY)
-	if err != nil {
-		return "", err
+	// 只有 USDT 有数据时才需要汇率折算
+	var rate float64
+	if sections[0].hasData() {
+		rate = s.usdRate(ctx)
 	}
 
-	// 查询今日明细
-	usdTodayRecords, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, todayStart, todayEnd, models.CurrencyUSD)
-	if err != nil {
-		logger.L().Errorf("Failed to query USD records: %v", err)
-		return "", fmt.Errorf("查询失败")
+	// 格式化输出
+	title := ""
+	if !allBooks {
+		title = models.AccountingBookLabel(book)
 	}
+	report := s.formatAccountingReport(now, title, sections, rate)
+	if allBooks && books.multiple() {
+		report += books.format()
+	}
+	return report, nil
+}
 
-	cnyTodayRecords, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, todayStart, todayEnd, models.CurrencyCNY)
-	if err != nil {
-		logger.L().Errorf("Failed to query CNY records: %v", err)
-		return "", fmt.Errorf("查询失败")
+// filterAccountingBook 过滤出指定账本的记录
+func filterAccountingBook(records []*models.AccountingRecord, book string) []*models.AccountingRecord {
+	filtered := make([]*models.AccountingRecord, 0, len(records))
+	for _, r := range records {
+		if r.Book == book {
+			filtered = append(filtered, r)
+		}
 	}
+	return filtered
+}
 
-	// 计算今日总额
-	usdTodayTotal := s.sumRecords(usdTodayRecords)
-	cnyTodayTotal := s.sumRecords(cnyTodayRecords)
+// accountingBookTotals 按账本、币种累计的余额（整数分），用于汇总账单的分账本小计
+type accountingBookTotals struct {
+	order  []string
+	totals map[string]map[string]int64
+}
 
-	// 计算总余额
-	usdBalance := usdYesterdayBalance + usdTodayTotal
-	cnyBalance := cnyYesterdayBalance + cnyTodayTotal
+func newAccountingBookTotals() *accountingBookTotals {
+	return &accountingBookTotals{totals: make(map[string]map[string]int64)}
+}
 
-	// 格式化输出
-	return s.formatAccountingReport(now, usdYesterdayBalance, usdTodayRecords, usdBalance, cnyYesterdayBalance, cnyTodayRecords, cnyBalance), nil
+// add 累加记录金额到所属账本
+func (t *accountingBookTotals) add(currency string, records []*models.AccountingRecord) {
+	for _, r := range records {
+		byCurrency, ok := t.totals[r.Book]
+		if !ok {
+			byCurrency = make(map[string]int64)
+			t.totals[r.Book] = byCurrency
+			t.order = append(t.order, r.Book)
+		}
+		byCurrency[currency] += models.AccountingCents(r.Amount)
+	}
+}
+
+// multiple 是否使用了非默认账本（只有默认账本时不展示小计，与分账本前的账单保持一致）
+func (t *accountingBookTotals) multiple() bool {
+	for _, book := range t.order {
+		if book != "" {
+			return true
+		}
+	}
+	return false
 }
 
-// calculateBalance 计算余额
-func (s *AccountingServiceImpl) calculateBalance(ctx context.Context, chatID int64, startTime, endTime time.Time, currency string) (float64, error) {
-	records, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, startTime, endTime, currency)
+// format 格式化分账本小计，默认账本在前，其余按名称排序
+func (t *accountingBookTotals) format() string {
+	books := append([]string(nil), t.order...)
+	sort.Slice(books, func(i, j int) bool {
+		if books[i] == "" || books[j] == "" {
+			return books[i] == ""
+		}
+		return books[i] < books[j]
+	})
+
+	var sb strings.Builder
+	sb.WriteString("\n📒 分账本小计\n")
+	for _, book := range books {
+		byCurrency := t.totals[book]
+		parts := make([]string, 0, 2)
+		for _, currency := range []string{models.CurrencyUSD, models.CurrencyCNY} {
+			if cents, ok := byCurrency[currency]; ok {
+				parts = append(parts, fmt.Sprintf("%s %s", accountingCurrencyLabel(currency), formatAmount(models.CentsToAmount(cents))))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", html.EscapeString(models.AccountingBookLabel(book)), strings.Join(parts, " ｜ ")))
+	}
+	return sb.String()
+}
+
+// accountingCurrencyLabel 币种展示名称
+func accountingCurrencyLabel(currency string) string {
+	if currency == models.CurrencyUSD {
+		return "USDT"
+	}
+	return "CNY"
+}
+
+// accountingCurrencySection 账单中单个币种的分区数据
+type accountingCurrencySection struct {
+	currency         string
+	yesterdayBalance float64
+	todayRecords     []*models.AccountingRecord
+	income           float64 // 今日收入合计
+	expense          float64 // 今日支出合计（负数）
+	balance          float64 // 昨日结余 + 今日净额
+}
+
+func newAccountingCurrencySection(currency string, yesterdayBalance float64, todayRecords []*models.AccountingRecord) accountingCurrencySection {
+	section := accountingCurrencySection{
+		currency:         currency,
+		yesterdayBalance: yesterdayBalance,
+		todayRecords:     todayRecords,
+	}
+	var incomeCents, expenseCents int64
+	for _, r := range todayRecords {
+		if cents := models.AccountingCents(r.Amount); cents >= 0 {
+			incomeCents += cents
+		} else {
+			expenseCents += cents
+		}
+	}
+	section.income = models.CentsToAmount(incomeCents)
+	section.expense = models.CentsToAmount(expenseCents)
+	section.balance = models.CentsToAmount(models.AccountingCents(yesterdayBalance) + incomeCents + expenseCents)
+	return section
+}
+
+// net 今日净额
+func (c accountingCurrencySection) net() float64 {
+	return models.CentsToAmount(models.AccountingCents(c.income) + models.AccountingCents(c.expense))
+}
+
+// hasData 是否有历史结余或今日记录
+func (c accountingCurrencySection) hasData() bool {
+	return c.yesterdayBalance != 0 || len(c.todayRecords) > 0
+}
+
+// usdRate 获取 USDT→人民币汇率（成功结果缓存 accountingRateCacheTTL），未配置或获取失败时返回 0（不折算）
+func (s *AccountingServiceImpl) usdRate(ctx context.Context) float64 {
+	if s.rateFunc == nil {
+		return 0
+	}
+	s.rateMu.Lock()
+	if s.cachedRate > 0 && time.Now().Before(s.rateExpiresAt) {
+		rate := s.cachedRate
+		s.rateMu.Unlock()
+		return rate
+	}
+	s.rateMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, accountingRateTimeout)
+	defer cancel()
+	rate, err := s.rateFunc(ctx)
+	if err != nil || rate <= 0 {
+		logger.Ctx(ctx).Warnf("Failed to get USDT rate for accounting report, skipping conversion: rate=%v err=%v", rate, err)
+		return 0
+	}
+
+	s.rateMu.Lock()
+	s.cachedRate = rate
+	s.rateExpiresAt = time.Now().Add(accountingRateCacheTTL)
+	s.rateMu.Unlock()
+	return rate
+}
+
+// groupLocation 获取群组时区，查询失败时回退到默认时区
+func (s *AccountingServiceImpl) groupLocation(ctx context.Context, chatID int64) *time.Location {
+	if s.groupRepo == nil {
+		return models.DefaultLocation()
+	}
+	group, err := s.groupRepo.GetByTelegramID(ctx, chatID)
 	if err != nil {
-		return 0, err
+		logger.Ctx(ctx).Warnf("Failed to load group timezone, using default: chat_id=%d err=%v", chatID, err)
+		return models.DefaultLocation()
 	}
-	return s.sumRecords(records), nil
+	return models.GroupLocation(group.Settings)
 }
 
-// sumRecords 汇总记录金额
+// sumRecords 汇总记录金额，按整数分累加，避免多笔小数相加产生浮点误差（兼容未舍入的历史记录）
 func (s *AccountingServiceImpl) sumRecords(records []*models.AccountingRecord) float64 {
-	var sum float64
+	var cents int64
 	for _, r := range records {
-		sum += r.Amount
+		cents += models.AccountingCents(r.Amount)
 	}
-	return sum
+	return models.CentsToAmount(cents)
 }
 
-// formatAccountingReport 格式化账单报告
-func (s *AccountingServiceImpl) formatAccountingReport(
-	now time.Time,
-	usdYesterdayBalance float64,
-	usdTodayRecords []*models.AccountingRecord,
-	usdBalance float64,
-	cnyYesterdayBalance float64,
-	cnyTodayRecords []*models.AccountingRecord,
-	cnyBalance float64,
-) string {
+// formatAccountingReport 格式化账单报告；book 非空时为单账本账单，rate > 0 时追加人民币口径的折算合计
+func (s *AccountingServiceImpl) formatAccountingReport(now time.Time, book string, sections []accountingCurrencySection, rate float64) string {
 	var sb strings.Builder
 
-	// 标题
-	sb.WriteString(fmt.Sprintf("📊 账单 - %s\n\n", now.Format("2006-01-02")))
+	// 标题，指定账本时带账本名称
+	if book != "" {
+		sb.WriteString(fmt.Sprintf("📊 账单（%s） - %s\n", html.EscapeString(book), now.Format("2006-01-02")))
+	} else {
+		sb.WriteString(fmt.Sprintf("📊 账单 - %s\n", now.Format("2006-01-02")))
+	}
 
-	// USDT 部分
-	sb.WriteString("💵 USDT\n")
-	sb.WriteString(fmt.Sprintf("昨日结余: %s\n", formatAmount(usdYesterdayBalance)))
-	if len(usdTodayRecords) > 0 {
-		sb.WriteString("今日明细:\n")
-		for _, r := range usdTodayRecords {
-			sb.WriteString(fmt.Sprintf("  %s %s\n", r.RecordedAt.Format("15:04"), formatAmount(r.Amount)))
+	for _, section := range sections {
+		if section.currency == models.CurrencyUSD {
+			sb.WriteString("\n💵 USDT\n")
+		} else {
+			sb.WriteString("\n💴 CNY\n")
 		}
-	} else {
-		sb.WriteString("今日明细: 无\n")
+		sb.WriteString(fmt.Sprintf("昨日结余: %s\n", formatAmount(section.yesterdayBalance)))
+		if len(section.todayRecords) > 0 {
+			sb.WriteString("今日明细:\n")
+			for _, r := range section.todayRecords {
+				line := fmt.Sprintf("  %s %s", r.RecordedAt.Format("15:04"), formatAmount(r.Amount))
+				if book == "" && r.Book != "" {
+					// 汇总账单中标注非默认账本的记录
+					line += " #" + html.EscapeString(r.Book)
+				}
+				sb.WriteString(line + "\n")
+			}
+			sb.WriteString(fmt.Sprintf("今日收入: %s ｜ 支出: %s ｜ 净额: %s\n",
+				formatAmount(section.income), formatAmount(section.expense), formatAmount(section.net())))
+		} else {
+			sb.WriteString("今日明细: 无\n")
+		}
+		sb.WriteString(fmt.Sprintf("总余额: <b>%s</b>\n", formatAmount(section.balance)))
 	}
-	sb.WriteString(fmt.Sprintf("总余额: <b>%s</b>\n\n", formatAmount(usdBalance)))
 
-	// CNY 部分
-	sb.WriteString("💴 CNY\n")
-	sb.WriteString(fmt.Sprintf("昨日结余: %s\n", formatAmount(cnyYesterdayBalance)))
-	if len(cnyTodayRecords) > 0 {
-		sb.WriteString("今日明细:\n")
-		for _, r := range cnyTodayRecords {
-			sb.WriteString(fmt.Sprintf("  %s %s\n", r.RecordedAt.Format("15:04"), formatAmount(r.Amount)))
+	if rate > 0 {
+		var todayNet, balance float64
+		for _, section := range sections {
+			factor := 1.0
+			if section.currency == models.CurrencyUSD {
+				factor = rate
+			}
+			todayNet += section.net() * factor
+			balance += section.balance * factor
 		}
-	} else {
-		sb.WriteString("今日明细: 无\n")
+		sb.WriteString(fmt.Sprintf("\n💱 折合人民币（1 USDT ≈ %.2f CNY）\n", rate))
+		sb.WriteString(fmt.Sprintf("今日净额: %s\n", formatAmount(todayNet)))
+		sb.WriteString(fmt.Sprintf("总净额: <b>%s</b>\n", formatAmount(balance)))
 	}
-	sb.WriteString(fmt.Sprintf("总余额: <b>%s</b>\n", formatAmount(cnyBalance)))
 
 	return sb.String()
 }
 
-// formatAmount 格式化金额（整数去掉.0，正数显示+号）
+// formatAmount 格式化金额（按 2 位小数四舍五入，整数去掉.0，正数显示+号）
 func formatAmount(amount float64) string {
+	amount = models.RoundAccountingAmount(amount)
 	if amount == float64(int64(amount)) {
 		// 整数，去掉.0
 		if amount >= 0 {
@@ -242,7 +531,7 @@ func formatAmount(amount float64) string {
 func (s *AccountingServiceImpl) GetRecentRecordsForDeletion(ctx context.Context, chatID int64) ([]*models.AccountingRecord, error) {
 	records, err := s.accountingRepo.GetRecentRecords(ctx, chatID, 2)
 	if err != nil {
-		logger.L().Errorf("Failed to get recent records: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to get recent records: %v", err)
 		return nil, fmt.Errorf("查询失败")
 	}
 	return records, nil
@@ -251,20 +540,73 @@ func (s *AccountingServiceImpl) GetRecentRecordsForDeletion(ctx context.Context,
 // DeleteRecord 删除记录
 func (s *AccountingServiceImpl) DeleteRecord(ctx context.Context, recordID string) error {
 	if err := s.accountingRepo.DeleteRecord(ctx, recordID); err != nil {
-		logger.L().Errorf("Failed to delete record %s: %v", recordID, err)
+		logger.Ctx(ctx).Errorf("Failed to delete record %s: %v", recordID, err)
 		return fmt.Errorf("删除失败")
 	}
-	logger.L().Infof("Accounting record %s deleted", recordID)
+	logger.Ctx(ctx).Infof("Accounting record %s deleted", recordID)
 	return nil
 }
 
+// dateRange 返回指定日期在群组时区下的 [当天 00:00, 次日 00:00)
+func (s *AccountingServiceImpl) dateRange(ctx context.Context, chatID int64, date time.Time) (time.Time, time.Time) {
+	loc := s.groupLocation(ctx, chatID)
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// GetRecordsByDate 获取指定日期的全部记录（USDT 与 CNY）
+func (s *AccountingServiceImpl) GetRecordsByDate(ctx context.Context, chatID int64, date time.Time) ([]*models.AccountingRecord, error) {
+	start, end := s.dateRange(ctx, chatID, date)
+	records, err := s.accountingRepo.GetRecordsByDateRange(ctx, chatID, start, end, "")
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to query records by date: chat_id=%d date=%s err=%v", chatID, start.Format("2006-01-02"), err)
+		return nil, fmt.Errorf("查询失败")
+	}
+	return records, nil
+}
+
+// DeleteByDate 删除指定日期的全部记录，范围与 GetRecordsByDate 一致
+func (s *AccountingServiceImpl) DeleteByDate(ctx context.Context, chatID int64, date time.Time) (int64, error) {
+	records, err := s.GetRecordsByDate(ctx, chatID, date)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+
+	count, err := s.accountingRepo.DeleteRecordsByIDs(ctx, chatID, ids)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to delete records by date: chat_id=%d date=%s err=%v", chatID, date.Format("2006-01-02"), err)
+		return 0, fmt.Errorf("删除失败")
+	}
+	logger.Ctx(ctx).Infof("Deleted %d accounting records for chat %d on %s", count, chatID, date.Format("2006-01-02"))
+	return count, nil
+}
+
+// ClearBookRecords 清空指定账本的记录
+func (s *AccountingServiceImpl) ClearBookRecords(ctx context.Context, chatID int64, book string) (int64, error) {
+	count, err := s.accountingRepo.DeleteAllByBook(ctx, chatID, book)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to clear book %q records for chat %d: %v", book, chatID, err)
+		return 0, fmt.Errorf("清空失败")
+	}
+	logger.Ctx(ctx).Infof("Cleared %d accounting records of book %q for chat %d", count, book, chatID)
+	return count, nil
+}
+
 // ClearAllRecords 清空所有记录
 func (s *AccountingServiceImpl) ClearAllRecords(ctx context.Context, chatID int64) (int64, error) {
 	count, err := s.accountingRepo.DeleteAllByChatID(ctx, chatID)
 	if err != nil {
-		logger.L().Errorf("Failed to clear all records for chat %d: %v", chatID, err)
+		logger.Ctx(ctx).Errorf("Failed to clear all records for chat %d: %v", chatID, err)
 		return 0, fmt.Errorf("清空失败")
 	}
-	logger.L().Infof("Cleared %d accounting records for chat %d", count, chatID)
+	logger.Ctx(ctx).Infof("Cleared %d accounting records for chat %d", count, chatID)
 	return count, nil
 }
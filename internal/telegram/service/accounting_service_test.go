@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryAccountingRepository 内存记账仓库，仅实现新增、查询与按 ID、账本删除
+type memoryAccountingRepository struct {
+	repository.AccountingRepository
+	records []*models.AccountingRecord
+}
+
+func (r *memoryAccountingRepository) CreateRecord(ctx context.Context, record *models.AccountingRecord) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *memoryAccountingRepository) CreateRecordOnce(ctx context.Context, record *models.AccountingRecord) (bool, error) {
+	for _, existing := range r.records {
+		if existing.OperationID == record.OperationID {
+			return false, nil
+		}
+	}
+	r.records = append(r.records, record)
+	return true, nil
+}
+
+func (r *memoryAccountingRepository) GetRecordsByDateRange(ctx context.Context, chatID int64, startTime, endTime time.Time, currency string) ([]*models.AccountingRecord, error) {
+	var result []*models.AccountingRecord
+	for _, record := range r.records {
+		if record.ChatID != chatID || (currency != "" && record.Currency != currency) {
+			continue
+		}
+		if (!startTime.IsZero() && record.RecordedAt.Before(startTime)) || !record.RecordedAt.Before(endTime) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+func (r *memoryAccountingRepository) DeleteRecordsByIDs(ctx context.Context, chatID int64, ids []primitive.ObjectID) (int64, error) {
+	remove := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []*models.AccountingRecord
+	var deleted int64
+	for _, record := range r.records {
+		if record.ChatID == chatID && remove[record.ID] {
+			deleted++
+			continue
+		}
+		kept = append(kept, record)
+	}
+	r.records = kept
+	return deleted, nil
+}
+
+func (r *memoryAccountingRepository) DeleteAllByBook(ctx context.Context, chatID int64, book string) (int64, error) {
+	var kept []*models.AccountingRecord
+	var deleted int64
+	for _, record := range r.records {
+		if record.ChatID == chatID && record.Book == book {
+			deleted++
+			continue
+		}
+		kept = append(kept, record)
+	}
+	r.records = kept
+	return deleted, nil
+}
+
+func (r *memoryAccountingRepository) GetLatestRecords(ctx context.Context, chatID int64, currency string, limit int64) ([]*models.AccountingRecord, error) {
+	var result []*models.AccountingRecord
+	for i := len(r.records) - 1; i >= 0 && int64(len(result)) < limit; i-- {
+		if record := r.records[i]; record.ChatID == chatID && record.Currency == currency {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+func TestAddRecordRoundsAmount(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+
+	inputs := map[string]float64{
+		"+100*7.2U": 720,
+		"-0.1+0.2Y": -0.3,
+		"入2.01/2":   1.01,
+		"出1.15*100": -115,
+	}
+	for input, want := range inputs {
+		repo.records = nil
+		if _, err := svc.AddRecord(context.Background(), -1, 7, input); err != nil {
+			t.Fatalf("AddRecord(%q) unexpected error: %v", input, err)
+		}
+		if got := repo.records[0].Amount; got != want {
+			t.Fatalf("AddRecord(%q) stored %v, want %v", input, got, want)
+		}
+	}
+
+	if _, err := svc.AddRecord(context.Background(), -1, 7, "+0.001U"); err == nil {
+		t.Fatal("expected error for amount that rounds to zero")
+	}
+}
+
+func TestAddLinkedExpenseIsIdempotent(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+
+	for i := range 2 {
+		created, err := svc.AddLinkedExpense(context.Background(), -1, 7, 100.005, models.CurrencyCNY, "下发 100.005", "sendmoney:NO1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created != (i == 0) {
+			t.Fatalf("call %d: expected created=%t, got %t", i, i == 0, created)
+		}
+	}
+	if len(repo.records) != 1 || repo.records[0].Amount != -100.01 || repo.records[0].Currency != models.CurrencyCNY {
+		t.Fatalf("expected one expense of -100.01 CNY, got %+v", repo.records)
+	}
+
+	if _, err := svc.AddLinkedExpense(context.Background(), -1, 7, 10, models.CurrencyCNY, "下发 10", ""); err == nil {
+		t.Fatal("expected error without operation id")
+	}
+}
+
+func TestQueryRecordsSumsInCents(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+
+	now := time.Now()
+	for _, amount := range []float64{0.1, 0.2, 100 * 7.2} {
+		// 模拟未舍入的历史记录
+		repo.records = append(repo.records, &models.AccountingRecord{ChatID: -1, Amount: amount, Currency: models.CurrencyUSD, RecordedAt: now})
+	}
+
+	report, err := svc.QueryRecords(context.Background(), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(report, "总余额: <b>+720.30</b>") {
+		t.Fatalf("expected rounded balance in report, got:\n%s", report)
+	}
+	if strings.Contains(report, "720.0000") {
+		t.Fatalf("unexpected floating point tail in report:\n%s", report)
+	}
+}
+
+func TestQueryRecordsSplitsCurrenciesWithConversion(t *testing.T) {
+	now := time.Now()
+	newRepo := func() *memoryAccountingRepository {
+		repo := &memoryAccountingRepository{}
+		add := func(amount float64, currency string, at time.Time) {
+			repo.records = append(repo.records, &models.AccountingRecord{ChatID: -1, Amount: amount, Currency: currency, RecordedAt: at})
+		}
+		add(50, models.CurrencyUSD, now.AddDate(0, 0, -3))
+		add(100, models.CurrencyUSD, now)
+		add(-30, models.CurrencyUSD, now)
+		add(1000, models.CurrencyCNY, now)
+		add(-200.5, models.CurrencyCNY, now)
+		return repo
+	}
+
+	rateCalls := 0
+	svc := NewAccountingService(newRepo(), nil, func(ctx context.Context) (float64, error) {
+		rateCalls++
+		return 7.2, nil
+	})
+	report, err := svc.QueryRecords(context.Background(), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"💵 USDT\n昨日结余: +50\n",
+		"今日收入: +100 ｜ 支出: -30 ｜ 净额: +70\n总余额: <b>+120</b>",
+		"今日收入: +1000 ｜ 支出: -200.50 ｜ 净额: +799.50\n总余额: <b>+799.50</b>",
+		"💱 折合人民币（1 USDT ≈ 7.20 CNY）",
+		// 70×7.2 + 799.5 = 1303.5；120×7.2 + 799.5 = 1663.5
+		"今日净额: +1303.50\n总净额: <b>+1663.50</b>",
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in report, got:\n%s", want, report)
+		}
+	}
+	if rateCalls != 1 {
+		t.Fatalf("expected rate to be fetched once, got %d", rateCalls)
+	}
+
+	// 汇率获取失败：只分币种展示，不折算
+	svc = NewAccountingService(newRepo(), nil, func(ctx context.Context) (float64, error) {
+		return 0, errors.New("okx unavailable")
+	})
+	report, err = svc.QueryRecords(context.Background(), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(report, "折合人民币") || !strings.Contains(report, "净额: +799.50") {
+		t.Fatalf("expected per-currency report without conversion, got:\n%s", report)
+	}
+
+	// 只有人民币记录时无需汇率
+	repo := &memoryAccountingRepository{}
+	repo.records = append(repo.records, &models.AccountingRecord{ChatID: -1, Amount: 10, Currency: models.CurrencyCNY, RecordedAt: now})
+	rateCalls = 0
+	svc = NewAccountingService(repo, nil, func(ctx context.Context) (float64, error) {
+		rateCalls++
+		return 7.2, nil
+	})
+	if _, err := svc.QueryRecords(context.Background(), -1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rateCalls != 0 {
+		t.Fatalf("expected no rate lookup without USDT records, got %d", rateCalls)
+	}
+}
+
+func TestDeleteByDateRemovesOnlyThatDay(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+
+	loc := models.DefaultLocation()
+	add := func(chatID int64, at time.Time, currency string) {
+		repo.records = append(repo.records, &models.AccountingRecord{ID: primitive.NewObjectID(), ChatID: chatID, Amount: 1, Currency: currency, RecordedAt: at})
+	}
+	add(-1, time.Date(2024, 1, 5, 0, 0, 0, 0, loc), models.CurrencyUSD)
+	add(-1, time.Date(2024, 1, 5, 23, 59, 0, 0, loc), models.CurrencyCNY)
+	add(-1, time.Date(2024, 1, 4, 23, 59, 0, 0, loc), models.CurrencyUSD)
+	add(-1, time.Date(2024, 1, 6, 0, 0, 0, 0, loc), models.CurrencyUSD)
+	add(-2, time.Date(2024, 1, 5, 12, 0, 0, 0, loc), models.CurrencyUSD)
+
+	date := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	records, err := svc.GetRecordsByDate(context.Background(), -1, date)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected 2 records on 2024-01-05, got %d err=%v", len(records), err)
+	}
+
+	count, err := svc.DeleteByDate(context.Background(), -1, date)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 deleted, got %d err=%v", count, err)
+	}
+	if len(repo.records) != 3 {
+		t.Fatalf("expected 3 records left, got %d", len(repo.records))
+	}
+
+	if count, err := svc.DeleteByDate(context.Background(), -1, date); err != nil || count != 0 {
+		t.Fatalf("expected nothing left to delete, got %d err=%v", count, err)
+	}
+}
+
+func TestAddRecordFlagsAnomalousAmount(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+	ctx := context.Background()
+
+	// 样本不足时不提醒
+	for _, input := range []string{"+100U", "+120U"} {
+		result, err := svc.AddRecord(ctx, -1, 7, input)
+		if err != nil || result.Anomalous {
+			t.Fatalf("AddRecord(%q) = %+v, %v; want no anomaly", input, result, err)
+		}
+	}
+	if _, err := svc.AddRecord(ctx, -1, 7, "-80U"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 均值 100，未超过 10 倍
+	if result, err := svc.AddRecord(ctx, -1, 7, "+1000U"); err != nil || result.Anomalous {
+		t.Fatalf("expected 1000 not to be anomalous, got %+v, %v", result, err)
+	}
+
+	// 均值 325，超过 10 倍，提醒但仍记录
+	result, err := svc.AddRecord(ctx, -1, 7, "+100000U")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Anomalous || result.Ratio != models.DefaultAccountingAnomalyRatio || result.Average != 325 {
+		t.Fatalf("expected anomaly against average 325, got %+v", result)
+	}
+	if got := repo.records[len(repo.records)-1].Amount; got != 100000 {
+		t.Fatalf("anomalous amount should still be recorded, last amount = %v", got)
+	}
+
+	// 其他币种的历史记录不参与均值
+	if result, err := svc.AddRecord(ctx, -1, 7, "+100000Y"); err != nil || result.Anomalous {
+		t.Fatalf("expected CNY without history not to be anomalous, got %+v, %v", result, err)
+	}
+}
+
+func TestAccountingBooks(t *testing.T) {
+	repo := &memoryAccountingRepository{}
+	svc := NewAccountingService(repo, nil, nil)
+	ctx := context.Background()
+
+	// 分账本前的历史记录没有 book 字段，归默认账本
+	repo.records = append(repo.records, &models.AccountingRecord{ChatID: -1, Amount: 50, Currency: models.CurrencyUSD, RecordedAt: time.Now().AddDate(0, 0, -3)})
+	for _, input := range []string{"+100U", "#公账 +20U", "#公账 出5Y", "#私账 -8U"} {
+		if _, err := svc.AddRecord(ctx, -1, 7, input); err != nil {
+			t.Fatalf("AddRecord(%q) unexpected error: %v", input, err)
+		}
+	}
+	if book := repo.records[2].Book; book != "公账" {
+		t.Fatalf("expected record in 公账, got %q", book)
+	}
+	if _, err := svc.AddRecord(ctx, -1, 7, "#默认账本 +1Y"); err != nil || repo.records[len(repo.records)-1].Book != "" {
+		t.Fatalf("expected #默认账本 to use default book, err=%v", err)
+	}
+	if _, err := svc.AddRecord(ctx, -1, 7, "#公账+20U"); err == nil || err.Error() != "输入格式错误" {
+		t.Fatalf("expected format error without space after book, got %v", err)
+	}
+
+	// 汇总全部账本并分账本小计
+	report, err := svc.QueryRecords(ctx, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"总余额: <b>+162</b>",
+		"+20 #公账",
+		"📒 分账本小计\n默认账本: USDT +150 ｜ CNY +1\n公账: USDT +20 ｜ CNY -5\n私账: USDT -8\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Fatalf("expected %q in report, got:\n%s", want, report)
+		}
+	}
+
+	// 单账本账单只统计该账本
+	report, err = svc.QueryBookRecords(ctx, -1, "公账")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(report, "📊 账单（公账）") || !strings.Contains(report, "总余额: <b>+20</b>") || strings.Contains(report, "分账本小计") {
+		t.Fatalf("unexpected book report:\n%s", report)
+	}
+
+	// 只清空指定账本
+	count, err := svc.ClearBookRecords(ctx, -1, "公账")
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 cleared, got %d err=%v", count, err)
+	}
+	if len(repo.records) != 4 {
+		t.Fatalf("expected 4 records left, got %d", len(repo.records))
+	}
+}
+
+func TestAccountingUSDRateIsCached(t *testing.T) {
+	calls := 0
+	fail := true
+	svc := NewAccountingService(&memoryAccountingRepository{}, nil, func(ctx context.Context) (float64, error) {
+		calls++
+		if fail {
+			return 0, errors.New("okx unavailable")
+		}
+		return 7.2, nil
+	}).(*AccountingServiceImpl)
+	ctx := context.Background()
+
+	// 失败不缓存，下次仍重新获取
+	if rate := svc.usdRate(ctx); rate != 0 {
+		t.Fatalf("expected no rate on failure, got %v", rate)
+	}
+	fail = false
+	for range 3 {
+		if rate := svc.usdRate(ctx); rate != 7.2 {
+			t.Fatalf("expected rate 7.2, got %v", rate)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected successful rate to be cached, got %d calls", calls)
+	}
+}
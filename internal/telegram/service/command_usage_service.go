@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+const (
+	// commandUsageFlushInterval 内存计数落库间隔
+	commandUsageFlushInterval = time.Minute
+	// commandUsageFlushTimeout 单次落库超时
+	commandUsageFlushTimeout = 10 * time.Second
+)
+
+// commandUsageKey 内存计数键：统计日期 + 命令名
+type commandUsageKey struct {
+	date    string
+	command string
+}
+
+// CommandUsageServiceImpl 命令使用统计服务：调用只在内存累加，后台按间隔批量 $inc 落库，避免拖慢命令主路径
+type CommandUsageServiceImpl struct {
+	repo repository.CommandUsageRepository
+	now  func() time.Time
+
+	mu      sync.Mutex
+	pending map[commandUsageKey]int64
+
+	flushMu sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewCommandUsageService 创建服务实例并启动后台落库
+func NewCommandUsageService(repo repository.CommandUsageRepository) CommandUsageService {
+	return newCommandUsageService(repo, commandUsageFlushInterval, time.Now)
+}
+
+func newCommandUsageService(repo repository.CommandUsageRepository, interval time.Duration, now func() time.Time) *CommandUsageServiceImpl {
+	s := &CommandUsageServiceImpl{
+		repo:    repo,
+		now:     now,
+		pending: make(map[commandUsageKey]int64),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, interval)
+	}()
+	return s
+}
+
+// Record 记录一次命令（或功能）调用，只做内存累加，由后台定期落库
+func (s *CommandUsageServiceImpl) Record(command string) {
+	if command == "" {
+		return
+	}
+	key := commandUsageKey{date: s.today(), command: command}
+	s.mu.Lock()
+	s.pending[key]++
+	s.mu.Unlock()
+}
+
+// TopCommands 返回近 days 天（含今天）的命令调用排行，最多 limit 条
+func (s *CommandUsageServiceImpl) TopCommands(ctx context.Context, days, limit int) (*CommandUsageReport, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("统计天数必须大于 0")
+	}
+
+	// 先落库内存中的计数，保证排行包含最新调用
+	s.flush(ctx)
+
+	since := s.now().In(models.DefaultLocation()).AddDate(0, 0, -(days - 1)).Format(models.CommandUsageDateLayout)
+	ranks, err := s.repo.SumSince(ctx, since)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to load command usage: since=%s, error=%v", since, err)
+		return nil, fmt.Errorf("查询命令使用统计失败")
+	}
+
+	report := &CommandUsageReport{Since: since}
+	for _, rank := range ranks {
+		report.Total += rank.Count
+	}
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].Count != ranks[j].Count {
+			return ranks[i].Count > ranks[j].Count
+		}
+		return ranks[i].Command < ranks[j].Command
+	})
+	if limit > 0 && len(ranks) > limit {
+		ranks = ranks[:limit]
+	}
+	report.Ranks = ranks
+	return report, nil
+}
+
+// Close 停止后台落库并写入剩余计数
+func (s *CommandUsageServiceImpl) Close(ctx context.Context) {
+	s.cancel()
+	s.wg.Wait()
+	flushed := s.flush(ctx)
+	logger.Ctx(ctx).Infof("Command usage recorder closed: flushed=%d", flushed)
+}
+
+func (s *CommandUsageServiceImpl) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+// flush 将内存计数批量落库，失败时合并回内存等待下次重试；返回落库的键数量
+func (s *CommandUsageServiceImpl) flush(ctx context.Context) int {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[commandUsageKey]int64, len(batch))
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return 0
+	}
+
+	usages := make([]*models.CommandUsage, 0, len(batch))
+	for key, count := range batch {
+		usages = append(usages, &models.CommandUsage{Date: key.date, Command: key.command, Count: count})
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commandUsageFlushTimeout)
+	defer cancel()
+	if err := s.repo.IncrementBatch(flushCtx, usages); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to flush command usage: keys=%d, error=%v", len(batch), err)
+		s.mu.Lock()
+		for key, count := range batch {
+			s.pending[key] += count
+		}
+		s.mu.Unlock()
+		return 0
+	}
+	return len(batch)
+}
+
+func (s *CommandUsageServiceImpl) today() string {
+	return s.now().In(models.DefaultLocation()).Format(models.CommandUsageDateLayout)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+// memoryCommandUsageRepository 内存实现的命令使用统计 Repository
+type memoryCommandUsageRepository struct {
+	mu      sync.Mutex
+	counts  map[commandUsageKey]int64
+	flushes int
+	failing bool
+}
+
+func (r *memoryCommandUsageRepository) IncrementBatch(ctx context.Context, usages []*models.CommandUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failing {
+		return errors.New("mongo down")
+	}
+	r.flushes++
+	for _, usage := range usages {
+		r.counts[commandUsageKey{date: usage.Date, command: usage.Command}] += usage.Count
+	}
+	return nil
+}
+
+func (r *memoryCommandUsageRepository) SumSince(ctx context.Context, sinceDate string) ([]models.CommandUsageRank, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	totals := make(map[string]int64)
+	for key, count := range r.counts {
+		if key.date >= sinceDate {
+			totals[key.command] += count
+		}
+	}
+	ranks := make([]models.CommandUsageRank, 0, len(totals))
+	for command, count := range totals {
+		ranks = append(ranks, models.CommandUsageRank{Command: command, Count: count})
+	}
+	sort.Slice(ranks, func(i, j int) bool { return ranks[i].Command < ranks[j].Command })
+	return ranks, nil
+}
+
+func (r *memoryCommandUsageRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func TestCommandUsageServiceAggregatesByDayAndRanks(t *testing.T) {
+	repo := &memoryCommandUsageRepository{counts: make(map[commandUsageKey]int64)}
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, models.DefaultLocation())
+	svc := newCommandUsageService(repo, time.Hour, func() time.Time { return now })
+	defer svc.Close(context.Background())
+
+	// 8 天前的调用不计入近 7 天排行
+	now = now.AddDate(0, 0, -7)
+	svc.Record("/health")
+	svc.Record("/health")
+	now = now.AddDate(0, 0, 7)
+
+	for range 3 {
+		svc.Record("/ping")
+	}
+	svc.Record("/health")
+	svc.Record("sifang")
+	svc.Record("")
+
+	report, err := svc.TopCommands(context.Background(), 7, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Since != "2026-03-04" {
+		t.Fatalf("expected since 2026-03-04, got %s", report.Since)
+	}
+	if report.Total != 5 {
+		t.Fatalf("expected total 5, got %d", report.Total)
+	}
+	want := []models.CommandUsageRank{{Command: "/ping", Count: 3}, {Command: "/health", Count: 1}}
+	if len(report.Ranks) != len(want) {
+		t.Fatalf("expected %d ranks, got %+v", len(want), report.Ranks)
+	}
+	for i := range want {
+		if report.Ranks[i] != want[i] {
+			t.Fatalf("rank %d: expected %+v, got %+v", i, want[i], report.Ranks[i])
+		}
+	}
+	// 同一天同一命令合并为一次 $inc
+	if got := repo.counts[commandUsageKey{date: "2026-03-10", command: "/ping"}]; got != 3 {
+		t.Fatalf("expected merged count 3, got %d", got)
+	}
+}
+
+func TestCommandUsageServiceRequeuesFailedFlush(t *testing.T) {
+	repo := &memoryCommandUsageRepository{counts: make(map[commandUsageKey]int64), failing: true}
+	svc := newCommandUsageService(repo, time.Hour, time.Now)
+
+	svc.Record("/ping")
+	if flushed := svc.flush(context.Background()); flushed != 0 {
+		t.Fatalf("expected failed flush, got %d", flushed)
+	}
+
+	repo.mu.Lock()
+	repo.failing = false
+	repo.mu.Unlock()
+	svc.Record("/ping")
+	svc.Close(context.Background())
+
+	var total int64
+	for _, count := range repo.counts {
+		total += count
+	}
+	if total != 2 || repo.flushes != 1 {
+		t.Fatalf("expected 2 calls in 1 flush after retry, got total=%d flushes=%d", total, repo.flushes)
+	}
+}
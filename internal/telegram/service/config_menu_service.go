@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,11 @@ import (
 const (
 	// MaxInputRetries 最大输入验证失败重试次数
 	MaxInputRetries = 3
+	// UserStateTTL 配置输入状态的有效期，超时后视为用户放弃输入
+	UserStateTTL = 5 * time.Minute
+
+	// configCancelAction 取消输入按钮的回调动作
+	configCancelAction = "cancel"
 )
 
 // ConfigMenuService 配置菜单服务
@@ -105,6 +111,15 @@ func (s *ConfigMenuService) buildButtonForItem(item models.ConfigItem, group *mo
 	}
 }
 
+// BuildInputCancelKeyboard 构建输入提示下方的「取消」按钮，仅发起输入的用户可点击
+func (s *ConfigMenuService) BuildInputCancelKeyboard(userID int64) *botModels.InlineKeyboardMarkup {
+	return &botModels.InlineKeyboardMarkup{
+		InlineKeyboard: [][]botModels.InlineKeyboardButton{
+			{{Text: "❌ 取消输入", CallbackData: fmt.Sprintf("config:%s:%d", configCancelAction, userID)}},
+		},
+	}
+}
+
 // HandleCallback 处理回调查询（用户点击按钮）
 // 注意：调用方需要先调用 GetOrCreateGroup 确保群组存在
 func (s *ConfigMenuService) HandleCallback(
@@ -134,6 +149,22 @@ func (s *ConfigMenuService) HandleCallback(
 		// 不可点击的按钮（如分类标题）
 		return "", false, nil
 
+	case configCancelAction:
+		// 取消输入：config:cancel:<发起输入的用户 ID>
+		if len(parts) < 3 {
+			return "❌ 缺少用户 ID", false, fmt.Errorf("missing user ID")
+		}
+		ownerID, parseErr := strconv.ParseInt(parts[2], 10, 64)
+		if parseErr != nil {
+			return "❌ 无效的回调数据", false, fmt.Errorf("invalid cancel owner: %s", parts[2])
+		}
+		if ownerID != userID {
+			return "⚠️ 只有发起输入的管理员可以取消", false, nil
+		}
+		s.ClearUserState(chatID, userID)
+		logger.Ctx(ctx).Infof("User state cancelled: chat_id=%d, user_id=%d", chatID, userID)
+		return "🚫 已取消输入", true, nil
+
 	case string(models.ConfigTypeToggle):
 		if len(parts) < 3 {
 			return "❌ 缺少配置项 ID", false, fmt.Errorf("missing config ID")
@@ -195,7 +226,7 @@ func (s *ConfigMenuService) handleToggle(ctx context.Context, group *models.Grou
 		statusText = "开启"
 	}
 
-	logger.L().Infof("Config toggle updated: chat_id=%d, config=%s, value=%v", group.TelegramID, configID, newValue)
+	logger.Ctx(ctx).Infof("Config toggle updated: chat_id=%d, config=%s, value=%v", group.TelegramID, configID, newValue)
 	return fmt.Sprintf("✅ %s 已%s", item.Name, statusText), true, nil
 }
 
@@ -228,7 +259,7 @@ func (s *ConfigMenuService) handleSelect(ctx context.Context, group *models.Grou
 		return "❌ 更新配置失败", false, err
 	}
 
-	logger.L().Infof("Config select updated: chat_id=%d, config=%s, value=%s", group.TelegramID, configID, nextOption.Value)
+	logger.Ctx(ctx).Infof("Config select updated: chat_id=%d, config=%s, value=%s", group.TelegramID, configID, nextOption.Value)
 	return fmt.Sprintf("✅ %s 已设置为：%s %s", item.Name, nextOption.Icon, nextOption.Label), true, nil
 }
 
@@ -241,18 +272,20 @@ func (s *ConfigMenuService) handleInput(ctx context.Context, chatID, userID int6
 	}
 
 	// 设置用户状态
+	now := time.Now()
 	state := &models.UserState{
 		UserID:     userID,
 		ChatID:     chatID,
 		Action:     fmt.Sprintf("input:%s", configID),
-		ExpiresAt:  time.Now().Add(5 * time.Minute).Unix(), // 5分钟过期
-		RetryCount: 0,                                      // 初始化重试次数
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(UserStateTTL).Unix(),
+		RetryCount: 0, // 初始化重试次数
 		Context:    ctx,
 	}
 	s.SetUserState(chatID, userID, state)
 
-	logger.L().Infof("User state set: chat_id=%d, user_id=%d, action=%s", chatID, userID, state.Action)
-	return fmt.Sprintf("📝 %s\n\n请在 5 分钟内发送文本消息：", item.InputPrompt), false, nil
+	logger.Ctx(ctx).Infof("User state set: chat_id=%d, user_id=%d, action=%s", chatID, userID, state.Action)
+	return fmt.Sprintf("📝 %s\n\n请在 %d 分钟内发送文本消息：", item.InputPrompt, int(UserStateTTL.Minutes())), false, nil
 }
 
 // handleAction 处理动作型配置（执行自定义操作）
@@ -276,11 +309,11 @@ func (s *ConfigMenuService) handleAction(ctx context.Context, chatID, userID int
 
 	// 执行操作
 	if err := handler(ctx, chatID, userID); err != nil {
-		logger.L().Errorf("Action handler failed: config=%s, error=%v", configID, err)
+		logger.Ctx(ctx).Errorf("Action handler failed: config=%s, error=%v", configID, err)
 		return fmt.Sprintf("❌ 操作失败: %v", err), false, err
 	}
 
-	logger.L().Infof("Action executed: chat_id=%d, config=%s", chatID, configID)
+	logger.Ctx(ctx).Infof("Action executed: chat_id=%d, config=%s", chatID, configID)
 	return fmt.Sprintf("✅ %s 执行成功", item.Name), true, nil
 }
 
@@ -294,18 +327,12 @@ func (s *ConfigMenuService) ProcessUserInput(
 	items []models.ConfigItem,
 ) (message string, err error) {
 	chatID := group.TelegramID
-	// 获取用户状态
+	// 获取用户状态（过期状态在取用时已清理，本条消息按普通消息处理）
 	state := s.GetUserState(chatID, userID)
 	if state == nil {
 		return "", nil // 用户没有待处理状态
 	}
 
-	// 检查是否过期
-	if time.Now().Unix() > state.ExpiresAt {
-		s.ClearUserState(chatID, userID)
-		return "⏰ 输入超时，请重新打开配置菜单", fmt.Errorf("user state expired")
-	}
-
 	// 解析状态：input:config_id
 	parts := strings.Split(state.Action, ":")
 	if len(parts) != 2 || parts[0] != "input" {
@@ -331,7 +358,7 @@ func (s *ConfigMenuService) ProcessUserInput(
 			if state.RetryCount >= MaxInputRetries {
 				// 超过最大重试次数，清除状态
 				s.ClearUserState(chatID, userID)
-				logger.L().Warnf("User exceeded max input retries: chat_id=%d, user_id=%d, config=%s", chatID, userID, configID)
+				logger.Ctx(ctx).Warnf("User exceeded max input retries: chat_id=%d, user_id=%d, config=%s", chatID, userID, configID)
 				return fmt.Sprintf("❌ 输入验证失败次数过多\n\n错误: %v\n\n请重新打开配置菜单", err), fmt.Errorf("max retries exceeded")
 			}
 
@@ -352,24 +379,44 @@ func (s *ConfigMenuService) ProcessUserInput(
 	// 清除用户状态
 	s.ClearUserState(chatID, userID)
 
-	logger.L().Infof("Config input updated: chat_id=%d, config=%s", chatID, configID)
+	logger.Ctx(ctx).Infof("Config input updated: chat_id=%d, config=%s", chatID, configID)
 	return fmt.Sprintf("✅ %s 已更新", item.Name), nil
 }
 
-// SetUserState 设置用户状态
+// SetUserState 设置用户状态，顺带清理其他已过期的状态
 func (s *ConfigMenuService) SetUserState(chatID, userID int64, state *models.UserState) {
+	s.PurgeExpiredUserStates(time.Now())
 	key := fmt.Sprintf("%d:%d", chatID, userID)
 	s.userStates.Store(key, state)
 }
 
-// GetUserState 获取用户状态
+// GetUserState 获取用户状态，已过期的状态会被清除并返回 nil
 func (s *ConfigMenuService) GetUserState(chatID, userID int64) *models.UserState {
 	key := fmt.Sprintf("%d:%d", chatID, userID)
 	val, ok := s.userStates.Load(key)
 	if !ok {
 		return nil
 	}
-	return val.(*models.UserState)
+	state := val.(*models.UserState)
+	if state.IsExpired(time.Now()) {
+		s.userStates.CompareAndDelete(key, state)
+		logger.L().Infof("User state expired: chat_id=%d, user_id=%d, action=%s", chatID, userID, state.Action)
+		return nil
+	}
+	return state
+}
+
+// PurgeExpiredUserStates 清理所有已过期的输入状态，返回清理数量
+func (s *ConfigMenuService) PurgeExpiredUserStates(now time.Time) int {
+	purged := 0
+	s.userStates.Range(func(key, val any) bool {
+		state := val.(*models.UserState)
+		if state.IsExpired(now) && s.userStates.CompareAndDelete(key, state) {
+			purged++
+		}
+		return true
+	})
+	return purged
 }
 
 // ClearUserState 清除用户状态
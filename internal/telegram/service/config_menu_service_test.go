@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"go_bot/internal/telegram/models"
 )
@@ -36,24 +37,60 @@ func (s *stubGroupService) ListActiveGroups(ctx context.Context) ([]*models.Grou
 	return nil, nil
 }
 
+func (s *stubGroupService) IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error {
+	return nil
+}
+
 func (s *stubGroupService) UpdateGroupSettings(ctx context.Context, telegramID int64, settings models.GroupSettings) error {
 	s.updateCalls++
 	s.lastSettings = settings
 	return nil
 }
 
-func (s *stubGroupService) LeaveGroup(ctx context.Context, telegramID int64) error {
+func (s *stubGroupService) RecordMemberChange(ctx context.Context, telegramID int64, joined, left int) error {
 	return nil
 }
 
-func (s *stubGroupService) HandleBotAddedToGroup(ctx context.Context, group *models.Group) error {
+func (s *stubGroupService) SyncMemberCount(ctx context.Context, telegramID int64, count int) error {
+	return nil
+}
+
+func (s *stubGroupService) LeaveGroup(ctx context.Context, telegramID int64) error {
 	return nil
 }
 
+func (s *stubGroupService) HandleBotAddedToGroup(ctx context.Context, group *models.Group) (bool, error) {
+	return false, nil
+}
+
 func (s *stubGroupService) HandleBotRemovedFromGroup(ctx context.Context, telegramID int64, reason string) error {
 	return nil
 }
 
+func (s *stubGroupService) UpdateGroupLabels(ctx context.Context, telegramID int64, note string, tags []string) error {
+	return nil
+}
+
+func (s *stubGroupService) AddGroupAdmin(ctx context.Context, telegramID, userID int64) error {
+	return nil
+}
+
+func (s *stubGroupService) RemoveGroupAdmin(ctx context.Context, telegramID, userID int64) error {
+	return nil
+}
+
+func (s *stubGroupService) AddCommandAlias(ctx context.Context, telegramID int64, alias, command string) error {
+	return nil
+}
+
+func (s *stubGroupService) RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error {
+	return nil
+}
+
+func (s *stubGroupService) PurgeExpiredGroups(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
 func (s *stubGroupService) ValidateGroups(ctx context.Context) (*GroupValidationResult, error) {
 	return &GroupValidationResult{}, nil
 }
@@ -62,6 +99,14 @@ func (s *stubGroupService) RepairGroups(ctx context.Context) (*GroupRepairResult
 	return &GroupRepairResult{}, nil
 }
 
+func (s *stubGroupService) ExportGroupConfigs(ctx context.Context) (*models.GroupConfigExport, error) {
+	return &models.GroupConfigExport{}, nil
+}
+
+func (s *stubGroupService) ImportGroupConfigs(ctx context.Context, export *models.GroupConfigExport, opts GroupConfigImportOptions) (*GroupConfigImportResult, error) {
+	return &GroupConfigImportResult{DryRun: opts.DryRun}, nil
+}
+
 func TestConfigMenuServiceHandleToggle_DisabledWhenSifangOff(t *testing.T) {
 	svc := NewConfigMenuService(&stubGroupService{})
 	group := &models.Group{Settings: models.GroupSettings{
@@ -158,3 +203,81 @@ func TestConfigMenuServiceHandleToggle_TogglesWhenAvailable(t *testing.T) {
 		t.Fatalf("expected persisted setting to be false")
 	}
 }
+
+func TestConfigMenuServiceIgnoresExpiredUserState(t *testing.T) {
+	svc := NewConfigMenuService(&stubGroupService{})
+	group := &models.Group{TelegramID: -100}
+	items := []models.ConfigItem{
+		{
+			ID:          "timezone",
+			Type:        models.ConfigTypeInput,
+			Name:        "群组时区",
+			InputSetter: func(s *models.GroupSettings, val string) { s.Timezone = val },
+		},
+	}
+
+	created := time.Now().Add(-UserStateTTL - time.Minute)
+	svc.SetUserState(group.TelegramID, 1, &models.UserState{
+		UserID:    1,
+		ChatID:    group.TelegramID,
+		Action:    "input:timezone",
+		CreatedAt: created,
+		ExpiresAt: created.Add(UserStateTTL).Unix(),
+	})
+
+	msg, err := svc.ProcessUserInput(context.Background(), group, 1, "Asia/Tokyo", items)
+	if msg != "" || err != nil {
+		t.Fatalf("expected expired state to be ignored, got msg=%q err=%v", msg, err)
+	}
+	if group.Settings.Timezone != "" {
+		t.Fatalf("expected settings untouched, got timezone %q", group.Settings.Timezone)
+	}
+	if svc.GetUserState(group.TelegramID, 1) != nil {
+		t.Fatal("expected expired state to be cleared")
+	}
+}
+
+func TestConfigMenuServicePurgeExpiredUserStates(t *testing.T) {
+	svc := NewConfigMenuService(&stubGroupService{})
+	now := time.Now()
+	svc.userStates.Store("-1:1", &models.UserState{ExpiresAt: now.Add(-time.Second).Unix()})
+	svc.userStates.Store("-1:2", &models.UserState{ExpiresAt: now.Add(time.Minute).Unix()})
+
+	if purged := svc.PurgeExpiredUserStates(now); purged != 1 {
+		t.Fatalf("expected 1 purged state, got %d", purged)
+	}
+	if svc.GetUserState(-1, 2) == nil {
+		t.Fatal("expected live state to be kept")
+	}
+}
+
+func TestConfigMenuServiceCancelInput(t *testing.T) {
+	svc := NewConfigMenuService(&stubGroupService{})
+	group := &models.Group{TelegramID: -100}
+	items := []models.ConfigItem{{ID: "timezone", Type: models.ConfigTypeInput, Name: "群组时区", InputPrompt: "请输入时区"}}
+
+	if _, _, err := svc.HandleCallback(context.Background(), group, 1, "config:input:timezone", items); err != nil {
+		t.Fatalf("unexpected error entering input state: %v", err)
+	}
+	keyboard := svc.BuildInputCancelKeyboard(1)
+	data := keyboard.InlineKeyboard[0][0].CallbackData
+	if data != "config:cancel:1" {
+		t.Fatalf("unexpected cancel callback data: %q", data)
+	}
+
+	msg, shouldUpdate, err := svc.HandleCallback(context.Background(), group, 2, data, items)
+	if err != nil || shouldUpdate || msg != "⚠️ 只有发起输入的管理员可以取消" {
+		t.Fatalf("expected other admins to be rejected, got msg=%q update=%v err=%v", msg, shouldUpdate, err)
+	}
+	if svc.GetUserState(group.TelegramID, 1) == nil {
+		t.Fatal("expected state to survive a foreign cancel")
+	}
+
+	msg, shouldUpdate, err = svc.HandleCallback(context.Background(), group, 1, data, items)
+	if err != nil || !shouldUpdate || msg != "🚫 已取消输入" {
+		t.Fatalf("unexpected cancel result: msg=%q update=%v err=%v", msg, shouldUpdate, err)
+	}
+	if svc.GetUserState(group.TelegramID, 1) != nil {
+		t.Fatal("expected state to be cleared after cancel")
+	}
+}
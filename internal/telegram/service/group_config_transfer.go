@@ -0,0 +1,141 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+)
+
+// GroupConfigImportOptions 配置导入选项
+type GroupConfigImportOptions struct {
+	DryRun bool // 仅计算差异，不写入
+	// IncludeMerchants 同时导入商户号绑定（MerchantID / MerchantIDs），默认保留当前环境的绑定
+	IncludeMerchants bool
+	// 执行导入的用户，写入商户号变更记录
+	OperatorID       int64
+	OperatorUsername string
+	OperatorName     string
+}
+
+// GroupConfigImportResult 配置导入（或 dry-run 预览）的结果
+type GroupConfigImportResult struct {
+	DryRun    bool                // 是否仅预览，未写入数据库
+	Total     int                 // 文件中的群组数
+	Changes   []GroupConfigChange // 配置有差异的群组
+	Unchanged int                 // 配置完全一致的群组数
+	Missing   []int64             // 当前环境不存在的群 ID（跳过，不会新建群组）
+	Applied   int                 // 实际写入成功的群组数（dry-run 时为 0）
+	Failed    []GroupConfigFailure
+}
+
+// GroupConfigChange 单个群组的配置差异
+type GroupConfigChange struct {
+	GroupID int64
+	Title   string
+	Fields  []string // 有差异的 GroupSettings 字段名
+}
+
+// GroupConfigFailure 单个群组写入失败的原因
+type GroupConfigFailure struct {
+	GroupID int64
+	Reason  string
+}
+
+// ExportGroupConfigs 导出全部群组（含已离群的）的配置，按群 ID 排序
+func (s *GroupServiceImpl) ExportGroupConfigs(ctx context.Context) (*models.GroupConfigExport, error) {
+	groups, err := s.groupRepo.ListAllGroups(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list groups for config export: %v", err)
+		return nil, fmt.Errorf("获取群组列表失败")
+	}
+
+	export := &models.GroupConfigExport{
+		Version:    models.GroupConfigExportVersion,
+		ExportedAt: time.Now(),
+		Groups:     make([]models.GroupConfigEntry, 0, len(groups)),
+	}
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		export.Groups = append(export.Groups, models.NewGroupConfigEntry(group))
+	}
+	slices.SortFunc(export.Groups, func(a, b models.GroupConfigEntry) int {
+		return cmp.Compare(a.TelegramID, b.TelegramID)
+	})
+
+	logger.Ctx(ctx).Infof("Group configs exported: groups=%d", len(export.Groups))
+	return export, nil
+}
+
+// ImportGroupConfigs 按群 ID 把导出文件中的配置写回 UpdateSettings；DryRun 时只计算差异不写入
+// 当前环境不存在的群组会被跳过，不会凭导出文件新建群组。群级管理员（AdminIDs）属于授权数据，始终保留当前值；
+// 商户号绑定仅在 IncludeMerchants 时导入，变更写入商户号历史
+func (s *GroupServiceImpl) ImportGroupConfigs(ctx context.Context, export *models.GroupConfigExport, opts GroupConfigImportOptions) (*GroupConfigImportResult, error) {
+	if export == nil {
+		return nil, fmt.Errorf("配置文件为空")
+	}
+	groups, err := s.groupRepo.ListAllGroups(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list groups for config import: %v", err)
+		return nil, fmt.Errorf("获取群组列表失败")
+	}
+	existing := make(map[int64]*models.Group, len(groups))
+	for _, group := range groups {
+		if group != nil {
+			existing[group.TelegramID] = group
+		}
+	}
+
+	dryRun := opts.DryRun
+	result := &GroupConfigImportResult{DryRun: dryRun, Total: len(export.Groups)}
+	for _, entry := range export.Groups {
+		group, ok := existing[entry.TelegramID]
+		if !ok {
+			result.Missing = append(result.Missing, entry.TelegramID)
+			continue
+		}
+		before := group.Settings
+		settings := importedSettings(before, entry.Settings, opts.IncludeMerchants)
+		fields := models.DiffGroupSettings(before, settings)
+		if len(fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+		result.Changes = append(result.Changes, GroupConfigChange{GroupID: group.TelegramID, Title: group.Title, Fields: fields})
+		if dryRun {
+			continue
+		}
+		if err := s.UpdateGroupSettings(ctx, group.TelegramID, settings); err != nil {
+			result.Failed = append(result.Failed, GroupConfigFailure{GroupID: group.TelegramID, Reason: err.Error()})
+			continue
+		}
+		s.recordMerchantHistory(ctx, &models.MerchantHistory{
+			ChatID:           group.TelegramID,
+			Action:           models.MerchantHistoryImport,
+			OperatorID:       opts.OperatorID,
+			OperatorUsername: opts.OperatorUsername,
+			OperatorName:     opts.OperatorName,
+		}, before, settings)
+		result.Applied++
+	}
+
+	logger.Ctx(ctx).Infof("Group configs imported: dry_run=%t merchants=%t total=%d changed=%d unchanged=%d missing=%d applied=%d failed=%d",
+		dryRun, opts.IncludeMerchants, result.Total, len(result.Changes), result.Unchanged, len(result.Missing), result.Applied, len(result.Failed))
+	return result, nil
+}
+
+// importedSettings 返回导入后实际写入的配置：群级管理员保留当前值，未确认导入商户号时同样保留当前绑定
+func importedSettings(current, imported models.GroupSettings, includeMerchants bool) models.GroupSettings {
+	imported.AdminIDs = current.AdminIDs
+	if !includeMerchants {
+		imported.MerchantID = current.MerchantID
+		imported.MerchantIDs = current.MerchantIDs
+	}
+	return imported
+}
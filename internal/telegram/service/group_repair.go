@@ -19,9 +19,10 @@ type GroupRepairResult struct {
 
 // RepairGroups 自动修复可矫正的问题，如缺少 tier 或四方开关冲突
 func (s *GroupServiceImpl) RepairGroups(ctx context.Context) (*GroupRepairResult, error) {
+	defer s.cache.reset()
 	groups, err := s.groupRepo.ListAllGroups(ctx)
 	if err != nil {
-		logger.L().Errorf("Failed to list groups for repair: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to list groups for repair: %v", err)
 		return nil, fmt.Errorf("获取群组列表失败")
 	}
 
@@ -39,7 +40,7 @@ func (s *GroupServiceImpl) RepairGroups(ctx context.Context) (*GroupRepairResult
 		expectedTier, tierErr := models.DetermineGroupTier(group.Settings)
 		if tierErr != nil {
 			// 配置冲突，无法安全修复
-			logger.L().Warnf("Skip repairing group %d due to conflicting settings: %v", group.TelegramID, tierErr)
+			logger.Ctx(ctx).Warnf("Skip repairing group %d due to conflicting settings: %v", group.TelegramID, tierErr)
 			result.SkippedGroups++
 			continue
 		}
@@ -58,7 +59,7 @@ func (s *GroupServiceImpl) RepairGroups(ctx context.Context) (*GroupRepairResult
 		}
 
 		if err := s.groupRepo.UpdateSettings(ctx, group.TelegramID, settings, expectedTier); err != nil {
-			logger.L().Errorf("Failed to repair group %d: %v", group.TelegramID, err)
+			logger.Ctx(ctx).Errorf("Failed to repair group %d: %v", group.TelegramID, err)
 			result.SkippedGroups++
 			continue
 		}
@@ -68,7 +69,7 @@ func (s *GroupServiceImpl) RepairGroups(ctx context.Context) (*GroupRepairResult
 		}
 		result.UpdatedGroups++
 
-		logger.L().Infof("Group repaired: chat_id=%d tier_fixed=%t auto_lookup_disabled=%t",
+		logger.Ctx(ctx).Infof("Group repaired: chat_id=%d tier_fixed=%t auto_lookup_disabled=%t",
 			group.TelegramID, needsTierFix, needsAutoLookupFix)
 	}
 
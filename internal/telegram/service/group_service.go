@@ -3,58 +3,135 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/repository"
 )
 
+// DefaultGroupCacheTTL 群组信息缓存时长，写操作会立即失效对应群组
+const DefaultGroupCacheTTL = 5 * time.Second
+
 // GroupServiceImpl 群组服务实现
 type GroupServiceImpl struct {
-	groupRepo repository.GroupRepository
+	groupRepo     repository.GroupRepository
+	cache         *ttlCache[int64, *models.Group] // 群组信息短 TTL 缓存（存取均深拷贝），写操作时失效
+	adminsChanged func(telegramID int64)          // 群级管理员可能变化时回调，为 nil 时不通知
+	history       MerchantHistoryService          // 记录系统触发的商户号变更，为 nil 时不记录
+}
+
+// GroupServiceOption 群组服务的可选依赖
+type GroupServiceOption func(*GroupServiceImpl)
+
+// WithGroupAdminsChanged 写入可能改变 settings.admin_ids 时回调（通常为 UserService.InvalidateGroupAdminCache），
+// 让权限缓存随群配置一起失效
+func WithGroupAdminsChanged(fn func(telegramID int64)) GroupServiceOption {
+	return func(s *GroupServiceImpl) {
+		s.adminsChanged = fn
+	}
+}
+
+// WithMerchantHistory 记录由群组服务自动触发的商户号变更（离群自动解绑、重新入群恢复存档）
+func WithMerchantHistory(history MerchantHistoryService) GroupServiceOption {
+	return func(s *GroupServiceImpl) {
+		s.history = history
+	}
 }
 
 // NewGroupService 创建群组服务
-func NewGroupService(groupRepo repository.GroupRepository) GroupService {
-	return &GroupServiceImpl{
+func NewGroupService(groupRepo repository.GroupRepository, opts ...GroupServiceOption) GroupService {
+	s := &GroupServiceImpl{
 		groupRepo: groupRepo,
+		cache:     newTTLCache[int64](DefaultGroupCacheTTL, (*models.Group).Clone),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// recordMerchantChange 商户号绑定有变化时写入变更记录（操作人为系统），失败仅记录日志
+func (s *GroupServiceImpl) recordMerchantChange(ctx context.Context, telegramID int64, action string, before, after models.GroupSettings) {
+	s.recordMerchantHistory(ctx, &models.MerchantHistory{
+		ChatID:       telegramID,
+		Action:       action,
+		OperatorName: models.MerchantHistorySystemOperator,
+	}, before, after)
+}
+
+// recordMerchantHistory 商户号绑定有变化时补全变更前后的商户号并写入 entry，失败仅记录日志
+func (s *GroupServiceImpl) recordMerchantHistory(ctx context.Context, entry *models.MerchantHistory, before, after models.GroupSettings) {
+	if s.history == nil {
+		return
+	}
+	oldIDs, newIDs := models.BoundMerchantIDs(before), models.BoundMerchantIDs(after)
+	if before.MerchantID == after.MerchantID && slices.Equal(oldIDs, newIDs) {
+		return
+	}
+
+	entry.OldMerchantIDs, entry.NewMerchantIDs = oldIDs, newIDs
+	entry.OldCurrent, entry.NewCurrent = before.MerchantID, after.MerchantID
+	if err := s.history.Record(ctx, entry); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to record merchant history: chat_id=%d, action=%s, err=%v", entry.ChatID, entry.Action, err)
+	}
+}
+
+// invalidateAdmins 通知群级管理员可能已变化
+func (s *GroupServiceImpl) invalidateAdmins(telegramID int64) {
+	if s.adminsChanged != nil {
+		s.adminsChanged(telegramID)
 	}
 }
 
 // CreateOrUpdateGroup 创建或更新群组
 func (s *GroupServiceImpl) CreateOrUpdateGroup(ctx context.Context, group *models.Group) error {
+	defer s.cache.invalidate(group.TelegramID)
 	if err := s.groupRepo.CreateOrUpdate(ctx, group); err != nil {
-		logger.L().Errorf("Failed to create/update group %d: %v", group.TelegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to create/update group %d: %v", group.TelegramID, err)
 		return fmt.Errorf("failed to create/update group: %w", err)
 	}
 
-	logger.L().Infof("Group %d (%s) created/updated", group.TelegramID, group.Title)
+	logger.Ctx(ctx).Infof("Group %d (%s) created/updated", group.TelegramID, group.Title)
 	return nil
 }
 
-// GetGroupInfo 获取群组信息
+// GetGroupInfo 获取群组信息（优先读取短 TTL 缓存）
 func (s *GroupServiceImpl) GetGroupInfo(ctx context.Context, telegramID int64) (*models.Group, error) {
+	cached, ok, generation := s.cache.get(telegramID)
+	if ok {
+		return cached, nil
+	}
+
 	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
-		logger.L().Errorf("Failed to get group info for %d: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to get group info for %d: %v", telegramID, err)
 		return nil, fmt.Errorf("获取群组信息失败")
 	}
 	ensureGroupTier(group)
+	s.cache.put(group.TelegramID, group, generation)
 	return group, nil
 }
 
 // GetOrCreateGroup 获取或创建群组记录（智能处理，群组不存在时自动创建）
 func (s *GroupServiceImpl) GetOrCreateGroup(ctx context.Context, chatInfo *TelegramChatInfo) (*models.Group, error) {
+	cached, ok, generation := s.cache.get(chatInfo.ChatID)
+	if ok {
+		return cached, nil
+	}
+
 	// 先尝试获取
 	group, err := s.groupRepo.GetByTelegramID(ctx, chatInfo.ChatID)
 	if err == nil {
 		ensureGroupTier(group)
+		s.cache.put(group.TelegramID, group, generation)
 		return group, nil
 	}
 
 	// 不存在则创建默认群组记录
-	logger.L().Infof("Group %d not found, auto-creating...", chatInfo.ChatID)
+	logger.Ctx(ctx).Infof("Group %d not found, auto-creating...", chatInfo.ChatID)
 
 	newGroup := &models.Group{
 		TelegramID: chatInfo.ChatID,
@@ -79,19 +156,20 @@ func (s *GroupServiceImpl) GetOrCreateGroup(ctx context.Context, chatInfo *Teleg
 	}
 
 	if err := s.groupRepo.CreateOrUpdate(ctx, newGroup); err != nil {
-		logger.L().Errorf("Failed to auto-create group %d: %v", chatInfo.ChatID, err)
+		logger.Ctx(ctx).Errorf("Failed to auto-create group %d: %v", chatInfo.ChatID, err)
 		return nil, fmt.Errorf("自动创建群组失败")
 	}
 
 	// 再次查询以获取数据库填充的默认值
 	createdGroup, err := s.groupRepo.GetByTelegramID(ctx, chatInfo.ChatID)
 	if err != nil {
-		logger.L().Errorf("Failed to reload group %d after creation: %v", chatInfo.ChatID, err)
+		logger.Ctx(ctx).Errorf("Failed to reload group %d after creation: %v", chatInfo.ChatID, err)
 		return nil, fmt.Errorf("自动创建群组失败")
 	}
 	ensureGroupTier(createdGroup)
+	s.cache.put(createdGroup.TelegramID, createdGroup, generation)
 
-	logger.L().Infof("Auto-created group record: chat_id=%d, title=%s", chatInfo.ChatID, chatInfo.Title)
+	logger.Ctx(ctx).Infof("Auto-created group record: chat_id=%d, title=%s", chatInfo.ChatID, chatInfo.Title)
 	return createdGroup, nil
 }
 
@@ -104,7 +182,7 @@ func (s *GroupServiceImpl) FindGroupByInterfaceID(ctx context.Context, interface
 
 	group, err := s.groupRepo.FindByInterfaceID(ctx, cleanID)
 	if err != nil {
-		logger.L().Errorf("Failed to find group by interface ID %s: %v", cleanID, err)
+		logger.Ctx(ctx).Errorf("Failed to find group by interface ID %s: %v", cleanID, err)
 		return nil, fmt.Errorf("获取接口绑定群组失败")
 	}
 
@@ -114,12 +192,13 @@ func (s *GroupServiceImpl) FindGroupByInterfaceID(ctx context.Context, interface
 
 // MarkBotLeft 标记 Bot 离开群组
 func (s *GroupServiceImpl) MarkBotLeft(ctx context.Context, telegramID int64) error {
+	defer s.cache.invalidate(telegramID)
 	if err := s.groupRepo.UpdateBotStatus(ctx, telegramID, models.BotStatusLeft); err != nil {
-		logger.L().Errorf("Failed to mark bot left for group %d: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to mark bot left for group %d: %v", telegramID, err)
 		return fmt.Errorf("标记失败: %w", err)
 	}
 
-	logger.L().Infof("Bot left group %d", telegramID)
+	logger.Ctx(ctx).Infof("Bot left group %d", telegramID)
 	return nil
 }
 
@@ -127,7 +206,7 @@ func (s *GroupServiceImpl) MarkBotLeft(ctx context.Context, telegramID int64) er
 func (s *GroupServiceImpl) ListActiveGroups(ctx context.Context) ([]*models.Group, error) {
 	groups, err := s.groupRepo.ListActiveGroups(ctx)
 	if err != nil {
-		logger.L().Errorf("Failed to list active groups: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to list active groups: %v", err)
 		return nil, fmt.Errorf("获取活跃群组列表失败")
 	}
 	for _, group := range groups {
@@ -136,60 +215,284 @@ func (s *GroupServiceImpl) ListActiveGroups(ctx context.Context) ([]*models.Grou
 	return groups, nil
 }
 
+// IterateActiveGroups 分批遍历活跃群组
+func (s *GroupServiceImpl) IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error {
+	var callbackErr error
+	err := s.groupRepo.IterateActiveGroups(ctx, func(groups []*models.Group) error {
+		for _, group := range groups {
+			ensureGroupTier(group)
+		}
+		callbackErr = fn(groups)
+		return callbackErr
+	})
+	if err != nil && callbackErr == nil {
+		logger.Ctx(ctx).Errorf("Failed to iterate active groups: %v", err)
+		return fmt.Errorf("获取活跃群组列表失败")
+	}
+	return err
+}
+
 // UpdateGroupSettings 更新群组配置
 func (s *GroupServiceImpl) UpdateGroupSettings(ctx context.Context, telegramID int64, settings models.GroupSettings) error {
+	defer s.cache.invalidate(telegramID)
+	defer s.invalidateAdmins(telegramID)
 	settings.InterfaceBindings = models.NormalizeInterfaceBindings(settings.InterfaceBindings)
+	settings.MerchantIDs = models.BoundMerchantIDs(settings)
 
 	tier, err := models.DetermineGroupTier(settings)
 	if err != nil {
-		logger.L().Warnf("Failed to determine tier for group %d: %v", telegramID, err)
+		logger.Ctx(ctx).Warnf("Failed to determine tier for group %d: %v", telegramID, err)
 		return fmt.Errorf("更新群组配置失败: %w", err)
 	}
 
 	if err := s.groupRepo.UpdateSettings(ctx, telegramID, settings, tier); err != nil {
-		logger.L().Errorf("Failed to update group settings for %d: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to update group settings for %d: %v", telegramID, err)
 		return fmt.Errorf("更新群组配置失败: %w", err)
 	}
 
-	logger.L().Infof("Group settings updated: group_id=%d tier=%s", telegramID, tier)
+	logger.Ctx(ctx).Infof("Group settings updated: group_id=%d tier=%s", telegramID, tier)
+	return nil
+}
+
+// UpdateGroupLabels 更新群组的管理备注与标签
+func (s *GroupServiceImpl) UpdateGroupLabels(ctx context.Context, telegramID int64, note string, tags []string) error {
+	defer s.cache.invalidate(telegramID)
+	note, err := models.NormalizeGroupNote(note)
+	if err != nil {
+		return err
+	}
+	tags, err = models.NormalizeGroupTags(tags)
+	if err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.UpdateLabels(ctx, telegramID, note, tags); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to update group labels for %d: %v", telegramID, err)
+		return fmt.Errorf("更新群组备注失败")
+	}
+
+	logger.Ctx(ctx).Infof("Group labels updated: group_id=%d tags=%v", telegramID, tags)
+	return nil
+}
+
+// AddGroupAdmin 添加群级管理员
+func (s *GroupServiceImpl) AddGroupAdmin(ctx context.Context, telegramID, userID int64) error {
+	defer s.cache.invalidate(telegramID)
+	defer s.invalidateAdmins(telegramID)
+	if userID <= 0 {
+		return fmt.Errorf("无效的用户 ID")
+	}
+	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("群组不存在")
+	}
+	if group.Settings.IsGroupAdmin(userID) {
+		return fmt.Errorf("用户 %d 已是本群管理员", userID)
+	}
+	if len(group.Settings.AdminIDs) >= models.MaxGroupAdmins {
+		return fmt.Errorf("本群管理员已达上限（%d 人）", models.MaxGroupAdmins)
+	}
+
+	if err := s.groupRepo.AddAdmin(ctx, telegramID, userID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to add group admin: group_id=%d user_id=%d err=%v", telegramID, userID, err)
+		return fmt.Errorf("添加本群管理员失败")
+	}
+	logger.Ctx(ctx).Infof("Group admin added: group_id=%d user_id=%d", telegramID, userID)
+	return nil
+}
+
+// RemoveGroupAdmin 移除群级管理员
+func (s *GroupServiceImpl) RemoveGroupAdmin(ctx context.Context, telegramID, userID int64) error {
+	defer s.cache.invalidate(telegramID)
+	defer s.invalidateAdmins(telegramID)
+	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("群组不存在")
+	}
+	if !group.Settings.IsGroupAdmin(userID) {
+		return fmt.Errorf("用户 %d 不是本群管理员", userID)
+	}
+
+	if err := s.groupRepo.RemoveAdmin(ctx, telegramID, userID); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to remove group admin: group_id=%d user_id=%d err=%v", telegramID, userID, err)
+		return fmt.Errorf("移除本群管理员失败")
+	}
+	logger.Ctx(ctx).Infof("Group admin removed: group_id=%d user_id=%d", telegramID, userID)
 	return nil
 }
 
-// LeaveGroup Bot 离开群组（删除群组记录）
+// AddCommandAlias 添加群级命令别名
+func (s *GroupServiceImpl) AddCommandAlias(ctx context.Context, telegramID int64, alias, command string) error {
+	defer s.cache.invalidate(telegramID)
+	alias, err := models.NormalizeCommandAlias(alias)
+	if err != nil {
+		return err
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return fmt.Errorf("目标命令不能为空")
+	}
+	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("群组不存在")
+	}
+	if existing, ok := group.Settings.FindCommandAlias(alias); ok {
+		return fmt.Errorf("别名「%s」已存在（指向 %s）", alias, existing.Command)
+	}
+	if len(group.Settings.CommandAliases) >= models.MaxCommandAliases {
+		return fmt.Errorf("本群别名已达上限（%d 个）", models.MaxCommandAliases)
+	}
+
+	added, err := s.groupRepo.AddCommandAlias(ctx, telegramID, models.CommandAlias{Alias: alias, Command: command}, models.MaxCommandAliases)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to add command alias: group_id=%d alias=%s err=%v", telegramID, alias, err)
+		return fmt.Errorf("添加命令别名失败")
+	}
+	if !added {
+		// 读取后到写入前有并发添加，条件更新未命中
+		return fmt.Errorf("别名「%s」已存在或本群别名已达上限（%d 个）", alias, models.MaxCommandAliases)
+	}
+	logger.Ctx(ctx).Infof("Command alias added: group_id=%d alias=%s command=%s", telegramID, alias, command)
+	return nil
+}
+
+// RemoveCommandAlias 移除群级命令别名
+func (s *GroupServiceImpl) RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error {
+	defer s.cache.invalidate(telegramID)
+	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("群组不存在")
+	}
+	item, ok := group.Settings.FindCommandAlias(alias)
+	if !ok {
+		return fmt.Errorf("别名「%s」不存在", strings.TrimSpace(alias))
+	}
+
+	if err := s.groupRepo.RemoveCommandAlias(ctx, telegramID, item.Alias); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to remove command alias: group_id=%d alias=%s err=%v", telegramID, item.Alias, err)
+		return fmt.Errorf("移除命令别名失败")
+	}
+	logger.Ctx(ctx).Infof("Command alias removed: group_id=%d alias=%s", telegramID, item.Alias)
+	return nil
+}
+
+// RecordMemberChange 按入群/退群事件增减成员数，使用原子更新避免并发事件计数错乱
+func (s *GroupServiceImpl) RecordMemberChange(ctx context.Context, telegramID int64, joined, left int) error {
+	defer s.cache.invalidate(telegramID)
+	if joined < 0 || left < 0 {
+		return fmt.Errorf("成员变动人数不能为负数")
+	}
+	if joined == 0 && left == 0 {
+		return nil
+	}
+
+	if err := s.groupRepo.AdjustMemberCount(ctx, telegramID, joined, left); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to adjust member count for %d: %v", telegramID, err)
+		return fmt.Errorf("更新群成员数失败")
+	}
+	return nil
+}
+
+// SyncMemberCount 按 Telegram 返回的实际人数校准成员数
+func (s *GroupServiceImpl) SyncMemberCount(ctx context.Context, telegramID int64, count int) error {
+	defer s.cache.invalidate(telegramID)
+	if count < 0 {
+		return fmt.Errorf("成员数不能为负数")
+	}
+
+	if err := s.groupRepo.SetMemberCount(ctx, telegramID, count, time.Now()); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to sync member count for %d: %v", telegramID, err)
+		return fmt.Errorf("校准群成员数失败")
+	}
+
+	logger.Ctx(ctx).Infof("Group member count synced: group_id=%d count=%d", telegramID, count)
+	return nil
+}
+
+// LeaveGroup Bot 主动离开群组：存档配置并标记离开，保留期内重新入群可恢复
 func (s *GroupServiceImpl) LeaveGroup(ctx context.Context, telegramID int64) error {
 	// 检查群组是否存在
 	_, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
-		logger.L().Errorf("Group %d not found for leave: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Group %d not found for leave: %v", telegramID, err)
 		return fmt.Errorf("群组不存在")
 	}
 
-	// 删除群组记录
-	if err := s.groupRepo.DeleteGroup(ctx, telegramID); err != nil {
-		logger.L().Errorf("Failed to delete group %d: %v", telegramID, err)
+	if err := s.HandleBotRemovedFromGroup(ctx, telegramID, "left"); err != nil {
 		return fmt.Errorf("离开群组失败: %w", err)
 	}
 
-	logger.L().Infof("Bot left and deleted group %d", telegramID)
+	logger.Ctx(ctx).Infof("Bot left group %d, settings archived for %s", telegramID, models.GroupArchiveRetention)
 	return nil
 }
 
-// HandleBotAddedToGroup Bot 被添加到群组
-func (s *GroupServiceImpl) HandleBotAddedToGroup(ctx context.Context, group *models.Group) error {
+// HandleBotAddedToGroup Bot 被添加到群组，保留期内存在离群存档时恢复上次配置
+func (s *GroupServiceImpl) HandleBotAddedToGroup(ctx context.Context, group *models.Group) (bool, error) {
+	defer s.cache.invalidate(group.TelegramID)
+	existing, err := s.groupRepo.GetByTelegramID(ctx, group.TelegramID)
+	if err != nil {
+		existing = nil
+	}
+
 	// 设置状态为活跃
 	group.BotStatus = models.BotStatusActive
 
 	if err := s.groupRepo.CreateOrUpdate(ctx, group); err != nil {
-		logger.L().Errorf("Failed to handle bot added to group %d: %v", group.TelegramID, err)
-		return fmt.Errorf("记录 Bot 加入群组失败: %w", err)
+		logger.Ctx(ctx).Errorf("Failed to handle bot added to group %d: %v", group.TelegramID, err)
+		return false, fmt.Errorf("记录 Bot 加入群组失败: %w", err)
 	}
 
-	logger.L().Infof("Bot added to group %d (%s)", group.TelegramID, group.Title)
-	return nil
+	logger.Ctx(ctx).Infof("Bot added to group %d (%s)", group.TelegramID, group.Title)
+
+	if existing == nil || existing.ArchivedSettings == nil {
+		return false, nil
+	}
+	if !existing.HasRestorableArchive(time.Now()) {
+		logger.Ctx(ctx).Infof("Discarding expired group archive: group_id=%d", group.TelegramID)
+		s.clearArchive(ctx, group.TelegramID)
+		return false, nil
+	}
+	return s.restoreArchive(ctx, existing), nil
 }
 
-// HandleBotRemovedFromGroup Bot 被移出群组
+// restoreArchive 恢复离群存档，已被其他群绑定的接口 ID 会被跳过
+func (s *GroupServiceImpl) restoreArchive(ctx context.Context, group *models.Group) bool {
+	before := group.Settings
+	settings := *group.ArchivedSettings
+	bindings := make([]models.InterfaceBinding, 0, len(settings.InterfaceBindings))
+	for _, binding := range settings.InterfaceBindings {
+		owner, err := s.groupRepo.FindByInterfaceID(ctx, binding.ID)
+		if err == nil && owner != nil && owner.TelegramID != group.TelegramID {
+			logger.Ctx(ctx).Warnf("Skip restoring interface binding already owned by another group: group_id=%d interface_id=%s owner=%d",
+				group.TelegramID, binding.ID, owner.TelegramID)
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+	settings.InterfaceBindings = bindings
+
+	if err := s.UpdateGroupSettings(ctx, group.TelegramID, settings); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to restore archived group settings: group_id=%d err=%v", group.TelegramID, err)
+		return false
+	}
+	s.recordMerchantChange(ctx, group.TelegramID, models.MerchantHistoryRestore, before, settings)
+	s.clearArchive(ctx, group.TelegramID)
+
+	logger.Ctx(ctx).Infof("Archived group settings restored: group_id=%d merchant_id=%d interfaces=%d",
+		group.TelegramID, settings.MerchantID, len(bindings))
+	return true
+}
+
+func (s *GroupServiceImpl) clearArchive(ctx context.Context, telegramID int64) {
+	defer s.cache.invalidate(telegramID)
+	if err := s.groupRepo.UpdateArchive(ctx, telegramID, nil); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to clear group archive: group_id=%d err=%v", telegramID, err)
+	}
+}
+
+// HandleBotRemovedFromGroup Bot 被移出群组：存档当前配置后清空绑定，保留群组记录以便重新入群时恢复
 func (s *GroupServiceImpl) HandleBotRemovedFromGroup(ctx context.Context, telegramID int64, reason string) error {
+	defer s.cache.invalidate(telegramID)
 	// 根据原因设置不同的状态
 	status := models.BotStatusKicked
 	if reason == "left" {
@@ -200,38 +503,66 @@ func (s *GroupServiceImpl) HandleBotRemovedFromGroup(ctx context.Context, telegr
 	group, err := s.groupRepo.GetByTelegramID(ctx, telegramID)
 	if err == nil && group != nil {
 		ensureGroupTier(group)
+		before := group.Settings
 		settings := group.Settings
 		changed := false
 
+		// 仅在 Bot 仍处于活跃状态时存档，避免 /leave 后的离群事件用已清空的配置覆盖存档
+		if group.BotStatus == "" || group.IsActive() {
+			archive := group.Settings
+			archive.MerchantIDs = slices.Clone(archive.MerchantIDs)
+			archive.InterfaceBindings = slices.Clone(archive.InterfaceBindings)
+			if err := s.groupRepo.UpdateArchive(ctx, telegramID, &archive); err != nil {
+				logger.Ctx(ctx).Warnf("Failed to archive group settings before removal: group_id=%d, err=%v", telegramID, err)
+			}
+		}
+
 		if settings.MerchantID != 0 {
-			logger.L().Infof("Auto-unbinding merchant ID after bot removal: group_id=%d, merchant_id=%d", telegramID, settings.MerchantID)
+			logger.Ctx(ctx).Infof("Auto-unbinding merchant ID after bot removal: group_id=%d, merchant_id=%d", telegramID, settings.MerchantID)
 			settings.MerchantID = 0
+			settings.MerchantIDs = nil
 			changed = true
 		}
 
 		if len(settings.InterfaceBindings) > 0 {
-			logger.L().Infof("Auto-unbinding interface bindings after bot removal: group_id=%d, count=%d", telegramID, len(settings.InterfaceBindings))
+			logger.Ctx(ctx).Infof("Auto-unbinding interface bindings after bot removal: group_id=%d, count=%d", telegramID, len(settings.InterfaceBindings))
 			settings.InterfaceBindings = nil
 			changed = true
 		}
 
 		if changed {
 			if err := s.UpdateGroupSettings(ctx, telegramID, settings); err != nil {
-				logger.L().Warnf("Failed to auto-reset bindings when bot removed: group_id=%d, err=%v", telegramID, err)
+				logger.Ctx(ctx).Warnf("Failed to auto-reset bindings when bot removed: group_id=%d, err=%v", telegramID, err)
+			} else {
+				s.recordMerchantChange(ctx, telegramID, models.MerchantHistoryUnbind, before, settings)
 			}
 		}
 	}
 
 	// 标记 Bot 离开
 	if err := s.groupRepo.UpdateBotStatus(ctx, telegramID, status); err != nil {
-		logger.L().Errorf("Failed to handle bot removed from group %d: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to handle bot removed from group %d: %v", telegramID, err)
 		return fmt.Errorf("记录 Bot 离开群组失败: %w", err)
 	}
 
-	logger.L().Infof("Bot removed from group %d, reason=%s, status=%s", telegramID, reason, status)
+	logger.Ctx(ctx).Infof("Bot removed from group %d, reason=%s, status=%s", telegramID, reason, status)
 	return nil
 }
 
+// PurgeExpiredGroups 清理 Bot 离开超过保留期的群组记录
+func (s *GroupServiceImpl) PurgeExpiredGroups(ctx context.Context, now time.Time) (int64, error) {
+	defer s.cache.reset()
+	deleted, err := s.groupRepo.DeleteInactiveBefore(ctx, now.Add(-models.GroupArchiveRetention))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to purge expired groups: %v", err)
+		return 0, fmt.Errorf("清理过期群组失败: %w", err)
+	}
+	if deleted > 0 {
+		logger.Ctx(ctx).Infof("Purged %d groups left more than %s ago", deleted, models.GroupArchiveRetention)
+	}
+	return deleted, nil
+}
+
 func ensureGroupTier(group *models.Group) {
 	if group == nil {
 		return
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestGroupServiceCachesGroupInfo(t *testing.T) {
+	repo := &countingGroupRepository{stubGroupRepository: &stubGroupRepository{storedGroup: &models.Group{TelegramID: 1, Settings: models.GroupSettings{MerchantIDs: []int32{1001}}}}}
+	svc := NewGroupService(repo).(*GroupServiceImpl)
+	ctx := context.Background()
+
+	first, err := svc.GetGroupInfo(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 调用方直接修改返回值不应污染缓存
+	first.Settings.AccountingEnabled = true
+	first.Settings.MerchantIDs[0] = 9999
+
+	second, err := svc.GetOrCreateGroup(ctx, &TelegramChatInfo{ChatID: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.getCalls != 1 {
+		t.Fatalf("expected cached lookup to skip repository, got %d calls", repo.getCalls)
+	}
+	if second.Settings.AccountingEnabled || second.Settings.MerchantIDs[0] != 1001 {
+		t.Fatalf("expected cache to be isolated from caller mutation, got %+v", second.Settings)
+	}
+
+	if err := svc.UpdateGroupSettings(ctx, 1, models.GroupSettings{MerchantID: 1002}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if _, err := svc.GetGroupInfo(ctx, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.getCalls != 2 {
+		t.Fatalf("expected settings update to invalidate cache, got %d calls", repo.getCalls)
+	}
+}
+
+// countingGroupRepository 统计按群查询次数，用于确认缓存命中
+type countingGroupRepository struct {
+	*stubGroupRepository
+	getCalls int
+}
+
+func (r *countingGroupRepository) GetByTelegramID(ctx context.Context, telegramID int64) (*models.Group, error) {
+	r.getCalls++
+	return r.stubGroupRepository.GetByTelegramID(ctx, telegramID)
+}
+
+func TestGroupServiceNotifiesAdminChanges(t *testing.T) {
+	repo := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -100}}
+	var notified []int64
+	svc := NewGroupService(repo, WithGroupAdminsChanged(func(telegramID int64) {
+		notified = append(notified, telegramID)
+	}))
+	ctx := context.Background()
+
+	if err := svc.AddGroupAdmin(ctx, -100, 5); err != nil {
+		t.Fatalf("unexpected add error: %v", err)
+	}
+	if err := svc.UpdateGroupSettings(ctx, -100, models.GroupSettings{AdminIDs: []int64{6}}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if len(notified) != 2 || notified[0] != -100 || notified[1] != -100 {
+		t.Fatalf("expected admin cache invalidation for each write, got %v", notified)
+	}
+}
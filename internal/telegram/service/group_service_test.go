@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -21,9 +22,14 @@ type groupUpdateRecord struct {
 type stubGroupRepository struct {
 	storedGroup     *models.Group
 	allGroups       []*models.Group
+	iterateBatch    int
+	iterateErr      error
 	lastUpdatedTier models.GroupTier
 	updateCalls     int
 	updateHistory   []groupUpdateRecord
+	purgeCutoff     time.Time
+	memberJoined    int
+	memberLeft      int
 }
 
 func (s *stubGroupRepository) CreateOrUpdate(ctx context.Context, group *models.Group) error {
@@ -69,6 +75,11 @@ func (s *stubGroupRepository) FindByInterfaceID(ctx context.Context, interfaceID
 }
 
 func (s *stubGroupRepository) UpdateBotStatus(ctx context.Context, telegramID int64, status string) error {
+	if s.storedGroup != nil {
+		now := time.Now()
+		s.storedGroup.BotStatus = status
+		s.storedGroup.BotLeftAt = &now
+	}
 	return nil
 }
 
@@ -76,6 +87,58 @@ func (s *stubGroupRepository) DeleteGroup(ctx context.Context, telegramID int64)
 	return nil
 }
 
+func (s *stubGroupRepository) UpdateArchive(ctx context.Context, telegramID int64, settings *models.GroupSettings) error {
+	if s.storedGroup != nil {
+		s.storedGroup.ArchivedSettings = settings
+	}
+	return nil
+}
+
+func (s *stubGroupRepository) UpdateLabels(ctx context.Context, telegramID int64, note string, tags []string) error {
+	if s.storedGroup != nil {
+		s.storedGroup.Note = note
+		s.storedGroup.Tags = tags
+	}
+	return nil
+}
+
+func (s *stubGroupRepository) AddAdmin(ctx context.Context, telegramID, userID int64) error {
+	if s.storedGroup != nil && !slices.Contains(s.storedGroup.Settings.AdminIDs, userID) {
+		s.storedGroup.Settings.AdminIDs = append(s.storedGroup.Settings.AdminIDs, userID)
+	}
+	return nil
+}
+
+func (s *stubGroupRepository) RemoveAdmin(ctx context.Context, telegramID, userID int64) error {
+	if s.storedGroup != nil {
+		s.storedGroup.Settings.AdminIDs = slices.DeleteFunc(s.storedGroup.Settings.AdminIDs, func(id int64) bool { return id == userID })
+	}
+	return nil
+}
+
+func (s *stubGroupRepository) AddCommandAlias(ctx context.Context, telegramID int64, alias models.CommandAlias, maxAliases int) (bool, error) {
+	if s.storedGroup == nil {
+		return false, nil
+	}
+	if _, ok := s.storedGroup.Settings.FindCommandAlias(alias.Alias); ok || len(s.storedGroup.Settings.CommandAliases) >= maxAliases {
+		return false, nil
+	}
+	s.storedGroup.Settings.CommandAliases = append(s.storedGroup.Settings.CommandAliases, alias)
+	return true, nil
+}
+
+func (s *stubGroupRepository) RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error {
+	if s.storedGroup != nil {
+		s.storedGroup.Settings.CommandAliases = slices.DeleteFunc(s.storedGroup.Settings.CommandAliases, func(item models.CommandAlias) bool { return item.Alias == alias })
+	}
+	return nil
+}
+
+func (s *stubGroupRepository) DeleteInactiveBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	s.purgeCutoff = cutoff
+	return 0, nil
+}
+
 func (s *stubGroupRepository) ListAllGroups(ctx context.Context) ([]*models.Group, error) {
 	if s.allGroups != nil {
 		return s.allGroups, nil
@@ -90,6 +153,29 @@ func (s *stubGroupRepository) ListActiveGroups(ctx context.Context) ([]*models.G
 	return nil, nil
 }
 
+// IterateActiveGroups 按 iterateBatch（默认 2）分批返回 allGroups 中的活跃群组
+func (s *stubGroupRepository) IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error {
+	if s.iterateErr != nil {
+		return s.iterateErr
+	}
+	batchSize := s.iterateBatch
+	if batchSize <= 0 {
+		batchSize = 2
+	}
+	var active []*models.Group
+	for _, group := range s.allGroups {
+		if group.IsActive() {
+			active = append(active, group)
+		}
+	}
+	for start := 0; start < len(active); start += batchSize {
+		if err := fn(active[start:min(start+batchSize, len(active))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *stubGroupRepository) UpdateSettings(ctx context.Context, telegramID int64, settings models.GroupSettings, tier models.GroupTier) error {
 	s.updateCalls++
 	s.lastUpdatedTier = tier
@@ -112,7 +198,21 @@ func (s *stubGroupRepository) UpdateSettings(ctx context.Context, telegramID int
 	return nil
 }
 
-func (s *stubGroupRepository) UpdateStats(ctx context.Context, telegramID int64, stats models.GroupStats) error {
+func (s *stubGroupRepository) IncrementMessageStats(ctx context.Context, telegramID int64, count int64, lastMessageAt time.Time) error {
+	return nil
+}
+
+func (s *stubGroupRepository) AdjustMemberCount(ctx context.Context, telegramID int64, joined, left int) error {
+	s.memberJoined += joined
+	s.memberLeft += left
+	return nil
+}
+
+func (s *stubGroupRepository) SetMemberCount(ctx context.Context, telegramID int64, count int, syncedAt time.Time) error {
+	if s.storedGroup != nil {
+		s.storedGroup.MemberCount = count
+		s.storedGroup.Stats.MemberCountSyncedAt = syncedAt
+	}
 	return nil
 }
 
@@ -171,6 +271,54 @@ func TestUpdateGroupSettingsSetsMerchantTier(t *testing.T) {
 	}
 }
 
+func TestIterateActiveGroupsBatchesAndDerivesTier(t *testing.T) {
+	repo := &stubGroupRepository{allGroups: []*models.Group{
+		{TelegramID: -1, BotStatus: models.BotStatusActive, Settings: models.GroupSettings{MerchantID: 1}},
+		{TelegramID: -2, BotStatus: models.BotStatusLeft},
+		{TelegramID: -3, BotStatus: models.BotStatusActive},
+		{TelegramID: -4, BotStatus: models.BotStatusActive},
+	}}
+	service := NewGroupService(repo)
+
+	var batches [][]int64
+	err := service.IterateActiveGroups(context.Background(), func(groups []*models.Group) error {
+		var ids []int64
+		for _, group := range groups {
+			if group.Tier == "" {
+				t.Fatalf("expected tier derived for group %d", group.TelegramID)
+			}
+			ids = append(ids, group.TelegramID)
+		}
+		batches = append(batches, ids)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 2 || !slices.Equal(batches[0], []int64{-1, -3}) || !slices.Equal(batches[1], []int64{-4}) {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+	if repo.allGroups[0].Tier != models.GroupTierMerchant {
+		t.Fatalf("expected merchant tier, got %s", repo.allGroups[0].Tier)
+	}
+
+	// 回调返回的错误原样返回并停止遍历
+	stop := errors.New("stop")
+	calls := 0
+	if err := service.IterateActiveGroups(context.Background(), func([]*models.Group) error {
+		calls++
+		return stop
+	}); !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected callback error after 1 call, got err=%v calls=%d", err, calls)
+	}
+
+	// 仓储错误转换为用户可读错误
+	repo.iterateErr = errors.New("mongo down")
+	if err := service.IterateActiveGroups(context.Background(), func([]*models.Group) error { return nil }); err == nil || err.Error() != "获取活跃群组列表失败" {
+		t.Fatalf("expected wrapped repository error, got %v", err)
+	}
+}
+
 func TestUpdateGroupSettingsRejectsConflictingBindings(t *testing.T) {
 	repo := &stubGroupRepository{
 		storedGroup: &models.Group{TelegramID: 1},
@@ -221,6 +369,274 @@ func TestHandleBotRemovedFromGroupResetsBindings(t *testing.T) {
 	}
 }
 
+func TestBotRejoinRestoresArchivedSettings(t *testing.T) {
+	repo := &stubGroupRepository{
+		storedGroup: &models.Group{
+			TelegramID: 1,
+			BotStatus:  models.BotStatusActive,
+			Settings: models.GroupSettings{
+				MerchantID:    456,
+				MerchantIDs:   []int32{456, 789},
+				SifangEnabled: true,
+				Timezone:      "Asia/Tokyo",
+			},
+		},
+	}
+	history := &recordingMerchantHistory{}
+	service := NewGroupService(repo, WithMerchantHistory(history))
+
+	if err := service.LeaveGroup(context.Background(), 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if repo.storedGroup.Settings.MerchantID != 0 || repo.storedGroup.ArchivedSettings == nil {
+		t.Fatalf("expected bindings cleared and archived, got settings=%+v archive=%+v", repo.storedGroup.Settings, repo.storedGroup.ArchivedSettings)
+	}
+
+	// 离群事件再次到达时不能用已清空的配置覆盖存档
+	if err := service.HandleBotRemovedFromGroup(context.Background(), 1, "left"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	archived := repo.storedGroup
+
+	restored, err := service.HandleBotAddedToGroup(context.Background(), &models.Group{TelegramID: 1, Title: "group"})
+	if err != nil || !restored {
+		t.Fatalf("expected settings to be restored, got restored=%v err=%v", restored, err)
+	}
+	if archived.ArchivedSettings.MerchantID != 456 {
+		t.Fatalf("expected archive to keep original merchant, got %+v", archived.ArchivedSettings)
+	}
+
+	settings := repo.storedGroup.Settings
+	if settings.MerchantID != 456 || len(settings.MerchantIDs) != 2 || settings.Timezone != "Asia/Tokyo" || repo.storedGroup.Tier != models.GroupTierMerchant {
+		t.Fatalf("unexpected restored group: tier=%s settings=%+v", repo.storedGroup.Tier, settings)
+	}
+	if repo.storedGroup.ArchivedSettings != nil {
+		t.Fatalf("expected archive to be cleared after restore")
+	}
+
+	// 自动解绑与存档恢复各记一条商户号变更，重复的离群事件不重复记录
+	if len(history.entries) != 2 {
+		t.Fatalf("expected unbind and restore history, got %+v", history.entries)
+	}
+	unbind, restore := history.entries[0], history.entries[1]
+	if unbind.Action != models.MerchantHistoryUnbind || unbind.OldCurrent != 456 || unbind.NewCurrent != 0 || unbind.OperatorName != models.MerchantHistorySystemOperator {
+		t.Fatalf("unexpected unbind history: %+v", unbind)
+	}
+	if restore.Action != models.MerchantHistoryRestore || restore.NewCurrent != 456 || len(restore.NewMerchantIDs) != 2 {
+		t.Fatalf("unexpected restore history: %+v", restore)
+	}
+}
+
+// recordingMerchantHistory 记录写入的商户号变更
+type recordingMerchantHistory struct {
+	entries []*models.MerchantHistory
+}
+
+func (h *recordingMerchantHistory) Record(ctx context.Context, entry *models.MerchantHistory) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func (h *recordingMerchantHistory) ListRecent(ctx context.Context, chatID int64, limit int) ([]*models.MerchantHistory, error) {
+	return h.entries, nil
+}
+
+func TestBotRejoinIgnoresExpiredArchive(t *testing.T) {
+	leftAt := time.Now().Add(-models.GroupArchiveRetention - time.Hour)
+	repo := &stubGroupRepository{
+		storedGroup: &models.Group{
+			TelegramID:       1,
+			BotStatus:        models.BotStatusKicked,
+			BotLeftAt:        &leftAt,
+			ArchivedSettings: &models.GroupSettings{MerchantID: 456},
+		},
+	}
+	service := NewGroupService(repo)
+
+	restored, err := service.HandleBotAddedToGroup(context.Background(), &models.Group{TelegramID: 1})
+	if err != nil || restored {
+		t.Fatalf("expected expired archive to be ignored, got restored=%v err=%v", restored, err)
+	}
+	if repo.storedGroup.Settings.MerchantID != 0 || repo.updateCalls != 0 {
+		t.Fatalf("expected settings untouched, got %+v", repo.storedGroup.Settings)
+	}
+
+	now := time.Now()
+	if _, err := service.PurgeExpiredGroups(context.Background(), now); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !repo.purgeCutoff.Equal(now.Add(-models.GroupArchiveRetention)) {
+		t.Fatalf("unexpected purge cutoff: %s", repo.purgeCutoff)
+	}
+}
+
+func TestUpdateGroupLabelsNormalizesTags(t *testing.T) {
+	repo := &stubGroupRepository{storedGroup: &models.Group{TelegramID: 100}}
+	svc := NewGroupService(repo)
+
+	if err := svc.UpdateGroupLabels(context.Background(), 100, "  张三的商户群 ", []string{"#VIP", "vip", "测试", ""}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.storedGroup.Note != "张三的商户群" {
+		t.Fatalf("unexpected note: %q", repo.storedGroup.Note)
+	}
+	if !slices.Equal(repo.storedGroup.Tags, []string{"vip", "测试"}) {
+		t.Fatalf("unexpected tags: %v", repo.storedGroup.Tags)
+	}
+
+	if err := svc.UpdateGroupLabels(context.Background(), 100, "", []string{strings.Repeat("长", models.MaxGroupTagLength+1)}); err == nil {
+		t.Fatal("expected error for overlong tag")
+	}
+	if err := svc.UpdateGroupLabels(context.Background(), 100, strings.Repeat("注", models.MaxGroupNoteLength+1), nil); err == nil {
+		t.Fatal("expected error for overlong note")
+	}
+}
+
+func TestGroupAdminAddRemove(t *testing.T) {
+	repo := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -100}}
+	svc := NewGroupService(repo)
+	ctx := context.Background()
+
+	if err := svc.AddGroupAdmin(ctx, -100, 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.AddGroupAdmin(ctx, -100, 42); err == nil {
+		t.Fatal("expected error for duplicate group admin")
+	}
+	if !slices.Equal(repo.storedGroup.Settings.AdminIDs, []int64{42}) {
+		t.Fatalf("unexpected admin ids: %v", repo.storedGroup.Settings.AdminIDs)
+	}
+
+	if err := svc.RemoveGroupAdmin(ctx, -100, 7); err == nil {
+		t.Fatal("expected error when removing non-admin")
+	}
+	if err := svc.RemoveGroupAdmin(ctx, -100, 42); err != nil || len(repo.storedGroup.Settings.AdminIDs) != 0 {
+		t.Fatalf("remove failed: ids=%v err=%v", repo.storedGroup.Settings.AdminIDs, err)
+	}
+
+	for i := range models.MaxGroupAdmins {
+		repo.storedGroup.Settings.AdminIDs = append(repo.storedGroup.Settings.AdminIDs, int64(1000+i))
+	}
+	if err := svc.AddGroupAdmin(ctx, -100, 42); err == nil {
+		t.Fatal("expected error when group admin limit reached")
+	}
+}
+
+func TestCommandAliasAddRemove(t *testing.T) {
+	repo := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -100}}
+	svc := NewGroupService(repo)
+	ctx := context.Background()
+
+	if err := svc.AddCommandAlias(ctx, -100, " 查账 ", "查询记账"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.AddCommandAlias(ctx, -100, "查账", "清零记账"); err == nil {
+		t.Fatal("expected error for duplicate alias")
+	}
+	if err := svc.AddCommandAlias(ctx, -100, "查 账", "查询记账"); err == nil {
+		t.Fatal("expected error for alias containing spaces")
+	}
+	want := []models.CommandAlias{{Alias: "查账", Command: "查询记账"}}
+	if !slices.Equal(repo.storedGroup.Settings.CommandAliases, want) {
+		t.Fatalf("unexpected aliases: %v", repo.storedGroup.Settings.CommandAliases)
+	}
+
+	if err := svc.RemoveCommandAlias(ctx, -100, "对账"); err == nil {
+		t.Fatal("expected error when removing unknown alias")
+	}
+	if err := svc.RemoveCommandAlias(ctx, -100, "查账"); err != nil || len(repo.storedGroup.Settings.CommandAliases) != 0 {
+		t.Fatalf("remove failed: aliases=%v err=%v", repo.storedGroup.Settings.CommandAliases, err)
+	}
+
+	for i := range models.MaxCommandAliases {
+		repo.storedGroup.Settings.CommandAliases = append(repo.storedGroup.Settings.CommandAliases,
+			models.CommandAlias{Alias: fmt.Sprintf("a%d", i), Command: "/ping"})
+	}
+	if err := svc.AddCommandAlias(ctx, -100, "查账", "查询记账"); err == nil {
+		t.Fatal("expected error when alias limit reached")
+	}
+}
+
+func TestGroupConfigExportImport(t *testing.T) {
+	repo := &stubGroupRepository{allGroups: []*models.Group{
+		{TelegramID: -200, Title: "B", Settings: models.GroupSettings{CalculatorEnabled: true}},
+		{TelegramID: -100, Title: "A", Settings: models.GroupSettings{CryptoEnabled: true, CryptoFloatRate: 0.12}},
+	}}
+	svc := NewGroupService(repo)
+	ctx := context.Background()
+
+	export, err := svc.ExportGroupConfigs(ctx)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if export.Version != models.GroupConfigExportVersion || len(export.Groups) != 2 || export.Groups[0].TelegramID != -200 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+
+	export.Groups[1].Settings.CryptoFloatRate = 0.2
+	export.Groups = append(export.Groups, models.GroupConfigEntry{TelegramID: -300})
+
+	preview, err := svc.ImportGroupConfigs(ctx, export, GroupConfigImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run failed: %v", err)
+	}
+	if repo.updateCalls != 0 {
+		t.Fatalf("dry-run must not write, got %d updates", repo.updateCalls)
+	}
+	if preview.Total != 3 || preview.Unchanged != 1 || !slices.Equal(preview.Missing, []int64{-300}) ||
+		len(preview.Changes) != 1 || preview.Changes[0].GroupID != -100 || !slices.Equal(preview.Changes[0].Fields, []string{"CryptoFloatRate"}) {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+
+	applied, err := svc.ImportGroupConfigs(ctx, export, GroupConfigImportOptions{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if applied.Applied != 1 || repo.updateCalls != 1 || repo.updateHistory[0].groupID != -100 ||
+		repo.updateHistory[0].settings.CryptoFloatRate != 0.2 {
+		t.Fatalf("unexpected import result: %+v history=%+v", applied, repo.updateHistory)
+	}
+}
+
+func TestGroupConfigImportKeepsAdminsAndMerchants(t *testing.T) {
+	repo := &stubGroupRepository{allGroups: []*models.Group{
+		{TelegramID: -100, Title: "A", Settings: models.GroupSettings{AdminIDs: []int64{1}, MerchantID: 10, MerchantIDs: []int32{10}}},
+	}}
+	history := &recordingMerchantHistory{}
+	svc := NewGroupService(repo, WithMerchantHistory(history))
+	ctx := context.Background()
+
+	export := &models.GroupConfigExport{Groups: []models.GroupConfigEntry{{
+		TelegramID: -100,
+		Settings:   models.GroupSettings{AdminIDs: []int64{2}, MerchantID: 20, MerchantIDs: []int32{20}},
+	}}}
+
+	// 未确认导入商户号时，管理员与商户号都保留当前值，没有差异
+	result, err := svc.ImportGroupConfigs(ctx, export, GroupConfigImportOptions{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Unchanged != 1 || repo.updateCalls != 0 || len(history.entries) != 0 {
+		t.Fatalf("expected admins and merchants to be kept, got result=%+v updates=%d", result, repo.updateCalls)
+	}
+
+	result, err = svc.ImportGroupConfigs(ctx, export, GroupConfigImportOptions{IncludeMerchants: true, OperatorID: 7, OperatorName: "owner"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Applied != 1 || !slices.Equal(result.Changes[0].Fields, []string{"MerchantID", "MerchantIDs"}) {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	written := repo.updateHistory[0].settings
+	if written.MerchantID != 20 || !slices.Equal(written.AdminIDs, []int64{1}) {
+		t.Fatalf("unexpected written settings: %+v", written)
+	}
+	if len(history.entries) != 1 || history.entries[0].Action != models.MerchantHistoryImport ||
+		history.entries[0].OperatorID != 7 || history.entries[0].OldCurrent != 10 || history.entries[0].NewCurrent != 20 {
+		t.Fatalf("unexpected merchant history: %+v", history.entries)
+	}
+}
+
 func TestValidateGroupsHealthy(t *testing.T) {
 	now := time.Now()
 	repo := &stubGroupRepository{
@@ -431,3 +847,31 @@ func TestRepairGroupsSkipsConflictingSettings(t *testing.T) {
 }
 
 var _ repository.GroupRepository = (*stubGroupRepository)(nil)
+
+func TestGroupServiceMemberCount(t *testing.T) {
+	repo := &stubGroupRepository{storedGroup: &models.Group{TelegramID: 100}}
+	service := NewGroupService(repo)
+
+	if err := service.RecordMemberChange(context.Background(), 100, 3, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.RecordMemberChange(context.Background(), 100, 0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.memberJoined != 3 || repo.memberLeft != 1 {
+		t.Fatalf("expected joined=3 left=1, got joined=%d left=%d", repo.memberJoined, repo.memberLeft)
+	}
+	if err := service.RecordMemberChange(context.Background(), 100, -1, 0); err == nil {
+		t.Fatal("expected negative change to be rejected")
+	}
+
+	if err := service.SyncMemberCount(context.Background(), 100, 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.storedGroup.MemberCount != 42 || repo.storedGroup.Stats.MemberCountSyncedAt.IsZero() {
+		t.Fatalf("expected member count synced, got %+v", repo.storedGroup)
+	}
+	if err := service.SyncMemberCount(context.Background(), 100, -1); err == nil {
+		t.Fatal("expected negative count to be rejected")
+	}
+}
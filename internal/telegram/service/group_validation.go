@@ -19,6 +19,8 @@ type GroupValidationResult struct {
 type GroupValidationIssue struct {
 	GroupID    int64
 	Title      string
+	Note       string
+	Tags       []string
 	StoredTier models.GroupTier
 	BotStatus  string
 	Problems   []string
@@ -28,7 +30,7 @@ type GroupValidationIssue struct {
 func (s *GroupServiceImpl) ValidateGroups(ctx context.Context) (*GroupValidationResult, error) {
 	groups, err := s.groupRepo.ListAllGroups(ctx)
 	if err != nil {
-		logger.L().Errorf("Failed to list groups for validation: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to list groups for validation: %v", err)
 		return nil, fmt.Errorf("获取群组列表失败")
 	}
 
@@ -53,6 +55,8 @@ func (s *GroupServiceImpl) ValidateGroups(ctx context.Context) (*GroupValidation
 		result.Issues = append(result.Issues, GroupValidationIssue{
 			GroupID:    group.TelegramID,
 			Title:      title,
+			Note:       group.Note,
+			Tags:       group.Tags,
 			StoredTier: group.Tier,
 			BotStatus:  group.BotStatus,
 			Problems:   problems,
@@ -70,7 +74,7 @@ func (s *GroupServiceImpl) ValidateGroups(ctx context.Context) (*GroupValidation
 		}
 	})
 
-	logger.L().Infof("Group validation finished: total=%d issues=%d", result.TotalGroups, len(result.Issues))
+	logger.Ctx(ctx).Infof("Group validation finished: total=%d issues=%d", result.TotalGroups, len(result.Issues))
 	return result, nil
 }
 
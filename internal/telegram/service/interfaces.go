@@ -15,6 +15,9 @@ type UserService interface {
 	// GrantAdminPermission 授予管理员权限（包含业务验证）
 	GrantAdminPermission(ctx context.Context, targetID, grantedBy int64) error
 
+	// GrantRole 授予管理角色（admin/super_admin），也用于管理员之间的升降级
+	GrantRole(ctx context.Context, targetID int64, role string, grantedBy int64) error
+
 	// RevokeAdminPermission 撤销管理员权限（包含业务验证）
 	RevokeAdminPermission(ctx context.Context, targetID, revokedBy int64) error
 
@@ -24,14 +27,38 @@ type UserService interface {
 	// ListAllAdmins 列出所有管理员
 	ListAllAdmins(ctx context.Context) ([]*models.User, error)
 
-	// CheckOwnerPermission 检查是否为 Owner
-	CheckOwnerPermission(ctx context.Context, telegramID int64) (bool, error)
+	// ListAdminsPage 分页列出管理员，role 为空表示全部管理角色，page 从 1 开始
+	ListAdminsPage(ctx context.Context, role string, page, pageSize int) (*AdminPage, error)
+
+	// CheckAdminPermission 检查是否为全局 Admin+，或 chatID 所在群的群级管理员（chatID 为 0 或私聊时只判断全局）
+	CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error)
+
+	// InvalidateGroupAdminCache 丢弃指定群的管理员权限缓存（群级管理员变化后调用，避免撤权后仍按缓存放行）
+	InvalidateGroupAdminCache(chatID int64)
 
-	// CheckAdminPermission 检查是否为 Admin+
-	CheckAdminPermission(ctx context.Context, telegramID int64) (bool, error)
+	// HasPermission 检查用户是否拥有指定权限（角色预设 + 额外授予）
+	HasPermission(ctx context.Context, telegramID int64, perm string) (bool, error)
+
+	// UpdateUserPermissions 为管理员增减额外权限，返回更新后的用户
+	UpdateUserPermissions(ctx context.Context, targetID int64, add, remove []string, operatorID int64) (*models.User, error)
 
 	// UpdateUserActivity 更新用户活跃时间
 	UpdateUserActivity(ctx context.Context, telegramID int64) error
+
+	// SetPreferredLanguage 设置用户偏好语言（zh/en），lang 为空时恢复自动选择
+	SetPreferredLanguage(ctx context.Context, telegramID int64, lang string) error
+
+	// GetPreferredLanguage 获取用户偏好语言（带短 TTL 缓存），未设置时返回空字符串
+	GetPreferredLanguage(ctx context.Context, telegramID int64) (string, error)
+
+	// RecordStartSource 校验并记录 /start 深链接来源参数（仅记录首次来源）
+	RecordStartSource(ctx context.Context, telegramID int64, payload string) error
+
+	// PreviewInactiveUsers 统计最后活跃早于 cutoff 的普通用户数量，并返回最久未活跃的前 limit 个
+	PreviewInactiveUsers(ctx context.Context, cutoff time.Time, limit int64) (int64, []*models.User, error)
+
+	// PurgeInactiveUsers 存档并删除不活跃的普通用户（管理员与 Owner 永不清理），返回清理数量
+	PurgeInactiveUsers(ctx context.Context, cutoff time.Time, operatorID int64) (int64, error)
 }
 
 // GroupService 群组业务逻辑接口
@@ -54,23 +81,56 @@ type GroupService interface {
 	// ListActiveGroups 列出所有活跃群组
 	ListActiveGroups(ctx context.Context) ([]*models.Group, error)
 
+	// IterateActiveGroups 分批遍历活跃群组（每批不超过 200 个），避免一次性加载全部群组；fn 返回的错误原样返回
+	IterateActiveGroups(ctx context.Context, fn func(groups []*models.Group) error) error
+
 	// UpdateGroupSettings 更新群组配置
 	UpdateGroupSettings(ctx context.Context, telegramID int64, settings models.GroupSettings) error
 
-	// LeaveGroup Bot 离开群组（删除群组记录）
+	// UpdateGroupLabels 更新群组的管理备注与标签（校验长度并对标签去重）
+	UpdateGroupLabels(ctx context.Context, telegramID int64, note string, tags []string) error
+
+	// AddGroupAdmin 添加群级管理员（仅管本群），已存在或超过上限时返回错误
+	AddGroupAdmin(ctx context.Context, telegramID, userID int64) error
+
+	// RemoveGroupAdmin 移除群级管理员，不在列表中时返回错误
+	RemoveGroupAdmin(ctx context.Context, telegramID, userID int64) error
+
+	// AddCommandAlias 添加群级命令别名，别名已存在或超过上限时返回错误（与内置命令的冲突由调用方校验）
+	AddCommandAlias(ctx context.Context, telegramID int64, alias, command string) error
+
+	// RemoveCommandAlias 移除群级命令别名，不存在时返回错误
+	RemoveCommandAlias(ctx context.Context, telegramID int64, alias string) error
+
+	// RecordMemberChange 按入群/退群人数原子增减成员数
+	RecordMemberChange(ctx context.Context, telegramID int64, joined, left int) error
+
+	// SyncMemberCount 按 Telegram 实际人数校准成员数
+	SyncMemberCount(ctx context.Context, telegramID int64, count int) error
+
+	// LeaveGroup Bot 离开群组（存档配置并标记离开，超过保留期后清理）
 	LeaveGroup(ctx context.Context, telegramID int64) error
 
-	// HandleBotAddedToGroup Bot 被添加到群组
-	HandleBotAddedToGroup(ctx context.Context, group *models.Group) error
+	// HandleBotAddedToGroup Bot 被添加到群组，返回是否恢复了保留期内的上次配置
+	HandleBotAddedToGroup(ctx context.Context, group *models.Group) (bool, error)
 
 	// HandleBotRemovedFromGroup Bot 被移出群组
 	HandleBotRemovedFromGroup(ctx context.Context, telegramID int64, reason string) error
 
+	// PurgeExpiredGroups 清理 Bot 离开超过保留期的群组记录，返回清理数量
+	PurgeExpiredGroups(ctx context.Context, now time.Time) (int64, error)
+
 	// ValidateGroups 校验群组数据
 	ValidateGroups(ctx context.Context) (*GroupValidationResult, error)
 
 	// RepairGroups 自动修复可矫正的问题（例如缺失 tier、冲突开关）
 	RepairGroups(ctx context.Context) (*GroupRepairResult, error)
+
+	// ExportGroupConfigs 导出全部群组配置（不含凭据与运行数据），用于备份与迁移
+	ExportGroupConfigs(ctx context.Context) (*models.GroupConfigExport, error)
+
+	// ImportGroupConfigs 按群 ID 导入配置，opts.DryRun 时仅返回差异不写入
+	ImportGroupConfigs(ctx context.Context, export *models.GroupConfigExport, opts GroupConfigImportOptions) (*GroupConfigImportResult, error)
 }
 
 // MessageService 消息业务逻辑接口
@@ -78,8 +138,8 @@ type MessageService interface {
 	// HandleTextMessage 处理文本消息
 	HandleTextMessage(ctx context.Context, msg *TextMessageInfo) error
 
-	// HandleMediaMessage 处理媒体消息
-	HandleMediaMessage(ctx context.Context, msg *MediaMessageInfo) error
+	// HandleMediaMessage 处理媒体消息，stored 表示消息是否已记录（去重跳过时为 false）
+	HandleMediaMessage(ctx context.Context, msg *MediaMessageInfo) (stored bool, err error)
 
 	// HandleEditedMessage 处理消息编辑
 	HandleEditedMessage(ctx context.Context, telegramMessageID, chatID int64, newText string, editedAt time.Time) error
@@ -89,6 +149,36 @@ type MessageService interface {
 
 	// GetChatMessageHistory 获取聊天消息历史
 	GetChatMessageHistory(ctx context.Context, chatID int64, limit int) ([]*models.Message, error)
+
+	// SearchMessages 按关键词搜索聊天消息
+	SearchMessages(ctx context.Context, chatID int64, keyword string, limit int) ([]*models.Message, error)
+
+	// SearchMessagesByEntity 按 @提及、话题标签或链接检索聊天消息（kind 见 models.MessageEntitySearch*）
+	SearchMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int) ([]*models.Message, error)
+
+	// ListMentionMessages 列出本群提及指定用户（@用户名或无用户名提及的用户 ID）的消息，按发送时间倒序
+	ListMentionMessages(ctx context.Context, chatID int64, username string, userID int64, limit int) ([]*models.Message, error)
+
+	// GetMessageTypeStats 按类型统计消息数量，since 为零值时统计全部
+	GetMessageTypeStats(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error)
+
+	// ArchiveDeletedMessages 将已记录的消息转存到删除留档，返回成功留档的数量
+	ArchiveDeletedMessages(ctx context.Context, chatID int64, messageIDs []int64, source string, deletedAt time.Time) (int, error)
+
+	// ListDeletedMessages 列出最近被删除的消息，chatID 为 0 时不限聊天
+	ListDeletedMessages(ctx context.Context, chatID int64, limit int) ([]*models.DeletedMessage, error)
+
+	// GetMessageEditHistory 获取消息及其编辑历史
+	GetMessageEditHistory(ctx context.Context, chatID, telegramMessageID int64) (*models.Message, error)
+
+	// RecordMediaArchive 记录媒体消息的归档地址
+	RecordMediaArchive(ctx context.Context, chatID, telegramMessageID int64, archiveURL string) error
+
+	// ApplyChatRetention 按群组当前保留期重算本群已有消息的过期时间，返回生效天数与更新数量
+	ApplyChatRetention(ctx context.Context, chatID int64) (int, int64, error)
+
+	// Close 停止批量写入并 flush 缓冲中剩余的消息
+	Close(ctx context.Context)
 }
 
 // TelegramUserInfo Telegram 用户信息 DTO
@@ -101,6 +191,14 @@ type TelegramUserInfo struct {
 	IsPremium    bool
 }
 
+// AdminPage 管理员分页查询结果
+type AdminPage struct {
+	Admins     []*models.User
+	Total      int64
+	Page       int
+	TotalPages int
+}
+
 // TelegramChatInfo Telegram 群组信息 DTO
 type TelegramChatInfo struct {
 	ChatID   int64
@@ -115,6 +213,7 @@ type TextMessageInfo struct {
 	ChatID            int64
 	UserID            int64
 	Text              string
+	Entities          []models.MessageEntity // 文本中的链接、@提及、话题标签等实体
 	ReplyToMessageID  int64
 	SentAt            time.Time
 }
@@ -127,6 +226,7 @@ type MediaMessageInfo struct {
 	MessageType       string
 	Caption           string
 	MediaFileID       string
+	MediaFileUniqueID string // Telegram file_unique_id，同一文件跨消息不变，作为去重键
 	MediaFileSize     int64
 	MediaMimeType     string
 	SentAt            time.Time
@@ -153,12 +253,18 @@ type ForwardService interface {
 
 // AccountingService 收支记账业务逻辑接口
 type AccountingService interface {
-	// AddRecord 添加记账记录
-	AddRecord(ctx context.Context, chatID, userID int64, input string) error
+	// AddRecord 添加记账记录，返回记录及金额异常提醒信息
+	AddRecord(ctx context.Context, chatID, userID int64, input string) (*AccountingAddResult, error)
+
+	// AddLinkedExpense 联动写入一笔出账记录（如四方下发成功后），按 operationID 幂等，重复调用返回 created=false
+	AddLinkedExpense(ctx context.Context, chatID, userID int64, amount float64, currency, remark, operationID string) (bool, error)
 
-	// QueryRecords 查询并格式化账单
+	// QueryRecords 查询并格式化账单，汇总全部账本，存在多个账本时附分账本小计
 	QueryRecords(ctx context.Context, chatID int64) (string, error)
 
+	// QueryBookRecords 查询并格式化指定账本的账单，book 为空时为默认账本
+	QueryBookRecords(ctx context.Context, chatID int64, book string) (string, error)
+
 	// GetRecentRecordsForDeletion 获取最近2天记录（用于删除界面）
 	GetRecentRecordsForDeletion(ctx context.Context, chatID int64) ([]*models.AccountingRecord, error)
 
@@ -167,15 +273,26 @@ type AccountingService interface {
 
 	// ClearAllRecords 清空所有记录
 	ClearAllRecords(ctx context.Context, chatID int64) (int64, error)
+
+	// ClearBookRecords 清空指定账本的记录，book 为空时为默认账本
+	ClearBookRecords(ctx context.Context, chatID int64, book string) (int64, error)
+
+	// GetRecordsByDate 获取指定日期（按群组时区）的全部记录，用于删除前确认
+	GetRecordsByDate(ctx context.Context, chatID int64, date time.Time) ([]*models.AccountingRecord, error)
+
+	// DeleteByDate 删除指定日期（按群组时区）的全部记录，返回删除条数
+	DeleteByDate(ctx context.Context, chatID int64, date time.Time) (int64, error)
 }
 
 // UpstreamBalanceService 上游群余额业务接口
 type UpstreamBalanceService interface {
+	// Adjust 调整余额；operationID 非空时同一 ID 只生效一次（重复调用返回当前余额），为空时不去重
 	Adjust(ctx context.Context, groupID int64, delta float64, operatorID int64, remark string, operationID string) (*UpstreamBalanceResult, bool, error)
 	SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64) (*UpstreamBalanceResult, error)
 	SetAlertLimit(ctx context.Context, groupID int64, limit int, operatorID int64) (*UpstreamBalanceResult, error)
 	Get(ctx context.Context, groupID int64) (*UpstreamBalanceResult, error)
 	ListAll(ctx context.Context) ([]*UpstreamBalanceResult, error)
+	// SettleDaily 按账单日跑量 × 费率扣减余额，operationID 透传给 Adjust
 	SettleDaily(ctx context.Context, groupID int64, targetDate time.Time, operatorID int64, operationID string) (*SettlementResult, error)
 	SubscribeEvents() <-chan *models.UpstreamBalanceEvent
 }
@@ -198,3 +315,115 @@ type SettlementResult struct {
 	BelowMin       bool
 	Report         string
 }
+
+// AccountingAddResult 记账结果，Anomalous 为 true 表示单笔金额明显偏离近期均值（仍已记录）
+type AccountingAddResult struct {
+	Record    *models.AccountingRecord
+	Anomalous bool
+	Average   float64 // 同币种近期记录金额绝对值的均值
+	Ratio     int     // 生效的异常倍数
+}
+
+// MemberEventService 成员入群/退群统计业务接口
+type MemberEventService interface {
+	// RecordJoin 记录成员入群
+	RecordJoin(ctx context.Context, chatID int64, user *TelegramUserInfo, at time.Time) error
+
+	// RecordLeave 记录成员退群
+	RecordLeave(ctx context.Context, chatID int64, user *TelegramUserInfo, at time.Time) error
+
+	// GetMemberStats 统计 since 之后的入群、退群人数，并返回最近 leaveLimit 条退群记录
+	GetMemberStats(ctx context.Context, chatID int64, since time.Time, leaveLimit int) (*MemberStats, error)
+}
+
+// MemberStats 成员变动统计结果
+type MemberStats struct {
+	ChatID       int64
+	Since        time.Time
+	Joined       int64
+	Left         int64
+	Net          int64 // 净增长 = 入群 - 退群
+	RecentLeaves []*models.MemberEvent
+}
+
+// MerchantHistoryService 商户号变更记录业务接口
+type MerchantHistoryService interface {
+	// Record 记录一次商户号变更
+	Record(ctx context.Context, entry *models.MerchantHistory) error
+
+	// ListRecent 列出群组最近 limit 条变更记录（最新在前）
+	ListRecent(ctx context.Context, chatID int64, limit int) ([]*models.MerchantHistory, error)
+}
+
+// ScheduledMessageService 群组定时消息业务接口
+type ScheduledMessageService interface {
+	// Create 校验触发规则并注册定时消息
+	Create(ctx context.Context, input *ScheduledMessageInput) (*models.ScheduledMessage, error)
+
+	// ListByChat 列出群组的定时消息
+	ListByChat(ctx context.Context, chatID int64) ([]*models.ScheduledMessage, error)
+
+	// Delete 删除群组内指定的定时消息
+	Delete(ctx context.Context, chatID int64, id string) error
+
+	// ListDue 列出已到期的定时消息
+	ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error)
+
+	// NextRunAt 返回最早的下一次触发时间，没有定时消息时返回 nil
+	NextRunAt(ctx context.Context) (*time.Time, error)
+
+	// Advance 推进到 now 之后的下一次触发时间，sent 为 true 时记录本次发送时间
+	Advance(ctx context.Context, message *models.ScheduledMessage, now time.Time, sent bool) error
+
+	// ClaimRun 发送前认领本次触发，已被其他实例认领时返回 false
+	ClaimRun(ctx context.Context, message *models.ScheduledMessage, now time.Time) (bool, error)
+}
+
+// ScheduledMessageInput 注册定时消息的参数
+type ScheduledMessageInput struct {
+	ChatID    int64
+	CreatedBy int64
+	Kind      string // models.ScheduleKindDaily / models.ScheduleKindCron
+	Spec      string // daily 为 HH:MM，cron 为 5 段表达式
+	Text      string
+	Timezone  string // 群组时区，空值使用默认时区
+}
+
+// SendMoneyQuotaService 四方下发每日限额业务接口
+type SendMoneyQuotaService interface {
+	// Reserve 在每日限额内预占下发额度，超限时返回 Allowed=false 的结果
+	Reserve(ctx context.Context, chatID int64, amount, limit float64, at time.Time) (*SendMoneyQuota, error)
+
+	// Release 下发失败后回退已预占的额度
+	Release(ctx context.Context, quota *SendMoneyQuota) error
+}
+
+// SendMoneyQuota 返回每日下发额度占用情况
+type SendMoneyQuota struct {
+	ChatID    int64
+	Date      string  // 群组时区下的日期（YYYY-MM-DD）
+	Amount    float64 // 本次下发金额
+	Limit     float64 // 每日限额，0 表示不限
+	Used      float64 // 当天已下发总额（Allowed 时包含本次）
+	Remaining float64 // 剩余额度
+	Allowed   bool    // 是否允许本次下发
+}
+
+// CommandUsageService 命令使用统计业务接口
+type CommandUsageService interface {
+	// Record 记录一次命令（或功能）调用，只做内存累加，由后台定期落库
+	Record(command string)
+
+	// TopCommands 返回近 days 天（含今天）的命令调用排行，最多 limit 条
+	TopCommands(ctx context.Context, days, limit int) (*CommandUsageReport, error)
+
+	// Close 停止后台落库并写入剩余计数
+	Close(ctx context.Context)
+}
+
+// CommandUsageReport 命令使用排行
+type CommandUsageReport struct {
+	Since string // 起始日期（YYYY-MM-DD，含）
+	Total int64  // 期间全部命令调用次数
+	Ranks []models.CommandUsageRank
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+// MemberEventServiceImpl 成员变动统计服务
+type MemberEventServiceImpl struct {
+	repo repository.MemberEventRepository
+}
+
+// NewMemberEventService 创建服务实例
+func NewMemberEventService(repo repository.MemberEventRepository) MemberEventService {
+	return &MemberEventServiceImpl{repo: repo}
+}
+
+// RecordJoin 记录成员入群
+func (s *MemberEventServiceImpl) RecordJoin(ctx context.Context, chatID int64, user *TelegramUserInfo, at time.Time) error {
+	return s.record(ctx, chatID, user, models.MemberEventJoin, at)
+}
+
+// RecordLeave 记录成员退群
+func (s *MemberEventServiceImpl) RecordLeave(ctx context.Context, chatID int64, user *TelegramUserInfo, at time.Time) error {
+	return s.record(ctx, chatID, user, models.MemberEventLeave, at)
+}
+
+func (s *MemberEventServiceImpl) record(ctx context.Context, chatID int64, user *TelegramUserInfo, eventType string, at time.Time) error {
+	if user == nil {
+		return fmt.Errorf("成员信息不能为空")
+	}
+
+	event := &models.MemberEvent{
+		ChatID:     chatID,
+		UserID:     user.TelegramID,
+		Username:   user.Username,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		EventType:  eventType,
+		OccurredAt: at,
+	}
+	if err := s.repo.Create(ctx, event); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to record member event: chat_id=%d, user_id=%d, type=%s, error=%v",
+			chatID, user.TelegramID, eventType, err)
+		return fmt.Errorf("记录成员变动失败")
+	}
+	return nil
+}
+
+// GetMemberStats 统计 since 之后的入群、退群人数与最近退群名单
+func (s *MemberEventServiceImpl) GetMemberStats(ctx context.Context, chatID int64, since time.Time, leaveLimit int) (*MemberStats, error) {
+	counts, err := s.repo.CountByType(ctx, chatID, since)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to count member events: chat_id=%d, error=%v", chatID, err)
+		return nil, fmt.Errorf("统计成员变动失败")
+	}
+
+	leaves, err := s.repo.ListRecent(ctx, chatID, models.MemberEventLeave, since, int64(leaveLimit))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list member leaves: chat_id=%d, error=%v", chatID, err)
+		return nil, fmt.Errorf("查询退群名单失败")
+	}
+
+	stats := &MemberStats{
+		ChatID:       chatID,
+		Since:        since,
+		Joined:       counts[models.MemberEventJoin],
+		Left:         counts[models.MemberEventLeave],
+		RecentLeaves: leaves,
+	}
+	stats.Net = stats.Joined - stats.Left
+	return stats, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestMemberEventServiceStats(t *testing.T) {
+	repo := &memoryMemberEventRepository{}
+	svc := NewMemberEventService(repo)
+	ctx := context.Background()
+	base := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	_ = svc.RecordJoin(ctx, -1, &TelegramUserInfo{TelegramID: 1}, base.Add(-10*24*time.Hour))
+	_ = svc.RecordJoin(ctx, -1, &TelegramUserInfo{TelegramID: 2}, base.Add(-time.Hour))
+	_ = svc.RecordJoin(ctx, -1, &TelegramUserInfo{TelegramID: 3}, base.Add(-time.Hour))
+	_ = svc.RecordLeave(ctx, -1, &TelegramUserInfo{TelegramID: 1, Username: "old"}, base)
+	_ = svc.RecordLeave(ctx, -2, &TelegramUserInfo{TelegramID: 9}, base)
+
+	stats, err := svc.GetMemberStats(ctx, -1, base.Add(-7*24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Joined != 2 || stats.Left != 1 || stats.Net != 1 {
+		t.Fatalf("unexpected stats: joined=%d left=%d net=%d", stats.Joined, stats.Left, stats.Net)
+	}
+	if len(stats.RecentLeaves) != 1 || stats.RecentLeaves[0].Username != "old" {
+		t.Fatalf("unexpected recent leaves: %+v", stats.RecentLeaves)
+	}
+
+	if err := svc.RecordLeave(ctx, -1, nil, base); err == nil {
+		t.Fatalf("expected nil member to be rejected")
+	}
+}
+
+type memoryMemberEventRepository struct {
+	events []*models.MemberEvent
+}
+
+func (r *memoryMemberEventRepository) Create(ctx context.Context, event *models.MemberEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryMemberEventRepository) CountByType(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	for _, event := range r.events {
+		if event.ChatID == chatID && !event.OccurredAt.Before(since) {
+			counts[event.EventType]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryMemberEventRepository) ListRecent(ctx context.Context, chatID int64, eventType string, since time.Time, limit int64) ([]*models.MemberEvent, error) {
+	var events []*models.MemberEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if event.ChatID == chatID && event.EventType == eventType && !event.OccurredAt.Before(since) {
+			events = append(events, event)
+		}
+	}
+	if limit > 0 && int64(len(events)) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *memoryMemberEventRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
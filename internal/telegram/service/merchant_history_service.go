@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+// MerchantHistoryServiceImpl 商户号变更记录服务
+type MerchantHistoryServiceImpl struct {
+	repo repository.MerchantHistoryRepository
+}
+
+// NewMerchantHistoryService 创建服务实例
+func NewMerchantHistoryService(repo repository.MerchantHistoryRepository) MerchantHistoryService {
+	return &MerchantHistoryServiceImpl{repo: repo}
+}
+
+// Record 记录一次商户号变更
+func (s *MerchantHistoryServiceImpl) Record(ctx context.Context, entry *models.MerchantHistory) error {
+	if entry == nil {
+		return fmt.Errorf("变更记录不能为空")
+	}
+	if err := s.repo.Create(ctx, entry); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to record merchant history: chat_id=%d, action=%s, error=%v", entry.ChatID, entry.Action, err)
+		return fmt.Errorf("记录商户号变更失败")
+	}
+	return nil
+}
+
+// ListRecent 列出群组最近 limit 条变更记录（最新在前）
+func (s *MerchantHistoryServiceImpl) ListRecent(ctx context.Context, chatID int64, limit int) ([]*models.MerchantHistory, error) {
+	entries, err := s.repo.ListRecent(ctx, chatID, int64(limit))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list merchant history: chat_id=%d, error=%v", chatID, err)
+		return nil, fmt.Errorf("查询商户号变更历史失败")
+	}
+	return entries, nil
+}
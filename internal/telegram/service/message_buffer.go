@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+const (
+	// messageFlushTimeout 单次批量落库超时
+	messageFlushTimeout = 10 * time.Second
+	// messageBufferMaxBatches 落库失败时最多保留的批次数，超出部分丢弃最早的消息，避免数据库长时间不可用时内存无限增长
+	messageBufferMaxBatches = 20
+)
+
+// MessageBatchConfig 消息批量写入配置，Size 条或 FlushInterval 到期（先到为准）触发一次落库
+type MessageBatchConfig struct {
+	Size          int
+	FlushInterval time.Duration
+}
+
+// enabled 是否启用批量写入，Size <= 1 时逐条写入
+func (c MessageBatchConfig) enabled() bool {
+	return c.Size > 1 && c.FlushInterval > 0
+}
+
+// bufferedMessage 缓冲中的消息，countStats 表示落库后是否计入群组统计（频道消息不计入）
+type bufferedMessage struct {
+	message    *models.Message
+	countStats bool
+}
+
+// messageWriteBuffer 消息写入缓冲：按大小与时间双触发批量落库，落库成功后回调 onFlushed 汇总更新群组统计
+type messageWriteBuffer struct {
+	repo      repository.MessageRepository
+	size      int
+	interval  time.Duration
+	onFlushed func(ctx context.Context, messages []*models.Message)
+
+	mu      sync.Mutex
+	pending []bufferedMessage
+
+	flushMu sync.Mutex // 串行化落库，保证同一消息的先后写入顺序
+	full    chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newMessageWriteBuffer(repo repository.MessageRepository, cfg MessageBatchConfig, onFlushed func(ctx context.Context, messages []*models.Message)) *messageWriteBuffer {
+	buffer := &messageWriteBuffer{
+		repo:      repo,
+		size:      cfg.Size,
+		interval:  cfg.FlushInterval,
+		onFlushed: onFlushed,
+		full:      make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buffer.cancel = cancel
+	buffer.wg.Add(1)
+	go func() {
+		defer buffer.wg.Done()
+		buffer.run(ctx)
+	}()
+
+	logger.L().Infof("Message write buffer started: size=%d, interval=%s", cfg.Size, cfg.FlushInterval)
+	return buffer
+}
+
+// add 加入缓冲，达到批量大小时通知后台立即落库
+func (b *messageWriteBuffer) add(message *models.Message, countStats bool) {
+	b.mu.Lock()
+	b.pending = append(b.pending, bufferedMessage{message: message, countStats: countStats})
+	full := len(b.pending) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flushIf 缓冲中存在满足 match 的消息时立即落库，用于读取或修改消息前保证一致性
+func (b *messageWriteBuffer) flushIf(ctx context.Context, match func(message *models.Message) bool) {
+	b.mu.Lock()
+	found := false
+	for _, item := range b.pending {
+		if match(item.message) {
+			found = true
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if found {
+		b.flush(ctx)
+	}
+}
+
+// flush 将当前缓冲批量落库，失败时放回缓冲等待下次重试
+func (b *messageWriteBuffer) flush(ctx context.Context) int {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return 0
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), messageFlushTimeout)
+	defer cancel()
+
+	messages := make([]*models.Message, 0, len(batch))
+	counted := make([]*models.Message, 0, len(batch))
+	for _, item := range batch {
+		messages = append(messages, item.message)
+		if item.countStats {
+			counted = append(counted, item.message)
+		}
+	}
+
+	if err := b.repo.BulkCreateMessages(flushCtx, messages); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to flush buffered messages: count=%d, error=%v", len(batch), err)
+		b.requeue(batch)
+		return 0
+	}
+
+	if b.onFlushed != nil && len(counted) > 0 {
+		b.onFlushed(flushCtx, counted)
+	}
+	logger.Ctx(ctx).Debugf("Buffered messages flushed: count=%d", len(batch))
+	return len(batch)
+}
+
+// requeue 落库失败的批次放回缓冲头部，超出上限时丢弃最早的消息
+func (b *messageWriteBuffer) requeue(batch []bufferedMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	merged := append(batch, b.pending...)
+	if limit := b.size * messageBufferMaxBatches; len(merged) > limit {
+		dropped := len(merged) - limit
+		logger.L().Errorf("Message write buffer overflow, dropping %d oldest messages", dropped)
+		merged = merged[dropped:]
+	}
+	b.pending = merged
+}
+
+// len 当前缓冲中的消息数量
+func (b *messageWriteBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+func (b *messageWriteBuffer) run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.flush(ctx)
+		case <-b.full:
+			b.flush(ctx)
+		}
+	}
+}
+
+// close 停止后台落库并 flush 剩余消息
+func (b *messageWriteBuffer) close(ctx context.Context) {
+	b.cancel()
+	b.wg.Wait()
+
+	if remaining := b.len(); remaining > 0 {
+		flushed := b.flush(ctx)
+		logger.Ctx(ctx).Infof("Message write buffer closed: flushed=%d, remaining=%d", flushed, remaining)
+		return
+	}
+	logger.Ctx(ctx).Info("Message write buffer closed")
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+// bulkMessageRepository 记录批量写入，可模拟写入失败
+type bulkMessageRepository struct {
+	stubMessageRepository
+	mu      sync.Mutex
+	batches [][]*models.Message
+	failErr error
+}
+
+func (r *bulkMessageRepository) BulkCreateMessages(ctx context.Context, messages []*models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failErr != nil {
+		return r.failErr
+	}
+	r.batches = append(r.batches, messages)
+	for _, message := range messages {
+		r.messages[message.TelegramMessageID] = message
+	}
+	return nil
+}
+
+func (r *bulkMessageRepository) batchCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+// statsGroupRepository 记录群组统计更新
+type statsGroupRepository struct {
+	stubGroupRepository
+	mu    sync.Mutex
+	stats models.GroupStats
+}
+
+func (r *statsGroupRepository) GetByTelegramID(ctx context.Context, telegramID int64) (*models.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &models.Group{TelegramID: telegramID, Stats: r.stats}, nil
+}
+
+func (r *statsGroupRepository) IncrementMessageStats(ctx context.Context, telegramID int64, count int64, lastMessageAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.TotalMessages += count
+	if lastMessageAt.After(r.stats.LastMessageAt) {
+		r.stats.LastMessageAt = lastMessageAt
+	}
+	return nil
+}
+
+func textMessage(id int64) *TextMessageInfo {
+	return &TextMessageInfo{TelegramMessageID: id, ChatID: -1, UserID: 7, Text: "hi", SentAt: time.Date(2024, 11, 20, 9, 0, int(id), 0, time.UTC)}
+}
+
+func TestMessageBufferFlushesOnSize(t *testing.T) {
+	repo := &bulkMessageRepository{stubMessageRepository: stubMessageRepository{messages: map[int64]*models.Message{}}}
+	groups := &statsGroupRepository{}
+	svc := NewMessageService(repo, groups, nil, MediaDedupConfig{}, MessageBatchConfig{Size: 3, FlushInterval: time.Hour}, models.MessageRetentionPolicy{}).(*MessageServiceImpl)
+	defer svc.Close(context.Background())
+
+	for id := int64(1); id <= 3; id++ {
+		if err := svc.HandleTextMessage(context.Background(), textMessage(id)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repo.batchCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if repo.batchCount() != 1 || len(repo.batches[0]) != 3 {
+		t.Fatalf("expected one batch of 3 messages, got %d batches", repo.batchCount())
+	}
+
+	groups.mu.Lock()
+	defer groups.mu.Unlock()
+	if groups.stats.TotalMessages != 3 || !groups.stats.LastMessageAt.Equal(textMessage(3).SentAt) {
+		t.Fatalf("expected stats aggregated per batch, got %+v", groups.stats)
+	}
+}
+
+func TestMessageBufferFlushesOnIntervalAndClose(t *testing.T) {
+	repo := &bulkMessageRepository{stubMessageRepository: stubMessageRepository{messages: map[int64]*models.Message{}}}
+	svc := NewMessageService(repo, &statsGroupRepository{}, nil, MediaDedupConfig{}, MessageBatchConfig{Size: 100, FlushInterval: 20 * time.Millisecond}, models.MessageRetentionPolicy{}).(*MessageServiceImpl)
+
+	_ = svc.HandleTextMessage(context.Background(), textMessage(1))
+	deadline := time.Now().Add(2 * time.Second)
+	for repo.batchCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if repo.batchCount() != 1 {
+		t.Fatalf("expected interval flush, got %d batches", repo.batchCount())
+	}
+
+	_ = svc.HandleTextMessage(context.Background(), textMessage(2))
+	svc.Close(context.Background())
+	if _, ok := repo.messages[2]; !ok {
+		t.Fatalf("expected remaining message flushed on close")
+	}
+}
+
+func TestMessageBufferFlushesBeforeRead(t *testing.T) {
+	repo := &bulkMessageRepository{stubMessageRepository: stubMessageRepository{messages: map[int64]*models.Message{}}}
+	svc := NewMessageService(repo, &statsGroupRepository{}, nil, MediaDedupConfig{}, MessageBatchConfig{Size: 100, FlushInterval: time.Hour}, models.MessageRetentionPolicy{}).(*MessageServiceImpl)
+	defer svc.Close(context.Background())
+
+	_ = svc.HandleTextMessage(context.Background(), textMessage(1))
+	message, err := svc.GetMessageEditHistory(context.Background(), -1, 1)
+	if err != nil || message.Text != "hi" {
+		t.Fatalf("expected buffered message readable, got %+v err=%v", message, err)
+	}
+}
+
+func TestMessageBufferRequeuesOnFailure(t *testing.T) {
+	repo := &bulkMessageRepository{
+		stubMessageRepository: stubMessageRepository{messages: map[int64]*models.Message{}},
+		failErr:               errors.New("db down"),
+	}
+	buffer := &messageWriteBuffer{repo: repo, size: 2}
+	for id := int64(1); id <= 2*messageBufferMaxBatches+3; id++ {
+		buffer.add(&models.Message{TelegramMessageID: id}, true)
+	}
+
+	if flushed := buffer.flush(context.Background()); flushed != 0 {
+		t.Fatalf("expected failed flush, got %d", flushed)
+	}
+	if got := buffer.len(); got != 2*messageBufferMaxBatches {
+		t.Fatalf("expected buffer capped at %d, got %d", 2*messageBufferMaxBatches, got)
+	}
+
+	repo.failErr = nil
+	if flushed := buffer.flush(context.Background()); flushed != 2*messageBufferMaxBatches {
+		t.Fatalf("expected requeued messages flushed, got %d", flushed)
+	}
+	if _, ok := repo.messages[1]; ok {
+		t.Fatalf("expected oldest messages dropped on overflow")
+	}
+}
+
+func TestRecordMediaArchiveFlushesBufferedMessage(t *testing.T) {
+	repo := &bulkMessageRepository{stubMessageRepository: stubMessageRepository{messages: map[int64]*models.Message{}}}
+	svc := NewMessageService(repo, &statsGroupRepository{}, nil, MediaDedupConfig{}, MessageBatchConfig{Size: 100, FlushInterval: time.Hour}, models.MessageRetentionPolicy{}).(*MessageServiceImpl)
+	defer svc.Close(context.Background())
+
+	if err := svc.HandleTextMessage(context.Background(), textMessage(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.RecordMediaArchive(context.Background(), -1, 1, "s3://bucket/key"); err != nil {
+		t.Fatalf("expected buffered message to be flushed before recording, got %v", err)
+	}
+	if got := repo.messages[1]; got.ArchiveURL != "s3://bucket/key" || got.ArchivedAt == nil {
+		t.Fatalf("expected archive url recorded, got %+v", got)
+	}
+
+	if err := svc.RecordMediaArchive(context.Background(), -1, 99, "s3://bucket/other"); err == nil {
+		t.Fatal("expected error for unrecorded message")
+	}
+}
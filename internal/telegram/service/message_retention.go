@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+)
+
+// messageRetentionCacheTTL 群组保留天数的缓存时长，避免每条消息都查询群组；群等级变化最迟在该时长后生效
+const messageRetentionCacheTTL = 5 * time.Minute
+
+// messageRetentionEntry 单个聊天的保留天数缓存
+type messageRetentionEntry struct {
+	days     int
+	loadedAt time.Time
+}
+
+// messageRetentionCache 按聊天缓存生效的保留天数，并发安全
+type messageRetentionCache struct {
+	mu      sync.Mutex
+	entries map[int64]messageRetentionEntry
+}
+
+func (c *messageRetentionCache) get(chatID int64, now time.Time) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[chatID]
+	if !ok || now.Sub(entry.loadedAt) >= messageRetentionCacheTTL {
+		return 0, false
+	}
+	return entry.days, true
+}
+
+func (c *messageRetentionCache) put(chatID int64, days int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[int64]messageRetentionEntry)
+	}
+	// 顺带清理过期项，防止长期运行后缓存无限增长
+	for id, entry := range c.entries {
+		if now.Sub(entry.loadedAt) >= messageRetentionCacheTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[chatID] = messageRetentionEntry{days: days, loadedAt: now}
+}
+
+func (c *messageRetentionCache) invalidate(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, chatID)
+}
+
+// retentionDays 返回聊天生效的保留天数：群组单独配置 > 群等级默认 > 全局默认
+func (s *MessageServiceImpl) retentionDays(ctx context.Context, chatID int64) int {
+	if s.retention.DefaultDays <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if days, ok := s.retentionCache.get(chatID, now); ok {
+		return days
+	}
+
+	var group *models.Group
+	if s.groupRepo != nil {
+		// 私聊、频道等没有群组记录时按全局默认值
+		group, _ = s.groupRepo.GetByTelegramID(ctx, chatID)
+	}
+	days := s.retention.DaysFor(group)
+	s.retentionCache.put(chatID, days, now)
+	return days
+}
+
+// applyRetention 写入前按聊天的保留期计算消息过期时间
+func (s *MessageServiceImpl) applyRetention(ctx context.Context, message *models.Message) {
+	message.ExpireAt = models.MessageExpireAt(message.SentAt, s.retentionDays(ctx, message.ChatID))
+}
+
+// ApplyChatRetention 群组保留期变更后，按新的保留天数重算本群已有消息的过期时间
+func (s *MessageServiceImpl) ApplyChatRetention(ctx context.Context, chatID int64) (int, int64, error) {
+	s.retentionCache.invalidate(chatID)
+	days := s.retentionDays(ctx, chatID)
+	if days <= 0 {
+		return 0, 0, nil
+	}
+
+	s.flushChat(ctx, chatID)
+	updated, err := s.messageRepo.UpdateChatExpiry(ctx, chatID, days)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to apply message retention: chat_id=%d, days=%d, error=%v", chatID, days, err)
+		return days, 0, fmt.Errorf("更新消息保留期失败")
+	}
+
+	logger.Ctx(ctx).Infof("Message retention applied: chat_id=%d, days=%d, updated=%d", chatID, days, updated)
+	return days, updated, nil
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go_bot/internal/logger"
@@ -10,18 +12,89 @@ import (
 	"go_bot/internal/telegram/repository"
 )
 
+// MediaDedupMode 媒体消息去重策略
+type MediaDedupMode string
+
+const (
+	MediaDedupOff  MediaDedupMode = "off"  // 不去重，每条媒体消息都完整记录
+	MediaDedupMark MediaDedupMode = "mark" // 照常记录，重复的消息标记 duplicate_of_message_id
+	MediaDedupSkip MediaDedupMode = "skip" // 重复的消息不再记录，只累加首条消息的 duplicate_count
+)
+
+// ErrMessageNotRecorded 写入归档地址时消息不存在（未记录或已被清理），重试不会成功
+var ErrMessageNotRecorded = errors.New("消息未记录，无法写入归档地址")
+
+// MediaDedupConfig 媒体消息去重配置，Window 内本群出现过同一文件即视为重复
+type MediaDedupConfig struct {
+	Mode   MediaDedupMode
+	Window time.Duration
+}
+
+// enabled 是否需要去重检查
+func (c MediaDedupConfig) enabled() bool {
+	return (c.Mode == MediaDedupMark || c.Mode == MediaDedupSkip) && c.Window > 0
+}
+
 // MessageServiceImpl 消息服务实现
 type MessageServiceImpl struct {
 	messageRepo repository.MessageRepository
 	groupRepo   repository.GroupRepository
+	deletedRepo repository.DeletedMessageRepository
+	mediaDedup  MediaDedupConfig
+	buffer      *messageWriteBuffer // 批量写入缓冲，未启用时为 nil（逐条写入）
+
+	retention      models.MessageRetentionPolicy // 消息保留期策略，用于计算 expire_at
+	retentionCache messageRetentionCache
 }
 
-// NewMessageService 创建消息服务
-func NewMessageService(messageRepo repository.MessageRepository, groupRepo repository.GroupRepository) MessageService {
-	return &MessageServiceImpl{
+// NewMessageService 创建消息服务，batch 启用时消息先进入缓冲再批量落库，retention 决定每条消息的过期时间
+func NewMessageService(messageRepo repository.MessageRepository, groupRepo repository.GroupRepository, deletedRepo repository.DeletedMessageRepository, mediaDedup MediaDedupConfig, batch MessageBatchConfig, retention models.MessageRetentionPolicy) MessageService {
+	svc := &MessageServiceImpl{
 		messageRepo: messageRepo,
 		groupRepo:   groupRepo,
+		deletedRepo: deletedRepo,
+		mediaDedup:  mediaDedup,
+		retention:   retention,
+	}
+	if batch.enabled() {
+		svc.buffer = newMessageWriteBuffer(messageRepo, batch, svc.updateBatchGroupStats)
+	}
+	return svc
+}
+
+// Close 停止批量写入并 flush 缓冲中剩余的消息（Bot 关闭时调用）
+func (s *MessageServiceImpl) Close(ctx context.Context) {
+	if s.buffer != nil {
+		s.buffer.close(ctx)
+	}
+}
+
+// saveMessage 写入消息：启用批量写入时进入缓冲（统计在落库后汇总更新），否则直接写入并更新统计
+func (s *MessageServiceImpl) saveMessage(ctx context.Context, message *models.Message, updateStats bool) error {
+	s.applyRetention(ctx, message)
+
+	if s.buffer != nil {
+		s.buffer.add(message, updateStats)
+		return nil
+	}
+
+	if err := s.messageRepo.CreateMessage(ctx, message); err != nil {
+		return err
+	}
+	if updateStats {
+		s.updateGroupStats(ctx, message.ChatID, message.SentAt)
 	}
+	return nil
+}
+
+// flushChat 读取或修改本聊天的消息前，先将缓冲中同一聊天的消息落库
+func (s *MessageServiceImpl) flushChat(ctx context.Context, chatID int64) {
+	if s.buffer == nil {
+		return
+	}
+	s.buffer.flushIf(ctx, func(message *models.Message) bool {
+		return chatID == 0 || message.ChatID == chatID
+	})
 }
 
 // HandleTextMessage 处理文本消息
@@ -35,23 +108,22 @@ func (s *MessageServiceImpl) HandleTextMessage(ctx context.Context, msg *TextMes
 		ReplyToMessageID:  msg.ReplyToMessageID,
 		SentAt:            msg.SentAt,
 	}
+	message.ApplyEntities(msg.Entities)
 
-	if err := s.messageRepo.CreateMessage(ctx, message); err != nil {
-		logger.L().Errorf("Failed to create text message: chat_id=%d, message_id=%d, error=%v",
+	if err := s.saveMessage(ctx, message, true); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to create text message: chat_id=%d, message_id=%d, error=%v",
 			msg.ChatID, msg.TelegramMessageID, err)
 		return fmt.Errorf("failed to record text message: %w", err)
 	}
 
-	// 更新群组统计信息
-	s.updateGroupStats(ctx, msg.ChatID, msg.SentAt)
-
-	logger.L().Infof("Text message recorded: chat_id=%d, message_id=%d, user_id=%d",
+	logger.Ctx(ctx).Infof("Text message recorded: chat_id=%d, message_id=%d, user_id=%d",
 		msg.ChatID, msg.TelegramMessageID, msg.UserID)
 	return nil
 }
 
-// HandleMediaMessage 处理媒体消息
-func (s *MessageServiceImpl) HandleMediaMessage(ctx context.Context, msg *MediaMessageInfo) error {
+// HandleMediaMessage 处理媒体消息，开启去重时按 file_unique_id 检查本群近期是否已记录同一文件；
+// skip 模式下跳过的重复消息返回 stored=false
+func (s *MessageServiceImpl) HandleMediaMessage(ctx context.Context, msg *MediaMessageInfo) (bool, error) {
 	message := &models.Message{
 		TelegramMessageID: msg.TelegramMessageID,
 		ChatID:            msg.ChatID,
@@ -59,34 +131,75 @@ func (s *MessageServiceImpl) HandleMediaMessage(ctx context.Context, msg *MediaM
 		MessageType:       msg.MessageType,
 		Caption:           msg.Caption,
 		MediaFileID:       msg.MediaFileID,
+		MediaFileUniqueID: msg.MediaFileUniqueID,
 		MediaFileSize:     msg.MediaFileSize,
 		MediaMimeType:     msg.MediaMimeType,
 		SentAt:            msg.SentAt,
 	}
 
-	if err := s.messageRepo.CreateMessage(ctx, message); err != nil {
-		logger.L().Errorf("Failed to create media message: chat_id=%d, message_id=%d, type=%s, error=%v",
-			msg.ChatID, msg.TelegramMessageID, msg.MessageType, err)
-		return fmt.Errorf("failed to record media message: %w", err)
+	if original := s.findDuplicateMedia(ctx, msg); original != nil {
+		if err := s.messageRepo.IncrementDuplicateCount(ctx, original.TelegramMessageID, original.ChatID); err != nil {
+			logger.Ctx(ctx).Warnf("Failed to increment duplicate count: chat_id=%d, message_id=%d, error=%v",
+				original.ChatID, original.TelegramMessageID, err)
+		}
+
+		if s.mediaDedup.Mode == MediaDedupSkip {
+			s.updateGroupStats(ctx, msg.ChatID, msg.SentAt)
+			logger.Ctx(ctx).Infof("Duplicate media skipped: chat_id=%d, message_id=%d, original_id=%d, user_id=%d",
+				msg.ChatID, msg.TelegramMessageID, original.TelegramMessageID, msg.UserID)
+			return false, nil
+		}
+		message.DuplicateOfMessageID = original.TelegramMessageID
 	}
 
-	// 更新群组统计信息
-	s.updateGroupStats(ctx, msg.ChatID, msg.SentAt)
+	if err := s.saveMessage(ctx, message, true); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to create media message: chat_id=%d, message_id=%d, type=%s, error=%v",
+			msg.ChatID, msg.TelegramMessageID, msg.MessageType, err)
+		return false, fmt.Errorf("failed to record media message: %w", err)
+	}
 
-	logger.L().Infof("Media message recorded: chat_id=%d, message_id=%d, type=%s, user_id=%d",
+	logger.Ctx(ctx).Infof("Media message recorded: chat_id=%d, message_id=%d, type=%s, user_id=%d",
 		msg.ChatID, msg.TelegramMessageID, msg.MessageType, msg.UserID)
-	return nil
+	return true, nil
+}
+
+// findDuplicateMedia 查找窗口内本群首条记录的同一文件，未开启去重、缺少 file_unique_id 或查询失败时返回 nil
+func (s *MessageServiceImpl) findDuplicateMedia(ctx context.Context, msg *MediaMessageInfo) *models.Message {
+	if !s.mediaDedup.enabled() || msg.MediaFileUniqueID == "" {
+		return nil
+	}
+
+	// 同一文件的首条消息可能仍在缓冲中，先落库再查
+	if s.buffer != nil {
+		s.buffer.flushIf(ctx, func(message *models.Message) bool {
+			return message.ChatID == msg.ChatID && message.MediaFileUniqueID == msg.MediaFileUniqueID
+		})
+	}
+
+	original, err := s.messageRepo.FindFirstMediaSince(ctx, msg.ChatID, msg.MediaFileUniqueID, msg.SentAt.Add(-s.mediaDedup.Window))
+	if err != nil {
+		// 查询失败时按普通消息记录，宁可多存也不丢
+		logger.Ctx(ctx).Warnf("Failed to check duplicate media: chat_id=%d, message_id=%d, error=%v",
+			msg.ChatID, msg.TelegramMessageID, err)
+		return nil
+	}
+	// 同一条消息重复投递（如 Telegram 重试）时不算重复
+	if original == nil || original.TelegramMessageID == msg.TelegramMessageID {
+		return nil
+	}
+	return original
 }
 
 // HandleEditedMessage 处理消息编辑
 func (s *MessageServiceImpl) HandleEditedMessage(ctx context.Context, telegramMessageID, chatID int64, newText string, editedAt time.Time) error {
+	s.flushChat(ctx, chatID)
 	if err := s.messageRepo.UpdateMessageEdit(ctx, telegramMessageID, chatID, newText, editedAt); err != nil {
-		logger.L().Errorf("Failed to update edited message: chat_id=%d, message_id=%d, error=%v",
+		logger.Ctx(ctx).Errorf("Failed to update edited message: chat_id=%d, message_id=%d, error=%v",
 			chatID, telegramMessageID, err)
 		return fmt.Errorf("failed to record message edit: %w", err)
 	}
 
-	logger.L().Infof("Message edit recorded: chat_id=%d, message_id=%d", chatID, telegramMessageID)
+	logger.Ctx(ctx).Infof("Message edit recorded: chat_id=%d, message_id=%d", chatID, telegramMessageID)
 	return nil
 }
 
@@ -102,44 +215,230 @@ func (s *MessageServiceImpl) RecordChannelPost(ctx context.Context, msg *Channel
 		SentAt:            msg.SentAt,
 	}
 
-	if err := s.messageRepo.CreateMessage(ctx, message); err != nil {
-		logger.L().Errorf("Failed to create channel post: chat_id=%d, message_id=%d, error=%v",
+	if err := s.saveMessage(ctx, message, false); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to create channel post: chat_id=%d, message_id=%d, error=%v",
 			msg.ChatID, msg.TelegramMessageID, err)
 		return fmt.Errorf("failed to record channel post: %w", err)
 	}
 
-	logger.L().Infof("Channel post recorded: chat_id=%d, message_id=%d, type=%s",
+	logger.Ctx(ctx).Infof("Channel post recorded: chat_id=%d, message_id=%d, type=%s",
 		msg.ChatID, msg.TelegramMessageID, msg.MessageType)
 	return nil
 }
 
 // GetChatMessageHistory 获取聊天消息历史
 func (s *MessageServiceImpl) GetChatMessageHistory(ctx context.Context, chatID int64, limit int) ([]*models.Message, error) {
+	s.flushChat(ctx, chatID)
 	messages, err := s.messageRepo.ListMessagesByChat(ctx, chatID, int64(limit), 0)
 	if err != nil {
-		logger.L().Errorf("Failed to get chat message history: chat_id=%d, error=%v", chatID, err)
+		logger.Ctx(ctx).Errorf("Failed to get chat message history: chat_id=%d, error=%v", chatID, err)
 		return nil, fmt.Errorf("failed to get message history: %w", err)
 	}
 
 	return messages, nil
 }
 
-// updateGroupStats 更新群组统计信息（内部辅助方法）
-func (s *MessageServiceImpl) updateGroupStats(ctx context.Context, chatID int64, messageTime time.Time) {
-	// 获取当前群组信息
-	group, err := s.groupRepo.GetByTelegramID(ctx, chatID)
+// SearchMessages 按关键词搜索聊天消息
+func (s *MessageServiceImpl) SearchMessages(ctx context.Context, chatID int64, keyword string, limit int) ([]*models.Message, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, fmt.Errorf("搜索关键词不能为空")
+	}
+	s.flushChat(ctx, chatID)
+
+	messages, err := s.messageRepo.SearchMessages(ctx, chatID, keyword, int64(limit))
 	if err != nil {
-		logger.L().Warnf("Failed to get group for stats update: chat_id=%d, error=%v", chatID, err)
-		return
+		logger.Ctx(ctx).Errorf("Failed to search messages: chat_id=%d, keyword=%q, error=%v", chatID, keyword, err)
+		return nil, fmt.Errorf("搜索消息失败")
+	}
+
+	return messages, nil
+}
+
+// SearchMessagesByEntity 按 @提及、话题标签或链接检索聊天消息
+func (s *MessageServiceImpl) SearchMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int) ([]*models.Message, error) {
+	switch kind {
+	case models.MessageEntitySearchMention:
+		value = models.NormalizeMention(value)
+	case models.MessageEntitySearchHashtag:
+		value = models.NormalizeHashtag(value)
+	case models.MessageEntitySearchURL:
+		value = strings.TrimSpace(value)
+	default:
+		return nil, fmt.Errorf("不支持的检索方式")
+	}
+	if value == "" {
+		return nil, fmt.Errorf("搜索内容不能为空")
 	}
+	s.flushChat(ctx, chatID)
 
-	// 更新统计信息
-	stats := group.Stats
-	stats.TotalMessages++
-	stats.LastMessageAt = messageTime
+	messages, err := s.messageRepo.FindMessagesByEntity(ctx, chatID, kind, value, int64(limit))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to search messages by entity: chat_id=%d, kind=%s, value=%q, error=%v", chatID, kind, value, err)
+		return nil, fmt.Errorf("搜索消息失败")
+	}
+
+	return messages, nil
+}
+
+// ListMentionMessages 列出本群提及指定用户的消息
+func (s *MessageServiceImpl) ListMentionMessages(ctx context.Context, chatID int64, username string, userID int64, limit int) ([]*models.Message, error) {
+	username = models.NormalizeMention(username)
+	if username == "" && userID == 0 {
+		return nil, fmt.Errorf("请指定要查询的用户名")
+	}
+	s.flushChat(ctx, chatID)
+
+	messages, err := s.messageRepo.FindMessagesMentioning(ctx, chatID, username, userID, int64(limit))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list mention messages: chat_id=%d, username=%q, user_id=%d, error=%v", chatID, username, userID, err)
+		return nil, fmt.Errorf("查询提及消息失败")
+	}
+
+	return messages, nil
+}
+
+// GetMessageTypeStats 按类型统计消息数量，since 为零值时统计全部
+func (s *MessageServiceImpl) GetMessageTypeStats(ctx context.Context, chatID int64, since time.Time) (map[string]int64, error) {
+	var (
+		counts map[string]int64
+		err    error
+	)
+	s.flushChat(ctx, chatID)
+	if since.IsZero() {
+		counts, err = s.messageRepo.CountMessagesByType(ctx, chatID)
+	} else {
+		counts, err = s.messageRepo.CountMessagesByTypeSince(ctx, chatID, since)
+	}
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to count messages by type: chat_id=%d, since=%v, error=%v", chatID, since, err)
+		return nil, fmt.Errorf("统计消息失败")
+	}
+
+	return counts, nil
+}
+
+// ArchiveDeletedMessages 将已记录的消息转存到删除留档，返回成功留档的数量
+// 未被记录过的消息（如 Bot 入群前发送或已过 TTL）无法还原内容，会被跳过
+func (s *MessageServiceImpl) ArchiveDeletedMessages(ctx context.Context, chatID int64, messageIDs []int64, source string, deletedAt time.Time) (int, error) {
+	if s.deletedRepo == nil {
+		return 0, fmt.Errorf("删除留档未启用")
+	}
+	s.flushChat(ctx, chatID)
+
+	archived := 0
+	for _, messageID := range messageIDs {
+		message, err := s.messageRepo.GetByTelegramID(ctx, messageID, chatID)
+		if err != nil {
+			logger.Ctx(ctx).Debugf("Deleted message not recorded, skip archive: chat_id=%d, message_id=%d, error=%v",
+				chatID, messageID, err)
+			continue
+		}
+
+		record := &models.DeletedMessage{
+			ChatID:            chatID,
+			TelegramMessageID: messageID,
+			UserID:            message.UserID,
+			MessageType:       message.MessageType,
+			Text:              message.Text,
+			Caption:           message.Caption,
+			MediaFileID:       message.MediaFileID,
+			Source:            source,
+			SentAt:            message.SentAt,
+			DeletedAt:         deletedAt,
+		}
+		if err := s.deletedRepo.Create(ctx, record); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to archive deleted message: chat_id=%d, message_id=%d, error=%v",
+				chatID, messageID, err)
+			return archived, fmt.Errorf("留档被删除消息失败")
+		}
+
+		if err := s.messageRepo.MarkDeleted(ctx, messageID, chatID, deletedAt); err != nil {
+			logger.Ctx(ctx).Warnf("Failed to mark message deleted: chat_id=%d, message_id=%d, error=%v",
+				chatID, messageID, err)
+		}
+		archived++
+	}
+
+	if archived > 0 {
+		logger.Ctx(ctx).Infof("Deleted messages archived: chat_id=%d, count=%d, source=%s", chatID, archived, source)
+	}
+	return archived, nil
+}
+
+// ListDeletedMessages 列出最近被删除的消息，chatID 为 0 时不限聊天
+func (s *MessageServiceImpl) ListDeletedMessages(ctx context.Context, chatID int64, limit int) ([]*models.DeletedMessage, error) {
+	if s.deletedRepo == nil {
+		return nil, fmt.Errorf("删除留档未启用")
+	}
+
+	messages, err := s.deletedRepo.ListRecent(ctx, chatID, int64(limit))
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list deleted messages: chat_id=%d, error=%v", chatID, err)
+		return nil, fmt.Errorf("查询被删除消息失败")
+	}
+	return messages, nil
+}
+
+// RecordMediaArchive 记录媒体消息的归档地址；先 flush 本群缓冲，保证刚收到的消息已落库
+func (s *MessageServiceImpl) RecordMediaArchive(ctx context.Context, chatID, telegramMessageID int64, archiveURL string) error {
+	s.flushChat(ctx, chatID)
+	found, err := s.messageRepo.SetArchiveURL(ctx, telegramMessageID, chatID, archiveURL, time.Now())
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to record media archive: chat_id=%d, message_id=%d, error=%v",
+			chatID, telegramMessageID, err)
+		return fmt.Errorf("记录归档地址失败")
+	}
+	if !found {
+		return ErrMessageNotRecorded
+	}
+	return nil
+}
+
+// GetMessageEditHistory 获取消息及其编辑历史
+func (s *MessageServiceImpl) GetMessageEditHistory(ctx context.Context, chatID, telegramMessageID int64) (*models.Message, error) {
+	s.flushChat(ctx, chatID)
+	message, err := s.messageRepo.GetByTelegramID(ctx, telegramMessageID, chatID)
+	if err != nil {
+		logger.Ctx(ctx).Warnf("Failed to load message edit history: chat_id=%d, message_id=%d, error=%v",
+			chatID, telegramMessageID, err)
+		return nil, fmt.Errorf("未找到该消息记录（可能未被记录或已过期）")
+	}
+	return message, nil
+}
+
+// updateBatchGroupStats 批量落库后按聊天汇总更新群组统计
+func (s *MessageServiceImpl) updateBatchGroupStats(ctx context.Context, messages []*models.Message) {
+	type chatStats struct {
+		count  int64
+		lastAt time.Time
+	}
+	stats := make(map[int64]*chatStats)
+	for _, message := range messages {
+		entry, ok := stats[message.ChatID]
+		if !ok {
+			entry = &chatStats{}
+			stats[message.ChatID] = entry
+		}
+		entry.count++
+		if message.SentAt.After(entry.lastAt) {
+			entry.lastAt = message.SentAt
+		}
+	}
+	for chatID, entry := range stats {
+		s.addGroupStats(ctx, chatID, entry.count, entry.lastAt)
+	}
+}
+
+// updateGroupStats 更新群组统计信息（内部辅助方法）
+func (s *MessageServiceImpl) updateGroupStats(ctx context.Context, chatID int64, messageTime time.Time) {
+	s.addGroupStats(ctx, chatID, 1, messageTime)
+}
 
-	if err := s.groupRepo.UpdateStats(ctx, chatID, stats); err != nil {
-		logger.L().Warnf("Failed to update group stats: chat_id=%d, error=%v", chatID, err)
+// addGroupStats 累加群组消息数并更新最后消息时间（原子更新，并发写入不会相互覆盖）
+func (s *MessageServiceImpl) addGroupStats(ctx context.Context, chatID int64, count int64, messageTime time.Time) {
+	if err := s.groupRepo.IncrementMessageStats(ctx, chatID, count, messageTime); err != nil {
+		logger.Ctx(ctx).Warnf("Failed to update group stats: chat_id=%d, error=%v", chatID, err)
 		// 不返回错误，仅记录日志
 	}
 }
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+func TestArchiveDeletedMessagesSkipsUnrecorded(t *testing.T) {
+	sentAt := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	messages := &stubMessageRepository{messages: map[int64]*models.Message{
+		101: {TelegramMessageID: 101, ChatID: -1, UserID: 7, MessageType: models.MessageTypeText, Text: "不当内容", SentAt: sentAt},
+	}}
+	deleted := &memoryDeletedMessageRepository{}
+	svc := NewMessageService(messages, nil, deleted, MediaDedupConfig{}, MessageBatchConfig{}, models.MessageRetentionPolicy{})
+
+	deletedAt := sentAt.Add(time.Minute)
+	archived, err := svc.ArchiveDeletedMessages(context.Background(), -1, []int64{101, 102}, models.DeletedMessageSourceBusiness, deletedAt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if archived != 1 || len(deleted.records) != 1 {
+		t.Fatalf("expected only the recorded message to be archived, got archived=%d records=%d", archived, len(deleted.records))
+	}
+
+	record := deleted.records[0]
+	if record.Text != "不当内容" || record.UserID != 7 || !record.SentAt.Equal(sentAt) || !record.DeletedAt.Equal(deletedAt) {
+		t.Fatalf("unexpected archived record: %+v", record)
+	}
+	if _, ok := messages.markedDeleted[101]; !ok {
+		t.Fatalf("expected original message marked deleted")
+	}
+}
+
+func TestHandleMediaMessageDedup(t *testing.T) {
+	sentAt := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	media := func(id int64, uniqueID string, at time.Time) *MediaMessageInfo {
+		return &MediaMessageInfo{
+			TelegramMessageID: id,
+			ChatID:            -1,
+			UserID:            7,
+			MessageType:       models.MessageTypePhoto,
+			MediaFileID:       fmt.Sprintf("file-%d", id),
+			MediaFileUniqueID: uniqueID,
+			SentAt:            at,
+		}
+	}
+
+	cases := []struct {
+		name         string
+		mode         MediaDedupMode
+		wantStored   []int64
+		wantDupOf    int64
+		wantDupCount int
+	}{
+		{name: "off", mode: MediaDedupOff, wantStored: []int64{1, 2, 3}},
+		{name: "mark", mode: MediaDedupMark, wantStored: []int64{1, 2, 3}, wantDupOf: 1, wantDupCount: 1},
+		{name: "skip", mode: MediaDedupSkip, wantStored: []int64{1, 3}, wantDupCount: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			messages := &stubMessageRepository{messages: map[int64]*models.Message{}}
+			svc := NewMessageService(messages, &stubGroupRepository{}, nil, MediaDedupConfig{Mode: tc.mode, Window: time.Hour}, MessageBatchConfig{}, models.MessageRetentionPolicy{})
+
+			inputs := []*MediaMessageInfo{
+				media(1, "uniq-a", sentAt),
+				media(2, "uniq-a", sentAt.Add(10*time.Minute)),
+				// 超出窗口的同一文件按新文件记录
+				media(3, "uniq-a", sentAt.Add(3*time.Hour)),
+			}
+			var reported []int64
+			for _, input := range inputs {
+				stored, err := svc.HandleMediaMessage(context.Background(), input)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if stored {
+					reported = append(reported, input.TelegramMessageID)
+				}
+			}
+			if fmt.Sprint(reported) != fmt.Sprint(tc.wantStored) {
+				t.Fatalf("expected stored report %v, got %v", tc.wantStored, reported)
+			}
+
+			var stored []int64
+			for _, id := range []int64{1, 2, 3} {
+				if _, ok := messages.messages[id]; ok {
+					stored = append(stored, id)
+				}
+			}
+			if fmt.Sprint(stored) != fmt.Sprint(tc.wantStored) {
+				t.Fatalf("expected stored %v, got %v", tc.wantStored, stored)
+			}
+			if second, ok := messages.messages[2]; ok && second.DuplicateOfMessageID != tc.wantDupOf {
+				t.Fatalf("expected duplicate_of=%d, got %d", tc.wantDupOf, second.DuplicateOfMessageID)
+			}
+			if got := messages.messages[1].DuplicateCount; got != tc.wantDupCount {
+				t.Fatalf("expected duplicate_count=%d, got %d", tc.wantDupCount, got)
+			}
+			if messages.messages[3].DuplicateOfMessageID != 0 {
+				t.Fatalf("expected message outside window not marked duplicate")
+			}
+		})
+	}
+}
+
+func TestHandleMediaMessageWithoutUniqueIDIsNotDeduped(t *testing.T) {
+	messages := &stubMessageRepository{messages: map[int64]*models.Message{}}
+	svc := NewMessageService(messages, &stubGroupRepository{}, nil, MediaDedupConfig{Mode: MediaDedupSkip, Window: time.Hour}, MessageBatchConfig{}, models.MessageRetentionPolicy{})
+
+	sentAt := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	for _, id := range []int64{1, 2} {
+		msg := &MediaMessageInfo{TelegramMessageID: id, ChatID: -1, MessageType: models.MessageTypeDocument, MediaFileID: "same", SentAt: sentAt}
+		if _, err := svc.HandleMediaMessage(context.Background(), msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(messages.messages) != 2 {
+		t.Fatalf("expected both messages stored, got %d", len(messages.messages))
+	}
+}
+
+func TestHandleTextMessageAppliesGroupRetention(t *testing.T) {
+	policy := models.MessageRetentionPolicy{DefaultDays: 7, TierDays: map[models.GroupTier]int{models.GroupTierMerchant: 30}}
+	groups := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -1, Tier: models.GroupTierMerchant}}
+	messages := &stubMessageRepository{messages: make(map[int64]*models.Message)}
+	svc := NewMessageService(messages, groups, nil, MediaDedupConfig{}, MessageBatchConfig{}, policy)
+
+	sentAt := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	if err := svc.HandleTextMessage(context.Background(), &TextMessageInfo{TelegramMessageID: 1, ChatID: -1, UserID: 7, Text: "hi", SentAt: sentAt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := messages.messages[1].ExpireAt; got == nil || !got.Equal(sentAt.Add(30*24*time.Hour)) {
+		t.Fatalf("expected merchant tier expiry, got %v", got)
+	}
+
+	// 群组单独配置在缓存失效后生效
+	groups.storedGroup.Settings.MessageRetentionDays = 90
+	svc.(*MessageServiceImpl).retentionCache.invalidate(-1)
+	if err := svc.HandleTextMessage(context.Background(), &TextMessageInfo{TelegramMessageID: 2, ChatID: -1, UserID: 7, Text: "hi", SentAt: sentAt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := messages.messages[2].ExpireAt; got == nil || !got.Equal(sentAt.Add(90*24*time.Hour)) {
+		t.Fatalf("expected group override expiry, got %v", got)
+	}
+}
+
+func TestHandleTextMessageStoresEntities(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -1}}
+	messages := &stubMessageRepository{messages: make(map[int64]*models.Message)}
+	svc := NewMessageService(messages, groups, nil, MediaDedupConfig{}, MessageBatchConfig{}, models.MessageRetentionPolicy{})
+
+	err := svc.HandleTextMessage(context.Background(), &TextMessageInfo{
+		TelegramMessageID: 1,
+		ChatID:            -1,
+		UserID:            7,
+		Text:              "@Bob 看 https://a.io #Ops",
+		Entities: []models.MessageEntity{
+			{Type: models.MessageEntityMention, Offset: 0, Length: 4},
+			{Type: models.MessageEntityURL, Offset: 7, Length: 12},
+			{Type: models.MessageEntityHashtag, Offset: 20, Length: 4},
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored := messages.messages[1]
+	if len(stored.Entities) != 3 || fmt.Sprint(stored.Mentions) != "[bob]" || fmt.Sprint(stored.URLs) != "[https://a.io]" || fmt.Sprint(stored.Hashtags) != "[ops]" {
+		t.Fatalf("unexpected stored entities: %+v", stored)
+	}
+
+	// 检索前按存储格式归一化
+	found, err := svc.SearchMessagesByEntity(context.Background(), -1, models.MessageEntitySearchMention, " @BOB ", 10)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("expected mention search to find the message, got %v err=%v", found, err)
+	}
+	if _, err := svc.SearchMessagesByEntity(context.Background(), -1, models.MessageEntitySearchHashtag, "#", 10); err == nil {
+		t.Fatal("expected empty hashtag to be rejected")
+	}
+}
+
+// stubMessageRepository 仅实现留档与媒体去重相关方法，其余方法未被调用
+type stubMessageRepository struct {
+	repository.MessageRepository
+	messages      map[int64]*models.Message
+	markedDeleted map[int64]time.Time
+}
+
+func (r *stubMessageRepository) CreateMessage(ctx context.Context, message *models.Message) error {
+	r.messages[message.TelegramMessageID] = message
+	return nil
+}
+
+func (r *stubMessageRepository) FindFirstMediaSince(ctx context.Context, chatID int64, fileUniqueID string, since time.Time) (*models.Message, error) {
+	var first *models.Message
+	for _, msg := range r.messages {
+		if msg.ChatID != chatID || msg.MediaFileUniqueID != fileUniqueID || msg.SentAt.Before(since) || msg.DuplicateOfMessageID != 0 {
+			continue
+		}
+		if first == nil || msg.SentAt.Before(first.SentAt) {
+			first = msg
+		}
+	}
+	return first, nil
+}
+
+func (r *stubMessageRepository) IncrementDuplicateCount(ctx context.Context, telegramMessageID, chatID int64) error {
+	if msg, ok := r.messages[telegramMessageID]; ok && msg.ChatID == chatID {
+		msg.DuplicateCount++
+	}
+	return nil
+}
+
+func (r *stubMessageRepository) GetByTelegramID(ctx context.Context, telegramMessageID, chatID int64) (*models.Message, error) {
+	msg, ok := r.messages[telegramMessageID]
+	if !ok || msg.ChatID != chatID {
+		return nil, fmt.Errorf("message not found: message_id=%d, chat_id=%d", telegramMessageID, chatID)
+	}
+	return msg, nil
+}
+
+func (r *stubMessageRepository) MarkDeleted(ctx context.Context, telegramMessageID, chatID int64, deletedAt time.Time) error {
+	if r.markedDeleted == nil {
+		r.markedDeleted = make(map[int64]time.Time)
+	}
+	r.markedDeleted[telegramMessageID] = deletedAt
+	return nil
+}
+
+func (r *stubMessageRepository) SetArchiveURL(ctx context.Context, telegramMessageID, chatID int64, archiveURL string, archivedAt time.Time) (bool, error) {
+	msg, ok := r.messages[telegramMessageID]
+	if !ok || msg.ChatID != chatID {
+		return false, nil
+	}
+	msg.ArchiveURL = archiveURL
+	msg.ArchivedAt = &archivedAt
+	return true, nil
+}
+
+type memoryDeletedMessageRepository struct {
+	records []*models.DeletedMessage
+}
+
+func (r *memoryDeletedMessageRepository) Create(ctx context.Context, message *models.DeletedMessage) error {
+	r.records = append(r.records, message)
+	return nil
+}
+
+func (r *memoryDeletedMessageRepository) ListRecent(ctx context.Context, chatID int64, limit int64) ([]*models.DeletedMessage, error) {
+	return r.records, nil
+}
+
+func (r *memoryDeletedMessageRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *stubMessageRepository) FindMessagesByEntity(ctx context.Context, chatID int64, kind, value string, limit int64) ([]*models.Message, error) {
+	var found []*models.Message
+	for _, msg := range r.messages {
+		if msg.ChatID != chatID {
+			continue
+		}
+		if (kind == models.MessageEntitySearchMention && slices.Contains(msg.Mentions, value)) ||
+			(kind == models.MessageEntitySearchHashtag && slices.Contains(msg.Hashtags, value)) {
+			found = append(found, msg)
+		}
+	}
+	return found, nil
+}
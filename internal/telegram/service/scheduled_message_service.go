@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go_bot/internal/logger"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MaxScheduledMessagesPerChat 每个群组最多注册的定时消息数量
+	MaxScheduledMessagesPerChat = 20
+	// MaxScheduledMessageLength 定时消息内容的最大字符数
+	MaxScheduledMessageLength = 1000
+)
+
+// ScheduledMessageServiceImpl 群组定时消息服务
+type ScheduledMessageServiceImpl struct {
+	repo repository.ScheduledMessageRepository
+}
+
+// NewScheduledMessageService 创建服务实例
+func NewScheduledMessageService(repo repository.ScheduledMessageRepository) ScheduledMessageService {
+	return &ScheduledMessageServiceImpl{repo: repo}
+}
+
+// Create 校验触发规则并注册定时消息
+func (s *ScheduledMessageServiceImpl) Create(ctx context.Context, input *ScheduledMessageInput) (*models.ScheduledMessage, error) {
+	if input == nil {
+		return nil, fmt.Errorf("参数不能为空")
+	}
+
+	text := strings.TrimSpace(input.Text)
+	if text == "" {
+		return nil, fmt.Errorf("消息内容不能为空")
+	}
+	if utf8.RuneCountInString(text) > MaxScheduledMessageLength {
+		return nil, fmt.Errorf("消息内容不能超过 %d 个字符", MaxScheduledMessageLength)
+	}
+
+	timezone := strings.TrimSpace(input.Timezone)
+	if timezone == "" {
+		timezone = models.DefaultGroupTimezone
+	}
+
+	message := &models.ScheduledMessage{
+		ChatID:    input.ChatID,
+		Text:      text,
+		Kind:      input.Kind,
+		Spec:      strings.Join(strings.Fields(input.Spec), " "),
+		Timezone:  timezone,
+		CreatedBy: input.CreatedBy,
+	}
+	next, err := message.NextRunAfter(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	message.NextRunAt = next
+
+	count, err := s.repo.CountByChat(ctx, input.ChatID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to count scheduled messages: chat_id=%d, error=%v", input.ChatID, err)
+		return nil, fmt.Errorf("查询定时消息失败")
+	}
+	if count >= MaxScheduledMessagesPerChat {
+		return nil, fmt.Errorf("每个群最多注册 %d 条定时消息，请先删除不需要的", MaxScheduledMessagesPerChat)
+	}
+
+	if err := s.repo.Create(ctx, message); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to create scheduled message: chat_id=%d, error=%v", input.ChatID, err)
+		return nil, fmt.Errorf("保存定时消息失败")
+	}
+
+	logger.Ctx(ctx).Infof("Scheduled message created: chat_id=%d id=%s kind=%s spec=%q next=%s",
+		message.ChatID, message.ID.Hex(), message.Kind, message.Spec, message.NextRunAt.Format(time.RFC3339))
+	return message, nil
+}
+
+// ListByChat 列出群组的定时消息
+func (s *ScheduledMessageServiceImpl) ListByChat(ctx context.Context, chatID int64) ([]*models.ScheduledMessage, error) {
+	messages, err := s.repo.ListByChat(ctx, chatID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list scheduled messages: chat_id=%d, error=%v", chatID, err)
+		return nil, fmt.Errorf("查询定时消息失败")
+	}
+	return messages, nil
+}
+
+// Delete 删除群组内指定的定时消息
+func (s *ScheduledMessageServiceImpl) Delete(ctx context.Context, chatID int64, id string) error {
+	id = strings.TrimSpace(id)
+	if !primitive.IsValidObjectID(id) {
+		return fmt.Errorf("无效的定时消息 ID")
+	}
+
+	deleted, err := s.repo.Delete(ctx, chatID, id)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to delete scheduled message: chat_id=%d, id=%s, error=%v", chatID, id, err)
+		return fmt.Errorf("删除定时消息失败")
+	}
+	if !deleted {
+		return fmt.Errorf("未找到该定时消息")
+	}
+
+	logger.Ctx(ctx).Infof("Scheduled message deleted: chat_id=%d id=%s", chatID, id)
+	return nil
+}
+
+// ListDue 列出已到期的定时消息
+func (s *ScheduledMessageServiceImpl) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error) {
+	return s.repo.ListDue(ctx, now, limit)
+}
+
+// NextRunAt 返回最早的下一次触发时间
+func (s *ScheduledMessageServiceImpl) NextRunAt(ctx context.Context) (*time.Time, error) {
+	return s.repo.NextRunAt(ctx)
+}
+
+// ClaimRun 发送前认领本次触发，已被其他实例认领时返回 false
+func (s *ScheduledMessageServiceImpl) ClaimRun(ctx context.Context, message *models.ScheduledMessage, now time.Time) (bool, error) {
+	return s.repo.ClaimRun(ctx, message, now)
+}
+
+// Advance 推进到 now 之后的下一次触发时间，sent 为 true 时记录本次发送时间
+func (s *ScheduledMessageServiceImpl) Advance(ctx context.Context, message *models.ScheduledMessage, now time.Time, sent bool) error {
+	next, err := message.NextRunAfter(now)
+	if err != nil {
+		return err
+	}
+
+	var lastRunAt *time.Time
+	if sent {
+		lastRunAt = &now
+	}
+	if err := s.repo.UpdateRun(ctx, message.ID.Hex(), next, lastRunAt); err != nil {
+		return err
+	}
+
+	message.NextRunAt = next
+	if sent {
+		message.LastRunAt = lastRunAt
+	}
+	return nil
+}
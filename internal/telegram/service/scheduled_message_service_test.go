@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScheduledMessageServiceCreateAndDelete(t *testing.T) {
+	repo := &memoryScheduledMessageRepository{}
+	svc := NewScheduledMessageService(repo)
+	ctx := context.Background()
+
+	message, err := svc.Create(ctx, &ScheduledMessageInput{
+		ChatID:   -1,
+		Kind:     models.ScheduleKindDaily,
+		Spec:     "09:00",
+		Text:     "  早报\n内容  ",
+		Timezone: "Asia/Tokyo",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if message.Text != "早报\n内容" {
+		t.Fatalf("expected trimmed text, got %q", message.Text)
+	}
+	local := message.NextRunAt.In(message.Location())
+	if local.Hour() != 9 || local.Minute() != 0 || !message.NextRunAt.After(time.Now()) {
+		t.Fatalf("unexpected next run: %s", local)
+	}
+
+	invalid := []*ScheduledMessageInput{
+		{ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "25:00", Text: "x"},
+		{ChatID: -1, Kind: models.ScheduleKindCron, Spec: "0 9 * *", Text: "x"},
+		{ChatID: -1, Kind: "weekly", Spec: "09:00", Text: "x"},
+		{ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "09:00", Text: "   "},
+		{ChatID: -1, Kind: models.ScheduleKindDaily, Spec: "09:00", Text: strings.Repeat("字", MaxScheduledMessageLength+1)},
+	}
+	for _, input := range invalid {
+		if _, err := svc.Create(ctx, input); err == nil {
+			t.Fatalf("expected error for input %+v", input)
+		}
+	}
+
+	if err := svc.Delete(ctx, -2, message.ID.Hex()); err == nil {
+		t.Fatalf("expected delete from another chat to fail")
+	}
+	if err := svc.Delete(ctx, -1, "not-an-id"); err == nil {
+		t.Fatalf("expected invalid id to be rejected")
+	}
+	if err := svc.Delete(ctx, -1, message.ID.Hex()); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if len(repo.messages) != 0 {
+		t.Fatalf("expected message to be removed, got %d", len(repo.messages))
+	}
+}
+
+func TestScheduledMessageServiceLimitAndAdvance(t *testing.T) {
+	repo := &memoryScheduledMessageRepository{}
+	svc := NewScheduledMessageService(repo)
+	ctx := context.Background()
+
+	for i := 0; i < MaxScheduledMessagesPerChat; i++ {
+		if _, err := svc.Create(ctx, &ScheduledMessageInput{ChatID: -1, Kind: models.ScheduleKindCron, Spec: "*/5 * * * *", Text: "ping"}); err != nil {
+			t.Fatalf("unexpected error at %d: %v", i, err)
+		}
+	}
+	if _, err := svc.Create(ctx, &ScheduledMessageInput{ChatID: -1, Kind: models.ScheduleKindCron, Spec: "*/5 * * * *", Text: "ping"}); err == nil {
+		t.Fatalf("expected per-chat limit to be enforced")
+	}
+
+	message := repo.messages[0]
+	now := time.Date(2024, 11, 20, 9, 3, 0, 0, time.UTC)
+	if err := svc.Advance(ctx, message, now, true); err != nil {
+		t.Fatalf("unexpected advance error: %v", err)
+	}
+	if want := time.Date(2024, 11, 20, 9, 5, 0, 0, time.UTC); !message.NextRunAt.Equal(want) {
+		t.Fatalf("expected next run %s, got %s", want, message.NextRunAt)
+	}
+	if message.LastRunAt == nil || !message.LastRunAt.Equal(now) {
+		t.Fatalf("expected last run to be recorded, got %v", message.LastRunAt)
+	}
+}
+
+type memoryScheduledMessageRepository struct {
+	messages []*models.ScheduledMessage
+	runs     map[string]bool
+}
+
+func (r *memoryScheduledMessageRepository) ClaimRun(ctx context.Context, message *models.ScheduledMessage, claimedAt time.Time) (bool, error) {
+	key := message.ID.Hex() + "@" + message.NextRunAt.Format(time.RFC3339)
+	if r.runs[key] {
+		return false, nil
+	}
+	if r.runs == nil {
+		r.runs = make(map[string]bool)
+	}
+	r.runs[key] = true
+	return true, nil
+}
+
+func (r *memoryScheduledMessageRepository) Create(ctx context.Context, message *models.ScheduledMessage) error {
+	message.ID = primitive.NewObjectID()
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *memoryScheduledMessageRepository) ListByChat(ctx context.Context, chatID int64) ([]*models.ScheduledMessage, error) {
+	var result []*models.ScheduledMessage
+	for _, message := range r.messages {
+		if message.ChatID == chatID {
+			result = append(result, message)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryScheduledMessageRepository) CountByChat(ctx context.Context, chatID int64) (int64, error) {
+	messages, _ := r.ListByChat(ctx, chatID)
+	return int64(len(messages)), nil
+}
+
+func (r *memoryScheduledMessageRepository) Delete(ctx context.Context, chatID int64, id string) (bool, error) {
+	for i, message := range r.messages {
+		if message.ChatID == chatID && message.ID.Hex() == id {
+			r.messages = append(r.messages[:i], r.messages[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryScheduledMessageRepository) ListDue(ctx context.Context, now time.Time, limit int64) ([]*models.ScheduledMessage, error) {
+	var result []*models.ScheduledMessage
+	for _, message := range r.messages {
+		if !message.NextRunAt.After(now) {
+			result = append(result, message)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryScheduledMessageRepository) NextRunAt(ctx context.Context) (*time.Time, error) {
+	var next *time.Time
+	for _, message := range r.messages {
+		if next == nil || message.NextRunAt.Before(*next) {
+			at := message.NextRunAt
+			next = &at
+		}
+	}
+	return next, nil
+}
+
+func (r *memoryScheduledMessageRepository) UpdateRun(ctx context.Context, id string, nextRunAt time.Time, lastRunAt *time.Time) error {
+	for _, message := range r.messages {
+		if message.ID.Hex() == id {
+			message.NextRunAt = nextRunAt
+			if lastRunAt != nil {
+				message.LastRunAt = lastRunAt
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memoryScheduledMessageRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
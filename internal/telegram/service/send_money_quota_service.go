@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go_bot/internal/telegram/repository"
+)
+
+// SendMoneyQuotaServiceImpl 四方下发每日限额服务
+type SendMoneyQuotaServiceImpl struct {
+	repo repository.SendMoneyRepository
+}
+
+// NewSendMoneyQuotaService 创建服务实例
+func NewSendMoneyQuotaService(repo repository.SendMoneyRepository) SendMoneyQuotaService {
+	return &SendMoneyQuotaServiceImpl{repo: repo}
+}
+
+// Reserve 按群组 + 日期累加下发金额，日期由 at 所在时区决定，跨天自动从 0 开始
+func (s *SendMoneyQuotaServiceImpl) Reserve(ctx context.Context, chatID int64, amount, limit float64, at time.Time) (*SendMoneyQuota, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("下发金额必须大于 0")
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	date := at.Format("2006-01-02")
+	total, allowed, err := s.repo.ReserveDaily(ctx, chatID, date, amount, limit)
+	if err != nil {
+		return nil, fmt.Errorf("累计下发额度失败: %w", err)
+	}
+
+	used := 0.0
+	if total != nil {
+		used = total.Total
+	}
+
+	quota := &SendMoneyQuota{
+		ChatID:  chatID,
+		Date:    date,
+		Amount:  amount,
+		Limit:   limit,
+		Used:    roundQuotaAmount(used),
+		Allowed: allowed,
+	}
+	if limit > 0 {
+		quota.Remaining = math.Max(roundQuotaAmount(limit-used), 0)
+	}
+	return quota, nil
+}
+
+// Release 回退已预占的额度
+func (s *SendMoneyQuotaServiceImpl) Release(ctx context.Context, quota *SendMoneyQuota) error {
+	if quota == nil || !quota.Allowed {
+		return nil
+	}
+	if err := s.repo.ReleaseDaily(ctx, quota.ChatID, quota.Date, quota.Amount); err != nil {
+		return fmt.Errorf("回退下发额度失败: %w", err)
+	}
+	return nil
+}
+
+func roundQuotaAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestSendMoneyQuotaReserveConcurrent(t *testing.T) {
+	repo := newMemorySendMoneyRepository()
+	svc := NewSendMoneyQuotaService(repo)
+	at := time.Date(2024, 3, 18, 23, 30, 0, 0, time.UTC)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quota, err := svc.Reserve(context.Background(), -100, 30, 100, at)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if quota.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 3 {
+		t.Fatalf("expected 3 reservations within limit, got %d", allowed)
+	}
+
+	quota, err := svc.Reserve(context.Background(), -100, 10, 100, at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !quota.Allowed || quota.Used != 100 || quota.Remaining != 0 {
+		t.Fatalf("unexpected quota after filling limit: %+v", quota)
+	}
+
+	rejected, err := svc.Reserve(context.Background(), -100, 0.01, 100, at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected.Allowed || rejected.Remaining != 0 {
+		t.Fatalf("expected rejection once limit reached: %+v", rejected)
+	}
+
+	nextDay, err := svc.Reserve(context.Background(), -100, 50, 100, at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !nextDay.Allowed || nextDay.Date != "2024-03-19" || nextDay.Used != 50 {
+		t.Fatalf("expected totals to reset on the next day: %+v", nextDay)
+	}
+
+	if err := svc.Release(context.Background(), nextDay); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if total := repo.totals[sendMoneyKey(-100, "2024-03-19")]; total != 0 {
+		t.Fatalf("expected released total 0, got %.2f", total)
+	}
+}
+
+func TestSendMoneyQuotaReserveUnlimited(t *testing.T) {
+	svc := NewSendMoneyQuotaService(newMemorySendMoneyRepository())
+	at := time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)
+
+	quota, err := svc.Reserve(context.Background(), -1, 5000, 0, at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !quota.Allowed || quota.Used != 5000 || quota.Remaining != 0 {
+		t.Fatalf("unexpected unlimited quota: %+v", quota)
+	}
+
+	if _, err := svc.Reserve(context.Background(), -1, 0, 0, at); err == nil {
+		t.Fatalf("expected error for non-positive amount")
+	}
+}
+
+type memorySendMoneyRepository struct {
+	mu     sync.Mutex
+	totals map[string]float64
+}
+
+func newMemorySendMoneyRepository() *memorySendMoneyRepository {
+	return &memorySendMoneyRepository{totals: make(map[string]float64)}
+}
+
+func sendMoneyKey(chatID int64, date string) string {
+	return fmt.Sprintf("%d:%s", chatID, date)
+}
+
+func (r *memorySendMoneyRepository) GetDailyTotal(ctx context.Context, chatID int64, date string) (*models.SendMoneyDailyTotal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total, ok := r.totals[sendMoneyKey(chatID, date)]
+	if !ok {
+		return nil, nil
+	}
+	return &models.SendMoneyDailyTotal{ChatID: chatID, Date: date, Total: total}, nil
+}
+
+func (r *memorySendMoneyRepository) ReserveDaily(ctx context.Context, chatID int64, date string, amount, limit float64) (*models.SendMoneyDailyTotal, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := sendMoneyKey(chatID, date)
+	current := r.totals[key]
+	if limit > 0 && current+amount > limit+1e-6 {
+		return &models.SendMoneyDailyTotal{ChatID: chatID, Date: date, Total: current}, false, nil
+	}
+	r.totals[key] = current + amount
+	return &models.SendMoneyDailyTotal{ChatID: chatID, Date: date, Total: current + amount}, true, nil
+}
+
+func (r *memorySendMoneyRepository) ReleaseDaily(ctx context.Context, chatID int64, date string, amount float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.totals[sendMoneyKey(chatID, date)] -= amount
+	return nil
+}
+
+func (r *memorySendMoneyRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
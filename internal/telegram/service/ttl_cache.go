@@ -0,0 +1,105 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheSweepSize 缓存条目超过该数量时，写入前顺带清理已过期条目
+const cacheSweepSize = 1024
+
+// ttlCache 按键缓存查库结果的短 TTL 缓存（并发安全），群组信息与管理员权限缓存共用
+// clone 不为 nil 时存取均复制，调用方修改返回值不会污染缓存
+// 每次失效都会推进 generation；查库前记录的 generation 与写入时不一致说明期间发生过写操作，
+// 查到的结果可能已过时，直接丢弃不写缓存，避免旧数据回填缓存
+type ttlCache[K comparable, V any] struct {
+	ttl        time.Duration
+	now        func() time.Time
+	clone      func(V) V
+	mu         sync.RWMutex
+	entries    map[K]ttlCacheEntry[V]
+	generation uint64
+}
+
+type ttlCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[K comparable, V any](ttl time.Duration, clone func(V) V) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:     ttl,
+		now:     time.Now,
+		clone:   clone,
+		entries: make(map[K]ttlCacheEntry[V]),
+	}
+}
+
+// get 返回未过期的缓存，同时返回当前 generation 供查库后写入时校验
+func (c *ttlCache[K, V]) get(key K) (value V, ok bool, generation uint64) {
+	c.mu.RLock()
+	generation = c.generation
+	entry, found := c.entries[key]
+	c.mu.RUnlock()
+	if c.ttl <= 0 || !found || !c.now().Before(entry.expiresAt) {
+		return value, false, generation
+	}
+	return c.copy(entry.value), true, generation
+}
+
+// put 写入查库结果；generation 已变化（期间有失效）时放弃写入
+func (c *ttlCache[K, V]) put(key K, value V, generation uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	if len(c.entries) >= cacheSweepSize {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = ttlCacheEntry[V]{value: c.copy(value), expiresAt: now.Add(c.ttl)}
+}
+
+// invalidate 删除指定键的缓存
+func (c *ttlCache[K, V]) invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	delete(c.entries, key)
+}
+
+// invalidateFunc 删除 match 返回 true 的全部缓存
+func (c *ttlCache[K, V]) invalidateFunc(match func(K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key := range c.entries {
+		if match(key) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// reset 清空全部缓存（批量写操作后使用）
+func (c *ttlCache[K, V]) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[K]ttlCacheEntry[V])
+}
+
+func (c *ttlCache[K, V]) copy(value V) V {
+	if c.clone == nil {
+		return value
+	}
+	return c.clone(value)
+}
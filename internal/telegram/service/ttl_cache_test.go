@@ -0,0 +1,94 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestTTLCacheExpires(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newTTLCache[int64](time.Second, (*models.Group).Clone)
+	cache.now = func() time.Time { return now }
+
+	_, _, generation := cache.get(1)
+	cache.put(1, &models.Group{TelegramID: 1}, generation)
+	if _, ok, _ := cache.get(1); !ok {
+		t.Fatal("expected cache hit within ttl")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := cache.get(1); ok {
+		t.Fatal("expected cache miss after ttl")
+	}
+
+	cache.put(2, &models.Group{TelegramID: 2}, generation)
+	cache.reset()
+	if _, ok, _ := cache.get(2); ok {
+		t.Fatal("expected reset to drop entries")
+	}
+}
+
+func TestTTLCacheDropsStalePutAfterInvalidate(t *testing.T) {
+	cache := newTTLCache[int64](time.Minute, (*models.Group).Clone)
+
+	// 查库期间群组被修改并失效，查到的旧数据不能回填缓存
+	_, _, generation := cache.get(1)
+	cache.invalidate(1)
+	cache.put(1, &models.Group{TelegramID: 1, Settings: models.GroupSettings{MerchantID: 1001}}, generation)
+	if _, ok, _ := cache.get(1); ok {
+		t.Fatal("expected stale put to be dropped after invalidate")
+	}
+
+	_, _, generation = cache.get(1)
+	cache.reset()
+	cache.put(1, &models.Group{TelegramID: 1}, generation)
+	if _, ok, _ := cache.get(1); ok {
+		t.Fatal("expected stale put to be dropped after reset")
+	}
+
+	_, _, generation = cache.get(1)
+	cache.put(1, &models.Group{TelegramID: 1}, generation)
+	if _, ok, _ := cache.get(1); !ok {
+		t.Fatal("expected put without invalidation to be cached")
+	}
+}
+
+func TestTTLCacheConcurrentAccess(t *testing.T) {
+	cache := newTTLCache[int64](time.Minute, (*models.Group).Clone)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _, generation := cache.get(id)
+				cache.put(id, &models.Group{TelegramID: id, Settings: models.GroupSettings{AdminIDs: []int64{id}}}, generation)
+				if group, ok, _ := cache.get(id); ok {
+					group.Settings.AdminIDs[0] = -1
+				}
+				cache.invalidate(id)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+func TestTTLCacheDropsResultAcrossInvalidateFunc(t *testing.T) {
+	cache := newTTLCache[adminCacheKey, bool](time.Minute, nil)
+
+	_, _, generation := cache.get(adminCacheKey{userID: 3})
+	// 查库期间发生撤权：旧结果不能写入缓存
+	cache.invalidateFunc(func(key adminCacheKey) bool { return key.userID == 3 })
+	cache.put(adminCacheKey{userID: 3}, true, generation)
+	if _, ok, _ := cache.get(adminCacheKey{userID: 3}); ok {
+		t.Fatal("expected stale result to be discarded")
+	}
+
+	_, _, generation = cache.get(adminCacheKey{userID: 3})
+	cache.put(adminCacheKey{userID: 3}, false, generation)
+	if allowed, ok, _ := cache.get(adminCacheKey{userID: 3}); !ok || allowed {
+		t.Fatalf("expected cached denial, got allowed=%v ok=%v", allowed, ok)
+	}
+}
@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"go_bot/internal/logger"
 	paymentservice "go_bot/internal/payment/service"
@@ -15,6 +18,8 @@ import (
 
 const (
 	defaultAlertLimitPerHour = 3
+	// upstreamBalanceEventBuffer 余额事件通道容量，监控消费跟不上时多出的事件直接丢弃
+	upstreamBalanceEventBuffer = 128
 )
 
 // UpstreamBalanceServiceImpl 上游群余额服务
@@ -23,7 +28,9 @@ type UpstreamBalanceServiceImpl struct {
 	groupRepo      repository.GroupRepository
 	paymentService paymentservice.Service
 	events         chan *models.UpstreamBalanceEvent
-	location       *time.Location
+	droppedEvents  atomic.Int64 // 因通道已满被丢弃的事件数
+	// fallbackToTotal 日结找不到目标日期的账单行时，是否回退为汇总内全部行的跑量合计
+	fallbackToTotal bool
 }
 
 type settlementItem struct {
@@ -35,24 +42,42 @@ type settlementItem struct {
 	RawAmount   string
 	RawRate     string
 	Description string
+	Note        string // 结算提示，例如按汇总总额回退
+}
+
+// settlementComparison 日结跑量对比：环比为前一日，同比为上周同日；对比日拉取失败或任一接口无账单时对应 OK 为 false
+type settlementComparison struct {
+	Volume     float64 // 目标日各接口跑量合计
+	PrevDay    float64
+	PrevDayOK  bool
+	LastWeek   float64
+	LastWeekOK bool
 }
 
 // NewUpstreamBalanceService 创建服务实例
+// fallbackToTotal 为 true 时，日结在账单中找不到目标日期会回退到汇总总额（并记警告日志）
 func NewUpstreamBalanceService(
 	repo repository.UpstreamBalanceRepository,
 	groupRepo repository.GroupRepository,
 	paymentSvc paymentservice.Service,
+	fallbackToTotal bool,
 ) UpstreamBalanceService {
 	return &UpstreamBalanceServiceImpl{
-		repo:           repo,
-		groupRepo:      groupRepo,
-		paymentService: paymentSvc,
-		events:         make(chan *models.UpstreamBalanceEvent, 128),
-		location:       mustLoadChinaLocation(),
+		repo:            repo,
+		groupRepo:       groupRepo,
+		paymentService:  paymentSvc,
+		events:          make(chan *models.UpstreamBalanceEvent, upstreamBalanceEventBuffer),
+		fallbackToTotal: fallbackToTotal,
 	}
 }
 
-// Adjust 调整余额
+// SettlementOperationID 日结的幂等键，手动 /日结 与自动日结共用：同一群同一账单日无论由哪条路径触发只扣减一次
+// （operation_id 全局唯一，须带群 ID）
+func SettlementOperationID(groupID int64, target time.Time) string {
+	return fmt.Sprintf("settle:%d:%s", groupID, target.Format("2006-01-02"))
+}
+
+// Adjust 调整余额，operationID 原样透传到仓储层做幂等
 func (s *UpstreamBalanceServiceImpl) Adjust(ctx context.Context, groupID int64, delta float64, operatorID int64, remark string, operationID string) (*UpstreamBalanceResult, bool, error) {
 	if delta == 0 {
 		return nil, false, fmt.Errorf("调整金额不能为 0")
@@ -87,7 +112,13 @@ func (s *UpstreamBalanceServiceImpl) Adjust(ctx context.Context, groupID int64,
 	return result, below, nil
 }
 
-// SetMinBalance 设置最低余额
+// minBalanceOperationID 阈值变更日志的幂等键：按变更前后的值与变更前记录的更新时间生成，
+// 基于同一状态的重复提交（重复点击/重复投递）只记一条，之后再改回同一值时前一状态已变，仍会记录
+func minBalanceOperationID(groupID int64, previous *models.UpstreamBalance, threshold float64) string {
+	return fmt.Sprintf("min_balance:%d:%.2f:%.2f:%d", groupID, previous.MinBalance, threshold, previous.UpdatedAt.UnixNano())
+}
+
+// SetMinBalance 设置最低余额，值有变化时写入幂等日志并立即发布事件触发余额监控评估
 func (s *UpstreamBalanceServiceImpl) SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64) (*UpstreamBalanceResult, error) {
 	if threshold < 0 {
 		return nil, fmt.Errorf("最低余额不能为负数")
@@ -97,7 +128,17 @@ func (s *UpstreamBalanceServiceImpl) SetMinBalance(ctx context.Context, groupID
 		return nil, err
 	}
 
-	balance, err := s.repo.SetMinBalance(ctx, groupID, threshold, operatorID)
+	current, err := s.repo.Get(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if current.MinBalance == threshold {
+		// 同值重复设置不写日志、不触发评估
+		logger.Ctx(ctx).Infof("Min balance unchanged, skipped: chat_id=%d threshold=%.2f operator=%d", groupID, threshold, operatorID)
+		return toBalanceResult(current), nil
+	}
+
+	balance, err := s.repo.SetMinBalance(ctx, groupID, threshold, operatorID, minBalanceOperationID(groupID, current, threshold))
 	if err != nil {
 		return nil, err
 	}
@@ -185,18 +226,18 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 		return nil, err
 	}
 
-	loc := s.location
-	if loc == nil {
-		loc = time.Local
-	}
-	target := targetDate.In(loc)
-	if target.IsZero() {
-		now := time.Now().In(loc)
-		target = previousBillingDate(now, loc)
+	// 「当天」边界按群组时区计算，targetDate 只取其日期部分
+	loc := models.GroupLocation(group.Settings)
+	var target time.Time
+	if targetDate.IsZero() {
+		target = models.PreviousBillingDate(time.Now(), loc)
+	} else {
+		target = models.DayStart(targetDate.Year(), targetDate.Month(), targetDate.Day(), loc)
 	}
 
-	start := time.Date(target.Year(), target.Month(), target.Day(), 0, 0, 0, 0, loc)
-	end := start.Add(24*time.Hour - time.Second)
+	// 夏令时切换当天不足或超过 24 小时，区间结束取次日起点前一秒
+	start := target
+	end := models.DayStart(target.Year(), target.Month(), target.Day()+1, loc).Add(-time.Second)
 
 	items := make([]settlementItem, 0, len(group.Settings.InterfaceBindings))
 	errors := make([]string, 0)
@@ -205,12 +246,20 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 	for _, binding := range group.Settings.InterfaceBindings {
 		summary, sumErr := s.paymentService.GetSummaryByDayByPZID(ctx, binding.ID, start, end)
 		if sumErr != nil {
-			logger.L().Errorf("SettleDaily summary failed: chat_id=%d pzid=%s err=%v", groupID, binding.ID, sumErr)
+			logger.Ctx(ctx).Errorf("SettleDaily summary failed: chat_id=%d pzid=%s err=%v", groupID, binding.ID, sumErr)
 			errors = append(errors, fmt.Sprintf("接口 %s 查询失败: %v", binding.ID, sumErr))
 			continue
 		}
 
 		itemSummary := pickPZIDItem(summary, target)
+		note := ""
+		if itemSummary == nil && s.fallbackToTotal && summary != nil && len(summary.Items) > 0 {
+			// 上游返回的日期格式无法匹配时，查询区间本就只有目标日一天，按汇总合计结算
+			itemSummary = sumPZIDItems(summary)
+			note = "未匹配到目标日期，按汇总总额结算"
+			logger.Ctx(ctx).Warnf("SettleDaily date not matched, fallback to summary total: chat_id=%d pzid=%s target=%s items=%d gross=%s",
+				groupID, binding.ID, target.Format("2006-01-02"), len(summary.Items), itemSummary.GrossAmount)
+		}
 		if itemSummary == nil {
 			items = append(items, settlementItem{
 				Binding:     binding,
@@ -227,6 +276,7 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 
 		volume, parseVolumeErr := parseAmount(itemSummary.GrossAmount)
 		if parseVolumeErr != nil {
+			logger.Ctx(ctx).Errorf("SettleDaily gross amount invalid: chat_id=%d pzid=%s raw=%q err=%v", groupID, binding.ID, itemSummary.GrossAmount, parseVolumeErr)
 			errors = append(errors, fmt.Sprintf("接口 %s 跑量解析失败: %v", binding.ID, parseVolumeErr))
 			continue
 		}
@@ -247,6 +297,7 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 			Deduction: deduction,
 			RawAmount: itemSummary.GrossAmount,
 			RawRate:   binding.Rate,
+			Note:      note,
 		})
 	}
 
@@ -269,7 +320,8 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 		below = balanceResult.Balance < balanceResult.MinBalance
 	}
 
-	report := s.buildSettlementReport(group, target, items, totalDeduction, balanceResult, errors)
+	comparison := s.compareSettlementVolume(ctx, group, target, items)
+	report := s.buildSettlementReport(group, target, items, totalDeduction, balanceResult, errors, comparison)
 
 	return &SettlementResult{
 		GroupID:        groupID,
@@ -281,6 +333,49 @@ func (s *UpstreamBalanceServiceImpl) SettleDaily(ctx context.Context, groupID in
 	}, nil
 }
 
+// compareSettlementVolume 额外拉取前一日与上周同日的跑量用于环比/同比，每个接口一次查询覆盖两个对比日
+// 对比数据只用于展示：任一接口拉取失败或在对比日没有账单时，该对比日整体视为无数据，避免部分接口的合计被当作全量对比；
+// 当日有接口未计入跑量（查询或费率失败）时同样不做对比，不影响日结扣费
+func (s *UpstreamBalanceServiceImpl) compareSettlementVolume(ctx context.Context, group *models.Group, target time.Time, items []settlementItem) *settlementComparison {
+	comparison := &settlementComparison{}
+	for _, it := range items {
+		comparison.Volume += it.Volume
+	}
+	if len(items) == 0 || len(items) != len(group.Settings.InterfaceBindings) {
+		return comparison
+	}
+
+	loc := target.Location()
+	prevDay := models.DayStart(target.Year(), target.Month(), target.Day()-1, loc)
+	lastWeek := models.DayStart(target.Year(), target.Month(), target.Day()-7, loc)
+	prevOK, lastWeekOK := true, true
+	var prevTotal, lastWeekTotal float64
+	for _, it := range items {
+		summary, err := s.paymentService.GetSummaryByDayByPZID(ctx, it.Binding.ID, lastWeek, target.Add(-time.Second))
+		if err != nil {
+			logger.Ctx(ctx).Warnf("SettleDaily comparison summary failed: chat_id=%d pzid=%s err=%v", group.TelegramID, it.Binding.ID, err)
+			return comparison
+		}
+		if volume, ok := pickPZIDVolume(summary, prevDay); ok {
+			prevTotal += volume
+		} else {
+			prevOK = false
+		}
+		if volume, ok := pickPZIDVolume(summary, lastWeek); ok {
+			lastWeekTotal += volume
+		} else {
+			lastWeekOK = false
+		}
+	}
+	if prevOK {
+		comparison.PrevDay, comparison.PrevDayOK = prevTotal, true
+	}
+	if lastWeekOK {
+		comparison.LastWeek, comparison.LastWeekOK = lastWeekTotal, true
+	}
+	return comparison
+}
+
 // SubscribeEvents 获取调整事件通道
 func (s *UpstreamBalanceServiceImpl) SubscribeEvents() <-chan *models.UpstreamBalanceEvent {
 	return s.events
@@ -307,6 +402,8 @@ func (s *UpstreamBalanceServiceImpl) validateUpstreamGroup(group *models.Group)
 	return nil
 }
 
+// publishEvent 非阻塞地投递余额事件：通道满时丢弃并计数，余额变更路径不会因监控消费慢而阻塞，
+// 丢失的事件由监控的周期扫描兜底
 func (s *UpstreamBalanceServiceImpl) publishEvent(ev *models.UpstreamBalanceEvent) {
 	if ev == nil {
 		return
@@ -314,7 +411,9 @@ func (s *UpstreamBalanceServiceImpl) publishEvent(ev *models.UpstreamBalanceEven
 	select {
 	case s.events <- ev:
 	default:
-		logger.L().Warn("Upstream balance event channel full, dropping event")
+		dropped := s.droppedEvents.Add(1)
+		logger.L().Warnf("Upstream balance event channel full, dropping event: group_id=%d, trigger=%s, dropped_total=%d",
+			ev.GroupID, ev.Trigger, dropped)
 	}
 }
 
@@ -325,31 +424,57 @@ func (s *UpstreamBalanceServiceImpl) buildSettlementReport(
 	total float64,
 	balance *UpstreamBalanceResult,
 	errors []string,
+	comparison *settlementComparison,
 ) string {
 	builder := &strings.Builder{}
 	builder.WriteString(fmt.Sprintf("📊 日结 - %s\n", target.Format("2006-01-02")))
-	builder.WriteString(fmt.Sprintf("群组：%s\n\n", group.Title))
+	builder.WriteString(fmt.Sprintf("群组：%s\n\n", html.EscapeString(group.Title)))
+
+	// 只详细展示有跑量的接口，无跑量的折叠为一行；合计不受影响（无跑量接口扣减为 0）
+	active := make([]settlementItem, 0, len(items))
+	idle := make([]string, 0)
+	for _, it := range items {
+		if it.Volume > 0 {
+			active = append(active, it)
+			continue
+		}
+		idle = append(idle, fmt.Sprintf("%s (%s)", html.EscapeString(bindingDisplayName(it.Binding.Name)), html.EscapeString(it.Binding.ID)))
+	}
 
 	if len(items) == 0 {
 		builder.WriteString("未获取到任何接口的账单数据。\n")
 	} else {
-		for _, it := range items {
+		for _, it := range active {
 			desc := it.Description
 			if desc == "" {
 				desc = fmt.Sprintf("跑量：%s，费率：%s%%", formatMoney(it.Volume), formatRatePercent(it.Rate))
 			}
-			builder.WriteString(fmt.Sprintf("• %s (%s)\n", bindingDisplayName(it.Binding.Name), it.Binding.ID))
+			builder.WriteString(fmt.Sprintf("• %s (%s)\n", html.EscapeString(bindingDisplayName(it.Binding.Name)), html.EscapeString(it.Binding.ID)))
 			if it.PZName != "" {
-				builder.WriteString(fmt.Sprintf("  渠道：%s\n", it.PZName))
+				builder.WriteString(fmt.Sprintf("  渠道：%s\n", html.EscapeString(it.PZName)))
 			}
-			builder.WriteString(fmt.Sprintf("  %s\n", desc))
+			builder.WriteString(fmt.Sprintf("  %s\n", html.EscapeString(desc)))
 			if it.Deduction > 0 {
 				builder.WriteString(fmt.Sprintf("  扣减：%s CNY\n", formatMoney(it.Deduction)))
 			}
+			if it.Note != "" {
+				builder.WriteString(fmt.Sprintf("  ⚠️ %s\n", html.EscapeString(it.Note)))
+			}
+		}
+		if len(idle) > 0 {
+			builder.WriteString(fmt.Sprintf("💤 %d 个接口无跑量：%s\n", len(idle), strings.Join(idle, "、")))
 		}
 		builder.WriteString("\n")
 	}
 
+	if comparison != nil {
+		builder.WriteString(fmt.Sprintf("总跑量：%s\n", formatMoney(comparison.Volume)))
+		builder.WriteString(fmt.Sprintf("环比（vs %s）：%s\n", target.AddDate(0, 0, -1).Format("01-02"),
+			formatVolumeChange(comparison.Volume, comparison.PrevDay, comparison.PrevDayOK)))
+		builder.WriteString(fmt.Sprintf("同比（vs 上周同日 %s）：%s\n\n", target.AddDate(0, 0, -7).Format("01-02"),
+			formatVolumeChange(comparison.Volume, comparison.LastWeek, comparison.LastWeekOK)))
+	}
+
 	builder.WriteString(fmt.Sprintf("总扣减：%s CNY\n", formatMoney(total)))
 	builder.WriteString(fmt.Sprintf("当前余额：%s CNY\n", formatMoney(balance.Balance)))
 	builder.WriteString(fmt.Sprintf("最低余额：%s CNY\n", formatMoney(balance.MinBalance)))
@@ -361,7 +486,7 @@ func (s *UpstreamBalanceServiceImpl) buildSettlementReport(
 		builder.WriteString("\n⚠️ 以下接口日结失败：\n")
 		for _, msg := range errors {
 			builder.WriteString("• ")
-			builder.WriteString(msg)
+			builder.WriteString(html.EscapeString(msg))
 			builder.WriteString("\n")
 		}
 	}
@@ -411,18 +536,76 @@ func parseRate(raw string) (float64, error) {
 	return rate, nil
 }
 
+// parseAmount 解析账单金额：去掉千分位逗号与各类空白，空值或非数字都返回错误，
+// 避免字段缺失时按 0 结算导致少扣
 func parseAmount(raw string) (float64, error) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" {
-		return 0, nil
+	cleaned := strings.Map(func(r rune) rune {
+		if r == ',' || r == '，' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, raw)
+	if cleaned == "" {
+		return 0, fmt.Errorf("跑量为空（账单缺少金额字段）")
 	}
-	value, err := strconv.ParseFloat(trimmed, 64)
+	value, err := strconv.ParseFloat(cleaned, 64)
 	if err != nil {
-		return 0, fmt.Errorf("金额格式错误: %w", err)
+		return 0, fmt.Errorf("金额格式错误: %q", raw)
 	}
 	return value, nil
 }
 
+// sumPZIDItems 合计汇总内所有行的跑量，用于日期无法匹配时的回退；
+// 任一行金额无法解析时保留原始值，交由调用方按解析失败处理
+func sumPZIDItems(summary *paymentservice.SummaryByPZID) *paymentservice.SummaryByPZIDItem {
+	total := 0.0
+	for _, item := range summary.Items {
+		if item == nil {
+			continue
+		}
+		value, err := parseAmount(item.GrossAmount)
+		if err != nil {
+			return &paymentservice.SummaryByPZIDItem{GrossAmount: item.GrossAmount}
+		}
+		total += value
+	}
+	return &paymentservice.SummaryByPZIDItem{GrossAmount: strconv.FormatFloat(total, 'f', -1, 64)}
+}
+
+// pickPZIDVolume 取汇总中指定日期的跑量，日期不存在或金额无法解析时 ok 为 false
+func pickPZIDVolume(summary *paymentservice.SummaryByPZID, date time.Time) (float64, bool) {
+	item := pickPZIDItem(summary, date)
+	if item == nil {
+		return 0, false
+	}
+	volume, err := parseAmount(item.GrossAmount)
+	if err != nil {
+		return 0, false
+	}
+	return volume, true
+}
+
+// formatVolumeChange 渲染跑量变化百分比，上涨 ↑、下跌 ↓；对比日无数据时返回「无数据」
+func formatVolumeChange(current, previous float64, ok bool) string {
+	switch {
+	case !ok:
+		return "无数据"
+	case previous == 0 && current == 0:
+		return "持平"
+	case previous == 0:
+		return fmt.Sprintf("↑ 新增 %s（对比日无跑量）", formatMoney(current))
+	}
+	change := (current - previous) / previous * 100
+	switch {
+	case change > 0:
+		return fmt.Sprintf("↑ %.2f%%", change)
+	case change < 0:
+		return fmt.Sprintf("↓ %.2f%%", -change)
+	default:
+		return "持平"
+	}
+}
+
 func pickPZIDItem(summary *paymentservice.SummaryByPZID, targetDate time.Time) *paymentservice.SummaryByPZIDItem {
 	if summary == nil || len(summary.Items) == 0 {
 		return nil
@@ -455,20 +638,6 @@ func formatRatePercent(v float64) string {
 	return fmt.Sprintf("%.2f", v*100)
 }
 
-func mustLoadChinaLocation() *time.Location {
-	loc, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		return time.FixedZone("CST", 8*3600)
-	}
-	return loc
-}
-
-func previousBillingDate(now time.Time, location *time.Location) time.Time {
-	local := now.In(location)
-	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
-	return midnight.AddDate(0, 0, -1)
-}
-
 func normalizeSummaryDate(raw string) string {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
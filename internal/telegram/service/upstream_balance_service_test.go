@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+func TestAdjustDoesNotBlockWhenEventConsumerStalls(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{
+		TelegramID: -1,
+		Tier:       models.GroupTierUpstream,
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{{Name: "通道A", ID: "iface-1"}},
+		},
+	}}
+	svc := NewUpstreamBalanceService(&memoryUpstreamBalanceRepository{}, groups, nil, false).(*UpstreamBalanceServiceImpl)
+
+	// 没有任何消费者，调用量远超通道容量
+	const workers, perWorker = 16, 50
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perWorker; j++ {
+					if _, _, err := svc.Adjust(context.Background(), -1, -1, 7, "", ""); err != nil {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Adjust blocked while the event channel was full")
+	}
+
+	total := int64(workers * perWorker)
+	if got := len(svc.events); got != upstreamBalanceEventBuffer {
+		t.Fatalf("expected channel filled to %d, got %d", upstreamBalanceEventBuffer, got)
+	}
+	if got := svc.droppedEvents.Load(); got != total-upstreamBalanceEventBuffer {
+		t.Fatalf("expected %d dropped events, got %d", total-upstreamBalanceEventBuffer, got)
+	}
+}
+
+func TestPublishEventWithSlowConsumer(t *testing.T) {
+	svc := NewUpstreamBalanceService(nil, nil, nil, false).(*UpstreamBalanceServiceImpl)
+
+	var received sync.WaitGroup
+	received.Add(1)
+	consumed := 0
+	stop := make(chan struct{})
+	go func() {
+		defer received.Done()
+		for {
+			select {
+			case <-svc.SubscribeEvents():
+				consumed++
+				time.Sleep(time.Millisecond)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				svc.publishEvent(&models.UpstreamBalanceEvent{GroupID: -1, Trigger: "adjust"})
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	received.Wait()
+
+	// 已消费 + 仍在通道中 + 被丢弃 = 发布总数
+	if got := int64(consumed+len(svc.events)) + svc.droppedEvents.Load(); got != 8*200 {
+		t.Fatalf("expected every event accounted for, got %d", got)
+	}
+}
+
+// memoryUpstreamBalanceRepository 并发安全的内存余额仓储，仅实现 Adjust、SetMinBalance 与 Get；与 Mongo 实现一样按 operationID 去重
+type memoryUpstreamBalanceRepository struct {
+	repository.UpstreamBalanceRepository
+	mu          sync.Mutex
+	balance     float64
+	minBalance  float64
+	operations  []string
+	settingLogs int
+}
+
+func (r *memoryUpstreamBalanceRepository) Adjust(ctx context.Context, groupID int64, delta float64, operatorID int64, remark string, opType models.BalanceOperationType, operationID string, metadata map[string]string) (*models.UpstreamBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if operationID != "" && slices.Contains(r.operations, operationID) {
+		return &models.UpstreamBalance{GroupID: groupID, Balance: r.balance}, nil
+	}
+	r.operations = append(r.operations, operationID)
+	r.balance += delta
+	return &models.UpstreamBalance{GroupID: groupID, Balance: r.balance}, nil
+}
+
+func (r *memoryUpstreamBalanceRepository) SetMinBalance(ctx context.Context, groupID int64, threshold float64, operatorID int64, operationID string) (*models.UpstreamBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.minBalance = threshold
+	if operationID == "" || !slices.Contains(r.operations, operationID) {
+		r.operations = append(r.operations, operationID)
+		r.settingLogs++
+	}
+	return &models.UpstreamBalance{GroupID: groupID, Balance: r.balance, MinBalance: r.minBalance}, nil
+}
+
+func (r *memoryUpstreamBalanceRepository) Get(ctx context.Context, groupID int64) (*models.UpstreamBalance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &models.UpstreamBalance{GroupID: groupID, Balance: r.balance, MinBalance: r.minBalance}, nil
+}
+
+func TestSetMinBalanceSkipsUnchangedValue(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{
+		TelegramID: -1,
+		Tier:       models.GroupTierUpstream,
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{{Name: "通道A", ID: "a"}},
+		},
+	}}
+	repo := &memoryUpstreamBalanceRepository{balance: 300}
+	svc := NewUpstreamBalanceService(repo, groups, nil, false).(*UpstreamBalanceServiceImpl)
+	ctx := context.Background()
+
+	result, err := svc.SetMinBalance(ctx, -1, 500, 7)
+	if err != nil || result.MinBalance != 500 {
+		t.Fatalf("unexpected result %+v err=%v", result, err)
+	}
+	// 变更后立即发布事件，监控无需等下一轮扫描
+	select {
+	case event := <-svc.events:
+		if event.Trigger != "set_min_balance" || !event.BelowMin {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	default:
+		t.Fatalf("expected balance event after threshold change")
+	}
+
+	// 同值重复设置：不写日志、不发布事件
+	if _, err := svc.SetMinBalance(ctx, -1, 500, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.settingLogs != 1 || len(svc.events) != 0 {
+		t.Fatalf("expected unchanged threshold skipped, logs=%d events=%d", repo.settingLogs, len(svc.events))
+	}
+
+	if _, err := svc.SetMinBalance(ctx, -1, 200, 7); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.settingLogs != 2 || len(svc.events) != 1 || !strings.HasPrefix(repo.operations[len(repo.operations)-1], "min_balance:-1:500.00:200.00:") {
+		t.Fatalf("expected second change logged with operation id, logs=%d ops=%v", repo.settingLogs, repo.operations)
+	}
+}
+
+func TestMinBalanceOperationIDKeysOnTransition(t *testing.T) {
+	base := time.Date(2024, 11, 20, 10, 0, 5, 0, time.UTC)
+	previous := &models.UpstreamBalance{MinBalance: 100, UpdatedAt: base}
+	if minBalanceOperationID(-1, previous, 500) != minBalanceOperationID(-1, &models.UpstreamBalance{MinBalance: 100, UpdatedAt: base}, 500) {
+		t.Fatalf("expected duplicate submissions from the same state to share an id")
+	}
+	if minBalanceOperationID(-1, previous, 500) == minBalanceOperationID(-1, previous, 600) {
+		t.Fatalf("expected different targets to get different ids")
+	}
+	// 100 → 500 → 100 → 500：再次改回时前一状态已更新，仍单独记录
+	if minBalanceOperationID(-1, previous, 500) == minBalanceOperationID(-1, &models.UpstreamBalance{MinBalance: 100, UpdatedAt: base.Add(time.Minute)}, 500) {
+		t.Fatalf("expected a repeated transition from a newer state to get a new id")
+	}
+}
+
+func TestAdjustPassesOperationIDToRepository(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{
+		TelegramID: -1,
+		Tier:       models.GroupTierUpstream,
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{{Name: "通道A", ID: "a", Rate: "10%"}},
+		},
+	}}
+	payments := &summaryPaymentService{summaries: map[string]*paymentservice.SummaryByPZID{
+		"a": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "2024-11-20", GrossAmount: "1000"}}},
+	}}
+	repo := &memoryUpstreamBalanceRepository{}
+	svc := NewUpstreamBalanceService(repo, groups, payments, false)
+	ctx := context.Background()
+
+	// 空 operationID 保持旧行为：每次都生效
+	for range 2 {
+		if _, _, err := svc.Adjust(ctx, -1, 100, 7, "", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for range 2 {
+		if _, _, err := svc.Adjust(ctx, -1, 100, 7, "", "adjust:-1:10"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if repo.balance != 300 {
+		t.Fatalf("expected balance 300, got %v", repo.balance)
+	}
+
+	target := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+	operationID := SettlementOperationID(-1, target)
+	if operationID != "settle:-1:2024-11-20" {
+		t.Fatalf("unexpected settlement operation id %q", operationID)
+	}
+	for range 2 {
+		if _, err := svc.SettleDaily(ctx, -1, target, 7, operationID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if repo.balance != 200 {
+		t.Fatalf("expected repeated settlement to deduct once, got balance %v", repo.balance)
+	}
+	if !slices.Contains(repo.operations, operationID) {
+		t.Fatalf("expected operation id passed to repository, got %v", repo.operations)
+	}
+}
+
+func TestBuildSettlementReportFoldsIdleBindings(t *testing.T) {
+	svc := &UpstreamBalanceServiceImpl{}
+	group := &models.Group{Title: "上游群"}
+	items := []settlementItem{
+		{Binding: models.InterfaceBinding{Name: "通道A", ID: "1001"}, Volume: 1000, Rate: 0.05, Deduction: 50},
+		{Binding: models.InterfaceBinding{Name: "通道B", ID: "1002"}, Description: "无数据"},
+		{Binding: models.InterfaceBinding{ID: "1003"}, RawAmount: "0.00"},
+	}
+	balance := &UpstreamBalanceResult{Balance: 950, MinBalance: 100}
+
+	report := svc.buildSettlementReport(group, time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC), items, 50, balance, nil, nil)
+
+	if !strings.Contains(report, "• 通道A (1001)") || !strings.Contains(report, "扣减：50.00 CNY") {
+		t.Fatalf("expected active binding in detail:\n%s", report)
+	}
+	if !strings.Contains(report, "💤 2 个接口无跑量：通道B (1002)、(未命名接口) (1003)") {
+		t.Fatalf("expected idle bindings folded into one line:\n%s", report)
+	}
+	if strings.Contains(report, "• 通道B") || strings.Contains(report, "无数据") {
+		t.Fatalf("expected idle bindings not listed in detail:\n%s", report)
+	}
+	if !strings.Contains(report, "总扣减：50.00 CNY") {
+		t.Fatalf("expected total unchanged:\n%s", report)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]float64{
+		"1234.5":         1234.5,
+		" 1,234,567.89 ": 1234567.89,
+		"12，345":         12345,
+		"1 000":          1000,
+		"0":              0,
+	}
+	for raw, want := range cases {
+		got, err := parseAmount(raw)
+		if err != nil || got != want {
+			t.Fatalf("parseAmount(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"", "   ", "abc", "12.3元"} {
+		if _, err := parseAmount(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+// summaryPaymentService 按接口 ID 返回固定账单汇总
+type summaryPaymentService struct {
+	paymentservice.Service
+	summaries map[string]*paymentservice.SummaryByPZID
+}
+
+func (s *summaryPaymentService) GetSummaryByDayByPZID(ctx context.Context, pzid string, start, end time.Time) (*paymentservice.SummaryByPZID, error) {
+	return s.summaries[pzid], nil
+}
+
+func TestSettleDailySummaryFallbackAndParseErrors(t *testing.T) {
+	newService := func(fallback bool) (*UpstreamBalanceServiceImpl, *memoryUpstreamBalanceRepository) {
+		groups := &stubGroupRepository{storedGroup: &models.Group{
+			TelegramID: -1,
+			Title:      "上游群",
+			Tier:       models.GroupTierUpstream,
+			Settings: models.GroupSettings{
+				InterfaceBindings: []models.InterfaceBinding{
+					{Name: "通道A", ID: "a", Rate: "10%"},
+					{Name: "通道B", ID: "b", Rate: "10%"},
+				},
+			},
+		}}
+		payments := &summaryPaymentService{summaries: map[string]*paymentservice.SummaryByPZID{
+			// 日期字段为无法识别的格式
+			"a": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "20号", GrossAmount: "1,000"}}},
+			// 缺少金额字段
+			"b": {Items: []*paymentservice.SummaryByPZIDItem{{Date: "2024-11-20", OrderCount: "3"}}},
+		}}
+		repo := &memoryUpstreamBalanceRepository{}
+		return NewUpstreamBalanceService(repo, groups, payments, fallback).(*UpstreamBalanceServiceImpl), repo
+	}
+	target := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	svc, _ := newService(false)
+	result, err := svc.SettleDaily(context.Background(), -1, target, 7, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalDeduction != 0 {
+		t.Fatalf("expected no deduction without fallback, got %v", result.TotalDeduction)
+	}
+	if !strings.Contains(result.Report, "接口 b 跑量解析失败") {
+		t.Fatalf("expected missing amount reported as failure:\n%s", result.Report)
+	}
+
+	svc, repo := newService(true)
+	result, err = svc.SettleDaily(context.Background(), -1, target, 7, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalDeduction != 100 || repo.balance != -100 {
+		t.Fatalf("expected fallback deduction 100, got %v (balance %v)", result.TotalDeduction, repo.balance)
+	}
+	if !strings.Contains(result.Report, "按汇总总额结算") {
+		t.Fatalf("expected fallback note in report:\n%s", result.Report)
+	}
+}
+
+// rangeSummaryPaymentService 按接口 ID 返回多日账单，只保留查询区间内的日期
+type rangeSummaryPaymentService struct {
+	paymentservice.Service
+	items map[string][]*paymentservice.SummaryByPZIDItem
+	fail  map[string]bool
+}
+
+func (s *rangeSummaryPaymentService) GetSummaryByDayByPZID(ctx context.Context, pzid string, start, end time.Time) (*paymentservice.SummaryByPZID, error) {
+	if s.fail[pzid] && start.Before(end.Add(-24*time.Hour)) {
+		return nil, errors.New("upstream unavailable")
+	}
+	summary := &paymentservice.SummaryByPZID{}
+	for _, item := range s.items[pzid] {
+		date, _ := time.ParseInLocation("2006-01-02", item.Date, start.Location())
+		if !date.Before(start) && !date.After(end) {
+			summary.Items = append(summary.Items, item)
+		}
+	}
+	return summary, nil
+}
+
+func TestSettleDailyVolumeComparison(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{
+		TelegramID: -1,
+		Title:      "上游群",
+		Tier:       models.GroupTierUpstream,
+		Settings: models.GroupSettings{
+			InterfaceBindings: []models.InterfaceBinding{
+				{Name: "通道A", ID: "a", Rate: "10%"},
+				{Name: "通道B", ID: "b", Rate: "10%"},
+			},
+		},
+	}}
+	payments := &rangeSummaryPaymentService{items: map[string][]*paymentservice.SummaryByPZIDItem{
+		"a": {
+			{Date: "2024-11-20", GrossAmount: "600"},
+			{Date: "2024-11-19", GrossAmount: "500"},
+		},
+		"b": {
+			{Date: "2024-11-20", GrossAmount: "600"},
+			{Date: "2024-11-19", GrossAmount: "500"},
+		},
+	}}
+	svc := NewUpstreamBalanceService(&memoryUpstreamBalanceRepository{}, groups, payments, false)
+	target := time.Date(2024, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	result, err := svc.SettleDaily(context.Background(), -1, target, 7, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TotalDeduction != 120 {
+		t.Fatalf("expected comparison not to affect deduction, got %v", result.TotalDeduction)
+	}
+	if !strings.Contains(result.Report, "总跑量：1200.00") || !strings.Contains(result.Report, "环比（vs 11-19）：↑ 20.00%") {
+		t.Fatalf("expected day-over-day change in report:\n%s", result.Report)
+	}
+	if !strings.Contains(result.Report, "同比（vs 上周同日 11-13）：无数据") {
+		t.Fatalf("expected missing week-over-week data marked:\n%s", result.Report)
+	}
+
+	// 任一接口在对比日没有账单时，不能拿部分接口的合计做对比
+	payments.items["b"] = payments.items["b"][:1]
+	result, err = svc.SettleDaily(context.Background(), -1, target, 7, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Report, "环比（vs 11-19）：无数据") || result.TotalDeduction != 120 {
+		t.Fatalf("expected partial comparison data marked missing:\n%s", result.Report)
+	}
+
+	// 对比日拉取失败时标「无数据」，日结照常完成
+	payments.fail = map[string]bool{"b": true}
+	result, err = svc.SettleDaily(context.Background(), -1, target, 7, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Report, "环比（vs 11-19）：无数据") || result.TotalDeduction != 120 {
+		t.Fatalf("expected comparison failure tolerated:\n%s", result.Report)
+	}
+}
+
+func TestFormatVolumeChange(t *testing.T) {
+	cases := []struct {
+		current, previous float64
+		ok                bool
+		want              string
+	}{
+		{current: 90, previous: 100, ok: true, want: "↓ 10.00%"},
+		{current: 150, previous: 100, ok: true, want: "↑ 50.00%"},
+		{current: 100, previous: 100, ok: true, want: "持平"},
+		{current: 0, previous: 0, ok: true, want: "持平"},
+		{current: 50, previous: 0, ok: true, want: "↑ 新增 50.00（对比日无跑量）"},
+		{current: 50, previous: 0, ok: false, want: "无数据"},
+	}
+	for _, tc := range cases {
+		if got := formatVolumeChange(tc.current, tc.previous, tc.ok); got != tc.want {
+			t.Fatalf("formatVolumeChange(%v, %v, %v) = %q, want %q", tc.current, tc.previous, tc.ok, got, tc.want)
+		}
+	}
+}
+
+// recordingSummaryPaymentService 记录日结查询区间
+type recordingSummaryPaymentService struct {
+	paymentservice.Service
+	starts, ends []time.Time
+}
+
+func (s *recordingSummaryPaymentService) GetSummaryByDayByPZID(ctx context.Context, pzid string, start, end time.Time) (*paymentservice.SummaryByPZID, error) {
+	s.starts = append(s.starts, start)
+	s.ends = append(s.ends, end)
+	return &paymentservice.SummaryByPZID{}, nil
+}
+
+func TestSettleDailyQueriesWholeBillingDay(t *testing.T) {
+	tests := []struct {
+		name             string
+		timezone         string
+		target           time.Time
+		wantStart        time.Time
+		wantEnd          time.Time
+		wantCompareStart time.Time // 对比区间的起点（上周同日）
+	}{
+		{
+			name:             "cross year",
+			timezone:         "Asia/Shanghai",
+			target:           time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			wantStart:        time.Date(2024, 12, 30, 16, 0, 0, 0, time.UTC),
+			wantEnd:          time.Date(2024, 12, 31, 15, 59, 59, 0, time.UTC),
+			wantCompareStart: time.Date(2024, 12, 23, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			// 当天 00:00 不存在，只有 23 小时
+			name:             "midnight DST start",
+			timezone:         "America/Sao_Paulo",
+			target:           time.Date(2018, 11, 4, 0, 0, 0, 0, time.UTC),
+			wantStart:        time.Date(2018, 11, 4, 3, 0, 0, 0, time.UTC),
+			wantEnd:          time.Date(2018, 11, 5, 1, 59, 59, 0, time.UTC),
+			wantCompareStart: time.Date(2018, 10, 28, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			// 夏令时结束当天有 25 小时
+			name:             "fall back",
+			timezone:         "America/New_York",
+			target:           time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC),
+			wantStart:        time.Date(2024, 11, 3, 4, 0, 0, 0, time.UTC),
+			wantEnd:          time.Date(2024, 11, 4, 4, 59, 59, 0, time.UTC),
+			wantCompareStart: time.Date(2024, 10, 27, 4, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := time.LoadLocation(tt.timezone); err != nil {
+				t.Skipf("timezone data unavailable: %v", err)
+			}
+			groups := &stubGroupRepository{storedGroup: &models.Group{
+				TelegramID: -1,
+				Tier:       models.GroupTierUpstream,
+				Settings: models.GroupSettings{
+					Timezone:          tt.timezone,
+					InterfaceBindings: []models.InterfaceBinding{{Name: "通道A", ID: "a", Rate: "10%"}},
+				},
+			}}
+			payments := &recordingSummaryPaymentService{}
+			svc := NewUpstreamBalanceService(&memoryUpstreamBalanceRepository{}, groups, payments, false)
+
+			result, err := svc.SettleDaily(context.Background(), -1, tt.target, 7, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.TargetDate.Format("2006-01-02") != tt.target.Format("2006-01-02") {
+				t.Fatalf("expected target date %s, got %v", tt.target.Format("2006-01-02"), result.TargetDate)
+			}
+			if len(payments.starts) != 2 {
+				t.Fatalf("expected settlement and comparison queries, got %d", len(payments.starts))
+			}
+			if !payments.starts[0].Equal(tt.wantStart) || !payments.ends[0].Equal(tt.wantEnd) {
+				t.Fatalf("expected range [%v, %v], got [%v, %v]", tt.wantStart, tt.wantEnd, payments.starts[0], payments.ends[0])
+			}
+			if !payments.starts[1].Equal(tt.wantCompareStart) || !payments.ends[1].Equal(tt.wantStart.Add(-time.Second)) {
+				t.Fatalf("unexpected comparison range [%v, %v]", payments.starts[1], payments.ends[1])
+			}
+		})
+	}
+}
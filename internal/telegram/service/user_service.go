@@ -3,22 +3,48 @@ package service
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"slices"
 	"time"
 
 	"go_bot/internal/logger"
+	"go_bot/internal/telegram/i18n"
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/repository"
 )
 
+// MaxStartPayloadLength /start 深链接参数的最大长度（Telegram 限制）
+const MaxStartPayloadLength = 64
+
+var startPayloadPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // UserServiceImpl 用户服务实现
 type UserServiceImpl struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	groupRepo  repository.GroupRepository
+	adminCache *ttlCache[adminCacheKey, bool] // CheckAdminPermission 短 TTL 缓存，授权/撤权时失效
+	langCache  *ttlCache[int64, string]       // GetPreferredLanguage 短 TTL 缓存，/lang 设置时失效
 }
 
-// NewUserService 创建用户服务
-func NewUserService(userRepo repository.UserRepository) UserService {
+// DefaultAdminCacheTTL 管理员权限判断结果的缓存时长，授权/撤权时会立即失效
+const DefaultAdminCacheTTL = 10 * time.Second
+
+// DefaultLangCacheTTL 用户偏好语言的缓存时长，/lang 设置时会立即失效
+const DefaultLangCacheTTL = time.Minute
+
+// adminCacheKey 缓存键：用户 + 群（群级管理员只在所在群生效，私聊为 0）
+type adminCacheKey struct {
+	userID int64
+	chatID int64
+}
+
+// NewUserService 创建用户服务，groupRepo 用于判断群级管理员
+func NewUserService(userRepo repository.UserRepository, groupRepo repository.GroupRepository) UserService {
 	return &UserServiceImpl{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		groupRepo:  groupRepo,
+		adminCache: newTTLCache[adminCacheKey, bool](DefaultAdminCacheTTL, nil),
+		langCache:  newTTLCache[int64, string](DefaultLangCacheTTL, nil),
 	}
 }
 
@@ -36,48 +62,64 @@ func (s *UserServiceImpl) RegisterOrUpdateUser(ctx context.Context, info *Telegr
 	}
 
 	if err := s.userRepo.CreateOrUpdate(ctx, user); err != nil {
-		logger.L().Errorf("Failed to register/update user %d: %v", info.TelegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to register/update user %d: %v", info.TelegramID, err)
 		return fmt.Errorf("failed to register user: %w", err)
 	}
 
-	logger.L().Infof("User %d (%s) registered/updated", info.TelegramID, info.Username)
+	logger.Ctx(ctx).Infof("User %d (%s) registered/updated", info.TelegramID, info.Username)
 	return nil
 }
 
 // GrantAdminPermission 授予管理员权限（包含业务验证）
 func (s *UserServiceImpl) GrantAdminPermission(ctx context.Context, targetID, grantedBy int64) error {
+	return s.GrantRole(ctx, targetID, models.RoleAdmin, grantedBy)
+}
+
+// GrantRole 授予管理角色（admin/super_admin），已是管理员时按目标角色升降级
+func (s *UserServiceImpl) GrantRole(ctx context.Context, targetID int64, role string, grantedBy int64) error {
+	if role != models.RoleAdmin && role != models.RoleSuperAdmin {
+		return fmt.Errorf("不支持授予的角色：%s", role)
+	}
+
 	// 1. 验证授权者权限
 	granter, err := s.userRepo.GetByTelegramID(ctx, grantedBy)
 	if err != nil {
-		logger.L().Errorf("Granter %d not found: %v", grantedBy, err)
+		logger.Ctx(ctx).Errorf("Granter %d not found: %v", grantedBy, err)
 		return fmt.Errorf("授权者不存在")
 	}
 
-	if !granter.IsOwner() {
-		logger.L().Warnf("User %d attempted to grant admin without owner permission", grantedBy)
-		return fmt.Errorf("只有 Owner 可以授予管理员权限")
+	if !granter.HasPermission(models.PermManageAdmins) {
+		logger.Ctx(ctx).Warnf("User %d attempted to grant %s without manage_admins permission", grantedBy, role)
+		return fmt.Errorf("没有授予管理员的权限")
 	}
 
 	// 2. 检查目标用户是否存在
 	target, err := s.userRepo.GetByTelegramID(ctx, targetID)
 	if err != nil {
-		logger.L().Errorf("Target user %d not found: %v", targetID, err)
+		logger.Ctx(ctx).Errorf("Target user %d not found: %v", targetID, err)
 		return fmt.Errorf("目标用户不存在")
 	}
 
-	// 3. 检查是否已经是管理员
-	if target.IsAdmin() {
-		logger.L().Infof("User %d is already an admin", targetID)
-		return fmt.Errorf("用户已经是管理员")
+	// 3. 不能调整 Owner 及拥有授权权限的管理员（仅 Owner 可以），角色未变化时提示
+	if target.IsOwner() {
+		return fmt.Errorf("不能修改 Owner 的角色")
+	}
+	if err := checkManageAdminsTarget(granter, target); err != nil {
+		return err
+	}
+	if target.Role == role {
+		logger.Ctx(ctx).Infof("User %d is already %s", targetID, role)
+		return fmt.Errorf("用户已经是%s", models.RoleLabel(role))
 	}
 
 	// 4. 执行授权
-	if err := s.userRepo.GrantAdmin(ctx, targetID, grantedBy); err != nil {
-		logger.L().Errorf("Failed to grant admin to %d: %v", targetID, err)
+	defer s.invalidateUserAdminCache(targetID)
+	if err := s.userRepo.GrantRole(ctx, targetID, role, grantedBy); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to grant %s to %d: %v", role, targetID, err)
 		return fmt.Errorf("授权失败: %w", err)
 	}
 
-	logger.L().Infof("User %d granted admin permission by %d", targetID, grantedBy)
+	logger.Ctx(ctx).Infof("User %d granted %s by %d (previous role: %s)", targetID, role, grantedBy, target.Role)
 	return nil
 }
 
@@ -86,41 +128,53 @@ func (s *UserServiceImpl) RevokeAdminPermission(ctx context.Context, targetID, r
 	// 1. 验证撤销者权限
 	revoker, err := s.userRepo.GetByTelegramID(ctx, revokedBy)
 	if err != nil {
-		logger.L().Errorf("Revoker %d not found: %v", revokedBy, err)
+		logger.Ctx(ctx).Errorf("Revoker %d not found: %v", revokedBy, err)
 		return fmt.Errorf("撤销者不存在")
 	}
 
-	if !revoker.IsOwner() {
-		logger.L().Warnf("User %d attempted to revoke admin without owner permission", revokedBy)
-		return fmt.Errorf("只有 Owner 可以撤销管理员权限")
+	if !revoker.HasPermission(models.PermManageAdmins) {
+		logger.Ctx(ctx).Warnf("User %d attempted to revoke admin without manage_admins permission", revokedBy)
+		return fmt.Errorf("没有撤销管理员的权限")
 	}
 
 	// 2. 检查目标用户
 	target, err := s.userRepo.GetByTelegramID(ctx, targetID)
 	if err != nil {
-		logger.L().Errorf("Target user %d not found: %v", targetID, err)
+		logger.Ctx(ctx).Errorf("Target user %d not found: %v", targetID, err)
 		return fmt.Errorf("目标用户不存在")
 	}
 
-	// 3. 不能撤销 Owner
+	// 3. 不能撤销 Owner，拥有授权权限的管理员仅 Owner 可以撤销
 	if target.IsOwner() {
-		logger.L().Warnf("User %d attempted to revoke owner permission", revokedBy)
+		logger.Ctx(ctx).Warnf("User %d attempted to revoke owner permission", revokedBy)
 		return fmt.Errorf("不能撤销 Owner 权限")
 	}
+	if err := checkManageAdminsTarget(revoker, target); err != nil {
+		return err
+	}
 
 	// 4. 检查是否已经是普通用户
 	if target.Role == models.RoleUser {
-		logger.L().Infof("User %d is already a regular user", targetID)
+		logger.Ctx(ctx).Infof("User %d is already a regular user", targetID)
 		return fmt.Errorf("用户已经是普通用户")
 	}
 
 	// 5. 执行撤销
+	defer s.invalidateUserAdminCache(targetID)
 	if err := s.userRepo.RevokeAdmin(ctx, targetID); err != nil {
-		logger.L().Errorf("Failed to revoke admin from %d: %v", targetID, err)
+		logger.Ctx(ctx).Errorf("Failed to revoke admin from %d: %v", targetID, err)
 		return fmt.Errorf("撤销失败: %w", err)
 	}
 
-	logger.L().Infof("User %d admin permission revoked by %d", targetID, revokedBy)
+	logger.Ctx(ctx).Infof("User %d admin permission revoked by %d", targetID, revokedBy)
+	return nil
+}
+
+// checkManageAdminsTarget 拥有「管理员授权」权限的用户只能由 Owner 调整，防止授权者之间互相撤销或提权
+func checkManageAdminsTarget(operator, target *models.User) error {
+	if !operator.IsOwner() && target.HasPermission(models.PermManageAdmins) {
+		return fmt.Errorf("只有 Owner 可以调整拥有「%s」权限的用户", models.PermissionLabel(models.PermManageAdmins))
+	}
 	return nil
 }
 
@@ -128,7 +182,7 @@ func (s *UserServiceImpl) RevokeAdminPermission(ctx context.Context, targetID, r
 func (s *UserServiceImpl) GetUserInfo(ctx context.Context, telegramID int64) (*models.User, error) {
 	user, err := s.userRepo.GetUserInfo(ctx, telegramID)
 	if err != nil {
-		logger.L().Errorf("Failed to get user info for %d: %v", telegramID, err)
+		logger.Ctx(ctx).Errorf("Failed to get user info for %d: %v", telegramID, err)
 		return nil, fmt.Errorf("获取用户信息失败")
 	}
 	return user, nil
@@ -138,35 +192,280 @@ func (s *UserServiceImpl) GetUserInfo(ctx context.Context, telegramID int64) (*m
 func (s *UserServiceImpl) ListAllAdmins(ctx context.Context) ([]*models.User, error) {
 	admins, err := s.userRepo.ListAdmins(ctx)
 	if err != nil {
-		logger.L().Errorf("Failed to list admins: %v", err)
+		logger.Ctx(ctx).Errorf("Failed to list admins: %v", err)
 		return nil, fmt.Errorf("获取管理员列表失败")
 	}
 	return admins, nil
 }
 
-// CheckOwnerPermission 检查是否为 Owner
-func (s *UserServiceImpl) CheckOwnerPermission(ctx context.Context, telegramID int64) (bool, error) {
-	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
+// ListAdminsPage 分页列出管理员，页码超出范围时返回最后一页
+func (s *UserServiceImpl) ListAdminsPage(ctx context.Context, role string, page, pageSize int) (*AdminPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("每页条数必须大于 0")
+	}
+	roles := models.AdminRoles
+	if role != "" {
+		if !slices.Contains(models.AdminRoles, role) {
+			return nil, fmt.Errorf("未知的管理角色：%s", role)
+		}
+		roles = []string{role}
+	}
+	page = max(page, 1)
+
+	admins, total, err := s.userRepo.ListAdminsPage(ctx, roles, int64((page-1)*pageSize), int64(pageSize))
+	if err == nil && len(admins) == 0 && total > 0 {
+		// 翻页期间有管理员被撤销，退回到最后一页
+		page = int((total + int64(pageSize) - 1) / int64(pageSize))
+		admins, total, err = s.userRepo.ListAdminsPage(ctx, roles, int64((page-1)*pageSize), int64(pageSize))
+	}
 	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list admins page: role=%q page=%d err=%v", role, page, err)
+		return nil, fmt.Errorf("获取管理员列表失败")
+	}
+
+	return &AdminPage{
+		Admins:     admins,
+		Total:      total,
+		Page:       page,
+		TotalPages: max(int((total+int64(pageSize)-1)/int64(pageSize)), 1),
+	}, nil
+}
+
+// CheckAdminPermission 检查是否为 Admin+：全局管理员在任意群生效，群级管理员仅在所在群生效
+// 结果按（用户, 群）短 TTL 缓存，查询出错时不缓存
+func (s *UserServiceImpl) CheckAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	if !models.IsGroupChatID(chatID) {
+		chatID = 0
+	}
+	allowed, ok, generation := s.adminCache.get(adminCacheKey{userID: telegramID, chatID: chatID})
+	if ok {
+		return allowed, nil
+	}
+
+	allowed, err := s.checkAdminPermission(ctx, telegramID, chatID)
+	if err == nil {
+		s.adminCache.put(adminCacheKey{userID: telegramID, chatID: chatID}, allowed, generation)
+	}
+	return allowed, err
+}
+
+// InvalidateGroupAdminCache 丢弃指定群的权限缓存，群配置中的群级管理员变化后调用
+func (s *UserServiceImpl) InvalidateGroupAdminCache(chatID int64) {
+	s.adminCache.invalidateFunc(func(key adminCacheKey) bool { return key.chatID == chatID })
+}
+
+// invalidateUserAdminCache 丢弃用户在所有群的权限缓存，授权/撤权后调用
+func (s *UserServiceImpl) invalidateUserAdminCache(telegramID int64) {
+	s.adminCache.invalidateFunc(func(key adminCacheKey) bool { return key.userID == telegramID })
+}
+
+func (s *UserServiceImpl) checkAdminPermission(ctx context.Context, telegramID, chatID int64) (bool, error) {
+	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
+	if err == nil && user.HasPermission(models.PermAdmin) {
+		return true, nil
+	}
+	if !models.IsGroupChatID(chatID) {
 		return false, err
 	}
-	return user.IsOwner(), nil
+
+	// 群级管理员可能从未私聊过 Bot，用户记录不存在时仍按群配置判断
+	group, groupErr := s.groupRepo.GetByTelegramID(ctx, chatID)
+	if groupErr != nil {
+		return false, groupErr
+	}
+	return group.Settings.IsGroupAdmin(telegramID), nil
 }
 
-// CheckAdminPermission 检查是否为 Admin+
-func (s *UserServiceImpl) CheckAdminPermission(ctx context.Context, telegramID int64) (bool, error) {
+// HasPermission 检查用户是否拥有指定权限
+func (s *UserServiceImpl) HasPermission(ctx context.Context, telegramID int64, perm string) (bool, error) {
 	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		return false, err
 	}
-	return user.IsAdmin(), nil
+	return user.HasPermission(perm), nil
+}
+
+// UpdateUserPermissions 为管理员增减额外权限：仅 Owner 可授予或收回「管理员授权」，
+// 角色预设已包含的权限无需额外记录，收回也只影响额外授予的部分
+func (s *UserServiceImpl) UpdateUserPermissions(ctx context.Context, targetID int64, add, remove []string, operatorID int64) (*models.User, error) {
+	operator, err := s.userRepo.GetByTelegramID(ctx, operatorID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Operator %d not found: %v", operatorID, err)
+		return nil, fmt.Errorf("操作者不存在")
+	}
+	if !operator.HasPermission(models.PermManageAdmins) {
+		logger.Ctx(ctx).Warnf("User %d attempted to update permissions without manage_admins permission", operatorID)
+		return nil, fmt.Errorf("没有调整权限的权限")
+	}
+
+	target, err := s.userRepo.GetByTelegramID(ctx, targetID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Target user %d not found: %v", targetID, err)
+		return nil, fmt.Errorf("目标用户不存在")
+	}
+	if target.IsOwner() {
+		return nil, fmt.Errorf("Owner 拥有全部权限，无需调整")
+	}
+	if !target.IsAdmin() {
+		return nil, fmt.Errorf("请先通过 /grant 授予管理员角色")
+	}
+	if err := checkManageAdminsTarget(operator, target); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]bool, len(add)+len(remove))
+	for _, list := range []struct {
+		names []string
+		grant bool
+	}{{add, true}, {remove, false}} {
+		for _, name := range list.names {
+			perm, err := models.ParsePermission(name)
+			if err != nil {
+				return nil, err
+			}
+			if perm == models.PermManageAdmins && !operator.IsOwner() {
+				return nil, fmt.Errorf("只有 Owner 可以调整「%s」权限", models.PermissionLabel(perm))
+			}
+			changes[perm] = list.grant
+		}
+	}
+
+	preset := make(map[string]bool)
+	for _, perm := range models.RolePermissions(target.Role) {
+		preset[perm] = true
+	}
+	extra := make(map[string]bool)
+	for _, perm := range target.Permissions {
+		extra[perm] = true
+	}
+	for perm, grant := range changes {
+		extra[perm] = grant && !preset[perm]
+	}
+
+	var permissions []string
+	for _, info := range models.AllPermissions {
+		if extra[info.Name] {
+			permissions = append(permissions, info.Name)
+		}
+	}
+
+	defer s.invalidateUserAdminCache(targetID)
+	if err := s.userRepo.UpdatePermissions(ctx, targetID, permissions); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to update permissions for %d: %v", targetID, err)
+		return nil, fmt.Errorf("更新权限失败")
+	}
+	target.Permissions = permissions
+
+	logger.Ctx(ctx).Infof("User %d permissions updated by %d: add=%v remove=%v extra=%v", targetID, operatorID, add, remove, permissions)
+	return target, nil
 }
 
 // UpdateUserActivity 更新用户活跃时间
 func (s *UserServiceImpl) UpdateUserActivity(ctx context.Context, telegramID int64) error {
 	if err := s.userRepo.UpdateLastActive(ctx, telegramID); err != nil {
-		logger.L().Warnf("Failed to update user activity for %d: %v", telegramID, err)
+		logger.Ctx(ctx).Warnf("Failed to update user activity for %d: %v", telegramID, err)
 		// 不返回错误，仅记录日志
 	}
 	return nil
 }
+
+// SetPreferredLanguage 设置用户偏好语言
+func (s *UserServiceImpl) SetPreferredLanguage(ctx context.Context, telegramID int64, lang string) error {
+	if lang != "" && !i18n.IsSupported(lang) {
+		return fmt.Errorf("不支持的语言：%s", lang)
+	}
+
+	if err := s.userRepo.UpdatePreferredLanguage(ctx, telegramID, lang); err != nil {
+		logger.Ctx(ctx).Errorf("Failed to update preferred language for %d: %v", telegramID, err)
+		return fmt.Errorf("设置语言失败")
+	}
+	s.langCache.invalidate(telegramID)
+
+	logger.Ctx(ctx).Infof("User %d preferred language set to %q", telegramID, lang)
+	return nil
+}
+
+// GetPreferredLanguage 返回用户通过 /lang 设置的偏好语言，未设置时返回空字符串
+// 每条回复都要解析语言，结果按用户短 TTL 缓存，查询出错时不缓存
+func (s *UserServiceImpl) GetPreferredLanguage(ctx context.Context, telegramID int64) (string, error) {
+	lang, ok, generation := s.langCache.get(telegramID)
+	if ok {
+		return lang, nil
+	}
+
+	user, err := s.GetUserInfo(ctx, telegramID)
+	if err != nil {
+		return "", err
+	}
+	if user != nil {
+		lang = user.PreferredLanguage
+	}
+	s.langCache.put(telegramID, lang, generation)
+	return lang, nil
+}
+
+// RecordStartSource 校验并记录深链接来源，非法参数直接拒绝，不落库
+func (s *UserServiceImpl) RecordStartSource(ctx context.Context, telegramID int64, payload string) error {
+	if err := ValidateStartPayload(payload); err != nil {
+		logger.Ctx(ctx).Warnf("Rejected start payload from user %d: %v", telegramID, err)
+		return err
+	}
+
+	written, err := s.userRepo.SetStartSource(ctx, telegramID, payload)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to record start source for %d: %v", telegramID, err)
+		return fmt.Errorf("记录来源失败")
+	}
+	if written {
+		logger.Ctx(ctx).Infof("User %d start source recorded: %s", telegramID, payload)
+	}
+	return nil
+}
+
+// ValidateStartPayload 校验深链接参数：Telegram 只允许 1-64 位字母、数字、下划线与中划线
+func ValidateStartPayload(payload string) error {
+	if payload == "" || len(payload) > MaxStartPayloadLength {
+		return fmt.Errorf("来源参数长度需为 1-%d 个字符", MaxStartPayloadLength)
+	}
+	if !startPayloadPattern.MatchString(payload) {
+		return fmt.Errorf("来源参数只能包含字母、数字、下划线和中划线")
+	}
+	return nil
+}
+
+// PreviewInactiveUsers 统计并预览不活跃用户
+func (s *UserServiceImpl) PreviewInactiveUsers(ctx context.Context, cutoff time.Time, limit int64) (int64, []*models.User, error) {
+	count, err := s.userRepo.CountInactiveBefore(ctx, cutoff)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to count inactive users: %v", err)
+		return 0, nil, fmt.Errorf("统计不活跃用户失败")
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+
+	users, err := s.userRepo.ListInactiveBefore(ctx, cutoff, limit)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to list inactive users: %v", err)
+		return 0, nil, fmt.Errorf("获取不活跃用户失败")
+	}
+	return count, users, nil
+}
+
+// PurgeInactiveUsers 存档并删除不活跃用户，结果写审计日志
+func (s *UserServiceImpl) PurgeInactiveUsers(ctx context.Context, cutoff time.Time, operatorID int64) (int64, error) {
+	defer s.adminCache.reset()
+	defer s.langCache.reset()
+	purged, err := s.userRepo.PurgeInactiveBefore(ctx, cutoff, operatorID)
+	if err != nil {
+		logger.Ctx(ctx).Errorf("Failed to purge inactive users: cutoff=%s operator=%d purged=%d err=%v", cutoff.Format(time.RFC3339), operatorID, purged, err)
+		if purged > 0 {
+			// 分批清理中途失败，已完成的批次不回滚
+			return purged, fmt.Errorf("清理不活跃用户中途失败，已清理 %d 个，可重新执行继续清理", purged)
+		}
+		return 0, fmt.Errorf("清理不活跃用户失败")
+	}
+
+	logger.Ctx(ctx).Infof("User purge audit: operator=%d cutoff=%s purged=%d", operatorID, cutoff.Format(time.RFC3339), purged)
+	return purged, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+// syncUserRepository 并发安全的内存用户仓库，统计按 ID 查询次数
+type syncUserRepository struct {
+	repository.UserRepository
+	mu       sync.Mutex
+	users    map[int64]*models.User
+	getCalls int
+}
+
+func (r *syncUserRepository) GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.getCalls++
+	user, ok := r.users[telegramID]
+	if !ok {
+		return nil, fmt.Errorf("user not found: %d", telegramID)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *syncUserRepository) GetUserInfo(ctx context.Context, telegramID int64) (*models.User, error) {
+	return r.GetByTelegramID(ctx, telegramID)
+}
+
+func (r *syncUserRepository) UpdatePreferredLanguage(ctx context.Context, telegramID int64, lang string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[telegramID].PreferredLanguage = lang
+	return nil
+}
+
+func (r *syncUserRepository) GrantRole(ctx context.Context, telegramID int64, role string, grantedBy int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[telegramID].Role = role
+	return nil
+}
+
+func (r *syncUserRepository) RevokeAdmin(ctx context.Context, telegramID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[telegramID].Role = models.RoleUser
+	r.users[telegramID].Permissions = nil
+	return nil
+}
+
+func (r *syncUserRepository) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getCalls
+}
+
+func newAdminCacheTestService() (*syncUserRepository, UserService) {
+	repo := &syncUserRepository{users: map[int64]*models.User{
+		1: {TelegramID: 1, Role: models.RoleOwner},
+		3: {TelegramID: 3, Role: models.RoleAdmin},
+	}}
+	return repo, NewUserService(repo, &stubGroupRepository{})
+}
+
+func TestCheckAdminPermissionCachesAndInvalidatesOnRevoke(t *testing.T) {
+	repo, svc := newAdminCacheTestService()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if ok, err := svc.CheckAdminPermission(ctx, 3, -100); err != nil || !ok {
+			t.Fatalf("expected admin, got %v (%v)", ok, err)
+		}
+	}
+	if calls := repo.calls(); calls != 1 {
+		t.Fatalf("expected repeated checks to hit cache, got %d repository calls", calls)
+	}
+
+	if err := svc.RevokeAdminPermission(ctx, 3, 1); err != nil {
+		t.Fatalf("unexpected revoke error: %v", err)
+	}
+	if ok, _ := svc.CheckAdminPermission(ctx, 3, -100); ok {
+		t.Fatal("expected revoke to invalidate cached permission")
+	}
+
+	if err := svc.GrantAdminPermission(ctx, 3, 1); err != nil {
+		t.Fatalf("unexpected grant error: %v", err)
+	}
+	if ok, _ := svc.CheckAdminPermission(ctx, 3, -100); !ok {
+		t.Fatal("expected grant to invalidate cached denial")
+	}
+}
+
+func TestGetPreferredLanguageCachesAndInvalidatesOnSet(t *testing.T) {
+	repo, svc := newAdminCacheTestService()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if lang, err := svc.GetPreferredLanguage(ctx, 3); err != nil || lang != "" {
+			t.Fatalf("expected no preference, got %q (%v)", lang, err)
+		}
+	}
+	if calls := repo.calls(); calls != 1 {
+		t.Fatalf("expected repeated lookups to hit cache, got %d repository calls", calls)
+	}
+
+	if err := svc.SetPreferredLanguage(ctx, 3, "en"); err != nil {
+		t.Fatalf("unexpected set error: %v", err)
+	}
+	if lang, _ := svc.GetPreferredLanguage(ctx, 3); lang != "en" {
+		t.Fatalf("expected set to invalidate cached preference, got %q", lang)
+	}
+}
+
+func TestCheckAdminPermissionGroupAdminInvalidation(t *testing.T) {
+	groups := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -100, Settings: models.GroupSettings{AdminIDs: []int64{5}}}}
+	svc := NewUserService(&syncUserRepository{users: map[int64]*models.User{}}, groups)
+	ctx := context.Background()
+
+	if ok, _ := svc.CheckAdminPermission(ctx, 5, -100); !ok {
+		t.Fatal("expected group admin to be allowed")
+	}
+	groups.storedGroup.Settings.AdminIDs = nil
+	svc.InvalidateGroupAdminCache(-100)
+	if ok, _ := svc.CheckAdminPermission(ctx, 5, -100); ok {
+		t.Fatal("expected removed group admin to be denied after invalidation")
+	}
+}
+
+func TestCheckAdminPermissionConcurrentRevoke(t *testing.T) {
+	_, svc := newAdminCacheTestService()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					svc.CheckAdminPermission(ctx, 3, -100)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := svc.RevokeAdminPermission(ctx, 3, 1); err != nil {
+			t.Fatalf("unexpected revoke error: %v", err)
+		}
+		if ok, _ := svc.CheckAdminPermission(ctx, 3, -100); ok {
+			close(stop)
+			wg.Wait()
+			t.Fatalf("round %d: revoked admin still allowed by cache", i)
+		}
+		if err := svc.GrantAdminPermission(ctx, 3, 1); err != nil {
+			t.Fatalf("unexpected grant error: %v", err)
+		}
+		if ok, _ := svc.CheckAdminPermission(ctx, 3, -100); !ok {
+			close(stop)
+			wg.Wait()
+			t.Fatalf("round %d: granted admin denied by cache", i)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
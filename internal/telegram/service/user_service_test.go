@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/repository"
+)
+
+func TestValidateStartPayload(t *testing.T) {
+	for _, payload := range []string{"ref_123", "invite-ABC", strings.Repeat("a", MaxStartPayloadLength)} {
+		if err := ValidateStartPayload(payload); err != nil {
+			t.Fatalf("expected %q to be valid, got %v", payload, err)
+		}
+	}
+	for _, payload := range []string{"", strings.Repeat("a", MaxStartPayloadLength+1), "ref 1", "$where", "ref.1", "邀请"} {
+		if err := ValidateStartPayload(payload); err == nil {
+			t.Fatalf("expected %q to be rejected", payload)
+		}
+	}
+}
+
+// memoryUserRepository 内存用户仓库，仅实现权限相关方法
+type memoryUserRepository struct {
+	repository.UserRepository
+	users map[int64]*models.User
+}
+
+func (r *memoryUserRepository) GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	user, ok := r.users[telegramID]
+	if !ok {
+		return nil, fmt.Errorf("user not found: %d", telegramID)
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) GrantRole(ctx context.Context, telegramID int64, role string, grantedBy int64) error {
+	r.users[telegramID].Role = role
+	r.users[telegramID].GrantedBy = grantedBy
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePermissions(ctx context.Context, telegramID int64, permissions []string) error {
+	r.users[telegramID].Permissions = permissions
+	return nil
+}
+
+func (r *memoryUserRepository) ListAdminsPage(ctx context.Context, roles []string, skip, limit int64) ([]*models.User, int64, error) {
+	var matched []*models.User
+	for id := int64(1); id <= int64(len(r.users)); id++ {
+		if user, ok := r.users[id]; ok && slices.Contains(roles, user.Role) {
+			matched = append(matched, user)
+		}
+	}
+	total := int64(len(matched))
+	if skip >= total {
+		return nil, total, nil
+	}
+	return matched[skip:min(skip+limit, total)], total, nil
+}
+
+func newPermissionTestService() (*memoryUserRepository, UserService) {
+	repo := &memoryUserRepository{users: map[int64]*models.User{
+		1: {TelegramID: 1, Role: models.RoleOwner},
+		2: {TelegramID: 2, Role: models.RoleSuperAdmin},
+		3: {TelegramID: 3, Role: models.RoleAdmin},
+		4: {TelegramID: 4, Role: models.RoleUser},
+	}}
+	return repo, NewUserService(repo, &stubGroupRepository{})
+}
+
+func TestGrantRoleRequiresManageAdmins(t *testing.T) {
+	repo, svc := newPermissionTestService()
+	ctx := context.Background()
+
+	if err := svc.GrantRole(ctx, 4, models.RoleAdmin, 2); err == nil {
+		t.Fatal("super admin must not grant admins")
+	}
+	if err := svc.GrantRole(ctx, 4, models.RoleSuperAdmin, 1); err != nil || repo.users[4].Role != models.RoleSuperAdmin {
+		t.Fatalf("owner should grant super admin, role=%s err=%v", repo.users[4].Role, err)
+	}
+	if err := svc.GrantRole(ctx, 4, models.RoleSuperAdmin, 1); err == nil {
+		t.Fatal("expected error when role is unchanged")
+	}
+	if err := svc.GrantRole(ctx, 1, models.RoleAdmin, 1); err == nil {
+		t.Fatal("owner role must not be changed")
+	}
+
+	if ok, err := svc.HasPermission(ctx, 4, models.PermBroadcast); err != nil || !ok {
+		t.Fatalf("super admin should have broadcast permission, ok=%v err=%v", ok, err)
+	}
+	if ok, _ := svc.CheckAdminPermission(ctx, 4, 0); !ok {
+		t.Fatal("super admin should pass admin check")
+	}
+}
+
+func TestUpdateUserPermissions(t *testing.T) {
+	repo, svc := newPermissionTestService()
+	ctx := context.Background()
+
+	user, err := svc.UpdateUserPermissions(ctx, 3, []string{models.PermFinance, models.PermAdmin}, nil, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 角色预设已包含的权限不额外记录
+	if want := []string{models.PermFinance}; !reflect.DeepEqual(repo.users[3].Permissions, want) || !reflect.DeepEqual(user.Permissions, want) {
+		t.Fatalf("unexpected extra permissions: %v", repo.users[3].Permissions)
+	}
+
+	if _, err := svc.UpdateUserPermissions(ctx, 3, nil, []string{models.PermFinance}, 1); err != nil || len(repo.users[3].Permissions) != 0 {
+		t.Fatalf("expected finance to be removed, got %v err=%v", repo.users[3].Permissions, err)
+	}
+
+	if _, err := svc.UpdateUserPermissions(ctx, 4, []string{models.PermFinance}, nil, 1); err == nil {
+		t.Fatal("regular users must be granted a role first")
+	}
+	if _, err := svc.UpdateUserPermissions(ctx, 3, []string{models.PermFinance}, nil, 2); err == nil {
+		t.Fatal("super admin must not change permissions")
+	}
+
+	// 被授予「管理员授权」的管理员不能再授出该权限，也不能调整同级
+	if _, err := svc.UpdateUserPermissions(ctx, 3, []string{models.PermManageAdmins}, nil, 1); err != nil {
+		t.Fatalf("owner should grant manage_admins: %v", err)
+	}
+	if _, err := svc.UpdateUserPermissions(ctx, 2, []string{models.PermManageAdmins}, nil, 3); err == nil {
+		t.Fatal("only owner may grant manage_admins")
+	}
+	if err := svc.RevokeAdminPermission(ctx, 3, 3); err == nil {
+		t.Fatal("manage_admins holders may only be revoked by owner")
+	}
+	if _, err := svc.UpdateUserPermissions(ctx, 2, []string{models.PermFinance}, nil, 3); err != nil {
+		t.Fatalf("manage_admins holder should adjust other admins: %v", err)
+	}
+}
+
+func TestCheckAdminPermissionGroupAdmin(t *testing.T) {
+	users := &memoryUserRepository{users: map[int64]*models.User{
+		3: {TelegramID: 3, Role: models.RoleAdmin},
+		4: {TelegramID: 4, Role: models.RoleUser},
+	}}
+	groups := &stubGroupRepository{storedGroup: &models.Group{TelegramID: -100, Settings: models.GroupSettings{AdminIDs: []int64{4, 5}}}}
+	svc := NewUserService(users, groups)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		userID int64
+		chatID int64
+		want   bool
+	}{
+		{"global admin in any chat", 3, 0, true},
+		{"group admin in own group", 4, -100, true},
+		{"group admin outside groups", 4, 4, false},
+		{"group admin without user record", 5, -100, true},
+		{"regular user", 6, -100, false},
+	}
+	for _, tt := range tests {
+		if got, _ := svc.CheckAdminPermission(ctx, tt.userID, tt.chatID); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListAdminsPage(t *testing.T) {
+	_, svc := newPermissionTestService()
+	ctx := context.Background()
+
+	page, err := svc.ListAdminsPage(ctx, "", 2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Total != 3 || page.Page != 2 || page.TotalPages != 2 || len(page.Admins) != 1 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+
+	// 页码超出范围时退回最后一页
+	page, err = svc.ListAdminsPage(ctx, models.RoleAdmin, 5, 2)
+	if err != nil || page.Page != 1 || len(page.Admins) != 1 || page.Admins[0].TelegramID != 3 {
+		t.Fatalf("unexpected clamped page: %+v err=%v", page, err)
+	}
+
+	if _, err := svc.ListAdminsPage(ctx, models.RoleUser, 1, 2); err == nil {
+		t.Fatal("expected error for non-admin role filter")
+	}
+}
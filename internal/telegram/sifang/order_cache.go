@@ -0,0 +1,138 @@
+package sifang
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+)
+
+// OrderCacheEntry 订单查询缓存项
+type OrderCacheEntry struct {
+	Detail    *paymentservice.OrderDetail // 订单详情，NotFound 为 true 时为空
+	NotFound  bool                        // 是否为「订单不存在」结果
+	FetchedAt time.Time                   // 实际查询上游的时间
+	expiresAt time.Time
+}
+
+// OrderCache 按商户号 + 订单号缓存查单结果，并发安全，带容量上限与过期清理
+type OrderCache struct {
+	mu          sync.Mutex
+	entries     map[string]*OrderCacheEntry
+	capacity    int
+	ttl         time.Duration
+	notFoundTTL time.Duration
+	now         func() time.Time
+}
+
+// NewOrderCache 创建订单查询缓存，ttl 用于成功结果，notFoundTTL 用于订单不存在结果
+func NewOrderCache(capacity int, ttl, notFoundTTL time.Duration) *OrderCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &OrderCache{
+		entries:     make(map[string]*OrderCacheEntry),
+		capacity:    capacity,
+		ttl:         ttl,
+		notFoundTTL: notFoundTTL,
+		now:         time.Now,
+	}
+}
+
+// Get 读取未过期的缓存项，过期项会被顺带删除
+func (c *OrderCache) Get(merchantID int64, orderNo string) (OrderCacheEntry, bool) {
+	if c == nil {
+		return OrderCacheEntry{}, false
+	}
+
+	key := orderCacheKey(merchantID, orderNo)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return OrderCacheEntry{}, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return OrderCacheEntry{}, false
+	}
+	return *entry, true
+}
+
+// Set 缓存成功查询到的订单详情
+func (c *OrderCache) Set(merchantID int64, orderNo string, detail *paymentservice.OrderDetail) {
+	if c == nil || detail == nil {
+		return
+	}
+	c.put(merchantID, orderNo, &OrderCacheEntry{Detail: detail}, c.ttl)
+}
+
+// SetNotFound 短暂缓存订单不存在结果，避免连续无效查询
+func (c *OrderCache) SetNotFound(merchantID int64, orderNo string) {
+	if c == nil {
+		return
+	}
+	c.put(merchantID, orderNo, &OrderCacheEntry{NotFound: true}, c.notFoundTTL)
+}
+
+// Len 返回当前缓存项数量（包含尚未清理的过期项）
+func (c *OrderCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *OrderCache) put(merchantID int64, orderNo string, entry *OrderCacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	key := orderCacheKey(merchantID, orderNo)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry.FetchedAt = now
+	entry.expiresAt = now.Add(ttl)
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.capacity {
+		c.evictLocked(now)
+	}
+	c.entries[key] = entry
+}
+
+// evictLocked 先清理过期项，仍然满额时淘汰最早过期的一项
+func (c *OrderCache) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) < c.capacity {
+		return
+	}
+
+	var (
+		oldestKey string
+		oldestAt  time.Time
+	)
+	for key, entry := range c.entries {
+		if oldestKey == "" || entry.expiresAt.Before(oldestAt) {
+			oldestKey = key
+			oldestAt = entry.expiresAt
+		}
+	}
+	delete(c.entries, oldestKey)
+}
+
+func orderCacheKey(merchantID int64, orderNo string) string {
+	return strconv.FormatInt(merchantID, 10) + ":" + strings.ToUpper(strings.TrimSpace(orderNo))
+}
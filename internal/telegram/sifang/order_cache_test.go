@@ -0,0 +1,68 @@
+package sifang
+
+import (
+	"testing"
+	"time"
+
+	paymentservice "go_bot/internal/payment/service"
+)
+
+func TestOrderCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)
+	cache := NewOrderCache(10, time.Minute, 10*time.Second)
+	cache.now = func() time.Time { return now }
+
+	detail := &paymentservice.OrderDetail{Order: &paymentservice.Order{Status: "paid"}}
+	cache.Set(1001, "abc1234567", detail)
+	cache.SetNotFound(1001, "MISSING0001")
+
+	entry, ok := cache.Get(1001, "ABC1234567")
+	if !ok || entry.Detail != detail || entry.NotFound {
+		t.Fatalf("expected case-insensitive hit with detail, got ok=%v entry=%+v", ok, entry)
+	}
+	if !entry.FetchedAt.Equal(now) {
+		t.Fatalf("expected fetched at %s, got %s", now, entry.FetchedAt)
+	}
+	if _, ok := cache.Get(1002, "ABC1234567"); ok {
+		t.Fatalf("expected miss for a different merchant")
+	}
+
+	now = now.Add(15 * time.Second)
+	if _, ok := cache.Get(1001, "MISSING0001"); ok {
+		t.Fatalf("expected not-found entry to expire after its shorter ttl")
+	}
+	if _, ok := cache.Get(1001, "ABC1234567"); !ok {
+		t.Fatalf("expected detail entry to still be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get(1001, "ABC1234567"); ok {
+		t.Fatalf("expected detail entry to expire")
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("expected expired entries to be removed, got %d", cache.Len())
+	}
+}
+
+func TestOrderCacheCapacity(t *testing.T) {
+	now := time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)
+	cache := NewOrderCache(2, time.Minute, 10*time.Second)
+	cache.now = func() time.Time { return now }
+
+	detail := &paymentservice.OrderDetail{Order: &paymentservice.Order{}}
+	cache.Set(1, "ORDER000001", detail)
+	now = now.Add(time.Second)
+	cache.Set(1, "ORDER000002", detail)
+	now = now.Add(time.Second)
+	cache.Set(1, "ORDER000003", detail)
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected capacity to be enforced, got %d", cache.Len())
+	}
+	if _, ok := cache.Get(1, "ORDER000001"); ok {
+		t.Fatalf("expected oldest entry to be evicted")
+	}
+	if _, ok := cache.Get(1, "ORDER000003"); !ok {
+		t.Fatalf("expected newest entry to be cached")
+	}
+}
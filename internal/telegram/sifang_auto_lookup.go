@@ -2,13 +2,16 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"strings"
+	"sync"
 	"time"
 
 	"go_bot/internal/logger"
 	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/service"
 	sifanglookup "go_bot/internal/telegram/sifang"
 
@@ -17,10 +20,14 @@ import (
 
 const (
 	maxAutoLookupOrders    = 5
+	autoLookupConcurrency  = 3
 	orderLookupTimeout     = 10 * time.Second
 	orderLookupSendTimeout = 5 * time.Second
 	notifyFailureBodyLimit = 200
 	notifyFailureURLLimit  = 120
+	orderCacheCapacity     = 512
+	orderCacheTTL          = time.Minute
+	orderNotFoundCacheTTL  = 15 * time.Second
 )
 
 func (b *Bot) tryTriggerSifangAutoLookup(ctx context.Context, msg *botModels.Message, fileNames ...string) {
@@ -28,7 +35,7 @@ func (b *Bot) tryTriggerSifangAutoLookup(ctx context.Context, msg *botModels.Mes
 		return
 	}
 
-	if msg.Chat.Type != "group" && msg.Chat.Type != "supergroup" {
+	if !isGroupChatType(msg.Chat.Type) {
 		return
 	}
 
@@ -41,7 +48,7 @@ func (b *Bot) tryTriggerSifangAutoLookup(ctx context.Context, msg *botModels.Mes
 
 	group, err := b.groupService.GetOrCreateGroup(ctx, chatInfo)
 	if err != nil {
-		logger.L().Warnf("Failed to load group for sifang auto lookup: chat_id=%d err=%v", msg.Chat.ID, err)
+		logger.Ctx(ctx).Warnf("Failed to load group for sifang auto lookup: chat_id=%d err=%v", msg.Chat.ID, err)
 		return
 	}
 
@@ -74,55 +81,134 @@ func (b *Bot) tryTriggerSifangAutoLookup(ctx context.Context, msg *botModels.Mes
 		return
 	}
 
+	skipped := 0
 	if len(orderNos) > maxAutoLookupOrders {
+		skipped = len(orderNos) - maxAutoLookupOrders
 		orderNos = append([]string{}, orderNos[:maxAutoLookupOrders]...)
 	} else {
 		orderNos = append([]string{}, orderNos...)
 	}
 
-	go b.performSifangOrderLookup(msg.Chat.ID, msg.ID, merchantID, orderNos)
+	go b.performSifangOrderLookup(msg.Chat.ID, msg.ID, merchantID, orderNos, skipped, models.GroupLocation(group.Settings))
 	go b.startOrderCascadeWorkflow(group, msg, orderNos)
 }
 
-func (b *Bot) performSifangOrderLookup(chatID int64, messageID int, merchantID int64, orderNos []string) {
+func (b *Bot) performSifangOrderLookup(chatID int64, messageID int, merchantID int64, orderNos []string, skipped int, loc *time.Location) {
 	if b.paymentService == nil {
 		return
 	}
 
-	var results []string
-	for _, orderNo := range orderNos {
-		lookupCtx, cancel := context.WithTimeout(context.Background(), orderLookupTimeout)
-		detail, err := b.paymentService.GetOrderDetail(lookupCtx, merchantID, orderNo, paymentservice.OrderNumberTypeAuto)
-		cancel()
+	lookup := func(ctx context.Context, orderNo string) (sifanglookup.OrderCacheEntry, bool, error) {
+		return b.lookupOrderDetailCached(ctx, merchantID, orderNo)
+	}
 
+	results := lookupOrderResults(context.Background(), orderNos, autoLookupConcurrency, loc, lookup, func(orderNo string, err error) {
 		if err != nil {
 			logger.L().Warnf("Sifang auto lookup failed: chat_id=%d merchant_id=%d order_no=%s err=%v", chatID, merchantID, orderNo, err)
-			results = append(results, formatLookupFailure(orderNo))
-			continue
-		}
-		if detail == nil || detail.Order == nil {
-			logger.L().Warnf("Sifang auto lookup returned empty detail: chat_id=%d merchant_id=%d order_no=%s", chatID, merchantID, orderNo)
-			results = append(results, formatLookupFailure(orderNo))
-			continue
+			return
 		}
+		logger.L().Warnf("Sifang auto lookup returned empty detail: chat_id=%d merchant_id=%d order_no=%s", chatID, merchantID, orderNo)
+	})
+
+	text := buildAutoLookupMessage(results, skipped)
+	if text == "" {
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(context.Background(), orderLookupSendTimeout)
+	defer cancel()
 
-		results = append(results, formatLookupSuccess(orderNo, detail))
+	if _, err := b.sendMessageWithMarkupAndMessage(sendCtx, chatID, text, nil); err != nil {
+		logger.L().Errorf("Failed to send sifang auto lookup result: chat_id=%d message_id=%d err=%v", chatID, messageID, err)
+	}
+}
+
+// lookupOrderDetailCached 优先读取查单缓存，未命中时请求上游并回填；返回的 bool 表示是否命中缓存
+func (b *Bot) lookupOrderDetailCached(ctx context.Context, merchantID int64, orderNo string) (sifanglookup.OrderCacheEntry, bool, error) {
+	if entry, ok := b.orderCache.Get(merchantID, orderNo); ok {
+		return entry, true, nil
+	}
+
+	detail, err := b.paymentService.GetOrderDetail(ctx, merchantID, orderNo, paymentservice.OrderNumberTypeAuto)
+	if err != nil {
+		if errors.Is(err, paymentservice.ErrOrderNotFound) {
+			b.orderCache.SetNotFound(merchantID, orderNo)
+		}
+		return sifanglookup.OrderCacheEntry{}, false, err
+	}
+	if detail == nil || detail.Order == nil {
+		b.orderCache.SetNotFound(merchantID, orderNo)
+		return sifanglookup.OrderCacheEntry{NotFound: true, FetchedAt: time.Now()}, false, nil
 	}
 
+	b.orderCache.Set(merchantID, orderNo, detail)
+	return sifanglookup.OrderCacheEntry{Detail: detail, FetchedAt: time.Now()}, false, nil
+}
+
+// lookupOrderResults 并发查询订单详情，结果按订单号原始顺序返回，命中缓存的结果标注来源与查询时间
+func lookupOrderResults(
+	ctx context.Context,
+	orderNos []string,
+	concurrency int,
+	loc *time.Location,
+	lookup func(ctx context.Context, orderNo string) (sifanglookup.OrderCacheEntry, bool, error),
+	onFailure func(orderNo string, err error),
+) []string {
+	results := make([]string, len(orderNos))
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if loc == nil {
+		loc = models.DefaultLocation()
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, orderNo := range orderNos {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(idx int, orderNo string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			lookupCtx, cancel := context.WithTimeout(ctx, orderLookupTimeout)
+			defer cancel()
+
+			entry, cached, err := lookup(lookupCtx, orderNo)
+			var result string
+			if err != nil || entry.NotFound || entry.Detail == nil || entry.Detail.Order == nil {
+				if onFailure != nil && !cached {
+					onFailure(orderNo, err)
+				}
+				result = formatLookupFailure(orderNo)
+			} else {
+				result = formatLookupSuccess(orderNo, entry.Detail)
+			}
+
+			if cached && err == nil {
+				result += fmt.Sprintf("\n来源：缓存（查询于 %s）", entry.FetchedAt.In(loc).Format("15:04:05"))
+			}
+			results[idx] = result
+		}(i, orderNo)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// buildAutoLookupMessage 将多笔查单结果合并为一条回复，无结果时返回空字符串
+func buildAutoLookupMessage(results []string, skipped int) string {
 	if len(results) == 0 {
-		return
+		return ""
 	}
 
 	builder := &strings.Builder{}
 	builder.WriteString("🔎 <b>四方订单自动查单</b>\n")
 	builder.WriteString(strings.Join(results, "\n\n"))
-
-	sendCtx, cancel := context.WithTimeout(context.Background(), orderLookupSendTimeout)
-	defer cancel()
-
-	if _, err := b.sendMessageWithMarkupAndMessage(sendCtx, chatID, builder.String(), nil); err != nil {
-		logger.L().Errorf("Failed to send sifang auto lookup result: chat_id=%d message_id=%d err=%v", chatID, messageID, err)
+	if skipped > 0 {
+		builder.WriteString(fmt.Sprintf("\n\n⚠️ 单条消息最多查询 %d 个订单号，已忽略其余 %d 个", len(results), skipped))
 	}
+	return builder.String()
 }
 
 func formatLookupFailure(orderNo string) string {
@@ -1,10 +1,16 @@
 package telegram
 
 import (
+	"context"
+	"errors"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	paymentservice "go_bot/internal/payment/service"
+	"go_bot/internal/telegram/models"
+	sifanglookup "go_bot/internal/telegram/sifang"
 )
 
 func TestFormatLookupSuccess_WithNotifyFailure(t *testing.T) {
@@ -65,3 +71,79 @@ func TestFormatLookupSuccess_WithoutNotifyFailure(t *testing.T) {
 		t.Fatalf("did not expect failure section in result: %s", result)
 	}
 }
+
+func TestLookupOrderResults_PreservesOrderAndBoundsConcurrency(t *testing.T) {
+	orderNos := []string{"ORDER0000001", "ORDER0000002", "ORDER0000003", "ORDER0000004"}
+
+	var inflight, peak int32
+	lookup := func(ctx context.Context, orderNo string) (sifanglookup.OrderCacheEntry, bool, error) {
+		current := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch orderNo {
+		case "ORDER0000002":
+			return sifanglookup.OrderCacheEntry{}, false, errors.New("not found")
+		case "ORDER0000003":
+			return sifanglookup.OrderCacheEntry{
+				Detail:    &paymentservice.OrderDetail{Order: &paymentservice.Order{StatusText: "已支付", Amount: "100"}},
+				FetchedAt: time.Date(2024, 3, 18, 4, 5, 6, 0, time.UTC),
+			}, true, nil
+		}
+		return sifanglookup.OrderCacheEntry{Detail: &paymentservice.OrderDetail{Order: &paymentservice.Order{StatusText: "已支付", Amount: "100"}}}, false, nil
+	}
+
+	var failures int32
+	results := lookupOrderResults(context.Background(), orderNos, 2, models.DefaultLocation(), lookup, func(string, error) {
+		atomic.AddInt32(&failures, 1)
+	})
+
+	if len(results) != len(orderNos) {
+		t.Fatalf("expected %d results, got %d", len(orderNos), len(results))
+	}
+	for i, orderNo := range orderNos {
+		if !strings.Contains(results[i], orderNo) {
+			t.Fatalf("result %d should belong to %s: %s", i, orderNo, results[i])
+		}
+	}
+	if !strings.Contains(results[1], "未找到订单") {
+		t.Fatalf("expected failure result for second order: %s", results[1])
+	}
+	if !strings.Contains(results[2], "来源：缓存（查询于 12:05:06）") {
+		t.Fatalf("expected cache annotation in local time: %s", results[2])
+	}
+	if strings.Contains(results[0], "来源：缓存") {
+		t.Fatalf("did not expect cache annotation for live result: %s", results[0])
+	}
+	if failures != 1 {
+		t.Fatalf("expected 1 failure callback, got %d", failures)
+	}
+	if peak > 2 {
+		t.Fatalf("expected concurrency <= 2, got %d", peak)
+	}
+}
+
+func TestBuildAutoLookupMessage(t *testing.T) {
+	if got := buildAutoLookupMessage(nil, 0); got != "" {
+		t.Fatalf("expected empty message for no results, got %q", got)
+	}
+
+	message := buildAutoLookupMessage([]string{"A", "B"}, 0)
+	if !strings.HasPrefix(message, "🔎 <b>四方订单自动查单</b>\nA\n\nB") {
+		t.Fatalf("unexpected merged message: %q", message)
+	}
+	if strings.Contains(message, "已忽略") {
+		t.Fatalf("did not expect truncation note: %q", message)
+	}
+
+	truncated := buildAutoLookupMessage([]string{"A", "B"}, 3)
+	if !strings.Contains(truncated, "最多查询 2 个订单号，已忽略其余 3 个") {
+		t.Fatalf("expected truncation note: %q", truncated)
+	}
+}
@@ -3,14 +3,50 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"go_bot/internal/telegram/models"
+	"go_bot/internal/version"
 )
 
 const defaultNetworkProbeURL = "https://api.telegram.org"
 
+// buildUptimeMessage 构建 /uptime 命令的响应文本：运行时长、启动时间、版本信息与后台任务状态
+func (b *Bot) buildUptimeMessage(ctx context.Context, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString("⏱ 实例运行状态\n\n")
+
+	if b.startTime.IsZero() {
+		sb.WriteString("运行时长：未知\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("运行时长：%s\n", formatDuration(now.Sub(b.startTime))))
+		sb.WriteString(fmt.Sprintf("启动时间：%s\n", b.startTime.In(models.DefaultLocation()).Format("2006-01-02 15:04:05")))
+	}
+
+	info := version.Get()
+	sb.WriteString(fmt.Sprintf("版本：%s\n", html.EscapeString(info.Version)))
+	commit := info.Commit
+	if info.Modified {
+		commit += "（含未提交改动）"
+	}
+	sb.WriteString(fmt.Sprintf("Commit：<code>%s</code>\n", html.EscapeString(commit)))
+	if info.BuildTime != "" {
+		sb.WriteString(fmt.Sprintf("构建时间：%s\n", html.EscapeString(info.BuildTime)))
+	}
+	sb.WriteString(fmt.Sprintf("Go：%s\n", info.GoVersion))
+
+	sb.WriteString("\n后台任务：\n")
+	for _, check := range b.backgroundTaskChecks() {
+		status, detail := check.run(ctx)
+		sb.WriteString(fmt.Sprintf("%s %s：%s\n", healthStatusIcon(status), check.name, detail))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
 // buildPingMessage 构建 /ping 命令的响应文本
 func (b *Bot) buildPingMessage(ctx context.Context) string {
 	lines := []string{"🏓 Pong!"}
@@ -22,7 +58,7 @@ func (b *Bot) buildPingMessage(ctx context.Context) string {
 
 	if b.workerPool != nil {
 		stats := b.workerPool.Stats()
-		lines = append(lines, fmt.Sprintf("🛠 工作池: %d 个协程，队列 %d/%d", stats.Workers, stats.QueueLength, stats.QueueCapacity))
+		lines = append(lines, fmt.Sprintf("🛠 工作池: %s，队列 %d/%d", stats.workersLabel(), stats.QueueLength, stats.QueueCapacity))
 	}
 
 	if b.db != nil {
@@ -30,7 +66,7 @@ func (b *Bot) buildPingMessage(ctx context.Context) string {
 		defer cancel()
 
 		if err := b.db.Client().Ping(dbCtx, nil); err != nil {
-			lines = append(lines, fmt.Sprintf("🗄 数据库: ⚠️ %v", err))
+			lines = append(lines, safeHTMLf("🗄 数据库: ⚠️ %v", err))
 		} else {
 			lines = append(lines, "🗄 数据库: ✅ 正常")
 		}
@@ -41,7 +77,7 @@ func (b *Bot) buildPingMessage(ctx context.Context) string {
 
 	latency, statusCode, err := probeNetwork(networkCtx, defaultNetworkProbeURL)
 	if err != nil {
-		lines = append(lines, fmt.Sprintf("🌐 网络: ⚠️ 测速失败 (%v)", err))
+		lines = append(lines, safeHTMLf("🌐 网络: ⚠️ 测速失败 (%v)", err))
 	} else {
 		lines = append(lines, fmt.Sprintf("🌐 网络延迟: %s（%s，HTTP %d）", latency.Round(time.Millisecond), defaultNetworkProbeURL, statusCode))
 	}
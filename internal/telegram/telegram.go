@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go_bot/internal/config"
@@ -19,6 +20,7 @@ import (
 	"go_bot/internal/telegram/models"
 	"go_bot/internal/telegram/repository"
 	"go_bot/internal/telegram/service"
+	sifanglookup "go_bot/internal/telegram/sifang"
 
 	"github.com/go-telegram/bot"
 	botModels "github.com/go-telegram/bot/models"
@@ -27,12 +29,44 @@ import (
 
 // Config Telegram Bot 配置
 type Config struct {
-	Token                string  // Bot Token
-	OwnerIDs             []int64 // Owner 用户 IDs
-	Debug                bool    // 是否开启调试模式
-	MessageRetentionDays int     // 消息保留天数（用于 TTL 索引）
-	ChannelID            int64   // 源频道 ID（用于转发功能）
-	DailyBillPushEnabled bool    // 是否启用每日账单自动推送
+	Token                     string  // Bot Token
+	OwnerIDs                  []int64 // Owner 用户 IDs
+	Debug                     bool    // 是否开启调试模式
+	MessageRetentionDays      int     // 全局默认消息保留天数（未单独配置的群，及旧消息迁移回填）
+	ChannelID                 int64   // 源频道 ID（用于转发功能）
+	ChannelForwardMode        string  // 频道消息投递方式：forward / copy
+	ChannelForwardTarget      string  // 频道消息默认投递目标（tier=... tag=...）
+	DailyBillPushEnabled      bool    // 是否启用每日账单自动推送
+	PanicAlertEnabled         bool    // handler panic 时是否私聊告警 owner
+	BalanceAlertChatID        int64   // 上游低余额告警群 ID，0 表示发到原群
+	BalanceAlertToOwners      bool    // 上游低余额告警私聊 owner（优先于告警群）
+	SettlementFallbackToTotal bool    // 日结找不到目标日期账单时回退到汇总总额
+	HealthPort                int     // 健康探针 HTTP 端口，0 表示不启动
+
+	// 媒体消息去重（按 file_unique_id）
+	MediaDedupMode   string        // off / mark / skip
+	MediaDedupWindow time.Duration // 回溯窗口
+
+	// 消息批量写入（大小与时间双触发）
+	MessageBatchSize          int           // 达到该条数立即落库，<= 1 表示逐条写入
+	MessageBatchFlushInterval time.Duration // 缓冲最长停留时间
+
+	MessageRetentionTierDays map[string]int // 按群等级覆盖的消息保留天数（basic/merchant/upstream -> 天数）
+
+	// 命令限流（按用户 + 命令的滑动窗口）
+	RateLimitWindow    time.Duration  // 窗口长度
+	RateLimitMax       int            // 默认阈值，0 表示关闭
+	RateLimitOverrides map[string]int // 指定命令或功能名的阈值
+
+	PendingStateSweepInterval time.Duration // 内存待处理状态（订单联动、四方待确认下发）的过期清理间隔
+
+	// 工作池 worker 数上下限，按队列水位在两者之间伸缩；0 表示默认（10-50）
+	WorkerPoolMinWorkers int
+	WorkerPoolMaxWorkers int
+	// 关闭时等待已入队任务处理完的最长时间，0 表示默认 5 秒
+	WorkerPoolDrainTimeout time.Duration
+
+	MediaArchive config.MediaArchiveConfig // 媒体归档（Backend 为空时关闭）
 }
 
 // Bot Telegram Bot 服务
@@ -40,9 +74,20 @@ type Bot struct {
 	bot                  *bot.Bot
 	db                   *mongo.Database
 	ownerIDs             []int64
-	messageRetentionDays int // 消息保留天数
+	messageRetention     models.MessageRetentionPolicy // 消息保留期策略（全局默认 + 按群等级覆盖）
+	balanceAlertChatID   int64                         // 上游低余额告警群 ID，0 表示发到原群
+	balanceAlertToOwners bool                          // 上游低余额告警私聊 owner
 	workerPool           *WorkerPool
+	workerDrainTimeout   time.Duration            // 关闭时排空工作池的最长等待时间
+	commandLimiter       *commandRateLimiter      // 按用户 + 命令限流
+	inlineLimiter        *commandRateLimiter      // inline U 价查询按用户限流
+	inlineQuotes         *inlineQuoteCache        // inline U 价查询结果短期缓存
+	permissionDenials    *permissionDenialTracker // 越权尝试计数
+	metrics              *botMetrics              // Prometheus 指标（/metrics）
+	panicAlerts          *panicAlertThrottle      // handler panic 告警节流，nil 表示不告警
 	startTime            time.Time
+	running              atomic.Bool   // 是否已开始接收更新，/readyz 据此判断
+	healthServer         *healthServer // 健康探针 HTTP 服务，nil 表示未启用
 	tempMessageCtx       context.Context
 	tempMessageCancel    context.CancelFunc
 
@@ -55,14 +100,28 @@ type Bot struct {
 	accountingService service.AccountingService // 收支记账服务
 	paymentService    paymentservice.Service
 	balanceService    service.UpstreamBalanceService
+	sendMoneyQuota    service.SendMoneyQuotaService   // 四方下发每日限额
+	memberEvents      service.MemberEventService      // 成员入群/退群统计
+	scheduledMessages service.ScheduledMessageService // 群组定时消息
+	merchantHistory   service.MerchantHistoryService  // 商户号变更记录
+	commandUsage      service.CommandUsageService     // 命令使用统计
 
 	// 功能管理器
-	featureManager *features.Manager
-	sifangFeature  *sifangfeature.Feature
+	featureManager  *features.Manager
+	sifangFeature   *sifangfeature.Feature
+	upstreamFeature *upstream.Feature
+	orderCache      *sifanglookup.OrderCache // 四方查单结果短期缓存
 
 	dailySummaryScheduler *dailySummaryScheduler
 	upstreamScheduler     *upstreamSettlementScheduler
 	balanceMonitor        *upstreamBalanceMonitor
+	sendMoneySweeper      *sendMoneyExpirationSweeper
+	channelRateWarmer     *channelRateWarmer
+	groupArchivePurger    *groupArchivePurger
+	pendingStateSweeper   *pendingStateSweeper
+
+	scheduledMessageScheduler *scheduledMessageScheduler
+	mediaArchiver             *mediaArchiver // 媒体归档工作池，nil 表示未启用
 
 	// Repository 层（仅用于初始化）
 	userRepo            repository.UserRepository
@@ -71,9 +130,30 @@ type Bot struct {
 	forwardRecordRepo   repository.ForwardRecordRepository
 	accountingRepo      repository.AccountingRepository
 	upstreamBalanceRepo repository.UpstreamBalanceRepository
-
-	orderCascadeStates map[string]*orderCascadeState
+	sendMoneyRepo       repository.SendMoneyRepository
+	orderCascadeRepo    repository.OrderCascadeRepository
+
+	sendMoneyExpirationRepo repository.SendMoneyExpirationRepository
+	dailyBillPushRepo       repository.DailyBillPushRepository
+	deletedMessageRepo      repository.DeletedMessageRepository
+	memberEventRepo         repository.MemberEventRepository
+	scheduledMessageRepo    repository.ScheduledMessageRepository
+	merchantHistoryRepo     repository.MerchantHistoryRepository
+	commandUsageRepo        repository.CommandUsageRepository
+	joinVerifyRepo          repository.JoinVerificationRepository
+
+	orderCascadeStates map[string]*models.OrderCascadeState
 	orderCascadeMu     sync.RWMutex
+
+	sentMessages *sentMessageLog // Bot 发出的消息（按群，带 TTL），供撤回等判断
+
+	pendingBroadcasts            pendingStore[*pendingBroadcast]            // 待确认的群发广播
+	pendingLeaves                pendingStore[*pendingLeave]                // 待确认的离群请求
+	pendingUserPurges            pendingStore[*pendingUserPurge]            // 待 Owner 确认的不活跃用户清理
+	pendingAccountingDateDeletes pendingStore[*pendingAccountingDateDelete] // 待确认的按日期删除记账
+
+	mediaAlertSentAt map[string]time.Time // 媒体告警最近一次群内提醒（chat_id:user_id -> 时间）
+	mediaAlertMu     sync.Mutex
 }
 
 // New 创建 Telegram Bot 实例
@@ -90,25 +170,56 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 	forwardRecordRepo := repository.NewForwardRecordRepository(db)
 	accountingRepo := repository.NewMongoAccountingRepository(db)
 	upstreamBalanceRepo := repository.NewMongoUpstreamBalanceRepository(db)
+	sendMoneyRepo := repository.NewMongoSendMoneyRepository(db)
+	orderCascadeRepo := repository.NewMongoOrderCascadeRepository(db)
+	sendMoneyExpirationRepo := repository.NewMongoSendMoneyExpirationRepository(db)
+	dailyBillPushRepo := repository.NewMongoDailyBillPushRepository(db)
+	deletedMessageRepo := repository.NewMongoDeletedMessageRepository(db)
+	memberEventRepo := repository.NewMongoMemberEventRepository(db)
+	scheduledMessageRepo := repository.NewMongoScheduledMessageRepository(db)
+	merchantHistoryRepo := repository.NewMongoMerchantHistoryRepository(db)
+	commandUsageRepo := repository.NewMongoCommandUsageRepository(db)
+	joinVerifyRepo := repository.NewMongoJoinVerificationRepository(db)
+
+	retentionPolicy := newMessageRetentionPolicy(cfg.MessageRetentionDays, cfg.MessageRetentionTierDays)
 
 	// 创建 services
-	userService := service.NewUserService(userRepo)
-	groupService := service.NewGroupService(groupRepo)
-	messageService := service.NewMessageService(messageRepo, groupRepo)
+	userService := service.NewUserService(userRepo, groupRepo)
+	merchantHistoryService := service.NewMerchantHistoryService(merchantHistoryRepo)
+	groupService := service.NewGroupService(groupRepo,
+		service.WithGroupAdminsChanged(userService.InvalidateGroupAdminCache),
+		service.WithMerchantHistory(merchantHistoryService))
+	messageService := service.NewMessageService(messageRepo, groupRepo, deletedMessageRepo, service.MediaDedupConfig{
+		Mode:   service.MediaDedupMode(cfg.MediaDedupMode),
+		Window: cfg.MediaDedupWindow,
+	}, service.MessageBatchConfig{
+		Size:          cfg.MessageBatchSize,
+		FlushInterval: cfg.MessageBatchFlushInterval,
+	}, retentionPolicy)
 	configMenuService := service.NewConfigMenuService(groupService)
-	accountingService := service.NewAccountingService(accountingRepo, groupRepo)
-	balanceService := service.NewUpstreamBalanceService(upstreamBalanceRepo, groupRepo, paymentSvc)
+	accountingService := service.NewAccountingService(accountingRepo, groupRepo, crypto.ReferenceRate)
+	balanceService := service.NewUpstreamBalanceService(upstreamBalanceRepo, groupRepo, paymentSvc, cfg.SettlementFallbackToTotal)
+	sendMoneyQuota := service.NewSendMoneyQuotaService(sendMoneyRepo)
+	memberEventService := service.NewMemberEventService(memberEventRepo)
+	scheduledMessageService := service.NewScheduledMessageService(scheduledMessageRepo)
+	commandUsageService := service.NewCommandUsageService(commandUsageRepo)
 
 	// 创建转发服务（如果配置了频道 ID）
 	var forwardService service.ForwardService
 	if cfg.ChannelID != 0 {
+		defaultTarget, err := forward.ParseTarget(cfg.ChannelForwardTarget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHANNEL_FORWARD_TARGET: %w", err)
+		}
 		forwardService = forward.NewService(
 			cfg.ChannelID,
+			forward.Mode(cfg.ChannelForwardMode),
+			defaultTarget,
 			groupService,
 			userService,
 			forwardRecordRepo,
 		)
-		logger.L().Infof("Forward service initialized: channel_id=%d", cfg.ChannelID)
+		logger.L().Infof("Forward service initialized: channel_id=%d, mode=%s, default_target=%s", cfg.ChannelID, cfg.ChannelForwardMode, defaultTarget)
 	} else {
 		logger.L().Warn("Forward service not initialized: CHANNEL_ID not configured or is 0")
 	}
@@ -116,11 +227,12 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 	// 创建功能管理器
 	featureManager := features.NewManager(groupService)
 
-	// 创建 worker pool (10 workers, 100 queue size)
-	workerPool := NewWorkerPool(10, 100)
+	// 创建 worker pool（worker 数按队列水位伸缩，队列容量 100）
+	workerPool := NewAdaptiveWorkerPool(workerPoolConfig(cfg.WorkerPoolMinWorkers, cfg.WorkerPoolMaxWorkers))
 
 	// 创建 bot 实例
-	opts := []bot.Option{}
+	// 每个 update 生成 trace id 放入 context，handler→service→repository 的日志通过 logger.Ctx(ctx) 串联
+	opts := []bot.Option{bot.WithMiddlewares(traceUpdate)}
 	if cfg.Debug {
 		opts = append(opts, bot.WithDebug())
 	}
@@ -134,8 +246,16 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 		bot:                  b,
 		db:                   db,
 		ownerIDs:             cfg.OwnerIDs,
-		messageRetentionDays: cfg.MessageRetentionDays,
+		messageRetention:     retentionPolicy,
+		balanceAlertChatID:   cfg.BalanceAlertChatID,
+		balanceAlertToOwners: cfg.BalanceAlertToOwners,
 		workerPool:           workerPool,
+		workerDrainTimeout:   cfg.WorkerPoolDrainTimeout,
+		commandLimiter:       newCommandRateLimiter(cfg.RateLimitWindow, cfg.RateLimitMax, cfg.RateLimitOverrides),
+		inlineLimiter:        newCommandRateLimiter(inlineQuoteRateWindow, inlineQuoteRateLimit, nil),
+		inlineQuotes:         newInlineQuoteCache(inlineQuoteCacheTTL, crypto.Quote),
+		permissionDenials:    newPermissionDenialTracker(permissionDenialWindow, permissionDenialAlertThreshold),
+		metrics:              newBotMetrics(),
 		startTime:            time.Now(),
 		userService:          userService,
 		groupService:         groupService,
@@ -144,21 +264,49 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 		forwardService:       forwardService,
 		accountingService:    accountingService,
 		balanceService:       balanceService,
+		sendMoneyQuota:       sendMoneyQuota,
+		memberEvents:         memberEventService,
+		scheduledMessages:    scheduledMessageService,
+		merchantHistory:      merchantHistoryService,
+		commandUsage:         commandUsageService,
 		paymentService:       paymentSvc,
 		featureManager:       featureManager,
+		orderCache:           sifanglookup.NewOrderCache(orderCacheCapacity, orderCacheTTL, orderNotFoundCacheTTL),
 		userRepo:             userRepo,
 		groupRepo:            groupRepo,
 		messageRepo:          messageRepo,
 		forwardRecordRepo:    forwardRecordRepo,
 		accountingRepo:       accountingRepo,
 		upstreamBalanceRepo:  upstreamBalanceRepo,
-		orderCascadeStates:   make(map[string]*orderCascadeState),
+		sendMoneyRepo:        sendMoneyRepo,
+		orderCascadeRepo:     orderCascadeRepo,
+
+		sendMoneyExpirationRepo: sendMoneyExpirationRepo,
+		dailyBillPushRepo:       dailyBillPushRepo,
+		deletedMessageRepo:      deletedMessageRepo,
+		memberEventRepo:         memberEventRepo,
+		scheduledMessageRepo:    scheduledMessageRepo,
+		merchantHistoryRepo:     merchantHistoryRepo,
+		commandUsageRepo:        commandUsageRepo,
+		joinVerifyRepo:          joinVerifyRepo,
+		orderCascadeStates:      make(map[string]*models.OrderCascadeState),
+		sentMessages:            newSentMessageLog(sentMessageTTL, sentMessageLimitPerChat),
 	}
 
 	tempCtx, tempCancel := context.WithCancel(context.Background())
 	telegramBot.tempMessageCtx = tempCtx
 	telegramBot.tempMessageCancel = tempCancel
 
+	// handler panic 统一记录堆栈并回复用户，可选告警 owner
+	if cfg.PanicAlertEnabled {
+		telegramBot.panicAlerts = newPanicAlertThrottle(panicAlertInterval)
+	}
+	workerPool.SetPanicHandler(telegramBot.handleWorkerPanic)
+
+	if cfg.HealthPort > 0 {
+		telegramBot.healthServer = newHealthServer(cfg.HealthPort, telegramBot.checkReadiness, telegramBot.metricsHandler())
+	}
+
 	// 初始化 owners
 	if err := telegramBot.initOwners(context.Background()); err != nil {
 		logger.L().Warnf("Failed to initialize owners: %v", err)
@@ -166,6 +314,7 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 
 	// 注册功能插件
 	telegramBot.registerFeatures()
+	featureManager.SetGuard(telegramBot.guardFeature)
 
 	// 注册 handlers
 	telegramBot.registerHandlers()
@@ -175,52 +324,112 @@ func New(cfg Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bo
 		return nil, fmt.Errorf("failed to ensure indexes: %w", err)
 	}
 
+	if err := telegramBot.initMediaArchiver(cfg.MediaArchive); err != nil {
+		return nil, err
+	}
+
 	telegramBot.initUpstreamBalanceMonitor()
 	telegramBot.initDailySummaryScheduler(cfg.DailyBillPushEnabled)
 	telegramBot.initUpstreamSettlementScheduler(cfg.DailyBillPushEnabled)
+	telegramBot.initSendMoneyExpirationSweeper()
+	telegramBot.initChannelRateWarmer()
+	telegramBot.initScheduledMessageScheduler()
+	telegramBot.initGroupArchivePurger()
+	telegramBot.initPendingStateSweeper(cfg.PendingStateSweepInterval)
+	telegramBot.restoreJoinVerifications(context.Background())
 
 	logger.L().Info("Telegram bot initialized successfully")
 	return telegramBot, nil
 }
 
 // asyncHandler 异步 handler 包装器
-// 将 handler 提交到 worker pool 异步执行
+// 将 handler 提交到 worker pool 异步执行，并记录处理次数与耗时指标（panic 时同样记录）
 func (b *Bot) asyncHandler(handler bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+		label := handlerMetricLabel(ctx, update)
+
 		// 提交到 worker pool
 		b.workerPool.Submit(HandlerTask{
 			Ctx:         ctx,
 			BotInstance: botInstance,
 			Update:      update,
-			Handler:     handler,
+			Handler: func(ctx context.Context, botInstance *bot.Bot, update *botModels.Update) {
+				start := time.Now()
+				defer func() { b.metrics.observeHandler(label, time.Since(start)) }()
+				handler(ctx, botInstance, update)
+			},
 		})
 	}
 }
 
+// newMessageRetentionPolicy 构建消息保留期策略，tierDays 的 key 为群等级（basic/merchant/upstream）
+func newMessageRetentionPolicy(defaultDays int, tierDays map[string]int) models.MessageRetentionPolicy {
+	policy := models.MessageRetentionPolicy{
+		DefaultDays: defaultDays,
+		TierDays:    make(map[models.GroupTier]int, len(tierDays)),
+	}
+	for tier, days := range tierDays {
+		if days > 0 {
+			policy.TierDays[models.NormalizeGroupTier(models.GroupTier(tier))] = days
+		}
+	}
+	return policy
+}
+
 // InitFromConfig 从应用配置初始化 Telegram Bot
 func InitFromConfig(cfg *config.Config, db *mongo.Database, paymentSvc paymentservice.Service) (*Bot, error) {
 	telegramCfg := Config{
-		Token:                cfg.TelegramToken,
-		OwnerIDs:             cfg.BotOwnerIDs,
-		Debug:                false, // 可根据需要从环境变量读取
-		MessageRetentionDays: cfg.MessageRetentionDays,
-		ChannelID:            cfg.ChannelID,
-		DailyBillPushEnabled: cfg.DailyBillPushEnabled,
+		Token:                     cfg.TelegramToken,
+		OwnerIDs:                  cfg.BotOwnerIDs,
+		Debug:                     false, // 可根据需要从环境变量读取
+		MessageRetentionDays:      cfg.MessageRetentionDays,
+		ChannelID:                 cfg.ChannelID,
+		ChannelForwardMode:        cfg.ChannelForwardMode,
+		ChannelForwardTarget:      cfg.ChannelForwardTarget,
+		DailyBillPushEnabled:      cfg.DailyBillPushEnabled,
+		PanicAlertEnabled:         cfg.PanicAlertEnabled,
+		BalanceAlertChatID:        cfg.BalanceAlertChatID,
+		BalanceAlertToOwners:      cfg.BalanceAlertToOwners,
+		SettlementFallbackToTotal: cfg.SettlementFallbackToTotal,
+		HealthPort:                cfg.HealthPort,
+		RateLimitWindow:           cfg.CommandRateLimit.Window,
+		RateLimitMax:              cfg.CommandRateLimit.Limit,
+		RateLimitOverrides:        cfg.CommandRateLimit.Overrides,
+		MediaDedupMode:            cfg.MediaDedup.Mode,
+		MediaDedupWindow:          cfg.MediaDedup.Window,
+		MessageBatchSize:          cfg.MessageBatch.Size,
+		MessageBatchFlushInterval: cfg.MessageBatch.FlushInterval,
+		MessageRetentionTierDays:  cfg.MessageRetentionTierDays,
+		PendingStateSweepInterval: cfg.PendingStateSweepInterval,
+		WorkerPoolMinWorkers:      cfg.WorkerPool.MinWorkers,
+		WorkerPoolMaxWorkers:      cfg.WorkerPool.MaxWorkers,
+		WorkerPoolDrainTimeout:    cfg.WorkerPool.DrainTimeout,
+		MediaArchive:              cfg.MediaArchive,
 	}
 	return New(telegramCfg, db, paymentSvc)
 }
 
 // Start 启动 Bot（阻塞式，应在 goroutine 中运行）
 func (b *Bot) Start(ctx context.Context) error {
-	logger.L().Info("Starting Telegram bot...")
+	logger.Ctx(ctx).Info("Starting Telegram bot...")
+	if b.healthServer != nil {
+		// 探针服务启动失败不影响 Bot 运行，/healthz 不可达会由编排系统发现并处理
+		if err := b.healthServer.start(); err != nil {
+			logger.Ctx(ctx).Errorf("Failed to start health server: %v", err)
+		}
+	}
+
+	b.running.Store(true)
 	b.bot.Start(ctx)
-	logger.L().Info("Telegram bot stopped")
+	b.running.Store(false)
+	logger.Ctx(ctx).Info("Telegram bot stopped")
 	return nil
 }
 
 // Stop 停止 Bot
 func (b *Bot) Stop(ctx context.Context) error {
-	logger.L().Info("Stopping Telegram bot...")
+	logger.Ctx(ctx).Info("Stopping Telegram bot...")
+	b.running.Store(false)
 
 	if b.tempMessageCancel != nil {
 		b.tempMessageCancel()
@@ -228,9 +437,26 @@ func (b *Bot) Stop(ctx context.Context) error {
 		b.tempMessageCtx = nil
 	}
 
-	// 关闭 worker pool
+	// 关闭 worker pool：已入队与执行中的任务处理完再继续，超时才放弃
 	if b.workerPool != nil {
-		b.workerPool.Shutdown()
+		if abandoned := b.workerPool.Shutdown(workerDrainTimeout(ctx, b.workerDrainTimeout)); abandoned > 0 {
+			logger.Ctx(ctx).Warnf("Worker pool abandoned %d task(s) during shutdown", abandoned)
+		}
+	}
+
+	// 归档任务会写回消息文档，需在关闭消息服务前停止
+	if b.mediaArchiver != nil {
+		b.mediaArchiver.stop()
+		b.mediaArchiver = nil
+	}
+
+	// 处理中的消息已全部入缓冲，flush 剩余消息后再关闭数据库
+	if b.messageService != nil {
+		b.messageService.Close(ctx)
+	}
+
+	if b.commandUsage != nil {
+		b.commandUsage.Close(ctx)
 	}
 
 	if b.dailySummaryScheduler != nil {
@@ -248,6 +474,37 @@ func (b *Bot) Stop(ctx context.Context) error {
 		b.balanceMonitor = nil
 	}
 
+	if b.sendMoneySweeper != nil {
+		b.sendMoneySweeper.stop()
+		b.sendMoneySweeper = nil
+	}
+
+	if b.channelRateWarmer != nil {
+		b.channelRateWarmer.stop()
+		b.channelRateWarmer = nil
+	}
+
+	if b.scheduledMessageScheduler != nil {
+		b.scheduledMessageScheduler.stop()
+		b.scheduledMessageScheduler = nil
+	}
+
+	if b.groupArchivePurger != nil {
+		b.groupArchivePurger.stop()
+		b.groupArchivePurger = nil
+	}
+
+	if b.pendingStateSweeper != nil {
+		b.pendingStateSweeper.stop()
+		b.pendingStateSweeper = nil
+	}
+
+	// 最后关闭探针服务，关闭过程中 /readyz 已返回 503
+	if b.healthServer != nil {
+		b.healthServer.stop(ctx)
+		b.healthServer = nil
+	}
+
 	// bot.Stop() 通过 context 取消实现
 	return nil
 }
@@ -266,19 +523,19 @@ func (b *Bot) initOwners(ctx context.Context) error {
 				LastActiveAt: time.Now(),
 			}
 			if err := b.userRepo.CreateOrUpdate(ctx, user); err != nil {
-				logger.L().Warnf("Failed to create owner %d: %v", ownerID, err)
+				logger.Ctx(ctx).Warnf("Failed to create owner %d: %v", ownerID, err)
 				continue
 			}
-			logger.L().Infof("Initialized owner: %d", ownerID)
+			logger.Ctx(ctx).Infof("Initialized owner: %d", ownerID)
 		} else if user.Role != models.RoleOwner {
 			// 用户存在但角色不是 owner，更新为 owner
 			user.Role = models.RoleOwner
 			user.UpdatedAt = time.Now()
 			if err := b.userRepo.CreateOrUpdate(ctx, user); err != nil {
-				logger.L().Warnf("Failed to update owner role for %d: %v", ownerID, err)
+				logger.Ctx(ctx).Warnf("Failed to update owner role for %d: %v", ownerID, err)
 				continue
 			}
-			logger.L().Infof("Updated user %d to owner", ownerID)
+			logger.Ctx(ctx).Infof("Updated user %d to owner", ownerID)
 		}
 	}
 	return nil
@@ -287,42 +544,113 @@ func (b *Bot) initOwners(ctx context.Context) error {
 // ensureIndexes 确保所有数据库索引存在
 func (b *Bot) ensureIndexes(ctx context.Context) error {
 	// 计算 TTL 秒数（天数 * 24小时 * 3600秒）
-	ttlSeconds := int32(b.messageRetentionDays * 24 * 3600)
+	ttlSeconds := int32(b.messageRetention.DefaultDays * 24 * 3600)
 
 	if err := b.userRepo.EnsureIndexes(ctx, ttlSeconds); err != nil {
 		return fmt.Errorf("failed to ensure user indexes: %w", err)
 	}
-	logger.L().Debug("User indexes ensured")
+	logger.Ctx(ctx).Debug("User indexes ensured")
 
 	if err := b.groupRepo.EnsureIndexes(ctx, ttlSeconds); err != nil {
 		return fmt.Errorf("failed to ensure group indexes: %w", err)
 	}
-	logger.L().Debug("Group indexes ensured")
+	logger.Ctx(ctx).Debug("Group indexes ensured")
 
 	if err := b.messageRepo.EnsureIndexes(ctx, ttlSeconds); err != nil {
 		return fmt.Errorf("failed to ensure message indexes: %w", err)
 	}
-	logger.L().Infof("Message indexes ensured (TTL: %d days = %d seconds)", b.messageRetentionDays, ttlSeconds)
+	logger.Ctx(ctx).Infof("Message indexes ensured (default retention: %d days, tier overrides: %v, expiry driven by expire_at)",
+		b.messageRetention.DefaultDays, b.messageRetention.TierDays)
+
+	if b.deletedMessageRepo != nil {
+		if err := b.deletedMessageRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure deleted message indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Deleted message indexes ensured")
+	}
+
+	if b.memberEventRepo != nil {
+		if err := b.memberEventRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure member event indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Member event indexes ensured")
+	}
+
+	if b.scheduledMessageRepo != nil {
+		if err := b.scheduledMessageRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure scheduled message indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Scheduled message indexes ensured")
+	}
+
+	if b.merchantHistoryRepo != nil {
+		if err := b.merchantHistoryRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure merchant history indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Merchant history indexes ensured")
+	}
+
+	if b.commandUsageRepo != nil {
+		if err := b.commandUsageRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure command usage indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Command usage indexes ensured")
+	}
+
+	if b.joinVerifyRepo != nil {
+		if err := b.joinVerifyRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure join verification indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Join verification indexes ensured")
+	}
 
 	// 确保转发记录索引（如果转发服务已启用）
 	if b.forwardRecordRepo != nil {
 		if err := b.forwardRecordRepo.EnsureIndexes(ctx); err != nil {
 			return fmt.Errorf("failed to ensure forward_records indexes: %w", err)
 		}
-		logger.L().Info("Forward records indexes ensured (TTL: 48 hours)")
+		logger.Ctx(ctx).Info("Forward records indexes ensured (TTL: 48 hours)")
 	}
 
 	// 确保收支记账索引
 	if err := b.accountingRepo.EnsureIndexes(ctx); err != nil {
 		return fmt.Errorf("failed to ensure accounting indexes: %w", err)
 	}
-	logger.L().Debug("Accounting indexes ensured")
+	logger.Ctx(ctx).Debug("Accounting indexes ensured")
 
 	if b.upstreamBalanceRepo != nil {
 		if err := b.upstreamBalanceRepo.EnsureIndexes(ctx); err != nil {
 			return fmt.Errorf("failed to ensure upstream balance indexes: %w", err)
 		}
-		logger.L().Debug("Upstream balance indexes ensured")
+		logger.Ctx(ctx).Debug("Upstream balance indexes ensured")
+	}
+
+	if b.sendMoneyRepo != nil {
+		if err := b.sendMoneyRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure send money indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Send money indexes ensured")
+	}
+
+	if b.orderCascadeRepo != nil {
+		if err := b.orderCascadeRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure order cascade indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Order cascade indexes ensured")
+	}
+
+	if b.sendMoneyExpirationRepo != nil {
+		if err := b.sendMoneyExpirationRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure send money expiration indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Send money expiration indexes ensured")
+	}
+
+	if b.dailyBillPushRepo != nil {
+		if err := b.dailyBillPushRepo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("failed to ensure daily bill push indexes: %w", err)
+		}
+		logger.Ctx(ctx).Debug("Daily bill push indexes ensured")
 	}
 
 	return nil
@@ -349,6 +677,26 @@ func (b *Bot) initDailySummaryScheduler(enabled bool) {
 	scheduler.start()
 }
 
+func (b *Bot) initSendMoneyExpirationSweeper() {
+	if b.sendMoneyExpirationRepo == nil || b.sifangFeature == nil {
+		logger.L().Warn("Send money expiration sweeper not started: dependency unavailable")
+		return
+	}
+	sweeper := newSendMoneyExpirationSweeper(b.sendMoneyExpirationRepo, b.expireSendMoneyMessage)
+	b.sendMoneySweeper = sweeper
+	sweeper.start()
+}
+
+func (b *Bot) initChannelRateWarmer() {
+	if b.paymentService == nil || b.sifangFeature == nil || b.groupService == nil {
+		logger.L().Warn("Channel rate warmer not started: dependency unavailable")
+		return
+	}
+	warmer := newChannelRateWarmer(b)
+	b.channelRateWarmer = warmer
+	warmer.start()
+}
+
 func (b *Bot) initUpstreamBalanceMonitor() {
 	if b.balanceService == nil || b.groupService == nil {
 		logger.L().Warn("Upstream balance monitor not started: service unavailable")
@@ -386,15 +734,16 @@ func (b *Bot) registerFeatures() {
 	b.featureManager.Register(calculator.New())
 
 	// 注册商户号绑定功能
-	b.featureManager.Register(merchant.New(b.groupService, b.userService))
+	b.featureManager.Register(merchant.New(b.groupService, b.userService, b.merchantHistory))
 
 	// 注册接口绑定功能
-	b.featureManager.Register(upstream.New(b.groupService, b.userService))
+	b.upstreamFeature = upstream.New(b.groupService, b.userService, b.paymentService)
+	b.featureManager.Register(b.upstreamFeature)
 	b.featureManager.Register(upstream.NewBalanceFeature(b.balanceService, b.userService, b.groupService))
 	b.featureManager.Register(upstream.NewSummaryFeature(b.paymentService))
 
 	// 注册四方支付功能
-	b.sifangFeature = sifangfeature.New(b.paymentService, b.userService)
+	b.sifangFeature = sifangfeature.New(b.paymentService, b.userService, b.sendMoneyQuota, b.accountingService)
 	b.featureManager.Register(b.sifangFeature)
 
 	// 注册加密货币价格查询功能
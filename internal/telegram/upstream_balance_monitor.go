@@ -3,7 +3,10 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go_bot/internal/logger"
@@ -29,6 +32,7 @@ type upstreamBalanceMonitor struct {
 	statesMu       sync.Mutex
 	states         map[int64]*balanceAlertState
 	interval       time.Duration
+	running        atomic.Bool
 }
 
 func newUpstreamBalanceMonitor(bot *Bot, balanceSvc service.UpstreamBalanceService, groupSvc service.GroupService) *upstreamBalanceMonitor {
@@ -60,9 +64,15 @@ func (m *upstreamBalanceMonitor) start() {
 		m.runPeriodic(ctx)
 	}()
 
+	m.running.Store(true)
 	logger.L().Info("Upstream balance monitor started")
 }
 
+// isRunning 监控是否处于运行状态
+func (m *upstreamBalanceMonitor) isRunning() bool {
+	return m != nil && m.running.Load()
+}
+
 func (m *upstreamBalanceMonitor) stop() {
 	if m == nil || m.cancel == nil {
 		return
@@ -70,6 +80,7 @@ func (m *upstreamBalanceMonitor) stop() {
 	m.cancel()
 	m.wg.Wait()
 	m.cancel = nil
+	m.running.Store(false)
 	logger.L().Info("Upstream balance monitor stopped")
 }
 
@@ -85,7 +96,7 @@ func (m *upstreamBalanceMonitor) consumeEvents(ctx context.Context) {
 			}
 			group, err := m.groupService.GetGroupInfo(ctx, ev.GroupID)
 			if err != nil {
-				logger.L().Warnf("Balance monitor failed to load group %d: %v", ev.GroupID, err)
+				logger.Ctx(ctx).Warnf("Balance monitor failed to load group %d: %v", ev.GroupID, err)
 				continue
 			}
 			m.evaluateAndAlert(ctx, group, ev.Balance, ev.MinBalance, ev.AlertLimitPerHour, false)
@@ -112,33 +123,35 @@ func (m *upstreamBalanceMonitor) runPeriodic(ctx context.Context) {
 }
 
 func (m *upstreamBalanceMonitor) scanBalances(ctx context.Context) {
-	groups, err := m.groupService.ListActiveGroups(ctx)
+	// 分批遍历，只保留开启轮询告警的上游群
+	eligible := make(map[int64]*models.Group)
+	err := m.groupService.IterateActiveGroups(ctx, func(groups []*models.Group) error {
+		for _, g := range groups {
+			if models.NormalizeGroupTier(g.Tier) != models.GroupTierUpstream {
+				continue
+			}
+			if len(g.Settings.InterfaceBindings) == 0 {
+				continue
+			}
+			if !models.IsBalanceMonitorEnabled(g.Settings) {
+				continue
+			}
+			eligible[g.TelegramID] = g
+		}
+		return nil
+	})
 	if err != nil {
-		logger.L().Warnf("Balance monitor list groups failed: %v", err)
+		logger.Ctx(ctx).Warnf("Balance monitor list groups failed: %v", err)
 		return
 	}
 
-	eligible := make(map[int64]*models.Group, len(groups))
-	for _, g := range groups {
-		if models.NormalizeGroupTier(g.Tier) != models.GroupTierUpstream {
-			continue
-		}
-		if len(g.Settings.InterfaceBindings) == 0 {
-			continue
-		}
-		if !models.IsBalanceMonitorEnabled(g.Settings) {
-			continue
-		}
-		eligible[g.TelegramID] = g
-	}
-
 	if len(eligible) == 0 {
 		return
 	}
 
 	results, err := m.balanceService.ListAll(ctx)
 	if err != nil {
-		logger.L().Warnf("Balance monitor list balances failed: %v", err)
+		logger.Ctx(ctx).Warnf("Balance monitor list balances failed: %v", err)
 		return
 	}
 
@@ -204,30 +217,93 @@ func (m *upstreamBalanceMonitor) evaluateAndAlert(ctx context.Context, group *mo
 	m.statesMu.Unlock()
 
 	if err := m.sendAlert(ctx, group, balance, minBalance); err != nil {
-		logger.L().Warnf("Balance alert failed: chat_id=%d err=%v", group.TelegramID, err)
+		// 没有任何接收方收到告警，退回本窗口配额以便下次扫描重发
+		logger.Ctx(ctx).Warnf("Balance alert failed: chat_id=%d err=%v", group.TelegramID, err)
 		m.statesMu.Lock()
 		state.sentInWindow--
 		m.statesMu.Unlock()
-		return
 	}
-
-	m.statesMu.Unlock()
 }
 
+// sendAlert 发送低余额告警：配置了告警目标时发到告警群或 owner 私聊，全部失败时回退到原群；
+// 任一接收方送达即返回 nil
 func (m *upstreamBalanceMonitor) sendAlert(ctx context.Context, group *models.Group, balance, minBalance float64) error {
 	alertCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	text := fmt.Sprintf(
-		"⚠️ 上游余额不足\n当前余额：%s CNY\n最低余额：%s CNY\n建议立即加款，例如发送「+1000」或调整阈值：/set_min_balance 金额",
-		formatAmount(balance),
-		formatAmount(minBalance),
-	)
+	targets, routed := m.bot.balanceAlertTargets(group.TelegramID, group.Settings.BalanceAlertTarget)
+	text := formatLowBalanceAlert(group, balance, minBalance, routed)
 
-	_, err := m.bot.sendMessageWithMarkupAndMessage(alertCtx, group.TelegramID, text, nil)
+	var lastErr error
+	delivered := 0
+	for _, chatID := range targets {
+		if _, err := m.bot.sendScheduledPush(alertCtx, group, chatID, text); err != nil {
+			logger.Ctx(ctx).Warnf("Balance alert delivery failed: source_chat_id=%d target_chat_id=%d err=%v", group.TelegramID, chatID, err)
+			lastErr = err
+			continue
+		}
+		delivered++
+	}
+	// 部分目标送达即算告警已发出（失败目标已逐个记录），只有全部失败才返回错误
+	if delivered > 0 {
+		return nil
+	}
+	if !routed {
+		return lastErr
+	}
+
+	// 告警群不可用（如 Bot 被移出）时回退到原群，避免告警丢失
+	_, err := m.bot.sendScheduledPush(alertCtx, group, group.TelegramID, formatLowBalanceAlert(group, balance, minBalance, false))
 	return err
 }
 
+// balanceAlertTargets 返回低余额告警的接收方，routed 表示告警不发在原群
+// override 为群级告警目标（/balance_config 设置），为空时按全局配置；指定的目标不可用时回退到原群
+func (b *Bot) balanceAlertTargets(sourceChatID int64, override string) (targets []int64, routed bool) {
+	switch override {
+	case models.BalanceAlertTargetGroup:
+		return []int64{sourceChatID}, false
+	case models.BalanceAlertTargetOwners:
+		if len(b.ownerIDs) > 0 {
+			return b.ownerIDs, true
+		}
+		return []int64{sourceChatID}, false
+	case models.BalanceAlertTargetChat:
+		if b.balanceAlertChatID != 0 && b.balanceAlertChatID != sourceChatID {
+			return []int64{b.balanceAlertChatID}, true
+		}
+		return []int64{sourceChatID}, false
+	}
+
+	if b.balanceAlertToOwners && len(b.ownerIDs) > 0 {
+		return b.ownerIDs, true
+	}
+	if b.balanceAlertChatID != 0 && b.balanceAlertChatID != sourceChatID {
+		return []int64{b.balanceAlertChatID}, true
+	}
+	return []int64{sourceChatID}, false
+}
+
+// formatLowBalanceAlert 低余额告警文案，发到原群以外时附上来源群标题与 chat ID
+func formatLowBalanceAlert(group *models.Group, balance, minBalance float64, withSource bool) string {
+	var text strings.Builder
+	text.WriteString("⚠️ 上游余额不足\n")
+	if withSource {
+		title := group.Title
+		if title == "" {
+			title = "未命名群组"
+		}
+		text.WriteString(fmt.Sprintf("来源群：%s（<code>%d</code>）\n", html.EscapeString(title), group.TelegramID))
+	}
+	text.WriteString(fmt.Sprintf("当前余额：%s CNY\n最低余额：%s CNY\n", formatAmount(balance), formatAmount(minBalance)))
+	if withSource {
+		text.WriteString("请在来源群加款，例如发送「+1000」，或在来源群调整阈值：/set_min_balance 金额")
+	} else {
+		text.WriteString("建议立即加款，例如发送「+1000」或调整阈值：/set_min_balance 金额")
+	}
+	return text.String()
+}
+
 func formatAmount(value float64) string {
 	return fmt.Sprintf("%.2f", value)
 }
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"go_bot/internal/telegram/models"
+)
+
+func TestBalanceAlertTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		bot        *Bot
+		override   string
+		wantTarget []int64
+		wantRouted bool
+	}{
+		{name: "fallback to source group", bot: &Bot{}, wantTarget: []int64{-1}},
+		{name: "alert group", bot: &Bot{balanceAlertChatID: -900}, wantTarget: []int64{-900}, wantRouted: true},
+		{name: "alert group is source group", bot: &Bot{balanceAlertChatID: -1}, wantTarget: []int64{-1}},
+		{name: "owners", bot: &Bot{balanceAlertToOwners: true, balanceAlertChatID: -900, ownerIDs: []int64{7, 8}}, wantTarget: []int64{7, 8}, wantRouted: true},
+		{name: "owners without owner ids", bot: &Bot{balanceAlertToOwners: true}, wantTarget: []int64{-1}},
+		{name: "group override beats global owners", bot: &Bot{balanceAlertToOwners: true, ownerIDs: []int64{7}}, override: models.BalanceAlertTargetGroup, wantTarget: []int64{-1}},
+		{name: "owners override", bot: &Bot{balanceAlertChatID: -900, ownerIDs: []int64{7}}, override: models.BalanceAlertTargetOwners, wantTarget: []int64{7}, wantRouted: true},
+		{name: "alert chat override", bot: &Bot{balanceAlertToOwners: true, balanceAlertChatID: -900, ownerIDs: []int64{7}}, override: models.BalanceAlertTargetChat, wantTarget: []int64{-900}, wantRouted: true},
+		{name: "alert chat override without alert chat", bot: &Bot{}, override: models.BalanceAlertTargetChat, wantTarget: []int64{-1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, routed := tt.bot.balanceAlertTargets(-1, tt.override)
+			if !slices.Equal(targets, tt.wantTarget) || routed != tt.wantRouted {
+				t.Fatalf("expected %v routed=%v, got %v routed=%v", tt.wantTarget, tt.wantRouted, targets, routed)
+			}
+		})
+	}
+}
+
+func TestFormatLowBalanceAlertIncludesSourceWhenRouted(t *testing.T) {
+	group := &models.Group{TelegramID: -100123, Title: "上游<A>"}
+
+	routed := formatLowBalanceAlert(group, 80, 100, true)
+	if !strings.Contains(routed, "来源群：上游&lt;A&gt;（<code>-100123</code>）") || !strings.Contains(routed, "当前余额：80.00 CNY") {
+		t.Fatalf("unexpected routed alert: %s", routed)
+	}
+
+	local := formatLowBalanceAlert(group, 80, 100, false)
+	if strings.Contains(local, "来源群") {
+		t.Fatalf("expected no source line in source group alert: %s", local)
+	}
+}
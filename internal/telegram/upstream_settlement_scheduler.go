@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"go_bot/internal/logger"
 	"go_bot/internal/telegram/models"
+	"go_bot/internal/telegram/service"
 )
 
 type upstreamSettlementScheduler struct {
@@ -18,6 +20,7 @@ type upstreamSettlementScheduler struct {
 	cancel   context.CancelFunc
 	done     chan struct{}
 	location *time.Location
+	running  atomic.Bool
 }
 
 func newUpstreamSettlementScheduler(bot *Bot) *upstreamSettlementScheduler {
@@ -36,10 +39,16 @@ func (s *upstreamSettlementScheduler) start() {
 	s.cancel = cancel
 	s.done = make(chan struct{})
 
+	s.running.Store(true)
 	go s.run(ctx)
 	logger.L().Info("Upstream settlement scheduler started")
 }
 
+// isRunning 调度器是否处于运行状态
+func (s *upstreamSettlementScheduler) isRunning() bool {
+	return s != nil && s.running.Load()
+}
+
 func (s *upstreamSettlementScheduler) stop() {
 	if s == nil || s.cancel == nil {
 		return
@@ -48,6 +57,7 @@ func (s *upstreamSettlementScheduler) stop() {
 	<-s.done
 	s.cancel = nil
 	s.done = nil
+	s.running.Store(false)
 	logger.L().Info("Upstream settlement scheduler stopped")
 }
 
@@ -55,21 +65,28 @@ func (s *upstreamSettlementScheduler) run(ctx context.Context) {
 	defer close(s.done)
 
 	for {
-		now := time.Now().In(s.location)
-		next := nextDailyRun(now, s.location)
+		now := time.Now()
+		groups := s.bot.listScheduledGroups(ctx, filterEligibleUpstreamGroups)
+		next := nextZonedDailyRun(now, s.location, groups)
 		wait := time.Until(next)
 		if wait <= 0 {
 			wait = time.Second
 		}
+		capped := wait > scheduleMaxWait
+		if capped {
+			wait = scheduleMaxWait
+		}
 
 		timer := time.NewTimer(wait)
-		logger.L().Debugf("Upstream settlement waiting %s until %s", wait.String(), next.Format(time.RFC3339))
+		logger.Ctx(ctx).Debugf("Upstream settlement waiting %s until %s", wait.String(), next.Format(time.RFC3339))
 		select {
 		case <-ctx.Done():
 			timer.Stop()
 			return
 		case <-timer.C:
-			s.dispatch(ctx)
+			if !capped {
+				s.dispatch(ctx)
+			}
 		}
 	}
 }
@@ -80,24 +97,11 @@ func (s *upstreamSettlementScheduler) dispatch(parent context.Context) {
 	}
 
 	startTime := time.Now()
-	targetDate := previousBillingDate(time.Now().In(s.location), s.location)
+	now := startTime
+	targetDate := models.PreviousBillingDate(now, s.location)
 	runCtx, cancel := context.WithTimeout(parent, 3*time.Minute)
 	defer cancel()
 
-	groups, err := s.bot.groupService.ListActiveGroups(runCtx)
-	if err != nil {
-		logger.L().Errorf("Upstream settlement failed to list groups: %v", err)
-		return
-	}
-
-	eligible := filterEligibleUpstreamGroups(groups)
-	if len(eligible) == 0 {
-		logger.L().Infof("Upstream settlement skipped: no eligible groups for %s", targetDate.Format("2006-01-02"))
-		return
-	}
-
-	logger.L().Infof("Upstream settlement started for %d groups, target_date=%s", len(eligible), targetDate.Format("2006-01-02"))
-
 	const workerLimit = 8
 	var mu sync.Mutex
 	failures := make([]string, 0)
@@ -105,26 +109,45 @@ func (s *upstreamSettlementScheduler) dispatch(parent context.Context) {
 	eg, egCtx := errgroup.WithContext(runCtx)
 	eg.SetLimit(workerLimit)
 
-	for _, group := range eligible {
-		group := group
-		eg.Go(func() error {
-			settleCtx, cancelGroup := context.WithTimeout(egCtx, 20*time.Second)
-			defer cancelGroup()
-
-			operationID := fmt.Sprintf("auto-settle:%d:%s", group.TelegramID, targetDate.Format("2006-01-02"))
-			if err := s.settleWithRetry(settleCtx, group, targetDate, operationID); err != nil {
-				mu.Lock()
-				failures = append(failures, fmt.Sprintf("%d(%s): %v", group.TelegramID, group.Title, err))
-				mu.Unlock()
+	// 分批遍历群组，每批只保留到期的上游群并交给工作池；工作池满时遍历随之等待
+	total := 0
+	iterErr := s.bot.groupService.IterateActiveGroups(runCtx, func(groups []*models.Group) error {
+		for _, group := range filterDueGroups(filterEligibleUpstreamGroups(groups), now) {
+			if total == 0 {
+				logger.L().Infof("Upstream settlement started, target_date=%s", targetDate.Format("2006-01-02"))
 			}
-			return nil
-		})
-	}
-
+			total++
+			eg.Go(func() error {
+				settleCtx, cancelGroup := context.WithTimeout(egCtx, 20*time.Second)
+				defer cancelGroup()
+
+				groupTarget := models.PreviousBillingDate(now, models.GroupLocation(group.Settings))
+				operationID := service.SettlementOperationID(group.TelegramID, groupTarget)
+				if err := s.settleWithRetry(settleCtx, group, groupTarget, operationID); err != nil {
+					mu.Lock()
+					failures = append(failures, fmt.Sprintf("%d(%s): %v", group.TelegramID, group.Title, err))
+					mu.Unlock()
+				}
+				return nil
+			})
+		}
+		return nil
+	})
 	_ = eg.Wait()
 
+	if iterErr != nil {
+		// 已遍历到的群照常结算，其余群等下次调度
+		logger.L().Errorf("Upstream settlement failed to list groups: %v", iterErr)
+	}
+	if total == 0 {
+		if iterErr == nil {
+			logger.L().Infof("Upstream settlement skipped: no eligible groups for %s", targetDate.Format("2006-01-02"))
+		}
+		return
+	}
+
 	duration := time.Since(startTime)
-	logger.L().Infof("Upstream settlement completed for %d groups (failures=%d) duration=%s", len(eligible), len(failures), duration.Round(time.Millisecond))
+	logger.L().Infof("Upstream settlement completed for %d groups (failures=%d) duration=%s", total, len(failures), duration.Round(time.Millisecond))
 
 	if len(failures) > 0 {
 		logger.L().Warnf("Upstream settlement failures: %v", failures)
@@ -142,16 +165,16 @@ func (s *upstreamSettlementScheduler) settleWithRetry(ctx context.Context, group
 
 		result, err := s.bot.balanceService.SettleDaily(ctx, group.TelegramID, targetDate, 0, operationID)
 		if err == nil {
-			if _, sendErr := s.bot.sendMessageWithMarkupAndMessage(ctx, group.TelegramID, result.Report, nil); sendErr != nil {
-				logger.L().Warnf("Upstream settlement send failed: chat_id=%d err=%v", group.TelegramID, sendErr)
+			if _, sendErr := s.bot.sendScheduledPush(ctx, group, group.TelegramID, result.Report); sendErr != nil {
+				logger.Ctx(ctx).Warnf("Upstream settlement send failed: chat_id=%d err=%v", group.TelegramID, sendErr)
 			} else {
-				logger.L().Infof("Upstream settlement sent: chat_id=%d date=%s", group.TelegramID, targetDate.Format("2006-01-02"))
+				logger.Ctx(ctx).Infof("Upstream settlement sent: chat_id=%d date=%s", group.TelegramID, targetDate.Format("2006-01-02"))
 			}
 			return nil
 		}
 
 		lastErr = err
-		logger.L().Warnf("Upstream settlement attempt %d failed: chat_id=%d err=%v", attempt, group.TelegramID, err)
+		logger.Ctx(ctx).Warnf("Upstream settlement attempt %d failed: chat_id=%d err=%v", attempt, group.TelegramID, err)
 
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			break
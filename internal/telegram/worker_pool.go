@@ -2,7 +2,13 @@ package telegram
 
 import (
 	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-telegram/bot"
 	botModels "github.com/go-telegram/bot/models"
@@ -18,77 +24,351 @@ type HandlerTask struct {
 	Handler     bot.HandlerFunc
 }
 
+// HandlerPanic handler panic 现场信息
+type HandlerPanic struct {
+	Worker    int    // 发生 panic 的 worker 编号
+	Handler   string // 触发 panic 的 handler 名称（从调用栈中解析），无法识别时为 unknown
+	Recovered any    // recover() 返回值
+	Stack     []byte // panic 时的调用栈
+}
+
+// PanicHandler handler panic 回调，在 worker 协程中同步执行
+type PanicHandler func(task HandlerTask, report HandlerPanic)
+
+const (
+	defaultWorkerPoolMinWorkers = 10
+	defaultWorkerPoolMaxWorkers = 50
+	defaultWorkerQueueSize      = 100
+	// defaultWorkerScaleInterval 伸缩检查间隔
+	defaultWorkerScaleInterval = 500 * time.Millisecond
+	// workerScaleUpPercent 队列水位达到该百分比视为繁忙
+	workerScaleUpPercent = 50
+	// workerScaleUpChecks 连续繁忙的检查次数达到后扩容（约 1 秒），过滤瞬时尖峰
+	workerScaleUpChecks = 2
+	// workerScaleDownChecks 连续空闲的检查次数达到后回收一个 worker（约 30 秒），扩缩容阈值不对称避免抖动
+	workerScaleDownChecks = 60
+	// defaultWorkerDrainTimeout 关闭时等待已入队任务处理完的默认时间
+	defaultWorkerDrainTimeout = 5 * time.Second
+	// workerForceStopGrace 排空超时、取消在途任务后再等待其退出的时间
+	workerForceStopGrace = time.Second
+)
+
+// WorkerPoolConfig 工作池配置：worker 数在 [MinWorkers, MaxWorkers] 之间按队列水位伸缩
+type WorkerPoolConfig struct {
+	MinWorkers    int
+	MaxWorkers    int // <= MinWorkers 时固定为 MinWorkers 个 worker，不伸缩
+	QueueSize     int
+	ScaleInterval time.Duration // 伸缩检查间隔，0 表示默认 500ms
+}
+
+// workerPoolConfig 由 Bot 配置生成工作池配置，未设置的上下限使用默认值
+func workerPoolConfig(minWorkers, maxWorkers int) WorkerPoolConfig {
+	if minWorkers <= 0 {
+		minWorkers = defaultWorkerPoolMinWorkers
+	}
+	if maxWorkers <= 0 {
+		maxWorkers = max(minWorkers, defaultWorkerPoolMaxWorkers)
+	}
+	return WorkerPoolConfig{MinWorkers: minWorkers, MaxWorkers: maxWorkers, QueueSize: defaultWorkerQueueSize}
+}
+
+// workerDrainTimeout 计算关闭时排空工作池的等待时间：未配置时取默认值，且不超过 ctx 的剩余时间
+func workerDrainTimeout(ctx context.Context, configured time.Duration) time.Duration {
+	timeout := configured
+	if timeout <= 0 {
+		timeout = defaultWorkerDrainTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// 留出时间给后续的消息 flush 与数据库关闭
+		timeout = max(min(timeout, time.Until(deadline)/2), time.Millisecond)
+	}
+	return timeout
+}
+
 // WorkerPool Handler 工作池
 type WorkerPool struct {
-	taskQueue chan HandlerTask
-	wg        sync.WaitGroup
-	workers   int
+	taskQueue  chan HandlerTask
+	retire     chan struct{} // 回收信号，只有空闲 worker 能收到
+	wg         sync.WaitGroup
+	minWorkers int
+	maxWorkers int
+	workers    atomic.Int64 // 当前 worker 数
+	nextID     int
+	onPanic    PanicHandler
+	active     atomic.Int64 // 正在执行 handler 的 worker 数
+	dropped    atomic.Int64 // 队列已满、关闭期间被拒绝或关闭超时被放弃的任务数
+
+	// 关闭状态：closeMu 保证 Submit 不会向已关闭的队列发送
+	closeMu     sync.RWMutex
+	closed      bool
+	forced      atomic.Bool     // 排空超时，worker 不再执行队列中剩余的任务
+	forceCtx    context.Context // 排空超时时取消，在途任务的 ctx 随之取消
+	forceCancel context.CancelFunc
+
+	scaleInterval time.Duration
+	busyChecks    int // 连续繁忙的检查次数（仅伸缩协程访问）
+	idleChecks    int // 连续空闲的检查次数（仅伸缩协程访问）
+	stopScaling   chan struct{}
+	scalerDone    chan struct{}
 }
 
 // WorkerPoolStats 工作池状态信息
 type WorkerPoolStats struct {
-	Workers       int
+	Workers       int // 当前 worker 数
+	MinWorkers    int // 伸缩下限
+	MaxWorkers    int // 伸缩上限
+	Active        int // 正在执行 handler 的 worker 数
 	QueueLength   int
 	QueueCapacity int
+	Dropped       int64 // 启动以来因队列已满、关闭期间提交或关闭超时被丢弃的任务数
 }
 
-// NewWorkerPool 创建工作池
+// workersLabel 展示当前 worker 数，可伸缩时附带上下限
+func (s WorkerPoolStats) workersLabel() string {
+	if s.MaxWorkers > s.MinWorkers {
+		return fmt.Sprintf("%d 个协程（%d-%d）", s.Workers, s.MinWorkers, s.MaxWorkers)
+	}
+	return fmt.Sprintf("%d 个协程", s.Workers)
+}
+
+// NewWorkerPool 创建固定大小的工作池
 // workers: worker 协程数量
 // queueSize: 任务队列大小
 func NewWorkerPool(workers int, queueSize int) *WorkerPool {
+	return NewAdaptiveWorkerPool(WorkerPoolConfig{MinWorkers: workers, MaxWorkers: workers, QueueSize: queueSize})
+}
+
+// NewAdaptiveWorkerPool 创建可伸缩的工作池：启动 MinWorkers 个 worker，
+// 队列持续高水位时扩容（每次约为伸缩区间的 1/4），持续空闲时逐个回收，始终保持在上下限之间
+func NewAdaptiveWorkerPool(cfg WorkerPoolConfig) *WorkerPool {
+	if cfg.MinWorkers < 1 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.ScaleInterval <= 0 {
+		cfg.ScaleInterval = defaultWorkerScaleInterval
+	}
+
+	forceCtx, forceCancel := context.WithCancel(context.Background())
 	pool := &WorkerPool{
-		taskQueue: make(chan HandlerTask, queueSize),
-		workers:   workers,
+		forceCtx:      forceCtx,
+		forceCancel:   forceCancel,
+		taskQueue:     make(chan HandlerTask, cfg.QueueSize),
+		retire:        make(chan struct{}),
+		minWorkers:    cfg.MinWorkers,
+		maxWorkers:    cfg.MaxWorkers,
+		scaleInterval: cfg.ScaleInterval,
 	}
 
 	// 启动 worker goroutines
-	for i := 0; i < workers; i++ {
-		pool.wg.Add(1)
-		go pool.worker(i)
+	for i := 0; i < cfg.MinWorkers; i++ {
+		pool.spawn()
 	}
 
-	logger.L().Infof("Worker pool started with %d workers, queue size %d", workers, queueSize)
+	if cfg.MaxWorkers > cfg.MinWorkers {
+		pool.stopScaling = make(chan struct{})
+		pool.scalerDone = make(chan struct{})
+		go pool.runScaler()
+	}
+
+	logger.L().Infof("Worker pool started with %d-%d workers, queue size %d", cfg.MinWorkers, cfg.MaxWorkers, cfg.QueueSize)
 	return pool
 }
 
-// worker 工作协程
+// spawn 启动一个新 worker（仅在构造时与伸缩协程中调用）
+func (p *WorkerPool) spawn() {
+	id := p.nextID
+	p.nextID++
+	p.workers.Add(1)
+	p.wg.Add(1)
+	go p.worker(id)
+}
+
+// worker 工作协程：持续处理任务，收到回收信号或队列关闭且取空后退出
 func (p *WorkerPool) worker(id int) {
 	defer p.wg.Done()
 
 	logger.L().Debugf("Worker %d started", id)
 
-	for task := range p.taskQueue {
-		// 执行 handler，带 panic recovery
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					logger.L().Errorf("Worker %d: handler panic recovered: %v", id, r)
-					// 可选：发送错误消息给用户
-					if task.Update.Message != nil {
-						_, _ = task.BotInstance.SendMessage(task.Ctx, &bot.SendMessageParams{
-							ChatID: task.Update.Message.Chat.ID,
-							Text:   "❌ 服务器内部错误，请稍后重试",
-						})
-					}
-				}
-			}()
+	for {
+		select {
+		case task, ok := <-p.taskQueue:
+			if !ok {
+				logger.L().Debugf("Worker %d stopped", id)
+				return
+			}
+			if p.forced.Load() {
+				p.dropped.Add(1)
+				continue
+			}
+			p.run(id, task)
+		case <-p.retire:
+			logger.L().Debugf("Worker %d retired", id)
+			return
+		}
+	}
+}
+
+// run 执行 handler，带 panic recovery
+// 任务 ctx 与接收 update 的 ctx 的取消解耦：Bot 停止拉取 update 后，已入队和执行中的任务仍能完整发出回复，
+// 避免分片消息、多步发送在关闭时发到一半；只有关闭排空超时才会取消
+func (p *WorkerPool) run(id int, task HandlerTask) {
+	p.active.Add(1)
+	defer p.active.Add(-1)
 
-			// 执行实际的 handler
-			task.Handler(task.Ctx, task.BotInstance, task.Update)
-		}()
+	if task.Ctx == nil {
+		task.Ctx = context.Background()
 	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(task.Ctx))
+	defer cancel()
+	stop := context.AfterFunc(p.forceCtx, cancel)
+	defer stop()
+	task.Ctx = ctx
+	defer func() {
+		if r := recover(); r != nil {
+			p.handlePanic(task, HandlerPanic{
+				Worker:    id,
+				Handler:   panicHandlerName(),
+				Recovered: r,
+				Stack:     debug.Stack(),
+			})
+		}
+	}()
 
-	logger.L().Debugf("Worker %d stopped", id)
+	// 执行实际的 handler
+	task.Handler(task.Ctx, task.BotInstance, task.Update)
 }
 
-// Submit 提交任务到工作池
-func (p *WorkerPool) Submit(task HandlerTask) {
+// runScaler 按固定间隔检查队列水位并伸缩 worker 数
+func (p *WorkerPool) runScaler() {
+	defer close(p.scalerDone)
+
+	ticker := time.NewTicker(p.scaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopScaling:
+			return
+		case <-ticker.C:
+			p.scale()
+		}
+	}
+}
+
+// scale 一次伸缩检查：队列水位连续 workerScaleUpChecks 次不低于 workerScaleUpPercent 时扩容，
+// 队列为空且不足一半 worker 在忙连续 workerScaleDownChecks 次时回收一个 worker；
+// 扩容后重新累计空闲次数，回收后重新累计繁忙次数，避免在阈值附近来回伸缩
+func (p *WorkerPool) scale() {
+	queued := len(p.taskQueue)
+	workers := int(p.workers.Load())
+	busy := cap(p.taskQueue) > 0 && queued*100 >= cap(p.taskQueue)*workerScaleUpPercent
+	idle := queued == 0 && int(p.active.Load())*2 < workers
+
+	switch {
+	case busy:
+		p.idleChecks = 0
+		p.busyChecks++
+		if p.busyChecks < workerScaleUpChecks || workers >= p.maxWorkers {
+			return
+		}
+		p.busyChecks = 0
+		added := min(max(1, (p.maxWorkers-p.minWorkers)/4), p.maxWorkers-workers)
+		for range added {
+			p.spawn()
+		}
+		logger.L().Infof("Worker pool scaled up: workers=%d->%d queue=%d/%d", workers, workers+added, queued, cap(p.taskQueue))
+	case idle:
+		p.busyChecks = 0
+		p.idleChecks++
+		if p.idleChecks < workerScaleDownChecks || workers <= p.minWorkers {
+			return
+		}
+		// 只有阻塞在 select 中的空闲 worker 能收到信号，全部在忙时本轮不回收
+		select {
+		case p.retire <- struct{}{}:
+			p.idleChecks = 0
+			p.workers.Add(-1)
+			logger.L().Infof("Worker pool scaled down: workers=%d->%d", workers, workers-1)
+		default:
+		}
+	default:
+		p.busyChecks = 0
+		p.idleChecks = 0
+	}
+}
+
+// SetPanicHandler 设置 handler panic 回调，需在提交任务前调用
+func (p *WorkerPool) SetPanicHandler(handler PanicHandler) {
+	p.onPanic = handler
+}
+
+// handlePanic 处理已恢复的 panic，回调本身 panic 时同样兜底，保证 worker 不退出
+func (p *WorkerPool) handlePanic(task HandlerTask, report HandlerPanic) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Ctx(task.Ctx).Errorf("Worker %d: panic handler panicked: %v", report.Worker, r)
+		}
+	}()
+
+	if p.onPanic != nil {
+		p.onPanic(task, report)
+		return
+	}
+
+	logger.Ctx(task.Ctx).Errorf("Worker %d: handler %s panic recovered: %v\n%s", report.Worker, report.Handler, report.Recovered, report.Stack)
+	if task.Update != nil && task.Update.Message != nil {
+		_, _ = task.BotInstance.SendMessage(task.Ctx, &bot.SendMessageParams{
+			ChatID: task.Update.Message.Chat.ID,
+			Text:   "❌ 服务器内部错误，请稍后重试",
+		})
+	}
+}
+
+// panicHandlerName 在 recover 所在的 defer 中调用，从 panic 调用栈里找出最外层的 handleXxx 函数
+// 中间件和 asyncHandler 包装出的闭包无法区分具体命令，因此以实际执行的 handler 方法名为准
+func panicHandlerName() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	name := "unknown"
+	for {
+		frame, more := frames.Next()
+		fn := strings.TrimSuffix(frame.Function, "-fm")
+		if idx := strings.LastIndex(fn, "."); idx >= 0 && strings.HasPrefix(fn[idx+1:], "handle") {
+			name = fn[idx+1:]
+		}
+		if !more {
+			break
+		}
+	}
+	return name
+}
+
+// Submit 提交任务到工作池，返回任务是否入队
+// 队列已满或工作池正在关闭时任务被拒绝并计入丢弃数
+func (p *WorkerPool) Submit(task HandlerTask) bool {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		logger.Ctx(task.Ctx).Warnf("Worker pool is shutting down, task rejected")
+		return false
+	}
+
 	select {
 	case p.taskQueue <- task:
 		// 任务成功提交
+		return true
 	default:
 		// 任务队列已满，记录警告
-		logger.L().Warnf("Worker pool queue is full, task dropped")
+		p.dropped.Add(1)
+		logger.Ctx(task.Ctx).Warnf("Worker pool queue is full, task dropped")
+		return false
 	}
 }
 
@@ -99,22 +379,69 @@ func (p *WorkerPool) Stats() WorkerPoolStats {
 	}
 
 	return WorkerPoolStats{
-		Workers:       p.workers,
+		Workers:       int(p.workers.Load()),
+		MinWorkers:    p.minWorkers,
+		MaxWorkers:    p.maxWorkers,
+		Active:        int(p.active.Load()),
 		QueueLength:   len(p.taskQueue),
 		QueueCapacity: cap(p.taskQueue),
+		Dropped:       p.dropped.Load(),
 	}
 }
 
-// Shutdown 优雅关闭工作池
-// 等待所有正在执行的任务完成
-func (p *WorkerPool) Shutdown() {
+// Shutdown 优雅关闭工作池，返回被放弃的任务数
+// 不再接受新任务，等待执行中与已入队的任务全部完成；timeout > 0 时最多等待 timeout，
+// 超时后取消在途任务的 ctx、丢弃队列中尚未开始的任务，再等待 workerForceStopGrace 后返回
+func (p *WorkerPool) Shutdown(timeout time.Duration) int {
 	logger.L().Info("Shutting down worker pool...")
 
-	// 关闭任务队列，不再接受新任务
+	// 先停止伸缩，避免关闭过程中继续启动或回收 worker
+	if p.stopScaling != nil {
+		close(p.stopScaling)
+		<-p.scalerDone
+	}
+
+	// 关闭任务队列，不再接受新任务；队列中剩余的任务会被 worker 取空后再退出
+	p.closeMu.Lock()
+	p.closed = true
 	close(p.taskQueue)
+	p.closeMu.Unlock()
+	defer p.forceCancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
 
-	// 等待所有 worker 完成
-	p.wg.Wait()
+	if timeout <= 0 {
+		<-done
+		logger.L().Info("Worker pool shut down successfully")
+		return 0
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		logger.L().Info("Worker pool shut down successfully")
+		return 0
+	case <-timer.C:
+	}
+
+	// 排空超时：队列中剩余任务由 worker 取出后直接计入丢弃数，在途任务被取消
+	p.forced.Store(true)
+	pending := len(p.taskQueue)
+	running := int(p.active.Load())
+	p.forceCancel()
+
+	grace := time.NewTimer(workerForceStopGrace)
+	defer grace.Stop()
+	select {
+	case <-done:
+	case <-grace.C:
+	}
 
-	logger.L().Info("Worker pool shut down successfully")
+	logger.L().Warnf("Worker pool drain timed out after %s: %d queued task(s) discarded, %d running task(s) cancelled", timeout, pending, running)
+	return pending + running
 }
@@ -0,0 +1,229 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	botModels "github.com/go-telegram/bot/models"
+)
+
+func TestAdaptiveWorkerPoolStressDoesNotLoseTasks(t *testing.T) {
+	pool := NewAdaptiveWorkerPool(WorkerPoolConfig{MinWorkers: 1, MaxWorkers: 8, QueueSize: 600, ScaleInterval: time.Millisecond})
+
+	const tasks = 500
+	var (
+		done sync.WaitGroup
+		ran  atomic.Int64
+	)
+	done.Add(tasks)
+	for range tasks {
+		pool.Submit(HandlerTask{Ctx: context.Background(), Handler: func(context.Context, *bot.Bot, *botModels.Update) {
+			defer done.Done()
+			time.Sleep(time.Millisecond)
+			ran.Add(1)
+		}})
+	}
+
+	peak := 0
+	finished := make(chan struct{})
+	go func() {
+		done.Wait()
+		close(finished)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-finished:
+			waiting = false
+		case <-time.After(time.Millisecond):
+			peak = max(peak, pool.Stats().Workers)
+		}
+	}
+
+	if got := ran.Load(); got != tasks {
+		t.Fatalf("expected %d tasks to run, got %d", tasks, got)
+	}
+	if dropped := pool.Stats().Dropped; dropped != 0 {
+		t.Fatalf("expected no dropped tasks, got %d", dropped)
+	}
+	if peak <= 1 {
+		t.Fatalf("expected pool to scale up under backlog, peak workers %d", peak)
+	}
+	if peak > 8 {
+		t.Fatalf("expected workers capped at 8, peak %d", peak)
+	}
+
+	// 空闲后逐步回收到下限
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Stats().Workers > 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected pool to scale back to 1 worker, still %d", pool.Stats().Workers)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 关闭时已入队的任务仍会执行完
+	var late atomic.Int64
+	for range 20 {
+		pool.Submit(HandlerTask{Ctx: context.Background(), Handler: func(context.Context, *bot.Bot, *botModels.Update) {
+			late.Add(1)
+		}})
+	}
+	pool.Shutdown(0)
+	if got := late.Load(); got != 20 {
+		t.Fatalf("expected queued tasks to drain on shutdown, ran %d", got)
+	}
+}
+
+func TestWorkerPoolScaleHysteresis(t *testing.T) {
+	// 间隔足够长，伸缩只由测试手动触发
+	pool := NewAdaptiveWorkerPool(WorkerPoolConfig{MinWorkers: 1, MaxWorkers: 5, QueueSize: 4, ScaleInterval: time.Hour})
+	defer pool.Shutdown(0)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	blocking := HandlerTask{Ctx: context.Background(), Handler: func(context.Context, *bot.Bot, *botModels.Update) {
+		started <- struct{}{}
+		<-release
+	}}
+	pool.Submit(blocking)
+	<-started
+	pool.Submit(blocking)
+	pool.Submit(blocking)
+
+	// 单次高水位不扩容，连续两次才扩容
+	pool.scale()
+	if got := pool.Stats().Workers; got != 1 {
+		t.Fatalf("expected no scale up after one busy check, got %d workers", got)
+	}
+	pool.scale()
+	if got := pool.Stats().Workers; got != 2 {
+		t.Fatalf("expected scale up after two busy checks, got %d workers", got)
+	}
+	<-started
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for stats := pool.Stats(); stats.Active > 0 || stats.QueueLength > 0; stats = pool.Stats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tasks did not finish: %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for range workerScaleDownChecks - 1 {
+		pool.scale()
+	}
+	if got := pool.Stats().Workers; got != 2 {
+		t.Fatalf("expected no scale down before %d idle checks, got %d workers", workerScaleDownChecks, got)
+	}
+	pool.scale()
+	if got := pool.Stats().Workers; got != 1 {
+		t.Fatalf("expected scale down after idle checks, got %d workers", got)
+	}
+
+	// 已在下限时不再回收
+	for range workerScaleDownChecks {
+		pool.scale()
+	}
+	if got := pool.Stats().Workers; got != 1 {
+		t.Fatalf("expected workers to stay at minimum, got %d", got)
+	}
+}
+
+func TestWorkerPoolShutdownDrainsQueuedTasks(t *testing.T) {
+	pool := NewWorkerPool(1, 10)
+
+	// 接收 update 的 ctx 已取消（Bot 停止拉取），已入队的任务仍以未取消的 ctx 执行完
+	updateCtx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	var (
+		ran      atomic.Int64
+		canceled atomic.Int64
+	)
+	for i := range 5 {
+		pool.Submit(HandlerTask{Ctx: updateCtx, Handler: func(ctx context.Context, _ *bot.Bot, _ *botModels.Update) {
+			if i == 0 {
+				<-release
+			}
+			if ctx.Err() != nil {
+				canceled.Add(1)
+			}
+			ran.Add(1)
+		}})
+	}
+	cancel()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if abandoned := pool.Shutdown(time.Second); abandoned != 0 {
+		t.Fatalf("expected no abandoned tasks, got %d", abandoned)
+	}
+	if got := ran.Load(); got != 5 {
+		t.Fatalf("expected all 5 queued tasks to run, got %d", got)
+	}
+	if got := canceled.Load(); got != 0 {
+		t.Fatalf("expected task ctx to stay alive during drain, %d task(s) saw cancellation", got)
+	}
+
+	// 关闭期间/之后提交的任务被拒绝，不会向已关闭的队列发送
+	if pool.Submit(HandlerTask{Ctx: context.Background(), Handler: func(context.Context, *bot.Bot, *botModels.Update) {
+		t.Error("rejected task must not run")
+	}}) {
+		t.Fatal("expected submit after shutdown to be rejected")
+	}
+	if dropped := pool.Stats().Dropped; dropped != 1 {
+		t.Fatalf("expected rejected task to be counted as dropped, got %d", dropped)
+	}
+}
+
+func TestWorkerPoolShutdownTimeoutAbandonsRemainingTasks(t *testing.T) {
+	pool := NewWorkerPool(1, 10)
+
+	started := make(chan struct{})
+	stuckCanceled := make(chan struct{})
+	pool.Submit(HandlerTask{Ctx: context.Background(), Handler: func(ctx context.Context, _ *bot.Bot, _ *botModels.Update) {
+		close(started)
+		<-ctx.Done()
+		close(stuckCanceled)
+	}})
+	<-started
+
+	var ran atomic.Int64
+	for range 3 {
+		pool.Submit(HandlerTask{Ctx: context.Background(), Handler: func(context.Context, *bot.Bot, *botModels.Update) {
+			ran.Add(1)
+		}})
+	}
+
+	if abandoned := pool.Shutdown(20 * time.Millisecond); abandoned != 4 {
+		t.Fatalf("expected 1 running + 3 queued tasks abandoned, got %d", abandoned)
+	}
+	select {
+	case <-stuckCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected running task ctx to be cancelled after drain timeout")
+	}
+	if got := ran.Load(); got != 0 {
+		t.Fatalf("expected queued tasks to be discarded after timeout, %d ran", got)
+	}
+	if dropped := pool.Stats().Dropped; dropped != 3 {
+		t.Fatalf("expected 3 discarded tasks counted as dropped, got %d", dropped)
+	}
+}
+
+func TestWorkerDrainTimeoutBoundedByContext(t *testing.T) {
+	if got := workerDrainTimeout(context.Background(), 0); got != defaultWorkerDrainTimeout {
+		t.Fatalf("expected default drain timeout, got %s", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if got := workerDrainTimeout(ctx, 30*time.Second); got > time.Second {
+		t.Fatalf("expected drain timeout to leave time for later shutdown steps, got %s", got)
+	}
+}
@@ -0,0 +1,71 @@
+// Package version 编译时注入的版本信息
+//
+// 构建时通过 -ldflags 注入：
+//
+//	go build -ldflags "-X go_bot/internal/version.Version=v1.2.0 -X go_bot/internal/version.Commit=$(git rev-parse --short HEAD) -X go_bot/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/bot
+//
+// 未注入时 Commit 与 BuildTime 回退到 Go 工具链写入二进制的 VCS 信息（需在 git 仓库内构建）
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version 版本号，默认 dev
+	Version = "dev"
+	// Commit 构建时的 git commit
+	Commit = ""
+	// BuildTime 构建时间（UTC，RFC3339）
+	BuildTime = ""
+)
+
+// Info 版本信息快照
+type Info struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	GoVersion string
+	Modified  bool // 构建时工作区有未提交的改动（仅 VCS 信息可用时）
+}
+
+// Get 返回当前二进制的版本信息
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = shortCommit(setting.Value)
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	return info
+}
+
+// shortCommit 截取 commit 前 12 位
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}